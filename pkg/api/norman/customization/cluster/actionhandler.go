@@ -75,6 +75,8 @@ func (a ActionHandler) ClusterActionHandler(actionName string, action *types.Act
 	switch actionName {
 	case v32.ClusterActionGenerateKubeconfig:
 		return a.GenerateKubeconfigActionHandler(actionName, action, apiContext)
+	case v32.ClusterActionGenerateKubeconfigBundle:
+		return a.GenerateKubeconfigBundleActionHandler(actionName, action, apiContext)
 	case v32.ClusterActionImportYaml:
 		return a.ImportYamlHandler(actionName, action, apiContext)
 	case v32.ClusterActionExportYaml:
@@ -116,6 +118,11 @@ func (a ActionHandler) ClusterActionHandler(actionName string, action *types.Act
 			return httperror.NewAPIError(httperror.PermissionDenied, "can not rotate encryption key")
 		}
 		return a.RotateEncryptionKey(actionName, action, apiContext)
+	case v32.ClusterActionRefreshServiceAccountToken:
+		if !canUpdateCluster(apiContext) {
+			return httperror.NewAPIError(httperror.PermissionDenied, "can not refresh service account token")
+		}
+		return a.RefreshServiceAccountToken(actionName, action, apiContext)
 	case v32.ClusterActionSaveAsTemplate:
 		if !canUpdateCluster(apiContext) {
 			return httperror.NewAPIError(httperror.PermissionDenied, "can not save the cluster as an RKETemplate")
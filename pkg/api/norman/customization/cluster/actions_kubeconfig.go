@@ -1,11 +1,15 @@
 package cluster
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 
 	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/parse"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/rancher/pkg/auth/tokens"
 	mgmtclient "github.com/rancher/rancher/pkg/client/generated/management/v3"
@@ -19,6 +23,11 @@
 	"k8s.io/apimachinery/pkg/labels"
 )
 
+const (
+	kubeconfigAuthModeToken = "token"
+	kubeconfigAuthModeExec  = "exec"
+)
+
 func (a ActionHandler) GenerateKubeconfigActionHandler(actionName string, action *types.Action, apiContext *types.APIContext) error {
 	var err error
 	var cluster mgmtclient.Cluster
@@ -53,17 +62,7 @@ func (a ActionHandler) GenerateKubeconfigActionHandler(actionName string, action
 		}
 	}
 
-	host := settings.ServerURL.Get()
-	if host == "" {
-		host = apiContext.Request.Host
-	} else {
-		u, err := url.Parse(host)
-		if err == nil {
-			host = u.Host
-		} else {
-			host = apiContext.Request.Host
-		}
-	}
+	host := kubeconfigHost(apiContext)
 
 	if endpointEnabled {
 		if err = a.createClusterAuthTokenDownstream(apiContext.ID, tokenKey); err != nil {
@@ -89,6 +88,83 @@ func (a ActionHandler) GenerateKubeconfigActionHandler(actionName string, action
 	return nil
 }
 
+// GenerateKubeconfigBundleActionHandler renders a single kubeconfig with a context for each cluster the caller can
+// access. The contexts connect through the Rancher server, authenticating with a kubeconfig token or the rancher CLI
+// depending on the requested auth mode. Kubeconfig tokens are only generated if the kubeconfig-generate-token setting is
+// enabled.
+func (a ActionHandler) GenerateKubeconfigBundleActionHandler(actionName string, action *types.Action, apiContext *types.APIContext) error {
+	input, err := parse.ReadBody(apiContext.Request)
+	if err != nil {
+		return err
+	}
+
+	var generateToken bool
+	tokenAllowed := strings.EqualFold(settings.KubeconfigGenerateToken.Get(), "true")
+	switch authMode, _ := input["authMode"].(string); authMode {
+	case "":
+		generateToken = tokenAllowed
+	case kubeconfigAuthModeToken:
+		if !tokenAllowed {
+			return httperror.NewAPIError(httperror.PermissionDenied, fmt.Sprintf("authMode %q is not allowed as the %s setting is disabled", authMode, settings.KubeconfigGenerateToken.Name))
+		}
+		generateToken = true
+	case kubeconfigAuthModeExec:
+		generateToken = false
+	default:
+		return httperror.NewAPIError(httperror.InvalidBodyContent, fmt.Sprintf("invalid authMode %q, must be %q or %q", authMode, kubeconfigAuthModeToken, kubeconfigAuthModeExec))
+	}
+
+	var clusters []mgmtclient.Cluster
+	if err := access.List(apiContext, apiContext.Version, mgmtclient.ClusterType, &types.QueryOptions{}, &clusters); err != nil {
+		return err
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].ID < clusters[j].ID
+	})
+
+	bundleClusters := make([]kubeconfig.BundleCluster, 0, len(clusters))
+	for _, cluster := range clusters {
+		bundleClusters = append(bundleClusters, kubeconfig.BundleCluster{
+			ID:   cluster.ID,
+			Name: cluster.Name,
+		})
+	}
+
+	var tokenKey string
+	if generateToken && len(bundleClusters) > 0 {
+		if tokenKey, err = a.ensureToken(apiContext); err != nil {
+			return err
+		}
+	}
+
+	host := kubeconfigHost(apiContext)
+	cfg, err := kubeconfig.ForTokenBasedBundle(bundleClusters, host, a.UserMgr.GetUser(apiContext), tokenKey)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"config": cfg,
+		"type":   "generateKubeconfigOutput",
+	}
+	apiContext.WriteResponse(http.StatusOK, data)
+	return nil
+}
+
+// kubeconfigHost returns the host of the Rancher server that kubeconfigs should point to, falling back to the host of
+// the request if the server-url setting is unset or invalid.
+func kubeconfigHost(apiContext *types.APIContext) string {
+	host := settings.ServerURL.Get()
+	if host == "" {
+		return apiContext.Request.Host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return apiContext.Request.Host
+	}
+	return u.Host
+}
+
 // createClusterAuthTokenDownstream will create a ClusterAuthToken in the downstream cluster if the token hashing feature flag is enabled.
 // This is required because, if the token hashing is enabled, then the controller will not be able to create the ClusterAuthToken
 // in the downstream cluster because the token is stored hashed in the local cluster.
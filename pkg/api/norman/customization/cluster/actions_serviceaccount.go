@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/norman/api/access"
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3client "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/clusteroperator"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RefreshServiceAccountToken requests the operator controller of a hosted cluster to revoke the service account token
+// Rancher uses to connect to the cluster and to generate a new one. The progress is reported with the
+// ServiceAccountTokenRefreshed condition of the cluster.
+func (a ActionHandler) RefreshServiceAccountToken(actionName string, action *types.Action, apiContext *types.APIContext) error {
+	response := map[string]interface{}{
+		"type": v3client.RefreshServiceAccountTokenOutputType,
+		v3client.RefreshServiceAccountTokenOutputFieldMessage: "refreshing service account token",
+	}
+
+	var mgmtCluster mgmtv3.Cluster
+	if err := access.ByID(apiContext, apiContext.Version, apiContext.Type, apiContext.ID, &mgmtCluster); err != nil {
+		response[v3client.RefreshServiceAccountTokenOutputFieldMessage] = "cluster does not exist"
+		apiContext.WriteResponse(http.StatusBadRequest, response)
+		return errors.Wrapf(err, "failed to get cluster by ID %s", apiContext.ID)
+	}
+
+	cluster, err := a.ClusterClient.Get(apiContext.ID, v1.GetOptions{})
+	if err != nil {
+		response[v3client.RefreshServiceAccountTokenOutputFieldMessage] = "cluster does not exist"
+		apiContext.WriteResponse(http.StatusBadRequest, response)
+		return errors.Wrapf(err, "failed to get cluster by ID %s", apiContext.ID)
+	}
+
+	if !isOperatorHostedCluster(cluster) {
+		return httperror.NewAPIError(httperror.InvalidAction, "service account token can only be refreshed for AKS, EKS and GKE clusters")
+	}
+	if cluster.Status.ServiceAccountTokenSecret == "" {
+		return httperror.NewAPIError(httperror.InvalidAction, "cluster has no service account token yet")
+	}
+
+	cluster = cluster.DeepCopy()
+	if cluster.Annotations == nil {
+		cluster.Annotations = map[string]string{}
+	}
+	cluster.Annotations[clusteroperator.ServiceAccountTokenRefreshAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	v3.ClusterConditionServiceAccountTokenRefreshed.Unknown(cluster)
+	v3.ClusterConditionServiceAccountTokenRefreshed.Message(cluster, "waiting for service account token to be refreshed")
+	if _, err := a.ClusterClient.Update(cluster); err != nil {
+		response[v3client.RefreshServiceAccountTokenOutputFieldMessage] = "failed to update cluster object"
+		apiContext.WriteResponse(http.StatusInternalServerError, response)
+		return errors.Wrapf(err, "unable to update cluster %s", cluster.Name)
+	}
+
+	apiContext.WriteResponse(http.StatusOK, response)
+	return nil
+}
+
+// isOperatorHostedCluster returns true if the cluster is provisioned or imported by one of the hosted provider
+// operators, which manage the service account token of the cluster.
+func isOperatorHostedCluster(cluster *v3.Cluster) bool {
+	return cluster.Spec.AKSConfig != nil || cluster.Spec.EKSConfig != nil || cluster.Spec.GKEConfig != nil
+}
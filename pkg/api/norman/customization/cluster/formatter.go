@@ -54,6 +54,11 @@ func (f *Formatter) Formatter(request *types.APIContext, resource *types.RawReso
 		} else {
 			resource.AddAction(request, v32.ClusterActionEnableMonitoring)
 		}
+
+		// If this is a cluster managed by a hosted provider operator
+		if hasHostedConfig(resource.Values) {
+			resource.AddAction(request, v32.ClusterActionRefreshServiceAccountToken)
+		}
 	}
 
 	// If this is an RKE1 cluster only
@@ -159,6 +164,16 @@ func rotateEncryptionKeyEnabled(clusterLister v3.ClusterLister, clusterName stri
 	return v32.ClusterConditionUpdated.IsTrue(cluster)
 }
 
+// hasHostedConfig returns true if the cluster values have the config of one of the hosted provider operators.
+func hasHostedConfig(values map[string]interface{}) bool {
+	for _, key := range []string{"aksConfig", "eksConfig", "gkeConfig"} {
+		if values[key] != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func setTrueIfNil(configMap map[string]interface{}, fieldName string) {
 	if configMap[fieldName] == nil {
 		configMap[fieldName] = true
@@ -183,6 +198,7 @@ func hideClusterTemplateAnswers(answerMap map[string]interface{}, clusterSpecPwd
 
 func (f *Formatter) CollectionFormatter(request *types.APIContext, collection *types.GenericCollection) {
 	collection.AddAction(request, "createFromTemplate")
+	collection.AddAction(request, v32.ClusterActionGenerateKubeconfigBundle)
 }
 
 func gatherClusterSpecPwdFields(schemas *types.Schemas, schema *types.Schema) map[string]interface{} {
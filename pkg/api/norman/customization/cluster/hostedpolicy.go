@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/clusteroperator"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// validateHostedClusterPolicies rejects the hosted cluster config if it violates a hosted cluster policy that applies to
+// its provider. On updates, only violations the previous config did not have are rejected, so that adding a policy does
+// not block unrelated changes of existing clusters. The instance types of EKS launch templates are only checked by the
+// operator controllers, which hold back changes that violate the policies.
+func (v *Validator) validateHostedClusterPolicies(request *types.APIContext, spec *v32.ClusterSpec) error {
+	if v.HostedClusterPolicies == nil {
+		return nil
+	}
+	config := clusteroperator.HostedConfigOf(spec)
+	if config == nil {
+		return nil
+	}
+	policies, err := v.HostedClusterPolicies.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	var prevConfig *clusteroperator.HostedClusterConfig
+	if request.Method == http.MethodPut {
+		prevCluster, err := v.ClusterLister.Get("", request.ID)
+		if err != nil {
+			return err
+		}
+		prevConfig = clusteroperator.HostedConfigOf(&prevCluster.Spec)
+	}
+	if violations := clusteroperator.HostedClusterPolicyViolations(policies, config, prevConfig); len(violations) > 0 {
+		return httperror.NewAPIError(httperror.InvalidBodyContent, "cluster violates hosted cluster policies: "+strings.Join(violations, "; "))
+	}
+	return nil
+}
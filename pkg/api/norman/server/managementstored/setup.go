@@ -268,6 +268,7 @@ func Clusters(ctx context.Context, schemas *types.Schemas, managementContext *co
 		Users:                         managementContext.Management.Users(""),
 		GrbLister:                     managementContext.Management.GlobalRoleBindings("").Controller().Lister(),
 		GrLister:                      managementContext.Management.GlobalRoles("").Controller().Lister(),
+		HostedClusterPolicies:         managementContext.Wrangler.Mgmt.HostedClusterPolicy().Cache(),
 	}
 
 	handler.CatalogTemplateVersionLister = managementContext.Management.CatalogTemplateVersions("").Controller().Lister()
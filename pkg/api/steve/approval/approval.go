@@ -0,0 +1,147 @@
+// Package approval exposes the approval of etcd snapshot restores of provisioned clusters. Approvals are created on
+// behalf of the requesting user in the system namespace, so that the approver recorded in an approval can not be forged
+// by the users of the namespace of the cluster.
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/steve/pkg/attributes"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	approveRestoreActionName = "approveEtcdSnapshotRestore"
+	maxInputBytes            = 64 << 10
+)
+
+// ApproveRestoreInput is the input of the approve etcd snapshot restore action.
+type ApproveRestoreInput struct {
+	// Generation is the generation of the approved restore, the current restore of the cluster if unset.
+	Generation *int   `json:"generation,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+}
+
+type approveRestoreHandler struct {
+	clusters  provisioningcontrollers.ClusterCache
+	approvals rkecontrollers.ApprovalClient
+}
+
+func Register(server *steve.Server, clients *wrangler.Context) {
+	h := &approveRestoreHandler{
+		clusters:  clients.Provisioning.Cluster().Cache(),
+		approvals: clients.RKE.Approval(),
+	}
+
+	server.BaseSchemas.MustImportAndCustomize(ApproveRestoreInput{}, nil)
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "provisioning.cattle.io",
+		Kind:  "Cluster",
+		Customize: func(schema *types.APISchema) {
+			if schema.ActionHandlers == nil {
+				schema.ActionHandlers = map[string]http.Handler{}
+			}
+			schema.ActionHandlers[approveRestoreActionName] = h
+			if schema.ResourceActions == nil {
+				schema.ResourceActions = map[string]schemas.Action{}
+			}
+			schema.ResourceActions[approveRestoreActionName] = schemas.Action{
+				Input: "approveRestoreInput",
+			}
+		},
+	})
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "rke.cattle.io",
+		Kind:  "Approval",
+		Customize: func(schema *types.APISchema) {
+			// approvals are only created through the approve action of the cluster, which sets the approver
+			attributes.AddDisallowMethods(schema,
+				http.MethodPost,
+				http.MethodPut,
+				http.MethodPatch)
+		},
+	})
+}
+
+func (h *approveRestoreHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := apiRequest.AccessControl.CanUpdate(apiRequest, types.APIObject{}, apiRequest.Schema); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	user, ok := request.UserFrom(req.Context())
+	if !ok {
+		apiRequest.WriteError(validation.Unauthorized)
+		return
+	}
+
+	input := &ApproveRestoreInput{}
+	if err := json.NewDecoder(http.MaxBytesReader(rw, req.Body, maxInputBytes)).Decode(input); err != nil {
+		apiRequest.WriteError(apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("failed to parse the input: %v", err)))
+		return
+	}
+	cluster, err := h.clusters.Get(apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	if cluster.Spec.RKEConfig == nil || cluster.Spec.RKEConfig.ETCDSnapshotRestore == nil || cluster.Spec.RKEConfig.ETCDSnapshotRestore.Name == "" {
+		apiRequest.WriteError(apierror.NewAPIError(validation.InvalidAction, "the cluster has no etcd snapshot restore to approve"))
+		return
+	}
+
+	approval, err := newApproval(cluster.Namespace, cluster.Name, cluster.Spec.RKEConfig.ETCDSnapshotRestore, user.GetName(), input)
+	if err != nil {
+		apiRequest.WriteError(apierror.NewAPIError(validation.InvalidBodyContent, err.Error()))
+		return
+	}
+	if _, err := h.approvals.Create(approval); err != nil && !apierrors.IsAlreadyExists(err) {
+		apiRequest.WriteError(err)
+		return
+	}
+	logrus.Infof("[approval] user %s approved etcd snapshot restore %d of snapshot %s of cluster %s/%s", user.GetName(), approval.Spec.Generation, approval.Spec.SnapshotName, cluster.Namespace, cluster.Name)
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// newApproval returns the approval of the restore of the cluster by the user. Approvals are named after the cluster, the
+// restore and the approver, so that approving a restore more than once does not create more approvals.
+func newApproval(clusterNamespace, clusterName string, restore *rkev1.ETCDSnapshotRestore, approver string, input *ApproveRestoreInput) (*rkev1.Approval, error) {
+	generation := restore.Generation
+	if input.Generation != nil && *input.Generation != generation {
+		return nil, fmt.Errorf("generation %d does not match the generation %d of the etcd snapshot restore of the cluster", *input.Generation, generation)
+	}
+	return &rkev1.Approval{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name.SafeConcatName(clusterNamespace, clusterName, "etcd-snapshot-restore", strconv.Itoa(generation),
+				name.Hex(restore.Name, 8), name.Hex(approver, 8)),
+			Namespace: namespace.System,
+		},
+		Spec: rkev1.ApprovalSpec{
+			ClusterNamespace: clusterNamespace,
+			ClusterName:      clusterName,
+			Operation:        rkev1.ApprovalOperationETCDSnapshotRestore,
+			SnapshotName:     restore.Name,
+			Generation:       generation,
+			Approver:         approver,
+			Comment:          input.Comment,
+		},
+	}, nil
+}
@@ -0,0 +1,43 @@
+package approval
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewApproval(t *testing.T) {
+	restore := &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 3}
+
+	approval, err := newApproval("fleet-default", "test", restore, "u-a", &ApproveRestoreInput{Comment: "restore after incident"})
+	require.NoError(t, err)
+	assert.Equal(t, "cattle-system", approval.Namespace)
+	assert.Equal(t, rkev1.ApprovalSpec{
+		ClusterNamespace: "fleet-default",
+		ClusterName:      "test",
+		Operation:        rkev1.ApprovalOperationETCDSnapshotRestore,
+		SnapshotName:     "snapshot",
+		Generation:       3,
+		Approver:         "u-a",
+		Comment:          "restore after incident",
+	}, approval.Spec)
+
+	generation := 3
+	again, err := newApproval("fleet-default", "test", restore, "u-a", &ApproveRestoreInput{Generation: &generation})
+	require.NoError(t, err)
+	assert.Equal(t, approval.Name, again.Name, "approving twice must not create a second approval")
+
+	other, err := newApproval("fleet-default", "test", restore, "u-b", &ApproveRestoreInput{})
+	require.NoError(t, err)
+	assert.NotEqual(t, approval.Name, other.Name)
+
+	otherSnapshot, err := newApproval("fleet-default", "test", &rkev1.ETCDSnapshotRestore{Name: "other", Generation: 3}, "u-a", &ApproveRestoreInput{})
+	require.NoError(t, err)
+	assert.NotEqual(t, approval.Name, otherSnapshot.Name)
+
+	generation = 2
+	_, err = newApproval("fleet-default", "test", restore, "u-a", &ApproveRestoreInput{Generation: &generation})
+	assert.Error(t, err)
+}
@@ -11,6 +11,7 @@
 
 var (
 	allowAll = map[string]bool{
+		"hostedclusterpolicies":                      true,
 		"podsecurityadmissionconfigurationtemplates": true,
 	}
 	allowPost = map[string]bool{
@@ -0,0 +1,489 @@
+// Package etcdsnapshot exposes the creation, restore and listing of etcd snapshots of provisioned clusters as actions of
+// the cluster, so that the Rancher CLI and other automation do not have to patch the cluster spec to drive them. The
+// progress of the requested operations is polled through the etcdSnapshotStatus link of the cluster.
+package etcdsnapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/snapshotclient"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	createActionName  = "createEtcdSnapshot"
+	restoreActionName = "restoreEtcdSnapshot"
+	listActionName    = "listEtcdSnapshots"
+	statusLinkName    = "etcdSnapshotStatus"
+	maxInputBytes     = 64 << 10
+	clusterSchemaID   = "provisioning.cattle.io.clusters"
+
+	operationCreate  = "create"
+	operationRestore = "restore"
+)
+
+// CreateEtcdSnapshotInput is the input of the create etcd snapshot action.
+type CreateEtcdSnapshotInput struct {
+	// MaxConcurrency is the maximum number of etcd nodes that create the snapshot at the same time, all of them if unset.
+	MaxConcurrency          int    `json:"maxConcurrency,omitempty"`
+	EncryptionKeySecretName string `json:"encryptionKeySecretName,omitempty"`
+}
+
+// RestoreEtcdSnapshotInput is the input of the restore etcd snapshot action.
+type RestoreEtcdSnapshotInput struct {
+	// Name is the name of the etcd snapshot object to restore.
+	Name string `json:"name"`
+	// RestoreRKEConfig is none (or empty), kubernetesVersion or all.
+	RestoreRKEConfig        string `json:"restoreRKEConfig,omitempty"`
+	EncryptionKeySecretName string `json:"encryptionKeySecretName,omitempty"`
+	MigrateStorageVersions  bool   `json:"migrateStorageVersions,omitempty"`
+	// PreviewOnly publishes the plans of the restore to a ConfigMap for review instead of restoring the snapshot.
+	PreviewOnly bool `json:"previewOnly,omitempty"`
+}
+
+// EtcdSnapshotOperation is the progress of a snapshot creation or restore requested for a cluster. The phase is empty
+// while the request has not been picked up by the control plane of the cluster yet.
+type EtcdSnapshotOperation struct {
+	Operation  string `json:"operation"`
+	Generation int    `json:"generation"`
+	// SnapshotName is the name of the etcd snapshot object that is restored.
+	SnapshotName string `json:"snapshotName,omitempty"`
+	Phase        string `json:"phase,omitempty"`
+	// Done is true once the operation finished, failed, was cancelled or was previewed.
+	Done     bool                          `json:"done"`
+	Machines []EtcdSnapshotMachineProgress `json:"machines,omitempty"`
+}
+
+// EtcdSnapshotMachineProgress is the progress of a snapshot creation on an etcd machine.
+type EtcdSnapshotMachineProgress struct {
+	MachineName string `json:"machineName"`
+	Phase       string `json:"phase,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// EtcdSnapshotStatusOutput is the progress of the last snapshot creation and restore requested for a cluster, which is
+// served by the etcd snapshot status link of the cluster.
+type EtcdSnapshotStatusOutput struct {
+	Create  *EtcdSnapshotOperation `json:"create,omitempty"`
+	Restore *EtcdSnapshotOperation `json:"restore,omitempty"`
+}
+
+// EtcdSnapshotSummary describes an etcd snapshot of a cluster.
+type EtcdSnapshotSummary struct {
+	// Name is the name of the etcd snapshot object, which is passed to the restore action.
+	Name      string `json:"name"`
+	FileName  string `json:"fileName,omitempty"`
+	NodeName  string `json:"nodeName,omitempty"`
+	Storage   string `json:"storage"`
+	CreatedAt string `json:"createdAt,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	// Restorable is false for snapshots that failed, are missing, or failed verification.
+	Restorable bool   `json:"restorable"`
+	Message    string `json:"message,omitempty"`
+}
+
+// ListEtcdSnapshotsOutput is the output of the list etcd snapshots action, newest snapshot first.
+type ListEtcdSnapshotsOutput struct {
+	Snapshots []EtcdSnapshotSummary `json:"snapshots"`
+}
+
+type handler struct {
+	clusters      provisioningcontrollers.ClusterCache
+	controlPlanes rkecontrollers.RKEControlPlaneCache
+	secrets       corecontrollers.SecretCache
+	snapshots     *snapshotclient.Client
+}
+
+func Register(server *steve.Server, clients *wrangler.Context) {
+	h := &handler{
+		clusters:      clients.Provisioning.Cluster().Cache(),
+		controlPlanes: clients.RKE.RKEControlPlane().Cache(),
+		secrets:       clients.Core.Secret().Cache(),
+		snapshots:     snapshotclient.NewFromContext(clients),
+	}
+
+	server.BaseSchemas.MustImportAndCustomize(CreateEtcdSnapshotInput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(RestoreEtcdSnapshotInput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(EtcdSnapshotOperation{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(ListEtcdSnapshotsOutput{}, nil)
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "provisioning.cattle.io",
+		Kind:  "Cluster",
+		Customize: func(schema *types.APISchema) {
+			if schema.ActionHandlers == nil {
+				schema.ActionHandlers = map[string]http.Handler{}
+			}
+			schema.ActionHandlers[createActionName] = http.HandlerFunc(h.create)
+			schema.ActionHandlers[restoreActionName] = http.HandlerFunc(h.restore)
+			schema.ActionHandlers[listActionName] = http.HandlerFunc(h.list)
+			if schema.ResourceActions == nil {
+				schema.ResourceActions = map[string]schemas.Action{}
+			}
+			schema.ResourceActions[createActionName] = schemas.Action{
+				Input:  "createEtcdSnapshotInput",
+				Output: "etcdSnapshotOperation",
+			}
+			schema.ResourceActions[restoreActionName] = schemas.Action{
+				Input:  "restoreEtcdSnapshotInput",
+				Output: "etcdSnapshotOperation",
+			}
+			schema.ResourceActions[listActionName] = schemas.Action{
+				Output: "listEtcdSnapshotsOutput",
+			}
+			if schema.LinkHandlers == nil {
+				schema.LinkHandlers = map[string]http.Handler{}
+			}
+			schema.LinkHandlers[statusLinkName] = http.HandlerFunc(h.status)
+			formatter := schema.Formatter
+			schema.Formatter = func(request *types.APIRequest, resource *types.RawResource) {
+				if formatter != nil {
+					formatter(request, resource)
+				}
+				if resource.APIObject.Data().Map("spec", "rkeConfig") == nil {
+					delete(resource.Actions, createActionName)
+					delete(resource.Actions, restoreActionName)
+					delete(resource.Actions, listActionName)
+					delete(resource.Links, statusLinkName)
+				}
+			}
+		},
+	})
+}
+
+// create requests a snapshot of the cluster and writes the requested operation.
+func (h *handler) create(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := canUpdate(apiRequest); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	input := &CreateEtcdSnapshotInput{}
+	if err := json.NewDecoder(http.MaxBytesReader(rw, req.Body, maxInputBytes)).Decode(input); err != nil {
+		apiRequest.WriteError(apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("failed to parse the input: %v", err)))
+		return
+	}
+	if err := h.checkEncryptionKeySecret(apiRequest, input.EncryptionKeySecretName); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	cluster, controlPlane, err := h.get(apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	if err := validateCreate(cluster, controlPlane, input); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	generation, err := h.snapshots.RequestSnapshot(cluster.Namespace, cluster.Name, rkev1.ETCDSnapshotCreate{
+		MaxConcurrency:          input.MaxConcurrency,
+		EncryptionKeySecretName: input.EncryptionKeySecretName,
+	})
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	logrus.Infof("[etcdsnapshot] user %s requested etcd snapshot creation %d of cluster %s/%s", userName(req), generation, cluster.Namespace, cluster.Name)
+	writeJSON(apiRequest, &EtcdSnapshotOperation{Operation: operationCreate, Generation: generation})
+}
+
+// restore requests the restore of a snapshot to the cluster and writes the requested operation.
+func (h *handler) restore(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := canUpdate(apiRequest); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	input := &RestoreEtcdSnapshotInput{}
+	if err := json.NewDecoder(http.MaxBytesReader(rw, req.Body, maxInputBytes)).Decode(input); err != nil {
+		apiRequest.WriteError(apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("failed to parse the input: %v", err)))
+		return
+	}
+	if err := h.checkEncryptionKeySecret(apiRequest, input.EncryptionKeySecretName); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	cluster, controlPlane, err := h.get(apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	var snapshot *rkev1.ETCDSnapshot
+	if input.Name != "" {
+		snapshot, err = h.snapshots.Get(cluster.Namespace, input.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			apiRequest.WriteError(err)
+			return
+		}
+	}
+	if err := validateRestore(cluster, controlPlane, snapshot, input); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	generation, err := h.snapshots.RequestRestore(cluster.Namespace, cluster.Name, rkev1.ETCDSnapshotRestore{
+		Name:                    input.Name,
+		RestoreRKEConfig:        input.RestoreRKEConfig,
+		EncryptionKeySecretName: input.EncryptionKeySecretName,
+		MigrateStorageVersions:  input.MigrateStorageVersions,
+		PreviewOnly:             input.PreviewOnly,
+	})
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	logrus.Infof("[etcdsnapshot] user %s requested etcd snapshot restore %d of snapshot %s to cluster %s/%s", userName(req), generation, input.Name, cluster.Namespace, cluster.Name)
+	writeJSON(apiRequest, &EtcdSnapshotOperation{Operation: operationRestore, Generation: generation, SnapshotName: input.Name})
+}
+
+// list writes the snapshots of the cluster, newest first.
+func (h *handler) list(_ http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := apiRequest.AccessControl.CanDo(apiRequest, clusterSchemaID, "get", apiRequest.Namespace, apiRequest.Name); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	snapshots, err := h.snapshots.List(apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	output := &ListEtcdSnapshotsOutput{Snapshots: []EtcdSnapshotSummary{}}
+	for _, snapshot := range snapshots {
+		output.Snapshots = append(output.Snapshots, summarize(snapshot))
+	}
+	writeJSON(apiRequest, output)
+}
+
+// status writes the progress of the last snapshot creation and restore requested for the cluster.
+func (h *handler) status(_ http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := apiRequest.AccessControl.CanDo(apiRequest, clusterSchemaID, "get", apiRequest.Namespace, apiRequest.Name); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	if req.Method != http.MethodGet {
+		apiRequest.WriteError(apierror.NewAPIError(validation.MethodNotAllowed, "only GET is allowed for the etcd snapshot status"))
+		return
+	}
+	cluster, controlPlane, err := h.get(apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	writeJSON(apiRequest, &EtcdSnapshotStatusOutput{
+		Create:  createOperation(cluster.Spec.RKEConfig, controlPlane),
+		Restore: restoreOperation(cluster.Spec.RKEConfig, controlPlane),
+	})
+}
+
+// canUpdate checks that the user may update the cluster of the request, as creating and restoring snapshots is done by
+// updating the cluster on behalf of the user.
+func canUpdate(apiRequest *types.APIRequest) error {
+	return apiRequest.AccessControl.CanDo(apiRequest, clusterSchemaID, "update", apiRequest.Namespace, apiRequest.Name)
+}
+
+// checkEncryptionKeySecret checks that the user may read the encryption key secret, and that the secret belongs to the
+// cluster of the request, so that the key of another cluster in the namespace can not be used.
+func (h *handler) checkEncryptionKeySecret(apiRequest *types.APIRequest, secretName string) error {
+	if secretName == "" {
+		return nil
+	}
+	if err := apiRequest.AccessControl.CanDo(apiRequest, "secrets", "get", apiRequest.Namespace, secretName); err != nil {
+		return err
+	}
+	secret, err := h.secrets.Get(apiRequest.Namespace, secretName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return validateEncryptionKeySecret(apiRequest.Name, secretName, secret)
+}
+
+// get returns the cluster and its control plane, which has the same name as the cluster.
+func (h *handler) get(namespace, name string) (*rancherv1.Cluster, *rkev1.RKEControlPlane, error) {
+	cluster, err := h.clusters.Get(namespace, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cluster.Spec.RKEConfig == nil {
+		return nil, nil, apierror.NewAPIError(validation.InvalidAction, "the cluster was not provisioned by Rancher and has no etcd snapshots")
+	}
+	controlPlane, err := h.controlPlanes.Get(namespace, name)
+	if apierrors.IsNotFound(err) {
+		return cluster, nil, nil
+	}
+	return cluster, controlPlane, err
+}
+
+// validateCreate refuses to request a snapshot of the cluster while another snapshot operation is in progress.
+func validateCreate(cluster *rancherv1.Cluster, controlPlane *rkev1.RKEControlPlane, input *CreateEtcdSnapshotInput) error {
+	if input.MaxConcurrency < 0 {
+		return apierror.NewAPIError(validation.InvalidBodyContent, "maxConcurrency must not be negative")
+	}
+	return checkInProgress(cluster, controlPlane)
+}
+
+// validateRestore refuses to restore a snapshot that does not belong to the cluster or can not be restored, and to
+// request a restore while another snapshot operation is in progress.
+func validateRestore(cluster *rancherv1.Cluster, controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot, input *RestoreEtcdSnapshotInput) error {
+	if input.Name == "" {
+		return apierror.NewAPIError(validation.MissingRequired, "the name of the etcd snapshot to restore is required")
+	}
+	switch input.RestoreRKEConfig {
+	case "", "none", "kubernetesVersion", "all":
+	default:
+		return apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("invalid restoreRKEConfig %s, must be none, kubernetesVersion or all", input.RestoreRKEConfig))
+	}
+	if snapshot == nil || snapshotclient.ClusterName(snapshot) != cluster.Name {
+		return apierror.NewAPIError(validation.NotFound, fmt.Sprintf("etcd snapshot %s of cluster %s not found", input.Name, cluster.Name))
+	}
+	if summary := summarize(snapshot); !summary.Restorable {
+		return apierror.NewAPIError(validation.InvalidAction, fmt.Sprintf("etcd snapshot %s can not be restored: %s", input.Name, summary.Message))
+	}
+	return checkInProgress(cluster, controlPlane)
+}
+
+// validateEncryptionKeySecret refuses encryption key secrets that do not exist or are not labeled with the name of the
+// cluster.
+func validateEncryptionKeySecret(clusterName, secretName string, secret *corev1.Secret) error {
+	if secret == nil || secret.Labels[capr.ClusterNameLabel] != clusterName {
+		return apierror.NewAPIError(validation.NotFound, fmt.Sprintf("etcd snapshot encryption key secret %s of cluster %s not found, it must be labeled %s=%s",
+			secretName, clusterName, capr.ClusterNameLabel, clusterName))
+	}
+	return nil
+}
+
+// checkInProgress returns an error if a snapshot creation or restore requested for the cluster is not done yet.
+func checkInProgress(cluster *rancherv1.Cluster, controlPlane *rkev1.RKEControlPlane) error {
+	for _, operation := range []*EtcdSnapshotOperation{
+		createOperation(cluster.Spec.RKEConfig, controlPlane),
+		restoreOperation(cluster.Spec.RKEConfig, controlPlane),
+	} {
+		if operation != nil && !operation.Done {
+			return apierror.NewAPIError(validation.Conflict, fmt.Sprintf("etcd snapshot %s %d of the cluster is still in progress", operation.Operation, operation.Generation))
+		}
+	}
+	return nil
+}
+
+// createOperation returns the progress of the snapshot creation requested by the RKE config of the cluster, or nil if
+// none was requested.
+func createOperation(rkeConfig *rancherv1.RKEConfig, controlPlane *rkev1.RKEControlPlane) *EtcdSnapshotOperation {
+	if rkeConfig == nil || rkeConfig.ETCDSnapshotCreate == nil || rkeConfig.ETCDSnapshotCreate.Generation == 0 {
+		return nil
+	}
+	operation := &EtcdSnapshotOperation{
+		Operation:  operationCreate,
+		Generation: rkeConfig.ETCDSnapshotCreate.Generation,
+	}
+	if controlPlane == nil {
+		return operation
+	}
+	status := snapshotclient.StatusOf(controlPlane)
+	if status.Create == nil || status.Create.Generation != operation.Generation {
+		return operation
+	}
+	operation.Phase, operation.Done = string(status.CreatePhase), done(status.CreatePhase)
+	for _, machine := range status.Create.Machines {
+		operation.Machines = append(operation.Machines, EtcdSnapshotMachineProgress{
+			MachineName: machine.MachineName,
+			Phase:       string(machine.Phase),
+			Error:       machine.Error,
+		})
+	}
+	return operation
+}
+
+// restoreOperation returns the progress of the snapshot restore requested by the RKE config of the cluster, or nil if
+// none was requested.
+func restoreOperation(rkeConfig *rancherv1.RKEConfig, controlPlane *rkev1.RKEControlPlane) *EtcdSnapshotOperation {
+	if rkeConfig == nil || rkeConfig.ETCDSnapshotRestore == nil || rkeConfig.ETCDSnapshotRestore.Generation == 0 {
+		return nil
+	}
+	operation := &EtcdSnapshotOperation{
+		Operation:    operationRestore,
+		Generation:   rkeConfig.ETCDSnapshotRestore.Generation,
+		SnapshotName: rkeConfig.ETCDSnapshotRestore.Name,
+	}
+	if controlPlane == nil {
+		return operation
+	}
+	status := snapshotclient.StatusOf(controlPlane)
+	if status.Restore == nil || status.Restore.Generation != operation.Generation {
+		return operation
+	}
+	operation.Phase, operation.Done = string(status.RestorePhase), done(status.RestorePhase)
+	return operation
+}
+
+func done(phase rkev1.ETCDSnapshotPhase) bool {
+	switch phase {
+	case rkev1.ETCDSnapshotPhaseFinished, rkev1.ETCDSnapshotPhaseFailed, rkev1.ETCDSnapshotPhaseCancelled, rkev1.ETCDSnapshotPhasePreviewed:
+		return true
+	}
+	return false
+}
+
+// summarize returns the summary of the snapshot, along with the reason it can not be restored if it is not restorable.
+func summarize(snapshot *rkev1.ETCDSnapshot) EtcdSnapshotSummary {
+	summary := EtcdSnapshotSummary{
+		Name:       snapshot.Name,
+		FileName:   snapshot.SnapshotFile.Name,
+		NodeName:   snapshot.SnapshotFile.NodeName,
+		Storage:    "local",
+		Size:       snapshot.SnapshotFile.Size,
+		Restorable: snapshotclient.Successful(snapshot),
+		Message:    snapshot.SnapshotFile.Message,
+	}
+	if snapshot.SnapshotFile.S3 != nil {
+		summary.Storage = "s3"
+	}
+	if snapshot.SnapshotFile.CreatedAt != nil {
+		summary.CreatedAt = snapshot.SnapshotFile.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	switch {
+	case snapshot.Status.Missing:
+		summary.Message = "the snapshot file is missing"
+	case !summary.Restorable && summary.Message == "":
+		summary.Message = "the snapshot was not taken successfully"
+	case snapshot.Status.Verification != nil && snapshot.Status.Verification.Result == rkev1.ETCDSnapshotChecksumMismatch:
+		summary.Restorable, summary.Message = false, "the snapshot failed verification: "+snapshot.Status.Verification.Message
+	case snapshot.Status.Checksum != nil && snapshot.Status.Checksum.Result == rkev1.ETCDSnapshotChecksumMismatch:
+		summary.Restorable, summary.Message = false, "the snapshot object does not match the snapshot file: "+snapshot.Status.Checksum.Message
+	}
+	return summary
+}
+
+func userName(req *http.Request) string {
+	if user, ok := request.UserFrom(req.Context()); ok {
+		return user.GetName()
+	}
+	return ""
+}
+
+func writeJSON(apiRequest *types.APIRequest, output interface{}) {
+	apiRequest.Response.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(apiRequest.Response).Encode(output); err != nil {
+		apiRequest.WriteError(err)
+	}
+}
@@ -0,0 +1,178 @@
+package etcdsnapshot
+
+import (
+	"testing"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newCluster(create, restore int) *rancherv1.Cluster {
+	return &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"},
+		Spec: rancherv1.ClusterSpec{
+			RKEConfig: &rancherv1.RKEConfig{
+				ETCDSnapshotCreate:  &rkev1.ETCDSnapshotCreate{Generation: create},
+				ETCDSnapshotRestore: &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: restore},
+			},
+		},
+	}
+}
+
+func newSnapshot(clusterName string) *rkev1.ETCDSnapshot {
+	return &rkev1.ETCDSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "snapshot",
+			Labels: map[string]string{capr.ClusterNameLabel: clusterName},
+		},
+		SnapshotFile: rkev1.ETCDSnapshotFile{
+			Name:      "etcd-snapshot-1",
+			CreatedAt: &metav1.Time{Time: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)},
+		},
+	}
+}
+
+func TestCreateOperation(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{
+		Status: rkev1.RKEControlPlaneStatus{
+			ETCDSnapshotCreate: &rkev1.ETCDSnapshotCreate{
+				Generation: 2,
+				Machines:   []rkev1.ETCDSnapshotMachineProgress{{MachineName: "m1", Phase: rkev1.ETCDSnapshotPhaseFinished}},
+			},
+			ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseFinished,
+		},
+	}
+
+	assert.Nil(t, createOperation(newCluster(0, 0).Spec.RKEConfig, controlPlane))
+	assert.Equal(t, &EtcdSnapshotOperation{
+		Operation:  operationCreate,
+		Generation: 2,
+		Phase:      "Finished",
+		Done:       true,
+		Machines:   []EtcdSnapshotMachineProgress{{MachineName: "m1", Phase: "Finished"}},
+	}, createOperation(newCluster(2, 0).Spec.RKEConfig, controlPlane))
+	assert.Equal(t, &EtcdSnapshotOperation{
+		Operation:  operationCreate,
+		Generation: 3,
+	}, createOperation(newCluster(3, 0).Spec.RKEConfig, controlPlane), "a request that was not picked up yet is not done")
+}
+
+func TestValidateCreate(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{
+		Status: rkev1.RKEControlPlaneStatus{
+			ETCDSnapshotCreate:       &rkev1.ETCDSnapshotCreate{Generation: 1},
+			ETCDSnapshotCreatePhase:  rkev1.ETCDSnapshotPhaseFinished,
+			ETCDSnapshotRestore:      &rkev1.ETCDSnapshotRestore{Generation: 1},
+			ETCDSnapshotRestorePhase: rkev1.ETCDSnapshotPhaseRestore,
+		},
+	}
+
+	assert.NoError(t, validateCreate(newCluster(1, 0), controlPlane, &CreateEtcdSnapshotInput{MaxConcurrency: 1}))
+	assert.ErrorContains(t, validateCreate(newCluster(1, 0), controlPlane, &CreateEtcdSnapshotInput{MaxConcurrency: -1}), "maxConcurrency must not be negative")
+	assert.ErrorContains(t, validateCreate(newCluster(2, 0), controlPlane, &CreateEtcdSnapshotInput{}), "etcd snapshot create 2 of the cluster is still in progress")
+	assert.ErrorContains(t, validateCreate(newCluster(1, 1), controlPlane, &CreateEtcdSnapshotInput{}), "etcd snapshot restore 1 of the cluster is still in progress")
+}
+
+func TestValidateRestore(t *testing.T) {
+	failed := newSnapshot("test")
+	failed.SnapshotFile.Status = "failed"
+	failed.SnapshotFile.Message = "etcdserver: request timed out"
+	corrupt := newSnapshot("test")
+	corrupt.Status.Verification = &rkev1.ETCDSnapshotVerification{
+		Result:  rkev1.ETCDSnapshotChecksumMismatch,
+		Message: "the digest of the snapshot does not match its database",
+	}
+
+	tests := []struct {
+		name        string
+		snapshot    *rkev1.ETCDSnapshot
+		input       *RestoreEtcdSnapshotInput
+		expectedErr string
+	}{
+		{
+			name:     "restorable snapshot",
+			snapshot: newSnapshot("test"),
+			input:    &RestoreEtcdSnapshotInput{Name: "snapshot", RestoreRKEConfig: "kubernetesVersion"},
+		},
+		{
+			name:        "missing name",
+			input:       &RestoreEtcdSnapshotInput{},
+			expectedErr: "the name of the etcd snapshot to restore is required",
+		},
+		{
+			name:        "invalid restore RKE config",
+			snapshot:    newSnapshot("test"),
+			input:       &RestoreEtcdSnapshotInput{Name: "snapshot", RestoreRKEConfig: "everything"},
+			expectedErr: "invalid restoreRKEConfig everything, must be none, kubernetesVersion or all",
+		},
+		{
+			name:        "snapshot not found",
+			input:       &RestoreEtcdSnapshotInput{Name: "snapshot"},
+			expectedErr: "etcd snapshot snapshot of cluster test not found",
+		},
+		{
+			name:        "snapshot of another cluster",
+			snapshot:    newSnapshot("other"),
+			input:       &RestoreEtcdSnapshotInput{Name: "snapshot"},
+			expectedErr: "etcd snapshot snapshot of cluster test not found",
+		},
+		{
+			name:        "failed snapshot",
+			snapshot:    failed,
+			input:       &RestoreEtcdSnapshotInput{Name: "snapshot"},
+			expectedErr: "etcd snapshot snapshot can not be restored: etcdserver: request timed out",
+		},
+		{
+			name:        "corrupt snapshot",
+			snapshot:    corrupt,
+			input:       &RestoreEtcdSnapshotInput{Name: "snapshot"},
+			expectedErr: "etcd snapshot snapshot can not be restored: the snapshot failed verification: the digest of the snapshot does not match its database",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRestore(newCluster(0, 0), nil, tt.snapshot, tt.input)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidateEncryptionKeySecret(t *testing.T) {
+	secret := func(clusterName string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "etcd-key", Labels: map[string]string{capr.ClusterNameLabel: clusterName}}}
+	}
+	assert.NoError(t, validateEncryptionKeySecret("test", "etcd-key", secret("test")))
+	assert.ErrorContains(t, validateEncryptionKeySecret("test", "etcd-key", secret("other")), "etcd snapshot encryption key secret etcd-key of cluster test not found, it must be labeled rke.cattle.io/cluster-name=test")
+	assert.ErrorContains(t, validateEncryptionKeySecret("test", "etcd-key", &corev1.Secret{}), "etcd snapshot encryption key secret etcd-key of cluster test not found")
+	assert.ErrorContains(t, validateEncryptionKeySecret("test", "etcd-key", nil), "etcd snapshot encryption key secret etcd-key of cluster test not found")
+}
+
+func TestSummarize(t *testing.T) {
+	snapshot := newSnapshot("test")
+	snapshot.SnapshotFile.S3 = &rkev1.ETCDSnapshotS3{Bucket: "snapshots"}
+	snapshot.SnapshotFile.Size = 1024
+
+	assert.Equal(t, EtcdSnapshotSummary{
+		Name:       "snapshot",
+		FileName:   "etcd-snapshot-1",
+		Storage:    "s3",
+		CreatedAt:  "2023-05-01T12:00:00Z",
+		Size:       1024,
+		Restorable: true,
+	}, summarize(snapshot))
+
+	snapshot.Status.Missing = true
+	summary := summarize(snapshot)
+	assert.False(t, summary.Restorable)
+	assert.Equal(t, "the snapshot file is missing", summary.Message)
+}
@@ -0,0 +1,177 @@
+// Package migration exposes the export and import of the CAPR state of provisioned clusters, so that the clusters can
+// be moved to another Rancher server without re-provisioning them.
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/lasso/pkg/client"
+	capimigration "github.com/rancher/rancher/pkg/capr/migration"
+	"github.com/rancher/rancher/pkg/wrangler"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	exportLinkName   = "export"
+	importActionName = "import"
+	namespaceParam   = "namespace"
+	maxBundleBytes   = 64 << 20
+)
+
+type exportHandler struct {
+	exporter *capimigration.Exporter
+}
+
+type importHandler struct {
+	importer *capimigration.Importer
+	clients  client.SharedClientFactory
+}
+
+func Register(server *steve.Server, clients *wrangler.Context) {
+	e := &exportHandler{
+		exporter: capimigration.NewExporter(clients),
+	}
+	i := &importHandler{
+		importer: capimigration.NewImporter(clients),
+		clients:  clients.ControllerFactory.SharedCacheFactory().SharedClientFactory(),
+	}
+
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "provisioning.cattle.io",
+		Kind:  "Cluster",
+		Customize: func(schema *types.APISchema) {
+			if schema.LinkHandlers == nil {
+				schema.LinkHandlers = map[string]http.Handler{}
+			}
+			schema.LinkHandlers[exportLinkName] = e
+			if schema.ActionHandlers == nil {
+				schema.ActionHandlers = map[string]http.Handler{}
+			}
+			schema.ActionHandlers[importActionName] = i
+			if schema.CollectionActions == nil {
+				schema.CollectionActions = map[string]schemas.Action{}
+			}
+			schema.CollectionActions[importActionName] = schemas.Action{}
+			formatter := schema.Formatter
+			schema.Formatter = func(request *types.APIRequest, resource *types.RawResource) {
+				if formatter != nil {
+					formatter(request, resource)
+				}
+				if canExport(request, resource.APIObject.Namespace()) != nil ||
+					resource.APIObject.Data().Map("spec", "rkeConfig") == nil {
+					delete(resource.Links, exportLinkName)
+				}
+			}
+		},
+	})
+}
+
+// canExport checks that the user can update the clusters and read the secrets of the namespace, as the bundle of a
+// cluster holds the tokens that nodes join the cluster with.
+func canExport(apiRequest *types.APIRequest, namespace string) error {
+	if err := apiRequest.AccessControl.CanUpdate(apiRequest, types.APIObject{}, apiRequest.Schema); err != nil {
+		return err
+	}
+	return apiRequest.AccessControl.CanDo(apiRequest, "secrets", "get", namespace, "")
+}
+
+func (e *exportHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := canExport(apiRequest, apiRequest.Namespace); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	if req.Method != http.MethodGet {
+		apiRequest.WriteError(apierror.NewAPIError(validation.MethodNotAllowed, "only GET is allowed for the export of clusters"))
+		return
+	}
+	user, ok := request.UserFrom(req.Context())
+	if !ok {
+		apiRequest.WriteError(validation.Unauthorized)
+		return
+	}
+
+	logrus.Infof("[migration] user %s requested the export of cluster %s/%s", user.GetName(), apiRequest.Namespace, apiRequest.Name)
+	bundle, err := e.exporter.Export(apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		logrus.Infof("[migration] export of cluster %s/%s requested by user %s failed: %v", apiRequest.Namespace, apiRequest.Name, user.GetName(), err)
+		apiRequest.WriteError(err)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", apiRequest.Name))
+	if err := json.NewEncoder(rw).Encode(bundle); err != nil {
+		logrus.Errorf("[migration] failed to write the bundle of cluster %s/%s: %v", apiRequest.Namespace, apiRequest.Name, err)
+	}
+}
+
+func (i *importHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	user, ok := request.UserFrom(req.Context())
+	if !ok {
+		apiRequest.WriteError(validation.Unauthorized)
+		return
+	}
+
+	bundle := &capimigration.Bundle{}
+	if err := json.NewDecoder(http.MaxBytesReader(rw, req.Body, maxBundleBytes)).Decode(bundle); err != nil {
+		apiRequest.WriteError(apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("failed to parse the bundle: %v", err)))
+		return
+	}
+	if err := bundle.Validate(); err != nil {
+		apiRequest.WriteError(apierror.NewAPIError(validation.InvalidBodyContent, err.Error()))
+		return
+	}
+	namespace := req.URL.Query().Get(namespaceParam)
+	if namespace == "" {
+		namespace = bundle.Namespace
+	}
+	if err := i.canImport(apiRequest, bundle, namespace); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	logrus.Infof("[migration] user %s requested the import of cluster %s/%s", user.GetName(), namespace, bundle.Cluster)
+	if err := i.importer.Import(req.Context(), user, bundle, namespace); err != nil {
+		logrus.Infof("[migration] import of cluster %s/%s requested by user %s failed: %v", namespace, bundle.Cluster, user.GetName(), err)
+		apiRequest.WriteError(err)
+		return
+	}
+	rw.WriteHeader(http.StatusCreated)
+}
+
+// canImport checks that the user can create and update objects of every kind of the bundle in the namespace. The
+// importer impersonates the user, so this only rejects bundles the user could not import early, before any object of
+// the bundle is created.
+func (i *importHandler) canImport(apiRequest *types.APIRequest, bundle *capimigration.Bundle, namespace string) error {
+	checked := map[string]bool{}
+	for _, obj := range bundle.Objects {
+		gvr, _, err := i.clients.ResourceForGVK(obj.GroupVersionKind())
+		if err != nil {
+			return apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("unknown kind %s of %s: %v", obj.GetKind(), obj.GetName(), err))
+		}
+		resource := gvr.Resource
+		if gvr.Group != "" {
+			resource = gvr.Group + "/" + gvr.Resource
+		}
+		if checked[resource] {
+			continue
+		}
+		checked[resource] = true
+		for _, verb := range []string{"create", "update"} {
+			if err := apiRequest.AccessControl.CanDo(apiRequest, resource, verb, namespace, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
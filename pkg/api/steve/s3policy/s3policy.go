@@ -0,0 +1,106 @@
+// Package s3policy serves the least privilege AWS policies for the etcd snapshot S3 bucket of provisioned clusters, so
+// that admins can create dedicated credentials for etcd snapshots instead of using keys with full access to S3.
+package s3policy
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/capr/planner"
+	"github.com/rancher/rancher/pkg/capr/s3client"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+const (
+	linkName        = "etcdSnapshotS3Policy"
+	principalParam  = "principal"
+	clusterSchemaID = "provisioning.cattle.io.clusters"
+)
+
+type handler struct {
+	controlPlanes rkecontrollers.RKEControlPlaneCache
+	secrets       corecontrollers.SecretCache
+}
+
+func Register(server *steve.Server, clients *wrangler.Context) {
+	h := &handler{
+		controlPlanes: clients.RKE.RKEControlPlane().Cache(),
+		secrets:       clients.Core.Secret().Cache(),
+	}
+
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "provisioning.cattle.io",
+		Kind:  "Cluster",
+		Customize: func(schema *types.APISchema) {
+			if schema.LinkHandlers == nil {
+				schema.LinkHandlers = map[string]http.Handler{}
+			}
+			schema.LinkHandlers[linkName] = h
+			formatter := schema.Formatter
+			schema.Formatter = func(request *types.APIRequest, resource *types.RawResource) {
+				if formatter != nil {
+					formatter(request, resource)
+				}
+				if resource.APIObject.Data().Map("spec", "rkeConfig", "etcd", "s3") == nil {
+					delete(resource.Links, linkName)
+				}
+			}
+		},
+	})
+}
+
+// ServeHTTP writes the policies for the etcd snapshot S3 bucket of the cluster. The optional principal query parameter
+// is the ARN of the user or role that the bucket policy grants access to.
+func (h *handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	// the policies reveal the S3 configuration of the cluster, so they are only served to users that can get it
+	if err := apiRequest.AccessControl.CanDo(apiRequest, clusterSchemaID, "get", apiRequest.Namespace, apiRequest.Name); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	if req.Method != http.MethodGet {
+		apiRequest.WriteError(apierror.NewAPIError(validation.MethodNotAllowed, "only GET is allowed for the etcd snapshot S3 policy"))
+		return
+	}
+
+	policies, err := h.policies(apiRequest.Namespace, apiRequest.Name, req.URL.Query().Get(principalParam))
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(policies); err != nil {
+		apiRequest.WriteError(err)
+	}
+}
+
+// policies returns the policies for the etcd snapshot S3 bucket of the cluster, with the bucket, folder and region
+// defaulted from the cloud credential of the S3 configuration.
+func (h *handler) policies(namespace, name, principal string) (s3client.Policies, error) {
+	// The control plane has the same name as the cluster.
+	controlPlane, err := h.controlPlanes.Get(namespace, name)
+	if err != nil {
+		return s3client.Policies{}, err
+	}
+	if controlPlane.Spec.ETCD == nil || controlPlane.Spec.ETCD.S3 == nil {
+		return s3client.Policies{}, apierror.NewAPIError(validation.InvalidAction, "etcd snapshots of the cluster are not stored in S3")
+	}
+
+	config, err := planner.GetS3Config(h.secrets, controlPlane.Spec.ETCD.S3, controlPlane)
+	if err != nil {
+		return s3client.Policies{}, err
+	}
+	policies, err := s3client.GeneratePolicies(config, principal)
+	if err != nil {
+		return s3client.Policies{}, apierror.NewAPIError(validation.InvalidAction, err.Error())
+	}
+	return policies, nil
+}
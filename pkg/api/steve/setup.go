@@ -3,12 +3,17 @@
 import (
 	"context"
 
+	"github.com/rancher/rancher/pkg/api/steve/approval"
 	"github.com/rancher/rancher/pkg/api/steve/catalog"
 	"github.com/rancher/rancher/pkg/api/steve/clusters"
 	"github.com/rancher/rancher/pkg/api/steve/disallow"
+	"github.com/rancher/rancher/pkg/api/steve/etcdsnapshot"
 	"github.com/rancher/rancher/pkg/api/steve/machine"
+	"github.com/rancher/rancher/pkg/api/steve/migration"
 	"github.com/rancher/rancher/pkg/api/steve/navlinks"
+	"github.com/rancher/rancher/pkg/api/steve/s3policy"
 	"github.com/rancher/rancher/pkg/api/steve/settings"
+	"github.com/rancher/rancher/pkg/api/steve/supervisor"
 	"github.com/rancher/rancher/pkg/api/steve/userpreferences"
 	"github.com/rancher/rancher/pkg/wrangler"
 	steve "github.com/rancher/steve/pkg/server"
@@ -20,6 +25,11 @@ func Setup(ctx context.Context, server *steve.Server, config *wrangler.Context)
 		return err
 	}
 	machine.Register(server, config)
+	supervisor.Register(server, config)
+	s3policy.Register(server, config)
+	migration.Register(server, config)
+	approval.Register(server, config)
+	etcdsnapshot.Register(server, config)
 	navlinks.Register(ctx, server)
 	settings.Register(server)
 	disallow.Register(server)
@@ -0,0 +1,257 @@
+// Package supervisor passes selected calls to the supervisor API of RKE2 and K3s through to the init node of
+// provisioned clusters, so that operations the supervisor already exposes do not require SSH access to the nodes.
+package supervisor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/capr"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	provcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	linkName           = "supervisor"
+	operationParam     = "operation"
+	runtimePlaceholder = "{runtime}"
+	maxResponseBytes   = 1 << 20
+	requestTimeout     = 30 * time.Second
+	clusterSchemaID    = "provisioning.cattle.io.clusters"
+)
+
+// operation is a supervisor API call that can be passed through.
+type operation struct {
+	method string
+	path   string
+	body   string
+}
+
+// operations are the supervisor API calls that can be passed through, keyed by the name of the operation. Only
+// read-only calls are allowed. Listing etcd snapshots is a POST as the supervisor handles all snapshot operations on
+// the same path. The certificate status is not included, as the supervisor does not expose it: the certificate check
+// of RKE2 and K3s reads the certificate files on the node itself.
+var operations = map[string]operation{
+	"readyz":         {method: http.MethodGet, path: "/v1-" + runtimePlaceholder + "/readyz"},
+	"encrypt-status": {method: http.MethodGet, path: "/v1-" + runtimePlaceholder + "/encrypt/status"},
+	"etcd-info":      {method: http.MethodGet, path: "/db/info"},
+	"etcd-snapshots": {method: http.MethodPost, path: "/db/snapshot", body: `{"operation":"list"}`},
+	"cacerts":        {method: http.MethodGet, path: "/cacerts"},
+}
+
+type dialerFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+type passthrough struct {
+	clusters      provcontrollers.ClusterCache
+	controlPlanes rkecontrollers.RKEControlPlaneCache
+	mgmtClusters  mgmtcontrollers.ClusterCache
+	secrets       corecontrollers.SecretCache
+	clusterDialer func(clusterID string) dialerFunc
+}
+
+func Register(server *steve.Server, clients *wrangler.Context) {
+	p := &passthrough{
+		clusters:      clients.Provisioning.Cluster().Cache(),
+		controlPlanes: clients.RKE.RKEControlPlane().Cache(),
+		mgmtClusters:  clients.Mgmt.Cluster().Cache(),
+		secrets:       clients.Core.Secret().Cache(),
+		clusterDialer: func(clusterID string) dialerFunc {
+			return clients.MultiClusterManager.ClusterDialer(clusterID)
+		},
+	}
+
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "provisioning.cattle.io",
+		Kind:  "Cluster",
+		Customize: func(schema *types.APISchema) {
+			if schema.LinkHandlers == nil {
+				schema.LinkHandlers = map[string]http.Handler{}
+			}
+			schema.LinkHandlers[linkName] = p
+			formatter := schema.Formatter
+			schema.Formatter = func(request *types.APIRequest, resource *types.RawResource) {
+				if formatter != nil {
+					formatter(request, resource)
+				}
+				if err := canUpdate(request, resource.APIObject.Namespace(), resource.APIObject.Name()); err != nil ||
+					resource.APIObject.Data().Map("spec", "rkeConfig") == nil {
+					delete(resource.Links, linkName)
+				}
+			}
+		},
+	})
+}
+
+func (p *passthrough) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := canUpdate(apiRequest, apiRequest.Namespace, apiRequest.Name); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	if req.Method != http.MethodGet {
+		apiRequest.WriteError(apierror.NewAPIError(validation.MethodNotAllowed, "only GET is allowed for supervisor operations"))
+		return
+	}
+	user, ok := request.UserFrom(req.Context())
+	if !ok {
+		apiRequest.WriteError(validation.Unauthorized)
+		return
+	}
+
+	operation := req.URL.Query().Get(operationParam)
+	logrus.Infof("[supervisor] user %s requested supervisor operation %s of cluster %s/%s", user.GetName(), operation, apiRequest.Namespace, apiRequest.Name)
+	if err := p.proxy(apiRequest, operation); err != nil {
+		logrus.Infof("[supervisor] supervisor operation %s of cluster %s/%s requested by user %s failed: %v", operation, apiRequest.Namespace, apiRequest.Name, user.GetName(), err)
+		apiRequest.WriteError(err)
+	}
+}
+
+// proxy passes the supervisor API call of the operation through to the init node of the cluster and writes its response.
+func (p *passthrough) proxy(apiRequest *types.APIRequest, operation string) error {
+	cluster, err := p.clusters.Get(apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		return err
+	}
+	if cluster.Spec.RKEConfig == nil || cluster.Status.ClusterName == "" {
+		return apierror.NewAPIError(validation.InvalidAction, "supervisor operations are only supported for provisioned RKE2 and K3s clusters")
+	}
+
+	// The control plane has the same name as the cluster.
+	controlPlane, err := p.controlPlanes.Get(cluster.Namespace, cluster.Name)
+	if err != nil {
+		return err
+	}
+	op, ok := lookupOperation(operation, capr.GetRuntime(controlPlane.Spec.KubernetesVersion))
+	if !ok {
+		return apierror.NewAPIError(validation.InvalidOption, fmt.Sprintf("unsupported supervisor operation %q", operation))
+	}
+
+	joinURL, err := p.initNodeJoinURL(cluster.Namespace, cluster.Name)
+	if err != nil {
+		return err
+	}
+	token, err := p.serverToken(controlPlane.Namespace, controlPlane.Name)
+	if err != nil {
+		return err
+	}
+	client, err := p.client(cluster.Status.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(apiRequest.Context(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, op.method, strings.TrimSuffix(joinURL, "/")+op.path, strings.NewReader(op.body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("server", token)
+	if op.body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call the supervisor API of cluster %s/%s: %w", cluster.Namespace, cluster.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		apiRequest.Response.Header().Set("Content-Type", contentType)
+	}
+	apiRequest.Response.WriteHeader(resp.StatusCode)
+	_, err = io.Copy(apiRequest.Response, io.LimitReader(resp.Body, maxResponseBytes))
+	return err
+}
+
+// lookupOperation returns the supervisor API call of the operation for the runtime.
+func lookupOperation(name, runtime string) (operation, bool) {
+	op, ok := operations[name]
+	if !ok {
+		return operation{}, false
+	}
+	op.path = strings.ReplaceAll(op.path, runtimePlaceholder, runtime)
+	return op, true
+}
+
+// canUpdate checks that the user may update the cluster, as the supervisor API is called with the server token of the
+// cluster.
+func canUpdate(apiRequest *types.APIRequest, namespace, name string) error {
+	return apiRequest.AccessControl.CanDo(apiRequest, clusterSchemaID, "update", namespace, name)
+}
+
+// initNodeJoinURL returns the supervisor URL of the init node of the cluster.
+func (p *passthrough) initNodeJoinURL(namespace, clusterName string) (string, error) {
+	secrets, err := p.secrets.List(namespace, labels.SelectorFromSet(map[string]string{
+		capr.ClusterNameLabel: clusterName,
+		capr.InitNodeLabel:    "true",
+	}))
+	if err != nil {
+		return "", err
+	}
+	for _, secret := range secrets {
+		if secret.Type == capr.SecretTypeMachinePlan && secret.Annotations[capr.JoinURLAnnotation] != "" {
+			return secret.Annotations[capr.JoinURLAnnotation], nil
+		}
+	}
+	return "", apierror.NewAPIError(validation.NotFound, fmt.Sprintf("the supervisor URL of the init node of cluster %s/%s is not known yet", namespace, clusterName))
+}
+
+// serverToken returns the token that servers of the cluster use to join it, which authorizes calls to the supervisor API.
+func (p *passthrough) serverToken(namespace, controlPlaneName string) (string, error) {
+	secret, err := p.secrets.Get(namespace, name.SafeConcatName(controlPlaneName, "rke", "state"))
+	if err != nil {
+		return "", err
+	}
+	if secret.Type != capr.SecretTypeClusterState || len(secret.Data["serverToken"]) == 0 {
+		return "", fmt.Errorf("secret %s/%s does not contain the server token of the cluster", secret.Namespace, secret.Name)
+	}
+	return string(secret.Data["serverToken"]), nil
+}
+
+// client returns an HTTP client that connects to the downstream cluster through its agent. The supervisor serves a
+// certificate signed by the server CA of the cluster, which is the CA of the Kubernetes API of the cluster.
+func (p *passthrough) client(clusterID string) (*http.Client, error) {
+	cluster, err := p.mgmtClusters.Get(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := base64.StdEncoding.DecodeString(cluster.Status.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA certificate of cluster %s: %w", clusterID, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("the CA certificate of cluster %s is not known yet", clusterID)
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: p.clusterDialer(clusterID),
+			TLSClientConfig: &tls.Config{
+				RootCAs: pool,
+			},
+		},
+		Timeout: requestTimeout,
+	}, nil
+}
@@ -0,0 +1,31 @@
+package supervisor
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupOperation(t *testing.T) {
+	tests := []struct {
+		operation string
+		runtime   string
+		expected  operation
+		ok        bool
+	}{
+		{operation: "encrypt-status", runtime: "rke2", expected: operation{method: http.MethodGet, path: "/v1-rke2/encrypt/status"}, ok: true},
+		{operation: "readyz", runtime: "k3s", expected: operation{method: http.MethodGet, path: "/v1-k3s/readyz"}, ok: true},
+		{operation: "etcd-info", runtime: "rke2", expected: operation{method: http.MethodGet, path: "/db/info"}, ok: true},
+		{operation: "etcd-snapshots", runtime: "k3s", expected: operation{method: http.MethodPost, path: "/db/snapshot", body: `{"operation":"list"}`}, ok: true},
+		{operation: "token", runtime: "rke2"},
+		{operation: "", runtime: "rke2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			op, ok := lookupOperation(tt.operation, tt.runtime)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, op)
+		})
+	}
+}
@@ -26,18 +26,21 @@ func init() {
 type ClusterConditionType string
 
 const (
-	ClusterActionGenerateKubeconfig    = "generateKubeconfig"
-	ClusterActionImportYaml            = "importYaml"
-	ClusterActionExportYaml            = "exportYaml"
-	ClusterActionViewMonitoring        = "viewMonitoring"
-	ClusterActionEditMonitoring        = "editMonitoring"
-	ClusterActionEnableMonitoring      = "enableMonitoring"
-	ClusterActionDisableMonitoring     = "disableMonitoring"
-	ClusterActionBackupEtcd            = "backupEtcd"
-	ClusterActionRestoreFromEtcdBackup = "restoreFromEtcdBackup"
-	ClusterActionRotateCertificates    = "rotateCertificates"
-	ClusterActionRotateEncryptionKey   = "rotateEncryptionKey"
-	ClusterActionSaveAsTemplate        = "saveAsTemplate"
+	ClusterActionGenerateKubeconfig       = "generateKubeconfig"
+	ClusterActionGenerateKubeconfigBundle = "generateKubeconfigBundle"
+	ClusterActionImportYaml               = "importYaml"
+	ClusterActionExportYaml               = "exportYaml"
+	ClusterActionViewMonitoring           = "viewMonitoring"
+	ClusterActionEditMonitoring           = "editMonitoring"
+	ClusterActionEnableMonitoring         = "enableMonitoring"
+	ClusterActionDisableMonitoring        = "disableMonitoring"
+	ClusterActionBackupEtcd               = "backupEtcd"
+	ClusterActionRestoreFromEtcdBackup    = "restoreFromEtcdBackup"
+	ClusterActionRotateCertificates       = "rotateCertificates"
+	ClusterActionRotateEncryptionKey      = "rotateEncryptionKey"
+	ClusterActionSaveAsTemplate           = "saveAsTemplate"
+
+	ClusterActionRefreshServiceAccountToken = "refreshServiceAccountToken"
 
 	// ClusterConditionReady Cluster ready to serve API (healthy when true, unhealthy when false)
 	ClusterConditionReady          condition.Cond = "Ready"
@@ -76,6 +79,17 @@ func init() {
 	ClusterConditionHarvesterCloudProviderConfigMigrated condition.Cond = "HarvesterCloudProviderConfigMigrated"
 	ClusterConditionACISecretsMigrated                   condition.Cond = "ACISecretsMigrated"
 	ClusterConditionRKESecretsMigrated                   condition.Cond = "RKESecretsMigrated"
+	// ClusterConditionHostedOperatorDeployed false when the hosted provider operator charts could not be installed
+	ClusterConditionHostedOperatorDeployed condition.Cond = "HostedOperatorDeployed"
+	// ClusterConditionServiceAccountTokenRefreshed unknown while a requested refresh of the service account token of a
+	// hosted cluster is pending, false when it failed
+	ClusterConditionServiceAccountTokenRefreshed condition.Cond = "ServiceAccountTokenRefreshed"
+	// ClusterConditionHostedQuotaSufficient false when the account of a hosted cluster is predicted to lack the quota for a
+	// change to its node pools, which is held back until the quota suffices
+	ClusterConditionHostedQuotaSufficient condition.Cond = "HostedQuotaSufficient"
+	// ClusterConditionHostedPolicyCompliant false when the config of a hosted cluster violates a hosted cluster policy,
+	// in which case the change is not passed to its operator
+	ClusterConditionHostedPolicyCompliant condition.Cond = "HostedPolicyCompliant"
 
 	ClusterDriverImported = "imported"
 	ClusterDriverLocal    = "local"
@@ -125,6 +139,20 @@ type ClusterSpecBase struct {
 	WindowsPreferedCluster                               bool                                    `json:"windowsPreferedCluster" norman:"noupdate"`
 	LocalClusterAuthEndpoint                             LocalClusterAuthEndpoint                `json:"localClusterAuthEndpoint,omitempty"`
 	ClusterSecrets                                       ClusterSecrets                          `json:"clusterSecrets" norman:"nocreate,noupdate"`
+	ClusterAgentDeploymentCustomization                  *AgentDeploymentCustomization           `json:"clusterAgentDeploymentCustomization,omitempty"`
+	FleetAgentDeploymentCustomization                    *AgentDeploymentCustomization           `json:"fleetAgentDeploymentCustomization,omitempty"`
+}
+
+// AgentDeploymentCustomization customizes the deployment of an agent that Rancher runs in the downstream cluster, such
+// as the cattle-cluster-agent or the fleet-agent.
+type AgentDeploymentCustomization struct {
+	// AppendTolerations are added to the default tolerations of the agent.
+	AppendTolerations []v1.Toleration `json:"appendTolerations,omitempty"`
+	// OverrideAffinity replaces the default affinity of the agent. Node affinity can be used to select the nodes the
+	// agent may run on.
+	OverrideAffinity *v1.Affinity `json:"overrideAffinity,omitempty"`
+	// OverrideResourceRequirements sets the resource requests and limits of the agent container.
+	OverrideResourceRequirements *v1.ResourceRequirements `json:"overrideResourceRequirements,omitempty"`
 }
 
 type ClusterSpec struct {
@@ -142,6 +170,9 @@ type ClusterSpec struct {
 	AKSConfig                           *aksv1.AKSClusterConfigSpec `json:"aksConfig,omitempty"`
 	EKSConfig                           *eksv1.EKSClusterConfigSpec `json:"eksConfig,omitempty"`
 	GKEConfig                           *gkev1.GKEClusterConfigSpec `json:"gkeConfig,omitempty"`
+	HostedNodePoolAutoscaling           []NodePoolAutoscaling       `json:"hostedNodePoolAutoscaling,omitempty"`
+	HostedPartition                     *HostedPartition            `json:"hostedPartition,omitempty"`
+	HostedQuotaPreflight                bool                        `json:"hostedQuotaPreflight,omitempty"`
 	ClusterTemplateName                 string                      `json:"clusterTemplateName,omitempty" norman:"type=reference[clusterTemplate],nocreate,noupdate"`
 	ClusterTemplateRevisionName         string                      `json:"clusterTemplateRevisionName,omitempty" norman:"type=reference[clusterTemplateRevision]"`
 	ClusterTemplateAnswers              Answer                      `json:"answers,omitempty"`
@@ -149,6 +180,30 @@ type ClusterSpec struct {
 	FleetWorkspaceName                  string                      `json:"fleetWorkspaceName,omitempty"`
 }
 
+// NodePoolAutoscaling configures the autoscaling of a node pool of an EKS, AKS or GKE cluster the same way for every
+// provider. It takes precedence over the autoscaling settings of the node pool in the config of the provider.
+type NodePoolAutoscaling struct {
+	// NodePool is the name of the EKS node group, AKS node pool or GKE node pool.
+	NodePool string `json:"nodePool"`
+	// Enabled scales the node pool between MinSize and MaxSize nodes. Disabling it turns off the autoscaling of AKS and
+	// GKE node pools, while EKS node groups keep their current size limits.
+	Enabled bool  `json:"enabled"`
+	MinSize int64 `json:"minSize,omitempty"`
+	MaxSize int64 `json:"maxSize,omitempty"`
+}
+
+// HostedPartition places an EKS, AKS or GKE cluster in a sovereign or otherwise isolated partition of its cloud, whose
+// API endpoints differ from those of the public partition. The endpoints of the partitions in use are passed to the
+// operator of the provider if its chart can be configured with them.
+type HostedPartition struct {
+	// Name is the partition, i.e. aws-us-gov or aws-cn for EKS, and AzureUSGovernmentCloud or AzureChinaCloud for AKS.
+	// The partition of an EKS cluster defaults to the partition of its region.
+	Name string `json:"name,omitempty"`
+	// Endpoints overrides the API endpoints of the partition by service. Partitions that are not known to Rancher, such
+	// as the sovereign regions of GKE, must set the endpoints of all services used by the operator.
+	Endpoints map[string]string `json:"endpoints,omitempty"`
+}
+
 type ImportedConfig struct {
 	KubeConfig string `json:"kubeConfig" norman:"type=password"`
 }
@@ -159,46 +214,47 @@ type ClusterStatus struct {
 	Conditions []ClusterCondition `json:"conditions,omitempty"`
 	// Component statuses will represent cluster's components (etcd/controller/scheduler) health
 	// https://kubernetes.io/docs/api-reference/v1.8/#componentstatus-v1-core
-	Driver                               string                    `json:"driver"`
-	Provider                             string                    `json:"provider"`
-	AgentImage                           string                    `json:"agentImage"`
-	AppliedAgentEnvVars                  []v1.EnvVar               `json:"appliedAgentEnvVars,omitempty"`
-	AgentFeatures                        map[string]bool           `json:"agentFeatures,omitempty"`
-	AuthImage                            string                    `json:"authImage"`
-	ComponentStatuses                    []ClusterComponentStatus  `json:"componentStatuses,omitempty"`
-	APIEndpoint                          string                    `json:"apiEndpoint,omitempty"`
-	ServiceAccountToken                  string                    `json:"serviceAccountToken,omitempty"`
-	ServiceAccountTokenSecret            string                    `json:"serviceAccountTokenSecret,omitempty"`
-	CACert                               string                    `json:"caCert,omitempty"`
-	Capacity                             v1.ResourceList           `json:"capacity,omitempty"`
-	Allocatable                          v1.ResourceList           `json:"allocatable,omitempty"`
-	AppliedSpec                          ClusterSpec               `json:"appliedSpec,omitempty"`
-	FailedSpec                           *ClusterSpec              `json:"failedSpec,omitempty"`
-	Requested                            v1.ResourceList           `json:"requested,omitempty"`
-	Limits                               v1.ResourceList           `json:"limits,omitempty"`
-	Version                              *version.Info             `json:"version,omitempty"`
-	AppliedPodSecurityPolicyTemplateName string                    `json:"appliedPodSecurityPolicyTemplateId"`
-	AppliedEnableNetworkPolicy           bool                      `json:"appliedEnableNetworkPolicy" norman:"nocreate,noupdate,default=false"`
-	Capabilities                         Capabilities              `json:"capabilities,omitempty"`
-	MonitoringStatus                     *MonitoringStatus         `json:"monitoringStatus,omitempty" norman:"nocreate,noupdate"`
-	NodeVersion                          int                       `json:"nodeVersion,omitempty"`
-	NodeCount                            int                       `json:"nodeCount,omitempty" norman:"nocreate,noupdate"`
-	LinuxWorkerCount                     int                       `json:"linuxWorkerCount,omitempty" norman:"nocreate,noupdate"`
-	WindowsWorkerCount                   int                       `json:"windowsWorkerCount,omitempty" norman:"nocreate,noupdate"`
-	IstioEnabled                         bool                      `json:"istioEnabled,omitempty" norman:"nocreate,noupdate,default=false"`
-	CertificatesExpiration               map[string]CertExpiration `json:"certificatesExpiration,omitempty"`
-	CurrentCisRunName                    string                    `json:"currentCisRunName,omitempty"`
-	AKSStatus                            AKSStatus                 `json:"aksStatus,omitempty" norman:"nocreate,noupdate"`
-	EKSStatus                            EKSStatus                 `json:"eksStatus,omitempty" norman:"nocreate,noupdate"`
-	GKEStatus                            GKEStatus                 `json:"gkeStatus,omitempty" norman:"nocreate,noupdate"`
-	PrivateRegistrySecret                string                    `json:"privateRegistrySecret,omitempty" norman:"nocreate,noupdate"` // Deprecated: use ClusterSpec.ClusterSecrets.PrivateRegistrySecret instead
-	S3CredentialSecret                   string                    `json:"s3CredentialSecret,omitempty" norman:"nocreate,noupdate"`    // Deprecated: use ClusterSpec.ClusterSecrets.S3CredentialSecret instead
-	WeavePasswordSecret                  string                    `json:"weavePasswordSecret,omitempty" norman:"nocreate,noupdate"`   // Deprecated: use ClusterSpec.ClusterSecrets.WeavePasswordSecret instead
-	VsphereSecret                        string                    `json:"vsphereSecret,omitempty" norman:"nocreate,noupdate"`         // Deprecated: use ClusterSpec.ClusterSecrets.VsphereSecret instead
-	VirtualCenterSecret                  string                    `json:"virtualCenterSecret,omitempty" norman:"nocreate,noupdate"`   // Deprecated: use ClusterSpec.ClusterSecrets.VirtualCenterSecret instead
-	OpenStackSecret                      string                    `json:"openStackSecret,omitempty" norman:"nocreate,noupdate"`       // Deprecated: use ClusterSpec.ClusterSecrets.OpenStackSecret instead
-	AADClientSecret                      string                    `json:"aadClientSecret,omitempty" norman:"nocreate,noupdate"`       // Deprecated: use ClusterSpec.ClusterSecrets.AADClientSecret instead
-	AADClientCertSecret                  string                    `json:"aadClientCertSecret,omitempty" norman:"nocreate,noupdate"`   // Deprecated: use ClusterSpec.ClusterSecrets.AADClientCertSecret instead
+	Driver                                     string                        `json:"driver"`
+	Provider                                   string                        `json:"provider"`
+	AgentImage                                 string                        `json:"agentImage"`
+	AppliedAgentEnvVars                        []v1.EnvVar                   `json:"appliedAgentEnvVars,omitempty"`
+	AppliedClusterAgentDeploymentCustomization *AgentDeploymentCustomization `json:"appliedClusterAgentDeploymentCustomization,omitempty"`
+	AgentFeatures                              map[string]bool               `json:"agentFeatures,omitempty"`
+	AuthImage                                  string                        `json:"authImage"`
+	ComponentStatuses                          []ClusterComponentStatus      `json:"componentStatuses,omitempty"`
+	APIEndpoint                                string                        `json:"apiEndpoint,omitempty"`
+	ServiceAccountToken                        string                        `json:"serviceAccountToken,omitempty"`
+	ServiceAccountTokenSecret                  string                        `json:"serviceAccountTokenSecret,omitempty"`
+	CACert                                     string                        `json:"caCert,omitempty"`
+	Capacity                                   v1.ResourceList               `json:"capacity,omitempty"`
+	Allocatable                                v1.ResourceList               `json:"allocatable,omitempty"`
+	AppliedSpec                                ClusterSpec                   `json:"appliedSpec,omitempty"`
+	FailedSpec                                 *ClusterSpec                  `json:"failedSpec,omitempty"`
+	Requested                                  v1.ResourceList               `json:"requested,omitempty"`
+	Limits                                     v1.ResourceList               `json:"limits,omitempty"`
+	Version                                    *version.Info                 `json:"version,omitempty"`
+	AppliedPodSecurityPolicyTemplateName       string                        `json:"appliedPodSecurityPolicyTemplateId"`
+	AppliedEnableNetworkPolicy                 bool                          `json:"appliedEnableNetworkPolicy" norman:"nocreate,noupdate,default=false"`
+	Capabilities                               Capabilities                  `json:"capabilities,omitempty"`
+	MonitoringStatus                           *MonitoringStatus             `json:"monitoringStatus,omitempty" norman:"nocreate,noupdate"`
+	NodeVersion                                int                           `json:"nodeVersion,omitempty"`
+	NodeCount                                  int                           `json:"nodeCount,omitempty" norman:"nocreate,noupdate"`
+	LinuxWorkerCount                           int                           `json:"linuxWorkerCount,omitempty" norman:"nocreate,noupdate"`
+	WindowsWorkerCount                         int                           `json:"windowsWorkerCount,omitempty" norman:"nocreate,noupdate"`
+	IstioEnabled                               bool                          `json:"istioEnabled,omitempty" norman:"nocreate,noupdate,default=false"`
+	CertificatesExpiration                     map[string]CertExpiration     `json:"certificatesExpiration,omitempty"`
+	CurrentCisRunName                          string                        `json:"currentCisRunName,omitempty"`
+	AKSStatus                                  AKSStatus                     `json:"aksStatus,omitempty" norman:"nocreate,noupdate"`
+	EKSStatus                                  EKSStatus                     `json:"eksStatus,omitempty" norman:"nocreate,noupdate"`
+	GKEStatus                                  GKEStatus                     `json:"gkeStatus,omitempty" norman:"nocreate,noupdate"`
+	PrivateRegistrySecret                      string                        `json:"privateRegistrySecret,omitempty" norman:"nocreate,noupdate"` // Deprecated: use ClusterSpec.ClusterSecrets.PrivateRegistrySecret instead
+	S3CredentialSecret                         string                        `json:"s3CredentialSecret,omitempty" norman:"nocreate,noupdate"`    // Deprecated: use ClusterSpec.ClusterSecrets.S3CredentialSecret instead
+	WeavePasswordSecret                        string                        `json:"weavePasswordSecret,omitempty" norman:"nocreate,noupdate"`   // Deprecated: use ClusterSpec.ClusterSecrets.WeavePasswordSecret instead
+	VsphereSecret                              string                        `json:"vsphereSecret,omitempty" norman:"nocreate,noupdate"`         // Deprecated: use ClusterSpec.ClusterSecrets.VsphereSecret instead
+	VirtualCenterSecret                        string                        `json:"virtualCenterSecret,omitempty" norman:"nocreate,noupdate"`   // Deprecated: use ClusterSpec.ClusterSecrets.VirtualCenterSecret instead
+	OpenStackSecret                            string                        `json:"openStackSecret,omitempty" norman:"nocreate,noupdate"`       // Deprecated: use ClusterSpec.ClusterSecrets.OpenStackSecret instead
+	AADClientSecret                            string                        `json:"aadClientSecret,omitempty" norman:"nocreate,noupdate"`       // Deprecated: use ClusterSpec.ClusterSecrets.AADClientSecret instead
+	AADClientCertSecret                        string                        `json:"aadClientCertSecret,omitempty" norman:"nocreate,noupdate"`   // Deprecated: use ClusterSpec.ClusterSecrets.AADClientCertSecret instead
 }
 
 type ClusterComponentStatus struct {
@@ -288,6 +344,13 @@ type GenerateKubeConfigOutput struct {
 	Config string `json:"config"`
 }
 
+// GenerateKubeconfigBundleInput is the input of the generateKubeconfigBundle collection action. AuthMode selects whether
+// the kubeconfig embeds a token or uses the rancher CLI as exec credential plugin. If empty, the kubeconfig-generate-token
+// setting decides. Embedding a token is rejected if the setting is disabled.
+type GenerateKubeconfigBundleInput struct {
+	AuthMode string `json:"authMode,omitempty" norman:"type=enum,options=token|exec"`
+}
+
 type ExportOutput struct {
 	YAMLOutput string `json:"yamlOutput"`
 }
@@ -361,6 +424,10 @@ type RotateEncryptionKeyOutput struct {
 	Message string `json:"message,omitempty"`
 }
 
+type RefreshServiceAccountTokenOutput struct {
+	Message string `json:"message,omitempty"`
+}
+
 type LocalClusterAuthEndpoint struct {
 	Enabled bool   `json:"enabled"`
 	FQDN    string `json:"fqdn,omitempty"`
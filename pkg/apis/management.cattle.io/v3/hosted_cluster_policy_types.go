@@ -0,0 +1,36 @@
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// HostedClusterPolicy constrains the configuration of EKS, AKS and GKE clusters provisioned by Rancher. When a hosted
+// cluster is created or updated, its configuration must satisfy every policy that applies to its provider. Changes that
+// violate a policy are not passed to the operator of the cluster, which is reported with its HostedPolicyCompliant
+// condition.
+type HostedClusterPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HostedClusterPolicySpec `json:"spec"`
+}
+
+type HostedClusterPolicySpec struct {
+	// Description explains the policy to users whose cluster configuration violates it.
+	Description string `json:"description,omitempty"`
+	// Providers are the drivers the policy applies to, any of EKS, AKS and GKE. The policy applies to all of them if
+	// empty.
+	Providers []string `json:"providers,omitempty"`
+	// MaxNodeCount limits the number of nodes of a cluster. Autoscaled node pools count with their maximum size.
+	MaxNodeCount *int64 `json:"maxNodeCount,omitempty"`
+	// AllowedInstanceTypes are the EKS instance types, including those of launch templates, AKS VM sizes and GKE machine
+	// types node pools may use. Entries may contain shell glob patterns, i.e. "m5.*". All types are allowed if empty.
+	AllowedInstanceTypes []string `json:"allowedInstanceTypes,omitempty"`
+	// AllowedRegions are the EKS regions, AKS resource locations and GKE regions or zones clusters may be created in.
+	// Entries may contain shell glob patterns, i.e. "us-*". All regions are allowed if empty.
+	AllowedRegions []string `json:"allowedRegions,omitempty"`
+}
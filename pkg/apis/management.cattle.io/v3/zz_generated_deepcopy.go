@@ -363,6 +363,39 @@ func (in *ActiveDirectoryTestAndApplyInput) DeepCopy() *ActiveDirectoryTestAndAp
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentDeploymentCustomization) DeepCopyInto(out *AgentDeploymentCustomization) {
+	*out = *in
+	if in.AppendTolerations != nil {
+		in, out := &in.AppendTolerations, &out.AppendTolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.OverrideAffinity != nil {
+		in, out := &in.OverrideAffinity, &out.OverrideAffinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OverrideResourceRequirements != nil {
+		in, out := &in.OverrideResourceRequirements, &out.OverrideResourceRequirements
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentDeploymentCustomization.
+func (in *AgentDeploymentCustomization) DeepCopy() *AgentDeploymentCustomization {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentDeploymentCustomization)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AlertCommonSpec) DeepCopyInto(out *AlertCommonSpec) {
 	*out = *in
@@ -2138,6 +2171,16 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 		*out = new(gkecattleiov1.GKEClusterConfigSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.HostedNodePoolAutoscaling != nil {
+		in, out := &in.HostedNodePoolAutoscaling, &out.HostedNodePoolAutoscaling
+		*out = make([]NodePoolAutoscaling, len(*in))
+		copy(*out, *in)
+	}
+	if in.HostedPartition != nil {
+		in, out := &in.HostedPartition, &out.HostedPartition
+		*out = new(HostedPartition)
+		(*in).DeepCopyInto(*out)
+	}
 	in.ClusterTemplateAnswers.DeepCopyInto(&out.ClusterTemplateAnswers)
 	if in.ClusterTemplateQuestions != nil {
 		in, out := &in.ClusterTemplateQuestions, &out.ClusterTemplateQuestions
@@ -2181,6 +2224,16 @@ func (in *ClusterSpecBase) DeepCopyInto(out *ClusterSpecBase) {
 	}
 	out.LocalClusterAuthEndpoint = in.LocalClusterAuthEndpoint
 	out.ClusterSecrets = in.ClusterSecrets
+	if in.ClusterAgentDeploymentCustomization != nil {
+		in, out := &in.ClusterAgentDeploymentCustomization, &out.ClusterAgentDeploymentCustomization
+		*out = new(AgentDeploymentCustomization)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FleetAgentDeploymentCustomization != nil {
+		in, out := &in.FleetAgentDeploymentCustomization, &out.FleetAgentDeploymentCustomization
+		*out = new(AgentDeploymentCustomization)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -2209,6 +2262,11 @@ func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AppliedClusterAgentDeploymentCustomization != nil {
+		in, out := &in.AppliedClusterAgentDeploymentCustomization, &out.AppliedClusterAgentDeploymentCustomization
+		*out = new(AgentDeploymentCustomization)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.AgentFeatures != nil {
 		in, out := &in.AgentFeatures, &out.AgentFeatures
 		*out = make(map[string]bool, len(*in))
@@ -3603,6 +3661,22 @@ func (in *GenerateKubeConfigOutput) DeepCopy() *GenerateKubeConfigOutput {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GenerateKubeconfigBundleInput) DeepCopyInto(out *GenerateKubeconfigBundleInput) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenerateKubeconfigBundleInput.
+func (in *GenerateKubeconfigBundleInput) DeepCopy() *GenerateKubeconfigBundleInput {
+	if in == nil {
+		return nil
+	}
+	out := new(GenerateKubeconfigBundleInput)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GenericLogin) DeepCopyInto(out *GenericLogin) {
 	*out = *in
@@ -4449,6 +4523,125 @@ func (in *HTTPClientConfig) DeepCopy() *HTTPClientConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostedClusterPolicy) DeepCopyInto(out *HostedClusterPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostedClusterPolicy.
+func (in *HostedClusterPolicy) DeepCopy() *HostedClusterPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HostedClusterPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostedClusterPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostedClusterPolicyList) DeepCopyInto(out *HostedClusterPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HostedClusterPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostedClusterPolicyList.
+func (in *HostedClusterPolicyList) DeepCopy() *HostedClusterPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(HostedClusterPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostedClusterPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostedClusterPolicySpec) DeepCopyInto(out *HostedClusterPolicySpec) {
+	*out = *in
+	if in.Providers != nil {
+		in, out := &in.Providers, &out.Providers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxNodeCount != nil {
+		in, out := &in.MaxNodeCount, &out.MaxNodeCount
+		*out = new(int64)
+		**out = **in
+	}
+	if in.AllowedInstanceTypes != nil {
+		in, out := &in.AllowedInstanceTypes, &out.AllowedInstanceTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedRegions != nil {
+		in, out := &in.AllowedRegions, &out.AllowedRegions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostedClusterPolicySpec.
+func (in *HostedClusterPolicySpec) DeepCopy() *HostedClusterPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HostedClusterPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostedPartition) DeepCopyInto(out *HostedPartition) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostedPartition.
+func (in *HostedPartition) DeepCopy() *HostedPartition {
+	if in == nil {
+		return nil
+	}
+	out := new(HostedPartition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImportClusterYamlInput) DeepCopyInto(out *ImportClusterYamlInput) {
 	*out = *in
@@ -6065,6 +6258,22 @@ func (in *NodePool) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePoolAutoscaling) DeepCopyInto(out *NodePoolAutoscaling) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolAutoscaling.
+func (in *NodePoolAutoscaling) DeepCopy() *NodePoolAutoscaling {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePoolAutoscaling)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodePoolList) DeepCopyInto(out *NodePoolList) {
 	*out = *in
@@ -8538,6 +8747,22 @@ func (in *Recipient) DeepCopy() *Recipient {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RefreshServiceAccountTokenOutput) DeepCopyInto(out *RefreshServiceAccountTokenOutput) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RefreshServiceAccountTokenOutput.
+func (in *RefreshServiceAccountTokenOutput) DeepCopy() *RefreshServiceAccountTokenOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(RefreshServiceAccountTokenOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceQuotaLimit) DeepCopyInto(out *ResourceQuotaLimit) {
 	*out = *in
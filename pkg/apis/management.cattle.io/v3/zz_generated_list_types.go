@@ -604,6 +604,23 @@ func NewGroupMember(namespace, name string, obj GroupMember) *GroupMember {
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// HostedClusterPolicyList is a list of HostedClusterPolicy resources
+type HostedClusterPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []HostedClusterPolicy `json:"items"`
+}
+
+func NewHostedClusterPolicy(namespace, name string, obj HostedClusterPolicy) *HostedClusterPolicy {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("HostedClusterPolicy").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // KontainerDriverList is a list of KontainerDriver resources
 type KontainerDriverList struct {
 	metav1.TypeMeta `json:",inline"`
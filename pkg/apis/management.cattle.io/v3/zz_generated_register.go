@@ -63,6 +63,7 @@
 	GoogleOAuthProviderResourceName                       = "googleoauthproviders"
 	GroupResourceName                                     = "groups"
 	GroupMemberResourceName                               = "groupmembers"
+	HostedClusterPolicyResourceName                       = "hostedclusterpolicies"
 	KontainerDriverResourceName                           = "kontainerdrivers"
 	LocalProviderResourceName                             = "localproviders"
 	ManagedChartResourceName                              = "managedcharts"
@@ -197,6 +198,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&GroupList{},
 		&GroupMember{},
 		&GroupMemberList{},
+		&HostedClusterPolicy{},
+		&HostedClusterPolicyList{},
 		&KontainerDriver{},
 		&KontainerDriverList{},
 		&LocalProvider{},
@@ -3,6 +3,7 @@
 import (
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/wrangler/pkg/genericcondition"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -31,6 +32,21 @@ type ClusterSpec struct {
 	EnableNetworkPolicy                                  *bool          `json:"enableNetworkPolicy,omitempty" norman:"default=false"`
 
 	RedeploySystemAgentGeneration int64 `json:"redeploySystemAgentGeneration,omitempty"`
+
+	ClusterAgentDeploymentCustomization *AgentDeploymentCustomization `json:"clusterAgentDeploymentCustomization,omitempty"`
+	FleetAgentDeploymentCustomization   *AgentDeploymentCustomization `json:"fleetAgentDeploymentCustomization,omitempty"`
+}
+
+// AgentDeploymentCustomization customizes the deployment of the cattle-cluster-agent or the fleet-agent in the cluster,
+// for example to schedule them on control plane nodes that carry custom taints.
+type AgentDeploymentCustomization struct {
+	// AppendTolerations are added to the default tolerations of the agent.
+	AppendTolerations []corev1.Toleration `json:"appendTolerations,omitempty"`
+	// OverrideAffinity replaces the default affinity of the agent. Node affinity can be used to select the nodes the
+	// agent may run on.
+	OverrideAffinity *corev1.Affinity `json:"overrideAffinity,omitempty"`
+	// OverrideResourceRequirements sets the resource requests and limits of the agent container.
+	OverrideResourceRequirements *corev1.ResourceRequirements `json:"overrideResourceRequirements,omitempty"`
 }
 
 type ClusterStatus struct {
@@ -40,6 +56,35 @@ type ClusterStatus struct {
 	AgentDeployed      bool                                `json:"agentDeployed,omitempty"`
 	ObservedGeneration int64                               `json:"observedGeneration"`
 	Conditions         []genericcondition.GenericCondition `json:"conditions,omitempty"`
+	// ProvisioningMilestones are the milestones that the cluster reached while it was provisioned, in the order they
+	// were reached.
+	ProvisioningMilestones []ProvisioningMilestone `json:"provisioningMilestones,omitempty"`
+	// SanityProbes are the results of the last run of the sanity probes of the cluster.
+	SanityProbes *SanityProbesStatus `json:"sanityProbes,omitempty"`
+}
+
+// SanityProbesStatus records the results of a run of the sanity probes of a cluster.
+type SanityProbesStatus struct {
+	// ObservedGeneration is the generation of the sanity probes that was run.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Checks are the results of the individual probes.
+	Checks []SanityProbeCheck `json:"checks,omitempty"`
+}
+
+type SanityProbeCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// ProvisioningMilestone records when a cluster first reached a milestone of its provisioning.
+type ProvisioningMilestone struct {
+	// Name is the name of the milestone, such as Initialized or Ready.
+	Name string `json:"name"`
+	// Time is when the milestone was first observed.
+	Time metav1.Time `json:"time"`
+	// Elapsed is the duration from the creation of the cluster until the milestone was reached.
+	Elapsed metav1.Duration `json:"elapsed"`
 }
 
 type ImportedConfig struct {
@@ -30,6 +30,72 @@ type RKEMachinePool struct {
 	MachineOS                    string                       `json:"machineOS,omitempty"`
 	DynamicSchemaSpec            string                       `json:"dynamicSchemaSpec,omitempty"`
 	HostnameLengthLimit          int                          `json:"hostnameLengthLimit,omitempty"`
+
+	// Zones are the failure domains the machines of the pool are spread across. A MachineDeployment is created for each
+	// zone, and the quantity of the pool is divided evenly between them, with any remainder assigned to the first zones.
+	// If the quantity is not set, each zone is given the default quantity. The MachineDeployment of the first zone keeps
+	// the name of the pool. For pools of machine configs, the zone is set in the machine config of each zone, which is
+	// supported by the amazonec2, azure and google machine drivers.
+	Zones []string `json:"zones,omitempty"`
+
+	// Harvester requests devices and hugepages for the machines of a pool provisioned on Harvester. It can only be set
+	// if the machine config of the pool is a HarvesterConfig.
+	Harvester *HarvesterMachinePoolConfig `json:"harvester,omitempty"`
+
+	// CloudInit is a cloud-config document that is merged with the user data of the machine config and the bootstrap
+	// script of Rancher, i.e. to install agents or configure disks at first boot. Lists are appended to the lists of the
+	// user data of the machine config, any other key may only be set in one of them. Only machine drivers that accept
+	// cloud-init user data are supported.
+	CloudInit string `json:"cloudInit,omitempty"`
+
+	// ETCDRestartDrainOptions override the drain options of the upgrade strategy for the machines of the pool while the
+	// cluster is restarted during etcd snapshot creation and restore, i.e. to shorten the grace period of pools running
+	// workloads that are slow to terminate, or to not drain them at all.
+	ETCDRestartDrainOptions *rkev1.DrainOptions `json:"etcdRestartDrainOptions,omitempty"`
+
+	// Placement spreads the machines of the pool across the hosts of the infrastructure provider, i.e. so that etcd
+	// machines do not land on the same hypervisor by chance. Settings the machine driver of the pool does not support
+	// are rejected.
+	Placement *RKEMachinePoolPlacement `json:"placement,omitempty"`
+
+	// KubernetesVersion holds the worker machines of the pool at a different version than the kubernetesVersion of the
+	// cluster, so that large worker fleets can be upgraded pool by pool after the control plane. It must be of the same
+	// distribution as the cluster, must not be newer than it, and must be within the supported kubelet version skew.
+	// It is ignored for pools with the etcd or control plane role.
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// ContainerRuntime configures the container runtime of the linux machines of the pool, i.e. the containerd
+	// snapshotter, SELinux support or an alternate runtime. Settings are validated against the Kubernetes version of the
+	// machines, and must not conflict with the machine config.
+	ContainerRuntime *rkev1.ContainerRuntime `json:"containerRuntime,omitempty"`
+}
+
+type RKEMachinePoolPlacement struct {
+	// HostAntiAffinity requires the machines of the pool to run on different hosts. Only supported by Harvester.
+	HostAntiAffinity bool `json:"hostAntiAffinity,omitempty"`
+	// AvailabilitySet is the availability set the machines of the pool are created in. Only supported by Azure.
+	AvailabilitySet string `json:"availabilitySet,omitempty"`
+	// FaultDomainCount is the number of fault domains of the availability set, between 1 and 3. Only supported by Azure.
+	FaultDomainCount int `json:"faultDomainCount,omitempty"`
+	// UpdateDomainCount is the number of update domains of the availability set, between 1 and 20. Only supported by
+	// Azure.
+	UpdateDomainCount int `json:"updateDomainCount,omitempty"`
+}
+
+type HarvesterMachinePoolConfig struct {
+	// PCIDevices are passed through to every machine of the pool, i.e. GPUs.
+	PCIDevices []HarvesterPCIDevice `json:"pciDevices,omitempty"`
+	// HugepagesPageSize backs the memory of the machines with hugepages of the given size, either "2Mi" or "1Gi". The
+	// hosts of the Harvester cluster must have enough hugepages of the size preallocated.
+	HugepagesPageSize string `json:"hugepagesPageSize,omitempty"`
+}
+
+type HarvesterPCIDevice struct {
+	// ResourceName is the resource name the device is advertised with on the hosts of the Harvester cluster, i.e.
+	// "nvidia.com/TU104GL_TESLA_T4".
+	ResourceName string `json:"resourceName,omitempty"`
+	// Count is the number of devices passed through to each machine. Defaults to 1.
+	Count int `json:"count,omitempty"`
 }
 
 type RKEMachinePoolRollingUpdate struct {
@@ -75,12 +141,66 @@ type RKEConfig struct {
 
 	ETCDSnapshotCreate   *rkev1.ETCDSnapshotCreate   `json:"etcdSnapshotCreate,omitempty"`
 	ETCDSnapshotRestore  *rkev1.ETCDSnapshotRestore  `json:"etcdSnapshotRestore,omitempty"`
+	ETCDSnapshotUpload   *rkev1.ETCDSnapshotUpload   `json:"etcdSnapshotUpload,omitempty"`
+	ETCDSnapshotSchedule *rkev1.ETCDSnapshotSchedule `json:"etcdSnapshotSchedule,omitempty"`
 	RotateCertificates   *rkev1.RotateCertificates   `json:"rotateCertificates,omitempty"`
 	RotateEncryptionKeys *rkev1.RotateEncryptionKeys `json:"rotateEncryptionKeys,omitempty"`
 
 	MachinePools        []RKEMachinePool        `json:"machinePools,omitempty"`
 	MachinePoolDefaults RKEMachinePoolDefaults  `json:"machinePoolDefaults,omitempty"`
 	InfrastructureRef   *corev1.ObjectReference `json:"infrastructureRef,omitempty"`
+	// Topology creates machine deployments of the cluster from the worker classes of a CAPI ClusterClass, in addition to
+	// the machine pools. The control plane of the cluster remains managed by Rancher.
+	Topology *RKETopology `json:"topology,omitempty"`
+
+	// SanityProbes verifies that the cluster is functional once it reports ready.
+	SanityProbes *SanityProbes `json:"sanityProbes,omitempty"`
+}
+
+// SanityProbes configures the probes that are run in a cluster once it reports ready, to catch clusters that are ready
+// but can not run workloads. The probes run in a pod in the cattle-system namespace of the cluster, which checks that
+// the cluster DNS resolves the kubernetes service and optionally that the ingress controller on the node of the pod
+// routes requests. The results are reported in the SanityProbesPassed condition of the cluster.
+type SanityProbes struct {
+	// Enabled runs the probes once the cluster is ready.
+	Enabled bool `json:"enabled,omitempty"`
+	// IngressHost is the host that is requested through the ingress controller of the cluster. The probe passes if the
+	// ingress controller responds with a status below 400. The ingress controller is not probed if unset.
+	IngressHost string `json:"ingressHost,omitempty"`
+	// Timeout is how long the probe pod may take to complete. Defaults to 5 minutes.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// Generation runs the probes again when it is changed.
+	Generation int64 `json:"generation,omitempty"`
+}
+
+// RKETopology defines the shape of the machines of a cluster through a CAPI ClusterClass, so that clusters of the same
+// shape can share the worker classes and machine templates of the class.
+type RKETopology struct {
+	// Class is the name of the ClusterClass in the namespace of the cluster.
+	Class string `json:"class"`
+	// MachineDeployments are the machine deployments created from the worker classes of the ClusterClass.
+	MachineDeployments []RKEMachineDeploymentTopology `json:"machineDeployments,omitempty"`
+}
+
+// RKEMachineDeploymentTopology is a machine deployment of a cluster that is created from a MachineDeploymentClass of the
+// ClusterClass of the cluster. Its machines are bootstrapped by Rancher, so the bootstrap template of the class must be
+// an RKEBootstrapTemplate, and are handled by the planner like the machines of a machine pool of the same name.
+type RKEMachineDeploymentTopology struct {
+	// Class is the name of the worker MachineDeploymentClass of the ClusterClass.
+	Class string `json:"class"`
+	// Name is the name of the machine deployment in the topology, which must not be used by a machine pool of the
+	// cluster.
+	Name     string `json:"name"`
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	EtcdRole         bool `json:"etcdRole,omitempty"`
+	ControlPlaneRole bool `json:"controlPlaneRole,omitempty"`
+	WorkerRole       bool `json:"workerRole,omitempty"`
+	// Labels and Annotations are added to the machine deployment and its machines, on top of the metadata of the
+	// MachineDeploymentClass.
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Paused      bool              `json:"paused,omitempty"`
 }
 
 type RKEMachinePoolDefaults struct {
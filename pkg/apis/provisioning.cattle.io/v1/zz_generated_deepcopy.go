@@ -30,6 +30,39 @@
 	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentDeploymentCustomization) DeepCopyInto(out *AgentDeploymentCustomization) {
+	*out = *in
+	if in.AppendTolerations != nil {
+		in, out := &in.AppendTolerations, &out.AppendTolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.OverrideAffinity != nil {
+		in, out := &in.OverrideAffinity, &out.OverrideAffinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OverrideResourceRequirements != nil {
+		in, out := &in.OverrideResourceRequirements, &out.OverrideResourceRequirements
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentDeploymentCustomization.
+func (in *AgentDeploymentCustomization) DeepCopy() *AgentDeploymentCustomization {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentDeploymentCustomization)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Cluster) DeepCopyInto(out *Cluster) {
 	*out = *in
@@ -131,6 +164,16 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ClusterAgentDeploymentCustomization != nil {
+		in, out := &in.ClusterAgentDeploymentCustomization, &out.ClusterAgentDeploymentCustomization
+		*out = new(AgentDeploymentCustomization)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FleetAgentDeploymentCustomization != nil {
+		in, out := &in.FleetAgentDeploymentCustomization, &out.FleetAgentDeploymentCustomization
+		*out = new(AgentDeploymentCustomization)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -152,6 +195,18 @@ func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 		*out = make([]genericcondition.GenericCondition, len(*in))
 		copy(*out, *in)
 	}
+	if in.ProvisioningMilestones != nil {
+		in, out := &in.ProvisioningMilestones, &out.ProvisioningMilestones
+		*out = make([]ProvisioningMilestone, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SanityProbes != nil {
+		in, out := &in.SanityProbes, &out.SanityProbes
+		*out = new(SanityProbesStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -165,6 +220,43 @@ func (in *ClusterStatus) DeepCopy() *ClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarvesterMachinePoolConfig) DeepCopyInto(out *HarvesterMachinePoolConfig) {
+	*out = *in
+	if in.PCIDevices != nil {
+		in, out := &in.PCIDevices, &out.PCIDevices
+		*out = make([]HarvesterPCIDevice, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarvesterMachinePoolConfig.
+func (in *HarvesterMachinePoolConfig) DeepCopy() *HarvesterMachinePoolConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(HarvesterMachinePoolConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarvesterPCIDevice) DeepCopyInto(out *HarvesterPCIDevice) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarvesterPCIDevice.
+func (in *HarvesterPCIDevice) DeepCopy() *HarvesterPCIDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(HarvesterPCIDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImportedConfig) DeepCopyInto(out *ImportedConfig) {
 	*out = *in
@@ -181,6 +273,24 @@ func (in *ImportedConfig) DeepCopy() *ImportedConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningMilestone) DeepCopyInto(out *ProvisioningMilestone) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	out.Elapsed = in.Elapsed
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningMilestone.
+func (in *ProvisioningMilestone) DeepCopy() *ProvisioningMilestone {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningMilestone)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RKEConfig) DeepCopyInto(out *RKEConfig) {
 	*out = *in
@@ -188,13 +298,23 @@ func (in *RKEConfig) DeepCopyInto(out *RKEConfig) {
 	if in.ETCDSnapshotCreate != nil {
 		in, out := &in.ETCDSnapshotCreate, &out.ETCDSnapshotCreate
 		*out = new(rkecattleiov1.ETCDSnapshotCreate)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.ETCDSnapshotRestore != nil {
 		in, out := &in.ETCDSnapshotRestore, &out.ETCDSnapshotRestore
 		*out = new(rkecattleiov1.ETCDSnapshotRestore)
 		**out = **in
 	}
+	if in.ETCDSnapshotUpload != nil {
+		in, out := &in.ETCDSnapshotUpload, &out.ETCDSnapshotUpload
+		*out = new(rkecattleiov1.ETCDSnapshotUpload)
+		**out = **in
+	}
+	if in.ETCDSnapshotSchedule != nil {
+		in, out := &in.ETCDSnapshotSchedule, &out.ETCDSnapshotSchedule
+		*out = new(rkecattleiov1.ETCDSnapshotSchedule)
+		**out = **in
+	}
 	if in.RotateCertificates != nil {
 		in, out := &in.RotateCertificates, &out.RotateCertificates
 		*out = new(rkecattleiov1.RotateCertificates)
@@ -212,11 +332,22 @@ func (in *RKEConfig) DeepCopyInto(out *RKEConfig) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	out.MachinePoolDefaults = in.MachinePoolDefaults
 	if in.InfrastructureRef != nil {
 		in, out := &in.InfrastructureRef, &out.InfrastructureRef
 		*out = new(corev1.ObjectReference)
 		**out = **in
 	}
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = new(RKETopology)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SanityProbes != nil {
+		in, out := &in.SanityProbes, &out.SanityProbes
+		*out = new(SanityProbes)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -230,6 +361,41 @@ func (in *RKEConfig) DeepCopy() *RKEConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKEMachineDeploymentTopology) DeepCopyInto(out *RKEMachineDeploymentTopology) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKEMachineDeploymentTopology.
+func (in *RKEMachineDeploymentTopology) DeepCopy() *RKEMachineDeploymentTopology {
+	if in == nil {
+		return nil
+	}
+	out := new(RKEMachineDeploymentTopology)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RKEMachinePool) DeepCopyInto(out *RKEMachinePool) {
 	*out = *in
@@ -288,6 +454,31 @@ func (in *RKEMachinePool) DeepCopyInto(out *RKEMachinePool) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Harvester != nil {
+		in, out := &in.Harvester, &out.Harvester
+		*out = new(HarvesterMachinePoolConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ETCDRestartDrainOptions != nil {
+		in, out := &in.ETCDRestartDrainOptions, &out.ETCDRestartDrainOptions
+		*out = new(rkecattleiov1.DrainOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(RKEMachinePoolPlacement)
+		**out = **in
+	}
+	if in.ContainerRuntime != nil {
+		in, out := &in.ContainerRuntime, &out.ContainerRuntime
+		*out = new(rkecattleiov1.ContainerRuntime)
+		**out = **in
+	}
 	return
 }
 
@@ -301,6 +492,38 @@ func (in *RKEMachinePool) DeepCopy() *RKEMachinePool {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKEMachinePoolDefaults) DeepCopyInto(out *RKEMachinePoolDefaults) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKEMachinePoolDefaults.
+func (in *RKEMachinePoolDefaults) DeepCopy() *RKEMachinePoolDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(RKEMachinePoolDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKEMachinePoolPlacement) DeepCopyInto(out *RKEMachinePoolPlacement) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKEMachinePoolPlacement.
+func (in *RKEMachinePoolPlacement) DeepCopy() *RKEMachinePoolPlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(RKEMachinePoolPlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RKEMachinePoolRollingUpdate) DeepCopyInto(out *RKEMachinePoolRollingUpdate) {
 	*out = *in
@@ -326,3 +549,84 @@ func (in *RKEMachinePoolRollingUpdate) DeepCopy() *RKEMachinePoolRollingUpdate {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKETopology) DeepCopyInto(out *RKETopology) {
+	*out = *in
+	if in.MachineDeployments != nil {
+		in, out := &in.MachineDeployments, &out.MachineDeployments
+		*out = make([]RKEMachineDeploymentTopology, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKETopology.
+func (in *RKETopology) DeepCopy() *RKETopology {
+	if in == nil {
+		return nil
+	}
+	out := new(RKETopology)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SanityProbeCheck) DeepCopyInto(out *SanityProbeCheck) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SanityProbeCheck.
+func (in *SanityProbeCheck) DeepCopy() *SanityProbeCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(SanityProbeCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SanityProbes) DeepCopyInto(out *SanityProbes) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SanityProbes.
+func (in *SanityProbes) DeepCopy() *SanityProbes {
+	if in == nil {
+		return nil
+	}
+	out := new(SanityProbes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SanityProbesStatus) DeepCopyInto(out *SanityProbesStatus) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]SanityProbeCheck, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SanityProbesStatus.
+func (in *SanityProbesStatus) DeepCopy() *SanityProbesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SanityProbesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,39 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ApprovalOperationETCDSnapshotRestore approves the etcd snapshot restore of a cluster.
+	ApprovalOperationETCDSnapshotRestore = "etcd-snapshot-restore"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Approval records that a user approved an operation on a cluster. Approvals are created by Rancher on behalf of the
+// approving user, through the approve etcd snapshot restore action of the cluster, in the cattle-system namespace, so
+// that users who can change the cluster cannot create approvals with a forged approver.
+type Approval struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ApprovalSpec `json:"spec"`
+}
+
+type ApprovalSpec struct {
+	// ClusterNamespace is the namespace of the cluster the operation is approved for.
+	ClusterNamespace string `json:"clusterNamespace"`
+	// ClusterName is the name of the cluster the operation is approved for.
+	ClusterName string `json:"clusterName"`
+	// Operation is the approved operation. Only etcd-snapshot-restore is supported.
+	Operation string `json:"operation"`
+	// SnapshotName is the name of the etcd snapshot of the approved etcd snapshot restore.
+	SnapshotName string `json:"snapshotName,omitempty"`
+	// Generation is the generation of the approved operation, i.e. the generation of the etcd snapshot restore.
+	Generation int `json:"generation"`
+	// Approver is the ID of the user who approved the operation.
+	Approver string `json:"approver"`
+	// Comment is an optional justification given by the approver.
+	Comment string `json:"comment,omitempty"`
+}
@@ -25,6 +25,11 @@ type RKEBootstrapStatus struct {
 	// DataSecretName is the name of the secret that stores the bootstrap data script.
 	// +optional
 	DataSecretName *string `json:"dataSecretName,omitempty"`
+
+	// JoinedTo is the server that the machine joined the cluster through, as recorded in its plan secret. It is empty for
+	// the init node and for machines that have not joined the cluster yet.
+	// +optional
+	JoinedTo string `json:"joinedTo,omitempty"`
 }
 
 // +genclient
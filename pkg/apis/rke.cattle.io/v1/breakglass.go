@@ -0,0 +1,32 @@
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// BreakGlassAccess manages an OS user that can log in to the linux machines of the cluster over SSH with the authorized
+// keys, for access to the nodes when the cluster itself is not reachable.
+type BreakGlassAccess struct {
+	// User is the name of the OS user. It must be a valid linux user name and must not be root. The user is created by
+	// Rancher; the plan of a machine on which a user of the same name already exists fails rather than taking the user
+	// over. Renaming the user does not remove the previous user from the machines.
+	User string `json:"user"`
+	// AuthorizedKeys are the SSH public keys, in authorized_keys format, that are allowed to log in as the user. Changing
+	// the keys replaces the authorized keys of the user on all machines.
+	AuthorizedKeys []string `json:"authorizedKeys,omitempty"`
+	// Sudo grants the user passwordless sudo.
+	Sudo bool `json:"sudo,omitempty"`
+	// Remove removes the user and its home directory from the machines, if the user was created by Rancher. Removing
+	// the break-glass access section without setting Remove first leaves the user on the machines.
+	Remove bool `json:"remove,omitempty"`
+}
+
+// BreakGlassAccessChange records a change of the break-glass access of the cluster.
+type BreakGlassAccessChange struct {
+	// Time is when the planner started applying the change.
+	Time metav1.Time `json:"time"`
+	// Action is one of create, update or remove.
+	Action string `json:"action"`
+	User   string `json:"user"`
+	// KeyFingerprints are the SHA256 fingerprints of the authorized keys after the change.
+	KeyFingerprints []string `json:"keyFingerprints,omitempty"`
+	Sudo            bool     `json:"sudo,omitempty"`
+}
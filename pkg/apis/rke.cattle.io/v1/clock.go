@@ -0,0 +1,13 @@
+package v1
+
+// Clock configures the observation and the synchronization of the clocks of the linux machines of the cluster. The
+// machines periodically measure the offset of their clock from the clock of the Rancher server, and the
+// ClocksSynchronized condition of the cluster is set to false if the clocks are further apart than the maximum skew.
+type Clock struct {
+	// MaxSkew is the duration the clocks of the machines may be apart, i.e. "3s". Defaults to 3s. As offsets are measured
+	// with a precision of a second, values below 2s are not meaningful.
+	MaxSkew string `json:"maxSkew,omitempty"`
+	// NTPServers are added to the chrony configuration of the machines, which then step their clocks to them. Machines
+	// without chrony are left untouched.
+	NTPServers []string `json:"ntpServers,omitempty"`
+}
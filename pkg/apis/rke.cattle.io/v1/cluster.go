@@ -30,8 +30,39 @@ type RKEClusterSpecCommon struct {
 	AdditionalManifest    string                 `json:"additionalManifest,omitempty"`
 	Registries            *Registry              `json:"registries,omitempty"`
 	ETCD                  *ETCD                  `json:"etcd,omitempty"`
+	// SystemDefaultRegistry is the registry, without a scheme, that the system images of the cluster and the installer
+	// images delivered in the plans of its machines are pulled from. It takes precedence over the system-default-registry
+	// of the machine configs and the system-default-registry setting.
+	SystemDefaultRegistry string `json:"systemDefaultRegistry,omitempty"`
 	// Increment to force all nodes to re-provision
 	ProvisionGeneration int `json:"provisionGeneration,omitempty"`
+	// KubernetesVersionChannel subscribes the cluster to a release channel, optionally upgrading it automatically.
+	KubernetesVersionChannel *KubernetesVersionChannel `json:"kubernetesVersionChannel,omitempty"`
+	// ProbeCustomizations change how the health probes of the components of the cluster connect to them.
+	ProbeCustomizations []ProbeCustomization `json:"probeCustomizations,omitempty"`
+	// CoreDNS customizes the upstream nameservers and stub domains of CoreDNS.
+	CoreDNS *CoreDNSConfig `json:"coreDNS,omitempty"`
+	// KubeProxy customizes the proxy mode of kube-proxy.
+	KubeProxy *KubeProxyConfig `json:"kubeProxy,omitempty"`
+	// ImagePolicy restricts the registries of the images that Rancher delivers to the machines of the cluster.
+	ImagePolicy *ImagePolicy `json:"imagePolicy,omitempty"`
+	// Diagnostics configures where the diagnostics bundles collected from the machines of the cluster are stored.
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+	// BreakGlassAccess manages an OS user with SSH authorized keys on the linux machines of the cluster.
+	BreakGlassAccess *BreakGlassAccess `json:"breakGlassAccess,omitempty"`
+	// Firewall opens the ports of the cluster components in the host firewall of the linux machines of the cluster.
+	Firewall *Firewall `json:"firewall,omitempty"`
+	// Clock observes the skew of the clocks of the linux machines of the cluster and optionally configures chrony on them.
+	Clock *Clock `json:"clock,omitempty"`
+	// FileIntegrity reports and optionally restores changes to the files that the plans of the linux machines write.
+	FileIntegrity *FileIntegrity `json:"fileIntegrity,omitempty"`
+	// MachineDeletionHooks are run before a machine of the cluster is drained and deleted.
+	MachineDeletionHooks []MachineDeletionHook `json:"machineDeletionHooks,omitempty"`
+	// ValidateOnly reconciles the cluster into its generated objects without creating any machines, and renders the plans
+	// that the machines of each machine pool would receive into the rendered plans secret of the cluster, so that they can
+	// be reviewed before the cluster is provisioned. Unset it to provision the machines of the cluster. It is ignored once
+	// the cluster was provisioned or has machines, as it would delete them.
+	ValidateOnly bool `json:"validateOnly,omitempty"`
 }
 
 type LocalClusterAuthEndpoint struct {
@@ -64,6 +95,37 @@ type ClusterUpgradeStrategy struct {
 	// How many workers should be upgraded at a time
 	WorkerConcurrency  string       `json:"workerConcurrency,omitempty"`
 	WorkerDrainOptions DrainOptions `json:"workerDrainOptions,omitempty"`
+
+	// MaxFailedNodes is the number of machines whose plan failed or did not converge within the
+	// machine-plan-convergence-timeout that are tolerated during an upgrade. Once more machines failed, the rollout is
+	// paused and the UpgradeFailureBudgetExceeded condition is set on the control plane, until the failed machines
+	// recover, are deleted, or the budget is raised. If unset, failed machines do not pause the rollout.
+	MaxFailedNodes *int `json:"maxFailedNodes,omitempty"`
+
+	// Canary upgrades canary machines to a new Kubernetes version before the other machines of their tier. The other
+	// machines are only upgraded once the probes of the canary machine passed for the soak time.
+	Canary *UpgradeCanary `json:"canary,omitempty"`
+
+	// Prewarm pulls the images of a new Kubernetes version on the linux machines of the cluster before any machine is
+	// upgraded, so that the services of a machine are down for less time once it is upgraded.
+	Prewarm *UpgradePrewarm `json:"prewarm,omitempty"`
+}
+
+type UpgradePrewarm struct {
+	// Timeout is how long the upgrade waits for the machines to pull the images. Machines that did not pull them by then
+	// pull them when they are upgraded. Defaults to 10 minutes.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type UpgradeCanary struct {
+	// Worker is the name of the worker machine that is upgraded before the other worker machines. If unset, the first
+	// worker machine by name is used.
+	Worker string `json:"worker,omitempty"`
+	// ControlPlane upgrades the init node before the other etcd and controlplane machines, and soaks it as well.
+	ControlPlane bool `json:"controlPlane,omitempty"`
+	// SoakTime is how long the probes of an upgraded canary machine must pass before the rest of its tier is upgraded.
+	// Defaults to 5 minutes.
+	SoakTime *metav1.Duration `json:"soakTime,omitempty"`
 }
 
 type DrainOptions struct {
@@ -88,6 +150,9 @@ type DrainOptions struct {
 	Timeout int `json:"timeout"`
 	// SkipWaitForDeleteTimeoutSeconds If pod DeletionTimestamp older than N seconds, skip waiting for the pod.  Seconds must be greater than 0 to skip.
 	SkipWaitForDeleteTimeoutSeconds int `json:"skipWaitForDeleteTimeoutSeconds"`
+	// FailOnBlockingPodDisruptionBudgets fails the drain before any pod is evicted if pod disruption budgets allow no
+	// disruption of the pods on the node, instead of retrying the evictions until the timeout.
+	FailOnBlockingPodDisruptionBudgets bool `json:"failOnBlockingPodDisruptionBudgets,omitempty"`
 
 	// PreDrainHooks A list of hooks to run prior to draining a node
 	PreDrainHooks []DrainHook `json:"preDrainHooks"`
@@ -102,6 +167,9 @@ type DrainHook struct {
 	Annotation string `json:"annotation,omitempty"`
 }
 
+// ProvisioningFileSource is the source of files delivered to machines. Either a secret or a configmap in the namespace of
+// the cluster can be referenced, which must list the cluster in its rke.cattle.io/object-authorized-for-clusters
+// annotation.
 type ProvisioningFileSource struct {
 	Secret    K8sObjectFileSource `json:"secret,omitempty"`
 	ConfigMap K8sObjectFileSource `json:"configMap,omitempty"`
@@ -114,9 +182,16 @@ type K8sObjectFileSource struct {
 }
 
 type KeyToPath struct {
-	Key         string `json:"key"`
-	Path        string `json:"path"`
-	Dynamic     bool   `json:"dynamic,omitempty"`
+	// Key is the key of the secret or configmap the content of the file is read from. If the key does not exist, the file
+	// is written empty and the FileSourcesResolved condition of the cluster is set to false.
+	Key string `json:"key"`
+	// Path is the absolute path of the file on the machine.
+	Path string `json:"path"`
+	// Dynamic files are written to the machine without restarting the distribution when their content changes.
+	Dynamic bool `json:"dynamic,omitempty"`
+	// Permissions of the file, such as 0600. Defaults to the default permissions of the file source.
 	Permissions string `json:"permissions,omitempty"`
-	Hash        string `json:"hash,omitempty"`
+	// Hash is the base64 encoded SHA-256 sum of the expected content. If set, plans are not generated while the content
+	// of the key does not match it.
+	Hash string `json:"hash,omitempty"`
 }
@@ -0,0 +1,18 @@
+package v1
+
+// ContainerRuntime configures the container runtime of the linux machines of a machine pool. The settings are rendered
+// into the config file of k3s or RKE2 on the machines.
+type ContainerRuntime struct {
+	// Snapshotter is the containerd snapshotter, one of overlayfs, native, fuse-overlayfs or stargz, or zfs for k3s.
+	// Defaults to overlayfs.
+	Snapshotter string `json:"snapshotter,omitempty"`
+	// SELinux enables SELinux support in the container runtime. The SELinux policy of the distribution must be installed
+	// on the machines.
+	SELinux bool `json:"selinux,omitempty"`
+	// Endpoint is the socket of a container runtime that is run on the machines instead of the embedded containerd, i.e.
+	// unix:///run/containerd/containerd.sock.
+	Endpoint string `json:"endpoint,omitempty"`
+	// DefaultRuntime is the runtime handler of containerd that runs pods without a runtime class, i.e. crun. The runtime
+	// must be installed on the machines.
+	DefaultRuntime string `json:"defaultRuntime,omitempty"`
+}
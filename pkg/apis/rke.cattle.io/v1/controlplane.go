@@ -27,6 +27,8 @@ type RKEControlPlaneSpec struct {
 	LocalClusterAuthEndpoint LocalClusterAuthEndpoint `json:"localClusterAuthEndpoint"`
 	ETCDSnapshotCreate       *ETCDSnapshotCreate      `json:"etcdSnapshotCreate,omitempty"`
 	ETCDSnapshotRestore      *ETCDSnapshotRestore     `json:"etcdSnapshotRestore,omitempty"`
+	ETCDSnapshotUpload       *ETCDSnapshotUpload      `json:"etcdSnapshotUpload,omitempty"`
+	ETCDSnapshotSchedule     *ETCDSnapshotSchedule    `json:"etcdSnapshotSchedule,omitempty"`
 	RotateCertificates       *RotateCertificates      `json:"rotateCertificates,omitempty"`
 	RotateEncryptionKeys     *RotateEncryptionKeys    `json:"rotateEncryptionKeys,omitempty"`
 	KubernetesVersion        string                   `json:"kubernetesVersion,omitempty"`
@@ -48,7 +50,48 @@ type RKEControlPlaneStatus struct {
 	ETCDSnapshotRestorePhase      ETCDSnapshotPhase                   `json:"etcdSnapshotRestorePhase,omitempty"`
 	ETCDSnapshotCreate            *ETCDSnapshotCreate                 `json:"etcdSnapshotCreate,omitempty"`
 	ETCDSnapshotCreatePhase       ETCDSnapshotPhase                   `json:"etcdSnapshotCreatePhase,omitempty"`
+	ETCDSnapshotUpload            *ETCDSnapshotUpload                 `json:"etcdSnapshotUpload,omitempty"`
+	ETCDSnapshotUploadPhase       ETCDSnapshotPhase                   `json:"etcdSnapshotUploadPhase,omitempty"`
 	ConfigGeneration              int64                               `json:"configGeneration,omitempty"`
 	Initialized                   bool                                `json:"initialized,omitempty"`
 	AgentConnected                bool                                `json:"agentConnected,omitempty"`
+	// ChannelKubernetesVersion is the latest version of the release channel the cluster is subscribed to.
+	ChannelKubernetesVersion string `json:"channelKubernetesVersion,omitempty"`
+	// ETCDCompactedRevision is the revision etcd was last compacted to after a snapshot was created.
+	ETCDCompactedRevision int64 `json:"etcdCompactedRevision,omitempty"`
+	// ETCDSnapshotEncryptionKeys are the data keys etcd snapshots were encrypted with, the current key last.
+	ETCDSnapshotEncryptionKeys []ETCDSnapshotEncryptionKey `json:"etcdSnapshotEncryptionKeys,omitempty"`
+	// ETCDSnapshotSchedule is the state of the snapshots scheduled by the ETCDSnapshotSchedule of the spec.
+	ETCDSnapshotSchedule *ETCDSnapshotScheduleStatus `json:"etcdSnapshotSchedule,omitempty"`
+	// BreakGlassAccessChanges are the latest changes of the break-glass access of the cluster, the most recent last.
+	BreakGlassAccessChanges []BreakGlassAccessChange `json:"breakGlassAccessChanges,omitempty"`
+	// UpgradeCanary is the progress of the canary machines of the latest Kubernetes upgrade.
+	UpgradeCanary *UpgradeCanaryStatus `json:"upgradeCanary,omitempty"`
+	// UpgradePrewarm is the progress of pulling the images of the latest Kubernetes upgrade before it is rolled out.
+	UpgradePrewarm *UpgradePrewarmStatus `json:"upgradePrewarm,omitempty"`
+}
+
+// UpgradeCanaryStatus is the progress of the canary machines of an upgrade to a Kubernetes version.
+type UpgradeCanaryStatus struct {
+	KubernetesVersion string                `json:"kubernetesVersion,omitempty"`
+	ControlPlane      *UpgradeCanaryMachine `json:"controlPlane,omitempty"`
+	Worker            *UpgradeCanaryMachine `json:"worker,omitempty"`
+}
+
+// UpgradePrewarmStatus is the progress of pulling the images of an upgrade to a Kubernetes version.
+type UpgradePrewarmStatus struct {
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	// StartTime is the time the machines were asked to pull the images.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Completed is set once the machines pulled the images or the timeout passed, after which the upgrade is rolled out.
+	Completed bool `json:"completed,omitempty"`
+}
+
+// UpgradeCanaryMachine is the progress of a canary machine.
+type UpgradeCanaryMachine struct {
+	Name string `json:"name"`
+	// SoakStartTime is the time the canary machine was upgraded and its probes passed since.
+	SoakStartTime *metav1.Time `json:"soakStartTime,omitempty"`
+	// Completed is set once the canary machine soaked, after which the rest of its tier is upgraded.
+	Completed bool `json:"completed,omitempty"`
 }
@@ -0,0 +1,51 @@
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+const (
+	// MachineDeletionHookFailurePolicyIgnore continues the deletion of the machine once the hook timed out.
+	MachineDeletionHookFailurePolicyIgnore = "Ignore"
+	// MachineDeletionHookFailurePolicyFail keeps retrying the hook after it timed out, blocking the deletion of the
+	// machine until the hook succeeds or is removed from the cluster.
+	MachineDeletionHookFailurePolicyFail = "Fail"
+)
+
+// MachineDeletionHook is run before a machine of the cluster is drained and deleted, i.e. to deregister the node of the
+// machine from monitoring, a CMDB or load balancers. Exactly one of HTTP and Command must be set. The hooks of a machine
+// run one after the other in the order they are listed in.
+type MachineDeletionHook struct {
+	// Name identifies the hook, it must be unique within the hooks of the cluster.
+	Name string `json:"name"`
+	// HTTP posts the deleted machine to a URL.
+	HTTP *MachineDeletionHookHTTP `json:"http,omitempty"`
+	// Command runs a node command on other machines of the cluster.
+	Command *MachineDeletionHookCommand `json:"command,omitempty"`
+	// TimeoutSeconds is how long the hook is retried before its failure policy applies. Defaults to 300.
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+	// FailurePolicy is Ignore or Fail. Defaults to Ignore.
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+// MachineDeletionHookHTTP posts the namespace, cluster and machine name, node name, roles and addresses of the deleted
+// machine as JSON to the URL. Any 2xx response is a success.
+type MachineDeletionHookHTTP struct {
+	// URL is the URL the machine is posted to. Its host must be allowed by the machine-deletion-hook-allowed-hosts
+	// setting. Redirects are not followed.
+	URL string `json:"url"`
+	// CABundle is the PEM encoded CA bundle that the certificate of the URL is verified with. Defaults to the system CAs.
+	CABundle string `json:"caBundle,omitempty"`
+	// TokenSecretName is the name of a secret in the namespace of the cluster whose token key is sent as bearer token.
+	// The secret must be labeled rke.cattle.io/cluster-name with the name of the cluster and
+	// rke.cattle.io/machine-deletion-hook with the name of the hook.
+	TokenSecretName string `json:"tokenSecretName,omitempty"`
+}
+
+// MachineDeletionHookCommand runs a node command on the machines of the cluster selected by the machine selector. The
+// deleted machine is never selected. In the arguments, $(MACHINE_NAME) and $(NODE_NAME) are replaced by the name of
+// the deleted machine and its node. Like all node commands, the command must be allowed by the
+// node-command-allowed-commands setting, and is only run while the cluster is ready.
+type MachineDeletionHookCommand struct {
+	MachineSelector *metav1.LabelSelector `json:"machineSelector,omitempty"`
+	Command         string                `json:"command"`
+	Args            []string              `json:"args,omitempty"`
+}
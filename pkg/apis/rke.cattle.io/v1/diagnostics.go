@@ -0,0 +1,8 @@
+package v1
+
+// Diagnostics configures the storage of diagnostics bundles. A bundle is collected from a machine when the
+// rke.cattle.io/collect-diagnostics annotation of the machine is set to a new value.
+type Diagnostics struct {
+	// S3 uploads the bundles to an S3 bucket. If unset, the bundles are stored in secrets in the namespace of the cluster.
+	S3 *ETCDSnapshotS3 `json:"s3,omitempty"`
+}
@@ -8,11 +8,46 @@
 	ETCDSnapshotPhaseStarted        ETCDSnapshotPhase = "Started"
 	ETCDSnapshotPhaseShutdown       ETCDSnapshotPhase = "Shutdown"
 	ETCDSnapshotPhaseRestore        ETCDSnapshotPhase = "Restore"
+	ETCDSnapshotPhaseCompact        ETCDSnapshotPhase = "Compact"
 	ETCDSnapshotPhaseRestartCluster ETCDSnapshotPhase = "RestartCluster"
+	ETCDSnapshotPhaseVerifying      ETCDSnapshotPhase = "Verifying"
+	ETCDSnapshotPhaseMigrateStorage ETCDSnapshotPhase = "MigrateStorage"
 	ETCDSnapshotPhaseFinished       ETCDSnapshotPhase = "Finished"
 	ETCDSnapshotPhaseFailed         ETCDSnapshotPhase = "Failed"
+	ETCDSnapshotPhaseCancelling     ETCDSnapshotPhase = "Cancelling"
+	ETCDSnapshotPhaseCancelled      ETCDSnapshotPhase = "Cancelled"
+	ETCDSnapshotPhasePreviewed      ETCDSnapshotPhase = "Previewed"
 )
 
+// ETCDSnapshotAzure is a container of Azure Blob Storage that etcd snapshots are offloaded to. The etcd nodes access it
+// with the SAS token or the service principal of an Azure cloud credential.
+type ETCDSnapshotAzure struct {
+	// StorageAccount is the name of the storage account of the container.
+	StorageAccount string `json:"storageAccount,omitempty"`
+	// Container is the name of the blob container that snapshots are written to.
+	Container string `json:"container,omitempty"`
+	// Folder is the prefix of the names of the snapshot blobs in the container.
+	Folder string `json:"folder,omitempty"`
+	// Endpoint is the URL of the blob service of the storage account. It defaults to the blob service of the storage
+	// account in the Azure cloud of the cloud credential.
+	Endpoint string `json:"endpoint,omitempty"`
+	// CloudCredentialName is the name of the Azure cloud credential the container is accessed with.
+	CloudCredentialName string `json:"cloudCredentialName,omitempty"`
+}
+
+// ETCDSnapshotGCS is a bucket of Google Cloud Storage that etcd snapshots are offloaded to. The etcd nodes access it
+// with the service account key of a Google cloud credential.
+type ETCDSnapshotGCS struct {
+	// Bucket is the name of the bucket that snapshots are written to.
+	Bucket string `json:"bucket,omitempty"`
+	// Folder is the prefix of the names of the snapshot objects in the bucket.
+	Folder string `json:"folder,omitempty"`
+	// Endpoint is the URL of the Cloud Storage API. It defaults to https://storage.googleapis.com.
+	Endpoint string `json:"endpoint,omitempty"`
+	// CloudCredentialName is the name of the Google cloud credential the bucket is accessed with.
+	CloudCredentialName string `json:"cloudCredentialName,omitempty"`
+}
+
 type ETCDSnapshotS3 struct {
 	Endpoint            string `json:"endpoint,omitempty"`
 	EndpointCA          string `json:"endpointCA,omitempty"`
@@ -21,11 +56,83 @@ type ETCDSnapshotS3 struct {
 	Region              string `json:"region,omitempty"`
 	CloudCredentialName string `json:"cloudCredentialName,omitempty"`
 	Folder              string `json:"folder,omitempty"`
+	// StorageClass is the storage class that snapshots are written to S3 with, such as STANDARD, STANDARD_IA, GLACIER_IR
+	// or a storage class specific to the provider. GLACIER and DEEP_ARCHIVE are not allowed, as their snapshots can not
+	// be restored. The distribution does not support setting the storage class, so snapshots created through Rancher
+	// are uploaded by Rancher instead, which requires the access and secret key of a cloud credential. Snapshots the
+	// distribution creates on its schedule are written with the default storage class of the bucket.
+	StorageClass string `json:"storageClass,omitempty"`
+	// FallbackEndpoints are tried in order by the etcd snapshot upload when the endpoint, or a previous fallback endpoint,
+	// is unreachable, e.g. the gateways of a replicated object store. They serve the same bucket with the same
+	// credentials and endpoint CA as the endpoint.
+	FallbackEndpoints []string `json:"fallbackEndpoints,omitempty"`
 }
 
 type ETCDSnapshotCreate struct {
 	// Changing the Generation is the only thing required to initiate a snapshot creation.
 	Generation int `json:"generation,omitempty"`
+	// Cancel aborts the snapshot creation of the current generation. Snapshots that are being taken or uploaded on nodes
+	// are not interrupted, but failed nodes are not retried, the snapshots the creation saved are deleted from the nodes
+	// and S3, and the cluster is restarted with its regular plans.
+	Cancel bool `json:"cancel,omitempty"`
+	// ScheduledAt is set by the planner on the snapshot creations it starts for the ETCDSnapshotSchedule of the control
+	// plane, to the time the snapshot was due. It is ignored in the spec.
+	ScheduledAt *metav1.Time `json:"scheduledAt,omitempty"`
+	// Machines is the progress of the snapshot creation on each etcd machine. It is set by the planner in the status,
+	// so that the machines that failed a snapshot can be told apart, and is ignored in the spec.
+	Machines []ETCDSnapshotMachineProgress `json:"machines,omitempty"`
+	// EncryptionKeySecretName is the name of a secret in the namespace of the cluster whose key field holds a customer
+	// managed key of at least 32 bytes. The secret must be labeled rke.cattle.io/cluster-name with the name of the
+	// cluster. The snapshot is encrypted with it on the etcd nodes before it is offloaded, instead of with the data key
+	// of the SnapshotEncryption of the cluster.
+	EncryptionKeySecretName string `json:"encryptionKeySecretName,omitempty"`
+	// MaxConcurrency is the maximum number of etcd nodes that create the snapshot at the same time, to limit the I/O
+	// pressure on large etcd clusters. The remaining nodes create it once a node completed. Zero creates the snapshot on
+	// all etcd nodes at once.
+	MaxConcurrency int `json:"maxConcurrency,omitempty"`
+}
+
+// ETCDSnapshotMachineProgress is the progress of a snapshot creation on an etcd machine.
+type ETCDSnapshotMachineProgress struct {
+	MachineName string `json:"machineName"`
+	// Phase is Started while the snapshot is created on the machine, and Finished or Failed once it completed.
+	Phase ETCDSnapshotPhase `json:"phase,omitempty"`
+	// Error is the reason the snapshot creation failed on the machine.
+	Error string `json:"error,omitempty"`
+	// StartTime is the time the snapshot plan was delivered to the machine.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Duration is how long the snapshot creation on the machine took, once it completed.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// ETCDSnapshotSchedule schedules snapshots that are created by the planner, in the same way as the snapshots requested
+// through ETCDSnapshotCreate, so that the snapshot options of Rancher, such as the load gate, compaction and encryption,
+// apply to them. It does not change the schedule of the distribution, which is disabled with DisableSnapshots.
+type ETCDSnapshotSchedule struct {
+	// Cron is the schedule of the snapshots in standard cron format, evaluated in UTC. A snapshot that is due while
+	// another snapshot operation is in progress is created once the operation completed.
+	Cron string `json:"cron,omitempty"`
+	// Paused stops the creation of scheduled snapshots. A scheduled snapshot that is in progress is not interrupted.
+	Paused bool `json:"paused,omitempty"`
+}
+
+// ETCDSnapshotScheduleStatus is the state of the snapshots scheduled by the ETCDSnapshotSchedule of a control plane.
+type ETCDSnapshotScheduleStatus struct {
+	// LastScheduleTime is the time the last scheduled snapshot was due, or the time the schedule was set until the first
+	// snapshot is due.
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSuccessfulTime is the time the last scheduled snapshot that was created successfully was due.
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// LastCompletionTime is the time the last scheduled snapshot that was created successfully completed.
+	LastCompletionTime *metav1.Time `json:"lastCompletionTime,omitempty"`
+}
+
+// ETCDSnapshotUpload uploads the local etcd snapshots of the etcd nodes that are not stored in the S3 bucket of the etcd
+// settings yet, i.e. snapshots that were taken before S3 was configured for the cluster. The uploaded snapshots are
+// recorded as S3 etcd snapshots with the metadata of their local counterparts.
+type ETCDSnapshotUpload struct {
+	// Changing the Generation is the only thing required to initiate a snapshot upload.
+	Generation int `json:"generation,omitempty"`
 }
 
 type ETCDSnapshotRestore struct {
@@ -36,6 +143,23 @@ type ETCDSnapshotRestore struct {
 	Generation int `json:"generation,omitempty"`
 	// Set to either none (or empty string), all, or kubernetesVersion
 	RestoreRKEConfig string `json:"restoreRKEConfig,omitempty"`
+	// Cancel aborts the snapshot restore of the current generation. A restore can only be cancelled before the services
+	// of the cluster are stopped, as etcd can only be brought back by completing the restore afterwards.
+	Cancel bool `json:"cancel,omitempty"`
+	// EncryptionKeySecretName is the name of the secret holding the customer managed key the snapshot was encrypted
+	// with when it was created. Like when creating the snapshot, the secret must be labeled rke.cattle.io/cluster-name
+	// with the name of the cluster.
+	EncryptionKeySecretName string `json:"encryptionKeySecretName,omitempty"`
+	// MigrateStorageVersions allows restoring a snapshot taken with an older minor version of Kubernetes than the one of
+	// the cluster. Once the cluster is restarted, all objects are rewritten so that they are stored in the storage
+	// versions of the current version. Without it, such a restore is refused.
+	MigrateStorageVersions bool `json:"migrateStorageVersions,omitempty"`
+	// PreviewOnly renders the plans that the machines of the cluster would receive to stop their services, restore the
+	// snapshot and restart, and publishes them to the restore preview ConfigMap of the cluster instead of running the
+	// restore. The cluster is not changed: if RestoreRKEConfig is set, the plans are rendered with the Kubernetes version
+	// and RKE config of the snapshot, but the cluster is only updated with them once the restore is run. Unsetting it
+	// afterwards starts the previewed restore.
+	PreviewOnly bool `json:"previewOnly,omitempty"`
 }
 
 // +genclient
@@ -65,13 +189,166 @@ type ETCDSnapshotFile struct {
 	Message   string          `json:"message,omitempty"`
 }
 
+type ETCDSnapshotChecksumResult string
+
+const (
+	ETCDSnapshotChecksumVerified     ETCDSnapshotChecksumResult = "Verified"
+	ETCDSnapshotChecksumMismatch     ETCDSnapshotChecksumResult = "Mismatch"
+	ETCDSnapshotChecksumUnverifiable ETCDSnapshotChecksumResult = "Unverifiable"
+)
+
+// ETCDSnapshotChecksum is the result of verifying an S3 snapshot object against the snapshot file on the node that
+// uploaded it.
+type ETCDSnapshotChecksum struct {
+	// SHA256 is the hex encoded SHA-256 digest of the snapshot file on the node that uploaded it.
+	SHA256 string `json:"sha256,omitempty"`
+	// ETag is the ETag of the S3 object at the time of verification.
+	ETag   string                     `json:"etag,omitempty"`
+	Result ETCDSnapshotChecksumResult `json:"result,omitempty"`
+	// Message describes why the object could not be verified or did not match.
+	Message    string       `json:"message,omitempty"`
+	VerifiedAt *metav1.Time `json:"verifiedAt,omitempty"`
+}
+
+// ETCDSnapshotVerification is the result of verifying the integrity of a snapshot file on the node that created it.
+type ETCDSnapshotVerification struct {
+	// Size is the size of the snapshot file in bytes.
+	Size int64 `json:"size,omitempty"`
+	// SHA256 is the hex encoded SHA-256 digest of the snapshot file.
+	SHA256 string                     `json:"sha256,omitempty"`
+	Result ETCDSnapshotChecksumResult `json:"result,omitempty"`
+	// Message describes why the snapshot file could not be verified or is corrupt.
+	Message    string       `json:"message,omitempty"`
+	VerifiedAt *metav1.Time `json:"verifiedAt,omitempty"`
+}
+
 type ETCDSnapshotStatus struct {
-	Missing bool `json:"missing"`
+	Missing      bool                      `json:"missing"`
+	Checksum     *ETCDSnapshotChecksum     `json:"checksum,omitempty"`
+	Verification *ETCDSnapshotVerification `json:"verification,omitempty"`
 }
 
+// ETCD configures the etcd snapshots of a cluster. Every snapshot is a full copy of the datastore, as neither RKE2 nor
+// K3s can save or restore incremental snapshots.
 type ETCD struct {
 	DisableSnapshots     bool            `json:"disableSnapshots,omitempty"`
 	SnapshotScheduleCron string          `json:"snapshotScheduleCron,omitempty"`
 	SnapshotRetention    int             `json:"snapshotRetention,omitempty"`
 	S3                   *ETCDSnapshotS3 `json:"s3,omitempty"`
+
+	// SnapshotDir is the absolute path of the directory on the etcd nodes that snapshots are saved to. If not set, the
+	// default directory of the distribution is used.
+	SnapshotDir string `json:"snapshotDir,omitempty"`
+	// SnapshotMinFreeDiskPercent is the percentage of the filesystem of the snapshot directory that must be free for a
+	// snapshot to be created on a node. If the filesystem has less free space, the snapshot fails before it is written.
+	SnapshotMinFreeDiskPercent int `json:"snapshotMinFreeDiskPercent,omitempty"`
+	// SnapshotLoadGate defers the creation of snapshots requested through Rancher while etcd is under load. Snapshots
+	// that are scheduled by the distribution itself are not deferred.
+	SnapshotLoadGate *ETCDSnapshotLoadGate `json:"snapshotLoadGate,omitempty"`
+	// Tuning configures the etcd members of the cluster. Changes are rolled out by restarting etcd on one node at a
+	// time, once all etcd members are healthy.
+	Tuning *ETCDTuning `json:"tuning,omitempty"`
+	// DisableRestartDrain disables draining the nodes while the cluster is restarted during etcd snapshot creation and
+	// restore, regardless of the drain options of the upgrade strategy and the machine pools.
+	DisableRestartDrain bool `json:"disableRestartDrain,omitempty"`
+	// SnapshotMaxAge is how old the newest successful snapshot of the cluster may be before the cluster is marked with
+	// the SnapshotOverdue condition. It overrides the etcd-snapshot-max-age setting, and a zero duration disables the check.
+	SnapshotMaxAge *metav1.Duration `json:"snapshotMaxAge,omitempty"`
+	// CompactAfterSnapshot compacts the keyspace of etcd to its latest revision once a snapshot requested through Rancher
+	// was created on all etcd nodes. The compaction is performed by the leader, and skipped unless all etcd members are
+	// healthy. The revision etcd was compacted to is recorded in the status of the control plane.
+	CompactAfterSnapshot bool `json:"compactAfterSnapshot,omitempty"`
+	// SnapshotEncryption encrypts the snapshots requested through Rancher on the etcd nodes with a data key that is
+	// wrapped by a KMS. The snapshots are uploaded to S3 by Rancher once they were encrypted, which requires the access
+	// and secret key of an S3 cloud credential. Snapshots that are scheduled by the distribution itself are not
+	// encrypted.
+	SnapshotEncryption *ETCDSnapshotEncryption `json:"snapshotEncryption,omitempty"`
+	// Azure offloads the snapshots requested through Rancher from the etcd nodes to a container of Azure Blob Storage,
+	// as the distributions can only upload snapshots to S3 themselves.
+	Azure *ETCDSnapshotAzure `json:"azure,omitempty"`
+	// GCS offloads the snapshots requested through Rancher from the etcd nodes to a bucket of Google Cloud Storage.
+	GCS *ETCDSnapshotGCS `json:"gcs,omitempty"`
+	// S3Retention prunes the S3 snapshots of the cluster from Rancher. SnapshotRetention is applied by the distribution
+	// on each node to the snapshots it took, which does not bound the snapshots kept in S3 when snapshots are stored both
+	// locally and in S3.
+	S3Retention *ETCDSnapshotS3Retention `json:"s3Retention,omitempty"`
+}
+
+// ETCDSnapshotS3Retention is the policy of the S3 snapshots of a cluster that are kept. Snapshots beyond the count or
+// older than the maximum age are deleted from S3, except for the newest successful snapshot of the cluster, which is
+// always kept. Snapshots that failed or whose object is missing are not pruned.
+type ETCDSnapshotS3Retention struct {
+	// Count is the number of the newest successful S3 snapshots that are kept. Zero keeps any number of snapshots.
+	Count int `json:"count,omitempty"`
+	// MaxAge is how old an S3 snapshot may get before it is pruned. Snapshots are kept regardless of their age if unset.
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// ETCDSnapshotEncryption configures the KMS that wraps the data keys snapshots are encrypted with. A new data key is
+// generated once the rotation interval has passed, the previous data keys are kept in the status of the control plane
+// so that older snapshots can still be restored.
+type ETCDSnapshotEncryption struct {
+	// Provider is the KMS that wraps the data keys, one of vault, awskms or azurekeyvault.
+	Provider string `json:"provider,omitempty"`
+	// KeyName is the name of the transit key in vault, the ID, ARN or alias of the key in AWS KMS or the name of the
+	// key in Azure Key Vault.
+	KeyName string `json:"keyName,omitempty"`
+	// Endpoint is the address of vault, the URL of the Azure key vault or a custom endpoint of AWS KMS.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Region is the AWS region of the key, the default region of the cloud credential if unset.
+	Region string `json:"region,omitempty"`
+	// VaultTransitMount is the path the transit secrets engine is mounted at in vault, transit if unset.
+	VaultTransitMount string `json:"vaultTransitMount,omitempty"`
+	// CloudCredentialSecretName is the cloud credential the KMS is accessed with. Vault credentials hold a token, AWS
+	// credentials an access and secret key and Azure credentials the tenant, client ID and secret of a service principal.
+	CloudCredentialSecretName string `json:"cloudCredentialSecretName,omitempty"`
+	// RotationInterval is how long a data key is used before a new one is generated, 720h if unset.
+	RotationInterval *metav1.Duration `json:"rotationInterval,omitempty"`
+}
+
+// ETCDSnapshotEncryptionKey is a data key snapshots were encrypted with, wrapped by the KMS.
+type ETCDSnapshotEncryptionKey struct {
+	// ID identifies the key on the etcd nodes, next to the snapshots it encrypted.
+	ID string `json:"id"`
+	// Encryption is the KMS configuration the data key was wrapped with, which is used to unwrap it again.
+	Encryption ETCDSnapshotEncryption `json:"encryption"`
+	// KeyVersion is the version of the KMS key that wrapped the data key.
+	KeyVersion string `json:"keyVersion,omitempty"`
+	// EncryptedDataKey is the data key encrypted by the KMS.
+	EncryptedDataKey []byte `json:"encryptedDataKey"`
+	// CreatedAt is when the data key was generated. Snapshots created from then on until the next key was generated
+	// are encrypted with it.
+	CreatedAt metav1.Time `json:"createdAt"`
+}
+
+// ETCDSnapshotLoadGate defers the creation of a snapshot on a node while the latency of its etcd member exceeds the
+// thresholds, measured from the etcd metrics of the node over a short window. A threshold of 0 is not checked. Once the
+// deadline has passed, the snapshot is created regardless of the load.
+type ETCDSnapshotLoadGate struct {
+	// MaxWALFsyncDurationMilliseconds is the highest average duration of the fsync of the etcd write-ahead log at which
+	// a snapshot is created.
+	MaxWALFsyncDurationMilliseconds int `json:"maxWALFsyncDurationMilliseconds,omitempty"`
+	// MaxBackendCommitDurationMilliseconds is the highest average duration of the commits of the etcd backend at which
+	// a snapshot is created.
+	MaxBackendCommitDurationMilliseconds int `json:"maxBackendCommitDurationMilliseconds,omitempty"`
+	// RetryIntervalSeconds is how long to wait before measuring the load again, 30 seconds if unset.
+	RetryIntervalSeconds int `json:"retryIntervalSeconds,omitempty"`
+	// DeadlineSeconds is how long the snapshot may be deferred, 1800 seconds if unset.
+	DeadlineSeconds int `json:"deadlineSeconds,omitempty"`
+}
+
+// ETCDTuning holds the etcd settings that are commonly tuned for the size and latency of a cluster. Unset fields keep
+// the defaults of etcd.
+type ETCDTuning struct {
+	// QuotaBackendBytes is the size limit of the etcd database, between 1GiB and 8GiB.
+	QuotaBackendBytes int64 `json:"quotaBackendBytes,omitempty"`
+	// HeartbeatIntervalMilliseconds is the interval in which the leader notifies the followers that it is still the
+	// leader, between 10ms and 1000ms.
+	HeartbeatIntervalMilliseconds int `json:"heartbeatIntervalMilliseconds,omitempty"`
+	// ElectionTimeoutMilliseconds is how long a follower waits for a heartbeat before it attempts to become the leader,
+	// between 5 times the heartbeat interval and 50000ms.
+	ElectionTimeoutMilliseconds int `json:"electionTimeoutMilliseconds,omitempty"`
+	// SnapshotCount is the number of committed transactions that trigger a snapshot of the raft log to disk, between
+	// 1000 and 1000000.
+	SnapshotCount int64 `json:"snapshotCount,omitempty"`
 }
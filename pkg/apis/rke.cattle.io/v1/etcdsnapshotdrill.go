@@ -0,0 +1,76 @@
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ETCDSnapshotDrillPhase string
+
+const (
+	ETCDSnapshotDrillPhaseProvisioning ETCDSnapshotDrillPhase = "Provisioning"
+	ETCDSnapshotDrillPhaseRestoring    ETCDSnapshotDrillPhase = "Restoring"
+	ETCDSnapshotDrillPhaseVerifying    ETCDSnapshotDrillPhase = "Verifying"
+	ETCDSnapshotDrillPhaseSucceeded    ETCDSnapshotDrillPhase = "Succeeded"
+	ETCDSnapshotDrillPhaseFailed       ETCDSnapshotDrillPhase = "Failed"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ETCDSnapshotDrill restores an etcd snapshot into a temporary single node sandbox cluster in a namespace of its own,
+// verifies the restored cluster and reports whether the snapshot is restorable. The cluster the snapshot was taken from
+// is not touched. The restored workloads of the cluster do not run in the sandbox, and its secrets, other than the S3
+// credentials to download the snapshot, are not available to the sandbox.
+type ETCDSnapshotDrill struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ETCDSnapshotDrillSpec   `json:"spec"`
+	Status            ETCDSnapshotDrillStatus `json:"status,omitempty"`
+}
+
+type ETCDSnapshotDrillSpec struct {
+	// SnapshotName is the name of the ETCDSnapshot in the namespace of the drill to restore. Only snapshots stored in S3
+	// can be restored to a sandbox cluster.
+	SnapshotName string `json:"snapshotName"`
+	// MachineConfigRef references the machine config in the namespace of the drill used to provision the machine of the
+	// sandbox cluster. It is copied to the namespace of the sandbox cluster.
+	MachineConfigRef *corev1.ObjectReference `json:"machineConfigRef"`
+	// CloudCredentialSecretName is the cloud credential used to provision the sandbox cluster.
+	CloudCredentialSecretName string `json:"cloudCredentialSecretName,omitempty"`
+	// Verification configures the checks run against the sandbox cluster once the snapshot is restored.
+	Verification ETCDSnapshotDrillVerification `json:"verification,omitempty"`
+	// Timeout is how long the drill may take before it fails. Defaults to one hour.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// KeepSandbox keeps the sandbox cluster after the drill completed, i.e. to inspect it.
+	KeepSandbox bool `json:"keepSandbox,omitempty"`
+}
+
+type ETCDSnapshotDrillVerification struct {
+	// Resources lists resources that must exist in the restored cluster.
+	Resources []ETCDSnapshotDrillResource `json:"resources,omitempty"`
+}
+
+type ETCDSnapshotDrillResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+type ETCDSnapshotDrillStatus struct {
+	Phase ETCDSnapshotDrillPhase `json:"phase,omitempty"`
+	// SandboxNamespace is the namespace the sandbox cluster is created in. It is removed along with the sandbox cluster.
+	SandboxNamespace   string                   `json:"sandboxNamespace,omitempty"`
+	SandboxClusterName string                   `json:"sandboxClusterName,omitempty"`
+	StartTime          *metav1.Time             `json:"startTime,omitempty"`
+	CompletionTime     *metav1.Time             `json:"completionTime,omitempty"`
+	Message            string                   `json:"message,omitempty"`
+	Checks             []ETCDSnapshotDrillCheck `json:"checks,omitempty"`
+}
+
+type ETCDSnapshotDrillCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,12 @@
+package v1
+
+// FileIntegrity configures the monitoring of the files that the plans of the linux machines of the cluster write to
+// them. The machines periodically compare the files with the content of their plan, and the ManagedFilesIntact
+// condition of a machine is set to false if any of them was changed or deleted on the machine.
+type FileIntegrity struct {
+	// PeriodSeconds is how often the machines compare the files with the content of their plan. Defaults to 300.
+	PeriodSeconds int `json:"periodSeconds,omitempty"`
+	// Restore applies the plan of a machine again once files of it were changed or deleted, which writes the files with
+	// the content of the plan. Only the digests of the files are delivered to the machines to compare them with.
+	Restore bool `json:"restore,omitempty"`
+}
@@ -0,0 +1,11 @@
+package v1
+
+// Firewall opens the ports of the cluster components in the host firewall of the linux machines of the cluster, using
+// firewalld if it is running and the inet filter table of nftables otherwise. The ports of a machine follow its roles
+// and the configuration of the cluster, i.e. the CNI and the embedded registry. Removing the firewall section leaves the
+// last opened ports in place.
+type Firewall struct {
+	// AdditionalPorts are opened on all machines in addition to the ports of the cluster components, as a port or port
+	// range and a protocol, i.e. "8080/tcp" or "7000-7010/udp".
+	AdditionalPorts []string `json:"additionalPorts,omitempty"`
+}
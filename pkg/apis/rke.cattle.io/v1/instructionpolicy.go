@@ -0,0 +1,30 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// InstructionPolicy restricts the instructions and files the planner may deliver to machines of clusters. Policies are
+// cluster-scoped, so that only administrators can manage them. When one or more policies apply to the namespace of a
+// cluster, any plan that contains an instruction command or file path not allowed by at least one of them is refused
+// by the planner, which is reported in the InstructionsAllowed condition of the cluster.
+type InstructionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              InstructionPolicySpec `json:"spec"`
+}
+
+type InstructionPolicySpec struct {
+	// Namespaces are the namespaces of the clusters the policy applies to. If empty, the policy applies to all clusters.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// AllowedCommands is a list of commands that instructions may execute. Entries are matched against the command of
+	// each instruction using path.Match syntax, i.e. "/var/lib/rancher/rke2/bin/*".
+	AllowedCommands []string `json:"allowedCommands,omitempty"`
+	// AllowedPaths is a list of paths that plan files may be written to. Entries use path.Match syntax, and an entry
+	// ending in "/**" allows any path beneath the given directory.
+	AllowedPaths []string `json:"allowedPaths,omitempty"`
+}
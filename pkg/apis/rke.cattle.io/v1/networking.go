@@ -0,0 +1,30 @@
+package v1
+
+type KubeProxyMode string
+
+const (
+	KubeProxyModeIPTables KubeProxyMode = "iptables"
+	KubeProxyModeIPVS     KubeProxyMode = "ipvs"
+	KubeProxyModeNFTables KubeProxyMode = "nftables"
+)
+
+// CoreDNSConfig customizes the CoreDNS deployment of the cluster.
+type CoreDNSConfig struct {
+	// Forwarders are the upstream nameservers that queries outside of the cluster domain are forwarded to, instead of
+	// the nameservers of the nodes. Only supported on rke2.
+	Forwarders []string `json:"forwarders,omitempty"`
+	// StubDomains forward queries for the given domains to dedicated nameservers.
+	StubDomains []CoreDNSStubDomain `json:"stubDomains,omitempty"`
+}
+
+type CoreDNSStubDomain struct {
+	Domain  string   `json:"domain"`
+	Servers []string `json:"servers"`
+}
+
+// KubeProxyConfig customizes kube-proxy on all nodes of the cluster.
+type KubeProxyConfig struct {
+	// Mode is the proxy mode of kube-proxy, one of iptables, ipvs or nftables. The nftables mode requires Kubernetes
+	// v1.31 or newer.
+	Mode KubeProxyMode `json:"mode,omitempty"`
+}
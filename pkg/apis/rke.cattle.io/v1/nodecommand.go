@@ -0,0 +1,65 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type NodeCommandPhase string
+
+const (
+	NodeCommandPhasePending   NodeCommandPhase = "Pending"
+	NodeCommandPhaseRunning   NodeCommandPhase = "Running"
+	NodeCommandPhaseSucceeded NodeCommandPhase = "Succeeded"
+	NodeCommandPhaseFailed    NodeCommandPhase = "Failed"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeCommand runs a one-off command on the machines of a cluster in the namespace of the command. The command is
+// delivered to the machines one at a time with their plans, its output is captured to the status of the command, and the
+// command is deleted once its TTL after finishing expired.
+type NodeCommand struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              NodeCommandSpec   `json:"spec"`
+	Status            NodeCommandStatus `json:"status,omitempty"`
+}
+
+type NodeCommandSpec struct {
+	// ClusterName is the name of the cluster to run the command on.
+	ClusterName string `json:"clusterName"`
+	// MachineSelector selects the machines of the cluster to run the command on by their labels. If empty, the command
+	// runs on all machines of the cluster.
+	MachineSelector *metav1.LabelSelector `json:"machineSelector,omitempty"`
+	// Command is the command to run. Only commands matching an entry of the node-command-allowed-commands setting are
+	// run. Entries may restrict the subcommand, the first argument, and the flags of the command.
+	Command string `json:"command"`
+	// Args are the arguments of the command.
+	Args []string `json:"args,omitempty"`
+	// TTLSecondsAfterFinished is how long the command is kept after it finished. Defaults to one hour.
+	TTLSecondsAfterFinished *int64 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+type NodeCommandStatus struct {
+	Phase NodeCommandPhase `json:"phase,omitempty"`
+	// ObservedGeneration is the generation of the spec that was validated. If the spec of a pending command changes, it
+	// is validated again; if the spec of a running command changes, the command fails.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ValidatedSpec is the copy of the spec that was validated. Only the validated spec is run on the machines.
+	ValidatedSpec  *NodeCommandSpec `json:"validatedSpec,omitempty"`
+	StartTime      *metav1.Time     `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time     `json:"completionTime,omitempty"`
+	Message        string           `json:"message,omitempty"`
+	// TargetMachines are the names of the machines selected when the command started, in the order the command runs on
+	// them.
+	TargetMachines []string            `json:"targetMachines,omitempty"`
+	Results        []NodeCommandResult `json:"results,omitempty"`
+}
+
+type NodeCommandResult struct {
+	MachineName string `json:"machineName"`
+	Succeeded   bool   `json:"succeeded"`
+	// Output is the combined output of the command on the machine, truncated to 32KiB.
+	Output string `json:"output,omitempty"`
+}
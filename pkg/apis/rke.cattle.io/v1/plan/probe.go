@@ -6,6 +6,10 @@ type HTTPGetAction struct {
 	ClientCert string `json:"clientCert,omitempty"`
 	ClientKey  string `json:"clientKey,omitempty"`
 	CACert     string `json:"caCert,omitempty"`
+	// ServerName overrides the SNI hostname of the request and the hostname the serving certificate is verified against.
+	ServerName string `json:"serverName,omitempty"`
+	// Headers are added to the request.
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 type Probe struct {
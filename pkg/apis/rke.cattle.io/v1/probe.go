@@ -0,0 +1,16 @@
+package v1
+
+// ProbeCustomization changes how the health probes of the components of a cluster connect to the components, i.e.
+// when the components are fronted by TLS-terminating proxies.
+type ProbeCustomization struct {
+	// Probes are the names of the probes the customization applies to, i.e. "kube-apiserver". If empty, the
+	// customization applies to every probe. Later customizations take precedence over earlier ones.
+	Probes []string `json:"probes,omitempty"`
+	// CACerts is a PEM encoded bundle of the CA certificates the probes verify the serving certificate against, in place
+	// of the CA of the component.
+	CACerts string `json:"caCerts,omitempty"`
+	// ServerName is the SNI hostname the probes send, and verify the serving certificate against.
+	ServerName string `json:"serverName,omitempty"`
+	// Headers are added to the requests of the probes.
+	Headers map[string]string `json:"headers,omitempty"`
+}
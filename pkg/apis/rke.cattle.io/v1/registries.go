@@ -57,3 +57,12 @@ type RegistryConfig struct {
 
 	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
 }
+
+// ImagePolicy restricts the images referenced by the manifests and install instructions in the plans of the machines of
+// a cluster. A plan referencing an image outside of the allowed registries is not delivered.
+type ImagePolicy struct {
+	// AllowedRegistries lists the registries images may be pulled from, i.e. "registry.example.com". An entry can be
+	// narrowed to the repositories beneath a path of the registry, i.e. "registry.example.com/rancher". Images without a
+	// registry are matched as images of docker.io.
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+}
@@ -0,0 +1,34 @@
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// KubernetesVersionChannel subscribes a cluster to a release channel of its Kubernetes distribution.
+type KubernetesVersionChannel struct {
+	// Name is the name of the release channel, i.e. "stable", "latest", or "v1.25". Defaults to "stable".
+	Name string `json:"name,omitempty"`
+	// URL of a channel server to resolve the channel from, i.e. "https://update.rke2.io/v1-release/channels". It must be
+	// one of the channel servers of the kubernetes-version-channel-servers setting. If not set, the channel is resolved
+	// from the KDM release data of the distribution. Either way, only versions of the KDM release data are used.
+	URL string `json:"url,omitempty"`
+	// AutoUpgrade upgrades the cluster to the latest version of the channel. If not set, the latest version of the
+	// channel is only reported in the status of the control plane.
+	AutoUpgrade *KubernetesVersionAutoUpgrade `json:"autoUpgrade,omitempty"`
+}
+
+type KubernetesVersionAutoUpgrade struct {
+	// PatchOnly restricts automatic upgrades to patch releases of the current minor version. If the channel moved to
+	// a newer minor version, the cluster follows the channel of its minor version instead, i.e. "v1.25".
+	PatchOnly bool `json:"patchOnly,omitempty"`
+	// MaintenanceWindow restricts when automatic upgrades may start. If not set, upgrades start as soon as a new
+	// version is available.
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+}
+
+type MaintenanceWindow struct {
+	// Days of the week the window opens on, i.e. "Saturday" or "Sat". Defaults to every day.
+	Days []string `json:"days,omitempty"`
+	// Start is the time of day the window opens at in UTC, in "15:04" format.
+	Start string `json:"start,omitempty"`
+	// Duration is how long the window stays open.
+	Duration metav1.Duration `json:"duration,omitempty"`
+}
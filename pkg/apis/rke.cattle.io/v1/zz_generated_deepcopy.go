@@ -45,11 +45,166 @@ func (in *AuthConfig) DeepCopy() *AuthConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Approval) DeepCopyInto(out *Approval) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Approval.
+func (in *Approval) DeepCopy() *Approval {
+	if in == nil {
+		return nil
+	}
+	out := new(Approval)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Approval) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApprovalList) DeepCopyInto(out *ApprovalList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Approval, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApprovalList.
+func (in *ApprovalList) DeepCopy() *ApprovalList {
+	if in == nil {
+		return nil
+	}
+	out := new(ApprovalList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApprovalList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApprovalSpec) DeepCopyInto(out *ApprovalSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApprovalSpec.
+func (in *ApprovalSpec) DeepCopy() *ApprovalSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApprovalSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BreakGlassAccess) DeepCopyInto(out *BreakGlassAccess) {
+	*out = *in
+	if in.AuthorizedKeys != nil {
+		in, out := &in.AuthorizedKeys, &out.AuthorizedKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BreakGlassAccess.
+func (in *BreakGlassAccess) DeepCopy() *BreakGlassAccess {
+	if in == nil {
+		return nil
+	}
+	out := new(BreakGlassAccess)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BreakGlassAccessChange) DeepCopyInto(out *BreakGlassAccessChange) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.KeyFingerprints != nil {
+		in, out := &in.KeyFingerprints, &out.KeyFingerprints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BreakGlassAccessChange.
+func (in *BreakGlassAccessChange) DeepCopy() *BreakGlassAccessChange {
+	if in == nil {
+		return nil
+	}
+	out := new(BreakGlassAccessChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Clock) DeepCopyInto(out *Clock) {
+	*out = *in
+	if in.NTPServers != nil {
+		in, out := &in.NTPServers, &out.NTPServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Clock.
+func (in *Clock) DeepCopy() *Clock {
+	if in == nil {
+		return nil
+	}
+	out := new(Clock)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterUpgradeStrategy) DeepCopyInto(out *ClusterUpgradeStrategy) {
 	*out = *in
 	in.ControlPlaneDrainOptions.DeepCopyInto(&out.ControlPlaneDrainOptions)
 	in.WorkerDrainOptions.DeepCopyInto(&out.WorkerDrainOptions)
+	if in.MaxFailedNodes != nil {
+		in, out := &in.MaxFailedNodes, &out.MaxFailedNodes
+		*out = new(int)
+		**out = **in
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(UpgradeCanary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Prewarm != nil {
+		in, out := &in.Prewarm, &out.Prewarm
+		*out = new(UpgradePrewarm)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -63,6 +218,71 @@ func (in *ClusterUpgradeStrategy) DeepCopy() *ClusterUpgradeStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerRuntime) DeepCopyInto(out *ContainerRuntime) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerRuntime.
+func (in *ContainerRuntime) DeepCopy() *ContainerRuntime {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerRuntime)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoreDNSConfig) DeepCopyInto(out *CoreDNSConfig) {
+	*out = *in
+	if in.Forwarders != nil {
+		in, out := &in.Forwarders, &out.Forwarders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StubDomains != nil {
+		in, out := &in.StubDomains, &out.StubDomains
+		*out = make([]CoreDNSStubDomain, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoreDNSConfig.
+func (in *CoreDNSConfig) DeepCopy() *CoreDNSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CoreDNSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoreDNSStubDomain) DeepCopyInto(out *CoreDNSStubDomain) {
+	*out = *in
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoreDNSStubDomain.
+func (in *CoreDNSStubDomain) DeepCopy() *CoreDNSStubDomain {
+	if in == nil {
+		return nil
+	}
+	out := new(CoreDNSStubDomain)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CustomMachine) DeepCopyInto(out *CustomMachine) {
 	*out = *in
@@ -182,6 +402,27 @@ func (in *DrainHook) DeepCopy() *DrainHook {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Diagnostics) DeepCopyInto(out *Diagnostics) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(ETCDSnapshotS3)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Diagnostics.
+func (in *Diagnostics) DeepCopy() *Diagnostics {
+	if in == nil {
+		return nil
+	}
+	out := new(Diagnostics)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DrainOptions) DeepCopyInto(out *DrainOptions) {
 	*out = *in
@@ -219,8 +460,43 @@ func (in *ETCD) DeepCopyInto(out *ETCD) {
 	if in.S3 != nil {
 		in, out := &in.S3, &out.S3
 		*out = new(ETCDSnapshotS3)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SnapshotLoadGate != nil {
+		in, out := &in.SnapshotLoadGate, &out.SnapshotLoadGate
+		*out = new(ETCDSnapshotLoadGate)
+		**out = **in
+	}
+	if in.Tuning != nil {
+		in, out := &in.Tuning, &out.Tuning
+		*out = new(ETCDTuning)
+		**out = **in
+	}
+	if in.SnapshotMaxAge != nil {
+		in, out := &in.SnapshotMaxAge, &out.SnapshotMaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.SnapshotEncryption != nil {
+		in, out := &in.SnapshotEncryption, &out.SnapshotEncryption
+		*out = new(ETCDSnapshotEncryption)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Azure != nil {
+		in, out := &in.Azure, &out.Azure
+		*out = new(ETCDSnapshotAzure)
+		**out = **in
+	}
+	if in.GCS != nil {
+		in, out := &in.GCS, &out.GCS
+		*out = new(ETCDSnapshotGCS)
 		**out = **in
 	}
+	if in.S3Retention != nil {
+		in, out := &in.S3Retention, &out.S3Retention
+		*out = new(ETCDSnapshotS3Retention)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -241,7 +517,7 @@ func (in *ETCDSnapshot) DeepCopyInto(out *ETCDSnapshot) {
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
 	in.SnapshotFile.DeepCopyInto(&out.SnapshotFile)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -263,9 +539,56 @@ func (in *ETCDSnapshot) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotAzure) DeepCopyInto(out *ETCDSnapshotAzure) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotAzure.
+func (in *ETCDSnapshotAzure) DeepCopy() *ETCDSnapshotAzure {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotAzure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotChecksum) DeepCopyInto(out *ETCDSnapshotChecksum) {
+	*out = *in
+	if in.VerifiedAt != nil {
+		in, out := &in.VerifiedAt, &out.VerifiedAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotChecksum.
+func (in *ETCDSnapshotChecksum) DeepCopy() *ETCDSnapshotChecksum {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotChecksum)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ETCDSnapshotCreate) DeepCopyInto(out *ETCDSnapshotCreate) {
 	*out = *in
+	if in.ScheduledAt != nil {
+		in, out := &in.ScheduledAt, &out.ScheduledAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Machines != nil {
+		in, out := &in.Machines, &out.Machines
+		*out = make([]ETCDSnapshotMachineProgress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -280,38 +603,57 @@ func (in *ETCDSnapshotCreate) DeepCopy() *ETCDSnapshotCreate {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ETCDSnapshotFile) DeepCopyInto(out *ETCDSnapshotFile) {
+func (in *ETCDSnapshotDrill) DeepCopyInto(out *ETCDSnapshotDrill) {
 	*out = *in
-	if in.CreatedAt != nil {
-		in, out := &in.CreatedAt, &out.CreatedAt
-		*out = (*in).DeepCopy()
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotDrill.
+func (in *ETCDSnapshotDrill) DeepCopy() *ETCDSnapshotDrill {
+	if in == nil {
+		return nil
 	}
-	if in.S3 != nil {
-		in, out := &in.S3, &out.S3
-		*out = new(ETCDSnapshotS3)
-		**out = **in
+	out := new(ETCDSnapshotDrill)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ETCDSnapshotDrill) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotDrillCheck) DeepCopyInto(out *ETCDSnapshotDrillCheck) {
+	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotFile.
-func (in *ETCDSnapshotFile) DeepCopy() *ETCDSnapshotFile {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotDrillCheck.
+func (in *ETCDSnapshotDrillCheck) DeepCopy() *ETCDSnapshotDrillCheck {
 	if in == nil {
 		return nil
 	}
-	out := new(ETCDSnapshotFile)
+	out := new(ETCDSnapshotDrillCheck)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ETCDSnapshotList) DeepCopyInto(out *ETCDSnapshotList) {
+func (in *ETCDSnapshotDrillList) DeepCopyInto(out *ETCDSnapshotDrillList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]ETCDSnapshot, len(*in))
+		*out = make([]ETCDSnapshotDrill, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -319,177 +661,1038 @@ func (in *ETCDSnapshotList) DeepCopyInto(out *ETCDSnapshotList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotList.
-func (in *ETCDSnapshotList) DeepCopy() *ETCDSnapshotList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotDrillList.
+func (in *ETCDSnapshotDrillList) DeepCopy() *ETCDSnapshotDrillList {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotDrillList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ETCDSnapshotDrillList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotDrillResource) DeepCopyInto(out *ETCDSnapshotDrillResource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotDrillResource.
+func (in *ETCDSnapshotDrillResource) DeepCopy() *ETCDSnapshotDrillResource {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotDrillResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotDrillSpec) DeepCopyInto(out *ETCDSnapshotDrillSpec) {
+	*out = *in
+	if in.MachineConfigRef != nil {
+		in, out := &in.MachineConfigRef, &out.MachineConfigRef
+		*out = new(corev1.ObjectReference)
+		**out = **in
+	}
+	in.Verification.DeepCopyInto(&out.Verification)
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotDrillSpec.
+func (in *ETCDSnapshotDrillSpec) DeepCopy() *ETCDSnapshotDrillSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotDrillSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotDrillStatus) DeepCopyInto(out *ETCDSnapshotDrillStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]ETCDSnapshotDrillCheck, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotDrillStatus.
+func (in *ETCDSnapshotDrillStatus) DeepCopy() *ETCDSnapshotDrillStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotDrillStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotDrillVerification) DeepCopyInto(out *ETCDSnapshotDrillVerification) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ETCDSnapshotDrillResource, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotDrillVerification.
+func (in *ETCDSnapshotDrillVerification) DeepCopy() *ETCDSnapshotDrillVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotDrillVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotFile) DeepCopyInto(out *ETCDSnapshotFile) {
+	*out = *in
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(ETCDSnapshotS3)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotFile.
+func (in *ETCDSnapshotFile) DeepCopy() *ETCDSnapshotFile {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotFile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotList) DeepCopyInto(out *ETCDSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ETCDSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotList.
+func (in *ETCDSnapshotList) DeepCopy() *ETCDSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ETCDSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotEncryption) DeepCopyInto(out *ETCDSnapshotEncryption) {
+	*out = *in
+	if in.RotationInterval != nil {
+		in, out := &in.RotationInterval, &out.RotationInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotEncryption.
+func (in *ETCDSnapshotEncryption) DeepCopy() *ETCDSnapshotEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotEncryptionKey) DeepCopyInto(out *ETCDSnapshotEncryptionKey) {
+	*out = *in
+	in.Encryption.DeepCopyInto(&out.Encryption)
+	if in.EncryptedDataKey != nil {
+		in, out := &in.EncryptedDataKey, &out.EncryptedDataKey
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	in.CreatedAt.DeepCopyInto(&out.CreatedAt)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotEncryptionKey.
+func (in *ETCDSnapshotEncryptionKey) DeepCopy() *ETCDSnapshotEncryptionKey {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotEncryptionKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotGCS) DeepCopyInto(out *ETCDSnapshotGCS) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotGCS.
+func (in *ETCDSnapshotGCS) DeepCopy() *ETCDSnapshotGCS {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotGCS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotLoadGate) DeepCopyInto(out *ETCDSnapshotLoadGate) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotLoadGate.
+func (in *ETCDSnapshotLoadGate) DeepCopy() *ETCDSnapshotLoadGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotLoadGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotMachineProgress) DeepCopyInto(out *ETCDSnapshotMachineProgress) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotMachineProgress.
+func (in *ETCDSnapshotMachineProgress) DeepCopy() *ETCDSnapshotMachineProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotMachineProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotRestore) DeepCopyInto(out *ETCDSnapshotRestore) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotRestore.
+func (in *ETCDSnapshotRestore) DeepCopy() *ETCDSnapshotRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotS3) DeepCopyInto(out *ETCDSnapshotS3) {
+	*out = *in
+	if in.FallbackEndpoints != nil {
+		in, out := &in.FallbackEndpoints, &out.FallbackEndpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotS3.
+func (in *ETCDSnapshotS3) DeepCopy() *ETCDSnapshotS3 {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotS3)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotS3Retention) DeepCopyInto(out *ETCDSnapshotS3Retention) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotS3Retention.
+func (in *ETCDSnapshotS3Retention) DeepCopy() *ETCDSnapshotS3Retention {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotS3Retention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotSchedule) DeepCopyInto(out *ETCDSnapshotSchedule) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotSchedule.
+func (in *ETCDSnapshotSchedule) DeepCopy() *ETCDSnapshotSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotScheduleStatus) DeepCopyInto(out *ETCDSnapshotScheduleStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastCompletionTime != nil {
+		in, out := &in.LastCompletionTime, &out.LastCompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotScheduleStatus.
+func (in *ETCDSnapshotScheduleStatus) DeepCopy() *ETCDSnapshotScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotSpec) DeepCopyInto(out *ETCDSnapshotSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotSpec.
+func (in *ETCDSnapshotSpec) DeepCopy() *ETCDSnapshotSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotStatus) DeepCopyInto(out *ETCDSnapshotStatus) {
+	*out = *in
+	if in.Checksum != nil {
+		in, out := &in.Checksum, &out.Checksum
+		*out = new(ETCDSnapshotChecksum)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(ETCDSnapshotVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotStatus.
+func (in *ETCDSnapshotStatus) DeepCopy() *ETCDSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotUpload) DeepCopyInto(out *ETCDSnapshotUpload) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotUpload.
+func (in *ETCDSnapshotUpload) DeepCopy() *ETCDSnapshotUpload {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotUpload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotVerification) DeepCopyInto(out *ETCDSnapshotVerification) {
+	*out = *in
+	if in.VerifiedAt != nil {
+		in, out := &in.VerifiedAt, &out.VerifiedAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotVerification.
+func (in *ETCDSnapshotVerification) DeepCopy() *ETCDSnapshotVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDTuning) DeepCopyInto(out *ETCDTuning) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDTuning.
+func (in *ETCDTuning) DeepCopy() *ETCDTuning {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDTuning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvVar) DeepCopyInto(out *EnvVar) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvVar.
+func (in *EnvVar) DeepCopy() *EnvVar {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvVar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileIntegrity) DeepCopyInto(out *FileIntegrity) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileIntegrity.
+func (in *FileIntegrity) DeepCopy() *FileIntegrity {
+	if in == nil {
+		return nil
+	}
+	out := new(FileIntegrity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Firewall) DeepCopyInto(out *Firewall) {
+	*out = *in
+	if in.AdditionalPorts != nil {
+		in, out := &in.AdditionalPorts, &out.AdditionalPorts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Firewall.
+func (in *Firewall) DeepCopy() *Firewall {
+	if in == nil {
+		return nil
+	}
+	out := new(Firewall)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenericMap.
+func (in *GenericMap) DeepCopy() *GenericMap {
+	if in == nil {
+		return nil
+	}
+	out := new(GenericMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePolicy) DeepCopyInto(out *ImagePolicy) {
+	*out = *in
+	if in.AllowedRegistries != nil {
+		in, out := &in.AllowedRegistries, &out.AllowedRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePolicy.
+func (in *ImagePolicy) DeepCopy() *ImagePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstructionPolicy) DeepCopyInto(out *InstructionPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstructionPolicy.
+func (in *InstructionPolicy) DeepCopy() *InstructionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(InstructionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *InstructionPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstructionPolicyList) DeepCopyInto(out *InstructionPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]InstructionPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstructionPolicyList.
+func (in *InstructionPolicyList) DeepCopy() *InstructionPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(InstructionPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *InstructionPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstructionPolicySpec) DeepCopyInto(out *InstructionPolicySpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedCommands != nil {
+		in, out := &in.AllowedCommands, &out.AllowedCommands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedPaths != nil {
+		in, out := &in.AllowedPaths, &out.AllowedPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstructionPolicySpec.
+func (in *InstructionPolicySpec) DeepCopy() *InstructionPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InstructionPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K8sObjectFileSource) DeepCopyInto(out *K8sObjectFileSource) {
+	*out = *in
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KeyToPath, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sObjectFileSource.
+func (in *K8sObjectFileSource) DeepCopy() *K8sObjectFileSource {
+	if in == nil {
+		return nil
+	}
+	out := new(K8sObjectFileSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyToPath) DeepCopyInto(out *KeyToPath) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyToPath.
+func (in *KeyToPath) DeepCopy() *KeyToPath {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyToPath)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeProxyConfig) DeepCopyInto(out *KubeProxyConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeProxyConfig.
+func (in *KubeProxyConfig) DeepCopy() *KubeProxyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeProxyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubernetesVersionAutoUpgrade) DeepCopyInto(out *KubernetesVersionAutoUpgrade) {
+	*out = *in
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindow)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesVersionAutoUpgrade.
+func (in *KubernetesVersionAutoUpgrade) DeepCopy() *KubernetesVersionAutoUpgrade {
+	if in == nil {
+		return nil
+	}
+	out := new(KubernetesVersionAutoUpgrade)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubernetesVersionChannel) DeepCopyInto(out *KubernetesVersionChannel) {
+	*out = *in
+	if in.AutoUpgrade != nil {
+		in, out := &in.AutoUpgrade, &out.AutoUpgrade
+		*out = new(KubernetesVersionAutoUpgrade)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesVersionChannel.
+func (in *KubernetesVersionChannel) DeepCopy() *KubernetesVersionChannel {
+	if in == nil {
+		return nil
+	}
+	out := new(KubernetesVersionChannel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalClusterAuthEndpoint) DeepCopyInto(out *LocalClusterAuthEndpoint) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalClusterAuthEndpoint.
+func (in *LocalClusterAuthEndpoint) DeepCopy() *LocalClusterAuthEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalClusterAuthEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachineDeletionHook) DeepCopyInto(out *MachineDeletionHook) {
+	*out = *in
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(MachineDeletionHookHTTP)
+		**out = **in
+	}
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = new(MachineDeletionHookCommand)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineDeletionHook.
+func (in *MachineDeletionHook) DeepCopy() *MachineDeletionHook {
 	if in == nil {
 		return nil
 	}
-	out := new(ETCDSnapshotList)
+	out := new(MachineDeletionHook)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ETCDSnapshotList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ETCDSnapshotRestore) DeepCopyInto(out *ETCDSnapshotRestore) {
+func (in *MachineDeletionHookCommand) DeepCopyInto(out *MachineDeletionHookCommand) {
 	*out = *in
+	if in.MachineSelector != nil {
+		in, out := &in.MachineSelector, &out.MachineSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotRestore.
-func (in *ETCDSnapshotRestore) DeepCopy() *ETCDSnapshotRestore {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineDeletionHookCommand.
+func (in *MachineDeletionHookCommand) DeepCopy() *MachineDeletionHookCommand {
 	if in == nil {
 		return nil
 	}
-	out := new(ETCDSnapshotRestore)
+	out := new(MachineDeletionHookCommand)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ETCDSnapshotS3) DeepCopyInto(out *ETCDSnapshotS3) {
+func (in *MachineDeletionHookHTTP) DeepCopyInto(out *MachineDeletionHookHTTP) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotS3.
-func (in *ETCDSnapshotS3) DeepCopy() *ETCDSnapshotS3 {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineDeletionHookHTTP.
+func (in *MachineDeletionHookHTTP) DeepCopy() *MachineDeletionHookHTTP {
 	if in == nil {
 		return nil
 	}
-	out := new(ETCDSnapshotS3)
+	out := new(MachineDeletionHookHTTP)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ETCDSnapshotSpec) DeepCopyInto(out *ETCDSnapshotSpec) {
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
 	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Duration = in.Duration
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotSpec.
-func (in *ETCDSnapshotSpec) DeepCopy() *ETCDSnapshotSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
 	if in == nil {
 		return nil
 	}
-	out := new(ETCDSnapshotSpec)
+	out := new(MaintenanceWindow)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ETCDSnapshotStatus) DeepCopyInto(out *ETCDSnapshotStatus) {
+func (in *Mirror) DeepCopyInto(out *Mirror) {
 	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rewrites != nil {
+		in, out := &in.Rewrites, &out.Rewrites
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotStatus.
-func (in *ETCDSnapshotStatus) DeepCopy() *ETCDSnapshotStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Mirror.
+func (in *Mirror) DeepCopy() *Mirror {
 	if in == nil {
 		return nil
 	}
-	out := new(ETCDSnapshotStatus)
+	out := new(Mirror)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *EnvVar) DeepCopyInto(out *EnvVar) {
+func (in *NodeCommand) DeepCopyInto(out *NodeCommand) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvVar.
-func (in *EnvVar) DeepCopy() *EnvVar {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCommand.
+func (in *NodeCommand) DeepCopy() *NodeCommand {
 	if in == nil {
 		return nil
 	}
-	out := new(EnvVar)
+	out := new(NodeCommand)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenericMap.
-func (in *GenericMap) DeepCopy() *GenericMap {
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeCommand) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeCommandList) DeepCopyInto(out *NodeCommandList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NodeCommand, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCommandList.
+func (in *NodeCommandList) DeepCopy() *NodeCommandList {
 	if in == nil {
 		return nil
 	}
-	out := new(GenericMap)
+	out := new(NodeCommandList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeCommandList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *K8sObjectFileSource) DeepCopyInto(out *K8sObjectFileSource) {
+func (in *NodeCommandResult) DeepCopyInto(out *NodeCommandResult) {
 	*out = *in
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]KeyToPath, len(*in))
-		copy(*out, *in)
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sObjectFileSource.
-func (in *K8sObjectFileSource) DeepCopy() *K8sObjectFileSource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCommandResult.
+func (in *NodeCommandResult) DeepCopy() *NodeCommandResult {
 	if in == nil {
 		return nil
 	}
-	out := new(K8sObjectFileSource)
+	out := new(NodeCommandResult)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KeyToPath) DeepCopyInto(out *KeyToPath) {
+func (in *NodeCommandSpec) DeepCopyInto(out *NodeCommandSpec) {
 	*out = *in
+	if in.MachineSelector != nil {
+		in, out := &in.MachineSelector, &out.MachineSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int64)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyToPath.
-func (in *KeyToPath) DeepCopy() *KeyToPath {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCommandSpec.
+func (in *NodeCommandSpec) DeepCopy() *NodeCommandSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(KeyToPath)
+	out := new(NodeCommandSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LocalClusterAuthEndpoint) DeepCopyInto(out *LocalClusterAuthEndpoint) {
+func (in *NodeCommandStatus) DeepCopyInto(out *NodeCommandStatus) {
 	*out = *in
+	if in.ValidatedSpec != nil {
+		in, out := &in.ValidatedSpec, &out.ValidatedSpec
+		*out = new(NodeCommandSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.TargetMachines != nil {
+		in, out := &in.TargetMachines, &out.TargetMachines
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]NodeCommandResult, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalClusterAuthEndpoint.
-func (in *LocalClusterAuthEndpoint) DeepCopy() *LocalClusterAuthEndpoint {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCommandStatus.
+func (in *NodeCommandStatus) DeepCopy() *NodeCommandStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(LocalClusterAuthEndpoint)
+	out := new(NodeCommandStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Mirror) DeepCopyInto(out *Mirror) {
+func (in *ProbeCustomization) DeepCopyInto(out *ProbeCustomization) {
 	*out = *in
-	if in.Endpoints != nil {
-		in, out := &in.Endpoints, &out.Endpoints
+	if in.Probes != nil {
+		in, out := &in.Probes, &out.Probes
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.Rewrites != nil {
-		in, out := &in.Rewrites, &out.Rewrites
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
 		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
@@ -498,12 +1701,12 @@ func (in *Mirror) DeepCopyInto(out *Mirror) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Mirror.
-func (in *Mirror) DeepCopy() *Mirror {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeCustomization.
+func (in *ProbeCustomization) DeepCopy() *ProbeCustomization {
 	if in == nil {
 		return nil
 	}
-	out := new(Mirror)
+	out := new(ProbeCustomization)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -813,6 +2016,65 @@ func (in *RKEClusterSpecCommon) DeepCopyInto(out *RKEClusterSpecCommon) {
 		*out = new(ETCD)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.KubernetesVersionChannel != nil {
+		in, out := &in.KubernetesVersionChannel, &out.KubernetesVersionChannel
+		*out = new(KubernetesVersionChannel)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProbeCustomizations != nil {
+		in, out := &in.ProbeCustomizations, &out.ProbeCustomizations
+		*out = make([]ProbeCustomization, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CoreDNS != nil {
+		in, out := &in.CoreDNS, &out.CoreDNS
+		*out = new(CoreDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KubeProxy != nil {
+		in, out := &in.KubeProxy, &out.KubeProxy
+		*out = new(KubeProxyConfig)
+		**out = **in
+	}
+	if in.ImagePolicy != nil {
+		in, out := &in.ImagePolicy, &out.ImagePolicy
+		*out = new(ImagePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Diagnostics != nil {
+		in, out := &in.Diagnostics, &out.Diagnostics
+		*out = new(Diagnostics)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BreakGlassAccess != nil {
+		in, out := &in.BreakGlassAccess, &out.BreakGlassAccess
+		*out = new(BreakGlassAccess)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Firewall != nil {
+		in, out := &in.Firewall, &out.Firewall
+		*out = new(Firewall)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Clock != nil {
+		in, out := &in.Clock, &out.Clock
+		*out = new(Clock)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FileIntegrity != nil {
+		in, out := &in.FileIntegrity, &out.FileIntegrity
+		*out = new(FileIntegrity)
+		**out = **in
+	}
+	if in.MachineDeletionHooks != nil {
+		in, out := &in.MachineDeletionHooks, &out.MachineDeletionHooks
+		*out = make([]MachineDeletionHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -951,13 +2213,23 @@ func (in *RKEControlPlaneSpec) DeepCopyInto(out *RKEControlPlaneSpec) {
 	if in.ETCDSnapshotCreate != nil {
 		in, out := &in.ETCDSnapshotCreate, &out.ETCDSnapshotCreate
 		*out = new(ETCDSnapshotCreate)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.ETCDSnapshotRestore != nil {
 		in, out := &in.ETCDSnapshotRestore, &out.ETCDSnapshotRestore
 		*out = new(ETCDSnapshotRestore)
 		**out = **in
 	}
+	if in.ETCDSnapshotUpload != nil {
+		in, out := &in.ETCDSnapshotUpload, &out.ETCDSnapshotUpload
+		*out = new(ETCDSnapshotUpload)
+		**out = **in
+	}
+	if in.ETCDSnapshotSchedule != nil {
+		in, out := &in.ETCDSnapshotSchedule, &out.ETCDSnapshotSchedule
+		*out = new(ETCDSnapshotSchedule)
+		**out = **in
+	}
 	if in.RotateCertificates != nil {
 		in, out := &in.RotateCertificates, &out.RotateCertificates
 		*out = new(RotateCertificates)
@@ -1007,8 +2279,42 @@ func (in *RKEControlPlaneStatus) DeepCopyInto(out *RKEControlPlaneStatus) {
 	if in.ETCDSnapshotCreate != nil {
 		in, out := &in.ETCDSnapshotCreate, &out.ETCDSnapshotCreate
 		*out = new(ETCDSnapshotCreate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ETCDSnapshotUpload != nil {
+		in, out := &in.ETCDSnapshotUpload, &out.ETCDSnapshotUpload
+		*out = new(ETCDSnapshotUpload)
 		**out = **in
 	}
+	if in.ETCDSnapshotEncryptionKeys != nil {
+		in, out := &in.ETCDSnapshotEncryptionKeys, &out.ETCDSnapshotEncryptionKeys
+		*out = make([]ETCDSnapshotEncryptionKey, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ETCDSnapshotSchedule != nil {
+		in, out := &in.ETCDSnapshotSchedule, &out.ETCDSnapshotSchedule
+		*out = new(ETCDSnapshotScheduleStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BreakGlassAccessChanges != nil {
+		in, out := &in.BreakGlassAccessChanges, &out.BreakGlassAccessChanges
+		*out = make([]BreakGlassAccessChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UpgradeCanary != nil {
+		in, out := &in.UpgradeCanary, &out.UpgradeCanary
+		*out = new(UpgradeCanaryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpgradePrewarm != nil {
+		in, out := &in.UpgradePrewarm, &out.UpgradePrewarm
+		*out = new(UpgradePrewarmStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1185,3 +2491,111 @@ func (in *RotateEncryptionKeys) DeepCopy() *RotateEncryptionKeys {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeCanary) DeepCopyInto(out *UpgradeCanary) {
+	*out = *in
+	if in.SoakTime != nil {
+		in, out := &in.SoakTime, &out.SoakTime
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeCanary.
+func (in *UpgradeCanary) DeepCopy() *UpgradeCanary {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeCanary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeCanaryMachine) DeepCopyInto(out *UpgradeCanaryMachine) {
+	*out = *in
+	if in.SoakStartTime != nil {
+		in, out := &in.SoakStartTime, &out.SoakStartTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeCanaryMachine.
+func (in *UpgradeCanaryMachine) DeepCopy() *UpgradeCanaryMachine {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeCanaryMachine)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeCanaryStatus) DeepCopyInto(out *UpgradeCanaryStatus) {
+	*out = *in
+	if in.ControlPlane != nil {
+		in, out := &in.ControlPlane, &out.ControlPlane
+		*out = new(UpgradeCanaryMachine)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Worker != nil {
+		in, out := &in.Worker, &out.Worker
+		*out = new(UpgradeCanaryMachine)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeCanaryStatus.
+func (in *UpgradeCanaryStatus) DeepCopy() *UpgradeCanaryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeCanaryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradePrewarm) DeepCopyInto(out *UpgradePrewarm) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradePrewarm.
+func (in *UpgradePrewarm) DeepCopy() *UpgradePrewarm {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradePrewarm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradePrewarmStatus) DeepCopyInto(out *UpgradePrewarmStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradePrewarmStatus.
+func (in *UpgradePrewarmStatus) DeepCopy() *UpgradePrewarmStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradePrewarmStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -26,6 +26,23 @@
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// ApprovalList is a list of Approval resources
+type ApprovalList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []Approval `json:"items"`
+}
+
+func NewApproval(namespace, name string, obj Approval) *Approval {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("Approval").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // CustomMachineList is a list of CustomMachine resources
 type CustomMachineList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -60,6 +77,57 @@ func NewETCDSnapshot(namespace, name string, obj ETCDSnapshot) *ETCDSnapshot {
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// ETCDSnapshotDrillList is a list of ETCDSnapshotDrill resources
+type ETCDSnapshotDrillList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ETCDSnapshotDrill `json:"items"`
+}
+
+func NewETCDSnapshotDrill(namespace, name string, obj ETCDSnapshotDrill) *ETCDSnapshotDrill {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("ETCDSnapshotDrill").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// InstructionPolicyList is a list of InstructionPolicy resources
+type InstructionPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []InstructionPolicy `json:"items"`
+}
+
+func NewInstructionPolicy(namespace, name string, obj InstructionPolicy) *InstructionPolicy {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("InstructionPolicy").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeCommandList is a list of NodeCommand resources
+type NodeCommandList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []NodeCommand `json:"items"`
+}
+
+func NewNodeCommand(namespace, name string, obj NodeCommand) *NodeCommand {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("NodeCommand").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // RKEBootstrapList is a list of RKEBootstrap resources
 type RKEBootstrapList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -28,8 +28,12 @@
 )
 
 var (
+	ApprovalResourceName             = "approvals"
 	CustomMachineResourceName        = "custommachines"
 	ETCDSnapshotResourceName         = "etcdsnapshots"
+	ETCDSnapshotDrillResourceName    = "etcdsnapshotdrills"
+	InstructionPolicyResourceName    = "instructionpolicies"
+	NodeCommandResourceName          = "nodecommands"
 	RKEBootstrapResourceName         = "rkebootstraps"
 	RKEBootstrapTemplateResourceName = "rkebootstraptemplates"
 	RKEClusterResourceName           = "rkeclusters"
@@ -57,10 +61,18 @@ func Resource(resource string) schema.GroupResource {
 // Adds the list of known types to Scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&Approval{},
+		&ApprovalList{},
 		&CustomMachine{},
 		&CustomMachineList{},
 		&ETCDSnapshot{},
 		&ETCDSnapshotList{},
+		&ETCDSnapshotDrill{},
+		&ETCDSnapshotDrillList{},
+		&InstructionPolicy{},
+		&InstructionPolicyList{},
+		&NodeCommand{},
+		&NodeCommandList{},
 		&RKEBootstrap{},
 		&RKEBootstrapList{},
 		&RKEBootstrapTemplate{},
@@ -46,6 +46,7 @@
 	ClusterSpecAnnotation         = "rke.cattle.io/cluster-spec"
 	ControlPlaneRoleLabel         = "rke.cattle.io/control-plane-role"
 	DrainAnnotation               = "rke.cattle.io/drain-options"
+	DrainBlockedByAnnotation      = "rke.cattle.io/drain-blocked-by"
 	DrainDoneAnnotation           = "rke.cattle.io/drain-done"
 	DrainErrorAnnotation          = "rke.cattle.io/drain-error"
 	EtcdRoleLabel                 = "rke.cattle.io/etcd-role"
@@ -61,6 +62,8 @@
 	MachineNameLabel              = "rke.cattle.io/machine-name"
 	MachineTemplateHashLabel      = "rke.cattle.io/machine-template-hash"
 	RKEMachinePoolNameLabel       = "rke.cattle.io/rke-machine-pool-name"
+	RKEMachinePoolZoneLabel       = "rke.cattle.io/rke-machine-pool-zone"
+	RKEBootstrapNameLabel         = "rke.cattle.io/rkebootstrap-name"
 	MachineNamespaceLabel         = "rke.cattle.io/machine-namespace"
 	MachineRequestType            = "rke.cattle.io/machine-request"
 	MachineUIDLabel               = "rke.cattle.io/machine"
@@ -74,8 +77,52 @@
 	WorkerRoleLabel               = "rke.cattle.io/worker-role"
 	AuthorizedObjectAnnotation    = "rke.cattle.io/object-authorized-for-clusters"
 
-	SecretTypeMachinePlan  = "rke.cattle.io/machine-plan"
-	SecretTypeClusterState = "rke.cattle.io/cluster-state"
+	// CollectDiagnosticsAnnotation requests a diagnostics bundle of the machine it is set on. A bundle is collected for
+	// every new value of the annotation, which is recorded in the DiagnosticsCollectedAnnotation once the bundle was
+	// stored. Removing the annotation cancels a collection that did not finish.
+	CollectDiagnosticsAnnotation   = "rke.cattle.io/collect-diagnostics"
+	DiagnosticsCollectedAnnotation = "rke.cattle.io/diagnostics-collected"
+
+	// FileIntegrityRestoredAnnotation records on a machine plan secret the last successful run of the file integrity
+	// instruction whose drift was restored by applying the plan again, so that the plan is applied again only once for
+	// each run.
+	FileIntegrityRestoredAnnotation = "rke.cattle.io/file-integrity-restored"
+
+	// MaintenanceWindowAnnotation restricts disruptive plan changes of a machine, such as restarts and upgrades, to a
+	// maintenance window, given as JSON, i.e. {"days":["Sat"],"start":"02:00","duration":"4h"} for four hours from 02:00
+	// UTC on Saturdays. It is set on machines, or on a machine pool through its machine deployment annotations.
+	MaintenanceWindowAnnotation = "rke.cattle.io/maintenance-window"
+
+	// MachineDeletionHooksAnnotation records the progress of the deletion hooks of a deleting machine as JSON, keyed by
+	// the names of the hooks.
+	MachineDeletionHooksAnnotation = "rke.cattle.io/machine-deletion-hooks"
+	// MachineDeletionHookLabel marks the token secret of an HTTP machine deletion hook with the name of the hook. Only
+	// secrets carrying it and the ClusterNameLabel of the cluster of the hook are sent to the URL of the hook.
+	MachineDeletionHookLabel = "rke.cattle.io/machine-deletion-hook"
+
+	// CloudCredentialAllowedClustersAnnotation restricts the clusters that may use a cloud credential to a comma
+	// separated list of <namespace>/<cluster name> entries, where a cluster name of * allows every cluster in the
+	// namespace. Cloud credentials without the annotation may be used by any cluster.
+	CloudCredentialAllowedClustersAnnotation = "provisioning.cattle.io/allowed-clusters"
+
+	// RemoveExternallyDeletedMachinesAnnotation opts a cluster in to deleting worker machines provisioned by node drivers
+	// whose nodes were removed from the cluster outside of Rancher, i.e. by deleting the virtual machine in the console
+	// of the cloud provider.
+	RemoveExternallyDeletedMachinesAnnotation = "provisioning.cattle.io/remove-externally-deleted-machines"
+
+	// CloudCredentialSecretStoreAnnotation refers to the data of a cloud credential in an external secret store with a
+	// reference of the form <provider>:<path>, i.e. "vault:kv/data/cloud/aws". The data is read from the store when
+	// machines are provisioned or etcd snapshots are encrypted with the cloud credential, and takes precedence over the
+	// data of the secret of the cloud credential. The path must be allowed for the namespace of the cloud credential by
+	// the secret-store-allowed-paths setting.
+	CloudCredentialSecretStoreAnnotation = "provisioning.cattle.io/secret-store-ref"
+
+	SecretTypeMachinePlan   = "rke.cattle.io/machine-plan"
+	SecretTypeClusterState  = "rke.cattle.io/cluster-state"
+	SecretTypeMachineState  = "rke.cattle.io/machine-state"
+	SecretTypeBootstrap     = "rke.cattle.io/bootstrap"
+	SecretTypeDiagnostics   = "rke.cattle.io/diagnostics"
+	SecretTypeRenderedPlans = "rke.cattle.io/rendered-plans"
 
 	MachineTemplateClonedFromGroupVersionAnn = "rke.cattle.io/cloned-from-group-version"
 	MachineTemplateClonedFromKindAnn         = "rke.cattle.io/cloned-from-kind"
@@ -98,9 +145,26 @@
 	Pending                      = condition.Cond("Pending")
 	Removed                      = condition.Cond("Removed")
 	PlanApplied                  = condition.Cond("PlanApplied")
+	PlanConverged                = condition.Cond("PlanConverged")
 	InfrastructureReady          = condition.Cond(capi.InfrastructureReadyCondition)
 	SystemUpgradeControllerReady = condition.Cond("SystemUpgradeControllerReady")
 	Bootstrapped                 = condition.Cond("Bootstrapped")
+	ETCDSnapshotCompatible       = condition.Cond("ETCDSnapshotCompatible")
+	Validated                    = condition.Cond("Validated")
+	ImagesAllowed                = condition.Cond("ImagesAllowed")
+	SnapshotOverdue              = condition.Cond("SnapshotOverdue")
+	UpgradeFailureBudgetExceeded = condition.Cond("UpgradeFailureBudgetExceeded")
+	DiagnosticsCollected         = condition.Cond("DiagnosticsCollected")
+	ETCDSnapshotRestoreApproved  = condition.Cond("ETCDSnapshotRestoreApproved")
+	ClocksSynchronized           = condition.Cond("ClocksSynchronized")
+	PlansRendered                = condition.Cond("PlansRendered")
+	SanityProbesPassed           = condition.Cond("SanityProbesPassed")
+	DrainBlocked                 = condition.Cond("DrainBlocked")
+	ManagedFilesIntact           = condition.Cond("ManagedFilesIntact")
+	MaintenanceWindowQueued      = condition.Cond("MaintenanceWindowQueued")
+	StorageVersionsMigrated      = condition.Cond("StorageVersionsMigrated")
+	InstructionsAllowed          = condition.Cond("InstructionsAllowed")
+	FileSourcesResolved          = condition.Cond("FileSourcesResolved")
 
 	RuntimeK3S  = "k3s"
 	RuntimeRKE2 = "rke2"
@@ -585,14 +649,10 @@ func ParseSnapshotClusterSpecOrError(snapshot *rkev1.ETCDSnapshot) (*provv1.Clus
 		return nil, fmt.Errorf("snapshot was nil")
 	}
 	if snapshot.SnapshotFile.Metadata != "" {
-		var md map[string]string
-		b, err := base64.StdEncoding.DecodeString(snapshot.SnapshotFile.Metadata)
+		md, err := ParseSnapshotMetadata(snapshot)
 		if err != nil {
 			return nil, err
 		}
-		if err := json.Unmarshal(b, &md); err != nil {
-			return nil, err
-		}
 		if v, ok := md["provisioning-cluster-spec"]; ok {
 			return DecompressClusterSpec(v)
 		}
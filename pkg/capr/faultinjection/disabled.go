@@ -0,0 +1,9 @@
+//go:build !faultinjection
+// +build !faultinjection
+
+package faultinjection
+
+// active is constant in builds without the faultinjection build tag, so that the hooks are compiled out.
+func active() bool {
+	return false
+}
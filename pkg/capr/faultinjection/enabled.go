@@ -0,0 +1,10 @@
+//go:build faultinjection
+// +build faultinjection
+
+package faultinjection
+
+import "github.com/rancher/rancher/pkg/features"
+
+func active() bool {
+	return features.PlannerFaultInjection.Enabled()
+}
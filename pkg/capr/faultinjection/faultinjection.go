@@ -0,0 +1,150 @@
+// Package faultinjection injects synthetic failures into the planner for resilience testing. Faults are only injected
+// when rancher is built with the faultinjection build tag and the planner-fault-injection feature is enabled. In any
+// other build the hooks of this package do nothing. The faults of a cluster are configured with the FaultsAnnotation on
+// its RKEControlPlane.
+package faultinjection
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/sirupsen/logrus"
+)
+
+// FaultsAnnotation holds the JSON encoded Faults to inject into the planner for the cluster of the RKEControlPlane.
+const FaultsAnnotation = "rke.cattle.io/inject-faults"
+
+type Faults struct {
+	// PlanApplyFailure reports the plans of the matching machines as failed.
+	PlanApplyFailure *Fault `json:"planApplyFailure,omitempty"`
+	// AgentResponseDelay withholds that the plans of the matching machines were applied until the delay of the fault
+	// passed since they were applied.
+	AgentResponseDelay *Fault `json:"agentResponseDelay,omitempty"`
+	// ETCDSnapshotFailure fails the creation of etcd snapshots on the matching machines.
+	ETCDSnapshotFailure *Fault `json:"etcdSnapshotFailure,omitempty"`
+}
+
+type Fault struct {
+	// Machines are the names of the machines the fault is injected for. If empty, it is injected for every machine.
+	Machines []string `json:"machines,omitempty"`
+	// Delay is the duration of the fault, if it has one, i.e. "30s".
+	Delay string `json:"delay,omitempty"`
+}
+
+var (
+	// appliedAt records when the plan of a machine was first seen applied, keyed by the namespace and name of the
+	// machine, so that delays are measured from the time of the agent response that is withheld.
+	appliedAt     = map[string]appliedPlan{}
+	appliedAtLock sync.Mutex
+)
+
+type appliedPlan struct {
+	plan *plan.NodePlan
+	at   time.Time
+}
+
+// ApplyToNode injects the plan apply failure and agent response delay faults of the cluster into the plan of the
+// machine, as loaded from its plan secret.
+func ApplyToNode(controlPlane *rkev1.RKEControlPlane, machineName string, node *plan.Node) {
+	if !active() || node == nil {
+		return
+	}
+	faults := parse(controlPlane)
+	applyToNode(faults, controlPlane.Namespace, machineName, node, time.Now())
+}
+
+// ETCDSnapshotFailure returns an error if the etcd snapshot creation on the machine must fail.
+func ETCDSnapshotFailure(controlPlane *rkev1.RKEControlPlane, machineName string) error {
+	if !active() {
+		return nil
+	}
+	if faults := parse(controlPlane); faults.ETCDSnapshotFailure.matches(machineName) {
+		return fmt.Errorf("injected fault: etcd snapshot on machine %s/%s failed", controlPlane.Namespace, machineName)
+	}
+	return nil
+}
+
+// RequeueAfter returns how long to wait before processing the control plane again, so that the plans withheld by an
+// agent response delay are picked up once the delay passed.
+func RequeueAfter(controlPlane *rkev1.RKEControlPlane) time.Duration {
+	if !active() {
+		return 0
+	}
+	delay, _ := parse(controlPlane).AgentResponseDelay.delay()
+	return delay
+}
+
+func applyToNode(faults Faults, namespace, machineName string, node *plan.Node, now time.Time) {
+	if faults.PlanApplyFailure.matches(machineName) && node.PlanDataExists {
+		node.Failed = true
+		node.InSync = false
+	}
+
+	key := namespace + "/" + machineName
+	delay, err := faults.AgentResponseDelay.delay()
+	if err != nil || delay <= 0 || !faults.AgentResponseDelay.matches(machineName) || !node.InSync {
+		appliedAtLock.Lock()
+		delete(appliedAt, key)
+		appliedAtLock.Unlock()
+		return
+	}
+
+	appliedAtLock.Lock()
+	defer appliedAtLock.Unlock()
+	applied, ok := appliedAt[key]
+	if !ok || applied.plan == nil || !equalPlans(applied.plan, node.AppliedPlan) {
+		applied = appliedPlan{plan: node.AppliedPlan, at: now}
+		appliedAt[key] = applied
+	}
+	if now.Before(applied.at.Add(delay)) {
+		node.InSync = false
+	}
+}
+
+func parse(controlPlane *rkev1.RKEControlPlane) Faults {
+	var faults Faults
+	value := controlPlane.Annotations[FaultsAnnotation]
+	if value == "" {
+		return faults
+	}
+	if err := json.Unmarshal([]byte(value), &faults); err != nil {
+		logrus.Warnf("[faultinjection] rkecluster %s/%s: ignoring invalid %s annotation: %v", controlPlane.Namespace, controlPlane.Name, FaultsAnnotation, err)
+		return Faults{}
+	}
+	return faults
+}
+
+func (f *Fault) matches(machineName string) bool {
+	if f == nil {
+		return false
+	}
+	if len(f.Machines) == 0 {
+		return true
+	}
+	for _, name := range f.Machines {
+		if name == machineName {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Fault) delay() (time.Duration, error) {
+	if f == nil || f.Delay == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(f.Delay)
+}
+
+func equalPlans(a, b *plan.NodePlan) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	aData, _ := json.Marshal(a)
+	bData, _ := json.Marshal(b)
+	return string(aData) == string(bData)
+}
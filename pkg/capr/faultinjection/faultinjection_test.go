@@ -0,0 +1,80 @@
+package faultinjection
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParse(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				FaultsAnnotation: `{"planApplyFailure":{"machines":["m-1"]},"agentResponseDelay":{"delay":"30s"}}`,
+			},
+		},
+	}
+	faults := parse(controlPlane)
+	assert.True(t, faults.PlanApplyFailure.matches("m-1"))
+	assert.False(t, faults.PlanApplyFailure.matches("m-2"))
+	assert.True(t, faults.AgentResponseDelay.matches("m-2"))
+	assert.False(t, faults.ETCDSnapshotFailure.matches("m-1"))
+
+	controlPlane.Annotations[FaultsAnnotation] = "invalid"
+	assert.Equal(t, Faults{}, parse(controlPlane))
+}
+
+func TestApplyToNode(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	appliedPlan := &plan.NodePlan{Files: []plan.File{{Path: "/etc/config.yaml"}}}
+
+	t.Run("plan apply failure", func(t *testing.T) {
+		node := &plan.Node{PlanDataExists: true, InSync: true}
+		applyToNode(Faults{PlanApplyFailure: &Fault{}}, "fleet-default", "m-1", node, now)
+		assert.True(t, node.Failed)
+		assert.False(t, node.InSync)
+	})
+
+	t.Run("agent response delay", func(t *testing.T) {
+		faults := Faults{AgentResponseDelay: &Fault{Machines: []string{"m-2"}, Delay: "1m"}}
+
+		node := &plan.Node{PlanDataExists: true, InSync: true, AppliedPlan: appliedPlan}
+		applyToNode(faults, "fleet-default", "m-2", node, now)
+		assert.False(t, node.InSync, "applied plan is withheld until the delay passed")
+
+		node = &plan.Node{PlanDataExists: true, InSync: true, AppliedPlan: appliedPlan}
+		applyToNode(faults, "fleet-default", "m-2", node, now.Add(time.Minute))
+		assert.True(t, node.InSync, "applied plan is reported once the delay passed")
+
+		node = &plan.Node{PlanDataExists: true, InSync: true, AppliedPlan: &plan.NodePlan{}}
+		applyToNode(faults, "fleet-default", "m-2", node, now.Add(time.Minute))
+		assert.False(t, node.InSync, "a newly applied plan is withheld again")
+
+		node = &plan.Node{PlanDataExists: true, InSync: true, AppliedPlan: appliedPlan}
+		applyToNode(faults, "fleet-default", "m-3", node, now)
+		assert.True(t, node.InSync, "other machines are not delayed")
+	})
+}
+
+func TestHooksInactive(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				FaultsAnnotation: `{"planApplyFailure":{},"agentResponseDelay":{"delay":"30s"},"etcdSnapshotFailure":{}}`,
+			},
+		},
+	}
+	if active() {
+		t.Skip("fault injection is active")
+	}
+
+	node := &plan.Node{PlanDataExists: true, InSync: true}
+	ApplyToNode(controlPlane, "m-1", node)
+	assert.False(t, node.Failed)
+	assert.NoError(t, ETCDSnapshotFailure(controlPlane, "m-1"))
+	assert.Zero(t, RequeueAfter(controlPlane))
+}
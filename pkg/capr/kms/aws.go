@@ -0,0 +1,72 @@
+package kms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+)
+
+// awsKMS wraps data keys with a symmetric key of AWS KMS. AWS KMS does not expose the versions of the backing keys of a
+// rotated key, the ciphertext identifies the backing key itself. The ARN of the key is recorded as the key version.
+type awsKMS struct {
+	keyName string
+	client  *kms.KMS
+}
+
+func newAWSKMS(encryption *rkev1.ETCDSnapshotEncryption, credentialData map[string][]byte) (*awsKMS, error) {
+	region := encryption.Region
+	if region == "" {
+		region = string(credentialData["defaultRegion"])
+	}
+	config := &aws.Config{
+		Region: aws.String(region),
+	}
+	if accessKey := string(credentialData["accessKey"]); accessKey != "" {
+		config.Credentials = credentials.NewStaticCredentials(accessKey, string(credentialData["secretKey"]), "")
+	}
+	if encryption.Endpoint != "" {
+		config.Endpoint = aws.String(encryption.Endpoint)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session for etcd snapshot encryption: %w", err)
+	}
+	return &awsKMS{
+		keyName: encryption.KeyName,
+		client:  kms.New(sess),
+	}, nil
+}
+
+func (a *awsKMS) Wrap(ctx context.Context, plaintext []byte) (WrappedKey, error) {
+	output, err := a.client.EncryptWithContext(ctx, &kms.EncryptInput{
+		KeyId:     aws.String(a.keyName),
+		Plaintext: plaintext,
+	})
+	if err != nil {
+		return WrappedKey{}, err
+	}
+	return WrappedKey{
+		KeyVersion: aws.StringValue(output.KeyId),
+		Ciphertext: output.CiphertextBlob,
+	}, nil
+}
+
+func (a *awsKMS) Unwrap(ctx context.Context, key WrappedKey) ([]byte, error) {
+	keyID := key.KeyVersion
+	if keyID == "" {
+		keyID = a.keyName
+	}
+	output, err := a.client.DecryptWithContext(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(keyID),
+		CiphertextBlob: key.Ciphertext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Plaintext, nil
+}
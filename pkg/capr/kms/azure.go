@@ -0,0 +1,89 @@
+package kms
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/v7.1/keyvault"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+)
+
+const azureKeyVaultResource = "https://vault.azure.net"
+
+// azureKeyVault wraps data keys with an RSA key of Azure Key Vault. The version of the key that wrapped a data key is
+// the last segment of the key identifier returned by Key Vault.
+type azureKeyVault struct {
+	vaultURL string
+	keyName  string
+	client   keyvault.BaseClient
+}
+
+func newAzureKeyVault(encryption *rkev1.ETCDSnapshotEncryption, credentials map[string][]byte) (*azureKeyVault, error) {
+	if encryption.Endpoint == "" {
+		return nil, fmt.Errorf("the URL of the key vault must be set as the endpoint of the etcd snapshot encryption")
+	}
+	tenantID, clientID, clientSecret := string(credentials["tenantId"]), string(credentials["clientId"]), string(credentials["clientSecret"])
+	if tenantID == "" || clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("the cloud credential of the etcd snapshot encryption must contain the tenantId, clientId and clientSecret of a service principal")
+	}
+	oauthConfig, err := adal.NewOAuthConfig(azure.PublicCloud.ActiveDirectoryEndpoint, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := adal.NewServicePrincipalToken(*oauthConfig, clientID, clientSecret, azureKeyVaultResource)
+	if err != nil {
+		return nil, err
+	}
+	client := keyvault.New()
+	client.Authorizer = autorest.NewBearerAuthorizer(token)
+	return &azureKeyVault{
+		vaultURL: strings.TrimSuffix(encryption.Endpoint, "/"),
+		keyName:  encryption.KeyName,
+		client:   client,
+	}, nil
+}
+
+func (a *azureKeyVault) Wrap(ctx context.Context, plaintext []byte) (WrappedKey, error) {
+	result, err := a.client.WrapKey(ctx, a.vaultURL, a.keyName, "", keyvault.KeyOperationsParameters{
+		Algorithm: keyvault.RSAOAEP256,
+		Value:     stringPtr(base64.RawURLEncoding.EncodeToString(plaintext)),
+	})
+	if err != nil {
+		return WrappedKey{}, err
+	}
+	if result.Result == nil || result.Kid == nil {
+		return WrappedKey{}, fmt.Errorf("key vault did not return the wrapped data key")
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(*result.Result)
+	if err != nil {
+		return WrappedKey{}, err
+	}
+	return WrappedKey{
+		KeyVersion: path.Base(*result.Kid),
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+func (a *azureKeyVault) Unwrap(ctx context.Context, key WrappedKey) ([]byte, error) {
+	result, err := a.client.UnwrapKey(ctx, a.vaultURL, a.keyName, key.KeyVersion, keyvault.KeyOperationsParameters{
+		Algorithm: keyvault.RSAOAEP256,
+		Value:     stringPtr(base64.RawURLEncoding.EncodeToString(key.Ciphertext)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Result == nil {
+		return nil, fmt.Errorf("key vault did not return the unwrapped data key")
+	}
+	return base64.RawURLEncoding.DecodeString(*result.Result)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,71 @@
+// Package kms wraps the data keys that etcd snapshots are encrypted with using the key management service of a cloud
+// provider, so that only the wrapped data keys need to be stored and the key encryption keys never leave the KMS.
+package kms
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+)
+
+const (
+	ProviderVault         = "vault"
+	ProviderAWSKMS        = "awskms"
+	ProviderAzureKeyVault = "azurekeyvault"
+
+	dataKeyBytes = 32
+)
+
+// WrappedKey is a data key encrypted by a KMS, along with the version of the key encryption key that encrypted it.
+type WrappedKey struct {
+	KeyVersion string
+	Ciphertext []byte
+}
+
+// Provider encrypts and decrypts data keys with a key encryption key held by a KMS. Providers keep the previous
+// versions of rotated keys, so data keys wrapped by an earlier version can still be unwrapped.
+type Provider interface {
+	Wrap(ctx context.Context, plaintext []byte) (WrappedKey, error)
+	Unwrap(ctx context.Context, key WrappedKey) ([]byte, error)
+}
+
+// New returns the provider of the encryption settings. credentials is the data of the cloud credential secret of the
+// settings, with the prefix of the keys removed.
+func New(encryption *rkev1.ETCDSnapshotEncryption, credentials map[string][]byte) (Provider, error) {
+	if encryption.KeyName == "" {
+		return nil, fmt.Errorf("etcd snapshot encryption key name must be set")
+	}
+	switch encryption.Provider {
+	case ProviderVault:
+		return newVault(encryption, credentials)
+	case ProviderAWSKMS:
+		return newAWSKMS(encryption, credentials)
+	case ProviderAzureKeyVault:
+		return newAzureKeyVault(encryption, credentials)
+	}
+	return nil, fmt.Errorf("unsupported etcd snapshot encryption provider %q, must be one of %s, %s or %s", encryption.Provider,
+		ProviderVault, ProviderAWSKMS, ProviderAzureKeyVault)
+}
+
+// GenerateDataKey generates a random data key and wraps it with the provider.
+func GenerateDataKey(ctx context.Context, provider Provider) ([]byte, WrappedKey, error) {
+	plaintext := make([]byte, dataKeyBytes)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, WrappedKey{}, err
+	}
+	wrapped, err := provider.Wrap(ctx, plaintext)
+	if err != nil {
+		return nil, WrappedKey{}, fmt.Errorf("failed to wrap etcd snapshot data key: %w", err)
+	}
+	return plaintext, wrapped, nil
+}
+
+// KeyID returns the ID of a wrapped data key, which is recorded in the names of the snapshots it encrypted.
+func KeyID(key WrappedKey) string {
+	sum := sha256.Sum256(key.Ciphertext)
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,105 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+)
+
+const defaultVaultTransitMount = "transit"
+
+// vault wraps data keys with a key of the transit secrets engine of HashiCorp Vault. The ciphertext of the transit
+// engine is prefixed with the version of the key that encrypted it, i.e. "vault:v3:...".
+type vault struct {
+	address string
+	mount   string
+	keyName string
+	token   string
+	client  *http.Client
+}
+
+func newVault(encryption *rkev1.ETCDSnapshotEncryption, credentials map[string][]byte) (*vault, error) {
+	if encryption.Endpoint == "" {
+		return nil, fmt.Errorf("the address of vault must be set as the endpoint of the etcd snapshot encryption")
+	}
+	token := string(credentials["token"])
+	if token == "" {
+		return nil, fmt.Errorf("the cloud credential of the etcd snapshot encryption does not contain a vault token")
+	}
+	mount := encryption.VaultTransitMount
+	if mount == "" {
+		mount = defaultVaultTransitMount
+	}
+	return &vault{
+		address: strings.TrimSuffix(encryption.Endpoint, "/"),
+		mount:   strings.Trim(mount, "/"),
+		keyName: encryption.KeyName,
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (v *vault) Wrap(ctx context.Context, plaintext []byte) (WrappedKey, error) {
+	var response struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	err := v.call(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}, &response)
+	if err != nil {
+		return WrappedKey{}, err
+	}
+	parts := strings.SplitN(response.Data.Ciphertext, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return WrappedKey{}, fmt.Errorf("unexpected ciphertext format of vault transit key %s", v.keyName)
+	}
+	return WrappedKey{
+		KeyVersion: parts[1],
+		Ciphertext: []byte(response.Data.Ciphertext),
+	}, nil
+}
+
+func (v *vault) Unwrap(ctx context.Context, key WrappedKey) ([]byte, error) {
+	var response struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.call(ctx, "decrypt", map[string]string{"ciphertext": string(key.Ciphertext)}, &response); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(response.Data.Plaintext)
+}
+
+func (v *vault) call(ctx context.Context, operation string, body interface{}, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", v.address, v.mount, operation, v.keyName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("vault transit %s with key %s failed with status %d: %s", operation, v.keyName, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
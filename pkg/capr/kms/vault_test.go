@@ -0,0 +1,90 @@
+package kms
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransit is a vault transit engine that "encrypts" by prefixing the plaintext with the current key version.
+func fakeTransit(t *testing.T, version string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/etcd":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"ciphertext": "vault:" + version + ":" + body["plaintext"]},
+			})
+		case "/v1/transit/decrypt/etcd":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"plaintext": body["ciphertext"][len("vault:v1:"):]},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVault(t *testing.T) {
+	server := fakeTransit(t, "v1")
+	defer server.Close()
+
+	provider, err := New(&rkev1.ETCDSnapshotEncryption{
+		Provider: ProviderVault,
+		KeyName:  "etcd",
+		Endpoint: server.URL + "/",
+	}, map[string][]byte{"token": []byte("token")})
+	require.NoError(t, err)
+
+	plaintext, wrapped, err := GenerateDataKey(context.Background(), provider)
+	require.NoError(t, err)
+	assert.Len(t, plaintext, dataKeyBytes)
+	assert.Equal(t, "v1", wrapped.KeyVersion)
+	assert.Equal(t, "vault:v1:"+base64.StdEncoding.EncodeToString(plaintext), string(wrapped.Ciphertext))
+	assert.Len(t, KeyID(wrapped), 12)
+
+	unwrapped, err := provider.Unwrap(context.Background(), wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, unwrapped)
+}
+
+func TestVaultErrors(t *testing.T) {
+	server := fakeTransit(t, "v1")
+	defer server.Close()
+
+	provider, err := New(&rkev1.ETCDSnapshotEncryption{
+		Provider: ProviderVault,
+		KeyName:  "etcd",
+		Endpoint: server.URL,
+	}, map[string][]byte{"token": []byte("invalid")})
+	require.NoError(t, err)
+	_, err = provider.Wrap(context.Background(), []byte("key"))
+	assert.ErrorContains(t, err, "status 403")
+
+	_, err = New(&rkev1.ETCDSnapshotEncryption{
+		Provider: ProviderVault,
+		KeyName:  "etcd",
+		Endpoint: server.URL,
+	}, nil)
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&rkev1.ETCDSnapshotEncryption{Provider: ProviderVault}, nil)
+	assert.ErrorContains(t, err, "key name")
+
+	_, err = New(&rkev1.ETCDSnapshotEncryption{Provider: "gcpkms", KeyName: "etcd"}, nil)
+	assert.ErrorContains(t, err, "unsupported")
+}
@@ -0,0 +1,72 @@
+package capr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+)
+
+// MaintenanceWindowState returns whether the maintenance window is open at the given time, and if not, how long until
+// it opens.
+func MaintenanceWindowState(window *rkev1.MaintenanceWindow, now time.Time) (bool, time.Duration, error) {
+	if window.Duration.Duration <= 0 {
+		return false, 0, fmt.Errorf("duration must be positive")
+	}
+	start, err := time.Parse("15:04", window.Start)
+	if err != nil {
+		return false, 0, fmt.Errorf("start %q must be in 15:04 format", window.Start)
+	}
+	days := map[time.Weekday]bool{}
+	for _, day := range window.Days {
+		weekday, ok := parseWeekday(day)
+		if !ok {
+			return false, 0, fmt.Errorf("unknown day %q", day)
+		}
+		days[weekday] = true
+	}
+
+	now = now.UTC()
+	var wait time.Duration
+	// start from the previous day, as its window may last past midnight
+	for offset := -1; offset <= 7; offset++ {
+		day := now.AddDate(0, 0, offset)
+		opens := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, time.UTC)
+		if len(days) > 0 && !days[opens.Weekday()] {
+			continue
+		}
+		if !now.Before(opens) && now.Before(opens.Add(window.Duration.Duration)) {
+			return true, 0, nil
+		}
+		if opens.After(now) {
+			wait = opens.Sub(now)
+			break
+		}
+	}
+	return false, wait, nil
+}
+
+// ParseMaintenanceWindow parses the JSON value of a MaintenanceWindowAnnotation and validates the window.
+func ParseMaintenanceWindow(value string) (*rkev1.MaintenanceWindow, error) {
+	window := &rkev1.MaintenanceWindow{}
+	if err := json.Unmarshal([]byte(value), window); err != nil {
+		return nil, fmt.Errorf("invalid maintenance window %q: %w", value, err)
+	}
+	if _, _, err := MaintenanceWindowState(window, time.Now()); err != nil {
+		return nil, fmt.Errorf("invalid maintenance window %q: %w", value, err)
+	}
+	return window, nil
+}
+
+func parseWeekday(day string) (time.Weekday, bool) {
+	day = strings.ToLower(strings.TrimSpace(day))
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if day == name || day == name[:3] {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
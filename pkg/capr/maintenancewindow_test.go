@@ -0,0 +1,84 @@
+package capr
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMaintenanceWindowState(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2023, 5, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		window       rkev1.MaintenanceWindow
+		expectedOpen bool
+		expectedWait time.Duration
+		expectedErr  bool
+	}{
+		{
+			name:         "open every day",
+			window:       rkev1.MaintenanceWindow{Start: "11:00", Duration: metav1.Duration{Duration: 2 * time.Hour}},
+			expectedOpen: true,
+		},
+		{
+			name:         "opens later today",
+			window:       rkev1.MaintenanceWindow{Start: "22:30", Duration: metav1.Duration{Duration: time.Hour}},
+			expectedWait: 10*time.Hour + 30*time.Minute,
+		},
+		{
+			name:         "window of the previous day lasts past midnight",
+			window:       rkev1.MaintenanceWindow{Days: []string{"Tue"}, Start: "22:00", Duration: metav1.Duration{Duration: 16 * time.Hour}},
+			expectedOpen: true,
+		},
+		{
+			name:         "opens on the next allowed day",
+			window:       rkev1.MaintenanceWindow{Days: []string{"saturday", "Sun"}, Start: "02:00", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+			expectedWait: 2*24*time.Hour + 14*time.Hour,
+		},
+		{
+			name:        "unknown day",
+			window:      rkev1.MaintenanceWindow{Days: []string{"Someday"}, Start: "02:00", Duration: metav1.Duration{Duration: time.Hour}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid start",
+			window:      rkev1.MaintenanceWindow{Start: "2am", Duration: metav1.Duration{Duration: time.Hour}},
+			expectedErr: true,
+		},
+		{
+			name:        "missing duration",
+			window:      rkev1.MaintenanceWindow{Start: "02:00"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open, wait, err := MaintenanceWindowState(&tt.window, now)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedOpen, open)
+			assert.Equal(t, tt.expectedWait, wait)
+		})
+	}
+}
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	window, err := ParseMaintenanceWindow(`{"days":["Sat"],"start":"02:00","duration":"4h"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, &rkev1.MaintenanceWindow{Days: []string{"Sat"}, Start: "02:00", Duration: metav1.Duration{Duration: 4 * time.Hour}}, window)
+
+	_, err = ParseMaintenanceWindow(`{"start":"02:00"}`)
+	assert.EqualError(t, err, `invalid maintenance window "{\"start\":\"02:00\"}": duration must be positive`)
+
+	_, err = ParseMaintenanceWindow("Sat 02:00")
+	assert.Error(t, err)
+}
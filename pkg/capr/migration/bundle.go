@@ -0,0 +1,167 @@
+// Package migration exports the CAPR state of a provisioned cluster into a portable bundle and imports it into another
+// Rancher server, so that Rancher can be migrated to a new server without re-provisioning downstream clusters.
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	provv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// BundleVersion is the version of the format of bundles.
+const BundleVersion = "v1"
+
+// identityFields are the fields of the metadata of an object that are assigned by the API server that stores the
+// object, or by the controllers running against it, and are therefore not exported.
+var identityFields = []string{
+	"uid",
+	"resourceVersion",
+	"generation",
+	"creationTimestamp",
+	"deletionTimestamp",
+	"deletionGracePeriodSeconds",
+	"managedFields",
+	"selfLink",
+	"finalizers",
+}
+
+// allowedKinds are the kinds of the objects that a bundle may contain, by group. Infrastructure machines are allowed by
+// group, as the kinds of their node drivers are generated.
+var allowedKinds = map[string][]string{
+	"":                              {"Secret"},
+	capi.GroupVersion.Group:         {"Cluster", "MachineDeployment", "MachineSet", "Machine"},
+	rkev1.SchemeGroupVersion.Group:  {"RKEControlPlane", "RKEBootstrap", "ETCDSnapshot", "CustomMachine"},
+	provv1.SchemeGroupVersion.Group: {"Cluster"},
+	"rke-machine.cattle.io":         nil,
+}
+
+// allowedSecretTypes are the types of the secrets that a bundle may contain: the state of the cluster, the plans of its
+// machines and the state of the machines provisioned by node drivers.
+var allowedSecretTypes = map[string]bool{
+	capr.SecretTypeClusterState: true,
+	capr.SecretTypeMachinePlan:  true,
+	capr.SecretTypeMachineState: true,
+}
+
+// Bundle is the portable CAPR state of a provisioned cluster. The objects of the bundle do not carry the identity they
+// were assigned by the exporting server, and the owner references between them are resolved by name when the bundle
+// is imported.
+type Bundle struct {
+	Version   string                      `json:"version"`
+	Namespace string                      `json:"namespace"`
+	Cluster   string                      `json:"cluster"`
+	Objects   []unstructured.Unstructured `json:"objects"`
+}
+
+// Validate checks that the bundle can be imported.
+func (b *Bundle) Validate() error {
+	if b.Version != BundleVersion {
+		return fmt.Errorf("unsupported bundle version %q, expected %q", b.Version, BundleVersion)
+	}
+	if b.Namespace == "" || b.Cluster == "" {
+		return fmt.Errorf("bundle does not specify the namespace and name of the cluster")
+	}
+	for _, obj := range b.Objects {
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" || obj.GetName() == "" {
+			return fmt.Errorf("bundle contains an object without kind, apiVersion or name")
+		}
+		if obj.GetNamespace() != b.Namespace {
+			return fmt.Errorf("%s %s is not in the namespace %s of the cluster", obj.GetKind(), obj.GetName(), b.Namespace)
+		}
+		if err := validateKind(&obj, b.Cluster); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateKind checks that obj is of a kind that is exported with a cluster. The clusters and the control plane of the
+// bundle must be those of the cluster of the bundle.
+func validateKind(obj *unstructured.Unstructured, cluster string) error {
+	gvk := obj.GroupVersionKind()
+	kinds, ok := allowedKinds[gvk.Group]
+	if !ok || (kinds == nil && !strings.HasSuffix(gvk.Kind, "Machine")) || (kinds != nil && !contains(kinds, gvk.Kind)) {
+		return fmt.Errorf("bundle contains %s %s, which is not part of the state of a cluster", gvk.GroupKind(), obj.GetName())
+	}
+	if gvk.Group == "" {
+		secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+		if !allowedSecretTypes[secretType] {
+			return fmt.Errorf("bundle contains secret %s of type %q, which is not part of the state of a cluster", obj.GetName(), secretType)
+		}
+	}
+	if isClusterKind(gvk) && obj.GetName() != cluster {
+		return fmt.Errorf("bundle contains %s %s, which is not the %s of cluster %s", obj.GetKind(), obj.GetName(), obj.GetKind(), cluster)
+	}
+	return nil
+}
+
+// isClusterKind returns true if objects of the kind have the same name as the cluster they belong to.
+func isClusterKind(gvk schema.GroupVersionKind) bool {
+	return gvk.Kind == "Cluster" || (gvk.Group == rkev1.SchemeGroupVersion.Group && gvk.Kind == "RKEControlPlane")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// stripIdentity removes the fields of obj that identify it on the exporting server. The UIDs of owner references are
+// removed as well, as the owners are assigned new UIDs when they are imported.
+func stripIdentity(obj *unstructured.Unstructured) {
+	for _, field := range identityFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	owners := obj.GetOwnerReferences()
+	for i := range owners {
+		owners[i].UID = ""
+	}
+	if len(owners) > 0 {
+		obj.SetOwnerReferences(owners)
+	}
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[capi.PausedAnnotation]; ok {
+		delete(annotations, capi.PausedAnnotation)
+		obj.SetAnnotations(annotations)
+	}
+}
+
+// rewriteNamespace moves obj from the namespace from to the namespace to, including the references to other objects in
+// its spec that are in the same namespace.
+func rewriteNamespace(obj *unstructured.Unstructured, from, to string) {
+	if from == to {
+		return
+	}
+	obj.SetNamespace(to)
+	if spec, ok := obj.Object["spec"].(map[string]interface{}); ok {
+		rewriteReferences(spec, from, to)
+	}
+}
+
+// rewriteReferences rewrites the namespace of the object references in value, which are maps with a name, a namespace
+// and a kind.
+func rewriteReferences(value interface{}, from, to string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := v["name"].(string); ok && v["namespace"] == from && v["kind"] != nil {
+			v["namespace"] = to
+		}
+		for _, child := range v {
+			rewriteReferences(child, from, to)
+		}
+	case []interface{}:
+		for _, child := range v {
+			rewriteReferences(child, from, to)
+		}
+	}
+}
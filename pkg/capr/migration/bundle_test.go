@@ -0,0 +1,226 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestStripIdentity(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "rke.cattle.io/v1",
+		"kind":       "RKEBootstrap",
+		"metadata": map[string]interface{}{
+			"name":              "test-bootstrap",
+			"namespace":         "fleet-default",
+			"uid":               "1234",
+			"resourceVersion":   "42",
+			"generation":        int64(3),
+			"creationTimestamp": "2023-01-01T00:00:00Z",
+			"finalizers":        []interface{}{"rke.cattle.io/bootstrap"},
+			"managedFields":     []interface{}{map[string]interface{}{"manager": "rancher"}},
+			"labels":            map[string]interface{}{"rke.cattle.io/cluster-name": "test"},
+			"annotations": map[string]interface{}{
+				"cluster.x-k8s.io/paused": "true",
+				"rke.cattle.io/join-url":  "https://10.0.0.1:9345",
+			},
+			"ownerReferences": []interface{}{
+				map[string]interface{}{
+					"apiVersion": "cluster.x-k8s.io/v1beta1",
+					"kind":       "Machine",
+					"name":       "test-machine",
+					"uid":        "5678",
+				},
+			},
+		},
+		"spec": map[string]interface{}{"clusterName": "test"},
+	}}
+
+	stripIdentity(obj)
+
+	assert.Equal(t, map[string]interface{}{
+		"name":        "test-bootstrap",
+		"namespace":   "fleet-default",
+		"labels":      map[string]interface{}{"rke.cattle.io/cluster-name": "test"},
+		"annotations": map[string]interface{}{"rke.cattle.io/join-url": "https://10.0.0.1:9345"},
+		"ownerReferences": []interface{}{
+			map[string]interface{}{
+				"apiVersion": "cluster.x-k8s.io/v1beta1",
+				"kind":       "Machine",
+				"name":       "test-machine",
+				"uid":        "",
+			},
+		},
+	}, obj.Object["metadata"])
+	assert.Equal(t, map[string]interface{}{"clusterName": "test"}, obj.Object["spec"])
+}
+
+func TestRewriteNamespace(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cluster.x-k8s.io/v1beta1",
+		"kind":       "Machine",
+		"metadata": map[string]interface{}{
+			"name":      "test-machine",
+			"namespace": "fleet-default",
+		},
+		"spec": map[string]interface{}{
+			"bootstrap": map[string]interface{}{
+				"configRef": map[string]interface{}{
+					"apiVersion": "rke.cattle.io/v1",
+					"kind":       "RKEBootstrap",
+					"name":       "test-bootstrap",
+					"namespace":  "fleet-default",
+				},
+			},
+			"infrastructureRef": map[string]interface{}{
+				"apiVersion": "rke-machine.cattle.io/v1",
+				"kind":       "Amazonec2Machine",
+				"name":       "test-machine",
+				"namespace":  "fleet-default",
+			},
+			"other": map[string]interface{}{
+				"name":      "not-a-reference",
+				"namespace": "fleet-default",
+			},
+			"external": []interface{}{
+				map[string]interface{}{
+					"kind":      "Secret",
+					"name":      "credentials",
+					"namespace": "cattle-global-data",
+				},
+			},
+		},
+	}}
+
+	rewriteNamespace(obj, "fleet-default", "fleet-migrated")
+
+	assert.Equal(t, "fleet-migrated", obj.GetNamespace())
+	namespace, _, _ := unstructured.NestedString(obj.Object, "spec", "bootstrap", "configRef", "namespace")
+	assert.Equal(t, "fleet-migrated", namespace)
+	namespace, _, _ = unstructured.NestedString(obj.Object, "spec", "infrastructureRef", "namespace")
+	assert.Equal(t, "fleet-migrated", namespace)
+	namespace, _, _ = unstructured.NestedString(obj.Object, "spec", "other", "namespace")
+	assert.Equal(t, "fleet-default", namespace, "maps without a kind are not references")
+	external, _, _ := unstructured.NestedSlice(obj.Object, "spec", "external")
+	assert.Equal(t, "cattle-global-data", external[0].(map[string]interface{})["namespace"], "references to other namespaces are kept")
+}
+
+func TestBundleValidate(t *testing.T) {
+	object := func(kind, namespace string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetAPIVersion("rke.cattle.io/v1")
+		obj.SetKind(kind)
+		obj.SetName("test")
+		obj.SetNamespace(namespace)
+		return obj
+	}
+	objectOf := func(apiVersion, kind, name string, fields map[string]interface{}) unstructured.Unstructured {
+		obj := unstructured.Unstructured{Object: fields}
+		if obj.Object == nil {
+			obj.Object = map[string]interface{}{}
+		}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetName(name)
+		obj.SetNamespace("fleet-default")
+		return obj
+	}
+	tests := []struct {
+		name    string
+		bundle  Bundle
+		wantErr string
+	}{
+		{
+			name: "valid",
+			bundle: Bundle{
+				Version:   BundleVersion,
+				Namespace: "fleet-default",
+				Cluster:   "test",
+				Objects:   []unstructured.Unstructured{object("RKEControlPlane", "fleet-default")},
+			},
+		},
+		{
+			name:    "unsupported version",
+			bundle:  Bundle{Version: "v0", Namespace: "fleet-default", Cluster: "test"},
+			wantErr: `unsupported bundle version "v0", expected "v1"`,
+		},
+		{
+			name:    "missing cluster",
+			bundle:  Bundle{Version: BundleVersion, Namespace: "fleet-default"},
+			wantErr: "bundle does not specify the namespace and name of the cluster",
+		},
+		{
+			name: "missing kind",
+			bundle: Bundle{
+				Version:   BundleVersion,
+				Namespace: "fleet-default",
+				Cluster:   "test",
+				Objects:   []unstructured.Unstructured{object("", "fleet-default")},
+			},
+			wantErr: "bundle contains an object without kind, apiVersion or name",
+		},
+		{
+			name: "object in other namespace",
+			bundle: Bundle{
+				Version:   BundleVersion,
+				Namespace: "fleet-default",
+				Cluster:   "test",
+				Objects:   []unstructured.Unstructured{object("RKEControlPlane", "default")},
+			},
+			wantErr: "RKEControlPlane test is not in the namespace fleet-default of the cluster",
+		},
+		{
+			name: "kind not part of the state of a cluster",
+			bundle: Bundle{
+				Version:   BundleVersion,
+				Namespace: "fleet-default",
+				Cluster:   "test",
+				Objects:   []unstructured.Unstructured{object("CustomMachine", "fleet-default"), objectOf("rbac.authorization.k8s.io/v1", "RoleBinding", "test", nil)},
+			},
+			wantErr: "bundle contains RoleBinding.rbac.authorization.k8s.io test, which is not part of the state of a cluster",
+		},
+		{
+			name: "infrastructure machine",
+			bundle: Bundle{
+				Version:   BundleVersion,
+				Namespace: "fleet-default",
+				Cluster:   "test",
+				Objects:   []unstructured.Unstructured{objectOf("rke-machine.cattle.io/v1", "Amazonec2Machine", "test", nil)},
+			},
+		},
+		{
+			name: "secret of other type",
+			bundle: Bundle{
+				Version:   BundleVersion,
+				Namespace: "fleet-default",
+				Cluster:   "test",
+				Objects: []unstructured.Unstructured{
+					objectOf("v1", "Secret", "test-machine-state", map[string]interface{}{"type": "rke.cattle.io/machine-state"}),
+					objectOf("v1", "Secret", "token", map[string]interface{}{"type": "kubernetes.io/service-account-token"}),
+				},
+			},
+			wantErr: `bundle contains secret token of type "kubernetes.io/service-account-token", which is not part of the state of a cluster`,
+		},
+		{
+			name: "cluster of other name",
+			bundle: Bundle{
+				Version:   BundleVersion,
+				Namespace: "fleet-default",
+				Cluster:   "test",
+				Objects:   []unstructured.Unstructured{objectOf("provisioning.cattle.io/v1", "Cluster", "other", nil)},
+			},
+			wantErr: "bundle contains Cluster other, which is not the Cluster of cluster test",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.bundle.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package migration
+
+import (
+	"fmt"
+
+	"github.com/rancher/lasso/pkg/dynamic"
+	provv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
+	provcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// Exporter collects the CAPR state of provisioned clusters into bundles.
+type Exporter struct {
+	clusters           provcontrollers.ClusterCache
+	controlPlanes      rkecontrollers.RKEControlPlaneCache
+	bootstraps         rkecontrollers.RKEBootstrapCache
+	etcdSnapshots      rkecontrollers.ETCDSnapshotCache
+	capiClusters       capicontrollers.ClusterCache
+	capiClusterClient  capicontrollers.ClusterClient
+	machineDeployments capicontrollers.MachineDeploymentCache
+	machineSets        capicontrollers.MachineSetCache
+	machines           capicontrollers.MachineCache
+	secrets            corecontrollers.SecretCache
+	dynamic            *dynamic.Controller
+}
+
+func NewExporter(clients *wrangler.Context) *Exporter {
+	return &Exporter{
+		clusters:           clients.Provisioning.Cluster().Cache(),
+		controlPlanes:      clients.RKE.RKEControlPlane().Cache(),
+		bootstraps:         clients.RKE.RKEBootstrap().Cache(),
+		etcdSnapshots:      clients.RKE.ETCDSnapshot().Cache(),
+		capiClusters:       clients.CAPI.Cluster().Cache(),
+		capiClusterClient:  clients.CAPI.Cluster(),
+		machineDeployments: clients.CAPI.MachineDeployment().Cache(),
+		machineSets:        clients.CAPI.MachineSet().Cache(),
+		machines:           clients.CAPI.Machine().Cache(),
+		secrets:            clients.Core.Secret().Cache(),
+		dynamic:            clients.Dynamic,
+	}
+}
+
+// Export returns the bundle of the cluster. The objects of the bundle are ordered so that every object follows the
+// objects it references: the secrets holding the tokens and plans of the cluster, the CAPI cluster and the control plane,
+// the machines with their bootstraps and infrastructure machines, the etcd snapshots and finally the cluster itself,
+// which the controllers of the importing server start reconciling once it is created.
+//
+// The CAPI cluster of the cluster is paused before its state is collected, so that this server stops reconciling the
+// cluster and its machines while the importing server takes them over. The bundle holds the CAPI cluster unpaused.
+func (e *Exporter) Export(namespace, clusterName string) (*Bundle, error) {
+	cluster, err := e.clusters.Get(namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.Spec.RKEConfig == nil {
+		return nil, fmt.Errorf("cluster %s/%s is not a provisioned RKE2 or K3s cluster", namespace, clusterName)
+	}
+
+	b := &bundleBuilder{
+		bundle: &Bundle{
+			Version:   BundleVersion,
+			Namespace: namespace,
+			Cluster:   clusterName,
+		},
+	}
+	capiCluster, err := e.pause(namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterSelector := labels.SelectorFromSet(labels.Set{capr.ClusterNameLabel: clusterName})
+	capiClusterSelector := labels.SelectorFromSet(labels.Set{capi.ClusterLabelName: clusterName})
+
+	// The control plane and the CAPI cluster have the same name as the cluster.
+	stateSecret, err := e.secrets.Get(namespace, name.SafeConcatName(clusterName, "rke", "state"))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	} else if err == nil {
+		if err := b.add(corev1.SchemeGroupVersion.WithKind("Secret"), stateSecret); err != nil {
+			return nil, err
+		}
+	}
+	secrets, err := e.secrets.List(namespace, clusterSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets {
+		if secret.Type != capr.SecretTypeMachinePlan {
+			continue
+		}
+		if err := b.add(corev1.SchemeGroupVersion.WithKind("Secret"), secret); err != nil {
+			return nil, err
+		}
+	}
+
+	capiCluster = capiCluster.DeepCopy()
+	capiCluster.Spec.Paused = false
+	if err := b.add(capi.GroupVersion.WithKind("Cluster"), capiCluster); err != nil {
+		return nil, err
+	}
+	controlPlane, err := e.controlPlanes.Get(namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.add(rkev1.SchemeGroupVersion.WithKind("RKEControlPlane"), controlPlane); err != nil {
+		return nil, err
+	}
+
+	machineDeployments, err := e.machineDeployments.List(namespace, capiClusterSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, machineDeployment := range machineDeployments {
+		if err := b.add(capi.GroupVersion.WithKind("MachineDeployment"), machineDeployment); err != nil {
+			return nil, err
+		}
+	}
+	machineSets, err := e.machineSets.List(namespace, capiClusterSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, machineSet := range machineSets {
+		if err := b.add(capi.GroupVersion.WithKind("MachineSet"), machineSet); err != nil {
+			return nil, err
+		}
+	}
+	machines, err := e.machines.List(namespace, capiClusterSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, machine := range machines {
+		if err := b.add(capi.GroupVersion.WithKind("Machine"), machine); err != nil {
+			return nil, err
+		}
+		if err := e.addMachineReferences(b, machine); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshots, err := e.etcdSnapshots.List(namespace, clusterSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if err := b.add(rkev1.SchemeGroupVersion.WithKind("ETCDSnapshot"), snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	cluster = cluster.DeepCopy()
+	// The status of the cluster refers to the management cluster of the exporting server and is rebuilt by the importing
+	// server.
+	cluster.Status = provv1.ClusterStatus{}
+	if err := b.add(provv1.SchemeGroupVersion.WithKind("Cluster"), cluster); err != nil {
+		return nil, err
+	}
+	return b.bundle, nil
+}
+
+// pause pauses the CAPI cluster of the cluster and returns it.
+func (e *Exporter) pause(namespace, clusterName string) (*capi.Cluster, error) {
+	capiCluster, err := e.capiClusters.Get(namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if capiCluster.Spec.Paused {
+		return capiCluster, nil
+	}
+	capiCluster = capiCluster.DeepCopy()
+	capiCluster.Spec.Paused = true
+	logrus.Infof("[migration] pausing CAPI cluster %s/%s for its export", namespace, clusterName)
+	return e.capiClusterClient.Update(capiCluster)
+}
+
+// addMachineReferences adds the bootstrap and the infrastructure machine of the machine to the bundle, along with the
+// state secret of infrastructure machines provisioned by node drivers, which holds the SSH keys of the machine.
+func (e *Exporter) addMachineReferences(b *bundleBuilder, machine *capi.Machine) error {
+	if ref := machine.Spec.Bootstrap.ConfigRef; ref != nil && ref.Kind == "RKEBootstrap" {
+		bootstrap, err := e.bootstraps.Get(machine.Namespace, ref.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		} else if err == nil {
+			if err := b.add(rkev1.SchemeGroupVersion.WithKind("RKEBootstrap"), bootstrap); err != nil {
+				return err
+			}
+		}
+	}
+
+	ref := machine.Spec.InfrastructureRef
+	if ref.Name == "" {
+		return nil
+	}
+	gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	infraMachine, err := e.dynamic.Get(gvk, machine.Namespace, ref.Name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := b.add(gvk, infraMachine); err != nil {
+		return err
+	}
+
+	stateSecret, err := e.secrets.Get(machine.Namespace, capr.MachineStateSecretName(ref.Name))
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if stateSecret.Type != capr.SecretTypeMachineState {
+		return nil
+	}
+	return b.add(corev1.SchemeGroupVersion.WithKind("Secret"), stateSecret)
+}
+
+// bundleBuilder adds objects to a bundle without their identity on the exporting server.
+type bundleBuilder struct {
+	bundle *Bundle
+}
+
+func (b *bundleBuilder) add(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	u := unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	stripIdentity(&u)
+	b.bundle.Objects = append(b.bundle.Objects, u)
+	return nil
+}
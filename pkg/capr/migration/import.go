@@ -0,0 +1,240 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/lasso/pkg/client"
+	provv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// Importer creates the objects of bundles on this server on behalf of the users that import them.
+type Importer struct {
+	restConfig *rest.Config
+	mapper     meta.RESTMapper
+}
+
+func NewImporter(clients *wrangler.Context) *Importer {
+	return &Importer{
+		restConfig: clients.RESTConfig,
+		mapper:     clients.RESTMapper,
+	}
+}
+
+// importer creates the objects of a bundle with clients that impersonate the user importing it, so that the user
+// cannot create or change anything through the import that they could not create or change themselves.
+type importer struct {
+	clients client.SharedClientFactory
+}
+
+func (i *Importer) forUser(u user.Info) (*importer, error) {
+	config := rest.CopyConfig(i.restConfig)
+	config.Impersonate = rest.ImpersonationConfig{
+		UserName: u.GetName(),
+		UID:      u.GetUID(),
+		Groups:   u.GetGroups(),
+		Extra:    u.GetExtra(),
+	}
+	clients, err := client.NewSharedClientFactory(config, &client.SharedClientFactoryOptions{Mapper: i.mapper})
+	if err != nil {
+		return nil, err
+	}
+	return &importer{clients: clients}, nil
+}
+
+// Import creates the objects of the bundle in the namespace, or in the namespace they were exported from if namespace
+// is empty, as the user u. Neither the cluster nor any other object of the bundle may exist yet.
+//
+// The objects are imported in three passes. First, the objects are created without their owner references and, if
+// they are reconciled by CAPI or CAPR controllers, paused, so that no controller acts on a partially imported cluster.
+// Then the owner references are restored with the UIDs the owners were assigned on this server, along with the status
+// of the objects, which holds the state of the machines and the etcd snapshots of the cluster. Finally, the objects are
+// unpaused. The cluster controllers then adopt the imported objects instead of provisioning new machines.
+//
+// The system agents running on the nodes of the cluster authenticate with tokens of service accounts of the exporting
+// server and must be pointed at this server separately.
+func (i *Importer) Import(ctx context.Context, u user.Info, bundle *Bundle, namespace string) error {
+	if err := bundle.Validate(); err != nil {
+		return err
+	}
+	if namespace == "" {
+		namespace = bundle.Namespace
+	}
+	imp, err := i.forUser(u)
+	if err != nil {
+		return err
+	}
+	return imp.importBundle(ctx, bundle, namespace)
+}
+
+func (i *importer) importBundle(ctx context.Context, bundle *Bundle, namespace string) error {
+
+	clusterClient, err := i.clients.ForKind(provv1.SchemeGroupVersion.WithKind("Cluster"))
+	if err != nil {
+		return err
+	}
+	err = clusterClient.Get(ctx, namespace, bundle.Cluster, &unstructured.Unstructured{}, metav1.GetOptions{})
+	if err == nil {
+		return fmt.Errorf("cluster %s/%s already exists", namespace, bundle.Cluster)
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	objects := make([]*unstructured.Unstructured, 0, len(bundle.Objects))
+	for _, obj := range bundle.Objects {
+		obj := obj.DeepCopy()
+		rewriteNamespace(obj, bundle.Namespace, namespace)
+		objects = append(objects, obj)
+	}
+
+	uids := map[string]types.UID{}
+	for _, obj := range objects {
+		uid, err := i.create(ctx, obj)
+		if err != nil {
+			return fmt.Errorf("failed to import %s %s/%s: %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+		}
+		uids[objectKey(obj.GroupVersionKind(), obj.GetName())] = uid
+	}
+	for _, obj := range objects {
+		if err := i.restore(ctx, obj, uids); err != nil {
+			return fmt.Errorf("failed to restore %s %s/%s: %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	for _, obj := range objects {
+		if !pausable(obj.GroupVersionKind()) {
+			continue
+		}
+		if err := i.unpause(ctx, obj); err != nil {
+			return fmt.Errorf("failed to unpause %s %s/%s: %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+
+	logrus.Infof("[migration] imported cluster %s/%s with %d objects", namespace, bundle.Cluster, len(objects))
+	return nil
+}
+
+// create creates the object without its status and owner references and returns its UID. An object that already
+// exists is not overwritten, as it may belong to another cluster; the objects of an earlier import that failed must be
+// removed before importing the bundle again.
+func (i *importer) create(ctx context.Context, obj *unstructured.Unstructured) (types.UID, error) {
+	c, err := i.clients.ForKind(obj.GroupVersionKind())
+	if err != nil {
+		return "", err
+	}
+
+	desired := obj.DeepCopy()
+	delete(desired.Object, "status")
+	desired.SetOwnerReferences(nil)
+	if pausable(desired.GroupVersionKind()) {
+		annotations := desired.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[capi.PausedAnnotation] = "true"
+		desired.SetAnnotations(annotations)
+	}
+
+	result := &unstructured.Unstructured{}
+	if err := c.Create(ctx, desired.GetNamespace(), desired, result, metav1.CreateOptions{}); err != nil {
+		return "", err
+	}
+	return result.GetUID(), nil
+}
+
+// restore sets the owner references and the status of the imported object. Owner references to objects that are not
+// part of the bundle are dropped, as their owners do not exist on this server.
+func (i *importer) restore(ctx context.Context, obj *unstructured.Unstructured, uids map[string]types.UID) error {
+	c, err := i.clients.ForKind(obj.GroupVersionKind())
+	if err != nil {
+		return err
+	}
+
+	var owners []metav1.OwnerReference
+	for _, owner := range obj.GetOwnerReferences() {
+		uid, ok := uids[objectKey(schema.FromAPIVersionAndKind(owner.APIVersion, owner.Kind), owner.Name)]
+		if !ok {
+			logrus.Infof("[migration] dropping owner reference of %s %s/%s to %s %s, which is not part of the bundle",
+				obj.GetKind(), obj.GetNamespace(), obj.GetName(), owner.Kind, owner.Name)
+			continue
+		}
+		owner.UID = uid
+		owners = append(owners, owner)
+	}
+	if len(owners) > 0 {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			existing := &unstructured.Unstructured{}
+			if err := c.Get(ctx, obj.GetNamespace(), obj.GetName(), existing, metav1.GetOptions{}); err != nil {
+				return err
+			}
+			existing.SetOwnerReferences(owners)
+			return c.Update(ctx, existing.GetNamespace(), existing, &unstructured.Unstructured{}, metav1.UpdateOptions{})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	status, ok := obj.Object["status"]
+	if !ok || status == nil || !hasStatus(obj.GroupVersionKind()) {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing := &unstructured.Unstructured{}
+		if err := c.Get(ctx, obj.GetNamespace(), obj.GetName(), existing, metav1.GetOptions{}); err != nil {
+			return err
+		}
+		existing.Object["status"] = status
+		return c.UpdateStatus(ctx, existing.GetNamespace(), existing, &unstructured.Unstructured{}, metav1.UpdateOptions{})
+	})
+}
+
+// unpause removes the paused annotation the object was imported with.
+func (i *importer) unpause(ctx context.Context, obj *unstructured.Unstructured) error {
+	c, err := i.clients.ForKind(obj.GroupVersionKind())
+	if err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing := &unstructured.Unstructured{}
+		if err := c.Get(ctx, obj.GetNamespace(), obj.GetName(), existing, metav1.GetOptions{}); err != nil {
+			return err
+		}
+		annotations := existing.GetAnnotations()
+		if _, ok := annotations[capi.PausedAnnotation]; !ok {
+			return nil
+		}
+		delete(annotations, capi.PausedAnnotation)
+		existing.SetAnnotations(annotations)
+		return c.Update(ctx, existing.GetNamespace(), existing, &unstructured.Unstructured{}, metav1.UpdateOptions{})
+	})
+}
+
+// objectKey identifies an object of a bundle independently of the version of its kind.
+func objectKey(gvk schema.GroupVersionKind, name string) string {
+	return gvk.Group + "/" + gvk.Kind + "/" + name
+}
+
+// pausable returns true if objects of the kind are reconciled by CAPI or CAPR controllers, which skip objects with the
+// paused annotation.
+func pausable(gvk schema.GroupVersionKind) bool {
+	return gvk.Group == capi.GroupVersion.Group || gvk.Group == rkev1.SchemeGroupVersion.Group || gvk.Group == "rke-machine.cattle.io"
+}
+
+// hasStatus returns true if the status of objects of the kind is restored on import. The status of secrets does not
+// exist and the status of clusters is rebuilt by this server.
+func hasStatus(gvk schema.GroupVersionKind) bool {
+	return gvk.Group != "" && gvk.Group != provv1.SchemeGroupVersion.Group
+}
@@ -0,0 +1,27 @@
+package planner
+
+import (
+	"github.com/Masterminds/semver/v3"
+	"github.com/rancher/rancher/pkg/settings"
+)
+
+// systemAgentAtLeast returns true if the system-agent version of the system-agent-version setting, which the agents of
+// all machines are installed and upgraded with, is at least the minimum version. Prereleases of a version count as the
+// version, as release candidates ship the features of their release. An unknown version supports no optional features.
+func systemAgentAtLeast(agentVersion, minimum string) bool {
+	version, err := semver.NewVersion(agentVersion)
+	if err != nil {
+		return false
+	}
+	core, err := version.SetPrerelease("")
+	if err != nil {
+		return false
+	}
+	return !core.LessThan(semver.MustParse(minimum))
+}
+
+// currentSystemAgentAtLeast returns true if the system-agent version of the system-agent-version setting is at least the
+// minimum version.
+func currentSystemAgentAtLeast(minimum string) bool {
+	return systemAgentAtLeast(settings.SystemAgentVersion.Get(), minimum)
+}
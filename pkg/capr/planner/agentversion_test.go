@@ -0,0 +1,48 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemAgentAtLeast(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		expected bool
+	}{
+		{
+			name:    "unknown version",
+			version: "",
+		},
+		{
+			name:    "invalid version",
+			version: "latest",
+		},
+		{
+			name:    "older release candidate",
+			version: "v0.3.3-rc2",
+		},
+		{
+			name:     "release candidate of the minimum version",
+			version:  "v0.3.4-rc1",
+			expected: true,
+		},
+		{
+			name:     "minimum version",
+			version:  "v0.3.4",
+			expected: true,
+		},
+		{
+			name:     "newer version",
+			version:  "v0.4.0",
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, systemAgentAtLeast(tt.version, minPlanEncodingSystemAgentVersion))
+		})
+	}
+}
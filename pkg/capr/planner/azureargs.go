@@ -0,0 +1,243 @@
+package planner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/kv"
+)
+
+const (
+	// ETCDSnapshotAzureUploadInstruction is the name of the instruction that offloads the local etcd snapshots of a node
+	// to Azure Blob Storage.
+	ETCDSnapshotAzureUploadInstruction = "etcd-snapshot-azure-upload"
+
+	etcdSnapshotAzureUploadScriptPath = "rancher_v2prov_etcd_snapshot/bin/azure-upload.sh"
+
+	// etcdSnapshotAzureUploadScript uploads the snapshot files in the snapshot directory that do not exist in the
+	// container yet as block blobs. Requests are authorized with the SAS token passed in through the environment or, if
+	// there is none, with an access token of the service principal passed in through the environment. Secrets are handed
+	// to curl on stdin to keep them out of the process list. For each snapshot in the container, a line with its name is
+	// printed, prefixed by whether it was uploaded.
+	etcdSnapshotAzureUploadScript = `#!/bin/sh
+
+dir=$1
+url=$2
+folder=$3
+
+if [ ! -d "$dir" ]; then
+	echo "etcd snapshot directory $dir does not exist" >&2
+	exit 1
+fi
+prefix=""
+if [ -n "$folder" ]; then
+	prefix="$folder/"
+fi
+
+token=""
+if [ -z "$AZURE_STORAGE_SAS_TOKEN" ]; then
+	token=$(printf '%s' "$AZURE_CLIENT_SECRET" |
+		curl --fail --silent --show-error \
+			--data grant_type=client_credentials \
+			--data-urlencode "client_id=$AZURE_CLIENT_ID" \
+			--data-urlencode client_secret@- \
+			--data-urlencode scope=https://storage.azure.com/.default \
+			"$AZURE_AUTHORITY_HOST/$AZURE_TENANT_ID/oauth2/v2.0/token" |
+		sed -n 's/.*"access_token" *: *"\([^"]*\)".*/\1/p')
+	if [ -z "$token" ]; then
+		echo "failed to get an access token of the Azure service principal" >&2
+		exit 1
+	fi
+fi
+
+blob() {
+	key=$1
+	shift
+	{
+		if [ -n "$token" ]; then
+			printf 'header = "Authorization: Bearer %s"\n' "$token"
+			printf 'url = "%s/%s"\n' "$url" "$key"
+		else
+			printf 'url = "%s/%s?%s"\n' "$url" "$key" "$AZURE_STORAGE_SAS_TOKEN"
+		fi
+	} | curl -K - --fail --silent --show-error --output /dev/null -H "x-ms-version: 2021-08-06" "$@"
+}
+
+failed=0
+for file in "$dir"/*; do
+	[ -f "$file" ] || continue
+	name=$(basename "$file")
+	case "$name" in
+	*[!A-Za-z0-9._-]*)
+		echo "skipping etcd snapshot $name as its name is not a valid blob name" >&2
+		continue
+		;;
+	esac
+	if blob "$prefix$name" --head 2>/dev/null; then
+		echo "exists $name"
+		continue
+	fi
+	if ! blob "$prefix$name" --upload-file "$file" -H "x-ms-blob-type: BlockBlob"; then
+		echo "failed to upload etcd snapshot $name" >&2
+		failed=1
+		continue
+	fi
+	echo "uploaded $name"
+done
+exit $failed
+`
+)
+
+var (
+	azureStorageAccountRegexp = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+	azureContainerRegexp      = regexp.MustCompile(`^[a-z0-9]([a-z0-9]|-[a-z0-9])*$`)
+
+	// azureClouds are the login and blob service hosts of the Azure clouds, by the environment of an Azure cloud
+	// credential.
+	azureClouds = map[string]azureCloud{
+		"":                       {authorityHost: "https://login.microsoftonline.com", blobSuffix: "blob.core.windows.net"},
+		"AzurePublicCloud":       {authorityHost: "https://login.microsoftonline.com", blobSuffix: "blob.core.windows.net"},
+		"AzureChinaCloud":        {authorityHost: "https://login.chinacloudapi.cn", blobSuffix: "blob.core.chinacloudapi.cn"},
+		"AzureUSGovernmentCloud": {authorityHost: "https://login.microsoftonline.us", blobSuffix: "blob.core.usgovcloudapi.net"},
+	}
+)
+
+type azureCloud struct {
+	authorityHost string
+	blobSuffix    string
+}
+
+// azureArgs is a struct that contains functions used to generate arguments for etcd snapshots offloaded to Azure Blob
+// Storage.
+type azureArgs struct {
+	secretCache corecontrollers.SecretCache
+}
+
+// ToArgs renders the arguments and environment variables of the upload script for the container of the passed in
+// ETCDSnapshotAzure, authorized by its cloud credential.
+func (a *azureArgs) ToArgs(azure *rkev1.ETCDSnapshotAzure, controlPlane *rkev1.RKEControlPlane) ([]string, []string, error) {
+	if azure.CloudCredentialName == "" {
+		return nil, nil, fmt.Errorf("etcd snapshots can not be offloaded to Azure Blob Storage without a cloud credential")
+	}
+	cred, err := getAzureCredential(a.secretCache, controlPlane.Namespace, azure.CloudCredentialName, controlPlane.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return azureUploadArgs(azure, cred)
+}
+
+// azureUploadArgs validates the container and returns the arguments and environment variables of the upload script.
+// A SAS token of the credential takes precedence over its service principal.
+func azureUploadArgs(azure *rkev1.ETCDSnapshotAzure, cred azureCredential) (args []string, env []string, _ error) {
+	if !azureStorageAccountRegexp.MatchString(azure.StorageAccount) {
+		return nil, nil, fmt.Errorf("invalid etcd snapshot Azure storage account %q: must consist of 3 to 24 lowercase letters and numbers", azure.StorageAccount)
+	}
+	if len(azure.Container) < 3 || len(azure.Container) > 63 || !azureContainerRegexp.MatchString(azure.Container) {
+		return nil, nil, fmt.Errorf("invalid etcd snapshot Azure container %q: must be between 3 and 63 characters long, consist of lowercase letters, numbers and single hyphens, and begin and end with a letter or number", azure.Container)
+	}
+	folder, err := normalizeSnapshotFolder(azure.Folder, "Azure")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cloud, ok := azureClouds[cred.Environment]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown Azure environment %q of the etcd snapshot cloud credential", cred.Environment)
+	}
+	endpoint := strings.TrimSuffix(azure.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s", azure.StorageAccount, cloud.blobSuffix)
+	} else if !strings.HasPrefix(endpoint, "https://") {
+		return nil, nil, fmt.Errorf("invalid etcd snapshot Azure endpoint %q: must be an https URL", azure.Endpoint)
+	}
+
+	switch {
+	case cred.SASToken != "":
+		env = []string{"AZURE_STORAGE_SAS_TOKEN=" + strings.TrimPrefix(cred.SASToken, "?")}
+	case cred.TenantID != "" && cred.ClientID != "" && cred.ClientSecret != "":
+		env = []string{
+			"AZURE_AUTHORITY_HOST=" + cloud.authorityHost,
+			"AZURE_TENANT_ID=" + cred.TenantID,
+			"AZURE_CLIENT_ID=" + cred.ClientID,
+			"AZURE_CLIENT_SECRET=" + cred.ClientSecret,
+		}
+	default:
+		return nil, nil, fmt.Errorf("the etcd snapshot cloud credential must have a SAS token, or the tenant ID, client ID and client secret of a service principal")
+	}
+
+	return []string{endpoint + "/" + azure.Container, folder}, env, nil
+}
+
+// normalizeSnapshotFolder strips leading, trailing and repeated slashes from the folder of an object store and
+// validates that it only contains characters that are safe to use in object names.
+func normalizeSnapshotFolder(folder, store string) (string, error) {
+	var segments []string
+	for _, segment := range strings.Split(strings.TrimSpace(folder), "/") {
+		if segment == "" {
+			continue
+		}
+		if segment == "." || segment == ".." || !s3FolderSegmentRegexp.MatchString(segment) {
+			return "", fmt.Errorf("invalid etcd snapshot %s folder %q: must only contain letters, numbers, slashes and the characters !-_.*'()", store, folder)
+		}
+		segments = append(segments, segment)
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// etcdSnapshotAzureUploadPlan returns the script and the instruction that offload the local snapshots of a node to the
+// Azure container of the control plane, if one is configured.
+func (p *Planner) etcdSnapshotAzureUploadPlan(controlPlane *rkev1.RKEControlPlane) ([]plan.File, []plan.OneTimeInstruction, error) {
+	if controlPlane.Spec.ETCD == nil || controlPlane.Spec.ETCD.Azure == nil {
+		return nil, nil, nil
+	}
+	args, env, err := p.etcdAzureArgs.ToArgs(controlPlane.Spec.ETCD.Azure, controlPlane)
+	if err != nil {
+		return nil, nil, err
+	}
+	scriptPath := etcdSnapshotScriptFile(controlPlane, etcdSnapshotAzureUploadScriptPath)
+	files := []plan.File{{
+		Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotAzureUploadScript)),
+		Path:    scriptPath,
+	}}
+	instruction := plan.OneTimeInstruction{
+		Name:    ETCDSnapshotAzureUploadInstruction,
+		Command: "sh",
+		Args:    append([]string{scriptPath, etcdSnapshotDir(controlPlane)}, args...),
+		Env:     env,
+	}
+	return files, []plan.OneTimeInstruction{instruction}, nil
+}
+
+type azureCredential struct {
+	SASToken     string
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+	Environment  string
+}
+
+func getAzureCredential(secretCache corecontrollers.SecretCache, namespace, name, clusterName string) (azureCredential, error) {
+	secret, err := getNodeCloudCredentialSecret(secretCache, namespace, name, clusterName)
+	if err != nil {
+		return azureCredential{}, fmt.Errorf("failed to lookup etcd snapshot Azure cloud credential: %w", err)
+	}
+
+	data := map[string][]byte{}
+	for k, v := range secret.Data {
+		_, k = kv.RSplit(k, "-")
+		data[k] = v
+	}
+
+	return azureCredential{
+		SASToken:     string(data["sasToken"]),
+		TenantID:     string(data["tenantId"]),
+		ClientID:     string(data["clientId"]),
+		ClientSecret: string(data["clientSecret"]),
+		Environment:  string(data["environment"]),
+	}, nil
+}
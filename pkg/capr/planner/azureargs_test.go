@@ -0,0 +1,96 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAzureUploadArgs(t *testing.T) {
+	servicePrincipal := azureCredential{TenantID: "tenant", ClientID: "client", ClientSecret: "secret"}
+
+	tests := []struct {
+		name         string
+		azure        rkev1.ETCDSnapshotAzure
+		cred         azureCredential
+		expectedArgs []string
+		expectedEnv  []string
+		expectErr    string
+	}{
+		{
+			name:         "sas token",
+			azure:        rkev1.ETCDSnapshotAzure{StorageAccount: "snapshots01", Container: "etcd-snapshots", Folder: "/prod//cluster/"},
+			cred:         azureCredential{SASToken: "?sv=2021-08-06&sig=abc"},
+			expectedArgs: []string{"https://snapshots01.blob.core.windows.net/etcd-snapshots", "prod/cluster"},
+			expectedEnv:  []string{"AZURE_STORAGE_SAS_TOKEN=sv=2021-08-06&sig=abc"},
+		},
+		{
+			name:         "service principal in sovereign cloud",
+			azure:        rkev1.ETCDSnapshotAzure{StorageAccount: "snapshots01", Container: "etcd"},
+			cred:         azureCredential{TenantID: "tenant", ClientID: "client", ClientSecret: "secret", Environment: "AzureUSGovernmentCloud"},
+			expectedArgs: []string{"https://snapshots01.blob.core.usgovcloudapi.net/etcd", ""},
+			expectedEnv: []string{
+				"AZURE_AUTHORITY_HOST=https://login.microsoftonline.us",
+				"AZURE_TENANT_ID=tenant",
+				"AZURE_CLIENT_ID=client",
+				"AZURE_CLIENT_SECRET=secret",
+			},
+		},
+		{
+			name:         "custom endpoint",
+			azure:        rkev1.ETCDSnapshotAzure{StorageAccount: "snapshots01", Container: "etcd", Endpoint: "https://snapshots01.privatelink.blob.core.windows.net/"},
+			cred:         azureCredential{SASToken: "sig=abc"},
+			expectedArgs: []string{"https://snapshots01.privatelink.blob.core.windows.net/etcd", ""},
+			expectedEnv:  []string{"AZURE_STORAGE_SAS_TOKEN=sig=abc"},
+		},
+		{
+			name:      "http endpoint",
+			azure:     rkev1.ETCDSnapshotAzure{StorageAccount: "snapshots01", Container: "etcd", Endpoint: "http://10.0.0.5:10000"},
+			cred:      servicePrincipal,
+			expectErr: `invalid etcd snapshot Azure endpoint "http://10.0.0.5:10000": must be an https URL`,
+		},
+		{
+			name:      "invalid storage account",
+			azure:     rkev1.ETCDSnapshotAzure{StorageAccount: "Snapshots", Container: "etcd"},
+			cred:      servicePrincipal,
+			expectErr: `invalid etcd snapshot Azure storage account "Snapshots": must consist of 3 to 24 lowercase letters and numbers`,
+		},
+		{
+			name:      "invalid container",
+			azure:     rkev1.ETCDSnapshotAzure{StorageAccount: "snapshots01", Container: "etcd--snapshots"},
+			cred:      servicePrincipal,
+			expectErr: `invalid etcd snapshot Azure container "etcd--snapshots": must be between 3 and 63 characters long, consist of lowercase letters, numbers and single hyphens, and begin and end with a letter or number`,
+		},
+		{
+			name:      "invalid folder",
+			azure:     rkev1.ETCDSnapshotAzure{StorageAccount: "snapshots01", Container: "etcd", Folder: "prod/../dev"},
+			cred:      servicePrincipal,
+			expectErr: `invalid etcd snapshot Azure folder "prod/../dev": must only contain letters, numbers, slashes and the characters !-_.*'()`,
+		},
+		{
+			name:      "unknown environment",
+			azure:     rkev1.ETCDSnapshotAzure{StorageAccount: "snapshots01", Container: "etcd"},
+			cred:      azureCredential{SASToken: "sig=abc", Environment: "AzureGermanCloud"},
+			expectErr: `unknown Azure environment "AzureGermanCloud" of the etcd snapshot cloud credential`,
+		},
+		{
+			name:      "incomplete service principal",
+			azure:     rkev1.ETCDSnapshotAzure{StorageAccount: "snapshots01", Container: "etcd"},
+			cred:      azureCredential{TenantID: "tenant", ClientID: "client"},
+			expectErr: "the etcd snapshot cloud credential must have a SAS token, or the tenant ID, client ID and client secret of a service principal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, env, err := azureUploadArgs(&tt.azure, tt.cred)
+			if tt.expectErr != "" {
+				assert.EqualError(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedArgs, args)
+			assert.Equal(t, tt.expectedEnv, env)
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package planner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	breakGlassAccessInstructionName = "break-glass-access"
+	breakGlassAccessScriptPath      = "rancher_v2prov_break_glass/bin/apply.sh"
+	breakGlassAccessKeysPath        = "rancher_v2prov_break_glass/authorized_keys"
+	maxBreakGlassAccessChanges      = 10
+
+	breakGlassAccessActionCreate = "create"
+	breakGlassAccessActionUpdate = "update"
+	breakGlassAccessActionRemove = "remove"
+
+	// breakGlassAccessUserComment is the comment of the users created by the break-glass access, which marks them as
+	// created by Rancher.
+	breakGlassAccessUserComment = "Rancher break-glass access"
+
+	breakGlassAccessScript = `#!/bin/sh
+# Creates, updates or removes the break-glass user. Users are marked as created by Rancher through their comment, so
+# that users which already existed on the node are neither taken over nor removed. Users created before they were
+# marked are recognized by the audit log. Every change made on the node is appended to the audit log.
+set -e
+user=$1
+keys=$2
+sudo=$3
+action=$4
+comment="` + breakGlassAccessUserComment + `"
+sudoers="/etc/sudoers.d/90-rancher-break-glass-$user"
+log=/var/log/rancher-break-glass-access.log
+
+audit() {
+	echo "$(date -u +%Y-%m-%dT%H:%M:%SZ) $*" >> "$log"
+}
+
+owned() {
+	if [ "$(getent passwd "$user" | cut -d: -f5)" = "$comment" ]; then
+		return 0
+	fi
+	if [ -f "$log" ] && grep -q " created user $user\$" "$log"; then
+		usermod -c "$comment" "$user"
+		return 0
+	fi
+	return 1
+}
+
+if [ "$action" = "remove" ]; then
+	if id "$user" > /dev/null 2>&1; then
+		if owned; then
+			userdel -r "$user" 2> /dev/null || userdel "$user"
+			audit "removed user $user"
+		else
+			audit "did not remove user $user as it was not created by Rancher"
+		fi
+	fi
+	rm -f "$sudoers"
+	exit 0
+fi
+
+if ! id "$user" > /dev/null 2>&1; then
+	shell=/bin/sh
+	if [ -x /bin/bash ]; then
+		shell=/bin/bash
+	fi
+	useradd -m -s "$shell" -c "$comment" "$user"
+	audit "created user $user"
+elif ! owned; then
+	audit "refused break-glass access for user $user as it already exists and was not created by Rancher"
+	echo "user $user already exists and was not created by Rancher, refusing to manage it as break-glass user" >&2
+	exit 1
+fi
+
+home=$(getent passwd "$user" | cut -d: -f6)
+mkdir -p "$home/.ssh"
+if ! cmp -s "$keys" "$home/.ssh/authorized_keys"; then
+	cp "$keys" "$home/.ssh/authorized_keys"
+	audit "replaced the authorized keys of user $user with $(grep -c . "$keys" || true) keys"
+fi
+chown -R "$user:" "$home/.ssh"
+chmod 0700 "$home/.ssh"
+chmod 0600 "$home/.ssh/authorized_keys"
+
+if [ "$sudo" = "true" ]; then
+	if [ ! -f "$sudoers" ]; then
+		echo "$user ALL=(ALL) NOPASSWD:ALL" > "$sudoers"
+		chmod 0440 "$sudoers"
+		audit "granted sudo to user $user"
+	fi
+elif [ -f "$sudoers" ]; then
+	rm -f "$sudoers"
+	audit "revoked sudo of user $user"
+fi
+`
+)
+
+var breakGlassUserRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+
+// validateBreakGlassAccess returns an error if the user of the break-glass access is not a valid linux user name or one
+// of the authorized keys is not a valid SSH public key.
+func validateBreakGlassAccess(access *rkev1.BreakGlassAccess) error {
+	if !breakGlassUserRegexp.MatchString(access.User) || access.User == "root" {
+		return fmt.Errorf("break-glass access user %q must be a valid linux user name other than root", access.User)
+	}
+	_, err := breakGlassKeyFingerprints(access.AuthorizedKeys)
+	return err
+}
+
+func breakGlassKeyFingerprints(authorizedKeys []string) ([]string, error) {
+	var fingerprints []string
+	for i, key := range authorizedKeys {
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("break-glass access authorized key %d is invalid: %w", i, err)
+		}
+		fingerprints = append(fingerprints, ssh.FingerprintSHA256(publicKey))
+	}
+	return fingerprints, nil
+}
+
+// addBreakGlassAccess adds the authorized keys and the instruction that applies the break-glass access of the control
+// plane to the plan of a linux machine. The instruction must be added after the install instruction, as changing the
+// authorized keys must not restart the distribution.
+func addBreakGlassAccess(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane, entry *planEntry) (plan.NodePlan, error) {
+	access := controlPlane.Spec.BreakGlassAccess
+	if access == nil || windows(entry) {
+		return nodePlan, nil
+	}
+	if err := validateBreakGlassAccess(access); err != nil {
+		return nodePlan, err
+	}
+
+	action := breakGlassAccessActionCreate
+	if access.Remove {
+		action = breakGlassAccessActionRemove
+	}
+	var keys strings.Builder
+	for _, key := range access.AuthorizedKeys {
+		keys.WriteString(strings.TrimSpace(key))
+		keys.WriteString("\n")
+	}
+	scriptPath := etcdSnapshotScriptFile(controlPlane, breakGlassAccessScriptPath)
+	keysPath := etcdSnapshotScriptFile(controlPlane, breakGlassAccessKeysPath)
+	nodePlan.Files = append(nodePlan.Files,
+		plan.File{
+			Content: base64.StdEncoding.EncodeToString([]byte(breakGlassAccessScript)),
+			Path:    scriptPath,
+		},
+		plan.File{
+			Content:     base64.StdEncoding.EncodeToString([]byte(keys.String())),
+			Path:        keysPath,
+			Permissions: "0600",
+		})
+	nodePlan.Instructions = append(nodePlan.Instructions, plan.OneTimeInstruction{
+		Name:    breakGlassAccessInstructionName,
+		Command: "sh",
+		Args: []string{
+			scriptPath,
+			access.User,
+			keysPath,
+			strconv.FormatBool(access.Sudo),
+			action,
+		},
+	})
+	return nodePlan, nil
+}
+
+// recordBreakGlassAccessChange appends the change of the break-glass access of the control plane since the last
+// recorded change to the status, keeping the latest changes only.
+func recordBreakGlassAccessChange(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, now metav1.Time) (rkev1.RKEControlPlaneStatus, error) {
+	access := controlPlane.Spec.BreakGlassAccess
+	if access == nil {
+		return status, nil
+	}
+	fingerprints, err := breakGlassKeyFingerprints(access.AuthorizedKeys)
+	if err != nil {
+		return status, err
+	}
+
+	change := rkev1.BreakGlassAccessChange{
+		Time:            now,
+		Action:          breakGlassAccessActionCreate,
+		User:            access.User,
+		KeyFingerprints: fingerprints,
+		Sudo:            access.Sudo,
+	}
+	var last *rkev1.BreakGlassAccessChange
+	if len(status.BreakGlassAccessChanges) > 0 {
+		last = &status.BreakGlassAccessChanges[len(status.BreakGlassAccessChanges)-1]
+	}
+	switch {
+	case access.Remove:
+		if last != nil && last.Action == breakGlassAccessActionRemove && last.User == access.User {
+			return status, nil
+		}
+		change.Action = breakGlassAccessActionRemove
+		change.KeyFingerprints = nil
+		change.Sudo = false
+	case last != nil && last.Action != breakGlassAccessActionRemove && last.User == access.User:
+		if last.Sudo == change.Sudo && equality.Semantic.DeepEqual(last.KeyFingerprints, change.KeyFingerprints) {
+			return status, nil
+		}
+		change.Action = breakGlassAccessActionUpdate
+	}
+
+	logrus.Infof("[planner] rkecluster %s/%s: break-glass access %s of user %s with keys %v, sudo %t", controlPlane.Namespace, controlPlane.Name,
+		change.Action, change.User, change.KeyFingerprints, change.Sudo)
+	// copy the changes, the status shares them with the cached control plane
+	changes := append([]rkev1.BreakGlassAccessChange{}, status.BreakGlassAccessChanges...)
+	status.BreakGlassAccessChanges = append(changes, change)
+	if len(status.BreakGlassAccessChanges) > maxBreakGlassAccessChanges {
+		status.BreakGlassAccessChanges = status.BreakGlassAccessChanges[len(status.BreakGlassAccessChanges)-maxBreakGlassAccessChanges:]
+	}
+	return status, nil
+}
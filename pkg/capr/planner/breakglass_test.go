@@ -0,0 +1,134 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testBreakGlassKey1            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAILsoxp/6uvx/k0Uo1b+5peBz5+YKfu3Bni6WWgSUYzXN admin@example.com"
+	testBreakGlassKey2            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGN+bbQtxt8VkOWJ9Kl85/yJa8mq+wKNIudIKD3inoyB admin@example.com"
+	testBreakGlassKey1Fingerprint = "SHA256:gdbqDCWKUH1bySKwDkXTk8QCDJnhDL9ZlEwlaXrFPy4"
+	testBreakGlassKey2Fingerprint = "SHA256:DAo7NB94CU/KEEKV4Eu7GqlYtYWI0QlgySkkvnQMSQ8"
+)
+
+func TestValidateBreakGlassAccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		access    rkev1.BreakGlassAccess
+		expectErr bool
+	}{
+		{
+			name:   "valid",
+			access: rkev1.BreakGlassAccess{User: "breakglass", AuthorizedKeys: []string{testBreakGlassKey1}},
+		},
+		{
+			name:      "root",
+			access:    rkev1.BreakGlassAccess{User: "root"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid user name",
+			access:    rkev1.BreakGlassAccess{User: "Break Glass"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid key",
+			access:    rkev1.BreakGlassAccess{User: "breakglass", AuthorizedKeys: []string{"ssh-ed25519 invalid"}},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBreakGlassAccess(&tt.access)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRecordBreakGlassAccessChange(t *testing.T) {
+	now := metav1.NewTime(time.Now())
+	change := func(action, user string, keys ...string) rkev1.BreakGlassAccessChange {
+		return rkev1.BreakGlassAccessChange{Time: now, Action: action, User: user, KeyFingerprints: keys}
+	}
+
+	tests := []struct {
+		name     string
+		access   *rkev1.BreakGlassAccess
+		changes  []rkev1.BreakGlassAccessChange
+		expected []rkev1.BreakGlassAccessChange
+	}{
+		{
+			name: "no break-glass access",
+		},
+		{
+			name:     "create",
+			access:   &rkev1.BreakGlassAccess{User: "breakglass", AuthorizedKeys: []string{testBreakGlassKey1}},
+			expected: []rkev1.BreakGlassAccessChange{change("create", "breakglass", testBreakGlassKey1Fingerprint)},
+		},
+		{
+			name:     "unchanged",
+			access:   &rkev1.BreakGlassAccess{User: "breakglass", AuthorizedKeys: []string{testBreakGlassKey1}},
+			changes:  []rkev1.BreakGlassAccessChange{change("create", "breakglass", testBreakGlassKey1Fingerprint)},
+			expected: []rkev1.BreakGlassAccessChange{change("create", "breakglass", testBreakGlassKey1Fingerprint)},
+		},
+		{
+			name:    "rotate keys",
+			access:  &rkev1.BreakGlassAccess{User: "breakglass", AuthorizedKeys: []string{testBreakGlassKey1, testBreakGlassKey2}},
+			changes: []rkev1.BreakGlassAccessChange{change("create", "breakglass", testBreakGlassKey1Fingerprint)},
+			expected: []rkev1.BreakGlassAccessChange{
+				change("create", "breakglass", testBreakGlassKey1Fingerprint),
+				change("update", "breakglass", testBreakGlassKey1Fingerprint, testBreakGlassKey2Fingerprint),
+			},
+		},
+		{
+			name:    "remove",
+			access:  &rkev1.BreakGlassAccess{User: "breakglass", AuthorizedKeys: []string{testBreakGlassKey1}, Remove: true},
+			changes: []rkev1.BreakGlassAccessChange{change("create", "breakglass", testBreakGlassKey1Fingerprint)},
+			expected: []rkev1.BreakGlassAccessChange{
+				change("create", "breakglass", testBreakGlassKey1Fingerprint),
+				change("remove", "breakglass"),
+			},
+		},
+		{
+			name:    "recreate after remove",
+			access:  &rkev1.BreakGlassAccess{User: "breakglass", AuthorizedKeys: []string{testBreakGlassKey1}},
+			changes: []rkev1.BreakGlassAccessChange{change("remove", "breakglass")},
+			expected: []rkev1.BreakGlassAccessChange{
+				change("remove", "breakglass"),
+				change("create", "breakglass", testBreakGlassKey1Fingerprint),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlPlane := &rkev1.RKEControlPlane{}
+			controlPlane.Spec.BreakGlassAccess = tt.access
+			status, err := recordBreakGlassAccessChange(controlPlane, rkev1.RKEControlPlaneStatus{BreakGlassAccessChanges: tt.changes}, now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, status.BreakGlassAccessChanges)
+		})
+	}
+}
+
+func TestRecordBreakGlassAccessChangeKeepsLatestChanges(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	controlPlane.Spec.BreakGlassAccess = &rkev1.BreakGlassAccess{User: "breakglass"}
+	status := rkev1.RKEControlPlaneStatus{}
+	for i := 0; i < maxBreakGlassAccessChanges+2; i++ {
+		controlPlane.Spec.BreakGlassAccess.Sudo = i%2 == 0
+		var err error
+		status, err = recordBreakGlassAccessChange(controlPlane, status, metav1.Now())
+		assert.NoError(t, err)
+	}
+	assert.Len(t, status.BreakGlassAccessChanges, maxBreakGlassAccessChanges)
+	assert.Equal(t, "update", status.BreakGlassAccessChanges[0].Action)
+}
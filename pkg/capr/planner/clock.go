@@ -0,0 +1,188 @@
+package planner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/settings"
+)
+
+const (
+	clockOffsetInstructionName = "clock-offset"
+	clockOffsetScriptPath      = "rancher_v2prov_clock/bin/offset.sh"
+	chronyInstructionName      = "configure-chrony"
+	chronyScriptPath           = "rancher_v2prov_clock/bin/chrony.sh"
+	defaultMaxClockSkew        = 3 * time.Second
+
+	clockOffsetScript = `#!/bin/sh
+# Prints the offset in seconds of the clock of the machine from the clock of the Rancher server, as measured with the
+# Date header of a response of the server. The certificate of the server is not verified, as it may not be valid yet or
+# anymore for a machine whose clock is off.
+server=$1
+before=$(date +%s)
+header=$(curl -sSIk --max-time 10 "$server/ping" | tr -d '\r' | sed -n 's/^[Dd]ate: //p')
+after=$(date +%s)
+if [ -z "$header" ]; then
+	echo "rancher server $server did not return its date" >&2
+	exit 1
+fi
+remote=$(date -d "$header" +%s)
+echo $(( (before + after) / 2 - remote ))
+`
+
+	chronyScript = `#!/bin/sh
+# Adds the NTP servers passed in as arguments to the chrony configuration, replacing the servers added by a previous
+# run, and steps the clock to them.
+set -e
+config=/etc/chrony.conf
+if [ -f /etc/chrony/chrony.conf ]; then
+	config=/etc/chrony/chrony.conf
+fi
+if [ ! -f "$config" ] || ! command -v chronyc > /dev/null 2>&1; then
+	echo "chrony is not installed, the NTP servers are not configured"
+	exit 0
+fi
+begin="# BEGIN rancher managed NTP servers"
+end="# END rancher managed NTP servers"
+sed -i "/^$begin\$/,/^$end\$/d" "$config"
+{
+	echo "$begin"
+	for server in "$@"; do
+		echo "server $server iburst"
+	done
+	echo "$end"
+} >> "$config"
+systemctl restart chronyd 2> /dev/null || systemctl restart chrony
+chronyc waitsync 6 > /dev/null 2>&1 || true
+chronyc makestep
+`
+)
+
+var ntpServerRegexp = regexp.MustCompile(`^[A-Za-z0-9.:-]+$`)
+
+// addClock adds the periodic instruction that measures the offset of the clock of a linux machine and, if NTP servers
+// are configured, the instruction that adds them to chrony to the plan of the machine. The chrony instruction is added
+// after the install instruction, as a change of the NTP servers must not restart the distribution.
+func addClock(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane, entry *planEntry) (plan.NodePlan, error) {
+	clock := controlPlane.Spec.Clock
+	if clock == nil || windows(entry) {
+		return nodePlan, nil
+	}
+	if _, err := maxClockSkew(clock); err != nil {
+		return nodePlan, err
+	}
+
+	if serverURL := settings.ServerURL.Get(); serverURL != "" {
+		scriptPath := etcdSnapshotScriptFile(controlPlane, clockOffsetScriptPath)
+		nodePlan.Files = append(nodePlan.Files, plan.File{
+			Content: base64.StdEncoding.EncodeToString([]byte(clockOffsetScript)),
+			Path:    scriptPath,
+		})
+		nodePlan.PeriodicInstructions = append(nodePlan.PeriodicInstructions, plan.PeriodicInstruction{
+			Name:          clockOffsetInstructionName,
+			Command:       "sh",
+			Args:          []string{scriptPath, strings.TrimSuffix(serverURL, "/")},
+			PeriodSeconds: 300,
+		})
+	}
+
+	if len(clock.NTPServers) > 0 {
+		for _, server := range clock.NTPServers {
+			if !ntpServerRegexp.MatchString(server) {
+				return nodePlan, fmt.Errorf("NTP server %q must be a host name or an IP address", server)
+			}
+		}
+		scriptPath := etcdSnapshotScriptFile(controlPlane, chronyScriptPath)
+		nodePlan.Files = append(nodePlan.Files, plan.File{
+			Content: base64.StdEncoding.EncodeToString([]byte(chronyScript)),
+			Path:    scriptPath,
+		})
+		nodePlan.Instructions = append(nodePlan.Instructions, plan.OneTimeInstruction{
+			Name:    chronyInstructionName,
+			Command: "sh",
+			Args:    append([]string{scriptPath}, clock.NTPServers...),
+		})
+	}
+	return nodePlan, nil
+}
+
+func maxClockSkew(clock *rkev1.Clock) (time.Duration, error) {
+	if clock.MaxSkew == "" {
+		return defaultMaxClockSkew, nil
+	}
+	maxSkew, err := time.ParseDuration(clock.MaxSkew)
+	if err != nil {
+		return 0, fmt.Errorf("invalid maximum clock skew %q: %w", clock.MaxSkew, err)
+	}
+	return maxSkew, nil
+}
+
+// reportClockSkew sets the ClocksSynchronized condition of the control plane from the clock offsets last measured by
+// the machines. Machines that did not measure their offset yet, or failed to, are not taken into account.
+func reportClockSkew(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterPlan *plan.Plan) rkev1.RKEControlPlaneStatus {
+	if controlPlane.Spec.Clock == nil {
+		return status
+	}
+	maxSkew, err := maxClockSkew(controlPlane.Spec.Clock)
+	if err != nil {
+		return status
+	}
+
+	offsets := map[string]int{}
+	for _, entry := range collect(clusterPlan, roleAnd(anyRoleWithoutWindows, isNotDeleting)) {
+		output, ok := entry.Plan.PeriodicOutput[clockOffsetInstructionName]
+		if !ok || output.ExitCode != 0 {
+			continue
+		}
+		offset, err := strconv.Atoi(strings.TrimSpace(string(output.Stdout)))
+		if err != nil {
+			continue
+		}
+		offsets[entry.Machine.Name] = offset
+	}
+	if len(offsets) == 0 {
+		return status
+	}
+
+	skew, message := clockSkew(offsets)
+	if skew > maxSkew {
+		capr.ClocksSynchronized.False(&status)
+		capr.ClocksSynchronized.Message(&status, fmt.Sprintf("clocks of the machines are %s apart, more than the maximum skew of %s: %s", skew, maxSkew, message))
+	} else {
+		capr.ClocksSynchronized.True(&status)
+		capr.ClocksSynchronized.Message(&status, "")
+	}
+	return status
+}
+
+// clockSkew returns the difference between the furthest apart offsets in seconds and the offsets of the machines
+// formatted for the condition message.
+func clockSkew(offsets map[string]int) (time.Duration, string) {
+	machines := make([]string, 0, len(offsets))
+	for machine := range offsets {
+		machines = append(machines, machine)
+	}
+	sort.Strings(machines)
+
+	minOffset, maxOffset := offsets[machines[0]], offsets[machines[0]]
+	formatted := make([]string, 0, len(machines))
+	for _, machine := range machines {
+		offset := offsets[machine]
+		if offset < minOffset {
+			minOffset = offset
+		}
+		if offset > maxOffset {
+			maxOffset = offset
+		}
+		formatted = append(formatted, fmt.Sprintf("%s %+ds", machine, offset))
+	}
+	return time.Duration(maxOffset-minOffset) * time.Second, strings.Join(formatted, ", ")
+}
@@ -0,0 +1,125 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func clockPlan(offsets map[string]plan.PeriodicInstructionOutput) *plan.Plan {
+	clusterPlan := &plan.Plan{
+		Machines: map[string]*capi.Machine{},
+		Nodes:    map[string]*plan.Node{},
+		Metadata: map[string]*plan.Metadata{},
+	}
+	for name, output := range offsets {
+		clusterPlan.Machines[name] = &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		clusterPlan.Nodes[name] = &plan.Node{PeriodicOutput: map[string]plan.PeriodicInstructionOutput{clockOffsetInstructionName: output}}
+		clusterPlan.Metadata[name] = &plan.Metadata{Labels: map[string]string{capr.WorkerRoleLabel: "true"}}
+	}
+	return clusterPlan
+}
+
+func TestReportClockSkew(t *testing.T) {
+	offset := func(stdout string) plan.PeriodicInstructionOutput {
+		return plan.PeriodicInstructionOutput{Stdout: []byte(stdout)}
+	}
+
+	tests := []struct {
+		name            string
+		clock           *rkev1.Clock
+		offsets         map[string]plan.PeriodicInstructionOutput
+		expectedStatus  string
+		expectedMessage string
+	}{
+		{
+			name:    "not observed",
+			offsets: map[string]plan.PeriodicInstructionOutput{"a": offset("0\n"), "b": offset("30\n")},
+		},
+		{
+			name:  "no offsets measured yet",
+			clock: &rkev1.Clock{},
+		},
+		{
+			name:           "synchronized",
+			clock:          &rkev1.Clock{},
+			offsets:        map[string]plan.PeriodicInstructionOutput{"a": offset("1\n"), "b": offset("-1\n")},
+			expectedStatus: "True",
+		},
+		{
+			name:            "skewed",
+			clock:           &rkev1.Clock{},
+			offsets:         map[string]plan.PeriodicInstructionOutput{"a": offset("1\n"), "b": offset("-4\n"), "c": offset("0\n")},
+			expectedStatus:  "False",
+			expectedMessage: "clocks of the machines are 5s apart, more than the maximum skew of 3s: a +1s, b -4s, c +0s",
+		},
+		{
+			name:           "maximum skew",
+			clock:          &rkev1.Clock{MaxSkew: "10s"},
+			offsets:        map[string]plan.PeriodicInstructionOutput{"a": offset("1\n"), "b": offset("-4\n")},
+			expectedStatus: "True",
+		},
+		{
+			name:  "failed and invalid measurements are ignored",
+			clock: &rkev1.Clock{},
+			offsets: map[string]plan.PeriodicInstructionOutput{
+				"a": offset("1\n"),
+				"b": {ExitCode: 1, Stdout: []byte("-30\n")},
+				"c": offset("not a number"),
+			},
+			expectedStatus: "True",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlPlane := &rkev1.RKEControlPlane{Spec: rkev1.RKEControlPlaneSpec{RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{Clock: tt.clock}}}
+			status := reportClockSkew(controlPlane, rkev1.RKEControlPlaneStatus{}, clockPlan(tt.offsets))
+			assert.Equal(t, tt.expectedStatus, capr.ClocksSynchronized.GetStatus(&status))
+			assert.Equal(t, tt.expectedMessage, capr.ClocksSynchronized.GetMessage(&status))
+		})
+	}
+}
+
+func TestAddClock(t *testing.T) {
+	original := settings.ServerURL.Get()
+	t.Cleanup(func() { _ = settings.ServerURL.Set(original) })
+	require.NoError(t, settings.ServerURL.Set("https://rancher.example.com/"))
+
+	controlPlane := createTestControlPlane("v1.25.7+rke2r1")
+	nodePlan, err := addClock(plan.NodePlan{}, controlPlane, createTestPlanEntry("linux"))
+	require.NoError(t, err)
+	assert.Empty(t, nodePlan.PeriodicInstructions, "no instruction without clock settings")
+
+	controlPlane.Spec.Clock = &rkev1.Clock{}
+	nodePlan, err = addClock(plan.NodePlan{}, controlPlane, createTestPlanEntry("windows"))
+	require.NoError(t, err)
+	assert.Empty(t, nodePlan.PeriodicInstructions, "no instruction for windows machines")
+
+	nodePlan, err = addClock(plan.NodePlan{}, controlPlane, createTestPlanEntry("linux"))
+	require.NoError(t, err)
+	require.Len(t, nodePlan.PeriodicInstructions, 1)
+	assert.Empty(t, nodePlan.Instructions)
+	assert.Equal(t, []string{nodePlan.Files[0].Path, "https://rancher.example.com"}, nodePlan.PeriodicInstructions[0].Args)
+
+	controlPlane.Spec.Clock.NTPServers = []string{"0.pool.ntp.org", "10.0.0.1"}
+	nodePlan, err = addClock(plan.NodePlan{}, controlPlane, createTestPlanEntry("linux"))
+	require.NoError(t, err)
+	require.Len(t, nodePlan.Instructions, 1)
+	assert.Equal(t, chronyInstructionName, nodePlan.Instructions[0].Name)
+	assert.Equal(t, []string{nodePlan.Files[1].Path, "0.pool.ntp.org", "10.0.0.1"}, nodePlan.Instructions[0].Args)
+
+	controlPlane.Spec.Clock.NTPServers = []string{"pool.ntp.org\nallow all"}
+	_, err = addClock(plan.NodePlan{}, controlPlane, createTestPlanEntry("linux"))
+	assert.Error(t, err)
+
+	controlPlane.Spec.Clock = &rkev1.Clock{MaxSkew: "three seconds"}
+	_, err = addClock(plan.NodePlan{}, controlPlane, createTestPlanEntry("linux"))
+	assert.Error(t, err)
+}
@@ -6,6 +6,7 @@
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
@@ -45,8 +46,17 @@ func (p *Planner) addETCD(config map[string]interface{}, controlPlane *rkev1.RKE
 	if controlPlane.Spec.ETCD.SnapshotScheduleCron != "" {
 		config["etcd-snapshot-schedule-cron"] = controlPlane.Spec.ETCD.SnapshotScheduleCron
 	}
+	if controlPlane.Spec.ETCD.SnapshotDir != "" {
+		if !path.IsAbs(controlPlane.Spec.ETCD.SnapshotDir) {
+			return nil, fmt.Errorf("etcd snapshot directory %s must be an absolute path", controlPlane.Spec.ETCD.SnapshotDir)
+		}
+		config["etcd-snapshot-dir"] = controlPlane.Spec.ETCD.SnapshotDir
+	}
+	if err := addETCDTuning(config, controlPlane.Spec.ETCD.Tuning); err != nil {
+		return nil, err
+	}
 
-	args, _, files, err := p.etcdS3Args.ToArgs(controlPlane.Spec.ETCD.S3, controlPlane, "etcd-", false)
+	args, _, files, err := p.etcdS3Args.ToArgs(controlPlane.Spec.ETCD.S3, controlPlane, entry, "etcd-", false)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +76,7 @@ func (p *Planner) addETCD(config map[string]interface{}, controlPlane *rkev1.RKE
 
 func addDefaults(config map[string]interface{}, controlPlane *rkev1.RKEControlPlane) {
 	if capr.GetRuntime(controlPlane.Spec.KubernetesVersion) == capr.RuntimeRKE2 {
-		config["cni"] = "calico"
+		config["cni"] = capr.DefaultRKE2CNI
 	}
 }
 
@@ -228,6 +238,10 @@ func (p *Planner) addChartConfigs(nodePlan plan.NodePlan, controlPlane *rkev1.RK
 	if err != nil {
 		return nodePlan, err
 	}
+	chartValues, err = addCoreDNSChartValues(controlPlane, chartValues)
+	if err != nil {
+		return nodePlan, err
+	}
 
 	var chartConfigs []runtime.Object
 	for _, chart := range capr.SortedKeys(chartValues) {
@@ -272,7 +286,7 @@ func (p *Planner) addChartConfigs(nodePlan plan.NodePlan, controlPlane *rkev1.RK
 
 func addOtherFiles(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane, entry *planEntry) (plan.NodePlan, error) {
 	nodePlan = addLocalClusterAuthenticationEndpointFile(nodePlan, controlPlane, entry)
-	return nodePlan, nil
+	return addCoreDNSCustomConfig(nodePlan, controlPlane, entry)
 }
 
 func restartStamp(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane, image string) string {
@@ -457,6 +471,10 @@ func configFile(controlPlane *rkev1.RKEControlPlane, filename string) string {
 		capr.GetRuntime(controlPlane.Spec.KubernetesVersion), filename)
 }
 
+// renderFiles renders the files of the machine selector files of the control plane that select the machine of the entry.
+// The content of the files is read from secrets and configmaps in the namespace of the control plane, which must be
+// authorized for the cluster. As the files are part of the plan, a change of the content of a non-dynamic file causes
+// the distribution to be restarted with it.
 func (p *Planner) renderFiles(controlPlane *rkev1.RKEControlPlane, entry *planEntry) ([]plan.File, error) {
 	var files []plan.File
 	for _, msf := range controlPlane.Spec.MachineSelectorFiles {
@@ -477,27 +495,14 @@ func (p *Planner) renderFiles(controlPlane *rkev1.RKEControlPlane, entry *planEn
 				if err != nil {
 					return files, fmt.Errorf("error retrieving secret %s/%s while rendering files: %v", controlPlane.Namespace, fs.Secret.Name, err)
 				}
-				if authorized, found := clusterObjectAuthorized(secret, capr.AuthorizedObjectAnnotation, controlPlane.Name); authorized && found {
-					for _, v := range fs.Secret.Items {
-						file := plan.File{
-							Path:    v.Path,
-							Content: base64.StdEncoding.EncodeToString(secret.Data[v.Key]),
-							Dynamic: v.Dynamic,
-						}
-						hash := sha256.Sum256(secret.Data[v.Key])
-						if v.Hash != "" && v.Hash != base64.StdEncoding.EncodeToString(hash[:]) {
-							return files, fmt.Errorf("secret %s does not cotain the expected content", secret.Name)
-						}
-						if v.Permissions != "" {
-							file.Permissions = v.Permissions
-						} else if fs.Secret.DefaultPermissions != "" {
-							file.Permissions = fs.Secret.DefaultPermissions
-						}
-						files = append(files, file)
-					}
-				} else {
+				if authorized, found := clusterObjectAuthorized(secret, capr.AuthorizedObjectAnnotation, controlPlane.Name); !authorized || !found {
 					return files, fmt.Errorf("error rendering files: cluster %s/%s was not authorized to access secret %s/%s", controlPlane.Namespace, controlPlane.Name, controlPlane.Namespace, fs.Secret.Name)
 				}
+				sourced, err := sourcedFiles("secret", secret, fs.Secret, secretFileLookup(secret))
+				if err != nil {
+					return files, err
+				}
+				files = append(files, sourced...)
 			}
 			if fs.ConfigMap.Name != "" {
 				configmap, err := p.configMapCache.Get(controlPlane.Namespace, fs.ConfigMap.Name)
@@ -505,33 +510,114 @@ func (p *Planner) renderFiles(controlPlane *rkev1.RKEControlPlane, entry *planEn
 					return files, fmt.Errorf("error retrieving configmap %s/%s while rendering files: %v", controlPlane.Namespace, fs.ConfigMap.Name, err)
 				}
 				// retrieve configmap and use contents
-				if authorized, found := clusterObjectAuthorized(configmap, capr.AuthorizedObjectAnnotation, controlPlane.Name); authorized && found {
-					for _, v := range fs.ConfigMap.Items {
-						file := plan.File{
-							Path:    v.Path,
-							Content: base64.StdEncoding.EncodeToString([]byte(configmap.Data[v.Key])),
-							Dynamic: v.Dynamic,
-						}
-						hash := sha256.Sum256([]byte(configmap.Data[v.Key]))
-						if v.Hash != "" && v.Hash != base64.StdEncoding.EncodeToString(hash[:]) {
-							return files, fmt.Errorf("configmap %s does not cotain the expected content", configmap.Name)
-						}
-						if v.Permissions != "" {
-							file.Permissions = v.Permissions
-						} else if fs.ConfigMap.DefaultPermissions != "" {
-							file.Permissions = fs.ConfigMap.DefaultPermissions
-						}
-						files = append(files, file)
-					}
-				} else {
+				if authorized, found := clusterObjectAuthorized(configmap, capr.AuthorizedObjectAnnotation, controlPlane.Name); !authorized || !found {
 					return files, fmt.Errorf("error rendering files: cluster %s/%s was not authorized to access configmap %s/%s", controlPlane.Namespace, controlPlane.Name, controlPlane.Namespace, fs.ConfigMap.Name)
 				}
+				sourced, err := sourcedFiles("configmap", configmap, fs.ConfigMap, configMapFileLookup(configmap))
+				if err != nil {
+					return files, err
+				}
+				files = append(files, sourced...)
 			}
 		}
 	}
 	return files, nil
 }
 
+// secretFileLookup returns the lookup function reading the content of files from the data of the secret.
+func secretFileLookup(secret *v1.Secret) func(key string) ([]byte, bool) {
+	return func(key string) ([]byte, bool) {
+		data, ok := secret.Data[key]
+		return data, ok
+	}
+}
+
+// configMapFileLookup returns the lookup function reading the content of files from the data of the configmap, falling
+// back to its binary data.
+func configMapFileLookup(configmap *v1.ConfigMap) func(key string) ([]byte, bool) {
+	return func(key string) ([]byte, bool) {
+		if data, ok := configmap.Data[key]; ok {
+			return []byte(data), true
+		}
+		data, ok := configmap.BinaryData[key]
+		return data, ok
+	}
+}
+
+// sourcedFiles returns the files for the items of the file source, reading their content from the secret or configmap
+// with the passed in lookup function. An item whose key does not exist in the object is written as an empty file, which
+// is reported by reportFileSources. If the item has a hash, the base64 encoded SHA-256 sum of the content must match it.
+func sourcedFiles(kind string, obj metav1.Object, source rkev1.K8sObjectFileSource, lookup func(key string) ([]byte, bool)) ([]plan.File, error) {
+	var files []plan.File
+	for _, v := range source.Items {
+		content, _ := lookup(v.Key)
+		hash := sha256.Sum256(content)
+		if v.Hash != "" && v.Hash != base64.StdEncoding.EncodeToString(hash[:]) {
+			return files, fmt.Errorf("%s %s/%s does not contain the expected content for key %s", kind, obj.GetNamespace(), obj.GetName(), v.Key)
+		}
+		file := plan.File{
+			Path:    v.Path,
+			Content: base64.StdEncoding.EncodeToString(content),
+			Dynamic: v.Dynamic,
+		}
+		if v.Permissions != "" {
+			file.Permissions = v.Permissions
+		} else if source.DefaultPermissions != "" {
+			file.Permissions = source.DefaultPermissions
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// missingFileKeys returns the items of the file source whose key does not exist in the secret or configmap, formatted
+// for the condition message.
+func missingFileKeys(kind string, obj metav1.Object, source rkev1.K8sObjectFileSource, lookup func(key string) ([]byte, bool)) []string {
+	var missing []string
+	for _, v := range source.Items {
+		if _, ok := lookup(v.Key); !ok {
+			missing = append(missing, fmt.Sprintf("%s %s/%s key %s for file %s", kind, obj.GetNamespace(), obj.GetName(), v.Key, v.Path))
+		}
+	}
+	return missing
+}
+
+// reportFileSources sets the FileSourcesResolved condition of the control plane to false if keys referenced by the
+// machine selector files do not exist in their secret or configmap, in which case the files are written empty. Sources
+// that cannot be retrieved or are not authorized for the cluster fail rendering the plans instead, and are skipped.
+func (p *Planner) reportFileSources(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) rkev1.RKEControlPlaneStatus {
+	var missing []string
+	for _, msf := range controlPlane.Spec.MachineSelectorFiles {
+		for _, fs := range msf.FileSources {
+			if fs.Secret.Name != "" {
+				secret, err := p.secretCache.Get(controlPlane.Namespace, fs.Secret.Name)
+				if err == nil {
+					if authorized, found := clusterObjectAuthorized(secret, capr.AuthorizedObjectAnnotation, controlPlane.Name); authorized && found {
+						missing = append(missing, missingFileKeys("secret", secret, fs.Secret, secretFileLookup(secret))...)
+					}
+				}
+			}
+			if fs.ConfigMap.Name != "" {
+				configmap, err := p.configMapCache.Get(controlPlane.Namespace, fs.ConfigMap.Name)
+				if err == nil {
+					if authorized, found := clusterObjectAuthorized(configmap, capr.AuthorizedObjectAnnotation, controlPlane.Name); authorized && found {
+						missing = append(missing, missingFileKeys("configmap", configmap, fs.ConfigMap, configMapFileLookup(configmap))...)
+					}
+				}
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		capr.FileSourcesResolved.False(&status)
+		capr.FileSourcesResolved.Message(&status, fmt.Sprintf("files are written empty as their keys do not exist: %s", strings.Join(missing, ", ")))
+	} else if capr.FileSourcesResolved.GetStatus(&status) != "" {
+		capr.FileSourcesResolved.True(&status)
+		capr.FileSourcesResolved.Message(&status, "")
+	}
+	return status
+}
+
 // addConfigFile will render the distribution configuration file and add it to the nodePlan. It also renders files that
 // are referenced in the distribution configuration file (for example, ACE and the cloud-provider). It returns the updated
 // NodePlan, the config that was rendered in a map, the joined server, and an error if one exists.
@@ -553,6 +639,13 @@ func (p *Planner) addConfigFile(nodePlan plan.NodePlan, controlPlane *rkev1.RKEC
 	}
 	nodePlan.Files = append(nodePlan.Files, files...)
 
+	if err := addKubeProxy(config, controlPlane); err != nil {
+		return nodePlan, config, "", err
+	}
+	if err := p.addContainerRuntime(config, controlPlane, entry); err != nil {
+		return nodePlan, config, "", err
+	}
+
 	joinedServer := addRoleConfig(config, controlPlane, entry, joinServer)
 	addLocalClusterAuthenticationEndpointConfig(config, controlPlane, entry)
 	addToken(config, entry, tokensSecret)
@@ -566,6 +659,12 @@ func (p *Planner) addConfigFile(nodePlan plan.NodePlan, controlPlane *rkev1.RKEC
 	if err := addTaints(config, entry, controlPlane); err != nil {
 		return nodePlan, config, joinedServer, err
 	}
+	if err := translateRemovedFlags(config, controlPlane, entry); err != nil {
+		return nodePlan, config, joinedServer, err
+	}
+	if err := lintConfig(config, controlPlane, entry); err != nil {
+		return nodePlan, config, joinedServer, err
+	}
 
 	for _, fileParam := range fileParams {
 		var content interface{}
@@ -0,0 +1,162 @@
+package planner
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/genericcondition"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSourcedFiles(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "license", Namespace: "fleet-default"},
+		Data: map[string][]byte{
+			"license": []byte("licensed"),
+			"empty":   {},
+		},
+	}
+	lookup := func(key string) ([]byte, bool) {
+		data, ok := secret.Data[key]
+		return data, ok
+	}
+	hash := sha256.Sum256([]byte("licensed"))
+
+	tests := []struct {
+		name     string
+		source   rkev1.K8sObjectFileSource
+		expected []plan.File
+		wantErr  bool
+	}{
+		{
+			name: "default permissions",
+			source: rkev1.K8sObjectFileSource{
+				Name:               "license",
+				DefaultPermissions: "0600",
+				Items: []rkev1.KeyToPath{
+					{Key: "license", Path: "/etc/license", Hash: base64.StdEncoding.EncodeToString(hash[:])},
+					{Key: "empty", Path: "/etc/empty", Permissions: "0644", Dynamic: true},
+				},
+			},
+			expected: []plan.File{
+				{Path: "/etc/license", Content: base64.StdEncoding.EncodeToString([]byte("licensed")), Permissions: "0600"},
+				{Path: "/etc/empty", Content: "", Permissions: "0644", Dynamic: true},
+			},
+		},
+		{
+			name: "missing key",
+			source: rkev1.K8sObjectFileSource{
+				Name:  "license",
+				Items: []rkev1.KeyToPath{{Key: "missing", Path: "/etc/license"}},
+			},
+			expected: []plan.File{
+				{Path: "/etc/license", Content: ""},
+			},
+		},
+		{
+			name: "missing key with hash",
+			source: rkev1.K8sObjectFileSource{
+				Name:  "license",
+				Items: []rkev1.KeyToPath{{Key: "missing", Path: "/etc/license", Hash: base64.StdEncoding.EncodeToString(hash[:])}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hash mismatch",
+			source: rkev1.K8sObjectFileSource{
+				Name:  "license",
+				Items: []rkev1.KeyToPath{{Key: "empty", Path: "/etc/license", Hash: base64.StdEncoding.EncodeToString(hash[:])}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := sourcedFiles("secret", secret, tt.source, lookup)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, files)
+		})
+	}
+}
+
+func TestReportFileSources(t *testing.T) {
+	p := &Planner{secretCache: &fakeSecretCache{secrets: []*corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "license",
+				Namespace:   "fleet-default",
+				Annotations: map[string]string{capr.AuthorizedObjectAnnotation: "test"},
+			},
+			Data: map[string][]byte{"license": []byte("licensed")},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "unauthorized", Namespace: "fleet-default"},
+		},
+	}}}
+
+	tests := []struct {
+		name            string
+		items           []rkev1.KeyToPath
+		secret          string
+		status          rkev1.RKEControlPlaneStatus
+		expectedStatus  string
+		expectedMessage string
+	}{
+		{
+			name:   "all keys exist",
+			secret: "license",
+			items:  []rkev1.KeyToPath{{Key: "license", Path: "/etc/license"}},
+		},
+		{
+			name:            "missing key",
+			secret:          "license",
+			items:           []rkev1.KeyToPath{{Key: "license", Path: "/etc/license"}, {Key: "missing", Path: "/etc/missing"}},
+			expectedStatus:  "False",
+			expectedMessage: "files are written empty as their keys do not exist: secret fleet-default/license key missing for file /etc/missing",
+		},
+		{
+			name:   "missing key resolved",
+			secret: "license",
+			items:  []rkev1.KeyToPath{{Key: "license", Path: "/etc/license"}},
+			status: rkev1.RKEControlPlaneStatus{Conditions: []genericcondition.GenericCondition{
+				{Type: string(capr.FileSourcesResolved), Status: "False", Message: "files are written empty"},
+			}},
+			expectedStatus: "True",
+		},
+		{
+			name:   "missing secret",
+			secret: "missing",
+			items:  []rkev1.KeyToPath{{Key: "missing", Path: "/etc/missing"}},
+		},
+		{
+			name:   "unauthorized secret",
+			secret: "unauthorized",
+			items:  []rkev1.KeyToPath{{Key: "missing", Path: "/etc/missing"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlPlane := &rkev1.RKEControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"},
+			}
+			controlPlane.Spec.MachineSelectorFiles = []rkev1.RKEProvisioningFiles{{
+				FileSources: []rkev1.ProvisioningFileSource{{
+					Secret: rkev1.K8sObjectFileSource{Name: tt.secret, Items: tt.items},
+				}},
+			}}
+			status := p.reportFileSources(controlPlane, tt.status)
+			assert.Equal(t, tt.expectedStatus, capr.FileSourcesResolved.GetStatus(&status))
+			assert.Equal(t, tt.expectedMessage, capr.FileSourcesResolved.GetMessage(&status))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/slice"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	componentArgSuffix  = "-arg"
+	rancherConfigSource = "rancher"
+)
+
+// repeatableFlags are component flags that are accumulated by the component when passed more than once, rather than
+// overridden by the last occurrence.
+var repeatableFlags = map[string]bool{
+	"api-audiences":             true,
+	"disable-admission-plugins": true,
+	"enable-admission-plugins":  true,
+	"feature-gates":             true,
+	"runtime-config":            true,
+	"service-account-issuer":    true,
+	"service-account-key-file":  true,
+	"tls-cipher-suites":         true,
+	"tls-sni-cert-key":          true,
+}
+
+// lintConfig checks the component args (such as kube-apiserver-arg) of the rendered distribution config for flags that
+// are passed more than once. Components only honor the last occurrence of most flags, so a flag that is duplicated or
+// given conflicting values fails plan generation instead of having one of the values silently ignored.
+func lintConfig(config map[string]interface{}, controlPlane *rkev1.RKEControlPlane, entry *planEntry) error {
+	var keys []string
+	for k := range config {
+		if strings.HasSuffix(k, componentArgSuffix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flags := map[string][]string{}
+		var order []string
+		for _, arg := range convertInterfaceToStringSlice(config[key]) {
+			flag, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+			if repeatableFlags[flag] {
+				continue
+			}
+			if _, ok := flags[flag]; !ok {
+				order = append(order, flag)
+			}
+			flags[flag] = append(flags[flag], arg)
+		}
+
+		for _, flag := range order {
+			args := flags[flag]
+			if len(args) < 2 {
+				continue
+			}
+
+			described := make([]string, 0, len(args))
+			conflicting := false
+			for _, arg := range args {
+				source, err := argSource(controlPlane, entry, key, arg)
+				if err != nil {
+					return err
+				}
+				described = append(described, fmt.Sprintf("%q (%s)", arg, source))
+				conflicting = conflicting || strings.TrimPrefix(arg, "--") != strings.TrimPrefix(args[0], "--")
+			}
+
+			if conflicting {
+				return fmt.Errorf("conflicting values for flag %s in %s of machine %s/%s: %s", flag, key, entry.Machine.Namespace, entry.Machine.Name, strings.Join(described, ", "))
+			}
+			return fmt.Errorf("duplicate flag %s in %s of machine %s/%s: %s", flag, key, entry.Machine.Namespace, entry.Machine.Name, strings.Join(described, ", "))
+		}
+	}
+
+	return nil
+}
+
+// argSource returns where the arg of the config key was set for the machine of the entry. As the config of a matching
+// machineSelectorConfig replaces the value of the key set by the machineGlobalConfig or a previous
+// machineSelectorConfig, the arg is attributed to the last of them that set the key. If none of them set the arg, it was
+// added by Rancher.
+func argSource(controlPlane *rkev1.RKEControlPlane, entry *planEntry, key, arg string) (string, error) {
+	source := ""
+	if v, ok := controlPlane.Spec.MachineGlobalConfig.Data[key]; ok && slice.ContainsString(convertInterfaceToStringSlice(v), arg) {
+		source = "machineGlobalConfig"
+	}
+
+	for i, opts := range controlPlane.Spec.MachineSelectorConfig {
+		sel, err := metav1.LabelSelectorAsSelector(opts.MachineLabelSelector)
+		if err != nil {
+			return "", err
+		}
+		if opts.MachineLabelSelector != nil && !sel.Matches(labels.Set(entry.Machine.Labels)) {
+			continue
+		}
+		if v, ok := opts.Config.Data[key]; ok {
+			source = ""
+			if slice.ContainsString(convertInterfaceToStringSlice(v), arg) {
+				source = fmt.Sprintf("machineSelectorConfig[%d]", i)
+			}
+		}
+	}
+
+	if source == "" {
+		return rancherConfigSource, nil
+	}
+	return source, nil
+}
@@ -0,0 +1,113 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestLintConfig(t *testing.T) {
+	entry := &planEntry{
+		Machine: &capi.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "fleet-default",
+				Name:      "machine",
+				Labels:    map[string]string{"role": "control-plane"},
+			},
+		},
+	}
+
+	controlPlane := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+				MachineGlobalConfig: rkev1.GenericMap{
+					Data: map[string]interface{}{
+						"kube-apiserver-arg": []interface{}{"audit-log-maxage=10"},
+					},
+				},
+				MachineSelectorConfig: []rkev1.RKESystemConfig{
+					{
+						MachineLabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "worker"}},
+						Config: rkev1.GenericMap{
+							Data: map[string]interface{}{
+								"kubelet-arg": []interface{}{"max-pods=100"},
+							},
+						},
+					},
+					{
+						MachineLabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "control-plane"}},
+						Config: rkev1.GenericMap{
+							Data: map[string]interface{}{
+								"kubelet-arg": []interface{}{"max-pods=110", "max-pods=250"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		config      map[string]interface{}
+		expectedErr string
+	}{
+		{
+			name: "no repeated flags",
+			config: map[string]interface{}{
+				"kube-apiserver-arg": []string{"audit-log-maxage=10", "--audit-log-maxbackup=5"},
+				"kubelet-arg":        []interface{}{"max-pods=110"},
+				"node-label":         []string{"a=b", "a=c"},
+			},
+		},
+		{
+			name: "repeatable flag",
+			config: map[string]interface{}{
+				"kube-apiserver-arg": []string{"feature-gates=A=true", "feature-gates=B=true"},
+			},
+		},
+		{
+			name: "conflicting values from user and rancher",
+			config: map[string]interface{}{
+				"kube-apiserver-arg": []string{"audit-log-maxage=10", "--audit-log-maxage=30"},
+			},
+			expectedErr: `conflicting values for flag audit-log-maxage in kube-apiserver-arg of machine fleet-default/machine: "audit-log-maxage=10" (machineGlobalConfig), "--audit-log-maxage=30" (rancher)`,
+		},
+		{
+			name: "conflicting values from machine selector config",
+			config: map[string]interface{}{
+				"kubelet-arg": []interface{}{"max-pods=110", "max-pods=250"},
+			},
+			expectedErr: `conflicting values for flag max-pods in kubelet-arg of machine fleet-default/machine: "max-pods=110" (machineSelectorConfig[1]), "max-pods=250" (machineSelectorConfig[1])`,
+		},
+		{
+			name: "duplicate flag",
+			config: map[string]interface{}{
+				"kube-scheduler-arg": "profiling",
+				"kube-proxy-arg":     []string{"profiling", "--profiling"},
+			},
+			expectedErr: `duplicate flag profiling in kube-proxy-arg of machine fleet-default/machine: "profiling" (rancher), "--profiling" (rancher)`,
+		},
+		{
+			name: "identical duplicate flag",
+			config: map[string]interface{}{
+				"etcd-arg": []string{"quota-backend-bytes=8589934592", "quota-backend-bytes=8589934592"},
+			},
+			expectedErr: `duplicate flag quota-backend-bytes in etcd-arg of machine fleet-default/machine: "quota-backend-bytes=8589934592" (rancher), "quota-backend-bytes=8589934592" (rancher)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lintConfig(tt.config, controlPlane, entry)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package planner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	snapshotterConfig              = "snapshotter"
+	selinuxConfig                  = "selinux"
+	containerRuntimeEndpointConfig = "container-runtime-endpoint"
+	defaultRuntimeConfig           = "default-runtime"
+)
+
+var (
+	containerRuntimeHandlerRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+	// defaultRuntimeVersion is the first Kubernetes version whose k3s and RKE2 releases accept the default runtime of
+	// containerd.
+	defaultRuntimeVersion = semver.MustParse("v1.29.4")
+
+	// snapshotters are the containerd snapshotters that are built into each distribution.
+	snapshotters = map[string][]string{
+		capr.RuntimeK3S:  {"overlayfs", "native", "fuse-overlayfs", "stargz", "zfs"},
+		capr.RuntimeRKE2: {"overlayfs", "native", "fuse-overlayfs", "stargz"},
+	}
+)
+
+// addContainerRuntime adds the container runtime settings of the machine pool of a linux machine to its config.
+func (p *Planner) addContainerRuntime(config map[string]interface{}, controlPlane *rkev1.RKEControlPlane, entry *planEntry) error {
+	if windows(entry) {
+		return nil
+	}
+	poolName, ok := entry.Machine.Labels[capr.RKEMachinePoolNameLabel]
+	if !ok {
+		return nil
+	}
+
+	cluster, err := p.rancherClusterCache.Get(controlPlane.Namespace, controlPlane.Spec.ClusterName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if cluster.Spec.RKEConfig == nil {
+		return nil
+	}
+	for _, pool := range cluster.Spec.RKEConfig.MachinePools {
+		if pool.Name == poolName && pool.ContainerRuntime != nil {
+			if err := renderContainerRuntime(config, pool.ContainerRuntime, controlPlane.Spec.KubernetesVersion); err != nil {
+				return fmt.Errorf("invalid container runtime of machine pool %s: %w", pool.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// renderContainerRuntime validates the container runtime settings against the distribution and version of Kubernetes
+// and sets them in the config. Settings that are also set to a different value by the machine config are rejected.
+func renderContainerRuntime(config map[string]interface{}, containerRuntime *rkev1.ContainerRuntime, kubernetesVersion string) error {
+	settings := map[string]interface{}{}
+
+	if containerRuntime.Endpoint != "" {
+		if containerRuntime.Snapshotter != "" || containerRuntime.DefaultRuntime != "" {
+			return fmt.Errorf("snapshotter and default runtime can not be set for a container runtime endpoint, as they configure the embedded containerd")
+		}
+		if !strings.HasPrefix(containerRuntime.Endpoint, "unix:///") && !strings.HasPrefix(containerRuntime.Endpoint, "/") {
+			return fmt.Errorf("container runtime endpoint %q must be a unix socket", containerRuntime.Endpoint)
+		}
+		settings[containerRuntimeEndpointConfig] = containerRuntime.Endpoint
+	}
+
+	if containerRuntime.Snapshotter != "" {
+		runtime := capr.GetRuntime(kubernetesVersion)
+		supported := false
+		for _, snapshotter := range snapshotters[runtime] {
+			supported = supported || snapshotter == containerRuntime.Snapshotter
+		}
+		if !supported {
+			return fmt.Errorf("snapshotter %q is not supported by %s, must be one of %s", containerRuntime.Snapshotter, runtime, strings.Join(snapshotters[runtime], ", "))
+		}
+		settings[snapshotterConfig] = containerRuntime.Snapshotter
+	}
+
+	if containerRuntime.DefaultRuntime != "" {
+		if !containerRuntimeHandlerRegexp.MatchString(containerRuntime.DefaultRuntime) {
+			return fmt.Errorf("invalid default runtime %q: must consist of lowercase letters, numbers and hyphens", containerRuntime.DefaultRuntime)
+		}
+		version, err := semver.NewVersion(kubernetesVersion)
+		if err != nil {
+			return err
+		}
+		if version.LessThan(defaultRuntimeVersion) {
+			return fmt.Errorf("default runtime requires Kubernetes %s or newer", defaultRuntimeVersion)
+		}
+		settings[defaultRuntimeConfig] = containerRuntime.DefaultRuntime
+	}
+
+	if containerRuntime.SELinux {
+		settings[selinuxConfig] = true
+	}
+
+	for _, key := range []string{containerRuntimeEndpointConfig, snapshotterConfig, defaultRuntimeConfig, selinuxConfig} {
+		value, ok := settings[key]
+		if !ok {
+			continue
+		}
+		if existing, ok := config[key]; ok && convert.ToString(existing) != convert.ToString(value) {
+			return fmt.Errorf("%s %v conflicts with %v of the machine config", key, value, existing)
+		}
+		config[key] = value
+	}
+	return nil
+}
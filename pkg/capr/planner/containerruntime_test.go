@@ -0,0 +1,102 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderContainerRuntime(t *testing.T) {
+	tests := []struct {
+		name              string
+		containerRuntime  rkev1.ContainerRuntime
+		kubernetesVersion string
+		config            map[string]interface{}
+		expected          map[string]interface{}
+		expectErr         string
+	}{
+		{
+			name:              "empty",
+			kubernetesVersion: "v1.28.5+rke2r1",
+			expected:          map[string]interface{}{},
+		},
+		{
+			name:              "embedded containerd",
+			containerRuntime:  rkev1.ContainerRuntime{Snapshotter: "native", SELinux: true, DefaultRuntime: "crun"},
+			kubernetesVersion: "v1.29.4+rke2r1",
+			expected:          map[string]interface{}{"snapshotter": "native", "selinux": true, "default-runtime": "crun"},
+		},
+		{
+			name:              "endpoint",
+			containerRuntime:  rkev1.ContainerRuntime{Endpoint: "unix:///run/containerd/containerd.sock"},
+			kubernetesVersion: "v1.28.5+k3s1",
+			expected:          map[string]interface{}{"container-runtime-endpoint": "unix:///run/containerd/containerd.sock"},
+		},
+		{
+			name:              "endpoint with snapshotter",
+			containerRuntime:  rkev1.ContainerRuntime{Endpoint: "/run/containerd/containerd.sock", Snapshotter: "native"},
+			kubernetesVersion: "v1.28.5+k3s1",
+			expectErr:         "snapshotter and default runtime can not be set for a container runtime endpoint, as they configure the embedded containerd",
+		},
+		{
+			name:              "tcp endpoint",
+			containerRuntime:  rkev1.ContainerRuntime{Endpoint: "tcp://10.0.0.1:1234"},
+			kubernetesVersion: "v1.28.5+k3s1",
+			expectErr:         `container runtime endpoint "tcp://10.0.0.1:1234" must be a unix socket`,
+		},
+		{
+			name:              "k3s snapshotter",
+			containerRuntime:  rkev1.ContainerRuntime{Snapshotter: "zfs"},
+			kubernetesVersion: "v1.28.5+k3s1",
+			expected:          map[string]interface{}{"snapshotter": "zfs"},
+		},
+		{
+			name:              "unsupported snapshotter",
+			containerRuntime:  rkev1.ContainerRuntime{Snapshotter: "zfs"},
+			kubernetesVersion: "v1.28.5+rke2r1",
+			expectErr:         `snapshotter "zfs" is not supported by rke2, must be one of overlayfs, native, fuse-overlayfs, stargz`,
+		},
+		{
+			name:              "invalid default runtime",
+			containerRuntime:  rkev1.ContainerRuntime{DefaultRuntime: "Crun"},
+			kubernetesVersion: "v1.29.4+rke2r1",
+			expectErr:         `invalid default runtime "Crun": must consist of lowercase letters, numbers and hyphens`,
+		},
+		{
+			name:              "default runtime on old version",
+			containerRuntime:  rkev1.ContainerRuntime{DefaultRuntime: "crun"},
+			kubernetesVersion: "v1.28.5+rke2r1",
+			expectErr:         "default runtime requires Kubernetes 1.29.4 or newer",
+		},
+		{
+			name:              "same as machine config",
+			containerRuntime:  rkev1.ContainerRuntime{SELinux: true},
+			kubernetesVersion: "v1.28.5+rke2r1",
+			config:            map[string]interface{}{"selinux": "true"},
+			expected:          map[string]interface{}{"selinux": true},
+		},
+		{
+			name:              "conflicting machine config",
+			containerRuntime:  rkev1.ContainerRuntime{Snapshotter: "native"},
+			kubernetesVersion: "v1.28.5+rke2r1",
+			config:            map[string]interface{}{"snapshotter": "overlayfs"},
+			expectErr:         "snapshotter native conflicts with overlayfs of the machine config",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if config == nil {
+				config = map[string]interface{}{}
+			}
+			err := renderContainerRuntime(config, &tt.containerRuntime, tt.kubernetesVersion)
+			if tt.expectErr != "" {
+				assert.EqualError(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, config)
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package planner
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/s3client"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const (
+	diagnosticsInstructionName = "collect-diagnostics"
+	diagnosticsScriptPath      = "rancher_v2prov_diagnostics/bin/collect.sh"
+	diagnosticsBundleKey       = "diagnostics.tar.gz"
+	diagnosticsS3Timeout       = 2 * time.Minute
+
+	// maxDiagnosticsBundleBytes keeps the bundle, which is saved as the output of the plan, well below the size limit
+	// of the plan secret.
+	maxDiagnosticsBundleBytes = 384 * 1024
+
+	diagnosticsScript = `#!/bin/sh
+# Collects the journal of the distribution and the system agent, the logs of the control plane components and a
+# summary of the system into a gzipped tarball that is written to stdout base64 encoded.
+runtime=$1
+max_bytes=$2
+
+work=$(mktemp -d)
+trap 'rm -rf "$work"' EXIT
+out="$work/diagnostics"
+mkdir -p "$out/journal" "$out/logs/pods" "$out/system"
+
+run() {
+	file=$1
+	shift
+	"$@" > "$out/$file" 2>&1 || true
+}
+
+run system/uname.txt uname -a
+run system/os-release.txt cat /etc/os-release
+run system/uptime.txt uptime
+run system/df.txt df -h
+run system/free.txt free -m
+run system/ps.txt ps aux
+run system/ip-addr.txt ip addr
+run system/ip-route.txt ip route
+run system/ss.txt ss -tunlp
+run system/dmesg.txt sh -c 'dmesg | tail -n 1000'
+if [ "$runtime" = "k3s" ]; then
+	run system/crictl-ps.txt k3s crictl ps -a
+else
+	run system/crictl-ps.txt env CRI_CONFIG_FILE=/var/lib/rancher/rke2/agent/etc/crictl.yaml /var/lib/rancher/rke2/bin/crictl ps -a
+fi
+
+for unit in "$runtime-server" "$runtime-agent" "$runtime" rancher-system-agent; do
+	if command -v journalctl > /dev/null 2>&1; then
+		run "journal/$unit.log" journalctl -u "$unit" -n 5000 --no-pager
+	fi
+done
+
+for file in /var/lib/rancher/$runtime/agent/containerd/containerd.log /var/lib/rancher/$runtime/agent/logs/kubelet.log; do
+	if [ -f "$file" ]; then
+		tail -n 2000 "$file" > "$out/logs/$(basename "$file")" 2>&1
+	fi
+done
+find /var/log/pods -path '*/kube-system_*' -name '*.log' 2>/dev/null | while read -r file; do
+	tail -n 500 "$file" > "$out/logs/pods/$(echo "${file#/var/log/pods/}" | tr '/' '_')" 2>&1
+done
+
+bundle() {
+	tar -czf "$work/bundle.tar.gz" -C "$work" diagnostics || exit 1
+	size=$(wc -c < "$work/bundle.tar.gz")
+}
+
+bundle
+if [ "$size" -gt "$max_bytes" ]; then
+	# the pod logs are the largest part of the bundle
+	rm -rf "$out/logs/pods"
+	bundle
+fi
+if [ "$size" -gt "$max_bytes" ]; then
+	echo "diagnostics bundle of $size bytes exceeds the limit of $max_bytes bytes" >&2
+	exit 1
+fi
+base64 "$work/bundle.tar.gz" | tr -d '\n'
+`
+)
+
+// runDiagnostics collects the diagnostics bundle of a machine that requested one through the collect diagnostics
+// annotation. Like node commands, the collection replaces the plan of the machine, so bundles are collected one
+// machine at a time before the cluster is reconciled, which restores the plan afterwards. The location of the bundle is
+// recorded in the DiagnosticsCollected condition of the machine.
+func (p *Planner) runDiagnostics(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, clusterPlan *plan.Plan) error {
+	entries := collect(clusterPlan, roleAnd(anyRoleWithoutWindows, roleAnd(isNotDeleting, diagnosticsRequested)))
+	if len(entries) == 0 {
+		return nil
+	}
+	entry := entries[0]
+
+	found, joinServer, _, err := p.findInitNode(controlPlane, clusterPlan)
+	if err != nil {
+		return err
+	}
+	if !found || joinServer == "" {
+		return errWaiting("waiting for the init node to collect diagnostics")
+	}
+
+	diagnosticsPlan, joinedServer, err := p.generateDiagnosticsPlan(controlPlane, tokensSecret, entry, joinServer)
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("collecting diagnostics of machine %s/%s", entry.Machine.Namespace, entry.Machine.Name)
+	err = assignAndCheckPlan(p.store, msg, entry, diagnosticsPlan, joinedServer, 1, 1)
+	if IsErrWaiting(err) {
+		return err
+	}
+
+	location := ""
+	if err == nil {
+		var bundle []byte
+		if bundle, err = decodeDiagnosticsBundle(entry.Plan.Output[diagnosticsInstructionName]); err == nil {
+			location, err = p.storeDiagnosticsBundle(controlPlane, entry, bundle)
+		}
+	}
+	if err := p.recordDiagnostics(entry, location, err); err != nil {
+		return err
+	}
+	logrus.Infof("[planner] rkecluster %s/%s: collected diagnostics of machine %s", controlPlane.Namespace, controlPlane.Name, entry.Machine.Name)
+	return errWaitingf("restoring plan of machine %s after collecting diagnostics", entry.Machine.Name)
+}
+
+// diagnosticsRequested returns true if the collect diagnostics annotation of the machine was set to a value that was
+// not handled yet.
+func diagnosticsRequested(entry *planEntry) bool {
+	request := entry.Machine.Annotations[capr.CollectDiagnosticsAnnotation]
+	return request != "" && request != entry.Machine.Annotations[capr.DiagnosticsCollectedAnnotation]
+}
+
+// generateDiagnosticsPlan generates a plan that contains an instruction running the diagnostics script.
+func (p *Planner) generateDiagnosticsPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, entry *planEntry, joinServer string) (plan.NodePlan, string, error) {
+	diagnosticsPlan, _, joinedServer, err := p.generatePlanWithConfigFiles(controlPlane, tokensSecret, entry, joinServer)
+	if err != nil {
+		return diagnosticsPlan, joinedServer, err
+	}
+	scriptPath := etcdSnapshotScriptFile(controlPlane, diagnosticsScriptPath)
+	diagnosticsPlan.Files = append(diagnosticsPlan.Files, plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(diagnosticsScript)),
+		Path:    scriptPath,
+	})
+	diagnosticsPlan.Instructions = append(diagnosticsPlan.Instructions, p.generateInstallInstructionWithSkipStart(controlPlane, entry),
+		plan.OneTimeInstruction{
+			Name:    diagnosticsInstructionName,
+			Command: "sh",
+			Args: []string{
+				scriptPath,
+				capr.GetRuntime(controlPlane.Spec.KubernetesVersion),
+				strconv.Itoa(maxDiagnosticsBundleBytes),
+			},
+			SaveOutput: true,
+		})
+	return diagnosticsPlan, joinedServer, nil
+}
+
+// decodeDiagnosticsBundle decodes the base64 encoded bundle written to the output of the diagnostics instruction.
+func decodeDiagnosticsBundle(output []byte) ([]byte, error) {
+	encoded := strings.TrimSpace(string(output))
+	if encoded == "" {
+		return nil, fmt.Errorf("diagnostics script did not output a bundle")
+	}
+	bundle, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode diagnostics bundle: %w", err)
+	}
+	return bundle, nil
+}
+
+// storeDiagnosticsBundle stores the bundle in the S3 bucket of the diagnostics settings of the cluster, or in a secret
+// owned by the machine if no bucket is configured, and returns its location.
+func (p *Planner) storeDiagnosticsBundle(controlPlane *rkev1.RKEControlPlane, entry *planEntry, bundle []byte) (string, error) {
+	if controlPlane.Spec.Diagnostics != nil && controlPlane.Spec.Diagnostics.S3 != nil {
+		config, err := GetS3Config(p.secretCache, controlPlane.Spec.Diagnostics.S3, controlPlane)
+		if err != nil {
+			return "", err
+		}
+		client, err := s3client.Get(config)
+		if err != nil {
+			return "", err
+		}
+		objectName := diagnosticsObjectName(controlPlane, entry, time.Now())
+		ctx, cancel := context.WithTimeout(p.ctx, diagnosticsS3Timeout)
+		defer cancel()
+		if err := client.Put(ctx, objectName, bytes.NewReader(bundle), int64(len(bundle)), "application/gzip"); err != nil {
+			return "", fmt.Errorf("failed to upload diagnostics bundle: %w", err)
+		}
+		return client.ObjectURL(objectName), nil
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name.SafeConcatName(entry.Machine.Name, "diagnostics"),
+			Namespace: entry.Machine.Namespace,
+			Labels: map[string]string{
+				capr.ClusterNameLabel: controlPlane.Name,
+				capr.MachineNameLabel: entry.Machine.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: capi.GroupVersion.String(),
+				Kind:       "Machine",
+				Name:       entry.Machine.Name,
+				UID:        entry.Machine.UID,
+			}},
+		},
+		Type: capr.SecretTypeDiagnostics,
+		Data: map[string][]byte{
+			diagnosticsBundleKey: bundle,
+		},
+	}
+	existing, err := p.secretCache.Get(secret.Namespace, secret.Name)
+	if apierrors.IsNotFound(err) {
+		_, err = p.secretClient.Create(secret)
+	} else if err == nil {
+		existing = existing.DeepCopy()
+		existing.Data = secret.Data
+		_, err = p.secretClient.Update(existing)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("secret %s/%s", secret.Namespace, secret.Name), nil
+}
+
+// diagnosticsObjectName returns the name of the S3 object of a bundle collected at the passed in time.
+func diagnosticsObjectName(controlPlane *rkev1.RKEControlPlane, entry *planEntry, now time.Time) string {
+	return path.Join("diagnostics", controlPlane.Name, fmt.Sprintf("%s-%d.tar.gz", entry.Machine.Name, now.Unix()))
+}
+
+// recordDiagnostics sets the DiagnosticsCollected condition of the machine to the location of the bundle, or to the
+// error that prevented its collection, and marks the request of the machine as handled.
+func (p *Planner) recordDiagnostics(entry *planEntry, location string, collectErr error) error {
+	machine := entry.Machine.DeepCopy()
+	if collectErr != nil {
+		conditions.MarkFalse(machine, capi.ConditionType(capr.DiagnosticsCollected), "CollectionFailed", capi.ConditionSeverityWarning, "%s", collectErr.Error())
+	} else {
+		conditions.Set(machine, &capi.Condition{
+			Type:    capi.ConditionType(capr.DiagnosticsCollected),
+			Status:  corev1.ConditionTrue,
+			Reason:  "Collected",
+			Message: location,
+		})
+	}
+	machine, err := p.machines.UpdateStatus(machine)
+	if err != nil {
+		return err
+	}
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	machine.Annotations[capr.DiagnosticsCollectedAnnotation] = entry.Machine.Annotations[capr.CollectDiagnosticsAnnotation]
+	_, err = p.machines.Update(machine)
+	return err
+}
@@ -0,0 +1,69 @@
+package planner
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestDiagnosticsRequested(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name: "no request",
+		},
+		{
+			name:        "new request",
+			annotations: map[string]string{capr.CollectDiagnosticsAnnotation: "1"},
+			expected:    true,
+		},
+		{
+			name: "handled request",
+			annotations: map[string]string{
+				capr.CollectDiagnosticsAnnotation:   "1",
+				capr.DiagnosticsCollectedAnnotation: "1",
+			},
+		},
+		{
+			name: "request after a handled request",
+			annotations: map[string]string{
+				capr.CollectDiagnosticsAnnotation:   "2",
+				capr.DiagnosticsCollectedAnnotation: "1",
+			},
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &planEntry{Machine: &capi.Machine{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}}
+			assert.Equal(t, tt.expected, diagnosticsRequested(entry))
+		})
+	}
+}
+
+func TestDecodeDiagnosticsBundle(t *testing.T) {
+	bundle, err := decodeDiagnosticsBundle([]byte(base64.StdEncoding.EncodeToString([]byte("bundle")) + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bundle"), bundle)
+
+	_, err = decodeDiagnosticsBundle([]byte(" \n"))
+	assert.Error(t, err)
+
+	_, err = decodeDiagnosticsBundle([]byte("diagnostics bundle exceeds the limit"))
+	assert.Error(t, err)
+}
+
+func TestDiagnosticsObjectName(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+	entry := &planEntry{Machine: &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine"}}}
+	assert.Equal(t, "diagnostics/cluster/machine-1700000000.tar.gz", diagnosticsObjectName(controlPlane, entry, time.Unix(1700000000, 0)))
+}
@@ -3,6 +3,7 @@
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/rancher/norman/types/convert"
@@ -10,8 +11,48 @@
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
 	"github.com/rancher/rancher/pkg/capr"
 	"github.com/rancher/wrangler/pkg/kv"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
+// drainOptionsFunc returns the drain options of the machine of the plan entry.
+type drainOptionsFunc func(entry *planEntry) rkev1.DrainOptions
+
+// tierDrainOptions returns a drainOptionsFunc that drains every machine with the same options.
+func tierDrainOptions(options rkev1.DrainOptions) drainOptionsFunc {
+	return func(*planEntry) rkev1.DrainOptions {
+		return options
+	}
+}
+
+// etcdRestartDrainOptions returns the drain options of the machines while the cluster is restarted during etcd snapshot
+// creation and restore. Nothing is drained if the restart drain is disabled for the cluster. Otherwise, the restart
+// drain options of the machine pool of a machine are used, falling back to the given default options for machines that
+// are not in a pool, or whose pool does not override them.
+func (p *Planner) etcdRestartDrainOptions(cp *rkev1.RKEControlPlane, defaultOptions rkev1.DrainOptions) (drainOptionsFunc, error) {
+	if cp.Spec.ETCD != nil && cp.Spec.ETCD.DisableRestartDrain {
+		return tierDrainOptions(rkev1.DrainOptions{}), nil
+	}
+
+	poolOptions := map[string]rkev1.DrainOptions{}
+	cluster, err := p.rancherClusterCache.Get(cp.Namespace, cp.Spec.ClusterName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	} else if err == nil && cluster.Spec.RKEConfig != nil {
+		for _, pool := range cluster.Spec.RKEConfig.MachinePools {
+			if pool.ETCDRestartDrainOptions != nil {
+				poolOptions[pool.Name] = *pool.ETCDRestartDrainOptions
+			}
+		}
+	}
+
+	return func(entry *planEntry) rkev1.DrainOptions {
+		if options, ok := poolOptions[entry.Machine.Labels[capr.RKEMachinePoolNameLabel]]; ok {
+			return options
+		}
+		return defaultOptions
+	}, nil
+}
+
 func getRestartStamp(plan *plan.NodePlan) string {
 	for _, instr := range plan.Instructions {
 		for _, env := range instr.Env {
@@ -112,3 +153,30 @@ func checkForDrainError(entry *planEntry, drainStep string) error {
 	}
 	return nil
 }
+
+// reconcileDrainBlocked reports the machines whose drain is blocked by pod disruption budgets in the DrainBlocked
+// condition of the status, with the budgets and the pods they cover as recorded on the plan secrets by the machine
+// drain controller.
+func reconcileDrainBlocked(status rkev1.RKEControlPlaneStatus, clusterPlan *plan.Plan) rkev1.RKEControlPlaneStatus {
+	var blocked []string
+	for _, entry := range collect(clusterPlan, anyRole) {
+		if blockedBy := entry.Metadata.Annotations[capr.DrainBlockedByAnnotation]; blockedBy != "" && isInDrain(entry) {
+			blocked = append(blocked, fmt.Sprintf("machine %s: %s", entry.Machine.Name, blockedBy))
+		}
+	}
+
+	if len(blocked) == 0 {
+		if capr.DrainBlocked.IsTrue(&status) {
+			capr.DrainBlocked.False(&status)
+			capr.DrainBlocked.Reason(&status, "")
+			capr.DrainBlocked.Message(&status, "")
+		}
+		return status
+	}
+
+	sort.Strings(blocked)
+	capr.DrainBlocked.True(&status)
+	capr.DrainBlocked.Reason(&status, "PodDisruptionBudget")
+	capr.DrainBlocked.Message(&status, strings.Join(blocked, "; "))
+	return status
+}
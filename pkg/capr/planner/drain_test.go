@@ -0,0 +1,136 @@
+package planner
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	ranchercontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+type fakeRancherClusterCache struct {
+	cluster *rancherv1.Cluster
+}
+
+func (f *fakeRancherClusterCache) Get(namespace, name string) (*rancherv1.Cluster, error) {
+	if f.cluster == nil || f.cluster.Namespace != namespace || f.cluster.Name != name {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: "provisioning.cattle.io", Resource: "clusters"}, name)
+	}
+	return f.cluster, nil
+}
+
+func (f *fakeRancherClusterCache) List(string, labels.Selector) ([]*rancherv1.Cluster, error) {
+	return nil, nil
+}
+
+func (f *fakeRancherClusterCache) AddIndexer(string, ranchercontrollers.ClusterIndexer) {}
+
+func (f *fakeRancherClusterCache) GetByIndex(string, string) ([]*rancherv1.Cluster, error) {
+	return nil, nil
+}
+
+func poolEntry(pool string) *planEntry {
+	return &planEntry{Machine: &capi.Machine{ObjectMeta: metav1.ObjectMeta{
+		Labels: map[string]string{capr.RKEMachinePoolNameLabel: pool},
+	}}}
+}
+
+func TestEtcdRestartDrainOptions(t *testing.T) {
+	defaultOptions := rkev1.DrainOptions{Enabled: true, GracePeriod: 600}
+	poolOptions := rkev1.DrainOptions{Enabled: true, GracePeriod: 30, Timeout: 120}
+
+	cp := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"},
+		Spec:       rkev1.RKEControlPlaneSpec{ClusterName: "test"},
+	}
+	p := &Planner{rancherClusterCache: &fakeRancherClusterCache{cluster: &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"},
+		Spec: rancherv1.ClusterSpec{RKEConfig: &rancherv1.RKEConfig{MachinePools: []rancherv1.RKEMachinePool{
+			{Name: "noisy", ETCDRestartDrainOptions: &poolOptions},
+			{Name: "quiet"},
+		}}},
+	}}}
+
+	drainOptions, err := p.etcdRestartDrainOptions(cp, defaultOptions)
+	require.NoError(t, err)
+	assert.Equal(t, poolOptions, drainOptions(poolEntry("noisy")))
+	assert.Equal(t, defaultOptions, drainOptions(poolEntry("quiet")))
+	assert.Equal(t, defaultOptions, drainOptions(poolEntry("")), "machines of custom clusters are not in a pool")
+
+	cp.Spec.ETCD = &rkev1.ETCD{DisableRestartDrain: true}
+	drainOptions, err = p.etcdRestartDrainOptions(cp, defaultOptions)
+	require.NoError(t, err)
+	assert.Equal(t, rkev1.DrainOptions{}, drainOptions(poolEntry("noisy")))
+
+	cp.Spec.ETCD = nil
+	cp.Spec.ClusterName = "missing"
+	drainOptions, err = p.etcdRestartDrainOptions(cp, defaultOptions)
+	require.NoError(t, err)
+	assert.Equal(t, defaultOptions, drainOptions(poolEntry("noisy")))
+}
+
+func TestReconcileDrainBlocked(t *testing.T) {
+	clusterPlan := func(annotations map[string]map[string]string) *plan.Plan {
+		p := &plan.Plan{Machines: map[string]*capi.Machine{}, Metadata: map[string]*plan.Metadata{}}
+		for name, a := range annotations {
+			p.Machines[name] = &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: name}}
+			p.Metadata[name] = &plan.Metadata{Labels: map[string]string{capr.WorkerRoleLabel: "true"}, Annotations: a}
+		}
+		return p
+	}
+
+	tests := []struct {
+		name            string
+		annotations     map[string]map[string]string
+		blocked         bool
+		expectedStatus  string
+		expectedMessage string
+	}{
+		{
+			name:        "no drains",
+			annotations: map[string]map[string]string{"m1": {}},
+		},
+		{
+			name: "blocked drain",
+			annotations: map[string]map[string]string{
+				"m1": {capr.DrainAnnotation: "{}", capr.DrainBlockedByAnnotation: "poddisruptionbudget default/web allows no disruption of pods web-1"},
+				"m2": {},
+			},
+			expectedStatus:  "True",
+			expectedMessage: "machine m1: poddisruptionbudget default/web allows no disruption of pods web-1",
+		},
+		{
+			name: "stale annotation after drain",
+			annotations: map[string]map[string]string{
+				"m1": {capr.DrainBlockedByAnnotation: "poddisruptionbudget default/web allows no disruption of pods web-1"},
+			},
+		},
+		{
+			name:           "no longer blocked",
+			annotations:    map[string]map[string]string{"m1": {capr.DrainAnnotation: "{}"}},
+			blocked:        true,
+			expectedStatus: "False",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := rkev1.RKEControlPlaneStatus{}
+			if tt.blocked {
+				capr.DrainBlocked.True(&status)
+				capr.DrainBlocked.Message(&status, "machine m1: blocked")
+			}
+			status = reconcileDrainBlocked(status, clusterPlan(tt.annotations))
+			assert.Equal(t, tt.expectedStatus, capr.DrainBlocked.GetStatus(&status))
+			assert.Equal(t, tt.expectedMessage, capr.DrainBlocked.GetMessage(&status))
+		})
+	}
+}
@@ -453,11 +453,12 @@ func (p *Planner) encryptionKeyRotationRestartService(cp *rkev1.RKEControlPlane,
 		)
 	}
 
-	probes, err := p.generateProbes(cp, entry, config)
+	probes, probeFiles, err := p.generateProbes(cp, entry, config)
 	if err != nil {
 		return "", status, err
 	}
 	nodePlan.Probes = probes
+	nodePlan.Files = append(nodePlan.Files, probeFiles...)
 
 	// retry is important here because without it, we always seem to run into some sort of issue such as:
 	// - the follower node reporting the wrong status after a restart
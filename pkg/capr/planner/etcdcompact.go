@@ -0,0 +1,170 @@
+package planner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	etcdCompactInstructionName = "etcd-compact"
+	etcdCompactScriptPath      = "rancher_v2prov_etcd_snapshot/bin/compact.sh"
+
+	// etcdCompactScript compacts the keyspace of etcd to its latest revision through the gRPC gateway of the local etcd
+	// member, if the member is the leader and all members of the cluster are healthy. Otherwise, the compaction is
+	// skipped, so that only one node compacts etcd and a degraded cluster is left alone.
+	etcdCompactScript = `
+#!/bin/sh
+
+tls=$1
+endpoint=https://127.0.0.1:2379
+
+call() {
+	curl -sf --cacert "$tls/server-ca.crt" --cert "$tls/client.crt" --key "$tls/client.key" "$@"
+}
+
+field() {
+	sed -n "s/.*\"$1\":\"\([0-9]*\)\".*/\1/p"
+}
+
+if ! command -v curl >/dev/null 2>&1; then
+	echo "skipped: curl is not available"
+	exit 0
+fi
+
+status=$(call -X POST -d '{}' "$endpoint/v3/maintenance/status")
+if [ -z "$status" ]; then
+	echo "skipped: unable to get the status of the local etcd member"
+	exit 0
+fi
+member=$(echo "$status" | field member_id)
+leader=$(echo "$status" | field leader)
+revision=$(echo "$status" | field revision)
+if [ -z "$member" ] || [ "$member" != "$leader" ]; then
+	echo "skipped: the local etcd member is not the leader"
+	exit 0
+fi
+if echo "$status" | grep -q '"errors"'; then
+	echo "skipped: the local etcd member reports errors"
+	exit 0
+fi
+if [ -z "$revision" ]; then
+	echo "skipped: unable to determine the revision of etcd"
+	exit 0
+fi
+
+members=$(call -X POST -d '{}' "$endpoint/v3/cluster/member/list" | grep -o '"clientURLs":\[[^]]*\]' | grep -o 'https://[^"]*')
+if [ -z "$members" ]; then
+	echo "skipped: unable to list the etcd members"
+	exit 0
+fi
+for url in $members; do
+	if ! call "$url/health" | grep -q '"health":"true"'; then
+		echo "skipped: etcd member $url is not healthy"
+		exit 0
+	fi
+done
+
+if ! call -X POST -d "{\"revision\":\"$revision\"}" "$endpoint/v3/kv/compaction" >/dev/null; then
+	echo "failed to compact etcd to revision $revision" >&2
+	exit 1
+fi
+echo "compacted $revision"
+`
+)
+
+// runEtcdCompaction delivers a plan to compact the keyspace of etcd to all etcd nodes, of which only the leader
+// compacts. It returns the revision etcd was compacted to, or 0 if the compaction was skipped or failed. As the snapshot
+// was already created, a failed compaction does not fail the snapshot creation.
+func (p *Planner) runEtcdCompaction(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, clusterPlan *plan.Plan, joinServer string) (int64, error) {
+	servers := collect(clusterPlan, isEtcd)
+	if len(servers) == 0 {
+		return 0, errors.New("failed to find node to perform etcd compaction")
+	}
+
+	var (
+		revision int64
+		skipped  []string
+	)
+	for _, server := range servers {
+		compactPlan, joinedServer, err := p.generateEtcdCompactPlan(controlPlane, tokensSecret, server, joinServer)
+		if err != nil {
+			return 0, err
+		}
+		msg := fmt.Sprintf("etcd compaction on machine %s/%s", server.Machine.Namespace, server.Machine.Name)
+		err = assignAndCheckPlan(p.store, msg, server, compactPlan, joinedServer, 1, 1)
+		if IsErrWaiting(err) {
+			return 0, err
+		}
+		output := strings.TrimSpace(string(server.Plan.Output[etcdCompactInstructionName]))
+		if err != nil {
+			logrus.Warnf("[planner] rkecluster %s/%s: %v: %s", controlPlane.Namespace, controlPlane.Name, err, output)
+			continue
+		}
+		if compacted := compactedRevision(server.Plan.Output[etcdCompactInstructionName]); compacted > 0 {
+			revision = compacted
+		} else if output != "" {
+			skipped = append(skipped, fmt.Sprintf("%s: %s", server.Machine.Name, output))
+		}
+	}
+
+	if revision == 0 {
+		logrus.Warnf("[planner] rkecluster %s/%s: etcd was not compacted after the snapshot: %s", controlPlane.Namespace, controlPlane.Name, strings.Join(skipped, ", "))
+	} else {
+		logrus.Infof("[planner] rkecluster %s/%s: compacted etcd to revision %d after the snapshot", controlPlane.Namespace, controlPlane.Name, revision)
+	}
+	return revision, nil
+}
+
+// generateEtcdCompactPlan generates a plan that contains an instruction to compact the keyspace of etcd.
+func (p *Planner) generateEtcdCompactPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, entry *planEntry, joinServer string) (plan.NodePlan, string, error) {
+	compactPlan, _, joinedServer, err := p.generatePlanWithConfigFiles(controlPlane, tokensSecret, entry, joinServer)
+	if err != nil {
+		return compactPlan, joinedServer, err
+	}
+	compactPlan.Files = append(compactPlan.Files, plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(etcdCompactScript)),
+		Path:    etcdSnapshotScriptFile(controlPlane, etcdCompactScriptPath),
+	})
+	compactPlan.Instructions = append(compactPlan.Instructions, p.generateInstallInstructionWithSkipStart(controlPlane, entry),
+		etcdCompactInstruction(controlPlane))
+	return compactPlan, joinedServer, nil
+}
+
+// etcdCompactInstruction generates an instruction that compacts the keyspace of etcd if the etcd member of the node is
+// the leader.
+func etcdCompactInstruction(controlPlane *rkev1.RKEControlPlane) plan.OneTimeInstruction {
+	return plan.OneTimeInstruction{
+		Name:    etcdCompactInstructionName,
+		Command: "sh",
+		Args: []string{
+			etcdSnapshotScriptFile(controlPlane, etcdCompactScriptPath),
+			fmt.Sprintf("/var/lib/rancher/%s/server/tls/etcd", capr.GetRuntime(controlPlane.Spec.KubernetesVersion)),
+		},
+		SaveOutput: true,
+	}
+}
+
+// compactedRevision returns the revision reported by the compaction instruction, or 0 if it did not compact etcd.
+func compactedRevision(output []byte) int64 {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != "compacted" {
+			continue
+		}
+		if revision, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			return revision
+		}
+	}
+	return 0
+}
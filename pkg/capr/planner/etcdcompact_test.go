@@ -0,0 +1,49 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtcdCompactInstruction(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	controlPlane.Spec.KubernetesVersion = "v1.25.9+k3s1"
+
+	instruction := etcdCompactInstruction(controlPlane)
+	assert.Equal(t, etcdCompactInstructionName, instruction.Name)
+	assert.Equal(t, "sh", instruction.Command)
+	assert.Equal(t, []string{"/var/lib/rancher/k3s/rancher_v2prov_etcd_snapshot/bin/compact.sh", "/var/lib/rancher/k3s/server/tls/etcd"}, instruction.Args)
+	assert.True(t, instruction.SaveOutput)
+}
+
+func TestCompactedRevision(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected int64
+	}{
+		{
+			name:     "compacted",
+			output:   "compacted 123456\n",
+			expected: 123456,
+		},
+		{
+			name:   "not the leader",
+			output: "skipped: the local etcd member is not the leader\n",
+		},
+		{
+			name: "no output",
+		},
+		{
+			name:   "invalid revision",
+			output: "compacted latest\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, compactedRevision([]byte(tt.output)))
+		})
+	}
+}
@@ -1,19 +1,187 @@
 package planner
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
 	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/faultinjection"
+	"github.com/rancher/wrangler/pkg/data/convert"
 	"github.com/rancher/wrangler/pkg/merr"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// ETCDSnapshotChecksumInstruction is the name of the instruction that reports the digests of the snapshot file
+	// created on a node, so that the uploaded S3 object can be verified against it.
+	ETCDSnapshotChecksumInstruction = "etcd-snapshot-s3-checksum"
+
+	etcdSnapshotChecksumScriptPath = "rancher_v2prov_etcd_snapshot/bin/checksum.sh"
+
+	etcdSnapshotDiskPreflightInstructionName = "etcd-snapshot-disk-preflight"
+	etcdSnapshotDiskPreflightScriptPath      = "rancher_v2prov_etcd_snapshot/bin/disk-preflight.sh"
+
+	etcdSnapshotDiskPreflightScript = `
+#!/bin/sh
+
+dir=$1
+minFree=$2
+
+mkdir -p "$dir" || exit 1
+used=$(df -P "$dir" | awk 'NR == 2 { sub("%", "", $5); print $5 }')
+if [ -z "$used" ]; then
+	echo "unable to determine free disk space of etcd snapshot directory $dir" >&2
+	exit 1
+fi
+free=$((100 - used))
+if [ "$free" -lt "$minFree" ]; then
+	echo "insufficient free disk space for etcd snapshot in $dir: ${free}% free, ${minFree}% required" >&2
+	exit 1
+fi
+`
+
+	etcdSnapshotCancelCleanupInstructionName = "etcd-snapshot-cancel-cleanup"
+	etcdSnapshotCancelCleanupScriptPath      = "rancher_v2prov_etcd_snapshot/bin/cancel-cleanup.sh"
+
+	// etcdSnapshotCancelCleanupScript deletes the snapshots a cancelled snapshot creation saved in the snapshot
+	// directory with the distribution, which also deletes them from S3 if S3 is configured, so that no partial set of
+	// snapshots of the cancelled creation is left behind.
+	etcdSnapshotCancelCleanupScript = `
+#!/bin/sh
+
+command=$1
+dir=$2
+name=$3
+
+snapshots=""
+for file in "$dir/$name"-*; do
+	[ -f "$file" ] || continue
+	snapshots="$snapshots $(basename "$file")"
+done
+if [ -z "$snapshots" ]; then
+	echo "no etcd snapshot $name found in $dir"
+	exit 0
+fi
+echo "deleting etcd snapshots$snapshots"
+exec "$command" etcd-snapshot delete $snapshots
+`
+
+	etcdSnapshotLoadGateInstructionName = "etcd-snapshot-load-gate"
+	etcdSnapshotLoadGateScriptPath      = "rancher_v2prov_etcd_snapshot/bin/load-gate.sh"
+
+	defaultEtcdSnapshotLoadGateRetryIntervalSeconds = 30
+	defaultEtcdSnapshotLoadGateDeadlineSeconds      = 1800
+
+	// etcdSnapshotLoadGateScript waits until the average WAL fsync and backend commit durations of the local etcd member,
+	// measured from its metrics over a 10 second window, are within the thresholds. The snapshot is not deferred if the
+	// metrics can not be collected, and is created regardless of the load once the deadline has passed.
+	etcdSnapshotLoadGateScript = `
+#!/bin/sh
+
+maxFsync=$1
+maxCommit=$2
+interval=$3
+deadline=$(($(date +%s) + $4))
+url=http://127.0.0.1:2381/metrics
+
+scrape() {
+	if command -v curl >/dev/null 2>&1; then
+		curl -sf "$url"
+	else
+		wget -qO- "$url"
+	fi | awk '
+		/^etcd_disk_wal_fsync_duration_seconds_sum/ { fs = $2 }
+		/^etcd_disk_wal_fsync_duration_seconds_count/ { fc = $2 }
+		/^etcd_disk_backend_commit_duration_seconds_sum/ { cs = $2 }
+		/^etcd_disk_backend_commit_duration_seconds_count/ { cc = $2 }
+		END { if (fc != "" && cc != "") print fs, fc, cs, cc }'
+}
+
+while true; do
+	before=$(scrape)
+	sleep 10
+	after=$(scrape)
+	if [ -z "$before" ] || [ -z "$after" ]; then
+		echo "unable to collect etcd metrics from $url, not deferring etcd snapshot"
+		exit 0
+	fi
+	latency=$(echo "$before $after" | awk '{
+		fsync = ($6 > $2) ? ($5 - $1) / ($6 - $2) * 1000 : 0
+		commit = ($8 > $4) ? ($7 - $3) / ($8 - $4) * 1000 : 0
+		printf "%d %d", fsync, commit
+	}')
+	fsync=${latency% *}
+	commit=${latency#* }
+	if { [ "$maxFsync" -eq 0 ] || [ "$fsync" -le "$maxFsync" ]; } && { [ "$maxCommit" -eq 0 ] || [ "$commit" -le "$maxCommit" ]; }; then
+		echo "etcd WAL fsync ${fsync}ms, backend commit ${commit}ms: creating etcd snapshot"
+		exit 0
+	fi
+	if [ "$(date +%s)" -ge "$deadline" ]; then
+		echo "etcd WAL fsync ${fsync}ms, backend commit ${commit}ms: deadline reached, creating etcd snapshot under load"
+		exit 0
+	fi
+	echo "etcd WAL fsync ${fsync}ms, backend commit ${commit}ms exceed thresholds of ${maxFsync}ms and ${maxCommit}ms: deferring etcd snapshot for ${interval}s"
+	sleep "$interval"
+done
+`
+
+	etcdSnapshotChecksumScript = `
+#!/bin/sh
+
+dir=$1
+name=$2
+shift 2
+
+file=""
+for candidate in "$dir/$name"-*; do
+	[ -f "$candidate" ] || continue
+	if [ -z "$file" ] || [ "$candidate" -nt "$file" ]; then
+		file=$candidate
+	fi
+done
+if [ -z "$file" ]; then
+	echo "no etcd snapshot $name found in $dir" >&2
+	exit 1
+fi
+snapshot=$(basename "$file")
+size=$(($(wc -c < "$file")))
+
+echo "name $snapshot"
+echo "created $(date +%s)"
+echo "size $size"
+echo "sha256 $(sha256sum "$file" | cut -d ' ' -f 1)"
+echo "md5 $(md5sum "$file" | cut -d ' ' -f 1)"
+
+for partSize in "$@"; do
+	if [ "$size" -lt "$partSize" ]; then
+		continue
+	fi
+	parts=""
+	i=0
+	while [ $((i * partSize)) -lt "$size" ]; do
+		parts="$parts $(dd if="$file" bs="$partSize" skip=$i count=1 2>/dev/null | md5sum | cut -d ' ' -f 1)"
+		i=$((i + 1))
+	done
+	echo "parts $partSize$parts"
+done
+`
+)
+
+// etcdSnapshotS3PartSizes are the part sizes that the S3 client embedded in k3s and rke2 uses for multipart uploads,
+// depending on its version. The ETag of a multipart upload is derived from the digests of its parts, so part digests
+// are reported for each of them.
+var etcdSnapshotS3PartSizes = []int64{16 << 20, 128 << 20}
+
 func (p *Planner) setEtcdSnapshotCreateState(status rkev1.RKEControlPlaneStatus, create *rkev1.ETCDSnapshotCreate, phase rkev1.ETCDSnapshotPhase) (rkev1.RKEControlPlaneStatus, error) {
-	if status.ETCDSnapshotCreatePhase != phase || !equality.Semantic.DeepEqual(status.ETCDSnapshotCreate, create) {
+	if etcdSnapshotCreateStateHash(status.ETCDSnapshotCreate, status.ETCDSnapshotCreatePhase) != etcdSnapshotCreateStateHash(create, phase) {
 		status.ETCDSnapshotCreatePhase = phase
 		status.ETCDSnapshotCreate = create
 		return status, errWaiting("refreshing etcd create state")
@@ -29,34 +197,205 @@ func (p *Planner) resetEtcdSnapshotCreateState(status rkev1.RKEControlPlaneStatu
 }
 
 func (p *Planner) startOrRestartEtcdSnapshotCreate(status rkev1.RKEControlPlaneStatus, snapshot *rkev1.ETCDSnapshotCreate) (rkev1.RKEControlPlaneStatus, error) {
-	if status.ETCDSnapshotCreate == nil || !equality.Semantic.DeepEqual(snapshot, status.ETCDSnapshotCreate) {
+	if status.ETCDSnapshotCreate == nil || !equality.Semantic.DeepEqual(withoutCreateCancel(snapshot), withoutCreateCancel(status.ETCDSnapshotCreate)) {
+		// the progress of the machines is recorded by the planner, and not taken from the spec
+		snapshot = snapshot.DeepCopy()
+		snapshot.Machines = nil
+		if snapshot.Cancel {
+			// a snapshot creation that is cancelled before it started is never started
+			return p.setEtcdSnapshotCreateState(status, snapshot, rkev1.ETCDSnapshotPhaseCancelled)
+		}
 		return p.setEtcdSnapshotCreateState(status, snapshot, rkev1.ETCDSnapshotPhaseStarted)
 	}
 	return status, nil
 }
 
-func (p *Planner) runEtcdSnapshotCreate(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, clusterPlan *plan.Plan, joinServer string) []error {
+// withoutCreateCancel returns a copy of the snapshot create with cancel and the progress of the machines unset, so that
+// cancelling a snapshot creation or recording its progress is not mistaken for a request to start a new one.
+func withoutCreateCancel(snapshot *rkev1.ETCDSnapshotCreate) *rkev1.ETCDSnapshotCreate {
+	if snapshot == nil {
+		return nil
+	}
+	snapshot = snapshot.DeepCopy()
+	snapshot.Cancel = false
+	snapshot.Machines = nil
+	return snapshot
+}
+
+// runEtcdSnapshotCreate delivers the snapshot plan to the etcd machines, to at most maxConcurrency of them at a time if it
+// is set, and returns the progress of the snapshot creation on each machine it was delivered to, updated from their
+// previous progress, along with the errors of the machines that are not done yet.
+func (p *Planner) runEtcdSnapshotCreate(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, clusterPlan *plan.Plan, joinServer string, machines []rkev1.ETCDSnapshotMachineProgress, maxConcurrency int, now time.Time) ([]rkev1.ETCDSnapshotMachineProgress, []error) {
 	servers := collect(clusterPlan, isEtcd)
 	if len(servers) == 0 {
-		return []error{errors.New("failed to find node to perform etcd snapshot")}
+		return machines, []error{errors.New("failed to find node to perform etcd snapshot")}
 	}
 
-	var errs []error
+	var (
+		errs     []error
+		progress []rkev1.ETCDSnapshotMachineProgress
+		gate     = etcdSnapshotCreateGate{maxConcurrency: maxConcurrency}
+	)
 
 	for _, server := range servers {
+		if err := faultinjection.ETCDSnapshotFailure(controlPlane, server.Machine.Name); err != nil {
+			errs = append(errs, err)
+			progress = append(progress, etcdSnapshotMachineProgress(machines, server.Machine.Name, err, now))
+			continue
+		}
 		createPlan, joinedServer, err := p.generateEtcdSnapshotCreatePlan(controlPlane, tokensSecret, server, joinServer)
 		if err != nil {
-			return []error{err}
+			return machines, []error{err}
 		}
 		msg := fmt.Sprintf("etcd snapshot on machine %s/%s", server.Machine.Namespace, server.Machine.Name)
 		if server.Machine.Status.NodeRef != nil && server.Machine.Status.NodeRef.Name != "" {
 			msg = fmt.Sprintf("etcd snapshot on node %s", server.Machine.Status.NodeRef.Name)
 		}
-		if err = assignAndCheckPlan(p.store, msg, server, createPlan, joinedServer, 3, 3); err != nil {
+		delivered := server.Plan != nil && equality.Semantic.DeepEqual(server.Plan.Plan, createPlan)
+		if !gate.admit(delivered) {
+			// the machine has no progress until the snapshot plan is delivered to it
+			errs = append(errs, errWaiting(fmt.Sprintf("waiting to start %s", msg)))
+			continue
+		}
+		err = assignAndCheckPlan(p.store, msg, server, createPlan, joinedServer, 3, 3)
+		gate.track(err)
+		if err != nil {
+			if !IsErrWaiting(err) && server.Plan != nil && server.Plan.Failed && len(server.Plan.Output[etcdSnapshotDiskPreflightInstructionName]) > 0 {
+				// include the reason the disk space preflight failed with rather than only reporting the failed plan
+				output := server.Plan.Output[etcdSnapshotDiskPreflightInstructionName]
+				err = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+			}
+			errs = append(errs, err)
+		}
+		progress = append(progress, etcdSnapshotMachineProgress(machines, server.Machine.Name, err, now))
+	}
+	return progress, errs
+}
+
+// etcdSnapshotCreateGate limits the number of etcd machines the snapshot plan is in flight on, i.e. delivered to but not
+// done yet, to maxConcurrency if it is set.
+type etcdSnapshotCreateGate struct {
+	maxConcurrency int
+	inFlight       int
+}
+
+// admit returns whether the snapshot plan may be delivered to the next machine. Machines the plan is already delivered to
+// are always admitted so that their progress keeps being checked.
+func (g *etcdSnapshotCreateGate) admit(delivered bool) bool {
+	return delivered || g.maxConcurrency <= 0 || g.inFlight < g.maxConcurrency
+}
+
+// track counts the machine as in flight if the snapshot plan is still being delivered to or run on it.
+func (g *etcdSnapshotCreateGate) track(err error) {
+	if IsErrWaiting(err) {
+		g.inFlight++
+	}
+}
+
+// runEtcdSnapshotCancelCleanup delivers the plan that deletes the snapshots of the cancelled snapshot creation to the
+// etcd machines the snapshot plan was delivered to, which covers snapshots that were being uploaded when the creation
+// was cancelled, and returns the progress of the machines with those the cleanup is done on marked as cancelled. A
+// cleanup that fails is recorded in the progress of the machine rather than blocking the cancellation.
+func (p *Planner) runEtcdSnapshotCancelCleanup(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, clusterPlan *plan.Plan, joinServer string, machines []rkev1.ETCDSnapshotMachineProgress) ([]rkev1.ETCDSnapshotMachineProgress, error) {
+	servers := map[string]*planEntry{}
+	for _, server := range collect(clusterPlan, isEtcd) {
+		servers[server.Machine.Name] = server
+	}
+
+	var errs []error
+	progress := make([]rkev1.ETCDSnapshotMachineProgress, 0, len(machines))
+	for _, machine := range machines {
+		server, ok := servers[machine.MachineName]
+		if machine.Phase == rkev1.ETCDSnapshotPhaseCancelled || !ok {
+			machine.Phase = rkev1.ETCDSnapshotPhaseCancelled
+			progress = append(progress, machine)
+			continue
+		}
+		cleanupPlan, joinedServer, err := p.generateEtcdSnapshotCancelCleanupPlan(controlPlane, tokensSecret, server, joinServer)
+		if err != nil {
+			return machines, err
+		}
+		msg := fmt.Sprintf("etcd snapshot cleanup on machine %s/%s", server.Machine.Namespace, server.Machine.Name)
+		if server.Machine.Status.NodeRef != nil && server.Machine.Status.NodeRef.Name != "" {
+			msg = fmt.Sprintf("etcd snapshot cleanup on node %s", server.Machine.Status.NodeRef.Name)
+		}
+		err = assignAndCheckPlan(p.store, msg, server, cleanupPlan, joinedServer, 3, 3)
+		switch {
+		case IsErrWaiting(err):
 			errs = append(errs, err)
+		case err != nil:
+			logrus.Warnf("[planner] rkecluster %s/%s: %v", controlPlane.Namespace, controlPlane.Name, err)
+			machine.Phase, machine.Error = rkev1.ETCDSnapshotPhaseCancelled, fmt.Sprintf("failed to delete snapshots: %v", err)
+		default:
+			machine.Phase, machine.Error = rkev1.ETCDSnapshotPhaseCancelled, ""
 		}
+		progress = append(progress, machine)
+	}
+	if len(errs) > 0 {
+		return progress, errWaiting(merr.NewErrors(errs...).Error())
+	}
+	return progress, nil
+}
+
+// generateEtcdSnapshotCancelCleanupPlan generates a plan that deletes the snapshots of the cancelled snapshot creation
+// from the node and S3.
+func (p *Planner) generateEtcdSnapshotCancelCleanupPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, entry *planEntry, joinServer string) (plan.NodePlan, string, error) {
+	cleanupPlan, _, joinedServer, err := p.generatePlanWithConfigFiles(controlPlane, tokensSecret, entry, joinServer)
+	if err != nil {
+		return cleanupPlan, joinedServer, err
 	}
-	return errs
+	cleanupPlan.Files = append(cleanupPlan.Files, plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotCancelCleanupScript)),
+		Path:    etcdSnapshotScriptFile(controlPlane, etcdSnapshotCancelCleanupScriptPath),
+	})
+	cleanupPlan.Instructions = append(cleanupPlan.Instructions, p.generateInstallInstructionWithSkipStart(controlPlane, entry),
+		etcdSnapshotCancelCleanupInstruction(controlPlane))
+	return cleanupPlan, joinedServer, nil
+}
+
+// etcdSnapshotCancelCleanupInstruction generates an instruction that deletes the snapshots saved by the current snapshot
+// creation on the node.
+func etcdSnapshotCancelCleanupInstruction(controlPlane *rkev1.RKEControlPlane) plan.OneTimeInstruction {
+	return plan.OneTimeInstruction{
+		Name:    etcdSnapshotCancelCleanupInstructionName,
+		Command: "sh",
+		Args: []string{
+			etcdSnapshotScriptFile(controlPlane, etcdSnapshotCancelCleanupScriptPath),
+			capr.GetRuntimeCommand(controlPlane.Spec.KubernetesVersion),
+			etcdSnapshotDir(controlPlane),
+			etcdSnapshotCreateName(controlPlane.Status.ETCDSnapshotCreate),
+		},
+		SaveOutput: true,
+	}
+}
+
+// etcdSnapshotMachineProgress returns the progress of the snapshot creation on the machine, given its previous progress
+// and the result of its snapshot plan. The duration is recorded once the snapshot creation on the machine completed.
+func etcdSnapshotMachineProgress(machines []rkev1.ETCDSnapshotMachineProgress, machineName string, err error, now time.Time) rkev1.ETCDSnapshotMachineProgress {
+	progress := rkev1.ETCDSnapshotMachineProgress{
+		MachineName: machineName,
+		StartTime:   &metav1.Time{Time: now},
+	}
+	for _, machine := range machines {
+		if machine.MachineName == machineName {
+			progress = *machine.DeepCopy()
+			break
+		}
+	}
+
+	switch {
+	case err == nil:
+		progress.Phase, progress.Error = rkev1.ETCDSnapshotPhaseFinished, ""
+	case IsErrWaiting(err):
+		progress.Phase, progress.Error, progress.Duration = rkev1.ETCDSnapshotPhaseStarted, "", nil
+		return progress
+	default:
+		progress.Phase, progress.Error = rkev1.ETCDSnapshotPhaseFailed, err.Error()
+	}
+	if progress.Duration == nil && progress.StartTime != nil {
+		progress.Duration = &metav1.Duration{Duration: now.Sub(progress.StartTime.Time).Round(time.Second)}
+	}
+	return progress
 }
 
 // generateEtcdSnapshotCreatePlan generates a plan that contains an instruction to create an etcd snapshot.
@@ -65,24 +404,216 @@ func (p *Planner) generateEtcdSnapshotCreatePlan(controlPlane *rkev1.RKEControlP
 		"etcd-snapshot",
 	}
 	createPlan, _, joinedServer, err := p.generatePlanWithConfigFiles(controlPlane, tokensSecret, entry, joinServer)
+	if err != nil {
+		return createPlan, joinedServer, err
+	}
+	if controlPlane.Spec.ETCD != nil && controlPlane.Spec.ETCD.SnapshotMinFreeDiskPercent != 0 {
+		instruction, err := etcdSnapshotDiskPreflightInstruction(controlPlane)
+		if err != nil {
+			return createPlan, joinedServer, err
+		}
+		createPlan.Files = append(createPlan.Files, plan.File{
+			Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotDiskPreflightScript)),
+			Path:    etcdSnapshotScriptFile(controlPlane, etcdSnapshotDiskPreflightScriptPath),
+		})
+		createPlan.Instructions = append(createPlan.Instructions, instruction)
+	}
+	if controlPlane.Spec.ETCD != nil && controlPlane.Spec.ETCD.SnapshotLoadGate != nil {
+		instruction, err := etcdSnapshotLoadGateInstruction(controlPlane)
+		if err != nil {
+			return createPlan, joinedServer, err
+		}
+		createPlan.Files = append(createPlan.Files, plan.File{
+			Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotLoadGateScript)),
+			Path:    etcdSnapshotScriptFile(controlPlane, etcdSnapshotLoadGateScriptPath),
+		})
+		createPlan.Instructions = append(createPlan.Instructions, instruction)
+	}
+	// the snapshot is saved with a name that is unique to the snapshot creation, so that the instructions that follow
+	// find the file it was saved to
+	snapshotName := etcdSnapshotCreateName(controlPlane.Status.ETCDSnapshotCreate)
+	args = append(args, "save", "--name="+snapshotName)
+	encryptFiles, encryptInstructions, err := p.etcdSnapshotEncryptPlan(controlPlane, snapshotName)
+	if err != nil {
+		return createPlan, joinedServer, err
+	}
+	s3 := controlPlane.Spec.ETCD != nil && S3Enabled(controlPlane.Spec.ETCD.S3)
+	var (
+		s3Upload  *plan.OneTimeInstruction
+		s3Files   []plan.File
+		encrypted = len(encryptInstructions) > 0
+	)
+	if s3 && (encrypted || controlPlane.Spec.ETCD.S3.StorageClass != "") {
+		// the distribution uploads the snapshot as soon as it was saved, and does not support setting its storage class,
+		// so the upload is left to Rancher, which uploads the snapshot once it was encrypted and with its storage class
+		config, err := GetS3Config(p.secretCache, controlPlane.Spec.ETCD.S3, controlPlane)
+		if err != nil {
+			return createPlan, joinedServer, err
+		}
+		instruction, files, err := etcdSnapshotUploadInstruction(controlPlane, config, controlPlane.Spec.ETCD.S3.FallbackEndpoints)
+		if err != nil {
+			return createPlan, joinedServer, fmt.Errorf("failed to upload etcd snapshots: %w", err)
+		}
+		args = append(args, "--etcd-s3=false")
+		s3Upload = &instruction
+		s3Files = append(files, plan.File{
+			Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotUploadScript)),
+			Path:    etcdSnapshotScriptFile(controlPlane, etcdSnapshotUploadScriptPath),
+		})
+	}
 	createPlan.Instructions = append(createPlan.Instructions, p.generateInstallInstructionWithSkipStart(controlPlane, entry),
 		plan.OneTimeInstruction{
 			Name:    "create",
 			Command: capr.GetRuntimeCommand(controlPlane.Spec.KubernetesVersion),
 			Args:    args,
 		})
+	// the snapshot is encrypted before its checksum is reported and it is offloaded, so that the checksum is verified
+	// against the encrypted copy in S3 and no copy of the snapshot leaves the node unencrypted
+	createPlan.Files = append(createPlan.Files, encryptFiles...)
+	createPlan.Instructions = append(createPlan.Instructions, encryptInstructions...)
+	if s3 {
+		createPlan.Files = append(createPlan.Files, plan.File{
+			Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotChecksumScript)),
+			Path:    etcdSnapshotScriptFile(controlPlane, etcdSnapshotChecksumScriptPath),
+		})
+		createPlan.Instructions = append(createPlan.Instructions, etcdSnapshotChecksumInstruction(controlPlane))
+	}
+	if s3Upload != nil {
+		createPlan.Files = append(createPlan.Files, s3Files...)
+		createPlan.Instructions = append(createPlan.Instructions, *s3Upload)
+	}
+	// snapshots are offloaded last, so that encrypted snapshots leave the node encrypted
+	files, instructions, err := p.etcdSnapshotAzureUploadPlan(controlPlane)
+	if err != nil {
+		return createPlan, joinedServer, err
+	}
+	createPlan.Files = append(createPlan.Files, files...)
+	createPlan.Instructions = append(createPlan.Instructions, instructions...)
+	files, instructions, err = p.etcdSnapshotGCSUploadPlan(controlPlane)
+	if err != nil {
+		return createPlan, joinedServer, err
+	}
+	createPlan.Files = append(createPlan.Files, files...)
+	createPlan.Instructions = append(createPlan.Instructions, instructions...)
 	return createPlan, joinedServer, err
 }
 
+// etcdSnapshotCreateName returns the name the snapshot of the passed in snapshot creation is saved with. The
+// distribution appends the node name and the creation time to it to name the snapshot file.
+func etcdSnapshotCreateName(create *rkev1.ETCDSnapshotCreate) string {
+	switch {
+	case create == nil:
+		return "on-demand"
+	case create.ScheduledAt != nil:
+		return fmt.Sprintf("scheduled-%d", create.ScheduledAt.Unix())
+	default:
+		return fmt.Sprintf("on-demand-%d", create.Generation)
+	}
+}
+
+func etcdSnapshotScriptFile(controlPlane *rkev1.RKEControlPlane, scriptPath string) string {
+	return fmt.Sprintf("/var/lib/rancher/%s/%s", capr.GetRuntime(controlPlane.Spec.KubernetesVersion), scriptPath)
+}
+
+// etcdSnapshotDir returns the directory that etcd snapshots are saved to on the nodes of the control plane.
+func etcdSnapshotDir(controlPlane *rkev1.RKEControlPlane) string {
+	if controlPlane.Spec.ETCD != nil && controlPlane.Spec.ETCD.SnapshotDir != "" {
+		return controlPlane.Spec.ETCD.SnapshotDir
+	}
+	if dir := convert.ToString(controlPlane.Spec.MachineGlobalConfig.Data["etcd-snapshot-dir"]); dir != "" {
+		return dir
+	}
+	return fmt.Sprintf("/var/lib/rancher/%s/server/db/snapshots", capr.GetRuntime(controlPlane.Spec.KubernetesVersion))
+}
+
+// etcdSnapshotDiskPreflightInstruction generates an instruction that fails if the filesystem of the snapshot directory
+// has less free space than required, so that a snapshot does not fill up the disk of the node.
+func etcdSnapshotDiskPreflightInstruction(controlPlane *rkev1.RKEControlPlane) (plan.OneTimeInstruction, error) {
+	minFree := controlPlane.Spec.ETCD.SnapshotMinFreeDiskPercent
+	if minFree < 0 || minFree > 100 {
+		return plan.OneTimeInstruction{}, fmt.Errorf("etcd snapshot minimum free disk percentage %d must be between 0 and 100", minFree)
+	}
+	return plan.OneTimeInstruction{
+		Name:    etcdSnapshotDiskPreflightInstructionName,
+		Command: "sh",
+		Args: []string{
+			etcdSnapshotScriptFile(controlPlane, etcdSnapshotDiskPreflightScriptPath),
+			etcdSnapshotDir(controlPlane),
+			strconv.Itoa(minFree),
+		},
+		SaveOutput: true,
+	}, nil
+}
+
+// etcdSnapshotLoadGateInstruction generates an instruction that blocks until the load of the etcd member of the node
+// is within the thresholds of the snapshot load gate, or its deadline has passed. The planner waits for the plan of the
+// node to complete, so that the snapshot is retried later without failing the snapshot creation.
+func etcdSnapshotLoadGateInstruction(controlPlane *rkev1.RKEControlPlane) (plan.OneTimeInstruction, error) {
+	gate := controlPlane.Spec.ETCD.SnapshotLoadGate
+	if gate.MaxWALFsyncDurationMilliseconds < 0 || gate.MaxBackendCommitDurationMilliseconds < 0 {
+		return plan.OneTimeInstruction{}, fmt.Errorf("etcd snapshot load gate thresholds must not be negative")
+	}
+	if gate.RetryIntervalSeconds < 0 || gate.DeadlineSeconds < 0 {
+		return plan.OneTimeInstruction{}, fmt.Errorf("etcd snapshot load gate retry interval and deadline must not be negative")
+	}
+	interval := gate.RetryIntervalSeconds
+	if interval == 0 {
+		interval = defaultEtcdSnapshotLoadGateRetryIntervalSeconds
+	}
+	deadline := gate.DeadlineSeconds
+	if deadline == 0 {
+		deadline = defaultEtcdSnapshotLoadGateDeadlineSeconds
+	}
+	return plan.OneTimeInstruction{
+		Name:    etcdSnapshotLoadGateInstructionName,
+		Command: "sh",
+		Args: []string{
+			etcdSnapshotScriptFile(controlPlane, etcdSnapshotLoadGateScriptPath),
+			strconv.Itoa(gate.MaxWALFsyncDurationMilliseconds),
+			strconv.Itoa(gate.MaxBackendCommitDurationMilliseconds),
+			strconv.Itoa(interval),
+			strconv.Itoa(deadline),
+		},
+		SaveOutput: true,
+	}, nil
+}
+
+// etcdSnapshotChecksumInstruction generates an instruction that reports the size and digests of the most recent
+// snapshot file saved by the current snapshot creation on the node, which is the snapshot that was just created and
+// uploaded to S3.
+func etcdSnapshotChecksumInstruction(controlPlane *rkev1.RKEControlPlane) plan.OneTimeInstruction {
+	args := []string{
+		etcdSnapshotScriptFile(controlPlane, etcdSnapshotChecksumScriptPath),
+		etcdSnapshotDir(controlPlane),
+		etcdSnapshotCreateName(controlPlane.Status.ETCDSnapshotCreate),
+	}
+	for _, partSize := range etcdSnapshotS3PartSizes {
+		args = append(args, strconv.FormatInt(partSize, 10))
+	}
+	return plan.OneTimeInstruction{
+		Name:       ETCDSnapshotChecksumInstruction,
+		Command:    "sh",
+		Args:       args,
+		SaveOutput: true,
+	}
+}
+
 func (p *Planner) createEtcdSnapshot(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, tokensSecret plan.Secret, clusterPlan *plan.Plan) (rkev1.RKEControlPlaneStatus, error) {
-	var err error
-	if controlPlane.Spec.ETCDSnapshotCreate == nil {
-		status, err := p.resetEtcdSnapshotCreateState(status)
+	ready := status.Initialized && capr.Bootstrapped.IsTrue(&status)
+	now := time.Now().UTC()
+	snapshot, status, next, err := etcdSnapshotCreateRequest(controlPlane, status, ready, now)
+	if !next.IsZero() {
+		p.rkeControlPlanes.EnqueueAfter(controlPlane.Namespace, controlPlane.Name, next.Sub(now))
+	}
+	if err != nil {
 		return status, err
 	}
+	if snapshot == nil {
+		return p.resetEtcdSnapshotCreateState(status)
+	}
 
 	// Don't create an etcd snapshot if the cluster is not initialized or bootstrapped.
-	if !status.Initialized || !capr.Bootstrapped.IsTrue(&status) {
+	if !ready {
 		return status, nil
 	}
 
@@ -96,17 +627,36 @@ func (p *Planner) createEtcdSnapshot(controlPlane *rkev1.RKEControlPlane, status
 		return status, nil
 	}
 
-	snapshot := controlPlane.Spec.ETCDSnapshotCreate
-
 	if status, err = p.startOrRestartEtcdSnapshotCreate(status, snapshot); err != nil {
 		return status, err
 	}
+	// the progress of the machines is kept when the state of the snapshot creation changes
+	snapshot = snapshot.DeepCopy()
+	snapshot.Machines = nil
+	if status.ETCDSnapshotCreate != nil {
+		snapshot.Machines = status.ETCDSnapshotCreate.Machines
+	}
 
 	switch controlPlane.Status.ETCDSnapshotCreatePhase {
 	case rkev1.ETCDSnapshotPhaseStarted:
+		if snapshot.Cancel {
+			logrus.Infof("[planner] rkecluster %s/%s: cancelling etcd snapshot creation", controlPlane.Namespace, controlPlane.Name)
+			return p.setEtcdSnapshotCreateState(status, snapshot, rkev1.ETCDSnapshotPhaseCancelling)
+		}
+		rotated := false
+		if status, rotated, err = p.rotateEtcdSnapshotEncryptionKey(controlPlane, status); err != nil {
+			return status, err
+		} else if rotated {
+			// the new data key must be recorded before any snapshot is encrypted with it
+			return status, errWaiting("rotated etcd snapshot encryption key")
+		}
 		var stateSet bool
 		var finErrs []error
-		if errs := p.runEtcdSnapshotCreate(controlPlane, tokensSecret, clusterPlan, joinServer); len(errs) > 0 {
+		machines, errs := p.runEtcdSnapshotCreate(controlPlane, tokensSecret, clusterPlan, joinServer, snapshot.Machines, snapshot.MaxConcurrency, time.Now())
+		snapshot.Machines = machines
+		if len(errs) > 0 {
+			// record the progress of the machines while the snapshot is created on them
+			status.ETCDSnapshotCreate = snapshot
 			for _, err := range errs {
 				if err == nil {
 					continue
@@ -126,6 +676,26 @@ func (p *Planner) createEtcdSnapshot(controlPlane *rkev1.RKEControlPlane, status
 			}
 			return status, errWaiting(merr.NewErrors(finErrs...).Error())
 		}
+		nextPhase := rkev1.ETCDSnapshotPhaseRestartCluster
+		if controlPlane.Spec.ETCD != nil && controlPlane.Spec.ETCD.CompactAfterSnapshot {
+			nextPhase = rkev1.ETCDSnapshotPhaseCompact
+		}
+		if status, err = p.setEtcdSnapshotCreateState(status, snapshot, nextPhase); err != nil {
+			return status, err
+		}
+		return status, nil
+	case rkev1.ETCDSnapshotPhaseCompact:
+		if snapshot.Cancel {
+			logrus.Infof("[planner] rkecluster %s/%s: cancelling etcd compaction after snapshot creation", controlPlane.Namespace, controlPlane.Name)
+			return p.setEtcdSnapshotCreateState(status, snapshot, rkev1.ETCDSnapshotPhaseCancelling)
+		}
+		revision, err := p.runEtcdCompaction(controlPlane, tokensSecret, clusterPlan, joinServer)
+		if err != nil {
+			return status, err
+		}
+		if revision > 0 {
+			status.ETCDCompactedRevision = revision
+		}
 		if status, err = p.setEtcdSnapshotCreateState(status, snapshot, rkev1.ETCDSnapshotPhaseRestartCluster); err != nil {
 			return status, err
 		}
@@ -134,13 +704,49 @@ func (p *Planner) createEtcdSnapshot(controlPlane *rkev1.RKEControlPlane, status
 		if err = p.runEtcdSnapshotManagementServiceStart(controlPlane, tokensSecret, clusterPlan, isEtcd, "etcd snapshot creation"); err != nil {
 			return status, err
 		}
+		if status, err = p.setEtcdSnapshotCreateState(status, snapshot, rkev1.ETCDSnapshotPhaseVerifying); err != nil {
+			return status, err
+		}
+		return status, nil
+	case rkev1.ETCDSnapshotPhaseVerifying:
+		// the snapshot was already created, so the verification is not cancelled
+		machines, err := p.runEtcdSnapshotVerification(controlPlane, snapshot, tokensSecret, clusterPlan, joinServer, time.Now())
+		snapshot.Machines = machines
+		if err != nil {
+			status.ETCDSnapshotCreate = snapshot
+			return status, err
+		}
+		var corrupt []string
+		for _, machine := range machines {
+			if machine.Phase == rkev1.ETCDSnapshotPhaseFailed {
+				corrupt = append(corrupt, machine.Error)
+			}
+		}
+		if len(corrupt) > 0 {
+			status, _ = p.setEtcdSnapshotCreateState(status, snapshot, rkev1.ETCDSnapshotPhaseFailed)
+			return status, errWaiting(strings.Join(corrupt, ", "))
+		}
 		if status, err = p.setEtcdSnapshotCreateState(status, snapshot, rkev1.ETCDSnapshotPhaseFinished); err != nil {
 			return status, err
 		}
 		return status, nil
-	case rkev1.ETCDSnapshotPhaseFailed:
-		fallthrough
-	case rkev1.ETCDSnapshotPhaseFinished:
+	case rkev1.ETCDSnapshotPhaseCancelling:
+		// delete the snapshots the cancelled creation saved and uploaded before the regular plans are restored on the
+		// nodes that the snapshot plan was delivered to
+		machines, err := p.runEtcdSnapshotCancelCleanup(controlPlane, tokensSecret, clusterPlan, joinServer, snapshot.Machines)
+		snapshot.Machines = machines
+		status.ETCDSnapshotCreate = snapshot
+		if err != nil {
+			return status, err
+		}
+		if err = p.runEtcdSnapshotManagementServiceStart(controlPlane, tokensSecret, clusterPlan, isEtcd, "etcd snapshot cancellation"); err != nil {
+			return status, err
+		}
+		if status, err = p.setEtcdSnapshotCreateState(status, snapshot, rkev1.ETCDSnapshotPhaseCancelled); err != nil {
+			return status, err
+		}
+		return status, nil
+	case rkev1.ETCDSnapshotPhaseFailed, rkev1.ETCDSnapshotPhaseFinished, rkev1.ETCDSnapshotPhaseCancelled:
 		return status, nil
 	default:
 		if status, err = p.setEtcdSnapshotCreateState(status, snapshot, rkev1.ETCDSnapshotPhaseStarted); err != nil {
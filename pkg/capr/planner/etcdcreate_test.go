@@ -0,0 +1,305 @@
+package planner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEtcdSnapshotDir(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	controlPlane.Spec.KubernetesVersion = "v1.25.9+rke2r1"
+	assert.Equal(t, "/var/lib/rancher/rke2/server/db/snapshots", etcdSnapshotDir(controlPlane))
+
+	controlPlane.Spec.MachineGlobalConfig.Data = map[string]interface{}{"etcd-snapshot-dir": "/data/user-snapshots"}
+	assert.Equal(t, "/data/user-snapshots", etcdSnapshotDir(controlPlane))
+
+	controlPlane.Spec.ETCD = &rkev1.ETCD{SnapshotDir: "/data/snapshots"}
+	assert.Equal(t, "/data/snapshots", etcdSnapshotDir(controlPlane))
+}
+
+func TestEtcdSnapshotCreateName(t *testing.T) {
+	scheduledAt := metav1.NewTime(time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, "on-demand", etcdSnapshotCreateName(nil))
+	assert.Equal(t, "on-demand-3", etcdSnapshotCreateName(&rkev1.ETCDSnapshotCreate{Generation: 3}))
+	assert.Equal(t, "scheduled-1696161600", etcdSnapshotCreateName(&rkev1.ETCDSnapshotCreate{Generation: 3, ScheduledAt: &scheduledAt}))
+}
+
+func TestEtcdSnapshotDiskPreflightInstruction(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	controlPlane.Spec.KubernetesVersion = "v1.25.9+k3s1"
+	controlPlane.Spec.ETCD = &rkev1.ETCD{
+		SnapshotDir:                "/data/snapshots",
+		SnapshotMinFreeDiskPercent: 20,
+	}
+
+	instruction, err := etcdSnapshotDiskPreflightInstruction(controlPlane)
+	assert.NoError(t, err)
+	assert.Equal(t, etcdSnapshotDiskPreflightInstructionName, instruction.Name)
+	assert.Equal(t, "sh", instruction.Command)
+	assert.Equal(t, []string{"/var/lib/rancher/k3s/rancher_v2prov_etcd_snapshot/bin/disk-preflight.sh", "/data/snapshots", "20"}, instruction.Args)
+	assert.True(t, instruction.SaveOutput)
+
+	controlPlane.Spec.ETCD.SnapshotMinFreeDiskPercent = 101
+	_, err = etcdSnapshotDiskPreflightInstruction(controlPlane)
+	assert.EqualError(t, err, "etcd snapshot minimum free disk percentage 101 must be between 0 and 100")
+}
+
+func TestEtcdSnapshotLoadGateInstruction(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	controlPlane.Spec.KubernetesVersion = "v1.25.9+rke2r1"
+	controlPlane.Spec.ETCD = &rkev1.ETCD{
+		SnapshotLoadGate: &rkev1.ETCDSnapshotLoadGate{
+			MaxWALFsyncDurationMilliseconds: 50,
+		},
+	}
+
+	instruction, err := etcdSnapshotLoadGateInstruction(controlPlane)
+	assert.NoError(t, err)
+	assert.Equal(t, etcdSnapshotLoadGateInstructionName, instruction.Name)
+	assert.Equal(t, "sh", instruction.Command)
+	assert.Equal(t, []string{"/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/bin/load-gate.sh", "50", "0", "30", "1800"}, instruction.Args)
+	assert.True(t, instruction.SaveOutput)
+
+	controlPlane.Spec.ETCD.SnapshotLoadGate.MaxBackendCommitDurationMilliseconds = 100
+	controlPlane.Spec.ETCD.SnapshotLoadGate.RetryIntervalSeconds = 60
+	controlPlane.Spec.ETCD.SnapshotLoadGate.DeadlineSeconds = 600
+	instruction, err = etcdSnapshotLoadGateInstruction(controlPlane)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/bin/load-gate.sh", "50", "100", "60", "600"}, instruction.Args)
+
+	controlPlane.Spec.ETCD.SnapshotLoadGate.MaxBackendCommitDurationMilliseconds = -1
+	_, err = etcdSnapshotLoadGateInstruction(controlPlane)
+	assert.Error(t, err)
+}
+
+func TestEtcdSnapshotCancelCleanupInstruction(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	controlPlane.Spec.KubernetesVersion = "v1.25.9+rke2r1"
+	controlPlane.Status.ETCDSnapshotCreate = &rkev1.ETCDSnapshotCreate{Generation: 2, Cancel: true}
+
+	instruction := etcdSnapshotCancelCleanupInstruction(controlPlane)
+	assert.Equal(t, etcdSnapshotCancelCleanupInstructionName, instruction.Name)
+	assert.Equal(t, "sh", instruction.Command)
+	assert.Equal(t, []string{
+		"/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/bin/cancel-cleanup.sh",
+		"rke2",
+		"/var/lib/rancher/rke2/server/db/snapshots",
+		"on-demand-2",
+	}, instruction.Args)
+	assert.True(t, instruction.SaveOutput)
+}
+
+func TestStartOrRestartEtcdSnapshotCreate(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        rkev1.RKEControlPlaneStatus
+		snapshot      *rkev1.ETCDSnapshotCreate
+		expectedPhase rkev1.ETCDSnapshotPhase
+		expectedErr   bool
+	}{
+		{
+			name:          "new snapshot",
+			snapshot:      &rkev1.ETCDSnapshotCreate{Generation: 1},
+			expectedPhase: rkev1.ETCDSnapshotPhaseStarted,
+			expectedErr:   true,
+		},
+		{
+			name:          "new snapshot that is cancelled",
+			snapshot:      &rkev1.ETCDSnapshotCreate{Generation: 1, Cancel: true},
+			expectedPhase: rkev1.ETCDSnapshotPhaseCancelled,
+			expectedErr:   true,
+		},
+		{
+			name: "cancelling a started snapshot does not restart it",
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotCreate:      &rkev1.ETCDSnapshotCreate{Generation: 1},
+				ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseStarted,
+			},
+			snapshot:      &rkev1.ETCDSnapshotCreate{Generation: 1, Cancel: true},
+			expectedPhase: rkev1.ETCDSnapshotPhaseStarted,
+		},
+		{
+			name: "recorded progress of the machines does not restart a snapshot",
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotCreate: &rkev1.ETCDSnapshotCreate{
+					Generation: 1,
+					Machines:   []rkev1.ETCDSnapshotMachineProgress{{MachineName: "etcd-a", Phase: rkev1.ETCDSnapshotPhaseStarted}},
+				},
+				ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseStarted,
+			},
+			snapshot:      &rkev1.ETCDSnapshotCreate{Generation: 1},
+			expectedPhase: rkev1.ETCDSnapshotPhaseStarted,
+		},
+		{
+			name: "new generation after cancelled snapshot",
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotCreate:      &rkev1.ETCDSnapshotCreate{Generation: 1, Cancel: true},
+				ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseCancelled,
+			},
+			snapshot:      &rkev1.ETCDSnapshotCreate{Generation: 2},
+			expectedPhase: rkev1.ETCDSnapshotPhaseStarted,
+			expectedErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := (&Planner{}).startOrRestartEtcdSnapshotCreate(tt.status, tt.snapshot)
+			assert.Equal(t, tt.expectedErr, err != nil)
+			assert.Equal(t, tt.expectedPhase, status.ETCDSnapshotCreatePhase)
+		})
+	}
+}
+
+func TestEtcdSnapshotMachineProgress(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	startTime := &metav1.Time{Time: now.Add(-90 * time.Second)}
+	started := []rkev1.ETCDSnapshotMachineProgress{
+		{MachineName: "etcd-a", Phase: rkev1.ETCDSnapshotPhaseStarted, StartTime: startTime},
+	}
+
+	tests := []struct {
+		name     string
+		machines []rkev1.ETCDSnapshotMachineProgress
+		err      error
+		expected rkev1.ETCDSnapshotMachineProgress
+	}{
+		{
+			name: "plan delivered",
+			err:  errWaiting("starting etcd snapshot on node etcd-a"),
+			expected: rkev1.ETCDSnapshotMachineProgress{
+				MachineName: "etcd-a",
+				Phase:       rkev1.ETCDSnapshotPhaseStarted,
+				StartTime:   &metav1.Time{Time: now},
+			},
+		},
+		{
+			name:     "in progress",
+			machines: started,
+			err:      errWaiting("waiting for etcd snapshot on node etcd-a"),
+			expected: started[0],
+		},
+		{
+			name:     "finished",
+			machines: started,
+			expected: rkev1.ETCDSnapshotMachineProgress{
+				MachineName: "etcd-a",
+				Phase:       rkev1.ETCDSnapshotPhaseFinished,
+				StartTime:   startTime,
+				Duration:    &metav1.Duration{Duration: 90 * time.Second},
+			},
+		},
+		{
+			name:     "failed",
+			machines: started,
+			err:      errors.New("operation etcd snapshot on node etcd-a failed"),
+			expected: rkev1.ETCDSnapshotMachineProgress{
+				MachineName: "etcd-a",
+				Phase:       rkev1.ETCDSnapshotPhaseFailed,
+				Error:       "operation etcd snapshot on node etcd-a failed",
+				StartTime:   startTime,
+				Duration:    &metav1.Duration{Duration: 90 * time.Second},
+			},
+		},
+		{
+			name: "duration is kept once completed",
+			machines: []rkev1.ETCDSnapshotMachineProgress{
+				{MachineName: "etcd-a", Phase: rkev1.ETCDSnapshotPhaseFinished, StartTime: startTime, Duration: &metav1.Duration{Duration: time.Minute}},
+			},
+			expected: rkev1.ETCDSnapshotMachineProgress{
+				MachineName: "etcd-a",
+				Phase:       rkev1.ETCDSnapshotPhaseFinished,
+				StartTime:   startTime,
+				Duration:    &metav1.Duration{Duration: time.Minute},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, etcdSnapshotMachineProgress(tt.machines, "etcd-a", tt.err, now))
+		})
+	}
+}
+
+func TestEtcdSnapshotCreateGate(t *testing.T) {
+	const (
+		pending = "pending"
+		running = "running"
+		done    = "done"
+		failed  = "failed"
+	)
+
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		machines       []string
+		expected       []bool
+	}{
+		{
+			name:     "unlimited",
+			machines: []string{pending, pending, pending},
+			expected: []bool{true, true, true},
+		},
+		{
+			name:           "delivered to max concurrency machines at a time",
+			maxConcurrency: 2,
+			machines:       []string{pending, pending, pending, pending},
+			expected:       []bool{true, true, false, false},
+		},
+		{
+			name:           "in flight machines hold back the rest",
+			maxConcurrency: 2,
+			machines:       []string{running, running, pending, pending},
+			expected:       []bool{true, true, false, false},
+		},
+		{
+			name:           "done machines free their slot",
+			maxConcurrency: 2,
+			machines:       []string{done, running, pending, pending},
+			expected:       []bool{true, true, true, false},
+		},
+		{
+			name:           "failed machines free their slot",
+			maxConcurrency: 2,
+			machines:       []string{failed, done, pending, pending},
+			expected:       []bool{true, true, true, true},
+		},
+		{
+			name:           "delivered machines are admitted beyond max concurrency",
+			maxConcurrency: 1,
+			machines:       []string{pending, running, done},
+			expected:       []bool{true, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := etcdSnapshotCreateGate{maxConcurrency: tt.maxConcurrency}
+			var admitted []bool
+			for _, machine := range tt.machines {
+				ok := gate.admit(machine != pending)
+				admitted = append(admitted, ok)
+				if !ok {
+					continue
+				}
+				switch machine {
+				case pending:
+					gate.track(errWaiting("starting etcd snapshot"))
+				case running:
+					gate.track(errWaiting("waiting for etcd snapshot"))
+				case failed:
+					gate.track(errors.New("operation etcd snapshot failed"))
+				default:
+					gate.track(nil)
+				}
+			}
+			assert.Equal(t, tt.expected, admitted)
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package planner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/kms"
+	"github.com/rancher/rancher/pkg/controllers/capr/machineprovision"
+	"github.com/rancher/wrangler/pkg/kv"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	etcdSnapshotEncryptInstructionName = "etcd-snapshot-encrypt"
+	etcdSnapshotEncryptScriptPath      = "rancher_v2prov_etcd_snapshot/bin/encrypt.sh"
+	etcdSnapshotDecryptInstructionName = "etcd-snapshot-decrypt"
+	etcdSnapshotDecryptScriptPath      = "rancher_v2prov_etcd_snapshot/bin/decrypt.sh"
+	etcdSnapshotKeyIDDir               = "rancher_v2prov_etcd_snapshot/keys"
+	etcdSnapshotDecryptedPath          = "rancher_v2prov_etcd_snapshot/restore.db"
+	etcdSnapshotDownloadedPath         = "rancher_v2prov_etcd_snapshot/download.db"
+
+	// etcdSnapshotDataKeyEnv is the environment variable that passes the data key to the scripts that use it.
+	etcdSnapshotDataKeyEnv = "ETCD_SNAPSHOT_DATA_KEY"
+
+	etcdSnapshotSecretKeyField     = "key"
+	etcdSnapshotSecretKeyMinLength = 32
+
+	defaultEtcdSnapshotEncryptionRotationInterval = 720 * time.Hour
+	etcdSnapshotKMSTimeout                        = 30 * time.Second
+
+	// etcdSnapshotEncryptScript encrypts the snapshot file that the snapshot creation of the passed in name saved on the
+	// node in place with the data key, and records the ID of the data key for the snapshot outside the snapshot
+	// directory, so that the retention of the distribution is not affected. The file is encrypted in place, so that the
+	// distribution still prunes it, and the distribution refreshes its record of the file when it is restarted after
+	// the snapshot creation. Files that are already encrypted are left as they are, so that the plan can be retried.
+	etcdSnapshotEncryptScript = `
+#!/bin/sh
+
+dir=$1
+name=$2
+keyID=$3
+keyIDDir=$4
+
+if ! command -v openssl >/dev/null 2>&1; then
+	echo "openssl is required to encrypt etcd snapshots" >&2
+	exit 1
+fi
+file=""
+for candidate in "$dir/$name"-*; do
+	[ -f "$candidate" ] || continue
+	if [ -z "$file" ] || [ "$candidate" -nt "$file" ]; then
+		file=$candidate
+	fi
+done
+if [ -z "$file" ]; then
+	echo "no etcd snapshot $name found in $dir" >&2
+	exit 1
+fi
+snapshot=$(basename "$file")
+if [ "$(head -c 8 "$file")" = "Salted__" ]; then
+	echo "etcd snapshot $snapshot is already encrypted"
+	exit 0
+fi
+if ! openssl enc -aes-256-cbc -pbkdf2 -salt -pass "env:` + etcdSnapshotDataKeyEnv + `" -in "$file" -out "$file.enc"; then
+	rm -f "$file.enc"
+	echo "failed to encrypt etcd snapshot $snapshot" >&2
+	exit 1
+fi
+mkdir -p "$keyIDDir" || exit 1
+mv "$file.enc" "$file" || exit 1
+echo "$keyID" > "$keyIDDir/$snapshot"
+echo "encrypted etcd snapshot $snapshot with data key $keyID"
+`
+
+	// etcdSnapshotDecryptScript decrypts the snapshot file to the restore path if it was encrypted, and links the
+	// restore path to the snapshot file otherwise. A snapshot is encrypted if the ID of its data key was recorded on the
+	// node, or, for snapshots that were downloaded from S3, if it starts with the salt header of openssl.
+	etcdSnapshotDecryptScript = `
+#!/bin/sh
+
+file=$1
+keyID=$2
+keyIDFile=$3
+out=$4
+
+rm -f "$out"
+mkdir -p "$(dirname "$out")" || exit 1
+if [ -n "$keyIDFile" ] && [ -f "$keyIDFile" ]; then
+	encryptedWith=$(cat "$keyIDFile")
+	if [ "$encryptedWith" != "$keyID" ]; then
+		echo "etcd snapshot $file was encrypted with data key $encryptedWith, not $keyID" >&2
+		exit 1
+	fi
+elif [ "$(head -c 8 "$file")" != "Salted__" ]; then
+	echo "etcd snapshot $file is not encrypted"
+	ln -s "$file" "$out"
+	exit 0
+fi
+if ! openssl enc -d -aes-256-cbc -pbkdf2 -pass "env:` + etcdSnapshotDataKeyEnv + `" -in "$file" -out "$out"; then
+	rm -f "$out"
+	echo "failed to decrypt etcd snapshot $file with data key $keyID" >&2
+	exit 1
+fi
+echo "decrypted etcd snapshot $file with data key $keyID"
+`
+)
+
+// rotateEtcdSnapshotEncryptionKey generates a new data key if snapshot encryption is enabled and there is no current
+// data key, the current data key is older than the rotation interval or the KMS configuration changed. The previous
+// data keys are kept, so that the snapshots they encrypted can still be restored.
+func (p *Planner) rotateEtcdSnapshotEncryptionKey(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, bool, error) {
+	if controlPlane.Spec.ETCD == nil || controlPlane.Spec.ETCD.SnapshotEncryption == nil {
+		return status, false, nil
+	}
+	encryption := controlPlane.Spec.ETCD.SnapshotEncryption
+	if !etcdSnapshotEncryptionKeyExpired(encryption, status.ETCDSnapshotEncryptionKeys, time.Now()) {
+		return status, false, nil
+	}
+
+	provider, err := p.etcdSnapshotKMSProvider(controlPlane, encryption)
+	if err != nil {
+		return status, false, err
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, etcdSnapshotKMSTimeout)
+	defer cancel()
+	plaintext, wrapped, err := kms.GenerateDataKey(ctx, provider)
+	if err != nil {
+		return status, false, err
+	}
+
+	key := rkev1.ETCDSnapshotEncryptionKey{
+		ID:               kms.KeyID(wrapped),
+		Encryption:       *encryption.DeepCopy(),
+		KeyVersion:       wrapped.KeyVersion,
+		EncryptedDataKey: wrapped.Ciphertext,
+		CreatedAt:        metav1.Now(),
+	}
+	key.Encryption.RotationInterval = nil
+	p.etcdSnapshotDataKeys.Store(etcdSnapshotDataKeyCacheKey(controlPlane, key.ID), plaintext)
+	logrus.Infof("[planner] rkecluster %s/%s: generated etcd snapshot data key %s wrapped by %s key %s", controlPlane.Namespace, controlPlane.Name, key.ID, encryption.Provider, encryption.KeyName)
+	status.ETCDSnapshotEncryptionKeys = append(status.ETCDSnapshotEncryptionKeys, key)
+	return status, true, nil
+}
+
+// etcdSnapshotEncryptionKeyExpired returns true if a new data key must be generated for the encryption settings.
+func etcdSnapshotEncryptionKeyExpired(encryption *rkev1.ETCDSnapshotEncryption, keys []rkev1.ETCDSnapshotEncryptionKey, now time.Time) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	current := keys[len(keys)-1]
+	if current.Encryption.Provider != encryption.Provider ||
+		current.Encryption.KeyName != encryption.KeyName ||
+		current.Encryption.Endpoint != encryption.Endpoint ||
+		current.Encryption.Region != encryption.Region ||
+		current.Encryption.VaultTransitMount != encryption.VaultTransitMount ||
+		current.Encryption.CloudCredentialSecretName != encryption.CloudCredentialSecretName {
+		return true
+	}
+	interval := defaultEtcdSnapshotEncryptionRotationInterval
+	if encryption.RotationInterval != nil && encryption.RotationInterval.Duration > 0 {
+		interval = encryption.RotationInterval.Duration
+	}
+	return !now.Before(current.CreatedAt.Add(interval))
+}
+
+// etcdSnapshotEncryptionKeyAt returns the data key that snapshots created at the passed in time were encrypted with,
+// or the current data key if the time is unknown. Nil is returned if the snapshot was created before the first data
+// key was generated.
+func etcdSnapshotEncryptionKeyAt(keys []rkev1.ETCDSnapshotEncryptionKey, createdAt *metav1.Time) *rkev1.ETCDSnapshotEncryptionKey {
+	if len(keys) == 0 {
+		return nil
+	}
+	if createdAt == nil {
+		return &keys[len(keys)-1]
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !createdAt.Before(&keys[i].CreatedAt) {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// etcdSnapshotKMSProvider returns the KMS provider of the encryption settings, accessed with their cloud credential.
+func (p *Planner) etcdSnapshotKMSProvider(controlPlane *rkev1.RKEControlPlane, encryption *rkev1.ETCDSnapshotEncryption) (kms.Provider, error) {
+	credentials := map[string][]byte{}
+	if encryption.CloudCredentialSecretName != "" {
+		secret, err := machineprovision.GetCloudCredentialSecret(p.secretCache, controlPlane.Namespace, encryption.CloudCredentialSecretName, controlPlane.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup etcd snapshot encryption cloud credential: %w", err)
+		}
+		// the KMS is called by Rancher, so the data of the secret store does not leave this server
+		secret, err = machineprovision.ResolveCloudCredentialSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range secret.Data {
+			_, k = kv.RSplit(k, "-")
+			credentials[k] = v
+		}
+	}
+	return kms.New(encryption, credentials)
+}
+
+// etcdSnapshotDataKey returns the plaintext of the data key, unwrapping it with the KMS it was wrapped by unless it was
+// unwrapped before.
+func (p *Planner) etcdSnapshotDataKey(controlPlane *rkev1.RKEControlPlane, key *rkev1.ETCDSnapshotEncryptionKey) ([]byte, error) {
+	cacheKey := etcdSnapshotDataKeyCacheKey(controlPlane, key.ID)
+	if plaintext, ok := p.etcdSnapshotDataKeys.Load(cacheKey); ok {
+		return plaintext.([]byte), nil
+	}
+	provider, err := p.etcdSnapshotKMSProvider(controlPlane, &key.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, etcdSnapshotKMSTimeout)
+	defer cancel()
+	plaintext, err := provider.Unwrap(ctx, kms.WrappedKey{
+		KeyVersion: key.KeyVersion,
+		Ciphertext: key.EncryptedDataKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap etcd snapshot data key %s: %w", key.ID, err)
+	}
+	p.etcdSnapshotDataKeys.Store(cacheKey, plaintext)
+	return plaintext, nil
+}
+
+// etcdSnapshotSecretKey returns the customer managed key of the named secret in the namespace of the control plane,
+// and its ID, which is derived from the key so that snapshots encrypted with another key are recognized on restore.
+func (p *Planner) etcdSnapshotSecretKey(controlPlane *rkev1.RKEControlPlane, secretName string) ([]byte, string, error) {
+	secret, err := p.secretCache.Get(controlPlane.Namespace, secretName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to lookup etcd snapshot encryption key secret %s/%s: %w", controlPlane.Namespace, secretName, err)
+	}
+	if secret.Labels[capr.ClusterNameLabel] != controlPlane.Spec.ClusterName {
+		return nil, "", fmt.Errorf("etcd snapshot encryption key secret %s/%s does not belong to cluster %s, it must be labeled %s=%s",
+			controlPlane.Namespace, secretName, controlPlane.Spec.ClusterName, capr.ClusterNameLabel, controlPlane.Spec.ClusterName)
+	}
+	plaintext := secret.Data[etcdSnapshotSecretKeyField]
+	if len(plaintext) < etcdSnapshotSecretKeyMinLength {
+		return nil, "", fmt.Errorf("the %s field of etcd snapshot encryption key secret %s/%s must hold at least %d bytes", etcdSnapshotSecretKeyField, controlPlane.Namespace, secretName, etcdSnapshotSecretKeyMinLength)
+	}
+	return plaintext, etcdSnapshotSecretKeyID(plaintext), nil
+}
+
+// etcdSnapshotSecretKeyID returns the ID of a customer managed key, a prefix of the SHA-256 digest of the key.
+func etcdSnapshotSecretKeyID(key []byte) string {
+	digest := sha256.Sum256(key)
+	return "secret-" + hex.EncodeToString(digest[:8])
+}
+
+func etcdSnapshotDataKeyCacheKey(controlPlane *rkev1.RKEControlPlane, id string) string {
+	return controlPlane.Namespace + "/" + controlPlane.Name + "/" + id
+}
+
+// etcdSnapshotDataKeyEnvVar returns the environment of the instructions that use the data key. The key is passed
+// through the environment, so that it is not written to the node, and it is only part of the plan of the node until the
+// snapshot operation completed and the regular plan is restored. The key is hex encoded, as it was when it was read
+// from a password file.
+func etcdSnapshotDataKeyEnvVar(plaintext []byte) string {
+	return etcdSnapshotDataKeyEnv + "=" + hex.EncodeToString(plaintext)
+}
+
+// etcdSnapshotEncryptPlan returns the files and the instruction that encrypt the snapshot created by the plan with the
+// customer managed key of the snapshot creation or, if there is none, with the current data key, if snapshot
+// encryption is enabled. The snapshot is looked up by the name it was saved with.
+func (p *Planner) etcdSnapshotEncryptPlan(controlPlane *rkev1.RKEControlPlane, snapshotName string) ([]plan.File, []plan.OneTimeInstruction, error) {
+	var (
+		keyID     string
+		plaintext []byte
+		err       error
+	)
+	switch {
+	case controlPlane.Spec.ETCDSnapshotCreate != nil && controlPlane.Spec.ETCDSnapshotCreate.EncryptionKeySecretName != "":
+		plaintext, keyID, err = p.etcdSnapshotSecretKey(controlPlane, controlPlane.Spec.ETCDSnapshotCreate.EncryptionKeySecretName)
+	case controlPlane.Spec.ETCD != nil && controlPlane.Spec.ETCD.SnapshotEncryption != nil && len(controlPlane.Status.ETCDSnapshotEncryptionKeys) > 0:
+		key := &controlPlane.Status.ETCDSnapshotEncryptionKeys[len(controlPlane.Status.ETCDSnapshotEncryptionKeys)-1]
+		keyID = key.ID
+		plaintext, err = p.etcdSnapshotDataKey(controlPlane, key)
+	default:
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	files := []plan.File{
+		{
+			Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotEncryptScript)),
+			Path:    etcdSnapshotScriptFile(controlPlane, etcdSnapshotEncryptScriptPath),
+		},
+	}
+	instruction := plan.OneTimeInstruction{
+		Name:    etcdSnapshotEncryptInstructionName,
+		Command: "sh",
+		Args: []string{
+			etcdSnapshotScriptFile(controlPlane, etcdSnapshotEncryptScriptPath),
+			etcdSnapshotDir(controlPlane),
+			snapshotName,
+			keyID,
+			etcdSnapshotScriptFile(controlPlane, etcdSnapshotKeyIDDir),
+		},
+		Env:        []string{etcdSnapshotDataKeyEnvVar(plaintext)},
+		SaveOutput: true,
+	}
+	return files, []plan.OneTimeInstruction{instruction}, nil
+}
+
+// etcdSnapshotDecryptPlan returns the files and the instruction that decrypt the snapshot file to the returned restore
+// path, with the customer managed key of the restore or, if there is none, with the data key that was current when the
+// snapshot was created. The key ID file records the data key of a local snapshot, and is empty for snapshots that were
+// downloaded from S3. No instruction is returned if neither key is available.
+func (p *Planner) etcdSnapshotDecryptPlan(controlPlane *rkev1.RKEControlPlane, file, keyIDFile string, createdAt *metav1.Time) ([]plan.File, []plan.OneTimeInstruction, string, error) {
+	var (
+		keyID     string
+		plaintext []byte
+		err       error
+	)
+	if restore := controlPlane.Spec.ETCDSnapshotRestore; restore != nil && restore.EncryptionKeySecretName != "" {
+		plaintext, keyID, err = p.etcdSnapshotSecretKey(controlPlane, restore.EncryptionKeySecretName)
+	} else if key := etcdSnapshotEncryptionKeyAt(controlPlane.Status.ETCDSnapshotEncryptionKeys, createdAt); key != nil {
+		keyID = key.ID
+		plaintext, err = p.etcdSnapshotDataKey(controlPlane, key)
+	} else {
+		return nil, nil, "", nil
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	restorePath := etcdSnapshotScriptFile(controlPlane, etcdSnapshotDecryptedPath)
+	files := []plan.File{
+		{
+			Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotDecryptScript)),
+			Path:    etcdSnapshotScriptFile(controlPlane, etcdSnapshotDecryptScriptPath),
+		},
+	}
+	instruction := plan.OneTimeInstruction{
+		Name:    etcdSnapshotDecryptInstructionName,
+		Command: "sh",
+		Args: []string{
+			etcdSnapshotScriptFile(controlPlane, etcdSnapshotDecryptScriptPath),
+			file,
+			keyID,
+			keyIDFile,
+			restorePath,
+		},
+		Env:        []string{etcdSnapshotDataKeyEnvVar(plaintext)},
+		SaveOutput: true,
+	}
+	return files, []plan.OneTimeInstruction{instruction}, restorePath, nil
+}
+
+// etcdSnapshotLocalDecryptPlan returns the decryption plan of the named snapshot in the snapshot directory of the nodes.
+func (p *Planner) etcdSnapshotLocalDecryptPlan(controlPlane *rkev1.RKEControlPlane, snapshotName string, createdAt *metav1.Time) ([]plan.File, []plan.OneTimeInstruction, string, error) {
+	return p.etcdSnapshotDecryptPlan(controlPlane,
+		path.Join(etcdSnapshotDir(controlPlane), snapshotName),
+		path.Join(etcdSnapshotScriptFile(controlPlane, etcdSnapshotKeyIDDir), snapshotName),
+		createdAt)
+}
+
+// etcdSnapshotS3DecryptPlan returns the plan that downloads the S3 snapshot and decrypts it, if it may be encrypted.
+// Snapshots are only encrypted before they are uploaded if the cloud credential has access keys, so no plan is returned
+// for credentials that use the IAM role of the nodes, and the distribution restores the snapshot from S3 itself.
+func (p *Planner) etcdSnapshotS3DecryptPlan(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot) ([]plan.File, []plan.OneTimeInstruction, string, error) {
+	if snapshot.SnapshotFile.S3 == nil {
+		return nil, nil, "", nil
+	}
+	config, err := GetS3Config(p.secretCache, snapshot.SnapshotFile.S3, controlPlane)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, nil, "", nil
+	}
+	downloadPath := etcdSnapshotScriptFile(controlPlane, etcdSnapshotDownloadedPath)
+	files, instructions, restorePath, err := p.etcdSnapshotDecryptPlan(controlPlane, downloadPath, "", snapshot.SnapshotFile.CreatedAt)
+	if err != nil || restorePath == "" {
+		return nil, nil, "", err
+	}
+	download, downloadFiles, err := etcdSnapshotS3DownloadInstruction(controlPlane, config, snapshot.SnapshotFile.Name, downloadPath)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return append(downloadFiles, files...), append([]plan.OneTimeInstruction{download}, instructions...), restorePath, nil
+}
@@ -0,0 +1,204 @@
+package planner
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestEtcdSnapshotEncryptionKeyExpired(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	encryption := rkev1.ETCDSnapshotEncryption{
+		Provider: "vault",
+		KeyName:  "etcd",
+		Endpoint: "https://vault.example.com",
+	}
+	keyCreatedAt := func(createdAt time.Time) []rkev1.ETCDSnapshotEncryptionKey {
+		return []rkev1.ETCDSnapshotEncryptionKey{{
+			ID:         "a",
+			Encryption: encryption,
+			CreatedAt:  metav1.NewTime(createdAt),
+		}}
+	}
+
+	tests := []struct {
+		name       string
+		encryption func(e *rkev1.ETCDSnapshotEncryption)
+		keys       []rkev1.ETCDSnapshotEncryptionKey
+		expected   bool
+	}{
+		{
+			name:     "no key",
+			expected: true,
+		},
+		{
+			name: "current key",
+			keys: keyCreatedAt(now.Add(-time.Hour)),
+		},
+		{
+			name:     "default rotation interval passed",
+			keys:     keyCreatedAt(now.Add(-defaultEtcdSnapshotEncryptionRotationInterval)),
+			expected: true,
+		},
+		{
+			name: "custom rotation interval passed",
+			encryption: func(e *rkev1.ETCDSnapshotEncryption) {
+				e.RotationInterval = &metav1.Duration{Duration: 30 * time.Minute}
+			},
+			keys:     keyCreatedAt(now.Add(-time.Hour)),
+			expected: true,
+		},
+		{
+			name: "key name changed",
+			encryption: func(e *rkev1.ETCDSnapshotEncryption) {
+				e.KeyName = "etcd-2"
+			},
+			keys:     keyCreatedAt(now.Add(-time.Hour)),
+			expected: true,
+		},
+		{
+			name: "provider changed",
+			encryption: func(e *rkev1.ETCDSnapshotEncryption) {
+				e.Provider = "awskms"
+			},
+			keys:     keyCreatedAt(now.Add(-time.Hour)),
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := encryption
+			if tt.encryption != nil {
+				tt.encryption(&e)
+			}
+			assert.Equal(t, tt.expected, etcdSnapshotEncryptionKeyExpired(&e, tt.keys, now))
+		})
+	}
+}
+
+func TestEtcdSnapshotEncryptionKeyAt(t *testing.T) {
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(720 * time.Hour)
+	keys := []rkev1.ETCDSnapshotEncryptionKey{
+		{ID: "first", CreatedAt: metav1.NewTime(first)},
+		{ID: "second", CreatedAt: metav1.NewTime(second)},
+	}
+	at := func(t time.Time) *metav1.Time {
+		mt := metav1.NewTime(t)
+		return &mt
+	}
+
+	assert.Nil(t, etcdSnapshotEncryptionKeyAt(nil, at(first)))
+	assert.Nil(t, etcdSnapshotEncryptionKeyAt(keys, at(first.Add(-time.Second))))
+
+	key := etcdSnapshotEncryptionKeyAt(keys, at(first))
+	require.NotNil(t, key)
+	assert.Equal(t, "first", key.ID)
+
+	key = etcdSnapshotEncryptionKeyAt(keys, at(second.Add(-time.Second)))
+	require.NotNil(t, key)
+	assert.Equal(t, "first", key.ID)
+
+	key = etcdSnapshotEncryptionKeyAt(keys, at(second.Add(time.Hour)))
+	require.NotNil(t, key)
+	assert.Equal(t, "second", key.ID)
+
+	key = etcdSnapshotEncryptionKeyAt(keys, nil)
+	require.NotNil(t, key)
+	assert.Equal(t, "second", key.ID)
+}
+
+type fakeSecretCache struct {
+	corecontrollers.SecretCache
+	secrets []*corev1.Secret
+}
+
+func (f *fakeSecretCache) Get(namespace, name string) (*corev1.Secret, error) {
+	for _, secret := range f.secrets {
+		if secret.Namespace == namespace && secret.Name == name {
+			return secret, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+func TestEtcdSnapshotSecretKeyPlans(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	p := &Planner{secretCache: &fakeSecretCache{secrets: []*corev1.Secret{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "etcd-key", Labels: map[string]string{capr.ClusterNameLabel: "c1"}}, Data: map[string][]byte{"key": key}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "short-key", Labels: map[string]string{capr.ClusterNameLabel: "c1"}}, Data: map[string][]byte{"key": []byte("short")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "other-key", Labels: map[string]string{capr.ClusterNameLabel: "c2"}}, Data: map[string][]byte{"key": key}},
+	}}}
+	keyID := etcdSnapshotSecretKeyID(key)
+	keyEnv := []string{"ETCD_SNAPSHOT_DATA_KEY=" + hex.EncodeToString(key)}
+
+	controlPlane := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "c1"},
+		Spec: rkev1.RKEControlPlaneSpec{
+			ClusterName:         "c1",
+			KubernetesVersion:   "v1.27.5+rke2r1",
+			ETCDSnapshotCreate:  &rkev1.ETCDSnapshotCreate{Generation: 1, EncryptionKeySecretName: "etcd-key"},
+			ETCDSnapshotRestore: &rkev1.ETCDSnapshotRestore{Name: "snapshot", EncryptionKeySecretName: "etcd-key"},
+		},
+	}
+
+	files, instructions, err := p.etcdSnapshotEncryptPlan(controlPlane, "on-demand-1")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Len(t, instructions, 1)
+	assert.Equal(t, []string{
+		"/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/bin/encrypt.sh",
+		"/var/lib/rancher/rke2/server/db/snapshots",
+		"on-demand-1",
+		keyID,
+		"/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/keys",
+	}, instructions[0].Args)
+	assert.Equal(t, keyEnv, instructions[0].Env)
+	for _, file := range files {
+		assert.NotContains(t, file.Content, hex.EncodeToString(key))
+	}
+
+	files, instructions, restorePath, err := p.etcdSnapshotLocalDecryptPlan(controlPlane, "on-demand-c1-1696161600", nil)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Len(t, instructions, 1)
+	assert.Equal(t, []string{
+		"/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/bin/decrypt.sh",
+		"/var/lib/rancher/rke2/server/db/snapshots/on-demand-c1-1696161600",
+		keyID,
+		"/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/keys/on-demand-c1-1696161600",
+		"/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/restore.db",
+	}, instructions[0].Args)
+	assert.Equal(t, keyEnv, instructions[0].Env)
+	assert.Equal(t, "/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/restore.db", restorePath)
+
+	controlPlane.Spec.ETCDSnapshotCreate.EncryptionKeySecretName = "short-key"
+	_, _, err = p.etcdSnapshotEncryptPlan(controlPlane, "on-demand-1")
+	assert.EqualError(t, err, "the key field of etcd snapshot encryption key secret fleet-default/short-key must hold at least 32 bytes")
+
+	controlPlane.Spec.ETCDSnapshotCreate.EncryptionKeySecretName = "other-key"
+	_, _, err = p.etcdSnapshotEncryptPlan(controlPlane, "on-demand-1")
+	assert.EqualError(t, err, "etcd snapshot encryption key secret fleet-default/other-key does not belong to cluster c1, it must be labeled rke.cattle.io/cluster-name=c1")
+
+	controlPlane.Spec.ETCDSnapshotRestore.EncryptionKeySecretName = "missing-key"
+	_, _, _, err = p.etcdSnapshotLocalDecryptPlan(controlPlane, "on-demand-c1-1696161600", nil)
+	assert.EqualError(t, err, `failed to lookup etcd snapshot encryption key secret fleet-default/missing-key: secrets "missing-key" not found`)
+
+	controlPlane.Spec.ETCDSnapshotCreate.EncryptionKeySecretName = ""
+	controlPlane.Spec.ETCDSnapshotRestore.EncryptionKeySecretName = ""
+	files, instructions, err = p.etcdSnapshotEncryptPlan(controlPlane, "on-demand-1")
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+	assert.Empty(t, instructions)
+}
@@ -0,0 +1,206 @@
+package planner
+
+import (
+	"fmt"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type etcdSnapshotRecoveryAction string
+
+const (
+	// etcdSnapshotRecoveryResume resumes the operation from the phase it was interrupted in.
+	etcdSnapshotRecoveryResume etcdSnapshotRecoveryAction = "Resume"
+	// etcdSnapshotRecoveryReset clears the state of the operation, so that the requested operation, if any, is started
+	// over.
+	etcdSnapshotRecoveryReset etcdSnapshotRecoveryAction = "Reset"
+	// etcdSnapshotRecoveryFail marks the operation as failed.
+	etcdSnapshotRecoveryFail etcdSnapshotRecoveryAction = "Fail"
+
+	etcdSnapshotRecoveryEventSource = "rancher-planner"
+
+	etcdSnapshotCreateOperation  = "etcd snapshot creation"
+	etcdSnapshotUploadOperation  = "etcd snapshot upload"
+	etcdSnapshotRestoreOperation = "etcd snapshot restore"
+)
+
+// interruptedEtcdSnapshotOperation is the state of an etcd snapshot operation of a control plane that was in progress
+// when the planner first processed the control plane after Rancher started.
+type interruptedEtcdSnapshotOperation struct {
+	// description of the operation, such as "etcd snapshot creation"
+	description string
+	phase       rkev1.ETCDSnapshotPhase
+	// recorded is whether the status records the operation that the phase belongs to
+	recorded bool
+	// requested is whether the spec still requests an operation
+	requested bool
+	// superseded is whether the requested operation differs from the recorded one
+	superseded bool
+	// requiresEtcd is whether the operation runs on etcd machines that are not deleting, and can only be resumed if any
+	// are left
+	requiresEtcd bool
+}
+
+// etcdSnapshotPhaseInProgress returns whether the phase is an intermediate phase of an etcd snapshot operation, which
+// is not waiting for a new operation to be requested.
+func etcdSnapshotPhaseInProgress(phase rkev1.ETCDSnapshotPhase) bool {
+	switch phase {
+	case "", rkev1.ETCDSnapshotPhaseFinished, rkev1.ETCDSnapshotPhaseFailed, rkev1.ETCDSnapshotPhaseCancelled, rkev1.ETCDSnapshotPhasePreviewed:
+		return false
+	}
+	return true
+}
+
+// interruptedEtcdSnapshotOperations returns the etcd snapshot operations of the control plane whose phase is in
+// progress.
+func interruptedEtcdSnapshotOperations(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) []interruptedEtcdSnapshotOperation {
+	var result []interruptedEtcdSnapshotOperation
+	if etcdSnapshotPhaseInProgress(status.ETCDSnapshotCreatePhase) {
+		op := interruptedEtcdSnapshotOperation{
+			description:  etcdSnapshotCreateOperation,
+			phase:        status.ETCDSnapshotCreatePhase,
+			recorded:     status.ETCDSnapshotCreate != nil,
+			requested:    cp.Spec.ETCDSnapshotCreate != nil,
+			superseded:   !equality.Semantic.DeepEqual(withoutCreateCancel(cp.Spec.ETCDSnapshotCreate), withoutCreateCancel(status.ETCDSnapshotCreate)),
+			requiresEtcd: true,
+		}
+		if scheduledEtcdSnapshotCreate(status.ETCDSnapshotCreate) {
+			// scheduled snapshot creations are not in the spec, they are only superseded by a new generation
+			op.requested = true
+			op.superseded = etcdSnapshotCreateRequested(cp.Spec.ETCDSnapshotCreate, status.ETCDSnapshotCreate)
+		}
+		result = append(result, op)
+	}
+	if etcdSnapshotPhaseInProgress(status.ETCDSnapshotUploadPhase) {
+		result = append(result, interruptedEtcdSnapshotOperation{
+			description:  etcdSnapshotUploadOperation,
+			phase:        status.ETCDSnapshotUploadPhase,
+			recorded:     status.ETCDSnapshotUpload != nil,
+			requested:    cp.Spec.ETCDSnapshotUpload != nil,
+			superseded:   !equality.Semantic.DeepEqual(cp.Spec.ETCDSnapshotUpload, status.ETCDSnapshotUpload),
+			requiresEtcd: true,
+		})
+	}
+	if etcdSnapshotPhaseInProgress(status.ETCDSnapshotRestorePhase) {
+		result = append(result, interruptedEtcdSnapshotOperation{
+			description: etcdSnapshotRestoreOperation,
+			phase:       status.ETCDSnapshotRestorePhase,
+			recorded:    status.ETCDSnapshotRestore != nil,
+			requested:   cp.Spec.ETCDSnapshotRestore != nil && cp.Spec.ETCDSnapshotRestore.Name != "",
+			superseded:  !equality.Semantic.DeepEqual(withoutRestoreCancel(cp.Spec.ETCDSnapshotRestore), withoutRestoreCancel(status.ETCDSnapshotRestore)),
+		})
+	}
+	return result
+}
+
+// etcdSnapshotRecovery decides how to recover the interrupted operation, given the number of etcd machines that are
+// not deleting, and returns the message explaining the decision.
+func etcdSnapshotRecovery(op interruptedEtcdSnapshotOperation, etcdMachines int) (etcdSnapshotRecoveryAction, string) {
+	switch {
+	case !op.recorded:
+		return etcdSnapshotRecoveryReset, fmt.Sprintf("%s was interrupted in phase %s, which was recorded without the operation, resetting its state", op.description, op.phase)
+	case !op.requested:
+		return etcdSnapshotRecoveryReset, fmt.Sprintf("%s was interrupted in phase %s and is no longer requested, resetting its state", op.description, op.phase)
+	case op.superseded:
+		return etcdSnapshotRecoveryReset, fmt.Sprintf("%s was interrupted in phase %s and a different one was requested since, starting the requested one", op.description, op.phase)
+	case op.requiresEtcd && etcdMachines == 0:
+		return etcdSnapshotRecoveryFail, fmt.Sprintf("%s was interrupted in phase %s and no etcd machines are left to resume it on", op.description, op.phase)
+	}
+	return etcdSnapshotRecoveryResume, fmt.Sprintf("resuming %s that was interrupted in phase %s", op.description, op.phase)
+}
+
+// recoverInterruptedEtcdSnapshotOperations checks the etcd snapshot operations of the control plane that are in
+// progress the first time the control plane is processed after Rancher started, and validates them against the spec
+// and the etcd machines of the cluster. Operations that are consistent are resumed, others are reset or failed. Each
+// decision is recorded as an event of the control plane.
+func (p *Planner) recoverInterruptedEtcdSnapshotOperations(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterPlan *plan.Plan) (rkev1.RKEControlPlaneStatus, error) {
+	if _, checked := p.recoveredControlPlanes.Load(cp.UID); checked {
+		return status, nil
+	}
+
+	etcdMachines := len(collect(clusterPlan, roleAnd(isEtcd, isNotDeleting)))
+	changed := false
+	for _, op := range interruptedEtcdSnapshotOperations(cp, status) {
+		action, message := etcdSnapshotRecovery(op, etcdMachines)
+		eventType := corev1.EventTypeNormal
+		switch action {
+		case etcdSnapshotRecoveryReset:
+			status = resetInterruptedEtcdSnapshotOperation(status, op.description)
+			eventType = corev1.EventTypeWarning
+			changed = true
+		case etcdSnapshotRecoveryFail:
+			status = failInterruptedEtcdSnapshotOperation(status, op.description)
+			eventType = corev1.EventTypeWarning
+			changed = true
+		}
+		logrus.Infof("[planner] rkecluster %s/%s: %s", cp.Namespace, cp.Name, message)
+		p.recordEvent(cp, eventType, "ETCDSnapshotOperation"+string(action), message)
+	}
+
+	p.recoveredControlPlanes.Store(cp.UID, true)
+	if changed {
+		return status, errWaiting("recovering interrupted etcd snapshot operations")
+	}
+	return status, nil
+}
+
+func resetInterruptedEtcdSnapshotOperation(status rkev1.RKEControlPlaneStatus, description string) rkev1.RKEControlPlaneStatus {
+	switch description {
+	case etcdSnapshotCreateOperation:
+		status.ETCDSnapshotCreate, status.ETCDSnapshotCreatePhase = nil, ""
+	case etcdSnapshotUploadOperation:
+		status.ETCDSnapshotUpload, status.ETCDSnapshotUploadPhase = nil, ""
+	case etcdSnapshotRestoreOperation:
+		status.ETCDSnapshotRestore, status.ETCDSnapshotRestorePhase = nil, ""
+	}
+	return status
+}
+
+func failInterruptedEtcdSnapshotOperation(status rkev1.RKEControlPlaneStatus, description string) rkev1.RKEControlPlaneStatus {
+	switch description {
+	case etcdSnapshotCreateOperation:
+		status.ETCDSnapshotCreatePhase = rkev1.ETCDSnapshotPhaseFailed
+	case etcdSnapshotUploadOperation:
+		status.ETCDSnapshotUploadPhase = rkev1.ETCDSnapshotPhaseFailed
+	}
+	return status
+}
+
+// recordEvent creates an event for the control plane. Failing to create the event is logged, but does not stop the
+// planner.
+func (p *Planner) recordEvent(cp *rkev1.RKEControlPlane, eventType, reason, message string) {
+	if p.events == nil {
+		return
+	}
+	now := metav1.Now()
+	_, err := p.events.Create(&corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", cp.Name, time.Now().UnixNano()),
+			Namespace: cp.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion:      rkev1.SchemeGroupVersion.String(),
+			Kind:            "RKEControlPlane",
+			Name:            cp.Name,
+			Namespace:       cp.Namespace,
+			UID:             cp.UID,
+			ResourceVersion: cp.ResourceVersion,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: etcdSnapshotRecoveryEventSource},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	})
+	if err != nil {
+		logrus.Errorf("[planner] rkecluster %s/%s: error creating event %s: %v", cp.Namespace, cp.Name, reason, err)
+	}
+}
@@ -0,0 +1,119 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEtcdSnapshotRecovery(t *testing.T) {
+	tests := []struct {
+		name         string
+		op           interruptedEtcdSnapshotOperation
+		etcdMachines int
+		expected     etcdSnapshotRecoveryAction
+	}{
+		{
+			name:         "consistent",
+			op:           interruptedEtcdSnapshotOperation{phase: rkev1.ETCDSnapshotPhaseStarted, recorded: true, requested: true, requiresEtcd: true},
+			etcdMachines: 1,
+			expected:     etcdSnapshotRecoveryResume,
+		},
+		{
+			name:     "phase without operation",
+			op:       interruptedEtcdSnapshotOperation{phase: rkev1.ETCDSnapshotPhaseRestartCluster, requested: true},
+			expected: etcdSnapshotRecoveryReset,
+		},
+		{
+			name:     "no longer requested",
+			op:       interruptedEtcdSnapshotOperation{phase: rkev1.ETCDSnapshotPhaseCompact, recorded: true},
+			expected: etcdSnapshotRecoveryReset,
+		},
+		{
+			name:     "superseded",
+			op:       interruptedEtcdSnapshotOperation{phase: rkev1.ETCDSnapshotPhaseShutdown, recorded: true, requested: true, superseded: true},
+			expected: etcdSnapshotRecoveryReset,
+		},
+		{
+			name:     "no etcd machines",
+			op:       interruptedEtcdSnapshotOperation{phase: rkev1.ETCDSnapshotPhaseStarted, recorded: true, requested: true, requiresEtcd: true},
+			expected: etcdSnapshotRecoveryFail,
+		},
+		{
+			name:     "restore without etcd machines",
+			op:       interruptedEtcdSnapshotOperation{phase: rkev1.ETCDSnapshotPhaseRestore, recorded: true, requested: true},
+			expected: etcdSnapshotRecoveryResume,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, message := etcdSnapshotRecovery(tt.op, tt.etcdMachines)
+			assert.Equal(t, tt.expected, action)
+			assert.NotEmpty(t, message)
+		})
+	}
+}
+
+func TestInterruptedEtcdSnapshotOperations(t *testing.T) {
+	cp := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			ETCDSnapshotCreate:  &rkev1.ETCDSnapshotCreate{Generation: 2, Cancel: true},
+			ETCDSnapshotRestore: &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 2},
+		},
+	}
+	status := rkev1.RKEControlPlaneStatus{
+		ETCDSnapshotCreate:       &rkev1.ETCDSnapshotCreate{Generation: 2},
+		ETCDSnapshotCreatePhase:  rkev1.ETCDSnapshotPhaseStarted,
+		ETCDSnapshotUpload:       &rkev1.ETCDSnapshotUpload{Generation: 1},
+		ETCDSnapshotUploadPhase:  rkev1.ETCDSnapshotPhaseFinished,
+		ETCDSnapshotRestore:      &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 1},
+		ETCDSnapshotRestorePhase: rkev1.ETCDSnapshotPhaseShutdown,
+	}
+
+	assert.Equal(t, []interruptedEtcdSnapshotOperation{
+		{
+			description:  etcdSnapshotCreateOperation,
+			phase:        rkev1.ETCDSnapshotPhaseStarted,
+			recorded:     true,
+			requested:    true,
+			requiresEtcd: true,
+		},
+		{
+			description: etcdSnapshotRestoreOperation,
+			phase:       rkev1.ETCDSnapshotPhaseShutdown,
+			recorded:    true,
+			requested:   true,
+			superseded:  true,
+		},
+	}, interruptedEtcdSnapshotOperations(cp, status))
+}
+
+func TestRecoverInterruptedEtcdSnapshotOperations(t *testing.T) {
+	cp := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "fleet-default", UID: "uid"},
+		Spec: rkev1.RKEControlPlaneSpec{
+			ETCDSnapshotUpload: &rkev1.ETCDSnapshotUpload{Generation: 1},
+		},
+	}
+	status := rkev1.RKEControlPlaneStatus{
+		ETCDSnapshotCreate:      &rkev1.ETCDSnapshotCreate{Generation: 1},
+		ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseRestartCluster,
+		ETCDSnapshotUpload:      &rkev1.ETCDSnapshotUpload{Generation: 1},
+		ETCDSnapshotUploadPhase: rkev1.ETCDSnapshotPhaseStarted,
+	}
+	p := &Planner{}
+
+	recovered, err := p.recoverInterruptedEtcdSnapshotOperations(cp, status, &plan.Plan{})
+	assert.True(t, IsErrWaiting(err))
+	assert.Nil(t, recovered.ETCDSnapshotCreate)
+	assert.Empty(t, recovered.ETCDSnapshotCreatePhase)
+	assert.Equal(t, rkev1.ETCDSnapshotPhaseFailed, recovered.ETCDSnapshotUploadPhase)
+
+	// the operations are only checked the first time the control plane is processed
+	recovered, err = p.recoverInterruptedEtcdSnapshotOperations(cp, status, &plan.Plan{})
+	assert.NoError(t, err)
+	assert.Equal(t, status, recovered)
+}
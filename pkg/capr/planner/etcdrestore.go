@@ -2,6 +2,8 @@
 
 import (
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/Masterminds/semver/v3"
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
@@ -11,13 +13,14 @@
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const ETCDRestoreMessage = "etcd restore"
 
 // setEtcdSnapshotRestoreState sets the restore schema and phase to the given restore and phase and returns an errWaiting if a change was made. Notably this function does not persist the change.
 func (p *Planner) setEtcdSnapshotRestoreState(status rkev1.RKEControlPlaneStatus, restore *rkev1.ETCDSnapshotRestore, phase rkev1.ETCDSnapshotPhase) (rkev1.RKEControlPlaneStatus, error) {
-	if !equality.Semantic.DeepEqual(status.ETCDSnapshotRestore, restore) || status.ETCDSnapshotRestorePhase != phase {
+	if etcdSnapshotRestoreStateHash(status.ETCDSnapshotRestore, status.ETCDSnapshotRestorePhase) != etcdSnapshotRestoreStateHash(restore, phase) {
 		status.ETCDSnapshotRestore = restore
 		status.ETCDSnapshotRestorePhase = phase
 		return status, errWaiting("refreshing etcd restore state")
@@ -35,12 +38,66 @@ func (p *Planner) resetEtcdSnapshotRestoreState(status rkev1.RKEControlPlaneStat
 
 // startOrRestartEtcdSnapshotRestore sets the started phase
 func (p *Planner) startOrRestartEtcdSnapshotRestore(status rkev1.RKEControlPlaneStatus, restore *rkev1.ETCDSnapshotRestore) (rkev1.RKEControlPlaneStatus, error) {
-	if status.ETCDSnapshotRestore == nil || !equality.Semantic.DeepEqual(restore, status.ETCDSnapshotRestore) {
+	if status.ETCDSnapshotRestore == nil || !equality.Semantic.DeepEqual(withoutRestoreCancel(restore), withoutRestoreCancel(status.ETCDSnapshotRestore)) {
+		if restore.Cancel {
+			// a restore that is cancelled before it started is never started
+			return p.setEtcdSnapshotRestoreState(status, restore, rkev1.ETCDSnapshotPhaseCancelled)
+		}
 		return p.setEtcdSnapshotRestoreState(status, restore, rkev1.ETCDSnapshotPhaseStarted)
 	}
 	return status, nil
 }
 
+// withoutRestoreCancel returns a copy of the restore with cancel unset, so that cancelling a restore is not mistaken for
+// a request to start a new one.
+func withoutRestoreCancel(restore *rkev1.ETCDSnapshotRestore) *rkev1.ETCDSnapshotRestore {
+	if restore == nil {
+		return nil
+	}
+	restore = restore.DeepCopy()
+	restore.Cancel = false
+	return restore
+}
+
+// etcdSnapshotIncompatibilities returns the differences between the cluster the etcd snapshot was taken of, as recorded
+// in its metadata, and the cluster of the control plane that may keep the cluster from coming up after restoring the
+// snapshot. Snapshots without metadata can not be validated.
+func etcdSnapshotIncompatibilities(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot) []string {
+	if snapshot == nil {
+		return nil
+	}
+	recorded, err := capr.ParseSnapshotMetadata(snapshot)
+	if err != nil {
+		return nil
+	}
+	current := capr.SnapshotMetadata(controlPlane)
+
+	var result []string
+	snapshotVersion, snapshotErr := semver.NewVersion(recorded[capr.SnapshotMetadataKubernetesVersion])
+	currentVersion, currentErr := semver.NewVersion(current[capr.SnapshotMetadataKubernetesVersion])
+	if snapshotErr == nil && currentErr == nil && snapshotVersion.Major() == currentVersion.Major() {
+		switch {
+		case snapshotVersion.Minor() > currentVersion.Minor():
+			result = append(result, fmt.Sprintf("snapshot was taken with Kubernetes %s which is newer than %s of the cluster", snapshotVersion.Original(), currentVersion.Original()))
+		case currentVersion.Minor()-snapshotVersion.Minor() > 1:
+			result = append(result, fmt.Sprintf("snapshot was taken with Kubernetes %s which is more than one minor version older than %s of the cluster", snapshotVersion.Original(), currentVersion.Original()))
+		}
+	}
+
+	snapshotETCD, snapshotErr := semver.NewVersion(recorded[capr.SnapshotMetadataETCDVersion])
+	currentETCD, currentErr := semver.NewVersion(current[capr.SnapshotMetadataETCDVersion])
+	if snapshotErr == nil && currentErr == nil && snapshotETCD.GreaterThan(currentETCD) {
+		result = append(result, fmt.Sprintf("snapshot was taken with etcd %s which is newer than etcd %s of the cluster", snapshotETCD.Original(), currentETCD.Original()))
+	}
+
+	for _, key := range []string{capr.SnapshotMetadataCNI, capr.SnapshotMetadataClusterCIDR, capr.SnapshotMetadataServiceCIDR} {
+		if recorded[key] != "" && recorded[key] != current[key] {
+			result = append(result, fmt.Sprintf("snapshot was taken with %s %s but the cluster has %s", key, recorded[key], current[key]))
+		}
+	}
+	return result
+}
+
 // runEtcdSnapshotRestorePlan runs the snapshot restoration plan by electing an init node (or designating the init node
 // that is specified on the snapshot), and renders/delivers the etcd restoration plan to that node.
 func (p *Planner) runEtcdSnapshotRestorePlan(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot, snapshotName string, tokensSecret plan.Secret, clusterPlan *plan.Plan) error {
@@ -86,18 +143,53 @@ func (p *Planner) generateEtcdSnapshotRestorePlan(controlPlane *rkev1.RKEControl
 		"--etcd-arg=advertise-client-urls=https://127.0.0.1:2379", // this is a workaround for: https://github.com/rancher/rke2/issues/4052 and can likely remain indefinitely (unless IPv6-only becomes a requirement)
 	}
 
-	if snapshot == nil {
+	var (
+		env                 []string
+		decryptInstructions []plan.OneTimeInstruction
+	)
+	if snapshot == nil || snapshot.SnapshotFile.S3 == nil {
 		// If the snapshot is nil, then we will assume the passed in snapshot name is a local snapshot.
-		args = append(args, fmt.Sprintf("--cluster-reset-restore-path=db/snapshots/%s", snapshotName), "--etcd-s3=false")
-	} else if snapshot.SnapshotFile.S3 == nil {
-		args = append(args, fmt.Sprintf("--cluster-reset-restore-path=db/snapshots/%s", snapshot.SnapshotFile.Name), "--etcd-s3=false")
+		var createdAt *metav1.Time
+		if snapshot != nil {
+			if verification := snapshot.Status.Verification; verification != nil && verification.Result == rkev1.ETCDSnapshotChecksumMismatch {
+				return plan.NodePlan{}, "", fmt.Errorf("refusing to restore etcd snapshot %s/%s as it failed verification: %s", snapshot.Namespace, snapshot.Name, verification.Message)
+			}
+			snapshotName = snapshot.SnapshotFile.Name
+			createdAt = snapshot.SnapshotFile.CreatedAt
+		}
+		restorePath := path.Join(etcdSnapshotDir(controlPlane), snapshotName)
+		files, instructions, decryptedPath, err := p.etcdSnapshotLocalDecryptPlan(controlPlane, snapshotName, createdAt)
+		if err != nil {
+			return plan.NodePlan{}, "", err
+		}
+		if decryptedPath != "" {
+			restorePath = decryptedPath
+			nodePlan.Files = append(nodePlan.Files, files...)
+			decryptInstructions = instructions
+		}
+		args = append(args, fmt.Sprintf("--cluster-reset-restore-path=%s", restorePath), "--etcd-s3=false")
 	} else {
-		args = append(args, fmt.Sprintf("--cluster-reset-restore-path=%s", snapshot.SnapshotFile.Name))
-		s3Args, _, _, err := p.etcdS3Args.ToArgs(snapshot.SnapshotFile.S3, controlPlane, "etcd-", true)
+		if checksum := snapshot.Status.Checksum; checksum != nil && checksum.Result == rkev1.ETCDSnapshotChecksumMismatch {
+			return plan.NodePlan{}, "", fmt.Errorf("refusing to restore etcd snapshot %s/%s as its S3 object did not match the uploaded snapshot file: %s", snapshot.Namespace, snapshot.Name, checksum.Message)
+		}
+		// snapshots that were encrypted before they were uploaded are downloaded and decrypted on the node first
+		files, instructions, decryptedPath, err := p.etcdSnapshotS3DecryptPlan(controlPlane, snapshot)
 		if err != nil {
 			return plan.NodePlan{}, "", err
 		}
-		args = append(args, s3Args...)
+		if decryptedPath != "" {
+			nodePlan.Files = append(nodePlan.Files, files...)
+			decryptInstructions = instructions
+			args = append(args, fmt.Sprintf("--cluster-reset-restore-path=%s", decryptedPath), "--etcd-s3=false")
+		} else {
+			args = append(args, fmt.Sprintf("--cluster-reset-restore-path=%s", snapshot.SnapshotFile.Name))
+			s3Args, s3Env, _, err := p.etcdS3Args.ToArgs(snapshot.SnapshotFile.S3, controlPlane, entry, "etcd-", true)
+			if err != nil {
+				return plan.NodePlan{}, "", err
+			}
+			args = append(args, s3Args...)
+			env = s3Env
+		}
 	}
 
 	// This is likely redundant but can make sense in the event that there is an external watchdog.
@@ -109,7 +201,7 @@ func (p *Planner) generateEtcdSnapshotRestorePlan(controlPlane *rkev1.RKEControl
 	// make sure to install the desired version before performing restore
 	stopPlan.Instructions = append(stopPlan.Instructions, p.generateInstallInstructionWithSkipStart(controlPlane, entry))
 
-	planInstructions := append(stopPlan.Instructions,
+	planInstructions := append(append(stopPlan.Instructions, decryptInstructions...),
 		plan.OneTimeInstruction{
 			Name:    "remove-etcd-db-dir",
 			Command: "rm",
@@ -121,8 +213,19 @@ func (p *Planner) generateEtcdSnapshotRestorePlan(controlPlane *rkev1.RKEControl
 	nodePlan.Instructions = append(planInstructions, plan.OneTimeInstruction{
 		Name:    "restore",
 		Args:    args,
+		Env:     env,
 		Command: capr.GetRuntimeCommand(controlPlane.Spec.KubernetesVersion),
 	})
+	if len(decryptInstructions) > 0 {
+		nodePlan.Instructions = append(nodePlan.Instructions, plan.OneTimeInstruction{
+			Name:    "remove-decrypted-etcd-snapshot",
+			Command: "rm",
+			Args: []string{
+				"-f",
+				etcdSnapshotScriptFile(controlPlane, etcdSnapshotDecryptedPath),
+				etcdSnapshotScriptFile(controlPlane, etcdSnapshotDownloadedPath),
+			}})
+	}
 
 	return nodePlan, joinedServer, nil
 }
@@ -240,16 +343,10 @@ func (p *Planner) runEtcdRestoreServiceStop(controlPlane *rkev1.RKEControlPlane,
 		if server.Plan == nil {
 			continue
 		}
-		stopPlan, joinedServer, err := p.generateStopServiceAndKillAllPlan(controlPlane, tokensSecret, server, joinServer)
+		stopPlan, joinedServer, err := p.generateEtcdRestoreServiceStopPlan(controlPlane, tokensSecret, server, joinServer)
 		if err != nil {
 			return err
 		}
-		if isEtcd(server) {
-			stopPlan.Instructions = append(stopPlan.Instructions, generateCreateEtcdTombstoneInstruction(controlPlane))
-		}
-		if roleOr(isEtcd, isControlPlane)(server) {
-			stopPlan.Instructions = append(stopPlan.Instructions, generateRemoveTLSAndCredDirInstructions(controlPlane)...)
-		}
 		if !equality.Semantic.DeepEqual(server.Plan.Plan, stopPlan) {
 			if err := p.store.UpdatePlan(server, stopPlan, joinedServer, 0, 0); err != nil {
 				return err
@@ -281,13 +378,36 @@ func (p *Planner) runEtcdRestoreServiceStop(controlPlane *rkev1.RKEControlPlane,
 	return nil
 }
 
+// generateEtcdRestoreServiceStopPlan returns the plan that stops the services of the server before an etcd snapshot is
+// restored. The etcd database of etcd servers is tombstoned, and the TLS and credential directories of etcd and control
+// plane servers are removed.
+func (p *Planner) generateEtcdRestoreServiceStopPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, server *planEntry, joinServer string) (plan.NodePlan, string, error) {
+	stopPlan, joinedServer, err := p.generateStopServiceAndKillAllPlan(controlPlane, tokensSecret, server, joinServer)
+	if err != nil {
+		return stopPlan, joinedServer, err
+	}
+	if isEtcd(server) {
+		stopPlan.Instructions = append(stopPlan.Instructions, generateCreateEtcdTombstoneInstruction(controlPlane))
+	}
+	if roleOr(isEtcd, isControlPlane)(server) {
+		stopPlan.Instructions = append(stopPlan.Instructions, generateRemoveTLSAndCredDirInstructions(controlPlane)...)
+	}
+	return stopPlan, joinedServer, nil
+}
+
 // runEtcdSnapshotManagementServiceStart walks through the reconciliation process for the controlplane and etcd nodes.
-// Notably, this function will blatantly ignore drain and concurrency options, as during an etcd snapshot operation, there is no necessity to drain nodes.
+// Notably, this function will blatantly ignore concurrency options, as during an etcd snapshot operation, there is no
+// necessity to restart nodes one at a time. Only the bootstrap node is drained, as configured by its machine pool.
 func (p *Planner) runEtcdSnapshotManagementServiceStart(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, clusterPlan *plan.Plan, include roleFilter, operation string) error {
+	drainOptions, err := p.etcdRestartDrainOptions(controlPlane, controlPlane.Spec.UpgradeStrategy.ControlPlaneDrainOptions)
+	if err != nil {
+		return err
+	}
+
 	// Generate and deliver desired plan for the bootstrap/init node first.
 	if err := p.reconcile(controlPlane, tokensSecret, clusterPlan, true, bootstrapTier, isEtcd, isNotInitNodeOrIsDeleting,
 		"1", "",
-		controlPlane.Spec.UpgradeStrategy.ControlPlaneDrainOptions); err != nil {
+		drainOptions, tierControlPlane(controlPlane), noHold, nil); err != nil {
 		return err
 	}
 
@@ -339,7 +459,12 @@ func (p *Planner) retrieveEtcdSnapshot(controlPlane *rkev1.RKEControlPlane) (*rk
 // Started -> When the phase is started, it gets set to shutdown
 // Shutdown -> When the phase is shutdown, it attempts to shut down etcd on all nodes (stop etcd)
 // Restore ->  When the phase is restore, it attempts to restore etcd
+// RestartCluster -> When the phase is restartcluster, it restarts the cluster with the restored etcd
+// MigrateStorage -> When the snapshot was taken with an older minor version of Kubernetes, it migrates the storage versions
 // Finished -> When the phase is finished, Restore returns nil.
+// Cancelled -> When cancel is set while the phase is started, the restore is abandoned and Restore returns nil.
+// Previewed -> When previewOnly is set, the plans of the restore are published while the phase is started instead of
+// shutting down the cluster, and Restore returns nil.
 func (p *Planner) restoreEtcdSnapshot(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, tokensSecret plan.Secret, clusterPlan *plan.Plan, currentVersion *semver.Version) (rkev1.RKEControlPlaneStatus, error) {
 	if cp.Spec.ETCDSnapshotRestore == nil || cp.Spec.ETCDSnapshotRestore.Name == "" {
 		return p.resetEtcdSnapshotRestoreState(status)
@@ -349,6 +474,15 @@ func (p *Planner) restoreEtcdSnapshot(cp *rkev1.RKEControlPlane, status rkev1.RK
 		return status, err
 	}
 
+	switch {
+	case cp.Status.ETCDSnapshotRestorePhase == rkev1.ETCDSnapshotPhaseCancelled, cp.Status.ETCDSnapshotRestorePhase == rkev1.ETCDSnapshotPhasePreviewed:
+		return status, nil
+	case cp.Status.ETCDSnapshotRestorePhase == rkev1.ETCDSnapshotPhaseStarted && cp.Spec.ETCDSnapshotRestore.Cancel:
+		// no machine has been touched yet, so the restore can be abandoned
+		logrus.Infof("[planner] rkecluster %s/%s: cancelling etcd snapshot restore", cp.Namespace, cp.Name)
+		return p.setEtcdSnapshotRestoreState(status, cp.Spec.ETCDSnapshotRestore, rkev1.ETCDSnapshotPhaseCancelled)
+	}
+
 	snapshot, err := p.retrieveEtcdSnapshot(cp)
 	if err != nil {
 		return status, err
@@ -372,11 +506,33 @@ func (p *Planner) restoreEtcdSnapshot(cp *rkev1.RKEControlPlane, status rkev1.RK
 
 	switch cp.Status.ETCDSnapshotRestorePhase {
 	case rkev1.ETCDSnapshotPhaseStarted:
+		if cp.Spec.ETCDSnapshotRestore.PreviewOnly {
+			// nothing is run, so neither approvals nor the readiness of the control plane are affected
+			if err := p.previewEtcdSnapshotRestore(cp, snapshot, tokensSecret, clusterPlan); err != nil {
+				return status, err
+			}
+			return p.setEtcdSnapshotRestoreState(status, cp.Spec.ETCDSnapshotRestore, rkev1.ETCDSnapshotPhasePreviewed)
+		}
+		if status, err = p.checkEtcdSnapshotRestoreApprovals(cp, status); err != nil {
+			return status, err
+		}
+		if status, err = checkEtcdSnapshotStorageMigration(cp, snapshot, status); err != nil {
+			return status, err
+		}
 		if status.Initialized || status.Ready {
 			status.Initialized = false
 			status.Ready = false
 			logrus.Debugf("[planner] rkecluster %s/%s: setting controlplane ready/initialized to false during etcd restore", cp.Namespace, cp.Name)
 		}
+		if incompatibilities := etcdSnapshotIncompatibilities(cp, snapshot); len(incompatibilities) > 0 {
+			message := strings.Join(incompatibilities, ", ")
+			logrus.Warnf("[planner] rkecluster %s/%s: etcd snapshot %s may not be compatible with the cluster: %s", cp.Namespace, cp.Name, cp.Spec.ETCDSnapshotRestore.Name, message)
+			capr.ETCDSnapshotCompatible.False(&status)
+			capr.ETCDSnapshotCompatible.Message(&status, message)
+		} else {
+			capr.ETCDSnapshotCompatible.True(&status)
+			capr.ETCDSnapshotCompatible.Message(&status, "")
+		}
 		status, _ = p.setEtcdSnapshotRestoreState(status, cp.Spec.ETCDSnapshotRestore, rkev1.ETCDSnapshotPhaseShutdown)
 		return status, errWaitingf("shutting down cluster")
 	case rkev1.ETCDSnapshotPhaseShutdown:
@@ -399,10 +555,18 @@ func (p *Planner) restoreEtcdSnapshot(cp *rkev1.RKEControlPlane, status rkev1.RK
 			return status, err
 		}
 		logrus.Infof("[planner] rkecluster %s/%s: running full reconcile during etcd restore to restart cluster", cp.Namespace, cp.Name)
-		// Run a full reconcile of the cluster at this point, ignoring drain and concurrency.
+		// Run a full reconcile of the cluster at this point, ignoring concurrency and the drain options of the upgrade strategy.
 		if status, err := p.fullReconcile(cp, status, tokensSecret, clusterPlan, true); err != nil {
 			return status, err
 		}
+		if _, required := etcdSnapshotStorageMigration(cp, snapshot); required {
+			return p.setEtcdSnapshotRestoreState(status, cp.Spec.ETCDSnapshotRestore, rkev1.ETCDSnapshotPhaseMigrateStorage)
+		}
+		return p.setEtcdSnapshotRestoreState(status, cp.Spec.ETCDSnapshotRestore, rkev1.ETCDSnapshotPhaseFinished)
+	case rkev1.ETCDSnapshotPhaseMigrateStorage:
+		if status, err = p.runEtcdSnapshotStorageMigration(cp, snapshot, status, tokensSecret, clusterPlan); err != nil {
+			return status, err
+		}
 		return p.setEtcdSnapshotRestoreState(status, cp.Spec.ETCDSnapshotRestore, rkev1.ETCDSnapshotPhaseFinished)
 	case rkev1.ETCDSnapshotPhaseFinished:
 		return status, nil
@@ -0,0 +1,141 @@
+package planner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartOrRestartEtcdSnapshotRestore(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        rkev1.RKEControlPlaneStatus
+		restore       *rkev1.ETCDSnapshotRestore
+		expectedPhase rkev1.ETCDSnapshotPhase
+		expectedErr   bool
+	}{
+		{
+			name:          "new restore",
+			restore:       &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 1},
+			expectedPhase: rkev1.ETCDSnapshotPhaseStarted,
+			expectedErr:   true,
+		},
+		{
+			name:          "new restore that is cancelled",
+			restore:       &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 1, Cancel: true},
+			expectedPhase: rkev1.ETCDSnapshotPhaseCancelled,
+			expectedErr:   true,
+		},
+		{
+			name: "cancelling a restore in progress does not restart it",
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotRestore:      &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 1},
+				ETCDSnapshotRestorePhase: rkev1.ETCDSnapshotPhaseShutdown,
+			},
+			restore:       &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 1, Cancel: true},
+			expectedPhase: rkev1.ETCDSnapshotPhaseShutdown,
+		},
+		{
+			name: "new snapshot after cancelled restore",
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotRestore:      &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 1, Cancel: true},
+				ETCDSnapshotRestorePhase: rkev1.ETCDSnapshotPhaseCancelled,
+			},
+			restore:       &rkev1.ETCDSnapshotRestore{Name: "other-snapshot", Generation: 1},
+			expectedPhase: rkev1.ETCDSnapshotPhaseStarted,
+			expectedErr:   true,
+		},
+		{
+			name: "unsetting preview only starts the previewed restore",
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotRestore:      &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 1, PreviewOnly: true},
+				ETCDSnapshotRestorePhase: rkev1.ETCDSnapshotPhasePreviewed,
+			},
+			restore:       &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 1},
+			expectedPhase: rkev1.ETCDSnapshotPhaseStarted,
+			expectedErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := (&Planner{}).startOrRestartEtcdSnapshotRestore(tt.status, tt.restore)
+			assert.Equal(t, tt.expectedErr, err != nil)
+			assert.Equal(t, tt.expectedPhase, status.ETCDSnapshotRestorePhase)
+		})
+	}
+}
+
+func TestEtcdSnapshotIncompatibilities(t *testing.T) {
+	newSnapshot := func(metadata map[string]string) *rkev1.ETCDSnapshot {
+		data, err := json.Marshal(metadata)
+		assert.NoError(t, err)
+		return &rkev1.ETCDSnapshot{
+			SnapshotFile: rkev1.ETCDSnapshotFile{Metadata: base64.StdEncoding.EncodeToString(data)},
+		}
+	}
+	controlPlane := &rkev1.RKEControlPlane{
+		Spec: rkev1.RKEControlPlaneSpec{
+			RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+				MachineGlobalConfig: rkev1.GenericMap{Data: map[string]interface{}{"cni": "cilium"}},
+			},
+			KubernetesVersion: "v1.25.9+rke2r1",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		snapshot *rkev1.ETCDSnapshot
+		expected []string
+	}{
+		{
+			name:     "snapshot of the same cluster",
+			snapshot: newSnapshot(capr.SnapshotMetadata(controlPlane)),
+		},
+		{
+			name:     "snapshot without metadata",
+			snapshot: &rkev1.ETCDSnapshot{},
+		},
+		{
+			name: "snapshot with only the cluster spec",
+			snapshot: newSnapshot(map[string]string{
+				EtcdSnapshotConfigMapKey: "spec",
+			}),
+		},
+		{
+			name: "snapshot of a newer cluster",
+			snapshot: newSnapshot(map[string]string{
+				capr.SnapshotMetadataKubernetesVersion: "v1.26.4+rke2r1",
+				capr.SnapshotMetadataETCDVersion:       "3.6",
+				capr.SnapshotMetadataCNI:               "calico",
+				capr.SnapshotMetadataClusterCIDR:       "10.42.0.0/16",
+				capr.SnapshotMetadataServiceCIDR:       "10.96.0.0/16",
+			}),
+			expected: []string{
+				"snapshot was taken with Kubernetes v1.26.4+rke2r1 which is newer than v1.25.9+rke2r1 of the cluster",
+				"snapshot was taken with etcd 3.6 which is newer than etcd 3.5 of the cluster",
+				"snapshot was taken with cni calico but the cluster has cilium",
+				"snapshot was taken with service-cidr 10.96.0.0/16 but the cluster has 10.43.0.0/16",
+			},
+		},
+		{
+			name: "snapshot of a much older cluster",
+			snapshot: newSnapshot(map[string]string{
+				capr.SnapshotMetadataKubernetesVersion: "v1.23.17+rke2r1",
+			}),
+			expected: []string{
+				"snapshot was taken with Kubernetes v1.23.17+rke2r1 which is more than one minor version older than v1.25.9+rke2r1 of the cluster",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, etcdSnapshotIncompatibilities(controlPlane, tt.snapshot))
+		})
+	}
+}
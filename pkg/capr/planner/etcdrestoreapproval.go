@@ -0,0 +1,62 @@
+package planner
+
+import (
+	"fmt"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// checkEtcdSnapshotRestoreApprovals keeps the etcd snapshot restore of the control plane from proceeding until as many
+// distinct users as required by the etcd-snapshot-restore-approvals setting approved it. Only approvals in the system
+// namespace are considered, as they are created by Rancher on behalf of the approving users.
+func (p *Planner) checkEtcdSnapshotRestoreApprovals(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
+	required := settings.ETCDSnapshotRestoreApprovals.GetInt()
+	if required <= 0 {
+		return status, nil
+	}
+
+	approvals, err := p.approvalCache.List(namespace.System, labels.Everything())
+	if err != nil {
+		return status, err
+	}
+	approvers := etcdSnapshotRestoreApprovers(approvals, controlPlane.Namespace, controlPlane.Name, controlPlane.Spec.ETCDSnapshotRestore)
+	if approvers.Len() < required {
+		message := fmt.Sprintf("etcd snapshot restore requires approvals of %d users, approved by %d", required, approvers.Len())
+		if approvers.Len() > 0 {
+			message = fmt.Sprintf("%s: %v", message, approvers.List())
+		}
+		capr.ETCDSnapshotRestoreApproved.False(&status)
+		capr.ETCDSnapshotRestoreApproved.Message(&status, message)
+		return status, errWaiting(message)
+	}
+
+	logrus.Infof("[planner] rkecluster %s/%s: etcd snapshot restore approved by %v", controlPlane.Namespace, controlPlane.Name, approvers.List())
+	capr.ETCDSnapshotRestoreApproved.True(&status)
+	capr.ETCDSnapshotRestoreApproved.Message(&status, fmt.Sprintf("approved by %v", approvers.List()))
+	return status, nil
+}
+
+// etcdSnapshotRestoreApprovers returns the distinct users that approved the restore of the cluster. Approvals are only
+// counted for the snapshot and the generation of the restore, so that approvals of previous restores, or of a restore of
+// another snapshot, are ignored.
+func etcdSnapshotRestoreApprovers(approvals []*rkev1.Approval, clusterNamespace, clusterName string, restore *rkev1.ETCDSnapshotRestore) sets.String {
+	approvers := sets.NewString()
+	for _, approval := range approvals {
+		if approval.Spec.ClusterNamespace != clusterNamespace ||
+			approval.Spec.ClusterName != clusterName ||
+			approval.Spec.Operation != rkev1.ApprovalOperationETCDSnapshotRestore ||
+			approval.Spec.SnapshotName != restore.Name ||
+			approval.Spec.Generation != restore.Generation ||
+			approval.Spec.Approver == "" {
+			continue
+		}
+		approvers.Insert(approval.Spec.Approver)
+	}
+	return approvers
+}
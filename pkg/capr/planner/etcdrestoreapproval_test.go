@@ -0,0 +1,67 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETCDSnapshotRestoreApprovers(t *testing.T) {
+	approval := func(clusterNamespace, clusterName, operation, snapshotName string, generation int, approver string) *rkev1.Approval {
+		return &rkev1.Approval{Spec: rkev1.ApprovalSpec{
+			ClusterNamespace: clusterNamespace,
+			ClusterName:      clusterName,
+			Operation:        operation,
+			SnapshotName:     snapshotName,
+			Generation:       generation,
+			Approver:         approver,
+		}}
+	}
+	restore := rkev1.ApprovalOperationETCDSnapshotRestore
+
+	tests := []struct {
+		name      string
+		approvals []*rkev1.Approval
+		expected  []string
+	}{
+		{
+			name:     "no approvals",
+			expected: []string{},
+		},
+		{
+			name: "distinct approvers",
+			approvals: []*rkev1.Approval{
+				approval("fleet-default", "test", restore, "snapshot", 2, "u-b"),
+				approval("fleet-default", "test", restore, "snapshot", 2, "u-a"),
+			},
+			expected: []string{"u-a", "u-b"},
+		},
+		{
+			name: "same approver twice",
+			approvals: []*rkev1.Approval{
+				approval("fleet-default", "test", restore, "snapshot", 2, "u-a"),
+				approval("fleet-default", "test", restore, "snapshot", 2, "u-a"),
+			},
+			expected: []string{"u-a"},
+		},
+		{
+			name: "other clusters, operations, snapshots and generations",
+			approvals: []*rkev1.Approval{
+				approval("fleet-default", "other", restore, "snapshot", 2, "u-a"),
+				approval("fleet-other", "test", restore, "snapshot", 2, "u-a"),
+				approval("fleet-default", "test", "other", "snapshot", 2, "u-b"),
+				approval("fleet-default", "test", restore, "other", 2, "u-b"),
+				approval("fleet-default", "test", restore, "snapshot", 1, "u-c"),
+				approval("fleet-default", "test", restore, "snapshot", 2, ""),
+				approval("fleet-default", "test", restore, "snapshot", 2, "u-d"),
+			},
+			expected: []string{"u-d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, etcdSnapshotRestoreApprovers(tt.approvals, "fleet-default", "test", &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 2}).List())
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package planner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// etcdRestorePreviewSnapshotAnnotation is the name of the etcd snapshot whose restore was previewed.
+	etcdRestorePreviewSnapshotAnnotation = "rke.cattle.io/etcd-restore-preview-snapshot"
+	// etcdRestorePreviewGenerationAnnotation is the generation of the etcd snapshot restore that was previewed.
+	etcdRestorePreviewGenerationAnnotation = "rke.cattle.io/etcd-restore-preview-generation"
+
+	redactedValue = "<redacted>"
+)
+
+// etcdRestorePreviewStep is a plan a machine would receive during a phase of an etcd snapshot restore, in the order the
+// plans are delivered.
+type etcdRestorePreviewStep struct {
+	Phase rkev1.ETCDSnapshotPhase `json:"phase"`
+	Plan  plan.NodePlan           `json:"plan"`
+}
+
+// etcdRestorePreviewConfigMapName returns the name of the ConfigMap that the plans of a previewed etcd snapshot restore
+// are published to.
+func etcdRestorePreviewConfigMapName(controlPlane *rkev1.RKEControlPlane) string {
+	return name.SafeConcatName(controlPlane.Name, "etcd", "restore", "preview")
+}
+
+// previewEtcdSnapshotRestore renders the plans that the machines of the cluster would receive during the shutdown,
+// restore and restart phases of the etcd snapshot restore of the control plane, and publishes them by machine name to
+// the restore preview ConfigMap. Unlike the restore, the preview does not elect or designate an init node and does not
+// pause the CAPI cluster, so no machine or cluster object is changed.
+func (p *Planner) previewEtcdSnapshotRestore(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot, tokensSecret plan.Secret, clusterPlan *plan.Plan) error {
+	controlPlane, err := etcdRestorePreviewControlPlane(controlPlane, snapshot)
+	if err != nil {
+		return err
+	}
+	initNode, err := previewEtcdRestoreInitNode(snapshot, clusterPlan)
+	if err != nil {
+		return err
+	}
+	joinServer := initNode.Metadata.Annotations[capr.JoinURLAnnotation]
+
+	steps := map[string][]etcdRestorePreviewStep{}
+	servers := collect(clusterPlan, anyRoleWithoutWindows)
+	for _, server := range servers {
+		if server.Plan == nil {
+			continue
+		}
+		stopPlan, _, err := p.generateEtcdRestoreServiceStopPlan(controlPlane, tokensSecret, server, joinServer)
+		if err != nil {
+			return err
+		}
+		steps[server.Machine.Name] = append(steps[server.Machine.Name], etcdRestorePreviewStep{Phase: rkev1.ETCDSnapshotPhaseShutdown, Plan: stopPlan})
+	}
+
+	restorePlan, _, err := p.generateEtcdSnapshotRestorePlan(controlPlane, snapshot, controlPlane.Spec.ETCDSnapshotRestore.Name, tokensSecret, initNode, joinServer)
+	if err != nil {
+		return err
+	}
+	steps[initNode.Machine.Name] = append(steps[initNode.Machine.Name], etcdRestorePreviewStep{Phase: rkev1.ETCDSnapshotPhaseRestore, Plan: restorePlan})
+
+	for _, server := range servers {
+		if server.Plan == nil || isDeleting(server) {
+			continue
+		}
+		// the init node is restarted first, and the other machines join it
+		serverJoinServer := joinServer
+		if server == initNode {
+			serverJoinServer = ""
+		}
+		restartPlan, _, err := p.desiredPlan(controlPlane, tokensSecret, server, serverJoinServer)
+		if err != nil {
+			return err
+		}
+		steps[server.Machine.Name] = append(steps[server.Machine.Name], etcdRestorePreviewStep{Phase: rkev1.ETCDSnapshotPhaseRestartCluster, Plan: restartPlan})
+	}
+
+	data := map[string]string{}
+	for machineName, machineSteps := range steps {
+		for i := range machineSteps {
+			machineSteps[i].Plan = redactPlan(machineSteps[i].Plan)
+		}
+		content, err := json.MarshalIndent(machineSteps, "", "  ")
+		if err != nil {
+			return err
+		}
+		data[machineName] = string(content)
+	}
+	if err := p.storeEtcdRestorePreview(controlPlane, data); err != nil {
+		return err
+	}
+	logrus.Infof("[planner] rkecluster %s/%s: published preview of etcd snapshot restore %d of %s for %d machines to configmap %s/%s",
+		controlPlane.Namespace, controlPlane.Name, controlPlane.Spec.ETCDSnapshotRestore.Generation, controlPlane.Spec.ETCDSnapshotRestore.Name,
+		len(data), controlPlane.Namespace, etcdRestorePreviewConfigMapName(controlPlane))
+	return nil
+}
+
+// etcdRestorePreviewControlPlane returns the control plane that the plans of the restore are rendered for. If the restore
+// restores the Kubernetes version or the RKE config recorded on the snapshot, they are applied to a copy of the control
+// plane, as the cluster is only updated with them when the restore is run.
+func etcdRestorePreviewControlPlane(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot) (*rkev1.RKEControlPlane, error) {
+	restoreRKEConfig := controlPlane.Spec.ETCDSnapshotRestore.RestoreRKEConfig
+	if restoreRKEConfig == "" || restoreRKEConfig == "none" {
+		return controlPlane, nil
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("unable to preview restoring the RKE config of etcd snapshot %s as its etcd snapshot CR was not found", controlPlane.Spec.ETCDSnapshotRestore.Name)
+	}
+	clusterSpec, err := capr.ParseSnapshotClusterSpecOrError(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	controlPlane = controlPlane.DeepCopy()
+	controlPlane.Spec.KubernetesVersion = clusterSpec.KubernetesVersion
+	if restoreRKEConfig == "all" && clusterSpec.RKEConfig != nil {
+		controlPlane.Spec.MachineGlobalConfig = clusterSpec.RKEConfig.MachineGlobalConfig
+		controlPlane.Spec.MachineSelectorConfig = clusterSpec.RKEConfig.MachineSelectorConfig
+		controlPlane.Spec.ChartValues = clusterSpec.RKEConfig.ChartValues
+		controlPlane.Spec.Registries = clusterSpec.RKEConfig.Registries
+		controlPlane.Spec.UpgradeStrategy = clusterSpec.RKEConfig.UpgradeStrategy
+		controlPlane.Spec.AdditionalManifest = clusterSpec.RKEConfig.AdditionalManifest
+	}
+	return controlPlane, nil
+}
+
+// previewEtcdRestoreInitNode returns the machine that the snapshot would be restored on, without marking it as the init
+// node. Local snapshots are restored on the machine they were taken on. S3 snapshots are restored on the current init
+// node, or on the machine that would be elected otherwise.
+func previewEtcdRestoreInitNode(snapshot *rkev1.ETCDSnapshot, clusterPlan *plan.Plan) (*planEntry, error) {
+	if snapshot != nil && snapshot.SnapshotFile.S3 == nil {
+		id, ok := snapshot.Labels[capr.MachineIDLabel]
+		if !ok {
+			return nil, fmt.Errorf("unable to designate machine as label %s on snapshot %s/%s did not exist", capr.MachineIDLabel, snapshot.Namespace, snapshot.Name)
+		}
+		for _, entry := range collect(clusterPlan, isEtcd) {
+			if entry.Machine.Labels[capr.MachineIDLabel] == id {
+				return entry, nil
+			}
+		}
+		return nil, fmt.Errorf("machine with machine ID %s of snapshot %s/%s was not found", id, snapshot.Namespace, snapshot.Name)
+	}
+
+	possibleInitNodes := collect(clusterPlan, canBeInitNode)
+	if snapshot == nil && len(possibleInitNodes) != 1 {
+		return nil, fmt.Errorf("more than one init node existed and no corresponding etcd snapshot CR found, no assumption can be made for the machine that contains the snapshot")
+	}
+	if initNodes := collect(clusterPlan, roleAnd(isInitNode, canBeInitNode)); len(initNodes) == 1 {
+		return initNodes[0], nil
+	}
+	for _, entry := range possibleInitNodes {
+		if entry.Metadata.Annotations[capr.JoinURLAnnotation] != "" {
+			return entry, nil
+		}
+	}
+	if len(possibleInitNodes) > 0 {
+		return possibleInitNodes[0], nil
+	}
+	return nil, errWaiting("waiting for viable init node")
+}
+
+// redactPlan returns a copy of the node plan without the contents of its files and the values of the environment
+// variables of its instructions, which hold the tokens of the cluster and the credentials of the snapshot storage and
+// must not be published to a ConfigMap.
+func redactPlan(nodePlan plan.NodePlan) plan.NodePlan {
+	result := plan.NodePlan{
+		Files:                make([]plan.File, 0, len(nodePlan.Files)),
+		Instructions:         make([]plan.OneTimeInstruction, 0, len(nodePlan.Instructions)),
+		PeriodicInstructions: nodePlan.PeriodicInstructions,
+		Probes:               nodePlan.Probes,
+	}
+	for _, file := range nodePlan.Files {
+		file.Content = redactedValue
+		result.Files = append(result.Files, file)
+	}
+	for _, instruction := range nodePlan.Instructions {
+		instruction.Env = redactEnv(instruction.Env)
+		result.Instructions = append(result.Instructions, instruction)
+	}
+	return result
+}
+
+func redactEnv(env []string) []string {
+	if len(env) == 0 {
+		return env
+	}
+	result := make([]string, 0, len(env))
+	for _, e := range env {
+		key, _, _ := strings.Cut(e, "=")
+		result = append(result, key+"="+redactedValue)
+	}
+	return result
+}
+
+// storeEtcdRestorePreview creates or updates the restore preview ConfigMap of the control plane with the previewed plans.
+func (p *Planner) storeEtcdRestorePreview(controlPlane *rkev1.RKEControlPlane, data map[string]string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      etcdRestorePreviewConfigMapName(controlPlane),
+			Namespace: controlPlane.Namespace,
+			Labels: map[string]string{
+				capr.ClusterNameLabel: controlPlane.Spec.ClusterName,
+			},
+			Annotations: map[string]string{
+				etcdRestorePreviewSnapshotAnnotation:   controlPlane.Spec.ETCDSnapshotRestore.Name,
+				etcdRestorePreviewGenerationAnnotation: strconv.Itoa(controlPlane.Spec.ETCDSnapshotRestore.Generation),
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: capr.RKEAPIVersion,
+				Kind:       "RKEControlPlane",
+				Name:       controlPlane.Name,
+				UID:        controlPlane.UID,
+			}},
+		},
+		Data: data,
+	}
+	existing, err := p.configMapCache.Get(configMap.Namespace, configMap.Name)
+	if apierrors.IsNotFound(err) {
+		_, err = p.configMaps.Create(configMap)
+	} else if err == nil {
+		existing = existing.DeepCopy()
+		existing.Annotations = configMap.Annotations
+		existing.Data = configMap.Data
+		_, err = p.configMaps.Update(existing)
+	}
+	return err
+}
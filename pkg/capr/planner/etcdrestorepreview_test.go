@@ -0,0 +1,216 @@
+package planner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func newPreviewPlan(machines ...string) *plan.Plan {
+	clusterPlan := &plan.Plan{
+		Nodes:    map[string]*plan.Node{},
+		Machines: map[string]*capi.Machine{},
+		Metadata: map[string]*plan.Metadata{},
+	}
+	for _, machineName := range machines {
+		clusterPlan.Machines[machineName] = &capi.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   machineName,
+				Labels: map[string]string{capr.MachineIDLabel: machineName + "-id"},
+			},
+			Status: capi.MachineStatus{
+				Conditions: capi.Conditions{{Type: capi.InfrastructureReadyCondition, Status: corev1.ConditionTrue}},
+			},
+		}
+		clusterPlan.Metadata[machineName] = &plan.Metadata{
+			Labels:      map[string]string{capr.EtcdRoleLabel: "true"},
+			Annotations: map[string]string{},
+		}
+	}
+	return clusterPlan
+}
+
+func TestPreviewEtcdRestoreInitNode(t *testing.T) {
+	local := &rkev1.ETCDSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "local", Labels: map[string]string{capr.MachineIDLabel: "etcd-2-id"}},
+	}
+	s3 := &rkev1.ETCDSnapshot{
+		ObjectMeta:   metav1.ObjectMeta{Name: "s3"},
+		SnapshotFile: rkev1.ETCDSnapshotFile{S3: &rkev1.ETCDSnapshotS3{Bucket: "snapshots"}},
+	}
+
+	withInitNode := newPreviewPlan("etcd-1", "etcd-2", "etcd-3")
+	withInitNode.Metadata["etcd-3"].Labels[capr.InitNodeLabel] = "true"
+	withJoinURL := newPreviewPlan("etcd-1", "etcd-2")
+	withJoinURL.Metadata["etcd-2"].Annotations[capr.JoinURLAnnotation] = "https://etcd-2:9345"
+
+	tests := []struct {
+		name         string
+		snapshot     *rkev1.ETCDSnapshot
+		plan         *plan.Plan
+		expectedNode string
+		expectedErr  string
+	}{
+		{
+			name:         "local snapshot is restored on the machine it was taken on",
+			snapshot:     local,
+			plan:         withInitNode,
+			expectedNode: "etcd-2",
+		},
+		{
+			name:        "machine of local snapshot is gone",
+			snapshot:    local,
+			plan:        newPreviewPlan("etcd-1"),
+			expectedErr: "machine with machine ID etcd-2-id of snapshot /local was not found",
+		},
+		{
+			name:         "S3 snapshot is restored on the current init node",
+			snapshot:     s3,
+			plan:         withInitNode,
+			expectedNode: "etcd-3",
+		},
+		{
+			name:         "S3 snapshot is restored on a machine with a join URL",
+			snapshot:     s3,
+			plan:         withJoinURL,
+			expectedNode: "etcd-2",
+		},
+		{
+			name:         "snapshot without object is restored on the only etcd machine",
+			plan:         newPreviewPlan("etcd-1"),
+			expectedNode: "etcd-1",
+		},
+		{
+			name:        "snapshot without object with multiple etcd machines",
+			plan:        newPreviewPlan("etcd-1", "etcd-2"),
+			expectedErr: "more than one init node existed and no corresponding etcd snapshot CR found, no assumption can be made for the machine that contains the snapshot",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := previewEtcdRestoreInitNode(tt.snapshot, tt.plan)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedNode, entry.Machine.Name)
+		})
+	}
+}
+
+func TestEtcdRestorePreviewControlPlane(t *testing.T) {
+	spec, err := capr.CompressInterface(rancherv1.ClusterSpec{
+		KubernetesVersion: "v1.26.8+rke2r1",
+		RKEConfig: &rancherv1.RKEConfig{
+			RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+				AdditionalManifest:  "snapshot-manifest",
+				MachineGlobalConfig: rkev1.GenericMap{Data: map[string]interface{}{"cni": "cilium"}},
+			},
+		},
+	})
+	require.NoError(t, err)
+	metadata, err := json.Marshal(map[string]string{"provisioning-cluster-spec": spec})
+	require.NoError(t, err)
+	snapshot := &rkev1.ETCDSnapshot{
+		ObjectMeta:   metav1.ObjectMeta{Name: "snapshot"},
+		SnapshotFile: rkev1.ETCDSnapshotFile{Metadata: base64.StdEncoding.EncodeToString(metadata)},
+	}
+
+	tests := []struct {
+		name               string
+		restoreRKEConfig   string
+		snapshot           *rkev1.ETCDSnapshot
+		expectedVersion    string
+		expectedManifest   string
+		expectedGlobalData map[string]interface{}
+		expectedErr        string
+	}{
+		{
+			name:               "none",
+			restoreRKEConfig:   "none",
+			snapshot:           snapshot,
+			expectedVersion:    "v1.27.5+rke2r1",
+			expectedManifest:   "cluster-manifest",
+			expectedGlobalData: map[string]interface{}{"cni": "calico"},
+		},
+		{
+			name:               "kubernetes version",
+			restoreRKEConfig:   "kubernetesVersion",
+			snapshot:           snapshot,
+			expectedVersion:    "v1.26.8+rke2r1",
+			expectedManifest:   "cluster-manifest",
+			expectedGlobalData: map[string]interface{}{"cni": "calico"},
+		},
+		{
+			name:               "all",
+			restoreRKEConfig:   "all",
+			snapshot:           snapshot,
+			expectedVersion:    "v1.26.8+rke2r1",
+			expectedManifest:   "snapshot-manifest",
+			expectedGlobalData: map[string]interface{}{"cni": "cilium"},
+		},
+		{
+			name:             "missing snapshot",
+			restoreRKEConfig: "all",
+			expectedErr:      "unable to preview restoring the RKE config of etcd snapshot snapshot as its etcd snapshot CR was not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &rkev1.RKEControlPlane{
+				Spec: rkev1.RKEControlPlaneSpec{
+					RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+						AdditionalManifest:  "cluster-manifest",
+						MachineGlobalConfig: rkev1.GenericMap{Data: map[string]interface{}{"cni": "calico"}},
+					},
+					ETCDSnapshotRestore: &rkev1.ETCDSnapshotRestore{Name: "snapshot", RestoreRKEConfig: tt.restoreRKEConfig, PreviewOnly: true},
+					KubernetesVersion:   "v1.27.5+rke2r1",
+				},
+			}
+			previewed, err := etcdRestorePreviewControlPlane(cp, tt.snapshot)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedVersion, previewed.Spec.KubernetesVersion)
+			assert.Equal(t, tt.expectedManifest, previewed.Spec.AdditionalManifest)
+			assert.Equal(t, tt.expectedGlobalData, previewed.Spec.MachineGlobalConfig.Data)
+			assert.Equal(t, "v1.27.5+rke2r1", cp.Spec.KubernetesVersion, "the control plane is not modified")
+			assert.Equal(t, "cluster-manifest", cp.Spec.AdditionalManifest, "the control plane is not modified")
+		})
+	}
+}
+
+func TestRedactPlan(t *testing.T) {
+	nodePlan := plan.NodePlan{
+		Files: []plan.File{{Path: "/etc/rancher/rke2/config.yaml.d/50-rancher.yaml", Content: "dG9rZW46IHNlY3JldA==", Permissions: "0600"}},
+		Instructions: []plan.OneTimeInstruction{
+			{Name: "shutdown", Command: "/bin/sh", Args: []string{"-c", "rke2-killall.sh"}},
+			{Name: "restore", Command: "rke2", Args: []string{"server", "--cluster-reset"}, Env: []string{"AWS_SECRET_ACCESS_KEY=secret", "NO_VALUE"}},
+		},
+	}
+
+	assert.Equal(t, plan.NodePlan{
+		Files: []plan.File{{Path: "/etc/rancher/rke2/config.yaml.d/50-rancher.yaml", Content: redactedValue, Permissions: "0600"}},
+		Instructions: []plan.OneTimeInstruction{
+			{Name: "shutdown", Command: "/bin/sh", Args: []string{"-c", "rke2-killall.sh"}},
+			{Name: "restore", Command: "rke2", Args: []string{"server", "--cluster-reset"}, Env: []string{"AWS_SECRET_ACCESS_KEY=" + redactedValue, "NO_VALUE=" + redactedValue}},
+		},
+	}, redactPlan(nodePlan))
+	assert.Equal(t, "dG9rZW46IHNlY3JldA==", nodePlan.Files[0].Content, "the node plan is not modified")
+	assert.Equal(t, "AWS_SECRET_ACCESS_KEY=secret", nodePlan.Instructions[1].Env[0], "the node plan is not modified")
+}
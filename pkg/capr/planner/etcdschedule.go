@@ -0,0 +1,106 @@
+package planner
+
+import (
+	"fmt"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/robfig/cron"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxMissedEtcdSnapshotSchedules bounds the number of schedule times that are skipped to find the latest due time, for
+// schedules that were missed for a long time.
+const maxMissedEtcdSnapshotSchedules = 100000
+
+// scheduledEtcdSnapshotCreate returns whether the snapshot creation was started for the snapshot schedule.
+func scheduledEtcdSnapshotCreate(create *rkev1.ETCDSnapshotCreate) bool {
+	return create != nil && create.ScheduledAt != nil
+}
+
+// etcdSnapshotCreateRequested returns whether the spec requests a snapshot creation that was not started yet. Scheduled
+// snapshot creations carry the generation of the spec they were started with, so the snapshot creation of the spec is
+// not started again once a scheduled snapshot creation completed.
+func etcdSnapshotCreateRequested(requested, current *rkev1.ETCDSnapshotCreate) bool {
+	if requested == nil {
+		return false
+	}
+	if scheduledEtcdSnapshotCreate(current) {
+		return requested.Generation != current.Generation
+	}
+	return current == nil || requested.Generation != current.Generation
+}
+
+// etcdSnapshotCreateRequest returns the snapshot creation that is run for the control plane: the scheduled snapshot
+// creation that was started last, unless the spec requests a new one, or the snapshot creation of the spec. A new
+// scheduled snapshot creation is started once the schedule is due, if the cluster is ready and no other etcd snapshot
+// operation is in progress or requested. The schedule status is updated, and the next time the schedule is due is
+// returned, so that the control plane can be processed again then.
+func etcdSnapshotCreateRequest(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, ready bool, now time.Time) (*rkev1.ETCDSnapshotCreate, rkev1.RKEControlPlaneStatus, time.Time, error) {
+	current := status.ETCDSnapshotCreate
+	request := cp.Spec.ETCDSnapshotCreate
+	if scheduledEtcdSnapshotCreate(current) && !etcdSnapshotCreateRequested(request, current) {
+		request = current
+	}
+
+	schedule := cp.Spec.ETCDSnapshotSchedule
+	if schedule == nil || schedule.Cron == "" {
+		return request, status, time.Time{}, nil
+	}
+	cronSchedule, err := cron.ParseStandard(schedule.Cron)
+	if err != nil {
+		return request, status, time.Time{}, fmt.Errorf("invalid etcd snapshot schedule %q: %w", schedule.Cron, err)
+	}
+
+	scheduleStatus := &rkev1.ETCDSnapshotScheduleStatus{}
+	if status.ETCDSnapshotSchedule != nil {
+		scheduleStatus = status.ETCDSnapshotSchedule.DeepCopy()
+	}
+	if scheduledEtcdSnapshotCreate(current) && status.ETCDSnapshotCreatePhase == rkev1.ETCDSnapshotPhaseFinished &&
+		(scheduleStatus.LastSuccessfulTime == nil || !scheduleStatus.LastSuccessfulTime.Equal(current.ScheduledAt)) {
+		scheduleStatus.LastSuccessfulTime = current.ScheduledAt.DeepCopy()
+		scheduleStatus.LastCompletionTime = &metav1.Time{Time: now}
+	}
+	if scheduleStatus.LastScheduleTime == nil {
+		// snapshots are not created for the times the schedule was due before it was set
+		scheduleStatus.LastScheduleTime = &metav1.Time{Time: now}
+	}
+	status.ETCDSnapshotSchedule = scheduleStatus
+
+	last := scheduleStatus.LastScheduleTime.UTC()
+	due := latestEtcdSnapshotSchedule(cronSchedule, last, now)
+	if due.IsZero() {
+		return request, status, cronSchedule.Next(last), nil
+	}
+	if schedule.Paused {
+		return request, status, time.Time{}, nil
+	}
+	if !ready || etcdSnapshotCreateRequested(request, current) ||
+		etcdSnapshotPhaseInProgress(status.ETCDSnapshotCreatePhase) ||
+		etcdSnapshotPhaseInProgress(status.ETCDSnapshotRestorePhase) ||
+		etcdSnapshotPhaseInProgress(status.ETCDSnapshotUploadPhase) {
+		// the snapshot is created once the control plane is processed after the operation completed
+		return request, status, time.Time{}, nil
+	}
+
+	logrus.Infof("[planner] rkecluster %s/%s: creating etcd snapshot scheduled at %s", cp.Namespace, cp.Name, due.Format(time.RFC3339))
+	scheduleStatus.LastScheduleTime = &metav1.Time{Time: due}
+	request = &rkev1.ETCDSnapshotCreate{
+		ScheduledAt: &metav1.Time{Time: due},
+	}
+	if cp.Spec.ETCDSnapshotCreate != nil {
+		request.Generation = cp.Spec.ETCDSnapshotCreate.Generation
+	}
+	return request, status, cronSchedule.Next(due), nil
+}
+
+// latestEtcdSnapshotSchedule returns the latest time the schedule was due after the last time and before or at now, or
+// the zero time if it was not due since. Snapshots for missed schedule times are not created separately.
+func latestEtcdSnapshotSchedule(schedule cron.Schedule, last, now time.Time) time.Time {
+	var due time.Time
+	for next, i := schedule.Next(last), 0; !next.IsZero() && !next.After(now) && i < maxMissedEtcdSnapshotSchedules; next, i = schedule.Next(next), i+1 {
+		due = next
+	}
+	return due
+}
@@ -0,0 +1,190 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/robfig/cron"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEtcdSnapshotCreateRequest(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 30, 0, 0, time.UTC)
+	at := func(hour, minute int) *metav1.Time {
+		return &metav1.Time{Time: time.Date(2023, 10, 1, hour, minute, 0, 0, time.UTC)}
+	}
+	hourly := &rkev1.ETCDSnapshotSchedule{Cron: "0 * * * *"}
+
+	tests := []struct {
+		name           string
+		spec           rkev1.RKEControlPlaneSpec
+		status         rkev1.RKEControlPlaneStatus
+		notReady       bool
+		expected       *rkev1.ETCDSnapshotCreate
+		expectedStatus *rkev1.ETCDSnapshotScheduleStatus
+		expectedNext   time.Time
+		expectErr      bool
+	}{
+		{
+			name:     "no schedule",
+			spec:     rkev1.RKEControlPlaneSpec{ETCDSnapshotCreate: &rkev1.ETCDSnapshotCreate{Generation: 1}},
+			expected: &rkev1.ETCDSnapshotCreate{Generation: 1},
+		},
+		{
+			name:      "invalid schedule",
+			spec:      rkev1.RKEControlPlaneSpec{ETCDSnapshotSchedule: &rkev1.ETCDSnapshotSchedule{Cron: "every hour"}},
+			expectErr: true,
+		},
+		{
+			name:           "schedule set",
+			spec:           rkev1.RKEControlPlaneSpec{ETCDSnapshotSchedule: hourly},
+			expectedStatus: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: &metav1.Time{Time: now}},
+			expectedNext:   at(13, 0).Time,
+		},
+		{
+			name: "not due",
+			spec: rkev1.RKEControlPlaneSpec{ETCDSnapshotSchedule: hourly},
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotSchedule: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(12, 0)},
+			},
+			expectedStatus: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(12, 0)},
+			expectedNext:   at(13, 0).Time,
+		},
+		{
+			name: "due starts latest missed schedule",
+			spec: rkev1.RKEControlPlaneSpec{
+				ETCDSnapshotSchedule: hourly,
+				ETCDSnapshotCreate:   &rkev1.ETCDSnapshotCreate{Generation: 2},
+			},
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotCreate:      &rkev1.ETCDSnapshotCreate{Generation: 2},
+				ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseFinished,
+				ETCDSnapshotSchedule:    &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(9, 0)},
+			},
+			expected:       &rkev1.ETCDSnapshotCreate{Generation: 2, ScheduledAt: at(12, 0)},
+			expectedStatus: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(12, 0)},
+			expectedNext:   at(13, 0).Time,
+		},
+		{
+			name: "due while not ready",
+			spec: rkev1.RKEControlPlaneSpec{ETCDSnapshotSchedule: hourly},
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotSchedule: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(11, 0)},
+			},
+			notReady:       true,
+			expectedStatus: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(11, 0)},
+		},
+		{
+			name: "due while paused",
+			spec: rkev1.RKEControlPlaneSpec{ETCDSnapshotSchedule: &rkev1.ETCDSnapshotSchedule{Cron: "0 * * * *", Paused: true}},
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotSchedule: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(11, 0)},
+			},
+			expectedStatus: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(11, 0)},
+		},
+		{
+			name: "due while requested snapshot is pending",
+			spec: rkev1.RKEControlPlaneSpec{
+				ETCDSnapshotSchedule: hourly,
+				ETCDSnapshotCreate:   &rkev1.ETCDSnapshotCreate{Generation: 3},
+			},
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotCreate:      &rkev1.ETCDSnapshotCreate{Generation: 2, ScheduledAt: at(10, 0)},
+				ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseFinished,
+				ETCDSnapshotSchedule:    &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(10, 0), LastSuccessfulTime: at(10, 0)},
+			},
+			expected:       &rkev1.ETCDSnapshotCreate{Generation: 3},
+			expectedStatus: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(10, 0), LastSuccessfulTime: at(10, 0)},
+		},
+		{
+			name: "due while restore is in progress",
+			spec: rkev1.RKEControlPlaneSpec{ETCDSnapshotSchedule: hourly},
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotRestorePhase: rkev1.ETCDSnapshotPhaseRestartCluster,
+				ETCDSnapshotSchedule:     &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(11, 0)},
+			},
+			expectedStatus: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(11, 0)},
+		},
+		{
+			name: "scheduled snapshot in progress",
+			spec: rkev1.RKEControlPlaneSpec{ETCDSnapshotSchedule: hourly},
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotCreate:      &rkev1.ETCDSnapshotCreate{ScheduledAt: at(12, 0)},
+				ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseStarted,
+				ETCDSnapshotSchedule:    &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(12, 0)},
+			},
+			expected:       &rkev1.ETCDSnapshotCreate{ScheduledAt: at(12, 0)},
+			expectedStatus: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(12, 0)},
+			expectedNext:   at(13, 0).Time,
+		},
+		{
+			name: "scheduled snapshot finished",
+			spec: rkev1.RKEControlPlaneSpec{
+				ETCDSnapshotSchedule: hourly,
+				ETCDSnapshotCreate:   &rkev1.ETCDSnapshotCreate{Generation: 1},
+			},
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotCreate:      &rkev1.ETCDSnapshotCreate{Generation: 1, ScheduledAt: at(12, 0)},
+				ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseFinished,
+				ETCDSnapshotSchedule:    &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(12, 0), LastSuccessfulTime: at(11, 0)},
+			},
+			expected: &rkev1.ETCDSnapshotCreate{Generation: 1, ScheduledAt: at(12, 0)},
+			expectedStatus: &rkev1.ETCDSnapshotScheduleStatus{
+				LastScheduleTime:   at(12, 0),
+				LastSuccessfulTime: at(12, 0),
+				LastCompletionTime: &metav1.Time{Time: now},
+			},
+			expectedNext: at(13, 0).Time,
+		},
+		{
+			name: "scheduled snapshot failed",
+			spec: rkev1.RKEControlPlaneSpec{ETCDSnapshotSchedule: hourly},
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDSnapshotCreate:      &rkev1.ETCDSnapshotCreate{ScheduledAt: at(12, 0)},
+				ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseFailed,
+				ETCDSnapshotSchedule:    &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(12, 0), LastSuccessfulTime: at(11, 0)},
+			},
+			expected:       &rkev1.ETCDSnapshotCreate{ScheduledAt: at(12, 0)},
+			expectedStatus: &rkev1.ETCDSnapshotScheduleStatus{LastScheduleTime: at(12, 0), LastSuccessfulTime: at(11, 0)},
+			expectedNext:   at(13, 0).Time,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &rkev1.RKEControlPlane{Spec: tt.spec}
+			request, status, next, err := etcdSnapshotCreateRequest(cp, tt.status, !tt.notReady, now)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, request)
+			assert.Equal(t, tt.expectedStatus, status.ETCDSnapshotSchedule)
+			assert.Equal(t, tt.expectedNext, next)
+		})
+	}
+}
+
+func TestLatestEtcdSnapshotSchedule(t *testing.T) {
+	schedule, err := cron.ParseStandard("*/15 * * * *")
+	require.NoError(t, err)
+	last := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.True(t, latestEtcdSnapshotSchedule(schedule, last, last.Add(10*time.Minute)).IsZero())
+	assert.Equal(t, last.Add(15*time.Minute), latestEtcdSnapshotSchedule(schedule, last, last.Add(15*time.Minute)))
+	assert.Equal(t, last.Add(24*time.Hour), latestEtcdSnapshotSchedule(schedule, last, last.Add(24*time.Hour+time.Minute)))
+}
+
+func TestEtcdSnapshotCreateRequested(t *testing.T) {
+	scheduledAt := &metav1.Time{Time: time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)}
+
+	assert.False(t, etcdSnapshotCreateRequested(nil, nil))
+	assert.True(t, etcdSnapshotCreateRequested(&rkev1.ETCDSnapshotCreate{}, nil))
+	assert.False(t, etcdSnapshotCreateRequested(&rkev1.ETCDSnapshotCreate{Generation: 1}, &rkev1.ETCDSnapshotCreate{Generation: 1}))
+	assert.True(t, etcdSnapshotCreateRequested(&rkev1.ETCDSnapshotCreate{Generation: 2}, &rkev1.ETCDSnapshotCreate{Generation: 1}))
+	assert.False(t, etcdSnapshotCreateRequested(&rkev1.ETCDSnapshotCreate{Generation: 1}, &rkev1.ETCDSnapshotCreate{Generation: 1, ScheduledAt: scheduledAt}))
+	assert.True(t, etcdSnapshotCreateRequested(&rkev1.ETCDSnapshotCreate{Generation: 2}, &rkev1.ETCDSnapshotCreate{Generation: 1, ScheduledAt: scheduledAt}))
+}
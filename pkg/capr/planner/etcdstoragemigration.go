@@ -0,0 +1,226 @@
+package planner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	storageMigrationInstructionName = "storage-version-migration"
+	storageMigrationScriptPath      = "rancher_v2prov_etcd_snapshot/bin/migrate-storage.sh"
+
+	// storageMigrationScript rewrites every object of all resources that can be listed and patched through the local
+	// kube-apiserver with an empty patch, so that it is stored in the storage version of the running version of
+	// Kubernetes. Objects are patched one at a time, so that a large resource is not held in memory and a concurrent
+	// change of an object does not fail the whole resource, and objects deleted in the meantime are ignored. Events and
+	// leases are skipped, as they are short-lived and rewritten constantly anyway. For each resource, a line with its
+	// name is printed, prefixed by whether it was migrated.
+	storageMigrationScript = `#!/bin/sh
+
+runtime=$1
+
+if [ "$runtime" = "k3s" ]; then
+	kubectl="k3s kubectl"
+else
+	kubectl="/var/lib/rancher/$runtime/bin/kubectl"
+fi
+export KUBECONFIG=/etc/rancher/$runtime/$runtime.yaml
+
+resources=$($kubectl api-resources --verbs=list,patch -o name)
+if [ -z "$resources" ]; then
+	echo "failed to list the API resources" >&2
+	exit 1
+fi
+
+failed=0
+for resource in $resources; do
+	case "$resource" in
+	events|events.events.k8s.io|leases.coordination.k8s.io)
+		continue
+		;;
+	esac
+	objects=$($kubectl get "$resource" --all-namespaces --no-headers -o custom-columns=NAMESPACE:.metadata.namespace,NAME:.metadata.name 2>/dev/null)
+	if [ -z "$objects" ]; then
+		continue
+	fi
+	migrated=1
+	while read -r ns name; do
+		if [ "$ns" = "<none>" ]; then
+			set -- "$resource" "$name"
+		else
+			set -- "$resource" "$name" -n "$ns"
+		fi
+		if ! out=$($kubectl patch "$@" --type=merge -p '{}' 2>&1 >/dev/null); then
+			case "$out" in
+			*NotFound*|*"not found"*)
+				;;
+			*)
+				echo "$out" >&2
+				migrated=0
+				;;
+			esac
+		fi
+	done <<-EOF
+	$objects
+	EOF
+	if [ $migrated -eq 1 ]; then
+		echo "migrated $resource"
+	else
+		echo "failed $resource"
+		failed=1
+	fi
+done
+exit $failed
+`
+)
+
+// etcdSnapshotStorageMigration returns the Kubernetes version the etcd snapshot was taken with, and whether the objects
+// of the snapshot have to be migrated to the storage versions of the Kubernetes version of the control plane after it
+// is restored, which is the case if the snapshot was taken with an older minor version.
+func etcdSnapshotStorageMigration(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot) (string, bool) {
+	if snapshot == nil {
+		return "", false
+	}
+	var version string
+	if metadata, err := capr.ParseSnapshotMetadata(snapshot); err == nil {
+		version = metadata[capr.SnapshotMetadataKubernetesVersion]
+	}
+	if version == "" {
+		if clusterSpec, err := capr.ParseSnapshotClusterSpecOrError(snapshot); err == nil && clusterSpec != nil {
+			version = clusterSpec.KubernetesVersion
+		}
+	}
+
+	snapshotVersion, err := semver.NewVersion(version)
+	if err != nil {
+		return "", false
+	}
+	currentVersion, err := semver.NewVersion(controlPlane.Spec.KubernetesVersion)
+	if err != nil {
+		return "", false
+	}
+	return snapshotVersion.Original(), snapshotVersion.Major() == currentVersion.Major() && snapshotVersion.Minor() < currentVersion.Minor()
+}
+
+// checkEtcdSnapshotStorageMigration refuses to restore an etcd snapshot taken with an older minor version of Kubernetes,
+// unless the restore migrates the storage versions of its objects afterwards. Otherwise, the restored cluster would be
+// left with objects stored in the storage versions of different versions of Kubernetes.
+func checkEtcdSnapshotStorageMigration(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
+	from, required := etcdSnapshotStorageMigration(controlPlane, snapshot)
+	if !required {
+		if capr.StorageVersionsMigrated.GetStatus(&status) != "" {
+			capr.StorageVersionsMigrated.True(&status)
+			capr.StorageVersionsMigrated.Reason(&status, "")
+			capr.StorageVersionsMigrated.Message(&status, "")
+		}
+		return status, nil
+	}
+
+	capr.StorageVersionsMigrated.False(&status)
+	if !controlPlane.Spec.ETCDSnapshotRestore.MigrateStorageVersions {
+		message := fmt.Sprintf("etcd snapshot was taken with Kubernetes %s and requires a storage version migration to %s, which must be allowed by setting migrateStorageVersions", from, controlPlane.Spec.KubernetesVersion)
+		capr.StorageVersionsMigrated.Reason(&status, "MigrationRequired")
+		capr.StorageVersionsMigrated.Message(&status, message)
+		return status, errWaiting(message)
+	}
+	capr.StorageVersionsMigrated.Reason(&status, "MigrationPending")
+	capr.StorageVersionsMigrated.Message(&status, fmt.Sprintf("storage versions will be migrated from Kubernetes %s to %s once the cluster is restored", from, controlPlane.Spec.KubernetesVersion))
+	return status, nil
+}
+
+// runEtcdSnapshotStorageMigration delivers a plan to migrate the objects of the restored etcd snapshot to the storage
+// versions of the Kubernetes version of the control plane to a control plane node, and records the outcome in the
+// StorageVersionsMigrated condition. As the cluster is already restored, a failed migration does not fail the restore.
+func (p *Planner) runEtcdSnapshotStorageMigration(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot, status rkev1.RKEControlPlaneStatus, tokensSecret plan.Secret, clusterPlan *plan.Plan) (rkev1.RKEControlPlaneStatus, error) {
+	from, required := etcdSnapshotStorageMigration(controlPlane, snapshot)
+	if !required {
+		return status, nil
+	}
+	servers := collect(clusterPlan, roleAnd(isControlPlane, isNotDeleting))
+	if len(servers) == 0 {
+		return status, errors.New("failed to find control plane node to perform storage version migration")
+	}
+	_, joinServer, _, err := p.findInitNode(controlPlane, clusterPlan)
+	if err != nil {
+		return status, err
+	}
+
+	server := servers[0]
+	migrationPlan, joinedServer, err := p.generateStorageMigrationPlan(controlPlane, tokensSecret, server, joinServer)
+	if err != nil {
+		return status, err
+	}
+	msg := fmt.Sprintf("storage version migration on machine %s/%s", server.Machine.Namespace, server.Machine.Name)
+	err = assignAndCheckPlan(p.store, msg, server, migrationPlan, joinedServer, 1, 1)
+	if IsErrWaiting(err) {
+		return status, err
+	}
+
+	migrated, failed := storageMigrationResult(server.Plan.Output[storageMigrationInstructionName])
+	if err != nil {
+		message := fmt.Sprintf("failed to migrate storage versions from Kubernetes %s to %s", from, controlPlane.Spec.KubernetesVersion)
+		if len(failed) > 0 {
+			message = fmt.Sprintf("%s for %s", message, strings.Join(failed, ", "))
+		}
+		logrus.Warnf("[planner] rkecluster %s/%s: %s: %v", controlPlane.Namespace, controlPlane.Name, message, err)
+		capr.StorageVersionsMigrated.False(&status)
+		capr.StorageVersionsMigrated.Reason(&status, "MigrationFailed")
+		capr.StorageVersionsMigrated.Message(&status, message)
+		return status, nil
+	}
+
+	logrus.Infof("[planner] rkecluster %s/%s: migrated storage versions of %d resources from Kubernetes %s to %s", controlPlane.Namespace, controlPlane.Name, len(migrated), from, controlPlane.Spec.KubernetesVersion)
+	capr.StorageVersionsMigrated.True(&status)
+	capr.StorageVersionsMigrated.Reason(&status, "")
+	capr.StorageVersionsMigrated.Message(&status, fmt.Sprintf("migrated storage versions of %d resources from Kubernetes %s to %s", len(migrated), from, controlPlane.Spec.KubernetesVersion))
+	return status, nil
+}
+
+// generateStorageMigrationPlan generates a plan that contains an instruction to migrate the storage versions of all
+// objects of the cluster.
+func (p *Planner) generateStorageMigrationPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, entry *planEntry, joinServer string) (plan.NodePlan, string, error) {
+	migrationPlan, _, joinedServer, err := p.generatePlanWithConfigFiles(controlPlane, tokensSecret, entry, joinServer)
+	if err != nil {
+		return migrationPlan, joinedServer, err
+	}
+	scriptPath := etcdSnapshotScriptFile(controlPlane, storageMigrationScriptPath)
+	migrationPlan.Files = append(migrationPlan.Files, plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(storageMigrationScript)),
+		Path:    scriptPath,
+	})
+	migrationPlan.Instructions = append(migrationPlan.Instructions, plan.OneTimeInstruction{
+		Name:       storageMigrationInstructionName,
+		Command:    "sh",
+		Args:       []string{scriptPath, capr.GetRuntime(controlPlane.Spec.KubernetesVersion)},
+		SaveOutput: true,
+	})
+	return migrationPlan, joinedServer, nil
+}
+
+// storageMigrationResult returns the resources the storage version migration migrated and failed to migrate.
+func storageMigrationResult(output []byte) (migrated, failed []string) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "migrated":
+			migrated = append(migrated, fields[1])
+		case "failed":
+			failed = append(failed, fields[1])
+		}
+	}
+	return migrated, failed
+}
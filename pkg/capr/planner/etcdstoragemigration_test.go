@@ -0,0 +1,89 @@
+package planner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckEtcdSnapshotStorageMigration(t *testing.T) {
+	newSnapshot := func(version string) *rkev1.ETCDSnapshot {
+		data, err := json.Marshal(map[string]string{capr.SnapshotMetadataKubernetesVersion: version})
+		assert.NoError(t, err)
+		return &rkev1.ETCDSnapshot{
+			SnapshotFile: rkev1.ETCDSnapshotFile{Metadata: base64.StdEncoding.EncodeToString(data)},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		snapshot        *rkev1.ETCDSnapshot
+		migrate         bool
+		expectedStatus  string
+		expectedReason  string
+		expectedMessage string
+		expectErr       bool
+	}{
+		{
+			name:     "snapshot of the same minor version",
+			snapshot: newSnapshot("v1.26.1+rke2r1"),
+		},
+		{
+			name:     "snapshot of a newer version",
+			snapshot: newSnapshot("v1.27.2+rke2r1"),
+		},
+		{
+			name:     "snapshot without metadata",
+			snapshot: &rkev1.ETCDSnapshot{},
+		},
+		{
+			name: "local snapshot without a CR",
+		},
+		{
+			name:            "snapshot of an older minor version",
+			snapshot:        newSnapshot("v1.25.9+rke2r1"),
+			expectedStatus:  "False",
+			expectedReason:  "MigrationRequired",
+			expectedMessage: "etcd snapshot was taken with Kubernetes v1.25.9+rke2r1 and requires a storage version migration to v1.26.4+rke2r1, which must be allowed by setting migrateStorageVersions",
+			expectErr:       true,
+		},
+		{
+			name:            "migration allowed",
+			snapshot:        newSnapshot("v1.25.9+rke2r1"),
+			migrate:         true,
+			expectedStatus:  "False",
+			expectedReason:  "MigrationPending",
+			expectedMessage: "storage versions will be migrated from Kubernetes v1.25.9+rke2r1 to v1.26.4+rke2r1 once the cluster is restored",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlPlane := &rkev1.RKEControlPlane{
+				Spec: rkev1.RKEControlPlaneSpec{
+					KubernetesVersion:   "v1.26.4+rke2r1",
+					ETCDSnapshotRestore: &rkev1.ETCDSnapshotRestore{Name: "snapshot", MigrateStorageVersions: tt.migrate},
+				},
+			}
+			status, err := checkEtcdSnapshotStorageMigration(controlPlane, tt.snapshot, rkev1.RKEControlPlaneStatus{})
+			if tt.expectErr {
+				assert.True(t, IsErrWaiting(err))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedStatus, capr.StorageVersionsMigrated.GetStatus(&status))
+			assert.Equal(t, tt.expectedReason, capr.StorageVersionsMigrated.GetReason(&status))
+			assert.Equal(t, tt.expectedMessage, capr.StorageVersionsMigrated.GetMessage(&status))
+		})
+	}
+}
+
+func TestStorageMigrationResult(t *testing.T) {
+	migrated, failed := storageMigrationResult([]byte("migrated deployments.apps\nfailed widgets.example.com\nError from server (Conflict)\nmigrated secrets\n"))
+	assert.Equal(t, []string{"deployments.apps", "secrets"}, migrated)
+	assert.Equal(t, []string{"widgets.example.com"}, failed)
+}
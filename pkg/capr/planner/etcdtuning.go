@@ -0,0 +1,92 @@
+package planner
+
+import (
+	"fmt"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+)
+
+// The bounds of the etcd tuning of a cluster. Values outside of them are either rejected by etcd or are known to leave
+// clusters unable to elect a leader or to serve requests.
+const (
+	minETCDQuotaBackendBytes     = 1 << 30
+	maxETCDQuotaBackendBytes     = 8 << 30
+	minETCDHeartbeatInterval     = 10
+	maxETCDHeartbeatInterval     = 1000
+	minETCDElectionTimeoutFactor = 5
+	maxETCDElectionTimeout       = 50000
+	minETCDSnapshotCount         = 1000
+	maxETCDSnapshotCount         = 1000000
+	defaultETCDHeartbeatInterval = 100
+	defaultETCDElectionTimeout   = 1000
+	etcdArg                      = "etcd-arg"
+	etcdQuotaBackendBytesFlag    = "quota-backend-bytes"
+	etcdHeartbeatIntervalFlag    = "heartbeat-interval"
+	etcdElectionTimeoutFlag      = "election-timeout"
+	etcdSnapshotCountFlag        = "snapshot-count"
+)
+
+// validateETCDTuning returns an error if a value of the etcd tuning is out of bounds, or if the heartbeat interval and
+// election timeout do not fit together, taking the defaults of etcd into account for the one that is not set.
+func validateETCDTuning(tuning *rkev1.ETCDTuning) error {
+	if tuning.QuotaBackendBytes != 0 && (tuning.QuotaBackendBytes < minETCDQuotaBackendBytes || tuning.QuotaBackendBytes > maxETCDQuotaBackendBytes) {
+		return fmt.Errorf("etcd quotaBackendBytes %d must be between %d and %d", tuning.QuotaBackendBytes, int64(minETCDQuotaBackendBytes), int64(maxETCDQuotaBackendBytes))
+	}
+	if tuning.HeartbeatIntervalMilliseconds != 0 && (tuning.HeartbeatIntervalMilliseconds < minETCDHeartbeatInterval || tuning.HeartbeatIntervalMilliseconds > maxETCDHeartbeatInterval) {
+		return fmt.Errorf("etcd heartbeatIntervalMilliseconds %d must be between %d and %d", tuning.HeartbeatIntervalMilliseconds, minETCDHeartbeatInterval, maxETCDHeartbeatInterval)
+	}
+	if tuning.ElectionTimeoutMilliseconds != 0 || tuning.HeartbeatIntervalMilliseconds != 0 {
+		heartbeatInterval := tuning.HeartbeatIntervalMilliseconds
+		if heartbeatInterval == 0 {
+			heartbeatInterval = defaultETCDHeartbeatInterval
+		}
+		electionTimeout := tuning.ElectionTimeoutMilliseconds
+		if electionTimeout == 0 {
+			electionTimeout = defaultETCDElectionTimeout
+		}
+		if electionTimeout < minETCDElectionTimeoutFactor*heartbeatInterval || electionTimeout > maxETCDElectionTimeout {
+			return fmt.Errorf("etcd electionTimeoutMilliseconds %d must be between %d (%d times the heartbeat interval) and %d", electionTimeout, minETCDElectionTimeoutFactor*heartbeatInterval, minETCDElectionTimeoutFactor, maxETCDElectionTimeout)
+		}
+	}
+	if tuning.SnapshotCount != 0 && (tuning.SnapshotCount < minETCDSnapshotCount || tuning.SnapshotCount > maxETCDSnapshotCount) {
+		return fmt.Errorf("etcd snapshotCount %d must be between %d and %d", tuning.SnapshotCount, minETCDSnapshotCount, maxETCDSnapshotCount)
+	}
+	return nil
+}
+
+// etcdTuningArgs returns the etcd args for the set values of the etcd tuning.
+func etcdTuningArgs(tuning *rkev1.ETCDTuning) []string {
+	var args []string
+	if tuning.QuotaBackendBytes != 0 {
+		args = append(args, fmt.Sprintf("%s=%d", etcdQuotaBackendBytesFlag, tuning.QuotaBackendBytes))
+	}
+	if tuning.HeartbeatIntervalMilliseconds != 0 {
+		args = append(args, fmt.Sprintf("%s=%d", etcdHeartbeatIntervalFlag, tuning.HeartbeatIntervalMilliseconds))
+	}
+	if tuning.ElectionTimeoutMilliseconds != 0 {
+		args = append(args, fmt.Sprintf("%s=%d", etcdElectionTimeoutFlag, tuning.ElectionTimeoutMilliseconds))
+	}
+	if tuning.SnapshotCount != 0 {
+		args = append(args, fmt.Sprintf("%s=%d", etcdSnapshotCountFlag, tuning.SnapshotCount))
+	}
+	return args
+}
+
+// addETCDTuning adds the args of the etcd tuning of the cluster to the etcd args of the config. Flags that are also
+// set through the etcd-arg of the machine config are reported as conflicting by lintConfig, so the typed fields can
+// not be silently overridden. Etcd is restarted to apply a change, which the etcd tier reconciles one node at a time
+// and only while all etcd nodes are healthy.
+func addETCDTuning(config map[string]interface{}, tuning *rkev1.ETCDTuning) error {
+	if tuning == nil {
+		return nil
+	}
+	if err := validateETCDTuning(tuning); err != nil {
+		return err
+	}
+	args := etcdTuningArgs(tuning)
+	if len(args) == 0 {
+		return nil
+	}
+	config[etcdArg] = append(append([]string{}, convertInterfaceToStringSlice(config[etcdArg])...), args...)
+	return nil
+}
@@ -0,0 +1,76 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddETCDTuning(t *testing.T) {
+	tests := []struct {
+		name         string
+		tuning       *rkev1.ETCDTuning
+		config       map[string]interface{}
+		expectedArgs interface{}
+		expectedErr  string
+	}{
+		{
+			name:   "no tuning",
+			config: map[string]interface{}{},
+		},
+		{
+			name: "all fields",
+			tuning: &rkev1.ETCDTuning{
+				QuotaBackendBytes:             4 << 30,
+				HeartbeatIntervalMilliseconds: 250,
+				ElectionTimeoutMilliseconds:   2500,
+				SnapshotCount:                 50000,
+			},
+			config:       map[string]interface{}{},
+			expectedArgs: []string{"quota-backend-bytes=4294967296", "heartbeat-interval=250", "election-timeout=2500", "snapshot-count=50000"},
+		},
+		{
+			name:         "appended to the etcd args of the machine config",
+			tuning:       &rkev1.ETCDTuning{SnapshotCount: 50000},
+			config:       map[string]interface{}{"etcd-arg": []interface{}{"auto-compaction-retention=2"}},
+			expectedArgs: []string{"auto-compaction-retention=2", "snapshot-count=50000"},
+		},
+		{
+			name:        "quota too large",
+			tuning:      &rkev1.ETCDTuning{QuotaBackendBytes: 16 << 30},
+			config:      map[string]interface{}{},
+			expectedErr: "etcd quotaBackendBytes 17179869184 must be between 1073741824 and 8589934592",
+		},
+		{
+			name:        "heartbeat interval too short",
+			tuning:      &rkev1.ETCDTuning{HeartbeatIntervalMilliseconds: 1},
+			config:      map[string]interface{}{},
+			expectedErr: "etcd heartbeatIntervalMilliseconds 1 must be between 10 and 1000",
+		},
+		{
+			name:        "heartbeat interval too long for the default election timeout",
+			tuning:      &rkev1.ETCDTuning{HeartbeatIntervalMilliseconds: 500},
+			config:      map[string]interface{}{},
+			expectedErr: "etcd electionTimeoutMilliseconds 1000 must be between 2500 (5 times the heartbeat interval) and 50000",
+		},
+		{
+			name:        "snapshot count too low",
+			tuning:      &rkev1.ETCDTuning{SnapshotCount: 10},
+			config:      map[string]interface{}{},
+			expectedErr: "etcd snapshotCount 10 must be between 1000 and 1000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := addETCDTuning(tt.config, tt.tuning)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedArgs, tt.config["etcd-arg"])
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package planner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/merr"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ETCDSnapshotUploadInstruction is the name of the instruction that uploads the local etcd snapshots of a node to
+	// S3. Its output lists the snapshots that are stored in S3, so that they can be recorded as S3 etcd snapshots.
+	ETCDSnapshotUploadInstruction = "etcd-snapshot-s3-upload"
+
+	etcdSnapshotUploadScriptPath = "rancher_v2prov_etcd_snapshot/bin/upload.sh"
+
+	defaultS3Endpoint = "s3.amazonaws.com"
+	defaultS3Region   = "us-east-1"
+
+	// etcdSnapshotUploadScript uploads the snapshot files in the snapshot directory, and the metadata the distribution
+	// saved next to them, that do not exist in the bucket yet. The requests are signed with the credentials passed in
+	// through the environment, which are handed to curl on stdin to keep them out of the process list. Snapshots are
+	// written with the storage class if one is passed, which the distribution does not support. The bucket URLs
+	// of the endpoints are passed last, and a request that can not reach an endpoint is retried against the next one,
+	// which is used for all following requests. For each snapshot in the bucket, a line with its name, size and
+	// modification time is printed, prefixed by whether it was uploaded.
+	etcdSnapshotUploadScript = `
+#!/bin/sh
+
+dir=$1
+folder=$2
+region=$3
+ca=$4
+insecure=$5
+storage_class=$6
+shift 6
+urls="$*"
+
+if ! curl --help all 2>/dev/null | grep -q -- --aws-sigv4; then
+	echo "curl 7.75 or later is required to upload etcd snapshots to S3" >&2
+	exit 1
+fi
+if [ ! -d "$dir" ]; then
+	echo "etcd snapshot directory $dir does not exist" >&2
+	exit 1
+fi
+
+tls=""
+if [ "$insecure" = "true" ]; then
+	tls="--insecure"
+elif [ -n "$ca" ]; then
+	tls="--cacert $ca"
+fi
+prefix=""
+if [ -n "$folder" ]; then
+	prefix="$folder/"
+fi
+class=""
+if [ -n "$storage_class" ]; then
+	class="-H x-amz-storage-class:$storage_class"
+fi
+token=""
+if [ -n "$AWS_SESSION_TOKEN" ]; then
+	token="-H x-amz-security-token:$AWS_SESSION_TOKEN"
+fi
+
+s3() {
+	key=$1
+	shift
+	while [ -n "$urls" ]; do
+		url=${urls%% *}
+		printf 'user = "%s:%s"\n' "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY" |
+			curl -K - --fail --silent --show-error --output /dev/null --aws-sigv4 "aws:amz:$region:s3" $tls $token "$@" "$url/$prefix$key"
+		rc=$?
+		case $rc in
+		5 | 6 | 7 | 28 | 35) ;;
+		*) return $rc ;;
+		esac
+		echo "etcd snapshot S3 endpoint $url is unreachable" >&2
+		if [ "$url" = "$urls" ]; then
+			urls=""
+		else
+			urls=${urls#* }
+		fi
+	done
+	return 1
+}
+
+metadata="$(dirname "$dir")/.metadata"
+failed=0
+for file in "$dir"/*; do
+	[ -f "$file" ] || continue
+	name=$(basename "$file")
+	case "$name" in
+	*[!A-Za-z0-9._-]*)
+		echo "skipping etcd snapshot $name as its name is not a valid S3 key" >&2
+		continue
+		;;
+	esac
+	line="$name $(($(wc -c < "$file"))) $(date -r "$file" +%s)"
+	if s3 "$name" --head 2>/dev/null; then
+		echo "exists $line"
+		continue
+	fi
+	if ! s3 "$name" --upload-file "$file" $class; then
+		echo "failed to upload etcd snapshot $name" >&2
+		failed=1
+		continue
+	fi
+	if [ -f "$metadata/$name" ] && ! s3 ".metadata/$name" --upload-file "$metadata/$name" $class; then
+		echo "failed to upload metadata of etcd snapshot $name" >&2
+	fi
+	echo "uploaded $line"
+done
+exit $failed
+`
+
+	etcdSnapshotDownloadInstructionName = "etcd-snapshot-s3-download"
+	etcdSnapshotDownloadScriptPath      = "rancher_v2prov_etcd_snapshot/bin/download.sh"
+
+	// etcdSnapshotDownloadScript downloads a snapshot from the bucket to the passed in path, signing the requests in the
+	// same way as the upload. The endpoints are tried in order until the snapshot was downloaded from one of them.
+	etcdSnapshotDownloadScript = `
+#!/bin/sh
+
+name=$1
+folder=$2
+region=$3
+ca=$4
+insecure=$5
+out=$6
+shift 6
+
+if ! curl --help all 2>/dev/null | grep -q -- --aws-sigv4; then
+	echo "curl 7.75 or later is required to download etcd snapshots from S3" >&2
+	exit 1
+fi
+
+tls=""
+if [ "$insecure" = "true" ]; then
+	tls="--insecure"
+elif [ -n "$ca" ]; then
+	tls="--cacert $ca"
+fi
+prefix=""
+if [ -n "$folder" ]; then
+	prefix="$folder/"
+fi
+token=""
+if [ -n "$AWS_SESSION_TOKEN" ]; then
+	token="-H x-amz-security-token:$AWS_SESSION_TOKEN"
+fi
+
+rm -f "$out"
+mkdir -p "$(dirname "$out")" || exit 1
+for url in "$@"; do
+	if printf 'user = "%s:%s"\n' "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY" |
+		curl -K - --fail --silent --show-error --output "$out" --aws-sigv4 "aws:amz:$region:s3" $tls $token "$url/$prefix$name"; then
+		echo "downloaded etcd snapshot $name from $url"
+		exit 0
+	fi
+done
+rm -f "$out"
+echo "failed to download etcd snapshot $name" >&2
+exit 1
+`
+)
+
+func (p *Planner) setEtcdSnapshotUploadState(status rkev1.RKEControlPlaneStatus, upload *rkev1.ETCDSnapshotUpload, phase rkev1.ETCDSnapshotPhase) (rkev1.RKEControlPlaneStatus, error) {
+	if etcdSnapshotUploadStateHash(status.ETCDSnapshotUpload, status.ETCDSnapshotUploadPhase) != etcdSnapshotUploadStateHash(upload, phase) {
+		status.ETCDSnapshotUploadPhase = phase
+		status.ETCDSnapshotUpload = upload
+		return status, errWaiting("refreshing etcd upload state")
+	}
+	return status, nil
+}
+
+func (p *Planner) resetEtcdSnapshotUploadState(status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
+	if status.ETCDSnapshotUpload == nil && status.ETCDSnapshotUploadPhase == "" {
+		return status, nil
+	}
+	return p.setEtcdSnapshotUploadState(status, nil, "")
+}
+
+// uploadEtcdSnapshots uploads the local etcd snapshots of all etcd nodes to the S3 bucket of the etcd settings when the
+// generation of the etcd snapshot upload changes. Like the snapshot creation, the upload replaces the plans of the etcd
+// nodes, which are restored once all nodes uploaded their snapshots.
+func (p *Planner) uploadEtcdSnapshots(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, tokensSecret plan.Secret, clusterPlan *plan.Plan) (rkev1.RKEControlPlaneStatus, error) {
+	var err error
+	if controlPlane.Spec.ETCDSnapshotUpload == nil {
+		return p.resetEtcdSnapshotUploadState(status)
+	}
+
+	// Don't upload etcd snapshots if the cluster is not initialized or bootstrapped.
+	if !status.Initialized || !capr.Bootstrapped.IsTrue(&status) {
+		return status, nil
+	}
+
+	found, joinServer, _, err := p.findInitNode(controlPlane, clusterPlan)
+	if err != nil {
+		logrus.Errorf("[planner] rkecluster %s/%s: error encountered while searching for init node during etcd snapshot upload: %v", controlPlane.Namespace, controlPlane.Name, err)
+		return status, err
+	}
+	if !found || joinServer == "" {
+		logrus.Warnf("[planner] rkecluster %s/%s: skipping etcd snapshot upload as cluster does not have an init node", controlPlane.Namespace, controlPlane.Name)
+		return status, nil
+	}
+
+	upload := controlPlane.Spec.ETCDSnapshotUpload
+	if status.ETCDSnapshotUpload == nil || *status.ETCDSnapshotUpload != *upload {
+		return p.setEtcdSnapshotUploadState(status, upload, rkev1.ETCDSnapshotPhaseStarted)
+	}
+
+	switch controlPlane.Status.ETCDSnapshotUploadPhase {
+	case rkev1.ETCDSnapshotPhaseStarted:
+		if errs := p.runEtcdSnapshotUpload(controlPlane, tokensSecret, clusterPlan, joinServer); len(errs) > 0 {
+			failed := false
+			for _, err := range errs {
+				if !IsErrWaiting(err) {
+					logrus.Errorf("[planner] rkecluster %s/%s: etcd snapshot upload failed: %v", controlPlane.Namespace, controlPlane.Name, err)
+					failed = true
+				}
+			}
+			if failed {
+				if status, err = p.setEtcdSnapshotUploadState(status, upload, rkev1.ETCDSnapshotPhaseFailed); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			return status, errWaiting(merr.NewErrors(errs...).Error())
+		}
+		return p.setEtcdSnapshotUploadState(status, upload, rkev1.ETCDSnapshotPhaseRestartCluster)
+	case rkev1.ETCDSnapshotPhaseRestartCluster:
+		if err = p.runEtcdSnapshotManagementServiceStart(controlPlane, tokensSecret, clusterPlan, isEtcd, "etcd snapshot upload"); err != nil {
+			return status, err
+		}
+		return p.setEtcdSnapshotUploadState(status, upload, rkev1.ETCDSnapshotPhaseFinished)
+	case rkev1.ETCDSnapshotPhaseFailed, rkev1.ETCDSnapshotPhaseFinished:
+		return status, nil
+	default:
+		return p.setEtcdSnapshotUploadState(status, upload, rkev1.ETCDSnapshotPhaseStarted)
+	}
+}
+
+func (p *Planner) runEtcdSnapshotUpload(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, clusterPlan *plan.Plan, joinServer string) []error {
+	if controlPlane.Spec.ETCD == nil || !S3Enabled(controlPlane.Spec.ETCD.S3) {
+		return []error{fmt.Errorf("etcd snapshots can not be uploaded as S3 is not configured for the cluster")}
+	}
+	config, err := GetS3Config(p.secretCache, controlPlane.Spec.ETCD.S3, controlPlane)
+	if err != nil {
+		return []error{err}
+	}
+	instruction, files, err := etcdSnapshotUploadInstruction(controlPlane, config, controlPlane.Spec.ETCD.S3.FallbackEndpoints)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, server := range collect(clusterPlan, roleAnd(isEtcd, isNotDeleting)) {
+		uploadPlan, _, joinedServer, err := p.generatePlanWithConfigFiles(controlPlane, tokensSecret, server, joinServer)
+		if err != nil {
+			return []error{err}
+		}
+		uploadPlan.Files = append(uploadPlan.Files, files...)
+		uploadPlan.Files = append(uploadPlan.Files, plan.File{
+			Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotUploadScript)),
+			Path:    etcdSnapshotScriptFile(controlPlane, etcdSnapshotUploadScriptPath),
+		})
+		uploadPlan.Instructions = append(uploadPlan.Instructions, p.generateInstallInstructionWithSkipStart(controlPlane, server), instruction)
+
+		msg := fmt.Sprintf("etcd snapshot upload on machine %s/%s", server.Machine.Namespace, server.Machine.Name)
+		if server.Machine.Status.NodeRef != nil && server.Machine.Status.NodeRef.Name != "" {
+			msg = fmt.Sprintf("etcd snapshot upload on node %s", server.Machine.Status.NodeRef.Name)
+		}
+		if err = assignAndCheckPlan(p.store, msg, server, uploadPlan, joinedServer, 3, 3); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// etcdSnapshotUploadInstruction generates the instruction that uploads the local etcd snapshots to the bucket of the
+// passed in S3 configuration, falling back to the fallback endpoints in order when an endpoint is unreachable, and the
+// file of the endpoint CA the uploads are verified with. The upload signs its requests itself, so unlike the
+// distribution it requires the access and secret key of a cloud credential.
+func etcdSnapshotUploadInstruction(controlPlane *rkev1.RKEControlPlane, config S3Config, fallbackEndpoints []string) (plan.OneTimeInstruction, []plan.File, error) {
+	storageClass := ""
+	if controlPlane.Spec.ETCD != nil && controlPlane.Spec.ETCD.S3 != nil && controlPlane.Spec.ETCD.S3.StorageClass != "" {
+		storageClass = controlPlane.Spec.ETCD.S3.StorageClass
+		if err := validateS3StorageClass(storageClass); err != nil {
+			return plan.OneTimeInstruction{}, nil, err
+		}
+	}
+
+	urls, caPath, files, env, err := etcdSnapshotS3Access(controlPlane, config, fallbackEndpoints, "uploaded")
+	if err != nil {
+		return plan.OneTimeInstruction{}, nil, err
+	}
+
+	return plan.OneTimeInstruction{
+		Name:    ETCDSnapshotUploadInstruction,
+		Command: "sh",
+		Args: append([]string{
+			etcdSnapshotScriptFile(controlPlane, etcdSnapshotUploadScriptPath),
+			etcdSnapshotDir(controlPlane),
+			config.Folder,
+			first(config.Region, defaultS3Region),
+			caPath,
+			strconv.FormatBool(config.SkipSSLVerify),
+			storageClass,
+		}, urls...),
+		Env:        env,
+		SaveOutput: true,
+	}, files, nil
+}
+
+// etcdSnapshotS3DownloadInstruction generates the instruction that downloads the named snapshot from the bucket of the
+// passed in S3 configuration to the passed in path, and the files it requires. Like the upload, it requires the access
+// and secret key of a cloud credential.
+func etcdSnapshotS3DownloadInstruction(controlPlane *rkev1.RKEControlPlane, config S3Config, snapshotName, out string) (plan.OneTimeInstruction, []plan.File, error) {
+	urls, caPath, files, env, err := etcdSnapshotS3Access(controlPlane, config, nil, "downloaded")
+	if err != nil {
+		return plan.OneTimeInstruction{}, nil, err
+	}
+	files = append(files, plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotDownloadScript)),
+		Path:    etcdSnapshotScriptFile(controlPlane, etcdSnapshotDownloadScriptPath),
+	})
+	return plan.OneTimeInstruction{
+		Name:    etcdSnapshotDownloadInstructionName,
+		Command: "sh",
+		Args: append([]string{
+			etcdSnapshotScriptFile(controlPlane, etcdSnapshotDownloadScriptPath),
+			snapshotName,
+			config.Folder,
+			first(config.Region, defaultS3Region),
+			caPath,
+			strconv.FormatBool(config.SkipSSLVerify),
+			out,
+		}, urls...),
+		Env:        env,
+		SaveOutput: true,
+	}, files, nil
+}
+
+// etcdSnapshotS3Access returns the bucket URLs of the endpoint and the fallback endpoints of the passed in S3
+// configuration, the path of the endpoint CA file and the files that deliver it, and the environment that passes the
+// credentials to the scripts that sign their requests themselves. The verb describes the transfer in errors.
+func etcdSnapshotS3Access(controlPlane *rkev1.RKEControlPlane, config S3Config, fallbackEndpoints []string, verb string) ([]string, string, []plan.File, []string, error) {
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, "", nil, nil, fmt.Errorf("etcd snapshots can not be %s without the access and secret key of an S3 cloud credential", verb)
+	}
+	if config.Bucket == "" {
+		return nil, "", nil, nil, fmt.Errorf("etcd snapshots can not be %s as no S3 bucket is configured", verb)
+	}
+
+	var urls []string
+	for _, endpoint := range append([]string{first(config.Endpoint, defaultS3Endpoint)}, fallbackEndpoints...) {
+		if strings.ContainsAny(endpoint, " \t\n") {
+			return nil, "", nil, nil, fmt.Errorf("invalid etcd snapshot S3 endpoint %q", endpoint)
+		}
+		if endpoint == "" {
+			continue
+		}
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "https://" + endpoint
+		}
+		urls = append(urls, strings.TrimSuffix(endpoint, "/")+"/"+config.Bucket)
+	}
+
+	var files []plan.File
+	caPath := ""
+	if config.EndpointCA != "" && !config.SkipSSLVerify {
+		ca, err := normalizeEndpointCA(config.EndpointCA)
+		if err != nil {
+			return nil, "", nil, nil, fmt.Errorf("invalid etcd snapshot S3 endpoint CA: %w", err)
+		}
+		caPath = configFile(controlPlane, fmt.Sprintf("s3-endpoint-ca-%s.crt", name.Hex(config.EndpointCA, 5)))
+		files = append(files, plan.File{
+			Content: base64.StdEncoding.EncodeToString([]byte(ca)),
+			Path:    caPath,
+		})
+	}
+
+	env := []string{
+		"AWS_ACCESS_KEY_ID=" + config.AccessKey,
+		"AWS_SECRET_ACCESS_KEY=" + config.SecretKey,
+	}
+	if config.SessionToken != "" {
+		env = append(env, "AWS_SESSION_TOKEN="+config.SessionToken)
+	}
+	return urls, caPath, files, env, nil
+}
@@ -0,0 +1,97 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtcdSnapshotUploadInstruction(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	controlPlane.Spec.KubernetesVersion = "v1.25.9+rke2r1"
+	controlPlane.Spec.ETCD = &rkev1.ETCD{SnapshotDir: "/data/snapshots"}
+
+	instruction, files, err := etcdSnapshotUploadInstruction(controlPlane, S3Config{
+		AccessKey: "access",
+		SecretKey: "secret",
+		Bucket:    "snapshots",
+		Folder:    "prod/cluster",
+	}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+	assert.Equal(t, ETCDSnapshotUploadInstruction, instruction.Name)
+	assert.Equal(t, "sh", instruction.Command)
+	assert.Equal(t, []string{
+		"/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/bin/upload.sh",
+		"/data/snapshots",
+		"prod/cluster",
+		"us-east-1",
+		"",
+		"false",
+		"",
+		"https://s3.amazonaws.com/snapshots",
+	}, instruction.Args)
+	assert.Equal(t, []string{"AWS_ACCESS_KEY_ID=access", "AWS_SECRET_ACCESS_KEY=secret"}, instruction.Env)
+	assert.True(t, instruction.SaveOutput)
+
+	controlPlane.Spec.ETCD.S3 = &rkev1.ETCDSnapshotS3{StorageClass: "STANDARD_IA"}
+	instruction, _, err = etcdSnapshotUploadInstruction(controlPlane, S3Config{
+		AccessKey:     "access",
+		SecretKey:     "secret",
+		Bucket:        "snapshots",
+		Endpoint:      "http://minio.example.com:9000/",
+		Region:        "eu-west-1",
+		EndpointCA:    "not a certificate",
+		SkipSSLVerify: true,
+	}, []string{"minio-replica.example.com:9000", "", "http://10.0.0.5:9000"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"/var/lib/rancher/rke2/rancher_v2prov_etcd_snapshot/bin/upload.sh",
+		"/data/snapshots",
+		"",
+		"eu-west-1",
+		"",
+		"true",
+		"STANDARD_IA",
+		"http://minio.example.com:9000/snapshots",
+		"https://minio-replica.example.com:9000/snapshots",
+		"http://10.0.0.5:9000/snapshots",
+	}, instruction.Args)
+
+	instruction, _, err = etcdSnapshotUploadInstruction(controlPlane, S3Config{AccessKey: "access", SecretKey: "secret", SessionToken: "token", Bucket: "snapshots"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AWS_ACCESS_KEY_ID=access", "AWS_SECRET_ACCESS_KEY=secret", "AWS_SESSION_TOKEN=token"}, instruction.Env)
+
+	controlPlane.Spec.ETCD.S3.StorageClass = "STANDARD IA"
+	_, _, err = etcdSnapshotUploadInstruction(controlPlane, S3Config{AccessKey: "access", SecretKey: "secret", Bucket: "snapshots"}, nil)
+	assert.Error(t, err)
+	controlPlane.Spec.ETCD.S3 = nil
+
+	_, _, err = etcdSnapshotUploadInstruction(controlPlane, S3Config{AccessKey: "access", SecretKey: "secret", Bucket: "snapshots", EndpointCA: "not a certificate"}, nil)
+	assert.Error(t, err)
+
+	_, _, err = etcdSnapshotUploadInstruction(controlPlane, S3Config{AccessKey: "access", SecretKey: "secret", Bucket: "snapshots"}, []string{"minio.example.com minio-replica.example.com"})
+	assert.EqualError(t, err, `invalid etcd snapshot S3 endpoint "minio.example.com minio-replica.example.com"`)
+
+	_, _, err = etcdSnapshotUploadInstruction(controlPlane, S3Config{Bucket: "snapshots"}, nil)
+	assert.EqualError(t, err, "etcd snapshots can not be uploaded without the access and secret key of an S3 cloud credential")
+
+	_, _, err = etcdSnapshotUploadInstruction(controlPlane, S3Config{AccessKey: "access", SecretKey: "secret"}, nil)
+	assert.EqualError(t, err, "etcd snapshots can not be uploaded as no S3 bucket is configured")
+}
+
+func TestSetEtcdSnapshotUploadState(t *testing.T) {
+	p := &Planner{}
+	status, err := p.setEtcdSnapshotUploadState(rkev1.RKEControlPlaneStatus{}, &rkev1.ETCDSnapshotUpload{Generation: 1}, rkev1.ETCDSnapshotPhaseStarted)
+	assert.True(t, IsErrWaiting(err))
+	assert.Equal(t, rkev1.ETCDSnapshotPhaseStarted, status.ETCDSnapshotUploadPhase)
+
+	status, err = p.setEtcdSnapshotUploadState(status, &rkev1.ETCDSnapshotUpload{Generation: 1}, rkev1.ETCDSnapshotPhaseStarted)
+	assert.NoError(t, err)
+
+	status, err = p.resetEtcdSnapshotUploadState(status)
+	assert.True(t, IsErrWaiting(err))
+	assert.Nil(t, status.ETCDSnapshotUpload)
+	assert.Empty(t, status.ETCDSnapshotUploadPhase)
+}
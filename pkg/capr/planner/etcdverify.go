@@ -0,0 +1,197 @@
+package planner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+)
+
+const (
+	// ETCDSnapshotVerifyInstruction is the name of the instruction that verifies the integrity of the snapshot file
+	// created on a node, and reports its size and digest.
+	ETCDSnapshotVerifyInstruction = "etcd-snapshot-verify"
+
+	etcdSnapshotVerifyScriptPath = "rancher_v2prov_etcd_snapshot/bin/verify.sh"
+
+	// etcdSnapshotVerifyScript verifies the most recent snapshot file saved by the snapshot creation of the passed in
+	// name, which is the snapshot that was just created. An etcd snapshot is a bolt database followed by the SHA-256
+	// digest of the database, which is checked against the content, along with etcdctl snapshot status if etcdutl or
+	// etcdctl is installed on the node.
+	// Compressed snapshots are decompressed to a temporary file first. Encrypted snapshots can not be verified without
+	// their key, so only their size and digest are reported.
+	etcdSnapshotVerifyScript = `#!/bin/sh
+
+dir=$1
+name=$2
+encrypted=$3
+
+file=""
+for candidate in "$dir/$name"-*; do
+	[ -f "$candidate" ] || continue
+	if [ -z "$file" ] || [ "$candidate" -nt "$file" ]; then
+		file=$candidate
+	fi
+done
+if [ -z "$file" ]; then
+	echo "no etcd snapshot $name found in $dir" >&2
+	exit 1
+fi
+snapshot=$(basename "$file")
+size=$(wc -c < "$file" | tr -d ' ')
+echo "snapshot $snapshot"
+echo "size $size"
+echo "sha256 $(sha256sum "$file" | cut -d ' ' -f 1)"
+
+if [ "$encrypted" = "true" ]; then
+	echo "result unverifiable the snapshot is encrypted"
+	exit 0
+fi
+
+db="$file"
+case "$snapshot" in
+*.zip)
+	if ! command -v unzip >/dev/null 2>&1; then
+		echo "result unverifiable unzip is not available to decompress the snapshot"
+		exit 0
+	fi
+	db=$(mktemp)
+	trap 'rm -f "$db"' EXIT
+	if ! unzip -p "$file" > "$db"; then
+		echo "result corrupt the snapshot can not be decompressed"
+		exit 1
+	fi
+	size=$(wc -c < "$db" | tr -d ' ')
+	;;
+esac
+
+if [ $((size % 512)) -ne 32 ]; then
+	echo "result corrupt the snapshot does not end with the digest of its database"
+	exit 1
+fi
+expected=$(tail -c 32 "$db" | od -An -v -tx1 | tr -d ' \n')
+actual=$(head -c $((size - 32)) "$db" | sha256sum | cut -d ' ' -f 1)
+if [ "$expected" != "$actual" ]; then
+	echo "result corrupt the digest of the snapshot does not match its database"
+	exit 1
+fi
+for etcdctl in etcdutl etcdctl; do
+	if command -v "$etcdctl" >/dev/null 2>&1; then
+		if ! ETCDCTL_API=3 "$etcdctl" snapshot status "$db" >/dev/null 2>&1; then
+			echo "result corrupt $etcdctl snapshot status failed"
+			exit 1
+		fi
+		break
+	fi
+done
+echo "result verified"
+`
+)
+
+// runEtcdSnapshotVerification delivers the regular plans of the etcd nodes, extended by an instruction that verifies the
+// integrity of the snapshot that was just created, and returns the progress of the snapshot creation on each of them,
+// updated from their previous progress. Machines whose snapshot is corrupt fail, and a waiting error is returned while
+// the verification has not completed on all machines yet.
+func (p *Planner) runEtcdSnapshotVerification(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshotCreate, tokensSecret plan.Secret, clusterPlan *plan.Plan, joinServer string, now time.Time) ([]rkev1.ETCDSnapshotMachineProgress, error) {
+	servers := collect(clusterPlan, isEtcd)
+	if len(servers) == 0 {
+		return snapshot.Machines, errors.New("failed to find node to verify etcd snapshot")
+	}
+
+	var (
+		waiting  []string
+		progress []rkev1.ETCDSnapshotMachineProgress
+	)
+	for _, server := range servers {
+		joinURL := joinServer
+		if isInitNode(server) {
+			joinURL = ""
+		}
+		verifyPlan, joinedServer, err := p.generateEtcdSnapshotVerifyPlan(controlPlane, snapshot, tokensSecret, server, joinURL)
+		if err != nil {
+			return snapshot.Machines, err
+		}
+		msg := fmt.Sprintf("etcd snapshot verification on machine %s/%s", server.Machine.Namespace, server.Machine.Name)
+		err = assignAndCheckPlan(p.store, msg, server, verifyPlan, joinedServer, 1, 1)
+		if IsErrWaiting(err) {
+			waiting = append(waiting, err.Error())
+		} else if err != nil {
+			name, verification := ParseETCDSnapshotVerifyOutput(server.Plan.Output[ETCDSnapshotVerifyInstruction])
+			if verification.Message != "" {
+				err = fmt.Errorf("etcd snapshot %s failed verification: %s", name, verification.Message)
+			}
+		}
+		machineProgress := etcdSnapshotMachineProgress(snapshot.Machines, server.Machine.Name, nil, now)
+		if err != nil && !IsErrWaiting(err) {
+			machineProgress.Phase, machineProgress.Error = rkev1.ETCDSnapshotPhaseFailed, err.Error()
+		}
+		progress = append(progress, machineProgress)
+	}
+	if len(waiting) > 0 {
+		return progress, errWaiting(strings.Join(waiting, ", "))
+	}
+	return progress, nil
+}
+
+// generateEtcdSnapshotVerifyPlan generates the desired plan of the node, with an additional instruction that verifies the
+// snapshot that was created on it.
+func (p *Planner) generateEtcdSnapshotVerifyPlan(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshotCreate, tokensSecret plan.Secret, entry *planEntry, joinServer string) (plan.NodePlan, string, error) {
+	verifyPlan, joinedServer, err := p.desiredPlan(controlPlane, tokensSecret, entry, joinServer)
+	if err != nil {
+		return verifyPlan, joinedServer, err
+	}
+	encrypted := snapshot.EncryptionKeySecretName != "" ||
+		(controlPlane.Spec.ETCD != nil && controlPlane.Spec.ETCD.SnapshotEncryption != nil && len(controlPlane.Status.ETCDSnapshotEncryptionKeys) > 0)
+	scriptPath := etcdSnapshotScriptFile(controlPlane, etcdSnapshotVerifyScriptPath)
+	verifyPlan.Files = append(verifyPlan.Files, plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotVerifyScript)),
+		Path:    scriptPath,
+	})
+	verifyPlan.Instructions = append(verifyPlan.Instructions, plan.OneTimeInstruction{
+		Name:       ETCDSnapshotVerifyInstruction,
+		Command:    "sh",
+		Args:       []string{scriptPath, etcdSnapshotDir(controlPlane), etcdSnapshotCreateName(controlPlane.Status.ETCDSnapshotCreate), strconv.FormatBool(encrypted)},
+		SaveOutput: true,
+	})
+	return verifyPlan, joinedServer, nil
+}
+
+// ParseETCDSnapshotVerifyOutput returns the name of the snapshot file reported by the output of the etcd snapshot
+// verify instruction, along with its size, digest and the result of its verification.
+func ParseETCDSnapshotVerifyOutput(output []byte) (string, rkev1.ETCDSnapshotVerification) {
+	var (
+		name   string
+		result rkev1.ETCDSnapshotVerification
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		switch key {
+		case "snapshot":
+			name = value
+		case "size":
+			result.Size, _ = strconv.ParseInt(value, 10, 64)
+		case "sha256":
+			result.SHA256 = value
+		case "result":
+			status, message, _ := strings.Cut(value, " ")
+			result.Message = message
+			switch status {
+			case "verified":
+				result.Result = rkev1.ETCDSnapshotChecksumVerified
+			case "corrupt":
+				result.Result = rkev1.ETCDSnapshotChecksumMismatch
+			default:
+				result.Result = rkev1.ETCDSnapshotChecksumUnverifiable
+			}
+		}
+	}
+	return name, result
+}
@@ -0,0 +1,58 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseETCDSnapshotVerifyOutput(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		expectedName string
+		expected     rkev1.ETCDSnapshotVerification
+	}{
+		{
+			name:         "verified",
+			output:       "snapshot on-demand-node1-1683115200\nsize 20512\nsha256 4bd2f847\nresult verified\n",
+			expectedName: "on-demand-node1-1683115200",
+			expected:     rkev1.ETCDSnapshotVerification{Size: 20512, SHA256: "4bd2f847", Result: rkev1.ETCDSnapshotChecksumVerified},
+		},
+		{
+			name:         "corrupt",
+			output:       "snapshot on-demand-node1-1683115200\nsize 20000\nsha256 4bd2f847\nresult corrupt the snapshot does not end with the digest of its database\n",
+			expectedName: "on-demand-node1-1683115200",
+			expected: rkev1.ETCDSnapshotVerification{
+				Size:    20000,
+				SHA256:  "4bd2f847",
+				Result:  rkev1.ETCDSnapshotChecksumMismatch,
+				Message: "the snapshot does not end with the digest of its database",
+			},
+		},
+		{
+			name:         "encrypted",
+			output:       "snapshot on-demand-node1-1683115200\nsize 20528\nsha256 9ac1e0d2\nresult unverifiable the snapshot is encrypted\n",
+			expectedName: "on-demand-node1-1683115200",
+			expected: rkev1.ETCDSnapshotVerification{
+				Size:    20528,
+				SHA256:  "9ac1e0d2",
+				Result:  rkev1.ETCDSnapshotChecksumUnverifiable,
+				Message: "the snapshot is encrypted",
+			},
+		},
+		{
+			name:   "no snapshot",
+			output: "no etcd snapshot found in /var/lib/rancher/rke2/server/db/snapshots\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, verification := ParseETCDSnapshotVerifyOutput([]byte(tt.output))
+			assert.Equal(t, tt.expectedName, name)
+			assert.Equal(t, tt.expected, verification)
+		})
+	}
+}
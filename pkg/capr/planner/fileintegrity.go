@@ -0,0 +1,93 @@
+package planner
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+)
+
+const (
+	// FileIntegrityInstruction is the name of the periodic instruction that reports the files of the plan of a machine
+	// that were changed or deleted on the machine.
+	FileIntegrityInstruction = "file-integrity"
+
+	fileIntegrityScriptPath   = "rancher_v2prov_file_integrity/bin/check.sh"
+	fileIntegrityManifestPath = "rancher_v2prov_file_integrity/manifest"
+
+	defaultFileIntegrityPeriodSeconds = 300
+
+	// fileIntegrityScript prints a line for each file of the manifest that was changed or deleted. Each line of the
+	// manifest is the sha256 digest of the content of a file, its permissions or "-", and its path.
+	fileIntegrityScript = `#!/bin/sh
+manifest=$1
+while read -r sum perms file; do
+	if [ ! -f "$file" ]; then
+		echo "deleted $file"
+	elif [ "$(sha256sum "$file" | cut -d ' ' -f 1)" != "$sum" ]; then
+		echo "modified $file"
+	fi
+done < "$manifest"
+`
+)
+
+// addFileIntegrity adds the periodic instruction that compares the files of the plan of a linux machine with their
+// content on the machine to the plan, along with the manifest of the digests of the files. Only the digests are
+// delivered, changed files are restored by applying the plan again. It must be added after all other files were added
+// to the plan. The manifest is minor, as it only changes along with other files of the plan.
+func addFileIntegrity(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane, entry *planEntry) (plan.NodePlan, error) {
+	integrity := controlPlane.Spec.FileIntegrity
+	if integrity == nil || windows(entry) {
+		return nodePlan, nil
+	}
+	if integrity.PeriodSeconds < 0 {
+		return nodePlan, fmt.Errorf("file integrity period %d must not be negative", integrity.PeriodSeconds)
+	}
+	period := integrity.PeriodSeconds
+	if period == 0 {
+		period = defaultFileIntegrityPeriodSeconds
+	}
+
+	var manifest strings.Builder
+	scriptPath := fileIntegrityFile(controlPlane, fileIntegrityScriptPath)
+	files := append(nodePlan.Files, plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(fileIntegrityScript)),
+		Path:    scriptPath,
+	})
+	for _, file := range files {
+		content, err := base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			return nodePlan, fmt.Errorf("decoding content of file %s: %w", file.Path, err)
+		}
+		digest := sha256.Sum256(content)
+		perms := file.Permissions
+		if perms == "" {
+			perms = "-"
+		}
+		fmt.Fprintf(&manifest, "%s %s %s\n", hex.EncodeToString(digest[:]), perms, file.Path)
+	}
+
+	manifestPath := fileIntegrityFile(controlPlane, fileIntegrityManifestPath)
+	nodePlan.Files = append(files, plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(manifest.String())),
+		Path:    manifestPath,
+		Minor:   true,
+	})
+	nodePlan.PeriodicInstructions = append(nodePlan.PeriodicInstructions, plan.PeriodicInstruction{
+		Name:          FileIntegrityInstruction,
+		Command:       "sh",
+		Args:          []string{scriptPath, manifestPath},
+		PeriodSeconds: period,
+	})
+	return nodePlan, nil
+}
+
+// fileIntegrityFile returns the path of a file of the file integrity instruction in the data directory of the runtime.
+func fileIntegrityFile(controlPlane *rkev1.RKEControlPlane, filePath string) string {
+	return fmt.Sprintf("/var/lib/rancher/%s/%s", capr.GetRuntime(controlPlane.Spec.KubernetesVersion), filePath)
+}
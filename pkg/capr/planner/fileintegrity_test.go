@@ -0,0 +1,71 @@
+package planner
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddFileIntegrity(t *testing.T) {
+	encode := func(content string) string {
+		return base64.StdEncoding.EncodeToString([]byte(content))
+	}
+	basePlan := func() plan.NodePlan {
+		return plan.NodePlan{
+			Files: []plan.File{
+				{Path: "/etc/rancher/rke2/config.yaml", Content: encode("token: secret\n"), Permissions: "0600"},
+				{Path: "/etc/rancher/rke2/copy.yaml", Content: encode("token: secret\n")},
+			},
+		}
+	}
+
+	controlPlane := createTestControlPlane("v1.25.7+rke2r1")
+	nodePlan, err := addFileIntegrity(basePlan(), controlPlane, createTestPlanEntry("linux"))
+	require.NoError(t, err)
+	assert.Equal(t, basePlan(), nodePlan, "no instruction without file integrity settings")
+
+	controlPlane.Spec.FileIntegrity = &rkev1.FileIntegrity{}
+	nodePlan, err = addFileIntegrity(basePlan(), controlPlane, createTestPlanEntry("windows"))
+	require.NoError(t, err)
+	assert.Equal(t, basePlan(), nodePlan, "no instruction for windows machines")
+
+	nodePlan, err = addFileIntegrity(basePlan(), controlPlane, createTestPlanEntry("linux"))
+	require.NoError(t, err)
+	require.Len(t, nodePlan.Files, 4)
+	script, manifest := nodePlan.Files[2], nodePlan.Files[3]
+	assert.Equal(t, "/var/lib/rancher/rke2/"+fileIntegrityScriptPath, script.Path)
+	assert.True(t, manifest.Minor)
+	require.Len(t, nodePlan.PeriodicInstructions, 1)
+	assert.Equal(t, plan.PeriodicInstruction{
+		Name:          FileIntegrityInstruction,
+		Command:       "sh",
+		Args:          []string{script.Path, manifest.Path},
+		PeriodSeconds: defaultFileIntegrityPeriodSeconds,
+	}, nodePlan.PeriodicInstructions[0])
+
+	content, err := base64.StdEncoding.DecodeString(manifest.Content)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 3)
+	// the sha256 digest of "token: secret\n"
+	sum := "03c9383f31ca107f2fc748d715cde9b86d67b2bc49669a3cd32b4fde5f234e21"
+	assert.Equal(t, sum+" 0600 /etc/rancher/rke2/config.yaml", lines[0])
+	assert.Equal(t, sum+" - /etc/rancher/rke2/copy.yaml", lines[1])
+	assert.Equal(t, script.Path, strings.Fields(lines[2])[2])
+
+	controlPlane.Spec.FileIntegrity = &rkev1.FileIntegrity{PeriodSeconds: 60, Restore: true}
+	nodePlan, err = addFileIntegrity(basePlan(), controlPlane, createTestPlanEntry("linux"))
+	require.NoError(t, err)
+	require.Len(t, nodePlan.Files, 4, "the content of the files is not copied to restore them")
+	assert.Equal(t, []string{script.Path, manifest.Path}, nodePlan.PeriodicInstructions[0].Args)
+	assert.Equal(t, 60, nodePlan.PeriodicInstructions[0].PeriodSeconds)
+
+	controlPlane.Spec.FileIntegrity = &rkev1.FileIntegrity{PeriodSeconds: -1}
+	_, err = addFileIntegrity(basePlan(), controlPlane, createTestPlanEntry("linux"))
+	assert.Error(t, err)
+}
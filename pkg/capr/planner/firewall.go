@@ -0,0 +1,184 @@
+package planner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	firewallInstructionName = "open-firewall-ports"
+	firewallScriptPath      = "rancher_v2prov_firewall/bin/apply.sh"
+
+	firewallScript = `#!/bin/sh
+# Opens the tcp and udp ports passed in as comma separated lists in the host firewall, replacing the ports opened by a
+# previous run. firewalld is used if it is running, the input chain of the inet filter table of nftables otherwise.
+set -e
+name="rancher-$1"
+tcp=$(echo "$2" | tr ',' ' ')
+udp=$(echo "$3" | tr ',' ' ')
+
+if command -v firewall-cmd > /dev/null 2>&1 && firewall-cmd --state > /dev/null 2>&1; then
+	if firewall-cmd --permanent --info-service="$name" > /dev/null 2>&1; then
+		for port in $(firewall-cmd --permanent --service="$name" --get-ports); do
+			firewall-cmd --permanent --service="$name" --remove-port="$port" > /dev/null
+		done
+	else
+		firewall-cmd --permanent --new-service="$name" > /dev/null
+	fi
+	for port in $tcp; do
+		firewall-cmd --permanent --service="$name" --add-port="$port/tcp" > /dev/null
+	done
+	for port in $udp; do
+		firewall-cmd --permanent --service="$name" --add-port="$port/udp" > /dev/null
+	done
+	if ! firewall-cmd --permanent --query-service="$name" > /dev/null 2>&1; then
+		firewall-cmd --permanent --add-service="$name" > /dev/null
+	fi
+	firewall-cmd --reload > /dev/null
+	echo "opened tcp ports $2 and udp ports $3 with firewalld service $name"
+	exit 0
+fi
+
+if command -v nft > /dev/null 2>&1 && nft list chain inet filter input > /dev/null 2>&1; then
+	for handle in $(nft -a list chain inet filter input | grep "comment \"$name\"" | sed 's/.*# handle //'); do
+		nft delete rule inet filter input handle "$handle"
+	done
+	if [ -n "$2" ]; then
+		nft insert rule inet filter input tcp dport "{ $2 }" accept comment "\"$name\""
+	fi
+	if [ -n "$3" ]; then
+		nft insert rule inet filter input udp dport "{ $3 }" accept comment "\"$name\""
+	fi
+	echo "opened tcp ports $2 and udp ports $3 in the input chain of the inet filter table"
+	exit 0
+fi
+
+echo "neither firewalld nor the inet filter table of nftables is in use, no ports opened"
+`
+)
+
+var firewallPortRegexp = regexp.MustCompile(`^(\d+)(?:-(\d+))?/(tcp|udp)$`)
+
+// addFirewall adds the instruction that opens the ports of the cluster components in the host firewall to the plan of a
+// linux machine. Same as the break-glass access, the instruction is added after the install instruction, as a change of
+// the opened ports must not restart the distribution.
+func addFirewall(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane, entry *planEntry) (plan.NodePlan, error) {
+	if controlPlane.Spec.Firewall == nil || windows(entry) {
+		return nodePlan, nil
+	}
+	tcp, udp, err := firewallPorts(controlPlane, entry)
+	if err != nil {
+		return nodePlan, err
+	}
+
+	runtime := capr.GetRuntime(controlPlane.Spec.KubernetesVersion)
+	scriptPath := etcdSnapshotScriptFile(controlPlane, firewallScriptPath)
+	nodePlan.Files = append(nodePlan.Files, plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(firewallScript)),
+		Path:    scriptPath,
+	})
+	nodePlan.Instructions = append(nodePlan.Instructions, plan.OneTimeInstruction{
+		Name:    firewallInstructionName,
+		Command: "sh",
+		Args: []string{
+			scriptPath,
+			runtime,
+			strings.Join(tcp, ","),
+			strings.Join(udp, ","),
+		},
+	})
+	return nodePlan, nil
+}
+
+// firewallPorts returns the sorted tcp and udp ports, or port ranges, that the machine of the entry must accept
+// connections on for its roles, the CNI and the embedded registry of the cluster, followed by the additional ports of
+// the firewall settings. The CNI is the one returned by capr.GetCNI, so that rke2 clusters with an empty cni get the
+// ports of canal, which rke2 deploys then, rather than those of the CNI Rancher defaults to.
+func firewallPorts(controlPlane *rkev1.RKEControlPlane, entry *planEntry) ([]string, []string, error) {
+	runtime := capr.GetRuntime(controlPlane.Spec.KubernetesVersion)
+	// kubelet and node ports
+	tcp := sets.NewString("10250", "30000-32767")
+	udp := sets.NewString("30000-32767")
+
+	if isControlPlane(entry) || isEtcd(entry) {
+		// supervisor
+		if runtime == capr.RuntimeRKE2 {
+			tcp.Insert("9345")
+		} else {
+			tcp.Insert("6443")
+		}
+	}
+	if isControlPlane(entry) {
+		tcp.Insert("6443")
+	}
+	if isEtcd(entry) {
+		tcp.Insert("2379", "2380")
+	}
+
+	for _, cni := range strings.Split(capr.GetCNI(controlPlane), ",") {
+		switch strings.TrimSpace(cni) {
+		case "canal":
+			udp.Insert("8472")
+			tcp.Insert("9099")
+		case "calico":
+			tcp.Insert("179", "5473", "9098", "9099")
+			udp.Insert("4789")
+		case "cilium":
+			udp.Insert("8472")
+			tcp.Insert("4240")
+		case "flannel":
+			if backend := convert.ToString(controlPlane.Spec.MachineGlobalConfig.Data["flannel-backend"]); strings.HasPrefix(backend, "wireguard") {
+				udp.Insert("51820", "51821")
+			} else {
+				udp.Insert("8472")
+			}
+		}
+	}
+
+	if convert.ToBool(controlPlane.Spec.MachineGlobalConfig.Data["embedded-registry"]) {
+		tcp.Insert("5001")
+	}
+
+	for _, port := range controlPlane.Spec.Firewall.AdditionalPorts {
+		ports, protocol, err := parseFirewallPort(port)
+		if err != nil {
+			return nil, nil, err
+		}
+		if protocol == "tcp" {
+			tcp.Insert(ports)
+		} else {
+			udp.Insert(ports)
+		}
+	}
+	return tcp.List(), udp.List(), nil
+}
+
+// parseFirewallPort parses a port or port range followed by its protocol, i.e. "8080/tcp" or "7000-7010/udp".
+func parseFirewallPort(port string) (string, string, error) {
+	matches := firewallPortRegexp.FindStringSubmatch(strings.TrimSpace(port))
+	if matches == nil {
+		return "", "", fmt.Errorf("firewall port %q must be a port or port range followed by /tcp or /udp", port)
+	}
+	from, _ := strconv.Atoi(matches[1])
+	to := from
+	if matches[2] != "" {
+		to, _ = strconv.Atoi(matches[2])
+	}
+	if from < 1 || to > 65535 || from > to {
+		return "", "", fmt.Errorf("firewall port %q must be within 1-65535", port)
+	}
+	ports := matches[1]
+	if matches[2] != "" {
+		ports += "-" + matches[2]
+	}
+	return ports, matches[3], nil
+}
@@ -0,0 +1,173 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirewallPorts(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		roles       []string
+		config      map[string]interface{}
+		firewall    rkev1.Firewall
+		expectedTCP []string
+		expectedUDP []string
+		expectErr   bool
+	}{
+		{
+			name:        "rke2 worker with default cni",
+			version:     "v1.25.7+rke2r1",
+			roles:       []string{capr.WorkerRoleLabel},
+			expectedTCP: []string{"10250", "179", "30000-32767", "5473", "9098", "9099"},
+			expectedUDP: []string{"30000-32767", "4789"},
+		},
+		{
+			name:        "rke2 worker with empty cni",
+			version:     "v1.25.7+rke2r1",
+			roles:       []string{capr.WorkerRoleLabel},
+			config:      map[string]interface{}{"cni": ""},
+			expectedTCP: []string{"10250", "30000-32767", "9099"},
+			expectedUDP: []string{"30000-32767", "8472"},
+		},
+		{
+			name:        "rke2 worker with multus and canal list",
+			version:     "v1.25.7+rke2r1",
+			roles:       []string{capr.WorkerRoleLabel},
+			config:      map[string]interface{}{"cni": []interface{}{"multus", "canal"}},
+			expectedTCP: []string{"10250", "30000-32767", "9099"},
+			expectedUDP: []string{"30000-32767", "8472"},
+		},
+		{
+			name:        "rke2 all roles with canal",
+			version:     "v1.25.7+rke2r1",
+			roles:       []string{capr.EtcdRoleLabel, capr.ControlPlaneRoleLabel, capr.WorkerRoleLabel},
+			config:      map[string]interface{}{"cni": "canal"},
+			expectedTCP: []string{"10250", "2379", "2380", "30000-32767", "6443", "9099", "9345"},
+			expectedUDP: []string{"30000-32767", "8472"},
+		},
+		{
+			name:        "rke2 etcd with multus and cilium",
+			version:     "v1.25.7+rke2r1",
+			roles:       []string{capr.EtcdRoleLabel},
+			config:      map[string]interface{}{"cni": "multus,cilium"},
+			expectedTCP: []string{"10250", "2379", "2380", "30000-32767", "4240", "9345"},
+			expectedUDP: []string{"30000-32767", "8472"},
+		},
+		{
+			name:        "rke2 without cni and embedded registry",
+			version:     "v1.25.7+rke2r1",
+			roles:       []string{capr.WorkerRoleLabel},
+			config:      map[string]interface{}{"cni": "none", "embedded-registry": true},
+			expectedTCP: []string{"10250", "30000-32767", "5001"},
+			expectedUDP: []string{"30000-32767"},
+		},
+		{
+			name:        "k3s control plane",
+			version:     "v1.25.7+k3s1",
+			roles:       []string{capr.ControlPlaneRoleLabel},
+			expectedTCP: []string{"10250", "30000-32767", "6443"},
+			expectedUDP: []string{"30000-32767", "8472"},
+		},
+		{
+			name:        "k3s etcd with wireguard",
+			version:     "v1.25.7+k3s1",
+			roles:       []string{capr.EtcdRoleLabel},
+			config:      map[string]interface{}{"flannel-backend": "wireguard-native"},
+			expectedTCP: []string{"10250", "2379", "2380", "30000-32767", "6443"},
+			expectedUDP: []string{"30000-32767", "51820", "51821"},
+		},
+		{
+			name:        "additional ports",
+			version:     "v1.25.7+k3s1",
+			roles:       []string{capr.WorkerRoleLabel},
+			config:      map[string]interface{}{"flannel-backend": "none"},
+			firewall:    rkev1.Firewall{AdditionalPorts: []string{"8080/tcp", "7000-7010/udp"}},
+			expectedTCP: []string{"10250", "30000-32767", "8080"},
+			expectedUDP: []string{"30000-32767", "7000-7010"},
+		},
+		{
+			name:      "invalid additional port",
+			version:   "v1.25.7+k3s1",
+			roles:     []string{capr.WorkerRoleLabel},
+			firewall:  rkev1.Firewall{AdditionalPorts: []string{"8080"}},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlPlane := createTestControlPlane(tt.version)
+			controlPlane.Spec.MachineGlobalConfig.Data = tt.config
+			controlPlane.Spec.Firewall = &tt.firewall
+			entry := createTestPlanEntryWithoutRoles("linux")
+			for _, role := range tt.roles {
+				entry.Metadata.Labels[role] = "true"
+			}
+
+			tcp, udp, err := firewallPorts(controlPlane, entry)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedTCP, tcp)
+			assert.Equal(t, tt.expectedUDP, udp)
+		})
+	}
+}
+
+func TestParseFirewallPort(t *testing.T) {
+	tests := []struct {
+		port             string
+		expectedPorts    string
+		expectedProtocol string
+		expectErr        bool
+	}{
+		{port: "8080/tcp", expectedPorts: "8080", expectedProtocol: "tcp"},
+		{port: " 7000-7010/udp ", expectedPorts: "7000-7010", expectedProtocol: "udp"},
+		{port: "8080", expectErr: true},
+		{port: "8080/sctp", expectErr: true},
+		{port: "0/tcp", expectErr: true},
+		{port: "65536/tcp", expectErr: true},
+		{port: "7010-7000/udp", expectErr: true},
+		{port: "8080/tcp; reboot", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.port, func(t *testing.T) {
+			ports, protocol, err := parseFirewallPort(tt.port)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedPorts, ports)
+			assert.Equal(t, tt.expectedProtocol, protocol)
+		})
+	}
+}
+
+func TestAddFirewall(t *testing.T) {
+	controlPlane := createTestControlPlane("v1.25.7+rke2r1")
+
+	nodePlan, err := addFirewall(plan.NodePlan{}, controlPlane, createTestPlanEntry("linux"))
+	require.NoError(t, err)
+	assert.Empty(t, nodePlan.Instructions, "no instruction without firewall settings")
+
+	controlPlane.Spec.Firewall = &rkev1.Firewall{}
+	nodePlan, err = addFirewall(plan.NodePlan{}, controlPlane, createTestPlanEntry("windows"))
+	require.NoError(t, err)
+	assert.Empty(t, nodePlan.Instructions, "no instruction for windows machines")
+
+	nodePlan, err = addFirewall(plan.NodePlan{}, controlPlane, createTestPlanEntry("linux"))
+	require.NoError(t, err)
+	require.Len(t, nodePlan.Instructions, 1)
+	require.Len(t, nodePlan.Files, 1)
+	assert.Equal(t, firewallInstructionName, nodePlan.Instructions[0].Name)
+	assert.Equal(t, []string{nodePlan.Files[0].Path, "rke2", "10250,179,30000-32767,5473,9098,9099", "30000-32767,4789"}, nodePlan.Instructions[0].Args)
+}
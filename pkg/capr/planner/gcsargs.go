@@ -0,0 +1,208 @@
+package planner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/kv"
+)
+
+const (
+	// ETCDSnapshotGCSUploadInstruction is the name of the instruction that offloads the local etcd snapshots of a node to
+	// Google Cloud Storage.
+	ETCDSnapshotGCSUploadInstruction = "etcd-snapshot-gcs-upload"
+
+	etcdSnapshotGCSUploadScriptPath = "rancher_v2prov_etcd_snapshot/bin/gcs-upload.sh"
+
+	defaultGCSEndpoint = "https://storage.googleapis.com"
+	defaultGCSTokenURI = "https://oauth2.googleapis.com/token"
+
+	// etcdSnapshotGCSUploadScript uploads the snapshot files in the snapshot directory that do not exist in the bucket
+	// yet. Requests are authorized with an access token that is exchanged for a JWT signed with openssl by the key of
+	// the service account passed in through the environment. The key is only written to a temporary file that is
+	// readable by its owner, and the token is handed to curl on stdin to keep it out of the process list. For each
+	// snapshot in the bucket, a line with its name is printed, prefixed by whether it was uploaded.
+	etcdSnapshotGCSUploadScript = `#!/bin/sh
+
+dir=$1
+url=$2
+bucket=$3
+folder=$4
+
+if [ ! -d "$dir" ]; then
+	echo "etcd snapshot directory $dir does not exist" >&2
+	exit 1
+fi
+prefix=""
+if [ -n "$folder" ]; then
+	prefix="$folder/"
+fi
+
+b64url() {
+	openssl base64 -A | tr '+/' '-_' | tr -d '='
+}
+
+key=$(mktemp)
+trap 'rm -f "$key"' EXIT
+printf '%s\n' "$GCS_PRIVATE_KEY" > "$key"
+now=$(date +%s)
+header=$(printf '{"alg":"RS256","typ":"JWT"}' | b64url)
+claims=$(printf '{"iss":"%s","scope":"https://www.googleapis.com/auth/devstorage.read_write","aud":"%s","iat":%d,"exp":%d}' \
+	"$GCS_CLIENT_EMAIL" "$GCS_TOKEN_URI" "$now" "$((now + 600))" | b64url)
+signature=$(printf '%s.%s' "$header" "$claims" | openssl dgst -sha256 -sign "$key" -binary | b64url)
+rm -f "$key"
+token=$(printf 'assertion=%s.%s.%s' "$header" "$claims" "$signature" |
+	curl --fail --silent --show-error \
+		--data grant_type=urn%3Aietf%3Aparams%3Aoauth%3Agrant-type%3Ajwt-bearer \
+		--data @- \
+		"$GCS_TOKEN_URI" |
+	sed -n 's/.*"access_token" *: *"\([^"]*\)".*/\1/p')
+if [ -z "$token" ]; then
+	echo "failed to get an access token of the Google service account" >&2
+	exit 1
+fi
+
+request() {
+	target=$1
+	shift
+	printf 'header = "Authorization: Bearer %s"\nurl = "%s"\n' "$token" "$target" |
+		curl -K - --fail --silent --show-error --output /dev/null "$@"
+}
+
+failed=0
+for file in "$dir"/*; do
+	[ -f "$file" ] || continue
+	name=$(basename "$file")
+	case "$name" in
+	*[!A-Za-z0-9._-]*)
+		echo "skipping etcd snapshot $name as its name is not a valid object name" >&2
+		continue
+		;;
+	esac
+	object=$(printf '%s' "$prefix$name" | sed 's|/|%2F|g')
+	if request "$url/storage/v1/b/$bucket/o/$object" 2>/dev/null; then
+		echo "exists $name"
+		continue
+	fi
+	if ! request "$url/upload/storage/v1/b/$bucket/o?uploadType=media&name=$object" -X POST --upload-file "$file" -H "Content-Type: application/octet-stream"; then
+		echo "failed to upload etcd snapshot $name" >&2
+		failed=1
+		continue
+	fi
+	echo "uploaded $name"
+done
+exit $failed
+`
+)
+
+var gcsBucketRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,61}[a-z0-9]$`)
+
+// gcsArgs is a struct that contains functions used to generate arguments for etcd snapshots offloaded to Google Cloud
+// Storage.
+type gcsArgs struct {
+	secretCache corecontrollers.SecretCache
+}
+
+// ToArgs renders the arguments and environment variables of the upload script for the bucket of the passed in
+// ETCDSnapshotGCS, authorized by the service account of its cloud credential.
+func (g *gcsArgs) ToArgs(gcs *rkev1.ETCDSnapshotGCS, controlPlane *rkev1.RKEControlPlane) ([]string, []string, error) {
+	if gcs.CloudCredentialName == "" {
+		return nil, nil, fmt.Errorf("etcd snapshots can not be offloaded to Google Cloud Storage without a cloud credential")
+	}
+	cred, err := getGCSCredential(g.secretCache, controlPlane.Namespace, gcs.CloudCredentialName, controlPlane.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gcsUploadArgs(gcs, cred)
+}
+
+// gcsUploadArgs validates the bucket and returns the arguments and environment variables of the upload script.
+func gcsUploadArgs(gcs *rkev1.ETCDSnapshotGCS, cred gcsCredential) ([]string, []string, error) {
+	if !gcsBucketRegexp.MatchString(gcs.Bucket) || strings.Contains(gcs.Bucket, "..") {
+		return nil, nil, fmt.Errorf("invalid etcd snapshot GCS bucket %q: must be between 3 and 63 characters long, consist of lowercase letters, numbers, dots, hyphens and underscores, and begin and end with a letter or number", gcs.Bucket)
+	}
+	folder, err := normalizeSnapshotFolder(gcs.Folder, "GCS")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	endpoint := strings.TrimSuffix(gcs.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = defaultGCSEndpoint
+	} else if !strings.HasPrefix(endpoint, "https://") {
+		return nil, nil, fmt.Errorf("invalid etcd snapshot GCS endpoint %q: must be an https URL", gcs.Endpoint)
+	}
+
+	if cred.Type != "service_account" || cred.ClientEmail == "" || cred.PrivateKey == "" {
+		return nil, nil, fmt.Errorf("the etcd snapshot cloud credential must have the key of a Google service account")
+	}
+	tokenURI := cred.TokenURI
+	if tokenURI == "" {
+		tokenURI = defaultGCSTokenURI
+	}
+
+	env := []string{
+		"GCS_CLIENT_EMAIL=" + cred.ClientEmail,
+		"GCS_PRIVATE_KEY=" + cred.PrivateKey,
+		"GCS_TOKEN_URI=" + tokenURI,
+	}
+	return []string{endpoint, gcs.Bucket, folder}, env, nil
+}
+
+// etcdSnapshotGCSUploadPlan returns the script and the instruction that offload the local snapshots of a node to the
+// GCS bucket of the control plane, if one is configured.
+func (p *Planner) etcdSnapshotGCSUploadPlan(controlPlane *rkev1.RKEControlPlane) ([]plan.File, []plan.OneTimeInstruction, error) {
+	if controlPlane.Spec.ETCD == nil || controlPlane.Spec.ETCD.GCS == nil {
+		return nil, nil, nil
+	}
+	args, env, err := p.etcdGCSArgs.ToArgs(controlPlane.Spec.ETCD.GCS, controlPlane)
+	if err != nil {
+		return nil, nil, err
+	}
+	scriptPath := etcdSnapshotScriptFile(controlPlane, etcdSnapshotGCSUploadScriptPath)
+	files := []plan.File{{
+		Content: base64.StdEncoding.EncodeToString([]byte(etcdSnapshotGCSUploadScript)),
+		Path:    scriptPath,
+	}}
+	instruction := plan.OneTimeInstruction{
+		Name:    ETCDSnapshotGCSUploadInstruction,
+		Command: "sh",
+		Args:    append([]string{scriptPath, etcdSnapshotDir(controlPlane)}, args...),
+		Env:     env,
+	}
+	return files, []plan.OneTimeInstruction{instruction}, nil
+}
+
+// gcsCredential is the key of a Google service account, as stored in the authEncodedJson field of a Google cloud
+// credential.
+type gcsCredential struct {
+	Type        string `json:"type"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+func getGCSCredential(secretCache corecontrollers.SecretCache, namespace, name, clusterName string) (gcsCredential, error) {
+	secret, err := getNodeCloudCredentialSecret(secretCache, namespace, name, clusterName)
+	if err != nil {
+		return gcsCredential{}, fmt.Errorf("failed to lookup etcd snapshot GCS cloud credential: %w", err)
+	}
+
+	data := map[string][]byte{}
+	for k, v := range secret.Data {
+		_, k = kv.RSplit(k, "-")
+		data[k] = v
+	}
+
+	var cred gcsCredential
+	if err := json.Unmarshal(data["authEncodedJson"], &cred); err != nil {
+		return gcsCredential{}, fmt.Errorf("failed to parse the service account key of the etcd snapshot GCS cloud credential: %w", err)
+	}
+	return cred, nil
+}
@@ -0,0 +1,174 @@
+package planner
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const defaultImageRegistry = "docker.io"
+
+// ImagePolicyError lists the images of a plan that are not allowed by the image policy of the cluster.
+type ImagePolicyError struct {
+	Machine string
+	Images  []string
+}
+
+func (e *ImagePolicyError) Error() string {
+	return fmt.Sprintf("refusing to deliver plan to machine %s: images not allowed by the image policy of the cluster: %s", e.Machine, strings.Join(e.Images, ", "))
+}
+
+// checkImagePolicy returns an ImagePolicyError if the node plan references an image outside of the allowed registries of
+// the image policy of the control plane. The images of the instructions and of the workloads in the manifests delivered
+// to the server manifests directory are checked. Images deployed by charts are not known to the planner and are not
+// checked.
+func checkImagePolicy(controlPlane *rkev1.RKEControlPlane, entry *planEntry, nodePlan plan.NodePlan) error {
+	if controlPlane.Spec.ImagePolicy == nil {
+		return nil
+	}
+
+	images, err := planImages(nodePlan)
+	if err != nil {
+		return err
+	}
+
+	var denied []string
+	for _, image := range images {
+		if !imageAllowed(controlPlane.Spec.ImagePolicy.AllowedRegistries, image) {
+			denied = append(denied, image)
+		}
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+	return &ImagePolicyError{
+		Machine: entry.Machine.Namespace + "/" + entry.Machine.Name,
+		Images:  denied,
+	}
+}
+
+// planImages returns the sorted, unique images referenced by the instructions and manifests of the node plan.
+func planImages(nodePlan plan.NodePlan) ([]string, error) {
+	images := map[string]bool{}
+	for _, instruction := range nodePlan.Instructions {
+		if instruction.Image != "" {
+			images[instruction.Image] = true
+		}
+	}
+	for _, instruction := range nodePlan.PeriodicInstructions {
+		if instruction.Image != "" {
+			images[instruction.Image] = true
+		}
+	}
+	for _, file := range nodePlan.Files {
+		if !strings.Contains(file.Path, "/server/manifests/") {
+			continue
+		}
+		content, err := base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			return nil, fmt.Errorf("decoding manifest %s: %w", file.Path, err)
+		}
+		objs, err := yaml.ToObjects(strings.NewReader(string(content)))
+		if err != nil {
+			return nil, fmt.Errorf("parsing manifest %s: %w", file.Path, err)
+		}
+		for _, obj := range objs {
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				collectImages(u.Object, images)
+			}
+		}
+	}
+
+	result := make([]string, 0, len(images))
+	for image := range images {
+		result = append(result, image)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// collectImages adds the values of all "image" fields of the containers found in the object to the images.
+func collectImages(obj interface{}, images map[string]bool) {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if key == "containers" || key == "initContainers" || key == "ephemeralContainers" {
+				if containers, ok := value.([]interface{}); ok {
+					for _, container := range containers {
+						c, _ := container.(map[string]interface{})
+						if image, ok := c["image"].(string); ok && image != "" {
+							images[image] = true
+						}
+					}
+				}
+			}
+			collectImages(value, images)
+		}
+	case []interface{}:
+		for _, value := range v {
+			collectImages(value, images)
+		}
+	}
+}
+
+// imageAllowed returns true if the image is in one of the allowed registries or repository paths.
+func imageAllowed(allowed []string, image string) bool {
+	name := imageName(image)
+	for _, a := range allowed {
+		a = strings.TrimSuffix(a, "/")
+		if name == a || strings.HasPrefix(name, a+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// imageName returns the fully qualified name of the image without its tag or digest, i.e.
+// "docker.io/rancher/system-agent" for "rancher/system-agent:v0.3.3".
+func imageName(image string) string {
+	if i := strings.Index(image, "@"); i != -1 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i != -1 && !strings.Contains(image[i:], "/") {
+		image = image[:i]
+	}
+	i := strings.Index(image, "/")
+	if i == -1 {
+		return defaultImageRegistry + "/library/" + image
+	}
+	if host := image[:i]; !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return defaultImageRegistry + "/" + image
+	}
+	return image
+}
+
+// reportImagePolicy records the result of the image policy check of the plans of the control plane in the ImagesAllowed
+// condition. Plans that violate the image policy are not delivered, and the control plane waits for the policy or the
+// cluster to be changed. The condition is only set to true once the plans were rendered without a violation, which is
+// the case if the reconciliation completed or is waiting for machines.
+func reportImagePolicy(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, err error) (rkev1.RKEControlPlaneStatus, error) {
+	if cp.Spec.ImagePolicy == nil {
+		return status, err
+	}
+	var imagePolicyErr *ImagePolicyError
+	if errors.As(err, &imagePolicyErr) {
+		capr.ImagesAllowed.False(&status)
+		capr.ImagesAllowed.Reason(&status, "ImageNotAllowed")
+		capr.ImagesAllowed.Message(&status, imagePolicyErr.Error())
+		return status, errWaiting(imagePolicyErr.Error())
+	}
+	if err == nil || IsErrWaiting(err) {
+		capr.ImagesAllowed.True(&status)
+		capr.ImagesAllowed.Reason(&status, "")
+		capr.ImagesAllowed.Message(&status, "")
+	}
+	return status, err
+}
@@ -0,0 +1,92 @@
+package planner
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: cattle-cluster-agent
+  namespace: cattle-system
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: registry.example.com/rancher/init:v1
+      containers:
+      - name: cluster-register
+        image: rancher/rancher-agent:v2.7.5
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: not-a-workload
+data:
+  image: ignored
+`
+
+func TestImageName(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                                   "docker.io/library/nginx",
+		"rancher/system-agent:v0.3.3-suc":         "docker.io/rancher/system-agent",
+		"registry.example.com/rancher/agent:v1":   "registry.example.com/rancher/agent",
+		"registry.example.com:5000/agent@sha256:": "registry.example.com:5000/agent",
+		"localhost/agent":                         "localhost/agent",
+	}
+	for image, expected := range tests {
+		assert.Equal(t, expected, imageName(image), image)
+	}
+}
+
+func TestImageAllowed(t *testing.T) {
+	allowed := []string{"registry.example.com/rancher/", "docker.io/rancher"}
+	assert.True(t, imageAllowed(allowed, "registry.example.com/rancher/agent:v1"))
+	assert.True(t, imageAllowed(allowed, "rancher/system-agent:v0.3.3"))
+	assert.False(t, imageAllowed(allowed, "registry.example.com/other/agent:v1"))
+	assert.False(t, imageAllowed(allowed, "registry.example.com.evil.com/rancher/agent:v1"))
+	assert.False(t, imageAllowed(allowed, "nginx"))
+}
+
+func TestCheckImagePolicy(t *testing.T) {
+	nodePlan := plan.NodePlan{
+		Instructions: []plan.OneTimeInstruction{{Name: "install", Image: "registry.example.com/rancher/system-agent-installer-rke2:v1.25.9-rke2r1"}},
+		Files: []plan.File{
+			{Path: "/var/lib/rancher/rke2/server/manifests/rancher/cluster-agent.yaml", Content: base64.StdEncoding.EncodeToString([]byte(testManifest))},
+			{Path: "/etc/rancher/rke2/config.yaml.d/50-rancher.yaml", Content: base64.StdEncoding.EncodeToString([]byte("image: ignored"))},
+		},
+	}
+	entry := createTestPlanEntry("linux")
+	entry.Machine.Namespace, entry.Machine.Name = "fleet-default", "machine"
+
+	controlPlane := createTestControlPlane("v1.25.9+rke2r1")
+	assert.NoError(t, checkImagePolicy(controlPlane, entry, nodePlan), "no policy allows all images")
+
+	controlPlane.Spec.ImagePolicy = &rkev1.ImagePolicy{AllowedRegistries: []string{"registry.example.com"}}
+	err := checkImagePolicy(controlPlane, entry, nodePlan)
+	var imagePolicyErr *ImagePolicyError
+	if assert.True(t, errors.As(err, &imagePolicyErr)) {
+		assert.Equal(t, "fleet-default/machine", imagePolicyErr.Machine)
+		assert.Equal(t, []string{"rancher/rancher-agent:v2.7.5"}, imagePolicyErr.Images)
+	}
+
+	status, err := reportImagePolicy(controlPlane, rkev1.RKEControlPlaneStatus{}, err)
+	assert.True(t, IsErrWaiting(err))
+	assert.True(t, capr.ImagesAllowed.IsFalse(&status))
+	assert.Equal(t, "ImageNotAllowed", capr.ImagesAllowed.GetReason(&status))
+
+	controlPlane.Spec.ImagePolicy.AllowedRegistries = append(controlPlane.Spec.ImagePolicy.AllowedRegistries, "docker.io/rancher")
+	err = checkImagePolicy(controlPlane, entry, nodePlan)
+	assert.NoError(t, err)
+	status, err = reportImagePolicy(controlPlane, status, err)
+	assert.NoError(t, err)
+	assert.True(t, capr.ImagesAllowed.IsTrue(&status))
+}
@@ -0,0 +1,136 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/slice"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// InstructionPolicyError describes the instruction command or file path of a plan that is not allowed by the
+// instruction policies of the cluster.
+type InstructionPolicyError struct {
+	Machine string
+	Reason  string
+}
+
+func (e *InstructionPolicyError) Error() string {
+	return fmt.Sprintf("refusing to deliver plan to machine %s: %s", e.Machine, e.Reason)
+}
+
+// checkInstructionPolicy validates the passed in node plan against the instruction policies that apply to the namespace
+// of the machine, and returns an InstructionPolicyError if it is not allowed. If no policies apply, all instructions and
+// files are allowed.
+func (p *PlanStore) checkInstructionPolicy(entry *planEntry, nodePlan plan.NodePlan) error {
+	if p.instructionPolicyCache == nil {
+		return nil
+	}
+	policies, err := p.instructionPolicyCache.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	if reason := validateInstructionPolicies(applicableInstructionPolicies(policies, entry.Machine.Namespace), nodePlan); reason != "" {
+		return &InstructionPolicyError{
+			Machine: entry.Machine.Namespace + "/" + entry.Machine.Name,
+			Reason:  reason,
+		}
+	}
+	return nil
+}
+
+// applicableInstructionPolicies returns the policies that apply to clusters in the namespace.
+func applicableInstructionPolicies(policies []*rkev1.InstructionPolicy, namespace string) []*rkev1.InstructionPolicy {
+	var result []*rkev1.InstructionPolicy
+	for _, policy := range policies {
+		if len(policy.Spec.Namespaces) == 0 || slice.ContainsString(policy.Spec.Namespaces, namespace) {
+			result = append(result, policy)
+		}
+	}
+	return result
+}
+
+// validateInstructionPolicies returns a description of the first instruction command or file path in the node plan that
+// is not allowed by any of the passed in policies, or an empty string if the plan is allowed.
+func validateInstructionPolicies(policies []*rkev1.InstructionPolicy, nodePlan plan.NodePlan) string {
+	if len(policies) == 0 {
+		return ""
+	}
+
+	for _, instruction := range nodePlan.Instructions {
+		if !commandAllowed(policies, instruction.Command) {
+			return fmt.Sprintf("command [%s] of instruction [%s] is not allowed by instruction policy", instruction.Command, instruction.Name)
+		}
+	}
+	for _, instruction := range nodePlan.PeriodicInstructions {
+		if !commandAllowed(policies, instruction.Command) {
+			return fmt.Sprintf("command [%s] of periodic instruction [%s] is not allowed by instruction policy", instruction.Command, instruction.Name)
+		}
+	}
+	for _, file := range nodePlan.Files {
+		if !pathAllowed(policies, file.Path) {
+			return fmt.Sprintf("file path [%s] is not allowed by instruction policy", file.Path)
+		}
+	}
+	return ""
+}
+
+// reportInstructionPolicy records a plan refused by the instruction policies in the InstructionsAllowed condition, and
+// waits for the policies or the cluster to be changed. The condition is set to true again once the plans were
+// delivered without a refusal, which is the case if the reconciliation completed or is waiting for machines.
+func reportInstructionPolicy(status rkev1.RKEControlPlaneStatus, err error) (rkev1.RKEControlPlaneStatus, error) {
+	var instructionPolicyErr *InstructionPolicyError
+	if errors.As(err, &instructionPolicyErr) {
+		capr.InstructionsAllowed.False(&status)
+		capr.InstructionsAllowed.Reason(&status, "InstructionNotAllowed")
+		capr.InstructionsAllowed.Message(&status, instructionPolicyErr.Error())
+		return status, errWaiting(instructionPolicyErr.Error())
+	}
+	if (err == nil || IsErrWaiting(err)) && capr.InstructionsAllowed.GetStatus(&status) != "" {
+		capr.InstructionsAllowed.True(&status)
+		capr.InstructionsAllowed.Reason(&status, "")
+		capr.InstructionsAllowed.Message(&status, "")
+	}
+	return status, err
+}
+
+func commandAllowed(policies []*rkev1.InstructionPolicy, command string) bool {
+	for _, policy := range policies {
+		for _, allowed := range policy.Spec.AllowedCommands {
+			if matchPolicyPattern(allowed, command) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func pathAllowed(policies []*rkev1.InstructionPolicy, filePath string) bool {
+	for _, policy := range policies {
+		for _, allowed := range policy.Spec.AllowedPaths {
+			if matchPolicyPattern(allowed, filePath) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchPolicyPattern matches the value against the pattern using path.Match. A pattern ending in "/**" matches anything
+// beneath the directory preceding it.
+func matchPolicyPattern(pattern, value string) bool {
+	if strings.HasSuffix(pattern, "/**") {
+		dir := path.Clean(strings.TrimSuffix(pattern, "**"))
+		if !strings.HasSuffix(dir, "/") {
+			dir += "/"
+		}
+		return strings.HasPrefix(path.Clean(value), dir)
+	}
+	matched, err := path.Match(pattern, path.Clean(value))
+	return err == nil && matched
+}
@@ -0,0 +1,158 @@
+package planner
+
+import (
+	"fmt"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateInstructionPolicies(t *testing.T) {
+	nodePlan := plan.NodePlan{
+		Files: []plan.File{
+			{Path: "/etc/rancher/rke2/config.yaml.d/50-rancher.yaml"},
+			{Path: "/var/lib/rancher/rke2/server/manifests/rancher/addons.yaml"},
+		},
+		Instructions: []plan.OneTimeInstruction{
+			{Name: "install", Command: "sh"},
+		},
+		PeriodicInstructions: []plan.PeriodicInstruction{
+			{Name: "etcd-snapshot-list-local", Command: "/var/lib/rancher/rke2/bin/rke2"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policies []*rkev1.InstructionPolicy
+		expected string
+	}{
+		{
+			name: "no policies",
+		},
+		{
+			name: "everything allowed",
+			policies: []*rkev1.InstructionPolicy{
+				{
+					Spec: rkev1.InstructionPolicySpec{
+						AllowedCommands: []string{"sh", "/var/lib/rancher/rke2/bin/*"},
+						AllowedPaths:    []string{"/etc/rancher/**", "/var/lib/rancher/**"},
+					},
+				},
+			},
+		},
+		{
+			name: "allowed across multiple policies",
+			policies: []*rkev1.InstructionPolicy{
+				{
+					Spec: rkev1.InstructionPolicySpec{
+						AllowedCommands: []string{"sh"},
+						AllowedPaths:    []string{"/etc/rancher/rke2/config.yaml.d/*"},
+					},
+				},
+				{
+					Spec: rkev1.InstructionPolicySpec{
+						AllowedCommands: []string{"/var/lib/rancher/rke2/bin/rke2"},
+						AllowedPaths:    []string{"/var/lib/rancher/rke2/server/manifests/rancher/*"},
+					},
+				},
+			},
+		},
+		{
+			name: "instruction command not allowed",
+			policies: []*rkev1.InstructionPolicy{
+				{
+					Spec: rkev1.InstructionPolicySpec{
+						AllowedCommands: []string{"/var/lib/rancher/rke2/bin/*"},
+						AllowedPaths:    []string{"/**"},
+					},
+				},
+			},
+			expected: "command [sh] of instruction [install] is not allowed by instruction policy",
+		},
+		{
+			name: "periodic instruction command not allowed",
+			policies: []*rkev1.InstructionPolicy{
+				{
+					Spec: rkev1.InstructionPolicySpec{
+						AllowedCommands: []string{"sh"},
+						AllowedPaths:    []string{"/**"},
+					},
+				},
+			},
+			expected: "command [/var/lib/rancher/rke2/bin/rke2] of periodic instruction [etcd-snapshot-list-local] is not allowed by instruction policy",
+		},
+		{
+			name: "file path not allowed",
+			policies: []*rkev1.InstructionPolicy{
+				{
+					Spec: rkev1.InstructionPolicySpec{
+						AllowedCommands: []string{"sh", "/**"},
+						AllowedPaths:    []string{"/etc/rancher/**"},
+					},
+				},
+			},
+			expected: "file path [/var/lib/rancher/rke2/server/manifests/rancher/addons.yaml] is not allowed by instruction policy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, validateInstructionPolicies(tt.policies, nodePlan))
+		})
+	}
+}
+
+func TestMatchPolicyPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{pattern: "sh", value: "sh", expected: true},
+		{pattern: "sh", value: "bash", expected: false},
+		{pattern: "/var/lib/rancher/rke2/bin/*", value: "/var/lib/rancher/rke2/bin/rke2", expected: true},
+		{pattern: "/var/lib/rancher/rke2/bin/*", value: "/var/lib/rancher/rke2/bin/sub/rke2", expected: false},
+		{pattern: "/etc/rancher/*", value: "/etc/rancher/../shadow", expected: false},
+		{pattern: "/etc/rancher/**", value: "/etc/rancher/rke2/config.yaml.d/50-rancher.yaml", expected: true},
+		{pattern: "/etc/rancher/**", value: "/etc/rancher", expected: false},
+		{pattern: "/etc/rancher/**", value: "/etc/rancher-other/config.yaml", expected: false},
+		{pattern: "/etc/rancher/**", value: "/etc/rancher/../shadow", expected: false},
+		{pattern: "/**", value: "/etc/shadow", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.expected, matchPolicyPattern(tt.pattern, tt.value))
+		})
+	}
+}
+
+func TestApplicableInstructionPolicies(t *testing.T) {
+	all := &rkev1.InstructionPolicy{}
+	all.Name = "all"
+	scoped := &rkev1.InstructionPolicy{Spec: rkev1.InstructionPolicySpec{Namespaces: []string{"fleet-default"}}}
+	scoped.Name = "scoped"
+
+	assert.Equal(t, []*rkev1.InstructionPolicy{all, scoped}, applicableInstructionPolicies([]*rkev1.InstructionPolicy{all, scoped}, "fleet-default"))
+	assert.Equal(t, []*rkev1.InstructionPolicy{all}, applicableInstructionPolicies([]*rkev1.InstructionPolicy{all, scoped}, "fleet-other"))
+}
+
+func TestReportInstructionPolicy(t *testing.T) {
+	status, err := reportInstructionPolicy(rkev1.RKEControlPlaneStatus{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "", capr.InstructionsAllowed.GetStatus(&status), "condition is only set once a plan was refused")
+
+	refused := &InstructionPolicyError{Machine: "fleet-default/machine", Reason: "file path [/etc/shadow] is not allowed by instruction policy"}
+	status, err = reportInstructionPolicy(status, fmt.Errorf("wrapped: %w", refused))
+	assert.True(t, IsErrWaiting(err))
+	assert.True(t, capr.InstructionsAllowed.IsFalse(&status))
+	assert.Equal(t, "InstructionNotAllowed", capr.InstructionsAllowed.GetReason(&status))
+	assert.Equal(t, refused.Error(), capr.InstructionsAllowed.GetMessage(&status))
+
+	status, err = reportInstructionPolicy(status, errWaiting("waiting for machines"))
+	assert.True(t, IsErrWaiting(err))
+	assert.True(t, capr.InstructionsAllowed.IsTrue(&status))
+}
@@ -0,0 +1,70 @@
+package planner
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+)
+
+// CheckPlanInvariants returns an error describing the first invariant that the node plan violates. Every node plan
+// generated by the planner must deliver each file path at most once, as the system agent would write the path with
+// whichever file comes last, and must only carry the passed in secrets, such as the join tokens of the cluster, in the
+// content of files and in the environment of instructions, which are not exposed in logs or in the status of the
+// machines. The check is exported so that forks extending plan generation can assert it in their own tests.
+func CheckPlanInvariants(nodePlan plan.NodePlan, secrets ...string) error {
+	paths := map[string]bool{}
+	for _, file := range nodePlan.Files {
+		if paths[file.Path] {
+			return fmt.Errorf("file %s is delivered more than once", file.Path)
+		}
+		paths[file.Path] = true
+	}
+
+	exposed := plan.NodePlan{
+		Files:                make([]plan.File, len(nodePlan.Files)),
+		Instructions:         make([]plan.OneTimeInstruction, len(nodePlan.Instructions)),
+		PeriodicInstructions: make([]plan.PeriodicInstruction, len(nodePlan.PeriodicInstructions)),
+		Error:                nodePlan.Error,
+		Probes:               nodePlan.Probes,
+	}
+	for i, file := range nodePlan.Files {
+		file.Content = ""
+		exposed.Files[i] = file
+	}
+	for i, instruction := range nodePlan.Instructions {
+		instruction.Env = nil
+		exposed.Instructions[i] = instruction
+	}
+	for i, instruction := range nodePlan.PeriodicInstructions {
+		instruction.Env = nil
+		exposed.PeriodicInstructions[i] = instruction
+	}
+	data, err := json.Marshal(exposed)
+	if err != nil {
+		return err
+	}
+	for i, secret := range secrets {
+		if secret != "" && strings.Contains(string(data), secret) {
+			return fmt.Errorf("secret %d is exposed outside of file contents and instruction environments", i)
+		}
+	}
+	return nil
+}
+
+// CheckPlanDeterministic generates a node plan twice and returns an error if the plans or the errors of the generations
+// differ. Plans must be deterministic for the same input, as any difference is delivered to the machine, and a change
+// of a non-dynamic file restarts the distribution on it.
+func CheckPlanDeterministic(generate func() (plan.NodePlan, error)) error {
+	first, firstErr := generate()
+	second, secondErr := generate()
+	if fmt.Sprint(firstErr) != fmt.Sprint(secondErr) {
+		return fmt.Errorf("plan generation is not deterministic: got errors %v and %v", firstErr, secondErr)
+	}
+	if !reflect.DeepEqual(first, second) {
+		return fmt.Errorf("plan generation is not deterministic: generated plans differ")
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package planner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+var invariantTokens = plan.Secret{
+	ServerToken: "7f3c9a1be2d44c0f8e6b5a2d1c9e8f70",
+	AgentToken:  "0d8e7c6b5a4f3e2d1c0b9a8f7e6d5c4b",
+}
+
+func TestCheckPlanInvariants(t *testing.T) {
+	content := base64.StdEncoding.EncodeToString([]byte("token: " + invariantTokens.ServerToken))
+	tests := []struct {
+		name     string
+		nodePlan plan.NodePlan
+		wantErr  bool
+	}{
+		{
+			name: "secrets in files and env",
+			nodePlan: plan.NodePlan{
+				Files:        []plan.File{{Path: "/etc/rancher/rke2/config.yaml.d/50-rancher.yaml", Content: content}},
+				Instructions: []plan.OneTimeInstruction{{Name: "install", Env: []string{"TOKEN=" + invariantTokens.AgentToken}}},
+			},
+		},
+		{
+			name: "duplicate file",
+			nodePlan: plan.NodePlan{
+				Files: []plan.File{{Path: "/etc/rancher/rke2/registries.yaml"}, {Path: "/etc/rancher/rke2/registries.yaml"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "secret in args",
+			nodePlan: plan.NodePlan{
+				Instructions: []plan.OneTimeInstruction{{Name: "install", Args: []string{"--token", invariantTokens.ServerToken}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "secret in file path",
+			nodePlan: plan.NodePlan{
+				Files: []plan.File{{Path: "/var/lib/rancher/" + invariantTokens.AgentToken}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPlanInvariants(tt.nodePlan, invariantTokens.ServerToken, invariantTokens.AgentToken)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// planInput is a permutation of the spec of a control plane and the roles and OS of a machine that plans are generated
+// for.
+type planInput struct {
+	version      uint8
+	roles        uint8
+	windows      bool
+	ace          bool
+	etcd         uint8
+	apiserverArg string
+	kubeletArg   string
+	manifest     string
+	joinServer   string
+}
+
+var invariantVersions = []string{"v1.25.9+rke2r1", "v1.26.8+k3s1", "v1.27.6+rke2r1", "v1.28.2+k3s1"}
+
+func (in planInput) controlPlane() *rkev1.RKEControlPlane {
+	cp := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cluster",
+			Namespace:   "fleet-default",
+			Annotations: map[string]string{capr.ClusterSpecAnnotation: "{}"},
+		},
+		Spec: rkev1.RKEControlPlaneSpec{
+			ClusterName:           "cluster",
+			ManagementClusterName: "local",
+			KubernetesVersion:     invariantVersions[int(in.version)%len(invariantVersions)],
+			LocalClusterAuthEndpoint: rkev1.LocalClusterAuthEndpoint{
+				Enabled: in.ace,
+			},
+		},
+	}
+	cp.Spec.AdditionalManifest = in.manifest
+	cp.Spec.MachineGlobalConfig = rkev1.GenericMap{Data: map[string]interface{}{
+		"kube-apiserver-arg": []interface{}{in.apiserverArg},
+	}}
+	cp.Spec.MachineSelectorConfig = []rkev1.RKESystemConfig{
+		{
+			Config: rkev1.GenericMap{Data: map[string]interface{}{
+				"kubelet-arg": []interface{}{in.kubeletArg},
+			}},
+		},
+	}
+	if in.etcd&1 != 0 {
+		cp.Spec.ETCD = &rkev1.ETCD{
+			DisableSnapshots:     in.etcd&2 != 0,
+			SnapshotRetention:    int(in.etcd >> 2),
+			SnapshotScheduleCron: "0 */5 * * *",
+		}
+	}
+	return cp
+}
+
+func (in planInput) entry() *planEntry {
+	etcd, controlPlane, worker := in.roles&1 != 0, in.roles&2 != 0, in.roles&4 != 0
+	if !etcd && !controlPlane {
+		worker = true
+	}
+	os := "linux"
+	if in.windows && !etcd && !controlPlane {
+		os = "windows"
+	}
+	labels := map[string]string{
+		capr.CattleOSLabel:         os,
+		capr.EtcdRoleLabel:         fmt.Sprint(etcd),
+		capr.ControlPlaneRoleLabel: fmt.Sprint(controlPlane),
+		capr.WorkerRoleLabel:       fmt.Sprint(worker),
+	}
+	if etcd && in.roles&8 != 0 {
+		labels[capr.InitNodeLabel] = "true"
+	}
+	return &planEntry{
+		Machine: &capi.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "fleet-default", Labels: labels},
+			Status: capi.MachineStatus{
+				NodeInfo: &v1.NodeSystemInfo{OperatingSystem: os},
+			},
+		},
+		Metadata: &plan.Metadata{
+			Labels: labels,
+			Annotations: map[string]string{
+				capr.AddressAnnotation:         "10.0.0.10",
+				capr.InternalAddressAnnotation: "192.168.0.10",
+			},
+		},
+	}
+}
+
+// checkPlanInput generates the plan with config files for the input and checks the plan invariants. Inputs that are
+// rejected by plan generation are not checked further, but must be rejected consistently.
+func checkPlanInput(t *testing.T, in planInput) {
+	t.Helper()
+	var p Planner
+	generate := func() (plan.NodePlan, error) {
+		nodePlan, _, _, err := p.generatePlanWithConfigFiles(in.controlPlane(), invariantTokens, in.entry(), in.joinServer)
+		return nodePlan, err
+	}
+
+	if err := CheckPlanDeterministic(generate); err != nil {
+		t.Fatalf("input %+v: %v", in, err)
+	}
+	nodePlan, err := generate()
+	if err != nil {
+		return
+	}
+	if err := CheckPlanInvariants(nodePlan, invariantTokens.ServerToken, invariantTokens.AgentToken); err != nil {
+		t.Fatalf("input %+v: %v", in, err)
+	}
+}
+
+func TestGeneratePlanWithConfigFilesProperties(t *testing.T) {
+	args := []string{"", "v=2", "--v=4", "audit-log-maxage=30", "feature-gates=A=true", "pod-eviction-timeout=1m", "service-account-api-audiences=rke2"}
+	manifests := []string{"", "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: test\n"}
+	joinServers := []string{"", "-", "https://10.0.0.1:9345"}
+
+	r := rand.New(rand.NewSource(1))
+	permutations := 500
+	if testing.Short() {
+		permutations = 50
+	}
+	for i := 0; i < permutations; i++ {
+		checkPlanInput(t, planInput{
+			version:      uint8(r.Intn(len(invariantVersions))),
+			roles:        uint8(r.Intn(16)),
+			windows:      r.Intn(2) == 0,
+			ace:          r.Intn(2) == 0,
+			etcd:         uint8(r.Intn(256)),
+			apiserverArg: args[r.Intn(len(args))],
+			kubeletArg:   args[r.Intn(len(args))],
+			manifest:     manifests[r.Intn(len(manifests))],
+			joinServer:   joinServers[r.Intn(len(joinServers))],
+		})
+	}
+}
+
+func FuzzGeneratePlanWithConfigFiles(f *testing.F) {
+	f.Add(uint8(0), uint8(7), false, true, uint8(5), "audit-log-maxage=30", "v=2", "", "")
+	f.Add(uint8(1), uint8(9), false, false, uint8(0), "", "", "apiVersion: v1\nkind: Namespace\n", "-")
+	f.Add(uint8(2), uint8(4), true, false, uint8(1), "pod-eviction-timeout=1m", "--v=4", "", "https://10.0.0.1:9345")
+	f.Fuzz(func(t *testing.T, version, roles uint8, windows, ace bool, etcd uint8, apiserverArg, kubeletArg, manifest, joinServer string) {
+		checkPlanInput(t, planInput{
+			version:      version,
+			roles:        roles,
+			windows:      windows,
+			ace:          ace,
+			etcd:         etcd,
+			apiserverArg: apiserverArg,
+			kubeletArg:   kubeletArg,
+			manifest:     manifest,
+			joinServer:   joinServer,
+		})
+	})
+}
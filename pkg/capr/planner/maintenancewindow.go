@@ -0,0 +1,122 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// maintenanceWindowQueue holds the disruptive plan changes of machines outside of their maintenance windows, and records
+// the machines it held until their windows open.
+type maintenanceWindowQueue struct {
+	cp  *rkev1.RKEControlPlane
+	now time.Time
+	// poolWindows are the maintenance windows of the machine pools of the cluster, keyed by the name of the pool.
+	poolWindows map[string]string
+	// queued are the times the maintenance windows of the held machines open, keyed by the name of the machine.
+	queued map[string]time.Time
+}
+
+// planHoldFunc returns why the change of the plan of the machine of the entry to the new plan is held, or an empty
+// string if it may be applied.
+type planHoldFunc func(entry *planEntry, newPlan plan.NodePlan) string
+
+// maintenanceWindowQueue returns the queue of the maintenance windows of the machines of the control plane at the time.
+func (p *Planner) maintenanceWindowQueue(cp *rkev1.RKEControlPlane, now time.Time) (*maintenanceWindowQueue, error) {
+	queue := &maintenanceWindowQueue{
+		cp:          cp,
+		now:         now,
+		poolWindows: map[string]string{},
+		queued:      map[string]time.Time{},
+	}
+	cluster, err := p.rancherClusterCache.Get(cp.Namespace, cp.Spec.ClusterName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	} else if err == nil && cluster.Spec.RKEConfig != nil {
+		for _, pool := range cluster.Spec.RKEConfig.MachinePools {
+			if window := pool.MachineDeploymentAnnotations[capr.MaintenanceWindowAnnotation]; window != "" {
+				queue.poolWindows[pool.Name] = window
+			}
+		}
+		if topology := cluster.Spec.RKEConfig.Topology; topology != nil {
+			// the machines of the machine deployments of the topology are labeled with their name as their pool
+			for _, machineDeployment := range topology.MachineDeployments {
+				if window := machineDeployment.Annotations[capr.MaintenanceWindowAnnotation]; window != "" {
+					queue.poolWindows[machineDeployment.Name] = window
+				}
+			}
+		}
+	}
+	return queue, nil
+}
+
+// hold holds the disruptive plan change of the machine of the entry if its maintenance window is closed. Only changes
+// that restart the engine of the machine, such as upgrades, are disruptive, so that other changes still remediate the
+// machine. The window of the machine takes precedence over the window of its machine pool. Machines with an invalid
+// window are not held, and neither are machines whose plan change is already in progress.
+func (q *maintenanceWindowQueue) hold(entry *planEntry, newPlan plan.NodePlan) string {
+	if q == nil || entry.Plan == nil || !shouldDrain(entry.Plan.AppliedPlan, newPlan) {
+		return ""
+	}
+	if isInDrain(entry) || entry.Plan.Failed || planAppliedButWaitingForProbes(entry) {
+		return ""
+	}
+	value := entry.Machine.Annotations[capr.MaintenanceWindowAnnotation]
+	if value == "" {
+		value = q.poolWindows[entry.Machine.Labels[capr.RKEMachinePoolNameLabel]]
+	}
+	if value == "" {
+		return ""
+	}
+
+	window, err := capr.ParseMaintenanceWindow(value)
+	if err != nil {
+		logrus.Warnf("[planner] rkecluster %s/%s: ignoring maintenance window of machine %s: %v", q.cp.Namespace, q.cp.Name, entry.Machine.Name, err)
+		return ""
+	}
+	open, wait, _ := capr.MaintenanceWindowState(window, q.now)
+	if open {
+		return ""
+	}
+	opens := q.now.UTC().Add(wait)
+	q.queued[entry.Machine.Name] = opens
+	return fmt.Sprintf("waiting for the maintenance window opening at %s", opens.Format(time.RFC3339))
+}
+
+// reportMaintenanceWindowQueue records the machines that were held until their maintenance windows open in the
+// MaintenanceWindowQueued condition of the status, and processes the control plane again once the first of the windows
+// opens.
+func (p *Planner) reportMaintenanceWindowQueue(status rkev1.RKEControlPlaneStatus, queue *maintenanceWindowQueue) rkev1.RKEControlPlaneStatus {
+	if queue == nil || len(queue.queued) == 0 {
+		if capr.MaintenanceWindowQueued.IsTrue(&status) {
+			capr.MaintenanceWindowQueued.False(&status)
+			capr.MaintenanceWindowQueued.Reason(&status, "")
+			capr.MaintenanceWindowQueued.Message(&status, "")
+		}
+		return status
+	}
+
+	var (
+		queued []string
+		first  time.Time
+	)
+	for name, opens := range queue.queued {
+		queued = append(queued, fmt.Sprintf("machine %s: until %s", name, opens.Format(time.RFC3339)))
+		if first.IsZero() || opens.Before(first) {
+			first = opens
+		}
+	}
+	sort.Strings(queued)
+	capr.MaintenanceWindowQueued.True(&status)
+	capr.MaintenanceWindowQueued.Reason(&status, "OutsideMaintenanceWindow")
+	capr.MaintenanceWindowQueued.Message(&status, strings.Join(queued, "; "))
+	p.rkeControlPlanes.EnqueueAfter(queue.cp.Namespace, queue.cp.Name, first.Sub(queue.now))
+	return status
+}
@@ -0,0 +1,124 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeControlPlanes struct {
+	rkecontrollers.RKEControlPlaneController
+	enqueuedAfter time.Duration
+}
+
+func (f *fakeControlPlanes) EnqueueAfter(_, _ string, duration time.Duration) {
+	f.enqueuedAfter = duration
+}
+
+func windowEntry(name, pool, window string) *planEntry {
+	entry := poolEntry(pool)
+	entry.Machine.Name = name
+	if window != "" {
+		entry.Machine.Annotations = map[string]string{capr.MaintenanceWindowAnnotation: window}
+	}
+	entry.Plan = &plan.Node{AppliedPlan: restartPlan("a")}
+	entry.Metadata = &plan.Metadata{}
+	return entry
+}
+
+// restartPlan returns a plan with the restart stamp, plans with different restart stamps restart the engine.
+func restartPlan(stamp string) *plan.NodePlan {
+	return &plan.NodePlan{Instructions: []plan.OneTimeInstruction{{Env: []string{"RESTART_STAMP=" + stamp}}}}
+}
+
+func TestMaintenanceWindowQueue(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2023, 5, 3, 12, 0, 0, 0, time.UTC)
+	cp := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"},
+		Spec:       rkev1.RKEControlPlaneSpec{ClusterName: "test"},
+	}
+	controlPlanes := &fakeControlPlanes{}
+	p := &Planner{
+		rkeControlPlanes: controlPlanes,
+		rancherClusterCache: &fakeRancherClusterCache{cluster: &rancherv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"},
+			Spec: rancherv1.ClusterSpec{RKEConfig: &rancherv1.RKEConfig{MachinePools: []rancherv1.RKEMachinePool{
+				{Name: "weekend", MachineDeploymentAnnotations: map[string]string{capr.MaintenanceWindowAnnotation: `{"days":["Sat"],"start":"02:00","duration":"4h"}`}},
+				{Name: "always"},
+			}}},
+		}},
+	}
+
+	queue, err := p.maintenanceWindowQueue(cp, now)
+	require.NoError(t, err)
+
+	upgrade := *restartPlan("b")
+	assert.Equal(t, "waiting for the maintenance window opening at 2023-05-06T02:00:00Z", queue.hold(windowEntry("pool-window", "weekend", ""), upgrade))
+	assert.Equal(t, "", queue.hold(windowEntry("machine-window", "weekend", `{"start":"11:00","duration":"2h"}`), upgrade), "the window of the machine takes precedence")
+	assert.Equal(t, "waiting for the maintenance window opening at 2023-05-03T20:00:00Z", queue.hold(windowEntry("tonight", "always", `{"start":"20:00","duration":"1h"}`), upgrade))
+	assert.Equal(t, "", queue.hold(windowEntry("no-window", "always", ""), upgrade))
+	assert.Equal(t, "", queue.hold(windowEntry("invalid", "always", `{"start":"20:00"}`), upgrade))
+
+	draining := windowEntry("draining", "weekend", "")
+	draining.Metadata.Annotations = map[string]string{capr.DrainAnnotation: "drain"}
+	assert.Equal(t, "", queue.hold(draining, upgrade), "plan changes in progress are not held")
+
+	status := p.reportMaintenanceWindowQueue(rkev1.RKEControlPlaneStatus{}, queue)
+	assert.True(t, capr.MaintenanceWindowQueued.IsTrue(&status))
+	assert.Equal(t, "machine pool-window: until 2023-05-06T02:00:00Z; machine tonight: until 2023-05-03T20:00:00Z", capr.MaintenanceWindowQueued.GetMessage(&status))
+	assert.Equal(t, 8*time.Hour, controlPlanes.enqueuedAfter)
+
+	status = p.reportMaintenanceWindowQueue(status, nil)
+	assert.True(t, capr.MaintenanceWindowQueued.IsFalse(&status))
+	assert.Equal(t, "", capr.MaintenanceWindowQueued.GetMessage(&status))
+}
+
+func TestMaintenanceWindowQueueHoldDisruptive(t *testing.T) {
+	queue := &maintenanceWindowQueue{
+		cp:     &rkev1.RKEControlPlane{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"}},
+		now:    time.Date(2023, 5, 3, 12, 0, 0, 0, time.UTC),
+		queued: map[string]time.Time{},
+	}
+	const window = `{"start":"20:00","duration":"1h"}`
+
+	tests := []struct {
+		name     string
+		applied  *plan.NodePlan
+		newPlan  plan.NodePlan
+		expected string
+	}{
+		{
+			name:    "new machine",
+			newPlan: *restartPlan("a"),
+		},
+		{
+			name:    "change without restart",
+			applied: restartPlan("a"),
+			newPlan: plan.NodePlan{
+				Instructions: append(restartPlan("a").Instructions, plan.OneTimeInstruction{Name: "remediate"}),
+			},
+		},
+		{
+			name:     "change with restart",
+			applied:  restartPlan("a"),
+			newPlan:  *restartPlan("b"),
+			expected: "waiting for the maintenance window opening at 2023-05-03T20:00:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := windowEntry("worker-a", "always", window)
+			entry.Plan.AppliedPlan = tt.applied
+			assert.Equal(t, tt.expected, queue.hold(entry, tt.newPlan))
+		})
+	}
+}
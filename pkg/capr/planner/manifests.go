@@ -2,7 +2,10 @@
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
@@ -18,8 +21,7 @@
   name: %s-etcd-snapshot-extra-metadata
   namespace: %s
 data:
-  %s: %s
-`
+%s`
 
 // getControlPlaneManifests returns a slice of plan.File objects that are necessary to be placed on a controlplane node.
 func (p *Planner) getControlPlaneManifests(controlPlane *rkev1.RKEControlPlane, entry *planEntry) (result []plan.File, _ error) {
@@ -35,13 +37,7 @@ func (p *Planner) getControlPlaneManifests(controlPlane *rkev1.RKEControlPlane,
 	}
 	result = append(result, clusterAgent)
 
-	// if we have a nil snapshotMetadata object, it's probably because the annotation didn't exist on the controlplane object. this is not breaking though so don't block.
-	snapshotMetadata := getEtcdSnapshotExtraMetadata(controlPlane, capr.GetRuntime(controlPlane.Spec.KubernetesVersion))
-	if snapshotMetadata == nil {
-		logrus.Errorf("Error while generating etcd snapshot extra metadata manifest for cluster %s", controlPlane.Spec.ClusterName)
-	} else {
-		result = append(result, *snapshotMetadata)
-	}
+	result = append(result, getEtcdSnapshotExtraMetadata(controlPlane, capr.GetRuntime(controlPlane.Spec.KubernetesVersion)))
 
 	addons := p.getAddons(controlPlane, capr.GetRuntime(controlPlane.Spec.KubernetesVersion))
 	result = append(result, addons)
@@ -49,20 +45,36 @@ func (p *Planner) getControlPlaneManifests(controlPlane *rkev1.RKEControlPlane,
 	return result, nil
 }
 
-// getEtcdSnapshotExtraMetadata returns a plan.File that contains the ConfigMap manifest of the cluster specification, if it exists.
-// Otherwise, it will return an empty plan.File and log an error.
-func getEtcdSnapshotExtraMetadata(controlPlane *rkev1.RKEControlPlane, runtime string) *plan.File {
+// getEtcdSnapshotExtraMetadata returns a plan.File that contains the ConfigMap manifest of the metadata that is recorded
+// in etcd snapshots, which are the cluster specification, if it exists, and the versions and network configuration of
+// the cluster.
+func getEtcdSnapshotExtraMetadata(controlPlane *rkev1.RKEControlPlane, runtime string) plan.File {
+	metadata := capr.SnapshotMetadata(controlPlane)
 	if v, ok := controlPlane.Annotations[capr.ClusterSpecAnnotation]; ok {
-		cm := fmt.Sprintf(EtcdSnapshotExtraMetadataConfigMapTemplate, runtime, metav1.NamespaceSystem, EtcdSnapshotConfigMapKey, v)
-		return &plan.File{
-			Content: base64.StdEncoding.EncodeToString([]byte(cm)),
-			Path:    fmt.Sprintf("/var/lib/rancher/%s/server/manifests/rancher/%s-etcd-snapshot-extra-metadata.yaml", runtime, runtime),
-			Dynamic: true,
-			Minor:   true,
-		}
+		metadata[EtcdSnapshotConfigMapKey] = v
+	} else {
+		logrus.Errorf("rkecluster %s/%s: unable to find cluster spec annotation for control plane", controlPlane.Spec.ClusterName, controlPlane.Namespace)
+	}
+
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	data := &strings.Builder{}
+	for _, k := range keys {
+		value, _ := json.Marshal(metadata[k])
+		fmt.Fprintf(data, "  %s: %s\n", k, value)
+	}
+
+	cm := fmt.Sprintf(EtcdSnapshotExtraMetadataConfigMapTemplate, runtime, metav1.NamespaceSystem, data.String())
+	return plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(cm)),
+		Path:    fmt.Sprintf("/var/lib/rancher/%s/server/manifests/rancher/%s-etcd-snapshot-extra-metadata.yaml", runtime, runtime),
+		Dynamic: true,
+		Minor:   true,
 	}
-	logrus.Errorf("rkecluster %s/%s: unable to find cluster spec annotation for control plane", controlPlane.Spec.ClusterName, controlPlane.Namespace)
-	return nil
 }
 
 // getClusterAgentManifestFile returns a plan.File that contains the cluster agent manifest.
@@ -0,0 +1,224 @@
+package planner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	"github.com/rancher/wrangler/pkg/yaml"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	kubeProxyArg           = "kube-proxy-arg"
+	kubeProxyModeFlag      = "proxy-mode"
+	rke2CoreDNSChart       = "rke2-coredns"
+	coreDNSCustomConfigMap = "coredns-custom"
+	defaultClusterDomain   = "cluster.local"
+)
+
+// kubernetes131 is the first Kubernetes version in which the nftables mode of kube-proxy is enabled by default.
+var kubernetes131 = semver.MustParse("v1.31.0")
+
+// validateKubeProxy returns an error if the proxy mode is unknown or not supported by the Kubernetes version.
+func validateKubeProxy(kubeProxy *rkev1.KubeProxyConfig, kubernetesVersion string) error {
+	switch kubeProxy.Mode {
+	case "", rkev1.KubeProxyModeIPTables, rkev1.KubeProxyModeIPVS:
+		return nil
+	case rkev1.KubeProxyModeNFTables:
+		version, err := semver.NewVersion(kubernetesVersion)
+		if err != nil {
+			return err
+		}
+		if version.LessThan(kubernetes131) {
+			return fmt.Errorf("kube-proxy mode %s requires Kubernetes %s or newer", kubeProxy.Mode, kubernetes131)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid kube-proxy mode %q: must be one of %s, %s or %s", kubeProxy.Mode, rkev1.KubeProxyModeIPTables, rkev1.KubeProxyModeIPVS, rkev1.KubeProxyModeNFTables)
+}
+
+// addKubeProxy adds the proxy mode of the cluster to the kube-proxy args of the config. A proxy mode that is also set
+// through the kube-proxy-arg of the machine config is reported as conflicting by lintConfig.
+func addKubeProxy(config map[string]interface{}, controlPlane *rkev1.RKEControlPlane) error {
+	kubeProxy := controlPlane.Spec.KubeProxy
+	if kubeProxy == nil || kubeProxy.Mode == "" {
+		return nil
+	}
+	if err := validateKubeProxy(kubeProxy, controlPlane.Spec.KubernetesVersion); err != nil {
+		return err
+	}
+	config[kubeProxyArg] = append(append([]string{}, convertInterfaceToStringSlice(config[kubeProxyArg])...), fmt.Sprintf("%s=%s", kubeProxyModeFlag, kubeProxy.Mode))
+	return nil
+}
+
+// validateCoreDNS returns an error if a nameserver or stub domain of the CoreDNS config is invalid, or if the config
+// can not be applied to the distribution.
+func validateCoreDNS(coreDNS *rkev1.CoreDNSConfig, runtime string) error {
+	if len(coreDNS.Forwarders) > 0 && runtime != capr.RuntimeRKE2 {
+		return fmt.Errorf("coreDNS forwarders are only supported on %s", capr.RuntimeRKE2)
+	}
+	for _, forwarder := range coreDNS.Forwarders {
+		if err := validateNameserver(forwarder); err != nil {
+			return fmt.Errorf("invalid coreDNS forwarder: %w", err)
+		}
+	}
+	seen := map[string]bool{}
+	for _, stubDomain := range coreDNS.StubDomains {
+		if errs := validation.IsDNS1123Subdomain(stubDomain.Domain); len(errs) > 0 {
+			return fmt.Errorf("invalid coreDNS stub domain %q: %s", stubDomain.Domain, strings.Join(errs, ", "))
+		}
+		if seen[stubDomain.Domain] {
+			return fmt.Errorf("coreDNS stub domain %s is configured more than once", stubDomain.Domain)
+		}
+		seen[stubDomain.Domain] = true
+		if len(stubDomain.Servers) == 0 {
+			return fmt.Errorf("coreDNS stub domain %s must have at least one server", stubDomain.Domain)
+		}
+		for _, server := range stubDomain.Servers {
+			if err := validateNameserver(server); err != nil {
+				return fmt.Errorf("invalid server of coreDNS stub domain %s: %w", stubDomain.Domain, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateNameserver returns an error if the nameserver is not an IP address, optionally followed by a port.
+func validateNameserver(nameserver string) error {
+	host := nameserver
+	if h, _, err := net.SplitHostPort(nameserver); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) == nil {
+		return fmt.Errorf("%q is not an IP address", nameserver)
+	}
+	return nil
+}
+
+// coreDNSServers returns the server blocks of the rke2-coredns chart for the CoreDNS config. The default server block
+// of the chart is kept, with its forward plugin pointed at the forwarders if any are set.
+func coreDNSServers(coreDNS *rkev1.CoreDNSConfig, clusterDomain string) []interface{} {
+	upstream := "/etc/resolv.conf"
+	if len(coreDNS.Forwarders) > 0 {
+		upstream = strings.Join(coreDNS.Forwarders, " ")
+	}
+	servers := []interface{}{
+		map[string]interface{}{
+			"zones": []interface{}{map[string]interface{}{"zone": "."}},
+			"port":  53,
+			"plugins": []interface{}{
+				map[string]interface{}{"name": "errors"},
+				map[string]interface{}{"name": "health", "configBlock": "lameduck 5s"},
+				map[string]interface{}{"name": "ready"},
+				map[string]interface{}{
+					"name":        "kubernetes",
+					"parameters":  clusterDomain + " in-addr.arpa ip6.arpa",
+					"configBlock": "pods insecure\nfallthrough in-addr.arpa ip6.arpa\nttl 30",
+				},
+				map[string]interface{}{"name": "prometheus", "parameters": "0.0.0.0:9153"},
+				map[string]interface{}{"name": "forward", "parameters": ". " + upstream},
+				map[string]interface{}{"name": "cache", "parameters": 30},
+				map[string]interface{}{"name": "loop"},
+				map[string]interface{}{"name": "reload"},
+				map[string]interface{}{"name": "loadbalance"},
+			},
+		},
+	}
+	for _, stubDomain := range coreDNS.StubDomains {
+		servers = append(servers, map[string]interface{}{
+			"zones": []interface{}{map[string]interface{}{"zone": stubDomain.Domain}},
+			"port":  53,
+			"plugins": []interface{}{
+				map[string]interface{}{"name": "errors"},
+				map[string]interface{}{"name": "cache", "parameters": 30},
+				map[string]interface{}{"name": "forward", "parameters": ". " + strings.Join(stubDomain.Servers, " ")},
+			},
+		})
+	}
+	return servers
+}
+
+// addCoreDNSChartValues returns the chart values with the CoreDNS config of an rke2 cluster rendered into the values
+// of the rke2-coredns chart, so that it is delivered in the same HelmChartConfig as the rest of its values. Setting the
+// servers of the chart through the chart values as well is refused, as one of them would be silently dropped.
+func addCoreDNSChartValues(controlPlane *rkev1.RKEControlPlane, chartValues map[string]interface{}) (map[string]interface{}, error) {
+	coreDNS := controlPlane.Spec.CoreDNS
+	clusterRuntime := capr.GetRuntime(controlPlane.Spec.KubernetesVersion)
+	if coreDNS == nil || clusterRuntime != capr.RuntimeRKE2 || (len(coreDNS.Forwarders) == 0 && len(coreDNS.StubDomains) == 0) {
+		return chartValues, nil
+	}
+	if err := validateCoreDNS(coreDNS, clusterRuntime); err != nil {
+		return nil, err
+	}
+
+	values := convert.ToMapInterface(chartValues[rke2CoreDNSChart])
+	if _, ok := values["servers"]; ok {
+		return nil, fmt.Errorf("coreDNS can not be configured while the servers of the %s chart are set in the chart values", rke2CoreDNSChart)
+	}
+
+	clusterDomain := convert.ToString(controlPlane.Spec.MachineGlobalConfig.Data["cluster-domain"])
+	if clusterDomain == "" {
+		clusterDomain = defaultClusterDomain
+	}
+
+	result := make(map[string]interface{}, len(chartValues)+1)
+	for k, v := range chartValues {
+		result[k] = v
+	}
+	merged := make(map[string]interface{}, len(values)+1)
+	for k, v := range values {
+		merged[k] = v
+	}
+	merged["servers"] = coreDNSServers(coreDNS, clusterDomain)
+	result[rke2CoreDNSChart] = merged
+	return result, nil
+}
+
+// addCoreDNSCustomConfig adds the stub domains of a k3s cluster to the plan as the coredns-custom ConfigMap, which the
+// CoreDNS deployment of k3s imports additional server blocks from. The manifest is reapplied whenever it changes.
+func addCoreDNSCustomConfig(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane, entry *planEntry) (plan.NodePlan, error) {
+	coreDNS := controlPlane.Spec.CoreDNS
+	clusterRuntime := capr.GetRuntime(controlPlane.Spec.KubernetesVersion)
+	if isOnlyWorker(entry) || coreDNS == nil || clusterRuntime != capr.RuntimeK3S || (len(coreDNS.Forwarders) == 0 && len(coreDNS.StubDomains) == 0) {
+		return nodePlan, nil
+	}
+	if err := validateCoreDNS(coreDNS, clusterRuntime); err != nil {
+		return nodePlan, err
+	}
+
+	data := map[string]string{}
+	for _, stubDomain := range coreDNS.StubDomains {
+		data[stubDomain.Domain+".server"] = fmt.Sprintf("%s:53 {\n    errors\n    cache 30\n    forward . %s\n}\n", stubDomain.Domain, strings.Join(stubDomain.Servers, " "))
+	}
+	contents, err := yaml.ToBytes([]runtime.Object{&v1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      coreDNSCustomConfigMap,
+			Namespace: "kube-system",
+		},
+		Data: data,
+	}})
+	if err != nil {
+		return nodePlan, err
+	}
+
+	nodePlan.Files = append(nodePlan.Files, plan.File{
+		Content: base64.StdEncoding.EncodeToString(contents),
+		Path:    fmt.Sprintf("/var/lib/rancher/%s/server/manifests/rancher/managed-coredns-custom.yaml", clusterRuntime),
+		Dynamic: true,
+	})
+	return nodePlan, nil
+}
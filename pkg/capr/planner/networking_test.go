@@ -0,0 +1,199 @@
+package planner
+
+import (
+	"encoding/base64"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddKubeProxy(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		mode     rkev1.KubeProxyMode
+		existing []string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:    "unset",
+			version: "v1.25.9+rke2r1",
+		},
+		{
+			name:     "ipvs",
+			version:  "v1.25.9+rke2r1",
+			mode:     rkev1.KubeProxyModeIPVS,
+			existing: []string{"v=2"},
+			expected: []string{"v=2", "proxy-mode=ipvs"},
+		},
+		{
+			name:     "nftables",
+			version:  "v1.31.1+k3s1",
+			mode:     rkev1.KubeProxyModeNFTables,
+			expected: []string{"proxy-mode=nftables"},
+		},
+		{
+			name:    "nftables on old version",
+			version: "v1.30.4+k3s1",
+			mode:    rkev1.KubeProxyModeNFTables,
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			version: "v1.25.9+rke2r1",
+			mode:    "userspace",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlPlane := createTestControlPlane(tt.version)
+			controlPlane.Spec.KubeProxy = &rkev1.KubeProxyConfig{Mode: tt.mode}
+			config := map[string]interface{}{}
+			if tt.existing != nil {
+				config[kubeProxyArg] = tt.existing
+			}
+			err := addKubeProxy(config, controlPlane)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.expected == nil {
+				assert.NotContains(t, config, kubeProxyArg)
+				return
+			}
+			assert.Equal(t, tt.expected, config[kubeProxyArg])
+		})
+	}
+}
+
+func TestValidateCoreDNS(t *testing.T) {
+	tests := []struct {
+		name    string
+		runtime string
+		coreDNS rkev1.CoreDNSConfig
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			runtime: capr.RuntimeRKE2,
+			coreDNS: rkev1.CoreDNSConfig{
+				Forwarders:  []string{"1.1.1.1", "[2606:4700::1111]:53"},
+				StubDomains: []rkev1.CoreDNSStubDomain{{Domain: "corp.example.com", Servers: []string{"10.0.0.10"}}},
+			},
+		},
+		{
+			name:    "forwarders on k3s",
+			runtime: capr.RuntimeK3S,
+			coreDNS: rkev1.CoreDNSConfig{Forwarders: []string{"1.1.1.1"}},
+			wantErr: true,
+		},
+		{
+			name:    "forwarder is not an IP address",
+			runtime: capr.RuntimeRKE2,
+			coreDNS: rkev1.CoreDNSConfig{Forwarders: []string{"dns.example.com"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid stub domain",
+			runtime: capr.RuntimeK3S,
+			coreDNS: rkev1.CoreDNSConfig{StubDomains: []rkev1.CoreDNSStubDomain{{Domain: "Corp_Example", Servers: []string{"10.0.0.10"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate stub domain",
+			runtime: capr.RuntimeK3S,
+			coreDNS: rkev1.CoreDNSConfig{StubDomains: []rkev1.CoreDNSStubDomain{
+				{Domain: "corp.example.com", Servers: []string{"10.0.0.10"}},
+				{Domain: "corp.example.com", Servers: []string{"10.0.0.11"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "stub domain without servers",
+			runtime: capr.RuntimeK3S,
+			coreDNS: rkev1.CoreDNSConfig{StubDomains: []rkev1.CoreDNSStubDomain{{Domain: "corp.example.com"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCoreDNS(&tt.coreDNS, tt.runtime)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAddCoreDNSChartValues(t *testing.T) {
+	controlPlane := createTestControlPlane("v1.25.9+rke2r1")
+	controlPlane.Spec.CoreDNS = &rkev1.CoreDNSConfig{
+		Forwarders:  []string{"1.1.1.1"},
+		StubDomains: []rkev1.CoreDNSStubDomain{{Domain: "corp.example.com", Servers: []string{"10.0.0.10", "10.0.0.11"}}},
+	}
+	chartValues := map[string]interface{}{
+		rke2CoreDNSChart: map[string]interface{}{"replicaCount": 3},
+	}
+
+	result, err := addCoreDNSChartValues(controlPlane, chartValues)
+	require.NoError(t, err)
+	values := result[rke2CoreDNSChart].(map[string]interface{})
+	assert.Equal(t, 3, values["replicaCount"])
+	servers := values["servers"].([]interface{})
+	if assert.Len(t, servers, 2) {
+		assert.Contains(t, servers[0].(map[string]interface{})["plugins"], map[string]interface{}{"name": "forward", "parameters": ". 1.1.1.1"})
+		assert.Contains(t, servers[0].(map[string]interface{})["plugins"], map[string]interface{}{
+			"name":        "kubernetes",
+			"parameters":  "cluster.local in-addr.arpa ip6.arpa",
+			"configBlock": "pods insecure\nfallthrough in-addr.arpa ip6.arpa\nttl 30",
+		})
+		assert.Contains(t, servers[1].(map[string]interface{})["plugins"], map[string]interface{}{"name": "forward", "parameters": ". 10.0.0.10 10.0.0.11"})
+	}
+	assert.NotContains(t, chartValues[rke2CoreDNSChart], "servers", "the chart values of the control plane must not be modified")
+
+	chartValues[rke2CoreDNSChart] = map[string]interface{}{"servers": []interface{}{}}
+	_, err = addCoreDNSChartValues(controlPlane, chartValues)
+	assert.Error(t, err, "servers set in the chart values conflict with the CoreDNS config")
+
+	k3s := createTestControlPlane("v1.25.9+k3s1")
+	k3s.Spec.CoreDNS = &rkev1.CoreDNSConfig{StubDomains: controlPlane.Spec.CoreDNS.StubDomains}
+	result, err = addCoreDNSChartValues(k3s, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestAddCoreDNSCustomConfig(t *testing.T) {
+	controlPlane := createTestControlPlane("v1.25.9+k3s1")
+	controlPlane.Spec.CoreDNS = &rkev1.CoreDNSConfig{
+		StubDomains: []rkev1.CoreDNSStubDomain{{Domain: "corp.example.com", Servers: []string{"10.0.0.10"}}},
+	}
+
+	worker := createTestPlanEntry("linux")
+	nodePlan, err := addCoreDNSCustomConfig(plan.NodePlan{}, controlPlane, worker)
+	assert.NoError(t, err)
+	assert.Empty(t, nodePlan.Files)
+
+	server := createTestPlanEntry("linux")
+	server.Metadata.Labels[capr.ControlPlaneRoleLabel] = "true"
+	nodePlan, err = addCoreDNSCustomConfig(plan.NodePlan{}, controlPlane, server)
+	require.NoError(t, err)
+	if assert.Len(t, nodePlan.Files, 1) {
+		file := nodePlan.Files[0]
+		assert.Equal(t, "/var/lib/rancher/k3s/server/manifests/rancher/managed-coredns-custom.yaml", file.Path)
+		assert.True(t, file.Dynamic)
+		content, err := base64.StdEncoding.DecodeString(file.Content)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "name: coredns-custom")
+		assert.Contains(t, string(content), "corp.example.com.server")
+		assert.Contains(t, string(content), "forward . 10.0.0.10")
+	}
+}
@@ -0,0 +1,212 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	nodeCommandInstructionName = "node-command"
+	maxNodeCommandOutputBytes  = 32 * 1024
+)
+
+// runNodeCommands runs the node command of the cluster that is running, or starts the oldest pending node command once
+// the cluster is ready. The command runs on the target machines one at a time by replacing their plans. A running
+// command is continued before the cluster is reconciled, as reconciling would revert the plans of the target machines;
+// once the command finished, the next reconciliation restores them.
+func (p *Planner) runNodeCommands(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, tokensSecret plan.Secret, clusterPlan *plan.Plan) error {
+	commands, err := p.nodeCommandCache.List(controlPlane.Namespace, labels.Everything())
+	if err != nil {
+		return err
+	}
+	command := nextNodeCommand(commands, controlPlane.Name)
+	if command == nil {
+		return nil
+	}
+	command = command.DeepCopy()
+
+	if command.Status.Phase == rkev1.NodeCommandPhasePending {
+		if !capr.Ready.IsTrue(&status) {
+			return nil
+		}
+		command, err = p.startNodeCommand(command, clusterPlan)
+		if err != nil || command.Status.Phase != rkev1.NodeCommandPhaseRunning {
+			return err
+		}
+	}
+
+	found, joinServer, _, err := p.findInitNode(controlPlane, clusterPlan)
+	if err != nil {
+		return err
+	}
+	if !found || joinServer == "" {
+		return errWaiting("waiting for the init node to run node commands")
+	}
+
+	done := sets.NewString()
+	for _, result := range command.Status.Results {
+		done.Insert(result.MachineName)
+	}
+	for _, machineName := range command.Status.TargetMachines {
+		if done.Has(machineName) {
+			continue
+		}
+		result, err := p.runNodeCommand(controlPlane, tokensSecret, clusterPlan, joinServer, command, machineName)
+		if err != nil {
+			return err
+		}
+		command.Status.Results = append(command.Status.Results, result)
+		if command, err = p.nodeCommands.UpdateStatus(command); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, result := range command.Status.Results {
+		if !result.Succeeded {
+			failed++
+		}
+	}
+	command.Status.Phase = rkev1.NodeCommandPhaseSucceeded
+	command.Status.Message = ""
+	if failed > 0 {
+		command.Status.Phase = rkev1.NodeCommandPhaseFailed
+		command.Status.Message = fmt.Sprintf("command failed on %d of %d machines", failed, len(command.Status.Results))
+	}
+	now := metav1.Now()
+	command.Status.CompletionTime = &now
+	if _, err := p.nodeCommands.UpdateStatus(command); err != nil {
+		return err
+	}
+	logrus.Infof("[planner] rkecluster %s/%s: node command %s finished: %s", controlPlane.Namespace, controlPlane.Name, command.Name, command.Status.Phase)
+	return errWaitingf("restoring plans after node command %s", command.Name)
+}
+
+// startNodeCommand selects the target machines of the pending command and marks it as running. If no machine matches
+// the validated machine selector of the command, the command fails.
+func (p *Planner) startNodeCommand(command *rkev1.NodeCommand, clusterPlan *plan.Plan) (*rkev1.NodeCommand, error) {
+	selector := labels.Everything()
+	if command.Status.ValidatedSpec.MachineSelector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(command.Status.ValidatedSpec.MachineSelector); err != nil {
+			return command, err
+		}
+	}
+
+	now := metav1.Now()
+	command.Status.StartTime = &now
+	command.Status.TargetMachines = nil
+	for _, entry := range collect(clusterPlan, roleAnd(anyRole, isNotDeleting)) {
+		if selector.Matches(labels.Set(entry.Machine.Labels)) {
+			command.Status.TargetMachines = append(command.Status.TargetMachines, entry.Machine.Name)
+		}
+	}
+	if len(command.Status.TargetMachines) == 0 {
+		command.Status.Phase = rkev1.NodeCommandPhaseFailed
+		command.Status.Message = "no machine of the cluster matches the machine selector"
+		command.Status.CompletionTime = &now
+	} else {
+		command.Status.Phase = rkev1.NodeCommandPhaseRunning
+	}
+	return p.nodeCommands.UpdateStatus(command)
+}
+
+// runNodeCommand delivers a plan running the command to the machine and returns the result once the plan was applied.
+func (p *Planner) runNodeCommand(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, clusterPlan *plan.Plan, joinServer string, command *rkev1.NodeCommand, machineName string) (rkev1.NodeCommandResult, error) {
+	result := rkev1.NodeCommandResult{
+		MachineName: machineName,
+	}
+	entry := &planEntry{
+		Machine:  clusterPlan.Machines[machineName],
+		Plan:     clusterPlan.Nodes[machineName],
+		Metadata: clusterPlan.Metadata[machineName],
+	}
+	if entry.Machine == nil || entry.Metadata == nil || isDeleting(entry) {
+		result.Output = "machine no longer exists"
+		return result, nil
+	}
+
+	commandPlan, joinedServer, err := p.generateNodeCommandPlan(controlPlane, tokensSecret, entry, joinServer, command)
+	if err != nil {
+		return result, err
+	}
+	msg := fmt.Sprintf("node command %s on machine %s/%s", command.Name, entry.Machine.Namespace, entry.Machine.Name)
+	err = assignAndCheckPlan(p.store, msg, entry, commandPlan, joinedServer, 1, 1)
+	if IsErrWaiting(err) {
+		return result, err
+	}
+	result.Succeeded = err == nil
+	if entry.Plan != nil {
+		result.Output = truncateNodeCommandOutput(entry.Plan.Output[nodeCommandInstructionName])
+	}
+	if err != nil && result.Output == "" {
+		result.Output = err.Error()
+	}
+	return result, nil
+}
+
+// generateNodeCommandPlan generates a plan that contains an instruction running the node command.
+func (p *Planner) generateNodeCommandPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, entry *planEntry, joinServer string, command *rkev1.NodeCommand) (plan.NodePlan, string, error) {
+	commandPlan, _, joinedServer, err := p.generatePlanWithConfigFiles(controlPlane, tokensSecret, entry, joinServer)
+	if err != nil {
+		return commandPlan, joinedServer, err
+	}
+	commandPlan.Instructions = append(commandPlan.Instructions, p.generateInstallInstructionWithSkipStart(controlPlane, entry),
+		nodeCommandInstruction(command))
+	return commandPlan, joinedServer, nil
+}
+
+// nodeCommandInstruction returns the instruction running the validated spec of the node command. The current spec is
+// never run, as it may have changed since it was validated.
+func nodeCommandInstruction(command *rkev1.NodeCommand) plan.OneTimeInstruction {
+	return plan.OneTimeInstruction{
+		Name:       nodeCommandInstructionName,
+		Command:    command.Status.ValidatedSpec.Command,
+		Args:       command.Status.ValidatedSpec.Args,
+		SaveOutput: true,
+	}
+}
+
+// nextNodeCommand returns the node command of the cluster that is running, or the oldest pending node command. Commands
+// that are not validated yet have no phase, and commands whose spec changed since it was validated are validated again
+// first, so neither is returned.
+func nextNodeCommand(commands []*rkev1.NodeCommand, clusterName string) *rkev1.NodeCommand {
+	var pending []*rkev1.NodeCommand
+	for _, command := range commands {
+		if command.Spec.ClusterName != clusterName || !command.DeletionTimestamp.IsZero() ||
+			command.Status.ValidatedSpec == nil || command.Status.ObservedGeneration != command.Generation {
+			continue
+		}
+		switch command.Status.Phase {
+		case rkev1.NodeCommandPhaseRunning:
+			return command
+		case rkev1.NodeCommandPhasePending:
+			pending = append(pending, command)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].CreationTimestamp.Equal(&pending[j].CreationTimestamp) {
+			return pending[i].CreationTimestamp.Before(&pending[j].CreationTimestamp)
+		}
+		return pending[i].Name < pending[j].Name
+	})
+	return pending[0]
+}
+
+func truncateNodeCommandOutput(output []byte) string {
+	if len(output) > maxNodeCommandOutputBytes {
+		output = output[:maxNodeCommandOutputBytes]
+	}
+	return string(output)
+}
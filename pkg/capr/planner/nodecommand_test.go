@@ -0,0 +1,93 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNextNodeCommand(t *testing.T) {
+	now := time.Now()
+	command := func(name, clusterName string, phase rkev1.NodeCommandPhase, created time.Time) *rkev1.NodeCommand {
+		spec := rkev1.NodeCommandSpec{ClusterName: clusterName, Command: "df"}
+		return &rkev1.NodeCommand{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created), Generation: 1},
+			Spec:       spec,
+			Status:     rkev1.NodeCommandStatus{Phase: phase, ObservedGeneration: 1, ValidatedSpec: spec.DeepCopy()},
+		}
+	}
+	changed := command("changed", "test", rkev1.NodeCommandPhasePending, now.Add(-time.Hour))
+	changed.Generation = 2
+
+	tests := []struct {
+		name     string
+		commands []*rkev1.NodeCommand
+		expected string
+	}{
+		{
+			name: "no commands",
+		},
+		{
+			name: "oldest pending command",
+			commands: []*rkev1.NodeCommand{
+				command("newer", "test", rkev1.NodeCommandPhasePending, now),
+				command("older", "test", rkev1.NodeCommandPhasePending, now.Add(-time.Minute)),
+				command("finished", "test", rkev1.NodeCommandPhaseSucceeded, now.Add(-time.Hour)),
+				command("unvalidated", "test", "", now.Add(-time.Hour)),
+				changed,
+			},
+			expected: "older",
+		},
+		{
+			name: "running command first",
+			commands: []*rkev1.NodeCommand{
+				command("pending", "test", rkev1.NodeCommandPhasePending, now.Add(-time.Minute)),
+				command("running", "test", rkev1.NodeCommandPhaseRunning, now),
+			},
+			expected: "running",
+		},
+		{
+			name: "other cluster",
+			commands: []*rkev1.NodeCommand{
+				command("other", "other", rkev1.NodeCommandPhaseRunning, now),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := nextNodeCommand(tt.commands, "test")
+			if tt.expected == "" {
+				assert.Nil(t, next)
+				return
+			}
+			if assert.NotNil(t, next) {
+				assert.Equal(t, tt.expected, next.Name)
+			}
+		})
+	}
+}
+
+func TestNodeCommandInstruction(t *testing.T) {
+	command := &rkev1.NodeCommand{
+		Spec: rkev1.NodeCommandSpec{Command: "rm", Args: []string{"-rf", "/"}},
+		Status: rkev1.NodeCommandStatus{
+			ValidatedSpec: &rkev1.NodeCommandSpec{Command: "df", Args: []string{"-h"}},
+		},
+	}
+	assert.Equal(t, plan.OneTimeInstruction{
+		Name:       nodeCommandInstructionName,
+		Command:    "df",
+		Args:       []string{"-h"},
+		SaveOutput: true,
+	}, nodeCommandInstruction(command), "only the validated spec is run")
+}
+
+func TestTruncateNodeCommandOutput(t *testing.T) {
+	assert.Equal(t, "output", truncateNodeCommandOutput([]byte("output")))
+	assert.Len(t, truncateNodeCommandOutput([]byte(strings.Repeat("a", maxNodeCommandOutputBytes+1))), maxNodeCommandOutputBytes)
+}
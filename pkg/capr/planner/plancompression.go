@@ -0,0 +1,65 @@
+package planner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/rancher/rancher/pkg/settings"
+)
+
+// PlanEncodingKey is the key of the machine plan secret that holds the encoding of the plan. It is only set if the plan
+// is compressed, in which case the agent decompresses the plan before it is applied. The checksums of the plan are
+// calculated over the plan as it is stored in the secret, so the applied and failed checksums reported by the agent
+// refer to the compressed plan.
+const PlanEncodingKey = "plan-encoding"
+
+// GzipPlanEncoding is the encoding of plans that are compressed with gzip.
+const GzipPlanEncoding = "gzip"
+
+// minPlanEncodingSystemAgentVersion is the first system-agent version that reads the plan encoding key. Older agents
+// would fail to parse compressed plans.
+const minPlanEncodingSystemAgentVersion = "v0.3.4"
+
+// planCompressionThreshold returns the size in bytes above which plans are compressed, or 0 if the system-agent of the
+// machines does not support compressed plans.
+func planCompressionThreshold() int {
+	if !currentSystemAgentAtLeast(minPlanEncodingSystemAgentVersion) {
+		return 0
+	}
+	return settings.MachinePlanCompressionThreshold.GetInt()
+}
+
+// encodePlan returns the plan data as it is stored in the machine plan secret, and its encoding. Plans that are larger
+// than the threshold in bytes are compressed, so that plans with many large files do not exceed the size limit of
+// secrets. Plans are stored as is if the threshold is not positive. The compression is deterministic, so that the
+// stored plan only changes if the plan does.
+func encodePlan(data []byte, threshold int) ([]byte, string, error) {
+	if threshold <= 0 || len(data) <= threshold {
+		return data, "", nil
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return nil, "", err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), GzipPlanEncoding, nil
+}
+
+// decodePlan returns the JSON of a plan stored in a machine plan secret. Compressed plans are recognized by the gzip
+// header, so that the applied plan, which is copied from the plan, can be decoded regardless of the encoding of the
+// current plan.
+func decodePlan(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(gz)
+}
@@ -0,0 +1,78 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestEncodePlan(t *testing.T) {
+	data := []byte(`{"files":[{"content":"` + string(bytes.Repeat([]byte("a"), 1024)) + `"}]}`)
+
+	tests := []struct {
+		name             string
+		threshold        int
+		expectedEncoding string
+	}{
+		{
+			name:      "below threshold",
+			threshold: 4096,
+		},
+		{
+			name:      "disabled",
+			threshold: 0,
+		},
+		{
+			name:             "above threshold",
+			threshold:        512,
+			expectedEncoding: GzipPlanEncoding,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, encoding, err := encodePlan(data, tt.threshold)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedEncoding, encoding)
+			if encoding == "" {
+				assert.Equal(t, data, encoded)
+			} else {
+				assert.Less(t, len(encoded), len(data))
+				again, _, err := encodePlan(data, tt.threshold)
+				require.NoError(t, err)
+				assert.Equal(t, encoded, again, "compression must be deterministic")
+			}
+
+			decoded, err := decodePlan(encoded)
+			require.NoError(t, err)
+			assert.Equal(t, data, decoded)
+		})
+	}
+}
+
+func TestSecretToNodeCompressedPlan(t *testing.T) {
+	nodePlan := plan.NodePlan{Files: []plan.File{{Path: "/etc/ca.pem", Content: string(bytes.Repeat([]byte("b"), 2048))}}}
+	data, err := json.Marshal(nodePlan)
+	require.NoError(t, err)
+	compressed, encoding, err := encodePlan(data, 1024)
+	require.NoError(t, err)
+	require.Equal(t, GzipPlanEncoding, encoding)
+
+	node, err := SecretToNode(&corev1.Secret{
+		Type: capr.SecretTypeMachinePlan,
+		Data: map[string][]byte{
+			"plan":          compressed,
+			"appliedPlan":   compressed,
+			PlanEncodingKey: []byte(encoding),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, nodePlan, node.Plan)
+	assert.Equal(t, nodePlan, *node.AppliedPlan)
+	assert.True(t, node.InSync)
+}
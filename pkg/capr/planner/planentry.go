@@ -53,6 +53,22 @@ func collect(p *plan.Plan, include roleFilter) (result []*planEntry) {
 	return result
 }
 
+// sortByFailureDomain orders the entries by the failure domain of their machine, keeping the existing order within a
+// failure domain. Machines without a failure domain come first. This ensures that when only a limited number of machines
+// may be unavailable, the machines of one zone are reconciled before the machines of the next zone.
+func sortByFailureDomain(entries []*planEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return failureDomain(entries[i]) < failureDomain(entries[j])
+	})
+}
+
+func failureDomain(entry *planEntry) string {
+	if entry.Machine == nil || entry.Machine.Spec.FailureDomain == nil {
+		return ""
+	}
+	return *entry.Machine.Spec.FailureDomain
+}
+
 func isEtcd(entry *planEntry) bool {
 	return entry.Metadata != nil && entry.Metadata.Labels[capr.EtcdRoleLabel] == "true"
 }
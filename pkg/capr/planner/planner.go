@@ -11,6 +11,8 @@
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/moby/locker"
@@ -107,12 +109,15 @@
 type Planner struct {
 	ctx                           context.Context
 	store                         *PlanStore
-	rkeBootstrap                  rkecontrollers.RKEBootstrapClient
-	rkeBootstrapCache             rkecontrollers.RKEBootstrapCache
+	applier                       *capr.ServerSideApplier
 	rkeControlPlanes              rkecontrollers.RKEControlPlaneController
 	etcdSnapshotCache             rkecontrollers.ETCDSnapshotCache
+	nodeCommands                  rkecontrollers.NodeCommandClient
+	nodeCommandCache              rkecontrollers.NodeCommandCache
+	approvalCache                 rkecontrollers.ApprovalCache
 	secretClient                  corecontrollers.SecretClient
 	secretCache                   corecontrollers.SecretCache
+	configMaps                    corecontrollers.ConfigMapClient
 	configMapCache                corecontrollers.ConfigMapCache
 	machines                      capicontrollers.MachineClient
 	machinesCache                 capicontrollers.MachineCache
@@ -123,7 +128,15 @@ type Planner struct {
 	rancherClusterCache           ranchercontrollers.ClusterCache
 	locker                        locker.Locker
 	etcdS3Args                    s3Args
+	etcdAzureArgs                 azureArgs
+	etcdGCSArgs                   gcsArgs
 	retrievalFunctions            InfoFunctions
+	events                        corecontrollers.EventClient
+	// etcdSnapshotDataKeys caches the unwrapped etcd snapshot data keys by cluster and key ID.
+	etcdSnapshotDataKeys sync.Map
+	// recoveredControlPlanes holds the UIDs of the control planes whose interrupted etcd snapshot operations were
+	// checked since Rancher started.
+	recoveredControlPlanes sync.Map
 }
 
 // InfoFunctions is a struct that contains various dynamic functions that allow for abstracting out Rancher-specific
@@ -138,15 +151,19 @@ func New(ctx context.Context, clients *wrangler.Context, functions InfoFunctions
 	clients.Mgmt.ClusterRegistrationToken().Cache().AddIndexer(clusterRegToken, func(obj *v3.ClusterRegistrationToken) ([]string, error) {
 		return []string{obj.Spec.ClusterName}, nil
 	})
-	store := NewStore(clients.Core.Secret(),
-		clients.CAPI.Machine().Cache())
+	applier := capr.NewServerSideApplier(capr.PlannerFieldManager, clients.ControllerFactory.SharedCacheFactory().SharedClientFactory())
+	store := NewStore(ctx, applier, clients.Core.Secret(),
+		clients.CAPI.Machine().Cache(),
+		clients.RKE.InstructionPolicy().Cache())
 	return &Planner{
 		ctx:                           ctx,
 		store:                         store,
+		applier:                       applier,
 		machines:                      clients.CAPI.Machine(),
 		machinesCache:                 clients.CAPI.Machine().Cache(),
 		secretClient:                  clients.Core.Secret(),
 		secretCache:                   clients.Core.Secret().Cache(),
+		configMaps:                    clients.Core.ConfigMap(),
 		configMapCache:                clients.Core.ConfigMap().Cache(),
 		clusterRegistrationTokenCache: clients.Mgmt.ClusterRegistrationToken().Cache(),
 		capiClient:                    clients.CAPI.Cluster(),
@@ -154,12 +171,20 @@ func New(ctx context.Context, clients *wrangler.Context, functions InfoFunctions
 		managementClusters:            clients.Mgmt.Cluster().Cache(),
 		rancherClusterCache:           clients.Provisioning.Cluster().Cache(),
 		rkeControlPlanes:              clients.RKE.RKEControlPlane(),
-		rkeBootstrap:                  clients.RKE.RKEBootstrap(),
-		rkeBootstrapCache:             clients.RKE.RKEBootstrap().Cache(),
 		etcdSnapshotCache:             clients.RKE.ETCDSnapshot().Cache(),
+		nodeCommands:                  clients.RKE.NodeCommand(),
+		nodeCommandCache:              clients.RKE.NodeCommand().Cache(),
+		approvalCache:                 clients.RKE.Approval().Cache(),
+		events:                        clients.Core.Event(),
 		etcdS3Args: s3Args{
 			secretCache: clients.Core.Secret().Cache(),
 		},
+		etcdAzureArgs: azureArgs{
+			secretCache: clients.Core.Secret().Cache(),
+		},
+		etcdGCSArgs: gcsArgs{
+			secretCache: clients.Core.Secret().Cache(),
+		},
 		retrievalFunctions: functions,
 	}
 }
@@ -215,6 +240,11 @@ func (p *Planner) Process(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlan
 		_ = p.locker.Unlock(uid)
 	}(cp.Namespace, cp.Name, string(cp.UID))
 
+	return reportInstructionPolicy(p.process(cp, status))
+}
+
+// process plans the control plane while it is locked.
+func (p *Planner) process(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
 	currentVersion, err := semver.NewVersion(cp.Spec.KubernetesVersion)
 	if err != nil {
 		return status, fmt.Errorf("rkecluster %s/%s: error semver parsing kubernetes version %s: %v", cp.Namespace, cp.Name, cp.Spec.KubernetesVersion, err)
@@ -250,6 +280,14 @@ func (p *Planner) Process(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlan
 		return status, nil
 	}
 
+	if err := p.preflight(cp, &status); err != nil {
+		return status, err
+	}
+
+	if cp.Spec.ValidateOnly {
+		return p.renderPlans(cp, status)
+	}
+
 	if !capiCluster.Status.InfrastructureReady {
 		return status, errWaiting("waiting for infrastructure ready")
 	}
@@ -298,22 +336,29 @@ func (p *Planner) Process(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlan
 				continue
 			}
 			logrus.Infof("[planner] rkecluster %s/%s: force deleting etcd machine %s/%s as cluster was not sane and machine was deleting", cp.Namespace, cp.Name, deletingEtcdNode.Machine.Namespace, deletingEtcdNode.Machine.Name)
-			// Update the CAPI machine annotation for exclude node draining and set it to true to get the CAPI controllers to not try to drain this node.
-			deletingEtcdNode.Machine.Annotations[capi.ExcludeNodeDrainingAnnotation] = "true"
-			deletingEtcdNode.Machine, err = p.machines.Update(deletingEtcdNode.Machine)
+			// Apply the CAPI machine annotation for exclude node draining and set it to true to get the CAPI controllers to not try to drain this node.
+			machine := &capi.Machine{}
+			err = p.applier.ApplyMetadata(p.ctx, &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        deletingEtcdNode.Machine.Name,
+					Namespace:   deletingEtcdNode.Machine.Namespace,
+					Annotations: map[string]string{capi.ExcludeNodeDrainingAnnotation: "true"},
+				},
+			}, machine)
 			if err != nil {
 				// If we get an error here, go ahead and return the error as this will re-enqueue and we can try again.
 				return status, err
 			}
-			rb, err := p.rkeBootstrapCache.Get(deletingEtcdNode.Machine.Spec.Bootstrap.ConfigRef.Namespace, deletingEtcdNode.Machine.Spec.Bootstrap.ConfigRef.Name)
-			if err != nil {
-				return status, err
-			}
-			rb = rb.DeepCopy()
+			deletingEtcdNode.Machine = machine
 			// Annotate the rkebootstrap with a "force remove" annotation. This will short-circuit the "safe etcd removal"
 			// logic because at this point we are completely taking the cluster down.
-			rb.Annotations[capr.ForceRemoveEtcdAnnotation] = "true"
-			_, err = p.rkeBootstrap.Update(rb)
+			err = p.applier.ApplyMetadata(p.ctx, &rkev1.RKEBootstrap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        deletingEtcdNode.Machine.Spec.Bootstrap.ConfigRef.Name,
+					Namespace:   deletingEtcdNode.Machine.Spec.Bootstrap.ConfigRef.Namespace,
+					Annotations: map[string]string{capr.ForceRemoveEtcdAnnotation: "true"},
+				},
+			}, &rkev1.RKEBootstrap{})
 			if err != nil {
 				return status, err
 			}
@@ -333,10 +378,18 @@ func (p *Planner) Process(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlan
 		return status, err
 	}
 
+	if status, err = p.recoverInterruptedEtcdSnapshotOperations(cp, status, plan); err != nil {
+		return status, err
+	}
+
 	if status, err = p.createEtcdSnapshot(cp, status, clusterSecretTokens, plan); err != nil {
 		return status, err
 	}
 
+	if status, err = p.uploadEtcdSnapshots(cp, status, clusterSecretTokens, plan); err != nil {
+		return status, err
+	}
+
 	if status, err = p.restoreEtcdSnapshot(cp, status, clusterSecretTokens, plan, currentVersion); err != nil {
 		return status, err
 	}
@@ -372,10 +425,45 @@ func (p *Planner) Process(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlan
 		return status, errWaiting("rkecontrolplane was already initialized but no etcd machines exist that have plans, indicating the etcd plane has been entirely replaced. Restoration from etcd snapshot is required.")
 	}
 
-	return p.fullReconcile(cp, status, clusterSecretTokens, plan, false)
+	if err := p.runNodeCommands(cp, status, clusterSecretTokens, plan); err != nil {
+		return status, err
+	}
+
+	if err := p.runDiagnostics(cp, clusterSecretTokens, plan); err != nil {
+		return status, err
+	}
+
+	status, err = recordBreakGlassAccessChange(cp, status, metav1.Now())
+	if err != nil {
+		return status, err
+	}
+
+	status = reportClockSkew(cp, status, plan)
+	status = p.reportFileSources(cp, status)
+
+	status, err = p.fullReconcile(cp, status, clusterSecretTokens, plan, false)
+	return reportImagePolicy(cp, status, err)
 }
 
-func (p *Planner) fullReconcile(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterSecretTokens plan.Secret, plan *plan.Plan, ignoreDrainAndConcurrency bool) (rkev1.RKEControlPlaneStatus, error) {
+// fullReconcile reconciles all tiers of the cluster. When the cluster is restarted during an etcd snapshot operation, the
+// concurrency of the upgrade strategy is ignored, and the machines are only drained as configured by their machine pool.
+// Outside of a restart, disruptive plan changes of machines are queued until their maintenance windows open.
+func (p *Planner) fullReconcile(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterSecretTokens plan.Secret, plan *plan.Plan, etcdRestart bool) (rkev1.RKEControlPlaneStatus, error) {
+	var (
+		queue *maintenanceWindowQueue
+		err   error
+	)
+	if !etcdRestart {
+		queue, err = p.maintenanceWindowQueue(cp, time.Now())
+		if err != nil {
+			return status, err
+		}
+	}
+	status, err = p.reconcileTiers(cp, status, clusterSecretTokens, plan, etcdRestart, queue.hold)
+	return p.reportMaintenanceWindowQueue(status, queue), err
+}
+
+func (p *Planner) reconcileTiers(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterSecretTokens plan.Secret, plan *plan.Plan, etcdRestart bool, windowHold planHoldFunc) (rkev1.RKEControlPlaneStatus, error) {
 	// on the first run through, electInitNode will return a `generic.ErrSkip` as it is attempting to wait for the cache to catch up.
 	joinServer, err := p.electInitNode(cp, plan)
 	if err != nil {
@@ -384,21 +472,53 @@ func (p *Planner) fullReconcile(cp *rkev1.RKEControlPlane, status rkev1.RKEContr
 
 	var (
 		firstIgnoreError                             error
-		controlPlaneDrainOptions, workerDrainOptions rkev1.DrainOptions
+		controlPlaneDrainOptions, workerDrainOptions drainOptionsFunc
 		controlPlaneConcurrency, workerConcurrency   string
+		controlPlaneHold, workerHold                 holdFunc = noHold, noHold
+		workerCanary                                 *rkev1.UpgradeCanaryMachine
 	)
 
-	if !ignoreDrainAndConcurrency {
-		controlPlaneDrainOptions = cp.Spec.UpgradeStrategy.ControlPlaneDrainOptions
-		workerDrainOptions = cp.Spec.UpgradeStrategy.WorkerDrainOptions
+	if etcdRestart {
+		restartDrainOptions, err := p.etcdRestartDrainOptions(cp, rkev1.DrainOptions{})
+		if err != nil {
+			return status, err
+		}
+		controlPlaneDrainOptions, workerDrainOptions = restartDrainOptions, restartDrainOptions
+	} else {
+		controlPlaneDrainOptions = tierDrainOptions(cp.Spec.UpgradeStrategy.ControlPlaneDrainOptions)
+		workerDrainOptions = tierDrainOptions(cp.Spec.UpgradeStrategy.WorkerDrainOptions)
 		controlPlaneConcurrency = cp.Spec.UpgradeStrategy.ControlPlaneConcurrency
 		workerConcurrency = cp.Spec.UpgradeStrategy.WorkerConcurrency
+		var holdUpgrade bool
+		status, holdUpgrade = checkUpgradeFailureBudget(cp, status, plan)
+		if holdUpgrade {
+			controlPlaneHold = holdAll("waiting for the upgrade failure budget")
+			workerHold = controlPlaneHold
+		}
+
+		status = startUpgradePrewarm(cp, status, time.Now())
+		if joinServer != "" {
+			if status, err = p.prewarmUpgrade(cp, status, clusterSecretTokens, plan, joinServer, time.Now()); err != nil {
+				return status, err
+			}
+		}
+
+		status = startUpgradeCanary(cp, status, plan)
+		if status.UpgradeCanary != nil {
+			canaryHold, soakTime := soakUpgradeCanary(cp, status.UpgradeCanary.ControlPlane, plan, tierControlPlane(cp), time.Now())
+			if soakTime > 0 {
+				p.rkeControlPlanes.EnqueueAfter(cp.Namespace, cp.Name, soakTime)
+			}
+			controlPlaneHold = holdAny(controlPlaneHold, canaryHold)
+			workerCanary = status.UpgradeCanary.Worker
+		}
 	}
+	status = reconcileDrainBlocked(status, plan)
 
 	// select all etcd and then filter to just initNodes so that unavailable count is correct
 	err = p.reconcile(cp, clusterSecretTokens, plan, true, bootstrapTier, isEtcd, isNotInitNodeOrIsDeleting,
 		"1", "",
-		controlPlaneDrainOptions)
+		controlPlaneDrainOptions, tierControlPlane(cp), controlPlaneHold, windowHold)
 	capr.Bootstrapped.True(&status)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
 	if err != nil {
@@ -419,7 +539,7 @@ func (p *Planner) fullReconcile(cp *rkev1.RKEControlPlane, status rkev1.RKEContr
 	// Process all nodes that have the etcd role and are NOT an init node or deleting. Only process 1 node at a time.
 	err = p.reconcile(cp, clusterSecretTokens, plan, true, etcdTier, isEtcd, isInitNodeOrDeleting,
 		"1", joinServer,
-		controlPlaneDrainOptions)
+		controlPlaneDrainOptions, tierControlPlane(cp), controlPlaneHold, windowHold)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
 	if err != nil {
 		return status, err
@@ -428,7 +548,7 @@ func (p *Planner) fullReconcile(cp *rkev1.RKEControlPlane, status rkev1.RKEContr
 	// Process all nodes that have the controlplane role and are NOT an init node or deleting.
 	err = p.reconcile(cp, clusterSecretTokens, plan, true, controlPlaneTier, isControlPlane, isInitNodeOrDeleting,
 		controlPlaneConcurrency, joinServer,
-		controlPlaneDrainOptions)
+		controlPlaneDrainOptions, tierControlPlane(cp), controlPlaneHold, windowHold)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
 	if err != nil {
 		return status, err
@@ -445,10 +565,18 @@ func (p *Planner) fullReconcile(cp *rkev1.RKEControlPlane, status rkev1.RKEContr
 		return status, errWaiting("marking control plane as initialized and ready")
 	}
 
-	// Process all nodes that are ONLY worker nodes.
+	// Process all nodes that are ONLY worker nodes, at the Kubernetes version of their machine pool.
+	workerControlPlanes, err := p.workerControlPlanes(cp)
+	if err != nil {
+		return status, err
+	}
+	canaryHold, soakTime := soakUpgradeCanary(cp, workerCanary, plan, workerControlPlanes, time.Now())
+	if soakTime > 0 {
+		p.rkeControlPlanes.EnqueueAfter(cp.Namespace, cp.Name, soakTime)
+	}
 	err = p.reconcile(cp, clusterSecretTokens, plan, false, workerTier, isOnlyWorker, isInitNodeOrDeleting,
 		workerConcurrency, "",
-		workerDrainOptions)
+		workerDrainOptions, workerControlPlanes, holdAny(workerHold, canaryHold), windowHold)
 	firstIgnoreError, err = ignoreErrors(firstIgnoreError, err)
 	if err != nil {
 		return status, err
@@ -895,13 +1023,14 @@ func getTaints(entry *planEntry, cp *rkev1.RKEControlPlane) (result []corev1.Tai
 }
 
 func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, clusterPlan *plan.Plan, required bool,
-	tierName string, include, exclude roleFilter, maxUnavailable string, forcedJoinURL string, drainOptions rkev1.DrainOptions) error {
+	tierName string, include, exclude roleFilter, maxUnavailable string, forcedJoinURL string, drainOptions drainOptionsFunc, controlPlanes controlPlaneFunc, hold holdFunc, windowHold planHoldFunc) error {
 	var (
 		ready, outOfSync, reconciling, nonReady, errMachines, draining, uncordoned []string
 		messages                                                                   = map[string][]string{}
 	)
 
 	entries := collect(clusterPlan, include)
+	sortByFailureDomain(entries)
 
 	concurrency, unavailable, err := calculateConcurrency(maxUnavailable, entries, exclude)
 	if err != nil {
@@ -937,10 +1066,14 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, tokensSecret pl
 		}
 
 		logrus.Debugf("[planner] rkecluster %s/%s reconcile tier %s - rendering desired plan for machine %s/%s with join URL: (%s)", controlPlane.Namespace, controlPlane.Name, tierName, entry.Machine.Namespace, entry.Machine.Name, joinURL)
-		plan, joinedURL, err := p.desiredPlan(controlPlane, tokensSecret, entry, joinURL)
+		entryControlPlane := controlPlanes(entry)
+		plan, joinedURL, err := p.desiredPlan(entryControlPlane, tokensSecret, entry, joinURL)
 		if err != nil {
 			return err
 		}
+		if err := checkImagePolicy(entryControlPlane, entry, plan); err != nil {
+			return err
+		}
 
 		if entry.Plan == nil {
 			logrus.Debugf("[planner] rkecluster %s/%s reconcile tier %s - setting initial plan for machine %s/%s", controlPlane.Namespace, controlPlane.Name, tierName, entry.Machine.Namespace, entry.Machine.Name)
@@ -966,13 +1099,18 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, tokensSecret pl
 			// 3. concurrency == 0 which means infinite concurrency.
 			// 4. unavailable < concurrency meaning we have capacity to make something unavailable
 			// 5. If the plans are in sync, but we are still waiting for probes, it is safe to apply new instructions
+			// Conditions 3 and 4 do not apply while the upgrade of the machine is held, so that it is not upgraded.
 			logrus.Debugf("[planner] rkecluster %s/%s reconcile tier %s - concurrency: %d, unavailable: %d", controlPlane.Namespace, controlPlane.Name, tierName, concurrency, unavailable)
-			if isInDrain(entry) || entry.Plan.Failed || concurrency == 0 || unavailable < concurrency || planAppliedButWaitingForProbes(entry) {
+			holdReason := hold(entry)
+			if holdReason == "" && windowHold != nil {
+				holdReason = windowHold(entry, plan)
+			}
+			if isInDrain(entry) || entry.Plan.Failed || (holdReason == "" && (concurrency == 0 || unavailable < concurrency)) || planAppliedButWaitingForProbes(entry) {
 				reconciling = append(reconciling, entry.Machine.Name)
 				if !isUnavailable(entry) {
 					unavailable++
 				}
-				if ok, err := p.drain(entry.Plan.AppliedPlan, plan, entry, clusterPlan, drainOptions); !ok && err != nil {
+				if ok, err := p.drain(entry.Plan.AppliedPlan, plan, entry, clusterPlan, drainOptions(entry)); !ok && err != nil {
 					return err
 				} else if ok && err == nil {
 					// Drain is done (or didn't need to be done) and there are no errors, so the plan should be updated to enact the reason the node was drained.
@@ -996,6 +1134,8 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, tokensSecret pl
 						messages[entry.Machine.Name] = append(messages[entry.Machine.Name], "draining node")
 					}
 				}
+			} else if holdReason != "" {
+				messages[entry.Machine.Name] = append(messages[entry.Machine.Name], holdReason)
 			}
 		} else if planStatusMessage != "" {
 			outOfSync = append(outOfSync, entry.Machine.Name)
@@ -1010,7 +1150,7 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, tokensSecret pl
 			} else {
 				messages[entry.Machine.Name] = append(messages[entry.Machine.Name], "waiting for uncordon to finish")
 			}
-		} else if !kubeletVersionUpToDate(controlPlane, entry.Machine) {
+		} else if !kubeletVersionUpToDate(entryControlPlane, entry.Machine) {
 			outOfSync = append(outOfSync, entry.Machine.Name)
 			messages[entry.Machine.Name] = append(messages[entry.Machine.Name], "waiting for kubelet to update")
 		} else if isControlPlane(entry) && !controlPlane.Status.AgentConnected {
@@ -1113,11 +1253,12 @@ func (p *Planner) desiredPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret
 		return nodePlan, joinedTo, err
 	}
 
-	probes, err := p.generateProbes(controlPlane, entry, config)
+	probes, probeFiles, err := p.generateProbes(controlPlane, entry, config)
 	if err != nil {
 		return nodePlan, joinedTo, err
 	}
 	nodePlan.Probes = probes
+	nodePlan.Files = append(nodePlan.Files, probeFiles...)
 
 	// Add instruction last because it hashes config content
 	nodePlan, err = p.addInstallInstructionWithRestartStamp(nodePlan, controlPlane, entry)
@@ -1125,6 +1266,21 @@ func (p *Planner) desiredPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret
 		return nodePlan, joinedTo, err
 	}
 
+	nodePlan, err = addBreakGlassAccess(nodePlan, controlPlane, entry)
+	if err != nil {
+		return nodePlan, joinedTo, err
+	}
+
+	nodePlan, err = addFirewall(nodePlan, controlPlane, entry)
+	if err != nil {
+		return nodePlan, joinedTo, err
+	}
+
+	nodePlan, err = addClock(nodePlan, controlPlane, entry)
+	if err != nil {
+		return nodePlan, joinedTo, err
+	}
+
 	if isInitNode(entry) && IsOnlyEtcd(entry) {
 		nodePlan, err = p.addInitNodePeriodicInstruction(nodePlan, controlPlane)
 		if err != nil {
@@ -1144,6 +1300,12 @@ func (p *Planner) desiredPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret
 			}
 		}
 	}
+
+	// Add file integrity last because it hashes all files of the plan
+	nodePlan, err = addFileIntegrity(nodePlan, controlPlane, entry)
+	if err != nil {
+		return nodePlan, joinedTo, err
+	}
 	return nodePlan, joinedTo, nil
 }
 
@@ -251,6 +251,32 @@ type args struct {
 	}
 }
 
+func Test_sortByFailureDomain(t *testing.T) {
+	entry := func(name, failureDomain string) *planEntry {
+		machine := &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if failureDomain != "" {
+			machine.Spec.FailureDomain = &failureDomain
+		}
+		return &planEntry{Machine: machine}
+	}
+
+	entries := []*planEntry{
+		entry("m1", "zone-b"),
+		entry("m2", "zone-a"),
+		entry("m3", ""),
+		entry("m4", "zone-b"),
+		entry("m5", "zone-a"),
+	}
+
+	sortByFailureDomain(entries)
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Machine.Name)
+	}
+	assert.Equal(t, []string{"m3", "m2", "m5", "m1", "m4"}, names)
+}
+
 func TestPlanner_getLowestMachineKubeletVersion(t *testing.T) {
 	type args struct {
 		versions       []string
@@ -438,6 +464,23 @@ func Test_getInstallerImage(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "cluster private registry - prefer system default registry of the control plane",
+			expected: "tenant.rancher.io/rancher/system-agent-installer-rke2:v1.25.7-rke2r1",
+			controlPlane: &rkev1.RKEControlPlane{
+				Spec: rkev1.RKEControlPlaneSpec{
+					RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+						SystemDefaultRegistry: "tenant.rancher.io/",
+						MachineGlobalConfig: rkev1.GenericMap{
+							Data: map[string]any{
+								"system-default-registry": "test.rancher.io",
+							},
+						},
+					},
+					KubernetesVersion: "v1.25.7+rke2r1",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,302 @@
+package planner
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// preflightRecheckInterval is how often the planner validates a cluster that failed preflight validation again.
+const preflightRecheckInterval = 30 * time.Second
+
+// PreflightValidator checks a provisioning cluster for problems that would prevent it from being provisioned or
+// updated. Validators are run by the planner before it plans the cluster.
+type PreflightValidator interface {
+	// Name returns the name of the validator, such as "network". It must be unique among the registered validators.
+	Name() string
+	// Validate returns all problems found with the cluster, or none if it is valid.
+	Validate(cluster *rancherv1.Cluster) []string
+}
+
+// PreflightError lists all problems found by the preflight validators.
+type PreflightError struct {
+	Problems []string
+}
+
+func (e *PreflightError) Error() string {
+	return "cluster failed preflight validation: " + strings.Join(e.Problems, "; ")
+}
+
+var (
+	preflightValidatorsLock sync.RWMutex
+	preflightValidators     []PreflightValidator
+)
+
+func init() {
+	RegisterPreflightValidator(preflightValidator{name: "version", validate: validateVersion})
+	RegisterPreflightValidator(preflightValidator{name: "network", validate: validateNetwork})
+	RegisterPreflightValidator(preflightValidator{name: "machine pools", validate: validateMachinePools})
+	RegisterPreflightValidator(preflightValidator{name: "topology", validate: ValidateTopology})
+	RegisterPreflightValidator(preflightValidator{name: "etcd s3", validate: validateETCDS3})
+	RegisterPreflightValidator(preflightValidator{name: "probes", validate: validateProbeCustomizations})
+}
+
+// RegisterPreflightValidator registers a preflight validator. RegisterPreflightValidator panics if a validator with the
+// same name is already registered.
+func RegisterPreflightValidator(validator PreflightValidator) {
+	preflightValidatorsLock.Lock()
+	defer preflightValidatorsLock.Unlock()
+
+	for _, v := range preflightValidators {
+		if v.Name() == validator.Name() {
+			panic(fmt.Sprintf("preflight validator %s is already registered", validator.Name()))
+		}
+	}
+	preflightValidators = append(preflightValidators, validator)
+}
+
+// Preflight runs all registered validators against the cluster, and returns a PreflightError listing the problems found
+// by all of them, so that they can be fixed at once. It returns nil if the cluster is valid or is not provisioned by
+// Rancher.
+func Preflight(cluster *rancherv1.Cluster) error {
+	if cluster == nil || cluster.Spec.RKEConfig == nil {
+		return nil
+	}
+
+	preflightValidatorsLock.RLock()
+	defer preflightValidatorsLock.RUnlock()
+
+	var problems []string
+	for _, v := range preflightValidators {
+		for _, problem := range v.Validate(cluster) {
+			problems = append(problems, fmt.Sprintf("%s: %s", v.Name(), problem))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return &PreflightError{Problems: problems}
+}
+
+type preflightValidator struct {
+	name     string
+	validate func(cluster *rancherv1.Cluster) []string
+}
+
+func (p preflightValidator) Name() string {
+	return p.name
+}
+
+func (p preflightValidator) Validate(cluster *rancherv1.Cluster) []string {
+	return p.validate(cluster)
+}
+
+// validateVersion checks that the Kubernetes version is a valid version of a supported distribution.
+func validateVersion(cluster *rancherv1.Cluster) []string {
+	version := cluster.Spec.KubernetesVersion
+	if version == "" {
+		return []string{"kubernetesVersion must be set"}
+	}
+	if _, err := semver.NewVersion(version); err != nil {
+		return []string{fmt.Sprintf("kubernetesVersion %s is not a valid version: %v", version, err)}
+	}
+	if !strings.Contains(version, capr.RuntimeK3S) && !strings.Contains(version, capr.RuntimeRKE2) {
+		return []string{fmt.Sprintf("kubernetesVersion %s is neither a %s nor a %s version", version, capr.RuntimeK3S, capr.RuntimeRKE2)}
+	}
+	return nil
+}
+
+// validateNetwork checks that the cluster and service CIDRs are valid and do not overlap.
+func validateNetwork(cluster *rancherv1.Cluster) []string {
+	var problems []string
+	parse := func(key, defaultValue string) []*net.IPNet {
+		value := convert.ToString(cluster.Spec.RKEConfig.MachineGlobalConfig.Data[key])
+		if value == "" {
+			value = defaultValue
+		}
+		var result []*net.IPNet
+		for _, cidr := range strings.Split(value, ",") {
+			_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s %s is not a valid CIDR", key, cidr))
+				continue
+			}
+			result = append(result, ipNet)
+		}
+		return result
+	}
+
+	clusterCIDRs := parse("cluster-cidr", capr.DefaultClusterCIDR)
+	serviceCIDRs := parse("service-cidr", capr.DefaultServiceCIDR)
+	for _, clusterCIDR := range clusterCIDRs {
+		for _, serviceCIDR := range serviceCIDRs {
+			if clusterCIDR.Contains(serviceCIDR.IP) || serviceCIDR.Contains(clusterCIDR.IP) {
+				problems = append(problems, fmt.Sprintf("cluster-cidr %s overlaps with service-cidr %s", clusterCIDR, serviceCIDR))
+			}
+		}
+	}
+	return problems
+}
+
+// validateMachinePools checks that the machine pools have unique names, a machine config, at least one role and a
+// quantity that is not negative.
+func validateMachinePools(cluster *rancherv1.Cluster) []string {
+	var problems []string
+	seen := map[string]bool{}
+	for i, pool := range cluster.Spec.RKEConfig.MachinePools {
+		if pool.Name == "" {
+			problems = append(problems, fmt.Sprintf("machine pool %d must have a name", i))
+			continue
+		}
+		if seen[pool.Name] {
+			problems = append(problems, fmt.Sprintf("machine pool name %s is used more than once", pool.Name))
+		}
+		seen[pool.Name] = true
+		if pool.NodeConfig == nil {
+			problems = append(problems, fmt.Sprintf("machine pool %s must have a machine config", pool.Name))
+		}
+		if !pool.EtcdRole && !pool.ControlPlaneRole && !pool.WorkerRole {
+			problems = append(problems, fmt.Sprintf("machine pool %s must have at least one role", pool.Name))
+		}
+		if pool.Quantity != nil && *pool.Quantity < 0 {
+			problems = append(problems, fmt.Sprintf("machine pool %s must not have a negative quantity", pool.Name))
+		}
+	}
+	return problems
+}
+
+// ValidateTopology checks that the topology of the cluster refers to a ClusterClass, and that its machine deployments
+// have a worker class, at least one role, a number of replicas that is not negative and names that are not used by
+// other machine deployments of the topology or by machine pools. The machine deployments of the topology are only
+// generated from a topology without problems.
+func ValidateTopology(cluster *rancherv1.Cluster) []string {
+	topology := cluster.Spec.RKEConfig.Topology
+	if topology == nil {
+		return nil
+	}
+
+	var problems []string
+	if topology.Class == "" {
+		problems = append(problems, "topology must have a ClusterClass")
+	}
+	seen := map[string]bool{}
+	for _, pool := range cluster.Spec.RKEConfig.MachinePools {
+		seen[pool.Name] = true
+	}
+	for i, machineDeployment := range topology.MachineDeployments {
+		if machineDeployment.Name == "" {
+			problems = append(problems, fmt.Sprintf("machine deployment %d of the topology must have a name", i))
+			continue
+		}
+		if seen[machineDeployment.Name] {
+			problems = append(problems, fmt.Sprintf("machine deployment name %s is used more than once", machineDeployment.Name))
+		}
+		seen[machineDeployment.Name] = true
+		if machineDeployment.Class == "" {
+			problems = append(problems, fmt.Sprintf("machine deployment %s must have a worker class", machineDeployment.Name))
+		}
+		if !machineDeployment.EtcdRole && !machineDeployment.ControlPlaneRole && !machineDeployment.WorkerRole {
+			problems = append(problems, fmt.Sprintf("machine deployment %s must have at least one role", machineDeployment.Name))
+		}
+		if machineDeployment.Replicas != nil && *machineDeployment.Replicas < 0 {
+			problems = append(problems, fmt.Sprintf("machine deployment %s must not have a negative number of replicas", machineDeployment.Name))
+		}
+	}
+	return problems
+}
+
+// validateETCDS3 checks the fields of the etcd snapshot S3 configuration that are set on the cluster itself. Fields
+// defaulted from the cloud credential are validated when the etcd args are rendered.
+func validateETCDS3(cluster *rancherv1.Cluster) []string {
+	if cluster.Spec.RKEConfig.ETCD == nil || !S3Enabled(cluster.Spec.RKEConfig.ETCD.S3) {
+		return nil
+	}
+
+	var problems []string
+	s3 := cluster.Spec.RKEConfig.ETCD.S3
+	if _, err := normalizeS3Bucket(s3.Bucket); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := validateS3Folder(s3.Folder); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if s3.StorageClass != "" {
+		if err := validateS3StorageClass(s3.StorageClass); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if strings.Contains(s3.Endpoint, "://") {
+		problems = append(problems, fmt.Sprintf("invalid etcd snapshot S3 endpoint %q: must not include a scheme", s3.Endpoint))
+	}
+	if s3.EndpointCA != "" && !strings.HasSuffix(s3.EndpointCA, ".crt") {
+		if _, err := normalizeEndpointCA(s3.EndpointCA); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid etcd snapshot S3 endpoint CA: %v", err))
+		}
+	}
+	return problems
+}
+
+// validateProbeCustomizations checks that the system-agent supports the server name and headers of the probe
+// customizations, as they are not applied otherwise.
+func validateProbeCustomizations(cluster *rancherv1.Cluster) []string {
+	if currentSystemAgentAtLeast(minProbeRequestOptionsSystemAgentVersion) {
+		return nil
+	}
+
+	var problems []string
+	for i, customization := range cluster.Spec.RKEConfig.ProbeCustomizations {
+		if customization.ServerName != "" || len(customization.Headers) > 0 {
+			problems = append(problems, fmt.Sprintf("serverName and headers of probe customization %d require system-agent %s or later, and are not applied", i, minProbeRequestOptionsSystemAgentVersion))
+		}
+	}
+	return problems
+}
+
+// preflight runs the preflight validators against the provisioning cluster of the control plane and records the result
+// in the Validated condition of the status. Only a cluster that is not initialized yet is not planned while it is
+// invalid, an existing cluster keeps being planned so that it can still be remediated, and its problems are only
+// reported. As changes to the machine pools of the cluster do not change the control plane, it is checked again after
+// an interval.
+func (p *Planner) preflight(cp *rkev1.RKEControlPlane, status *rkev1.RKEControlPlaneStatus) error {
+	cluster, err := p.rancherClusterCache.Get(cp.Namespace, cp.Spec.ClusterName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	err = Preflight(cluster)
+	if _, ok := err.(*PreflightError); ok {
+		p.rkeControlPlanes.EnqueueAfter(cp.Namespace, cp.Name, preflightRecheckInterval)
+	}
+	return setValidated(status, err)
+}
+
+// setValidated records the result of the preflight validation in the Validated condition of the status. It returns an
+// error to stop planning only if the validation failed before the cluster was initialized.
+func setValidated(status *rkev1.RKEControlPlaneStatus, err error) error {
+	preflightErr, ok := err.(*PreflightError)
+	if !ok {
+		capr.Validated.True(status)
+		capr.Validated.Reason(status, "")
+		capr.Validated.Message(status, "")
+		return nil
+	}
+
+	capr.Validated.False(status)
+	capr.Validated.Reason(status, "PreflightFailed")
+	capr.Validated.Message(status, strings.Join(preflightErr.Problems, "; "))
+	if status.Initialized {
+		return nil
+	}
+	return errWaiting(err.Error())
+}
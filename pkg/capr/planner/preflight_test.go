@@ -0,0 +1,232 @@
+package planner
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPreflight(t *testing.T) {
+	negative := int32(-1)
+
+	tests := []struct {
+		name     string
+		cluster  *rancherv1.Cluster
+		expected []string
+	}{
+		{
+			name:    "not provisioned by rancher",
+			cluster: &rancherv1.Cluster{},
+		},
+		{
+			name: "valid",
+			cluster: &rancherv1.Cluster{
+				Spec: rancherv1.ClusterSpec{
+					KubernetesVersion: "v1.25.9+rke2r1",
+					RKEConfig: &rancherv1.RKEConfig{
+						RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+							MachineGlobalConfig: rkev1.GenericMap{Data: map[string]interface{}{
+								"cluster-cidr": "10.44.0.0/16,fd00:44::/56",
+								"service-cidr": "10.45.0.0/16,fd00:45::/112",
+							}},
+							ETCD: &rkev1.ETCD{S3: &rkev1.ETCDSnapshotS3{Bucket: "snapshots", Endpoint: "s3.example.com"}},
+						},
+						MachinePools: []rancherv1.RKEMachinePool{
+							{Name: "pool", EtcdRole: true, NodeConfig: &corev1.ObjectReference{Name: "config"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "all problems are reported",
+			cluster: &rancherv1.Cluster{
+				Spec: rancherv1.ClusterSpec{
+					KubernetesVersion: "v1.25.9",
+					RKEConfig: &rancherv1.RKEConfig{
+						RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+							MachineGlobalConfig: rkev1.GenericMap{Data: map[string]interface{}{
+								"cluster-cidr": "10.43.0.0/15",
+							}},
+							ETCD: &rkev1.ETCD{S3: &rkev1.ETCDSnapshotS3{Bucket: "Snapshots", Endpoint: "https://s3.example.com"}},
+						},
+						MachinePools: []rancherv1.RKEMachinePool{
+							{Name: "pool", WorkerRole: true, NodeConfig: &corev1.ObjectReference{Name: "config"}},
+							{Name: "pool", Quantity: &negative},
+						},
+					},
+				},
+			},
+			expected: []string{
+				"version: kubernetesVersion v1.25.9 is neither a k3s nor a rke2 version",
+				"network: cluster-cidr 10.42.0.0/15 overlaps with service-cidr 10.43.0.0/16",
+				"machine pools: machine pool name pool is used more than once",
+				"machine pools: machine pool pool must have a machine config",
+				"machine pools: machine pool pool must have at least one role",
+				"machine pools: machine pool pool must not have a negative quantity",
+				`etcd s3: invalid etcd snapshot S3 bucket "Snapshots": must consist of lowercase letters, numbers, dots and hyphens, and begin and end with a letter or number`,
+				`etcd s3: invalid etcd snapshot S3 endpoint "https://s3.example.com": must not include a scheme`,
+			},
+		},
+		{
+			name: "invalid topology",
+			cluster: &rancherv1.Cluster{
+				Spec: rancherv1.ClusterSpec{
+					KubernetesVersion: "v1.25.9+rke2r1",
+					RKEConfig: &rancherv1.RKEConfig{
+						MachinePools: []rancherv1.RKEMachinePool{
+							{Name: "pool", EtcdRole: true, NodeConfig: &corev1.ObjectReference{Name: "config"}},
+						},
+						Topology: &rancherv1.RKETopology{
+							MachineDeployments: []rancherv1.RKEMachineDeploymentTopology{
+								{Name: "workers", Class: "default-worker", WorkerRole: true},
+								{Name: "pool", Replicas: &negative},
+								{Class: "default-worker"},
+							},
+						},
+					},
+				},
+			},
+			expected: []string{
+				"topology: topology must have a ClusterClass",
+				"topology: machine deployment name pool is used more than once",
+				"topology: machine deployment pool must have a worker class",
+				"topology: machine deployment pool must have at least one role",
+				"topology: machine deployment pool must not have a negative number of replicas",
+				"topology: machine deployment 2 of the topology must have a name",
+			},
+		},
+		{
+			name: "invalid CIDR",
+			cluster: &rancherv1.Cluster{
+				Spec: rancherv1.ClusterSpec{
+					KubernetesVersion: "v1.25.9+k3s1",
+					RKEConfig: &rancherv1.RKEConfig{
+						RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+							MachineGlobalConfig: rkev1.GenericMap{Data: map[string]interface{}{
+								"service-cidr": "10.43.0.0",
+							}},
+						},
+					},
+				},
+			},
+			expected: []string{"network: service-cidr 10.43.0.0 is not a valid CIDR"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Preflight(tt.cluster)
+			if len(tt.expected) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.IsType(t, &PreflightError{}, err) {
+				assert.Equal(t, tt.expected, err.(*PreflightError).Problems)
+			}
+		})
+	}
+}
+
+func TestRegisterPreflightValidatorDuplicate(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterPreflightValidator(preflightValidator{name: "network"})
+	})
+}
+
+func TestSetValidated(t *testing.T) {
+	preflightErr := &PreflightError{Problems: []string{"version: kubernetesVersion must be set"}}
+
+	tests := []struct {
+		name            string
+		initialized     bool
+		err             error
+		expectedStatus  string
+		expectedMessage string
+		expectedErr     bool
+	}{
+		{
+			name:           "valid",
+			expectedStatus: "True",
+		},
+		{
+			name:            "invalid new cluster is not planned",
+			err:             preflightErr,
+			expectedStatus:  "False",
+			expectedMessage: "version: kubernetesVersion must be set",
+			expectedErr:     true,
+		},
+		{
+			name:            "invalid existing cluster is still planned",
+			initialized:     true,
+			err:             preflightErr,
+			expectedStatus:  "False",
+			expectedMessage: "version: kubernetesVersion must be set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &rkev1.RKEControlPlaneStatus{Initialized: tt.initialized}
+			err := setValidated(status, tt.err)
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedStatus, capr.Validated.GetStatus(status))
+			assert.Equal(t, tt.expectedMessage, capr.Validated.GetMessage(status))
+		})
+	}
+}
+
+func TestValidateProbeCustomizations(t *testing.T) {
+	tests := []struct {
+		name           string
+		agentVersion   string
+		customizations []rkev1.ProbeCustomization
+		expected       []string
+	}{
+		{
+			name:           "supported",
+			agentVersion:   minProbeRequestOptionsSystemAgentVersion,
+			customizations: []rkev1.ProbeCustomization{{ServerName: "cluster.example.com"}},
+		},
+		{
+			name:           "CA only",
+			agentVersion:   "v0.3.3",
+			customizations: []rkev1.ProbeCustomization{{CACerts: "ca"}},
+		},
+		{
+			name:         "unsupported",
+			agentVersion: "v0.3.3",
+			customizations: []rkev1.ProbeCustomization{
+				{ServerName: "cluster.example.com"},
+				{Headers: map[string]string{"X-Probe": "rancher"}},
+			},
+			expected: []string{
+				"serverName and headers of probe customization 0 require system-agent v0.3.4 or later, and are not applied",
+				"serverName and headers of probe customization 1 require system-agent v0.3.4 or later, and are not applied",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := settings.SystemAgentVersion.Get()
+			t.Cleanup(func() { _ = settings.SystemAgentVersion.Set(original) })
+			assert.NoError(t, settings.SystemAgentVersion.Set(tt.agentVersion))
+
+			cluster := &rancherv1.Cluster{
+				Spec: rancherv1.ClusterSpec{
+					RKEConfig: &rancherv1.RKEConfig{
+						RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{ProbeCustomizations: tt.customizations},
+					},
+				},
+			}
+			assert.Equal(t, tt.expected, validateProbeCustomizations(cluster))
+		})
+	}
+}
@@ -2,73 +2,17 @@
 
 import (
 	"fmt"
-	"strings"
 
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
 	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/probes"
 	"github.com/rancher/wrangler/pkg/data/convert"
 )
 
-var allProbes = map[string]plan.Probe{
-	"calico": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
-		HTTPGetAction: plan.HTTPGetAction{
-			URL: "http://127.0.0.1:9099/liveness",
-		},
-	},
-	"etcd": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
-		HTTPGetAction: plan.HTTPGetAction{
-			URL: "http://127.0.0.1:2381/health",
-		},
-	},
-	"kube-apiserver": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
-		HTTPGetAction: plan.HTTPGetAction{
-			URL:        "https://127.0.0.1:6443/readyz",
-			CACert:     "/var/lib/rancher/%s/server/tls/server-ca.crt",
-			ClientCert: "/var/lib/rancher/%s/server/tls/client-kube-apiserver.crt",
-			ClientKey:  "/var/lib/rancher/%s/server/tls/client-kube-apiserver.key",
-		},
-	},
-	"kube-scheduler": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
-		HTTPGetAction: plan.HTTPGetAction{
-			URL: "https://127.0.0.1:%s/healthz",
-		},
-	},
-	"kube-controller-manager": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
-		HTTPGetAction: plan.HTTPGetAction{
-			URL: "https://127.0.0.1:%s/healthz",
-		},
-	},
-	"kubelet": {
-		InitialDelaySeconds: 1,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    2,
-		HTTPGetAction: plan.HTTPGetAction{
-			URL: "http://127.0.0.1:10248/healthz",
-		},
-	},
-}
+// minProbeRequestOptionsSystemAgentVersion is the first system-agent version that sends the server name and headers of
+// probes. Older agents ignore them.
+const minProbeRequestOptionsSystemAgentVersion = "v0.3.4"
 
 func isCalico(controlPlane *rkev1.RKEControlPlane, runtime string) bool {
 	if runtime != capr.RuntimeRKE2 {
@@ -103,80 +47,48 @@ func renderSecureProbe(arg interface{}, rawProbe plan.Probe, runtime string, def
 		// Our goal here is to generate the tlsCert. If we get to this point, we know we will be using the defaultCert
 		TLSCert = certDir + "/" + defaultCert
 	}
-	return replaceCACertAndPortForProbes(rawProbe, TLSCert, securePort)
+	return probes.SetCACertAndPort(rawProbe, TLSCert, securePort)
 }
 
-// generateProbes generates probes for the machine (based on type of machine) to the nodePlan and returns the probes and an error
-// if one occurred.
-func (p *Planner) generateProbes(controlPlane *rkev1.RKEControlPlane, entry *planEntry, config map[string]interface{}) (map[string]plan.Probe, error) {
+// generateProbes generates probes for the machine (based on type of machine) with the probe customizations of the
+// cluster applied, as far as the system-agent supports them, and returns the probes, the files the probes refer to, and an error if one occurred.
+func (p *Planner) generateProbes(controlPlane *rkev1.RKEControlPlane, entry *planEntry, config map[string]interface{}) (map[string]plan.Probe, []plan.File, error) {
 	var (
 		runtime    = capr.GetRuntime(controlPlane.Spec.KubernetesVersion)
 		probeNames []string
-		probes     = map[string]plan.Probe{}
 	)
 
 	if runtime != capr.RuntimeK3S && isEtcd(entry) {
-		probeNames = append(probeNames, "etcd")
+		probeNames = append(probeNames, probes.ETCD)
 	}
 	if isControlPlane(entry) {
-		probeNames = append(probeNames, "kube-apiserver")
-		probeNames = append(probeNames, "kube-controller-manager")
-		probeNames = append(probeNames, "kube-scheduler")
+		probeNames = append(probeNames, probes.KubeAPIServer)
+		probeNames = append(probeNames, probes.KubeControllerManager)
+		probeNames = append(probeNames, probes.KubeScheduler)
 	}
 	if !(IsOnlyEtcd(entry) && runtime == capr.RuntimeK3S) {
 		// k3s doesn't run the kubelet on etcd only nodes
-		probeNames = append(probeNames, "kubelet")
+		probeNames = append(probeNames, probes.Kubelet)
 	}
 	if !IsOnlyEtcd(entry) && isCalico(controlPlane, runtime) && roleNot(windows)(entry) {
-		probeNames = append(probeNames, "calico")
-	}
-
-	for _, probeName := range probeNames {
-		probes[probeName] = allProbes[probeName]
+		probeNames = append(probeNames, probes.Calico)
 	}
 
-	probes = replaceRuntimeForProbes(probes, runtime)
+	result := probes.Defaults(runtime, probeNames...)
 
 	if isControlPlane(entry) {
-		kcmProbe, err := renderSecureProbe(config[KubeControllerManagerArg], probes["kube-controller-manager"], runtime, DefaultKubeControllerManagerDefaultSecurePort, DefaultKubeControllerManagerCertDir, DefaultKubeControllerManagerCert)
+		kcmProbe, err := renderSecureProbe(config[KubeControllerManagerArg], result[probes.KubeControllerManager], runtime, DefaultKubeControllerManagerDefaultSecurePort, DefaultKubeControllerManagerCertDir, DefaultKubeControllerManagerCert)
 		if err != nil {
-			return probes, err
+			return result, nil, err
 		}
-		probes["kube-controller-manager"] = kcmProbe
+		result[probes.KubeControllerManager] = kcmProbe
 
-		ksProbe, err := renderSecureProbe(config[KubeSchedulerArg], probes["kube-scheduler"], runtime, DefaultKubeSchedulerDefaultSecurePort, DefaultKubeSchedulerCertDir, DefaultKubeSchedulerCert)
+		ksProbe, err := renderSecureProbe(config[KubeSchedulerArg], result[probes.KubeScheduler], runtime, DefaultKubeSchedulerDefaultSecurePort, DefaultKubeSchedulerCertDir, DefaultKubeSchedulerCert)
 		if err != nil {
-			return probes, err
+			return result, nil, err
 		}
-		probes["kube-scheduler"] = ksProbe
-	}
-	return probes, nil
-}
-
-// replaceCACertAndPortForProbes adds/replaces the CACert and URL with rendered values based on the values provided.
-func replaceCACertAndPortForProbes(probe plan.Probe, cacert, port string) (plan.Probe, error) {
-	if cacert == "" || port == "" {
-		return plan.Probe{}, fmt.Errorf("CA cert (%s) or port (%s) not defined properly", cacert, port)
-	}
-	probe.HTTPGetAction.CACert = cacert
-	probe.HTTPGetAction.URL = fmt.Sprintf(probe.HTTPGetAction.URL, port)
-	return probe, nil
-}
-
-func replaceRuntimeForProbes(probes map[string]plan.Probe, runtime string) map[string]plan.Probe {
-	result := map[string]plan.Probe{}
-	for k, v := range probes {
-		v.HTTPGetAction.CACert = replaceRuntime(v.HTTPGetAction.CACert, runtime)
-		v.HTTPGetAction.ClientCert = replaceRuntime(v.HTTPGetAction.ClientCert, runtime)
-		v.HTTPGetAction.ClientKey = replaceRuntime(v.HTTPGetAction.ClientKey, runtime)
-		result[k] = v
+		result[probes.KubeScheduler] = ksProbe
 	}
-	return result
-}
 
-func replaceRuntime(str string, runtime string) string {
-	if !strings.Contains(str, "%s") {
-		return str
-	}
-	return fmt.Sprintf(str, runtime)
+	return probes.Customize(result, controlPlane.Spec.ProbeCustomizations, runtime, currentSystemAgentAtLeast(minProbeRequestOptionsSystemAgentVersion))
 }
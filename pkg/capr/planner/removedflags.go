@@ -0,0 +1,130 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// removedFlag is a component flag that was removed in a Kubernetes minor version. If the flag was renamed without
+// changing its values, the replacement is set and the flag is translated rather than rejected.
+type removedFlag struct {
+	removedIn   string
+	replacement string
+}
+
+// removedFlags are the flags of the Kubernetes components, by the config key of their args, that were removed in a
+// Kubernetes version. Components fail to start with a flag they do not know anymore, so the flags are checked before
+// they are delivered to a machine that is upgraded to the version.
+var removedFlags = map[string]map[string]removedFlag{
+	"kube-apiserver-arg": {
+		"insecure-bind-address":         {removedIn: "1.24"},
+		"insecure-port":                 {removedIn: "1.24"},
+		"address":                       {removedIn: "1.24"},
+		"port":                          {removedIn: "1.24"},
+		"service-account-api-audiences": {removedIn: "1.20", replacement: "api-audiences"},
+	},
+	"kube-controller-manager-arg": {
+		"address":              {removedIn: "1.24"},
+		"port":                 {removedIn: "1.24"},
+		"deleting-pods-burst":  {removedIn: "1.24"},
+		"deleting-pods-qps":    {removedIn: "1.24"},
+		"register-retry-count": {removedIn: "1.24"},
+		"pod-eviction-timeout": {removedIn: "1.27"},
+		"enable-taint-manager": {removedIn: "1.29"},
+		"horizontal-pod-autoscaler-use-rest-clients": {removedIn: "1.21"},
+	},
+	"kube-scheduler-arg": {
+		"address":                    {removedIn: "1.23"},
+		"port":                       {removedIn: "1.23"},
+		"policy-config-file":         {removedIn: "1.23"},
+		"policy-configmap":           {removedIn: "1.23"},
+		"policy-configmap-namespace": {removedIn: "1.23"},
+		"use-legacy-policy-config":   {removedIn: "1.23"},
+	},
+	"kubelet-arg": {
+		"cni-bin-dir":                            {removedIn: "1.24"},
+		"cni-cache-dir":                          {removedIn: "1.24"},
+		"cni-conf-dir":                           {removedIn: "1.24"},
+		"docker-endpoint":                        {removedIn: "1.24"},
+		"dynamic-config-dir":                     {removedIn: "1.24"},
+		"experimental-dockershim-root-directory": {removedIn: "1.24"},
+		"image-pull-progress-deadline":           {removedIn: "1.24"},
+		"network-plugin":                         {removedIn: "1.24"},
+		"network-plugin-mtu":                     {removedIn: "1.24"},
+		"non-masquerade-cidr":                    {removedIn: "1.24"},
+		"container-runtime":                      {removedIn: "1.27"},
+	},
+}
+
+// translateRemovedFlags checks the component args of the rendered distribution config for flags that were removed in
+// the Kubernetes version of the control plane. Flags that were renamed are translated to their replacement with a
+// warning, while other removed flags fail plan generation, so that the components are not crash looping after the
+// upgrade.
+func translateRemovedFlags(config map[string]interface{}, controlPlane *rkev1.RKEControlPlane, entry *planEntry) error {
+	version, err := semver.NewVersion(controlPlane.Spec.KubernetesVersion)
+	if err != nil {
+		return fmt.Errorf("error parsing kubernetes version %s: %w", controlPlane.Spec.KubernetesVersion, err)
+	}
+
+	var keys []string
+	for k := range config {
+		if removedFlags[k] != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		args := convertInterfaceToStringSlice(config[key])
+		translated := make([]string, 0, len(args))
+		changed := false
+		for _, arg := range args {
+			removed, ok := flagRemoved(key, arg, version)
+			if !ok {
+				translated = append(translated, arg)
+				continue
+			}
+			flag, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+			if removed.replacement == "" {
+				return fmt.Errorf("flag %s in %s of machine %s/%s was removed in Kubernetes %s and can not be used with %s", flag, key, entry.Machine.Namespace, entry.Machine.Name, removed.removedIn, controlPlane.Spec.KubernetesVersion)
+			}
+			replaced := removed.replacement
+			if strings.HasPrefix(arg, "--") {
+				replaced = "--" + replaced
+			}
+			if hasValue {
+				replaced += "=" + value
+			}
+			logrus.Warnf("[planner] rkecluster %s/%s: flag %s in %s of machine %s/%s was removed in Kubernetes %s, using %s instead", controlPlane.Namespace, controlPlane.Name, flag, key, entry.Machine.Namespace, entry.Machine.Name, removed.removedIn, removed.replacement)
+			translated = append(translated, replaced)
+			changed = true
+		}
+		if changed {
+			config[key] = translated
+		}
+	}
+	return nil
+}
+
+// flagRemoved returns the removed flag that the arg of the config key sets, if it was removed in or before the
+// Kubernetes version.
+func flagRemoved(key, arg string, version *semver.Version) (removedFlag, bool) {
+	flag, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+	removed, ok := removedFlags[key][flag]
+	if !ok {
+		return removedFlag{}, false
+	}
+	removedIn, err := semver.NewVersion(removed.removedIn)
+	if err != nil {
+		return removedFlag{}, false
+	}
+	if version.Major() > removedIn.Major() || (version.Major() == removedIn.Major() && version.Minor() >= removedIn.Minor()) {
+		return removed, true
+	}
+	return removedFlag{}, false
+}
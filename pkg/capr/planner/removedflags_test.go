@@ -0,0 +1,72 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestTranslateRemovedFlags(t *testing.T) {
+	entry := &planEntry{Machine: &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "fleet-default"}}}
+	tests := []struct {
+		name     string
+		version  string
+		config   map[string]interface{}
+		expected map[string]interface{}
+		wantErr  bool
+	}{
+		{
+			name:    "removed in a later version",
+			version: "v1.26.8+rke2r1",
+			config: map[string]interface{}{
+				"kube-controller-manager-arg": []interface{}{"pod-eviction-timeout=1m"},
+			},
+			expected: map[string]interface{}{
+				"kube-controller-manager-arg": []interface{}{"pod-eviction-timeout=1m"},
+			},
+		},
+		{
+			name:    "removed",
+			version: "v1.27.5+k3s1",
+			config: map[string]interface{}{
+				"kube-controller-manager-arg": []interface{}{"node-monitor-grace-period=20s", "pod-eviction-timeout=1m"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "removed flag of another component",
+			version: "v1.27.5+k3s1",
+			config: map[string]interface{}{
+				"kube-apiserver-arg": []interface{}{"pod-eviction-timeout=1m"},
+			},
+			expected: map[string]interface{}{
+				"kube-apiserver-arg": []interface{}{"pod-eviction-timeout=1m"},
+			},
+		},
+		{
+			name:    "renamed",
+			version: "v1.25.9+rke2r1",
+			config: map[string]interface{}{
+				"kube-apiserver-arg": []interface{}{"--service-account-api-audiences=rke2", "audit-log-maxage=30"},
+			},
+			expected: map[string]interface{}{
+				"kube-apiserver-arg": []string{"--api-audiences=rke2", "audit-log-maxage=30"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &rkev1.RKEControlPlane{Spec: rkev1.RKEControlPlaneSpec{KubernetesVersion: tt.version}}
+			err := translateRemovedFlags(tt.config, cp, entry)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, tt.config)
+		})
+	}
+}
@@ -1,16 +1,39 @@
 package planner
 
 import (
+	"bytes"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
 	"fmt"
+	"net"
+	"regexp"
 	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/s3client"
 	"github.com/rancher/rancher/pkg/controllers/capr/machineprovision"
 	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	"github.com/rancher/wrangler/pkg/kv"
 	"github.com/rancher/wrangler/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// maxEndpointCASize is the maximum size in bytes of an S3 endpoint CA bundle. The bundle is delivered to nodes as part
+// of the plan secret, so it must remain small enough to not push the plan past secret size limits.
+const maxEndpointCASize = 64 * 1024
+
+var (
+	s3BucketRegexp        = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*[a-z0-9]$`)
+	s3FolderSegmentRegexp = regexp.MustCompile(`^[a-zA-Z0-9!_.*'()-]+$`)
+	s3StorageClassRegexp  = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+	// s3SessionTokenVersion is the first Kubernetes version whose k3s and RKE2 releases accept the session token of
+	// short-lived S3 credentials.
+	s3SessionTokenVersion = semver.MustParse("v1.31.0")
 )
 
 // s3Args is a struct that contains functions used to generate arguments for etcd snapshots stored in S3
@@ -37,8 +60,12 @@ func S3Enabled(s3 *rkev1.ETCDSnapshotS3) bool {
 	return false
 }
 
-// ToArgs renders a slice of arguments and environment variables, as well as files (if S3 endpoints are required). If secretKeyInEnv is set to true, it will set the AWS_SECRET_ACCESS_KEY as an environment variable rather than as an argument.
-func (s *s3Args) ToArgs(s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControlPlane, prefix string, secretKeyInEnv bool) (args []string, env []string, files []plan.File, err error) {
+// ToArgs renders a slice of arguments and environment variables, as well as files (if S3 endpoints are required), for the machine of the passed in entry. If secretKeyInEnv is set to true, it will set the AWS_SECRET_ACCESS_KEY as an environment variable rather than as an argument.
+// The session token of short-lived credentials is passed the same way as the secret key, and is only supported by
+// Kubernetes versions whose distribution accepts it; the distribution would otherwise access S3 with incomplete
+// credentials.
+// If the cloud credential uses the IAM role of the nodes, no access keys are rendered and the machine must run in AWS.
+func (s *s3Args) ToArgs(s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControlPlane, entry *planEntry, prefix string, secretKeyInEnv bool) (args []string, env []string, files []plan.File, err error) {
 	if s3 == nil {
 		return
 	}
@@ -56,30 +83,57 @@ func (s *s3Args) ToArgs(s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControl
 		credName = controlPlane.Spec.ETCD.S3.CloudCredentialName
 	}
 
-	s3Cred, err = getS3Credential(s.secretCache, controlPlane.Namespace, credName)
+	s3Cred, err = getS3Credential(s.secretCache, controlPlane.Namespace, credName, controlPlane.Name)
 	if err != nil {
 		return
 	}
 
-	if s3.Bucket != "" || s3Cred.Bucket != "" {
-		args = append(args, fmt.Sprintf("--%ss3-bucket=%s", prefix, first(s3.Bucket, s3Cred.Bucket)))
+	bucket, err := normalizeS3Bucket(first(s3.Bucket, s3Cred.Bucket))
+	if err != nil {
+		return
 	}
+	folder := normalizeS3Folder(first(s3.Folder, s3Cred.Folder))
 
-	if s3Cred.AccessKey != "" {
+	if bucket != "" {
+		args = append(args, fmt.Sprintf("--%ss3-bucket=%s", prefix, bucket))
+	}
+
+	if s3Cred.UseIAMRole {
+		if !runsInAWS(entry) {
+			err = fmt.Errorf("etcd snapshot cloud credential %s uses the IAM role of the nodes, but machine %s/%s does not run in AWS", credName, entry.Machine.Namespace, entry.Machine.Name)
+			return
+		}
+	} else if s3Cred.AccessKey != "" {
 		args = append(args, fmt.Sprintf("--%ss3-access-key=%s", prefix, s3Cred.AccessKey))
 	}
-	if s3Cred.SecretKey != "" {
+	if s3Cred.SecretKey != "" && !s3Cred.UseIAMRole {
 		if secretKeyInEnv {
 			env = append(env, fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%s", s3Cred.SecretKey))
 		} else {
 			args = append(args, fmt.Sprintf("--%ss3-secret-key=%s", prefix, s3Cred.SecretKey))
 		}
 	}
+	if s3Cred.SessionToken != "" && !s3Cred.UseIAMRole {
+		var supported bool
+		supported, err = kubernetesVersionAtLeast(controlPlane.Spec.KubernetesVersion, s3SessionTokenVersion)
+		if err != nil {
+			return
+		}
+		if !supported {
+			err = fmt.Errorf("etcd snapshot cloud credential %s has a session token, which is not supported by Kubernetes %s, it requires %s or newer", credName, controlPlane.Spec.KubernetesVersion, s3SessionTokenVersion.Original())
+			return
+		}
+		if secretKeyInEnv {
+			env = append(env, fmt.Sprintf("AWS_SESSION_TOKEN=%s", s3Cred.SessionToken))
+		} else {
+			args = append(args, fmt.Sprintf("--%ss3-session-token=%s", prefix, s3Cred.SessionToken))
+		}
+	}
 	if v := first(s3.Region, s3Cred.Region); v != "" {
 		args = append(args, fmt.Sprintf("--%ss3-region=%s", prefix, v))
 	}
-	if v := first(s3.Folder, s3Cred.Folder); v != "" {
-		args = append(args, fmt.Sprintf("--%ss3-folder=%s", prefix, v))
+	if folder != "" {
+		args = append(args, fmt.Sprintf("--%ss3-folder=%s", prefix, folder))
 	}
 	if v := first(s3.Endpoint, s3Cred.Endpoint); v != "" {
 		args = append(args, fmt.Sprintf("--%ss3-endpoint=%s", prefix, v))
@@ -95,21 +149,33 @@ func (s *s3Args) ToArgs(s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControl
 			// Check to see if the s3Cred.EndpointCA matches the s3.EndpointCA. If it does, use that CA, otherwise,
 			// fallback to just specifying the CA as an argument.
 			if s3Cred.EndpointCA != "" {
+				ca, caErr := normalizeEndpointCA(s3Cred.EndpointCA)
+				if caErr != nil {
+					err = fmt.Errorf("invalid endpoint CA in etcd snapshot cloud credential %s: %w", credName, caErr)
+					return
+				}
 				s3CAName := fmt.Sprintf("s3-endpoint-ca-%s.crt", name.Hex(s3Cred.EndpointCA, 5))
 				filePath := configFile(controlPlane, s3CAName)
 				if filePath == v {
 					// If the filepath of the s3cred endpointCA matches the endpoint CA that was used for the snapshot, go ahead and include the file for posterity
 					files = append(files, plan.File{
-						Content: base64.StdEncoding.EncodeToString([]byte(s3Cred.EndpointCA)),
+						Content: base64.StdEncoding.EncodeToString([]byte(ca)),
 						Path:    filePath,
 					})
 				}
 			}
 		} else {
+			ca, caErr := normalizeEndpointCA(v)
+			if caErr != nil {
+				err = fmt.Errorf("invalid etcd snapshot S3 endpoint CA: %w", caErr)
+				return
+			}
+			// The file is named after the CA as it is configured rather than the normalized CA, so that the path
+			// recorded in existing snapshots keeps matching the file.
 			s3CAName := fmt.Sprintf("s3-endpoint-ca-%s.crt", name.Hex(v, 5))
 			filePath := configFile(controlPlane, s3CAName)
 			files = append(files, plan.File{
-				Content: base64.StdEncoding.EncodeToString([]byte(v)),
+				Content: base64.StdEncoding.EncodeToString([]byte(ca)),
 				Path:    filePath,
 			})
 			args = append(args, fmt.Sprintf("--%ss3-endpoint-ca=%s", prefix, filePath))
@@ -124,6 +190,200 @@ func (s *s3Args) ToArgs(s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControl
 	return
 }
 
+// runsInAWS returns whether the machine of the entry runs in AWS, either because it was provisioned by the amazonec2 node
+// driver or because the AWS cloud provider set its provider ID.
+func runsInAWS(entry *planEntry) bool {
+	if entry.Machine.Spec.InfrastructureRef.Kind == "Amazonec2Machine" {
+		return true
+	}
+	return entry.Machine.Spec.ProviderID != nil && strings.HasPrefix(*entry.Machine.Spec.ProviderID, "aws://")
+}
+
+// normalizeEndpointCA validates that the passed in endpoint CA is a PEM encoded certificate or bundle of concatenated
+// certificates, and returns it with CRLF line endings converted to LF and a single trailing newline. Validating here
+// surfaces a malformed CA on the control plane rather than as an opaque TLS failure on the node during upload.
+func normalizeEndpointCA(ca string) (string, error) {
+	if len(ca) > maxEndpointCASize {
+		return "", fmt.Errorf("CA bundle is %d bytes which exceeds the maximum of %d bytes", len(ca), maxEndpointCASize)
+	}
+
+	data := bytes.TrimSpace([]byte(strings.ReplaceAll(ca, "\r\n", "\n")))
+	if len(data) == 0 {
+		return "", fmt.Errorf("CA bundle is empty")
+	}
+
+	var (
+		rest  = data
+		count int
+	)
+	for len(bytes.TrimSpace(rest)) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			if count == 0 {
+				return "", fmt.Errorf("CA bundle is not PEM encoded")
+			}
+			return "", fmt.Errorf("CA bundle contains invalid PEM data after certificate %d", count)
+		}
+		count++
+		if block.Type != "CERTIFICATE" {
+			return "", fmt.Errorf("CA bundle entry %d is of type %s, expected CERTIFICATE", count, block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return "", fmt.Errorf("CA bundle entry %d could not be parsed: %w", count, err)
+		}
+	}
+
+	return string(data) + "\n", nil
+}
+
+// normalizeS3Bucket trims surrounding whitespace from the bucket name and validates it, so that a bucket the etcd
+// snapshot upload on the node cannot use is not rendered into the plan.
+func normalizeS3Bucket(bucket string) (string, error) {
+	bucket = strings.TrimSpace(bucket)
+	return bucket, validateS3Bucket(bucket)
+}
+
+// validateS3Bucket validates the bucket name against the S3 bucket naming rules, so that a typo is reported on the
+// control plane rather than by the etcd snapshot upload on the node.
+func validateS3Bucket(bucket string) error {
+	if bucket == "" {
+		return nil
+	}
+	if len(bucket) < 3 || len(bucket) > 63 {
+		return fmt.Errorf("invalid etcd snapshot S3 bucket %q: must be between 3 and 63 characters long", bucket)
+	}
+	if !s3BucketRegexp.MatchString(bucket) {
+		return fmt.Errorf("invalid etcd snapshot S3 bucket %q: must consist of lowercase letters, numbers, dots and hyphens, and begin and end with a letter or number", bucket)
+	}
+	if strings.Contains(bucket, "..") {
+		return fmt.Errorf("invalid etcd snapshot S3 bucket %q: must not contain two adjacent dots", bucket)
+	}
+	if net.ParseIP(bucket) != nil {
+		return fmt.Errorf("invalid etcd snapshot S3 bucket %q: must not be formatted as an IP address", bucket)
+	}
+	return nil
+}
+
+// normalizeS3Folder trims surrounding whitespace and slashes from the folder and collapses repeated slashes, as they
+// would otherwise end up in the key prefix of the snapshots.
+func normalizeS3Folder(folder string) string {
+	var segments []string
+	for _, segment := range strings.Split(strings.TrimSpace(folder), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// validateS3Folder validates that the folder only contains characters that are safe to use in S3 object keys. Leading,
+// trailing and repeated slashes are not reported, as the folder is normalized when it is rendered into the plan.
+// Problems are only reported, as folders that were accepted before must keep working.
+func validateS3Folder(folder string) error {
+	for _, segment := range strings.Split(normalizeS3Folder(folder), "/") {
+		if segment == "" {
+			continue
+		}
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("invalid etcd snapshot S3 folder %q: must not contain relative path segments", folder)
+		}
+		if !s3FolderSegmentRegexp.MatchString(segment) {
+			return fmt.Errorf("invalid etcd snapshot S3 folder %q: must only contain letters, numbers, slashes and the characters !-_.*'()", folder)
+		}
+	}
+	return nil
+}
+
+// validateS3StorageClass validates the etcd snapshot S3 storage class. The Glacier storage classes other than Glacier
+// Instant Retrieval are rejected, as their objects can not be downloaded to restore them without restoring them in S3
+// first.
+func validateS3StorageClass(storageClass string) error {
+	if !s3StorageClassRegexp.MatchString(storageClass) {
+		return fmt.Errorf("invalid etcd snapshot S3 storage class %q: must only contain letters, numbers, underscores and hyphens", storageClass)
+	}
+	switch strings.ToUpper(storageClass) {
+	case "GLACIER", "DEEP_ARCHIVE":
+		return fmt.Errorf("invalid etcd snapshot S3 storage class %q: snapshots in archive storage classes can not be restored, use GLACIER_IR instead", storageClass)
+	}
+	return nil
+}
+
+// kubernetesVersionAtLeast returns whether the Kubernetes version is the minimum version or newer. Pre-releases of a
+// version, such as release candidates, are considered to be that version.
+func kubernetesVersionAtLeast(kubernetesVersion string, minimum *semver.Version) (bool, error) {
+	version, err := semver.NewVersion(kubernetesVersion)
+	if err != nil {
+		return false, err
+	}
+	release, err := version.SetPrerelease("")
+	if err != nil {
+		return false, err
+	}
+	return !release.LessThan(minimum), nil
+}
+
+// S3Config is the configuration used to access the S3 bucket of an etcd snapshot.
+type S3Config = s3client.Config
+
+// GetS3Config returns the configuration used to access the bucket of the passed in ETCDSnapshotS3, with any fields that
+// are not set on it defaulted from its cloud credential. If the ETCDSnapshotS3 does not reference a cloud credential,
+// the cloud credential of the control plane etcd S3 configuration is used. If the cloud credential uses the IAM role of
+// the nodes, the config has no access keys, so that the management plane accesses the bucket with its own IAM role.
+func GetS3Config(secretCache corecontrollers.SecretCache, s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControlPlane) (S3Config, error) {
+	credName := s3.CloudCredentialName
+	if credName == "" && controlPlane.Spec.ETCD != nil && controlPlane.Spec.ETCD.S3 != nil {
+		credName = controlPlane.Spec.ETCD.S3.CloudCredentialName
+	}
+
+	s3Cred, err := getS3Credential(secretCache, controlPlane.Namespace, credName, controlPlane.Name)
+	if err != nil {
+		return S3Config{}, err
+	}
+
+	bucket, err := normalizeS3Bucket(first(s3.Bucket, s3Cred.Bucket))
+	if err != nil {
+		return S3Config{}, err
+	}
+	folder := normalizeS3Folder(first(s3.Folder, s3Cred.Folder))
+
+	endpointCA := first(s3.EndpointCA, s3Cred.EndpointCA)
+	if endpointCA == s3.EndpointCA && strings.HasSuffix(endpointCA, ".crt") {
+		// The snapshot references the file the CA was written to on the node, the CA itself is only known by the credential.
+		endpointCA = s3Cred.EndpointCA
+	}
+
+	if s3Cred.UseIAMRole {
+		s3Cred.AccessKey, s3Cred.SecretKey, s3Cred.SessionToken = "", "", ""
+	}
+
+	return S3Config{
+		AccessKey:     s3Cred.AccessKey,
+		SecretKey:     s3Cred.SecretKey,
+		SessionToken:  s3Cred.SessionToken,
+		Region:        first(s3.Region, s3Cred.Region),
+		Endpoint:      first(s3.Endpoint, s3Cred.Endpoint),
+		EndpointCA:    endpointCA,
+		SkipSSLVerify: s3.SkipSSLVerify || s3Cred.SkipSSLVerify,
+		Bucket:        bucket,
+		Folder:        folder,
+	}, nil
+}
+
+// getNodeCloudCredentialSecret returns the secret of a cloud credential whose data is passed to the machines of the
+// cluster. Cloud credentials that refer to an external secret store are refused, as the data of the store would
+// otherwise be copied into the plan secrets of the machines.
+func getNodeCloudCredentialSecret(secretCache corecontrollers.SecretCache, namespace, name, clusterName string) (*corev1.Secret, error) {
+	secret, err := machineprovision.GetCloudCredentialSecret(secretCache, namespace, name, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := secret.Annotations[capr.CloudCredentialSecretStoreAnnotation]; ok {
+		return nil, fmt.Errorf("cloud credential %s refers to an external secret store, which is only supported for provisioning machines and encrypting etcd snapshots", name)
+	}
+	return secret, nil
+}
+
 type s3Credential struct {
 	AccessKey     string
 	SecretKey     string
@@ -133,14 +393,19 @@ type s3Credential struct {
 	SkipSSLVerify bool
 	Bucket        string
 	Folder        string
+	// SessionToken is set if the access keys of the credential are short-lived credentials issued by STS.
+	SessionToken string
+	// UseIAMRole is set if the bucket is accessed with the instance profile or web identity of the nodes rather than
+	// the access keys of the credential.
+	UseIAMRole bool
 }
 
-func getS3Credential(secretCache corecontrollers.SecretCache, namespace, name string) (result s3Credential, _ error) {
+func getS3Credential(secretCache corecontrollers.SecretCache, namespace, name, clusterName string) (result s3Credential, _ error) {
 	if name == "" {
 		return result, nil
 	}
 
-	secret, err := machineprovision.GetCloudCredentialSecret(secretCache, namespace, name)
+	secret, err := getNodeCloudCredentialSecret(secretCache, namespace, name, clusterName)
 	if err != nil {
 		return result, fmt.Errorf("failed to lookup etcdSnapshotCloudCredentialName: %w", err)
 	}
@@ -154,11 +419,13 @@ func getS3Credential(secretCache corecontrollers.SecretCache, namespace, name st
 	return s3Credential{
 		AccessKey:     string(data["accessKey"]),
 		SecretKey:     string(data["secretKey"]),
+		SessionToken:  string(data["sessionToken"]),
 		Region:        string(data["defaultRegion"]),
 		Endpoint:      string(data["defaultEndpoint"]),
 		EndpointCA:    string(data["defaultEndpointCA"]),
 		SkipSSLVerify: string(data["defaultSkipSSLVerify"]) == "true",
 		Bucket:        string(data["defaultBucket"]),
 		Folder:        string(data["defaultFolder"]),
+		UseIAMRole:    string(data["useIAMRole"]) == "true",
 	}, nil
 }
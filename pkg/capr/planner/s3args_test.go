@@ -0,0 +1,473 @@
+package planner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func generateTestCA(t *testing.T, commonName string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestNormalizeEndpointCA(t *testing.T) {
+	caOne := generateTestCA(t, "one")
+	caTwo := generateTestCA(t, "two")
+
+	tests := []struct {
+		name        string
+		ca          string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "single certificate",
+			ca:       caOne,
+			expected: caOne,
+		},
+		{
+			name:     "bundle of certificates",
+			ca:       caOne + caTwo,
+			expected: caOne + caTwo,
+		},
+		{
+			name:     "crlf line endings and surrounding whitespace",
+			ca:       "\r\n  " + strings.ReplaceAll(caOne, "\n", "\r\n") + "\r\n\r\n",
+			expected: caOne,
+		},
+		{
+			name:        "empty",
+			ca:          "  \n",
+			expectedErr: "CA bundle is empty",
+		},
+		{
+			name:        "not pem",
+			ca:          "not a certificate",
+			expectedErr: "CA bundle is not PEM encoded",
+		},
+		{
+			name:        "trailing garbage",
+			ca:          caOne + "garbage",
+			expectedErr: "CA bundle contains invalid PEM data after certificate 1",
+		},
+		{
+			name:        "wrong block type",
+			ca:          string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")})),
+			expectedErr: "CA bundle entry 1 is of type PRIVATE KEY, expected CERTIFICATE",
+		},
+		{
+			name:        "invalid certificate",
+			ca:          caOne + string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("bad")})),
+			expectedErr: "CA bundle entry 2 could not be parsed",
+		},
+		{
+			name:        "too large",
+			ca:          strings.Repeat(caOne, maxEndpointCASize/len(caOne)+1),
+			expectedErr: "exceeds the maximum",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := normalizeEndpointCA(tt.ca)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestToArgsEndpointCAFile(t *testing.T) {
+	ca := "\r\n" + strings.ReplaceAll(generateTestCA(t, "one"), "\n", "\r\n")
+	normalized, err := normalizeEndpointCA(ca)
+	require.NoError(t, err)
+
+	controlPlane := createTestControlPlane("v1.25.7+rke2r1")
+	s3 := &rkev1.ETCDSnapshotS3{Bucket: "snapshots", EndpointCA: ca}
+	args, _, files, err := (&s3Args{}).ToArgs(s3, controlPlane, createTestPlanEntry("linux"), "etcd-", false)
+	require.NoError(t, err)
+
+	// the file is named after the CA as configured, so that the path matches the one recorded in existing snapshots
+	expectedPath := configFile(controlPlane, "s3-endpoint-ca-"+name.Hex(ca, 5)+".crt")
+	require.Len(t, files, 1)
+	assert.Equal(t, expectedPath, files[0].Path)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(normalized)), files[0].Content)
+	assert.Contains(t, args, "--etcd-s3-endpoint-ca="+expectedPath)
+
+	// a snapshot that records the path of the CA file references it, along with the CA of the cloud credential
+	s3 = &rkev1.ETCDSnapshotS3{Bucket: "snapshots", EndpointCA: expectedPath}
+	args, _, _, err = (&s3Args{}).ToArgs(s3, controlPlane, createTestPlanEntry("linux"), "etcd-", false)
+	require.NoError(t, err)
+	assert.Contains(t, args, "--etcd-s3-endpoint-ca="+expectedPath)
+}
+
+func TestToArgsSessionToken(t *testing.T) {
+	secretCache := &fakeSecretCache{secrets: []*corev1.Secret{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-global-data", Name: "cc-sts"}, Data: map[string][]byte{
+			"s3credentialConfig-accessKey":    []byte("ASIA"),
+			"s3credentialConfig-secretKey":    []byte("secret"),
+			"s3credentialConfig-sessionToken": []byte("token"),
+		}},
+	}}
+	s3 := &rkev1.ETCDSnapshotS3{Bucket: "snapshots", CloudCredentialName: "cattle-global-data:cc-sts"}
+	args := &s3Args{secretCache: secretCache}
+
+	tests := []struct {
+		name           string
+		version        string
+		secretKeyInEnv bool
+		expectedArgs   []string
+		expectedEnv    []string
+		expectedErr    string
+	}{
+		{
+			name:         "config file",
+			version:      "v1.31.1+rke2r1",
+			expectedArgs: []string{"--etcd-s3-bucket=snapshots", "--etcd-s3-access-key=ASIA", "--etcd-s3-secret-key=secret", "--etcd-s3-session-token=token", "--etcd-s3"},
+		},
+		{
+			name:           "instruction",
+			version:        "v1.31.1+rke2r1",
+			secretKeyInEnv: true,
+			expectedArgs:   []string{"--etcd-s3-bucket=snapshots", "--etcd-s3-access-key=ASIA", "--etcd-s3"},
+			expectedEnv:    []string{"AWS_SECRET_ACCESS_KEY=secret", "AWS_SESSION_TOKEN=token"},
+		},
+		{
+			name:         "release candidate",
+			version:      "v1.31.0-rc1+rke2r1",
+			expectedArgs: []string{"--etcd-s3-bucket=snapshots", "--etcd-s3-access-key=ASIA", "--etcd-s3-secret-key=secret", "--etcd-s3-session-token=token", "--etcd-s3"},
+		},
+		{
+			name:        "unsupported version",
+			version:     "v1.30.4+rke2r1",
+			expectedErr: "etcd snapshot cloud credential cattle-global-data:cc-sts has a session token, which is not supported by Kubernetes v1.30.4+rke2r1, it requires v1.31.0 or newer",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlPlane := createTestControlPlane(tt.version)
+			a, env, _, err := args.ToArgs(s3, controlPlane, createTestPlanEntry("linux"), "etcd-", tt.secretKeyInEnv)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedArgs, a)
+			assert.Equal(t, tt.expectedEnv, env)
+		})
+	}
+}
+
+func TestNormalizeS3Bucket(t *testing.T) {
+	tests := []struct {
+		name        string
+		bucket      string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:     "valid",
+			bucket:   "etcd-snapshots.example",
+			expected: "etcd-snapshots.example",
+		},
+		{
+			name:     "surrounding whitespace",
+			bucket:   " etcd-snapshots.example ",
+			expected: "etcd-snapshots.example",
+		},
+		{
+			name:        "too short",
+			bucket:      "ab",
+			expectedErr: `invalid etcd snapshot S3 bucket "ab": must be between 3 and 63 characters long`,
+		},
+		{
+			name:        "uppercase",
+			bucket:      "Snapshots",
+			expectedErr: `invalid etcd snapshot S3 bucket "Snapshots": must consist of lowercase letters, numbers, dots and hyphens, and begin and end with a letter or number`,
+		},
+		{
+			name:        "trailing slash",
+			bucket:      "snapshots/",
+			expectedErr: `invalid etcd snapshot S3 bucket "snapshots/": must consist of lowercase letters, numbers, dots and hyphens, and begin and end with a letter or number`,
+		},
+		{
+			name:        "adjacent dots",
+			bucket:      "etcd..snapshots",
+			expectedErr: `invalid etcd snapshot S3 bucket "etcd..snapshots": must not contain two adjacent dots`,
+		},
+		{
+			name:        "ip address",
+			bucket:      "192.168.0.1",
+			expectedErr: `invalid etcd snapshot S3 bucket "192.168.0.1": must not be formatted as an IP address`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, err := normalizeS3Bucket(tt.bucket)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, bucket)
+		})
+	}
+}
+
+func TestNormalizeS3Folder(t *testing.T) {
+	tests := []struct {
+		name     string
+		folder   string
+		expected string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "only slashes",
+			folder: "//",
+		},
+		{
+			name:     "leading, trailing and repeated slashes",
+			folder:   " /clusters//prod/ ",
+			expected: "clusters/prod",
+		},
+		{
+			name:     "unchanged",
+			folder:   "backups/c-m-abc_(1).old",
+			expected: "backups/c-m-abc_(1).old",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, normalizeS3Folder(tt.folder))
+		})
+	}
+}
+
+func TestValidateS3Folder(t *testing.T) {
+	tests := []struct {
+		name        string
+		folder      string
+		expectedErr string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "safe special characters",
+			folder: "backups/c-m-abc_(1).old",
+		},
+		{
+			name:   "leading, trailing and repeated slashes",
+			folder: "/clusters//prod/",
+		},
+		{
+			name:        "relative segment",
+			folder:      "backups/../prod",
+			expectedErr: `invalid etcd snapshot S3 folder "backups/../prod": must not contain relative path segments`,
+		},
+		{
+			name:        "invalid characters",
+			folder:      "backups/prod cluster",
+			expectedErr: `invalid etcd snapshot S3 folder "backups/prod cluster": must only contain letters, numbers, slashes and the characters !-_.*'()`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateS3Folder(tt.folder)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateS3StorageClass(t *testing.T) {
+	tests := []struct {
+		name         string
+		storageClass string
+		expectedErr  string
+	}{
+		{
+			name:         "standard",
+			storageClass: "STANDARD_IA",
+		},
+		{
+			name:         "provider specific",
+			storageClass: "cold-tier",
+		},
+		{
+			name:         "glacier instant retrieval",
+			storageClass: "GLACIER_IR",
+		},
+		{
+			name:         "glacier",
+			storageClass: "GLACIER",
+			expectedErr:  `invalid etcd snapshot S3 storage class "GLACIER": snapshots in archive storage classes can not be restored, use GLACIER_IR instead`,
+		},
+		{
+			name:         "deep archive",
+			storageClass: "deep_archive",
+			expectedErr:  `invalid etcd snapshot S3 storage class "deep_archive": snapshots in archive storage classes can not be restored, use GLACIER_IR instead`,
+		},
+		{
+			name:         "invalid storage class",
+			storageClass: "STANDARD IA",
+			expectedErr:  `invalid etcd snapshot S3 storage class "STANDARD IA": must only contain letters, numbers, underscores and hyphens`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateS3StorageClass(tt.storageClass)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestKubernetesVersionAtLeast(t *testing.T) {
+	minimum := semver.MustParse("v1.31.0")
+	tests := []struct {
+		name     string
+		version  string
+		expected bool
+		wantErr  bool
+	}{
+		{
+			name:     "newer",
+			version:  "v1.31.2+rke2r1",
+			expected: true,
+		},
+		{
+			name:     "release candidate",
+			version:  "v1.31.0-rc1+k3s1",
+			expected: true,
+		},
+		{
+			name:    "older",
+			version: "v1.30.6+rke2r1",
+		},
+		{
+			name:    "invalid",
+			version: "latest",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atLeast, err := kubernetesVersionAtLeast(tt.version, minimum)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, atLeast)
+		})
+	}
+}
+
+func TestRunsInAWS(t *testing.T) {
+	providerID := func(id string) *string {
+		return &id
+	}
+
+	tests := []struct {
+		name     string
+		machine  capi.Machine
+		expected bool
+	}{
+		{
+			name:    "custom machine",
+			machine: capi.Machine{Spec: capi.MachineSpec{InfrastructureRef: corev1.ObjectReference{Kind: "CustomMachine"}}},
+		},
+		{
+			name:     "amazonec2 machine",
+			machine:  capi.Machine{Spec: capi.MachineSpec{InfrastructureRef: corev1.ObjectReference{Kind: "Amazonec2Machine"}}},
+			expected: true,
+		},
+		{
+			name: "custom machine with AWS provider ID",
+			machine: capi.Machine{Spec: capi.MachineSpec{
+				InfrastructureRef: corev1.ObjectReference{Kind: "CustomMachine"},
+				ProviderID:        providerID("aws:///us-east-1a/i-0123456789abcdef0"),
+			}},
+			expected: true,
+		},
+		{
+			name: "custom machine with other provider ID",
+			machine: capi.Machine{Spec: capi.MachineSpec{
+				InfrastructureRef: corev1.ObjectReference{Kind: "CustomMachine"},
+				ProviderID:        providerID("rke2://server-a"),
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, runsInAWS(&planEntry{Machine: &tt.machine}))
+		})
+	}
+}
+
+func TestGetNodeCloudCredentialSecret(t *testing.T) {
+	secretCache := &fakeSecretCache{secrets: []*corev1.Secret{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-global-data", Name: "cc-s3"}, Data: map[string][]byte{"s3credentialConfig-accessKey": []byte("AKIA")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "cattle-global-data", Name: "cc-vault", Annotations: map[string]string{capr.CloudCredentialSecretStoreAnnotation: "vault:kv/data/cloud/aws"}}},
+	}}
+
+	secret, err := getNodeCloudCredentialSecret(secretCache, "fleet-default", "cattle-global-data:cc-s3", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "cc-s3", secret.Name)
+
+	_, err = getNodeCloudCredentialSecret(secretCache, "fleet-default", "cattle-global-data:cc-vault", "c1")
+	assert.EqualError(t, err, "cloud credential cattle-global-data:cc-vault refers to an external secret store, which is only supported for provisioning machines and encrypting etcd snapshots")
+}
@@ -0,0 +1,50 @@
+package planner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// etcdSnapshotCreateStateHash returns a hash of the semantic etcd snapshot create state, so that a status that was only
+// round-tripped through the API server, i.e. a zero valued create decoded as nil, is not considered changed.
+func etcdSnapshotCreateStateHash(create *rkev1.ETCDSnapshotCreate, phase rkev1.ETCDSnapshotPhase) string {
+	if create != nil && equality.Semantic.DeepEqual(*create, rkev1.ETCDSnapshotCreate{}) {
+		create = nil
+	}
+	return stateHash(create, phase)
+}
+
+// etcdSnapshotRestoreStateHash returns a hash of the semantic etcd snapshot restore state.
+func etcdSnapshotRestoreStateHash(restore *rkev1.ETCDSnapshotRestore, phase rkev1.ETCDSnapshotPhase) string {
+	if restore != nil && *restore == (rkev1.ETCDSnapshotRestore{}) {
+		restore = nil
+	}
+	return stateHash(restore, phase)
+}
+
+// etcdSnapshotUploadStateHash returns a hash of the semantic etcd snapshot upload state.
+func etcdSnapshotUploadStateHash(upload *rkev1.ETCDSnapshotUpload, phase rkev1.ETCDSnapshotPhase) string {
+	if upload != nil && *upload == (rkev1.ETCDSnapshotUpload{}) {
+		upload = nil
+	}
+	return stateHash(upload, phase)
+}
+
+func stateHash(spec interface{}, phase rkev1.ETCDSnapshotPhase) string {
+	data, err := json.Marshal(struct {
+		Spec  interface{}             `json:"spec"`
+		Phase rkev1.ETCDSnapshotPhase `json:"phase"`
+	}{
+		Spec:  spec,
+		Phase: phase,
+	})
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,42 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetEtcdSnapshotCreateState(t *testing.T) {
+	p := &Planner{}
+	status := rkev1.RKEControlPlaneStatus{
+		ETCDSnapshotCreate:      &rkev1.ETCDSnapshotCreate{Generation: 1},
+		ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseStarted,
+	}
+
+	_, err := p.setEtcdSnapshotCreateState(status, &rkev1.ETCDSnapshotCreate{Generation: 1}, rkev1.ETCDSnapshotPhaseStarted)
+	assert.NoError(t, err)
+
+	status, err = p.setEtcdSnapshotCreateState(status, &rkev1.ETCDSnapshotCreate{Generation: 1}, rkev1.ETCDSnapshotPhaseShutdown)
+	assert.True(t, IsErrWaiting(err))
+	assert.Equal(t, rkev1.ETCDSnapshotPhaseShutdown, status.ETCDSnapshotCreatePhase)
+
+	// a zero valued create is the same state as no create
+	_, err = p.setEtcdSnapshotCreateState(rkev1.RKEControlPlaneStatus{ETCDSnapshotCreate: &rkev1.ETCDSnapshotCreate{}}, nil, "")
+	assert.NoError(t, err)
+}
+
+func TestSetEtcdSnapshotRestoreState(t *testing.T) {
+	p := &Planner{}
+	status := rkev1.RKEControlPlaneStatus{
+		ETCDSnapshotRestore:      &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 1},
+		ETCDSnapshotRestorePhase: rkev1.ETCDSnapshotPhaseStarted,
+	}
+
+	_, err := p.setEtcdSnapshotRestoreState(status, &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 1}, rkev1.ETCDSnapshotPhaseStarted)
+	assert.NoError(t, err)
+
+	status, err = p.setEtcdSnapshotRestoreState(status, &rkev1.ETCDSnapshotRestore{Name: "snapshot", Generation: 2}, rkev1.ETCDSnapshotPhaseStarted)
+	assert.True(t, IsErrWaiting(err))
+	assert.Equal(t, 2, status.ETCDSnapshotRestore.Generation)
+}
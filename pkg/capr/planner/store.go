@@ -4,6 +4,7 @@
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
@@ -15,11 +16,14 @@
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
 	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/faultinjection"
 	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
 	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	"github.com/rancher/wrangler/pkg/generic"
 	corev1 "k8s.io/api/core/v1"
@@ -27,6 +31,7 @@
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	capi "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
@@ -36,17 +41,30 @@
 	FailedPlanStatusMessage  = "failure while applying plan"
 )
 
+// PlanUpdatedAtKey is the key of the machine plan secret that holds the time the plan was last changed, in RFC 3339
+// format. It is removed once the plan has been applied.
+const PlanUpdatedAtKey = "plan-updated-at"
+
+// plannerDataKeys are the keys of the machine plan secret that are owned by the planner.
+var plannerDataKeys = []string{"plan", PlanEncodingKey, PlanUpdatedAtKey, "max-failures", "failure-threshold"}
+
 type PlanStore struct {
-	secrets      corecontrollers.SecretClient
-	secretsCache corecontrollers.SecretCache
-	machineCache capicontrollers.MachineCache
+	ctx                    context.Context
+	applier                *capr.ServerSideApplier
+	secrets                corecontrollers.SecretClient
+	secretsCache           corecontrollers.SecretCache
+	machineCache           capicontrollers.MachineCache
+	instructionPolicyCache rkecontrollers.InstructionPolicyCache
 }
 
-func NewStore(secrets corecontrollers.SecretController, machineCache capicontrollers.MachineCache) *PlanStore {
+func NewStore(ctx context.Context, applier *capr.ServerSideApplier, secrets corecontrollers.SecretController, machineCache capicontrollers.MachineCache, instructionPolicyCache rkecontrollers.InstructionPolicyCache) *PlanStore {
 	return &PlanStore{
-		secrets:      secrets,
-		secretsCache: secrets.Cache(),
-		machineCache: machineCache,
+		ctx:                    ctx,
+		applier:                applier,
+		secrets:                secrets,
+		secretsCache:           secrets.Cache(),
+		machineCache:           machineCache,
+		instructionPolicyCache: instructionPolicyCache,
 	}
 }
 
@@ -110,6 +128,7 @@ func (p *PlanStore) Load(cluster *capi.Cluster, rkeControlPlane *rkev1.RKEContro
 		if node == nil {
 			continue
 		}
+		faultinjection.ApplyToNode(rkeControlPlane, machineName, node)
 		if node.PlanDataExists {
 			anyPlanDelivered = true
 		}
@@ -223,7 +242,11 @@ func SecretToNode(secret *corev1.Secret) (*plan.Node, error) {
 	}
 
 	if len(planData) > 0 {
-		if err := json.Unmarshal(planData, &result.Plan); err != nil {
+		planJSON, err := decodePlan(planData)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(planJSON, &result.Plan); err != nil {
 			return nil, err
 		}
 	} else {
@@ -231,8 +254,12 @@ func SecretToNode(secret *corev1.Secret) (*plan.Node, error) {
 	}
 
 	if len(appliedPlanData) > 0 {
+		appliedPlanJSON, err := decodePlan(appliedPlanData)
+		if err != nil {
+			return nil, err
+		}
 		newPlan := &plan.NodePlan{}
-		if err := json.Unmarshal(appliedPlanData, newPlan); err != nil {
+		if err := json.Unmarshal(appliedPlanJSON, newPlan); err != nil {
 			return nil, err
 		}
 		result.AppliedPlan = newPlan
@@ -362,6 +389,10 @@ func (p *PlanStore) UpdatePlan(entry *planEntry, newNodePlan plan.NodePlan, join
 	if maxFailures < failureThreshold && failureThreshold != -1 && maxFailures != -1 {
 		return fmt.Errorf("failureThreshold (%d) cannot be greater than maxFailures (%d)", failureThreshold, maxFailures)
 	}
+	if err := p.checkInstructionPolicy(entry, newNodePlan); err != nil {
+		return err
+	}
+
 	secret, err := p.getPlanSecretFromMachine(entry.Machine)
 	if err != nil {
 		return err
@@ -371,6 +402,10 @@ func (p *PlanStore) UpdatePlan(entry *planEntry, newNodePlan plan.NodePlan, join
 	if err != nil {
 		return err
 	}
+	data, encoding, err := encodePlan(data, planCompressionThreshold())
+	if err != nil {
+		return err
+	}
 
 	secret = secret.DeepCopy()
 	if secret.Data == nil {
@@ -395,23 +430,33 @@ func (p *PlanStore) UpdatePlan(entry *planEntry, newNodePlan plan.NodePlan, join
 
 	capr.CopyPlanMetadataToSecret(secret, entry.Metadata)
 
-	// If the plan is being updated, then delete the probe-statuses so their healthy status will be reported as healthy only when they pass.
-	delete(secret.Data, "probe-statuses")
-
+	if !bytes.Equal(secret.Data["plan"], data) {
+		secret.Data[PlanUpdatedAtKey] = []byte(time.Now().UTC().Format(time.RFC3339))
+	}
 	secret.Data["plan"] = data
+	// If the plan is being updated, then delete the probe-statuses so their healthy status will be reported as healthy only when they pass.
+	removeData := []string{"probe-statuses"}
+	if encoding != "" {
+		secret.Data[PlanEncodingKey] = []byte(encoding)
+	} else {
+		delete(secret.Data, PlanEncodingKey)
+		removeData = append(removeData, PlanEncodingKey)
+	}
 	if maxFailures > 0 || maxFailures == -1 {
 		secret.Data["max-failures"] = []byte(strconv.Itoa(maxFailures))
 	} else {
 		delete(secret.Data, "max-failures")
+		removeData = append(removeData, "max-failures")
 	}
 
 	if failureThreshold > 0 || failureThreshold == -1 {
 		secret.Data["failure-threshold"] = []byte(strconv.Itoa(failureThreshold))
 	} else {
 		delete(secret.Data, "failure-threshold")
+		removeData = append(removeData, "failure-threshold")
 	}
 
-	updatedSecret, err := p.secrets.Update(secret)
+	updatedSecret, err := p.applyPlanSecret(secret, entry.Metadata, removeData, nil)
 	if err != nil {
 		return err
 	}
@@ -434,7 +479,7 @@ func (p *PlanStore) updatePlanSecretLabelsAndAnnotations(entry *planEntry) error
 
 	secret = secret.DeepCopy()
 	capr.CopyPlanMetadataToSecret(secret, entry.Metadata)
-	updatedSecret, err := p.secrets.Update(secret)
+	updatedSecret, err := p.applyPlanSecret(secret, entry.Metadata, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -459,7 +504,7 @@ func (p *PlanStore) removePlanSecretLabel(entry *planEntry, key string) error {
 
 	secret = secret.DeepCopy()
 	delete(secret.Labels, key)
-	updatedSecret, err := p.secrets.Update(secret)
+	updatedSecret, err := p.applyPlanSecret(secret, entry.Metadata, nil, []string{key})
 	if err != nil {
 		return err
 	}
@@ -472,6 +517,86 @@ func (p *PlanStore) removePlanSecretLabel(entry *planEntry, key string) error {
 	return nil
 }
 
+// applyPlanSecret applies the labels and annotations of the plan metadata and the data of the machine plan secret that
+// are owned by the planner with server-side apply, so that changes of other controllers to the secret do not cause
+// conflicts. The other labels and annotations of the secret are left to the field managers that set them. The given
+// data keys and labels are removed with a merge patch afterwards, as they may be owned by other field managers, i.e.
+// the probe statuses that are written by the system-agent.
+func (p *PlanStore) applyPlanSecret(secret *corev1.Secret, metadata *plan.Metadata, removeData, removeLabels []string) (*corev1.Secret, error) {
+	applied := plannerPlanSecret(secret, metadata, removeLabels)
+
+	result := &corev1.Secret{}
+	if err := p.applier.Apply(p.ctx, applied, result); err != nil {
+		return nil, err
+	}
+
+	patch := removalPatch(result, removeData, removeLabels)
+	if patch == nil {
+		return result, nil
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	return p.secrets.Patch(result.Namespace, result.Name, types.MergePatchType, data)
+}
+
+// plannerPlanSecret returns the machine plan secret to apply: the labels and annotations of the plan metadata, except
+// for the labels that are removed, and the data keys owned by the planner.
+func plannerPlanSecret(secret *corev1.Secret, metadata *plan.Metadata, removeLabels []string) *corev1.Secret {
+	applied := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secret.Name,
+			Namespace:   secret.Namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		Data: map[string][]byte{},
+	}
+	if metadata != nil {
+		excludes := map[string]struct{}{}
+		for _, key := range removeLabels {
+			excludes[key] = struct{}{}
+		}
+		capr.CopyMapWithExcludes(applied.Labels, metadata.Labels, excludes)
+		capr.CopyMap(applied.Annotations, metadata.Annotations)
+	}
+	for _, key := range plannerDataKeys {
+		if value, ok := secret.Data[key]; ok {
+			applied.Data[key] = value
+		}
+	}
+	return applied
+}
+
+// removalPatch returns a merge patch that removes the given data keys and labels from the secret, or nil if the secret
+// has none of them.
+func removalPatch(secret *corev1.Secret, removeData, removeLabels []string) map[string]interface{} {
+	patch := map[string]interface{}{}
+	removedData := map[string]interface{}{}
+	for _, key := range removeData {
+		if _, ok := secret.Data[key]; ok {
+			removedData[key] = nil
+		}
+	}
+	if len(removedData) > 0 {
+		patch["data"] = removedData
+	}
+	removedLabels := map[string]interface{}{}
+	for _, key := range removeLabels {
+		if _, ok := secret.Labels[key]; ok {
+			removedLabels[key] = nil
+		}
+	}
+	if len(removedLabels) > 0 {
+		patch["metadata"] = map[string]interface{}{"labels": removedLabels}
+	}
+	if len(patch) == 0 {
+		return nil
+	}
+	return patch
+}
+
 // assignAndCheckPlan assigns the given newPlan to the designated server in the planEntry, and will return nil if the plan is assigned and in sync.
 func assignAndCheckPlan(store *PlanStore, msg string, entry *planEntry, newPlan plan.NodePlan, joinedTo string, failureThreshold, maxRetries int) error {
 	if entry.Plan == nil || !equality.Semantic.DeepEqual(entry.Plan.Plan, newPlan) {
@@ -3,7 +3,11 @@
 import (
 	"testing"
 
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestJoinURLFromAddress(t *testing.T) {
@@ -39,3 +43,49 @@ func TestJoinURLFromAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestRemovalPatch(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{capr.InitNodeLabel: "true"},
+		},
+		Data: map[string][]byte{
+			"plan":           []byte("{}"),
+			"probe-statuses": []byte("{}"),
+		},
+	}
+
+	assert.Nil(t, removalPatch(secret, []string{"max-failures"}, []string{capr.EtcdRoleLabel}))
+	assert.Equal(t, map[string]interface{}{
+		"data": map[string]interface{}{"probe-statuses": nil},
+	}, removalPatch(secret, []string{"probe-statuses", "max-failures"}, nil))
+	assert.Equal(t, map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{capr.InitNodeLabel: nil}},
+	}, removalPatch(secret, nil, []string{capr.InitNodeLabel}))
+}
+
+func TestPlannerPlanSecret(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "m1-machine-plan",
+			Namespace:   "fleet-default",
+			Labels:      map[string]string{capr.InitNodeLabel: "true", capr.EtcdRoleLabel: "true", "other.io/label": "x"},
+			Annotations: map[string]string{capr.JoinedToAnnotation: "stale"},
+		},
+		Data: map[string][]byte{
+			"plan":           []byte("{}"),
+			"probe-statuses": []byte("{}"),
+		},
+	}
+	metadata := &plan.Metadata{
+		Labels:      map[string]string{capr.EtcdRoleLabel: "true", capr.InitNodeLabel: "true"},
+		Annotations: map[string]string{capr.JoinedToAnnotation: "https://10.0.0.1:9345"},
+	}
+
+	applied := plannerPlanSecret(secret, metadata, []string{capr.InitNodeLabel})
+	assert.Equal(t, "fleet-default", applied.Namespace)
+	assert.Equal(t, "m1-machine-plan", applied.Name)
+	assert.Equal(t, map[string]string{capr.EtcdRoleLabel: "true"}, applied.Labels)
+	assert.Equal(t, map[string]string{capr.JoinedToAnnotation: "https://10.0.0.1:9345"}, applied.Annotations)
+	assert.Equal(t, map[string][]byte{"plan": []byte("{}")}, applied.Data)
+}
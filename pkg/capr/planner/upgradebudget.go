@@ -0,0 +1,56 @@
+package planner
+
+import (
+	"fmt"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const upgradeFailureBudgetExceededReason = "FailureBudgetExceeded"
+
+// failedMachines returns the names of the machines that count against the upgrade failure budget: machines whose plan
+// failed to apply, and machines whose plan did not converge within the machine-plan-convergence-timeout.
+func failedMachines(clusterPlan *plan.Plan) []string {
+	var failed []string
+	for _, entry := range collect(clusterPlan, roleAnd(anyRole, isNotDeleting)) {
+		if (entry.Plan != nil && entry.Plan.Failed) || conditions.IsFalse(entry.Machine, capi.ConditionType(capr.PlanConverged)) {
+			failed = append(failed, entry.Machine.Name)
+		}
+	}
+	return failed
+}
+
+// checkUpgradeFailureBudget returns true if more machines failed than the upgrade strategy of the control plane
+// tolerates, in which case no further machines are upgraded. The UpgradeFailureBudgetExceeded condition of the status
+// is updated accordingly.
+func checkUpgradeFailureBudget(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterPlan *plan.Plan) (rkev1.RKEControlPlaneStatus, bool) {
+	budget := controlPlane.Spec.UpgradeStrategy.MaxFailedNodes
+	var failed []string
+	if budget != nil {
+		failed = failedMachines(clusterPlan)
+	}
+
+	if budget == nil || len(failed) <= *budget {
+		if capr.UpgradeFailureBudgetExceeded.IsTrue(&status) {
+			logrus.Infof("[planner] rkecluster %s/%s: resuming rollout as the upgrade failure budget is no longer exceeded", controlPlane.Namespace, controlPlane.Name)
+			capr.UpgradeFailureBudgetExceeded.False(&status)
+			capr.UpgradeFailureBudgetExceeded.Message(&status, "")
+			capr.UpgradeFailureBudgetExceeded.Reason(&status, "")
+		}
+		return status, false
+	}
+
+	message := fmt.Sprintf("rollout paused as %d machine(s) failed, more than the %d tolerated: %s", len(failed), *budget, atMostThree(failed))
+	if !capr.UpgradeFailureBudgetExceeded.IsTrue(&status) {
+		logrus.Warnf("[planner] rkecluster %s/%s: %s", controlPlane.Namespace, controlPlane.Name, message)
+	}
+	capr.UpgradeFailureBudgetExceeded.True(&status)
+	capr.UpgradeFailureBudgetExceeded.Message(&status, message)
+	capr.UpgradeFailureBudgetExceeded.Reason(&status, upgradeFailureBudgetExceededReason)
+	return status, true
+}
@@ -0,0 +1,101 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+func upgradeBudgetPlan() *plan.Plan {
+	clusterPlan := &plan.Plan{
+		Machines: map[string]*capi.Machine{},
+		Nodes:    map[string]*plan.Node{},
+		Metadata: map[string]*plan.Metadata{},
+	}
+	add := func(name string, node *plan.Node, converged bool) {
+		machine := &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if converged {
+			conditions.MarkTrue(machine, capi.ConditionType(capr.PlanConverged))
+		} else {
+			conditions.MarkFalse(machine, capi.ConditionType(capr.PlanConverged), "PlanConvergenceTimeout", capi.ConditionSeverityWarning, "stuck")
+		}
+		clusterPlan.Machines[name] = machine
+		clusterPlan.Nodes[name] = node
+		clusterPlan.Metadata[name] = &plan.Metadata{Labels: map[string]string{capr.WorkerRoleLabel: "true"}}
+	}
+	add("healthy", &plan.Node{InSync: true}, true)
+	add("failed", &plan.Node{Failed: true}, true)
+	add("stuck", &plan.Node{}, false)
+	return clusterPlan
+}
+
+func TestFailedMachines(t *testing.T) {
+	assert.Equal(t, []string{"failed", "stuck"}, failedMachines(upgradeBudgetPlan()))
+}
+
+func TestCheckUpgradeFailureBudget(t *testing.T) {
+	budget := func(n int) *int {
+		return &n
+	}
+
+	tests := []struct {
+		name     string
+		budget   *int
+		exceeded bool
+	}{
+		{
+			name: "no budget",
+		},
+		{
+			name:   "within budget",
+			budget: budget(2),
+		},
+		{
+			name:     "budget exceeded",
+			budget:   budget(1),
+			exceeded: true,
+		},
+		{
+			name:     "no failures tolerated",
+			budget:   budget(0),
+			exceeded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlPlane := &rkev1.RKEControlPlane{}
+			controlPlane.Spec.UpgradeStrategy.MaxFailedNodes = tt.budget
+
+			status, exceeded := checkUpgradeFailureBudget(controlPlane, rkev1.RKEControlPlaneStatus{}, upgradeBudgetPlan())
+			assert.Equal(t, tt.exceeded, exceeded)
+			assert.Equal(t, tt.exceeded, capr.UpgradeFailureBudgetExceeded.IsTrue(&status))
+			if tt.exceeded {
+				assert.Contains(t, capr.UpgradeFailureBudgetExceeded.GetMessage(&status), "failed,stuck")
+				assert.Equal(t, upgradeFailureBudgetExceededReason, capr.UpgradeFailureBudgetExceeded.GetReason(&status))
+			}
+		})
+	}
+}
+
+func TestCheckUpgradeFailureBudgetResumes(t *testing.T) {
+	controlPlane := &rkev1.RKEControlPlane{}
+	tolerated := 1
+	controlPlane.Spec.UpgradeStrategy.MaxFailedNodes = &tolerated
+
+	status, exceeded := checkUpgradeFailureBudget(controlPlane, rkev1.RKEControlPlaneStatus{}, upgradeBudgetPlan())
+	assert.True(t, exceeded)
+
+	// raising the budget resumes the rollout
+	tolerated = 2
+	status, exceeded = checkUpgradeFailureBudget(controlPlane, status, upgradeBudgetPlan())
+	assert.False(t, exceeded)
+	assert.True(t, capr.UpgradeFailureBudgetExceeded.IsFalse(&status))
+	assert.Empty(t, capr.UpgradeFailureBudgetExceeded.GetMessage(&status))
+}
@@ -0,0 +1,127 @@
+package planner
+
+import (
+	"fmt"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const defaultUpgradeCanarySoakTime = 5 * time.Minute
+
+// holdFunc returns why the upgrade of the machine of the entry is held, or an empty string if it may be upgraded.
+type holdFunc func(entry *planEntry) string
+
+func noHold(*planEntry) string {
+	return ""
+}
+
+// holdAll holds the upgrade of all machines for the reason.
+func holdAll(reason string) holdFunc {
+	return func(*planEntry) string {
+		return reason
+	}
+}
+
+// holdAny returns the reason of the first of the holds that holds the upgrade of the machine.
+func holdAny(holds ...holdFunc) holdFunc {
+	return func(entry *planEntry) string {
+		for _, hold := range holds {
+			if reason := hold(entry); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	}
+}
+
+// startUpgradeCanary selects the canary machines once the Kubernetes version of the control plane changed. The init node
+// is the control plane canary, as it is upgraded first anyway, and the worker canary is the configured worker or the
+// first worker machine by name. Initial provisioning is not an upgrade, so no canary machines are selected for it.
+func startUpgradeCanary(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterPlan *plan.Plan) rkev1.RKEControlPlaneStatus {
+	canary := cp.Spec.UpgradeStrategy.Canary
+	if canary == nil {
+		status.UpgradeCanary = nil
+		return status
+	}
+	if status.UpgradeCanary != nil && status.UpgradeCanary.KubernetesVersion == cp.Spec.KubernetesVersion {
+		status.UpgradeCanary = status.UpgradeCanary.DeepCopy()
+		return status
+	}
+
+	canaryStatus := &rkev1.UpgradeCanaryStatus{KubernetesVersion: cp.Spec.KubernetesVersion}
+	if status.Ready && status.AppliedSpec != nil && status.AppliedSpec.KubernetesVersion != cp.Spec.KubernetesVersion {
+		if canary.ControlPlane {
+			if entries := collect(clusterPlan, roleAnd(isInitNode, isNotDeleting)); len(entries) > 0 {
+				canaryStatus.ControlPlane = &rkev1.UpgradeCanaryMachine{Name: entries[0].Machine.Name}
+			}
+		}
+		for _, entry := range collect(clusterPlan, roleAnd(isOnlyWorker, isNotDeleting)) {
+			if canary.Worker == "" || canary.Worker == entry.Machine.Name {
+				canaryStatus.Worker = &rkev1.UpgradeCanaryMachine{Name: entry.Machine.Name}
+				break
+			}
+		}
+		if canary.Worker != "" && canaryStatus.Worker == nil {
+			logrus.Warnf("[planner] rkecluster %s/%s: canary worker machine %s not found, upgrading workers without a canary", cp.Namespace, cp.Name, canary.Worker)
+		}
+		logrus.Infof("[planner] rkecluster %s/%s: upgrading canary machines to %s first", cp.Namespace, cp.Name, cp.Spec.KubernetesVersion)
+	}
+	status.UpgradeCanary = canaryStatus
+	return status
+}
+
+// soakUpgradeCanary updates the soak of the canary machine of a tier. The soak starts once the plan of the canary
+// machine is in sync, its probes pass and its kubelet runs the Kubernetes version of its control plane, and restarts if
+// any of them no longer holds before the soak time passed. The upgrade of the other machines of the tier is held until
+// the canary machine soaked, and the remaining soak time is returned so that the control plane is processed again then.
+func soakUpgradeCanary(cp *rkev1.RKEControlPlane, canary *rkev1.UpgradeCanaryMachine, clusterPlan *plan.Plan, controlPlanes controlPlaneFunc, now time.Time) (holdFunc, time.Duration) {
+	if canary == nil || canary.Completed {
+		return noHold, 0
+	}
+
+	machine := clusterPlan.Machines[canary.Name]
+	if machine == nil || machine.DeletionTimestamp != nil {
+		logrus.Warnf("[planner] rkecluster %s/%s: canary machine %s was removed, continuing the upgrade", cp.Namespace, cp.Name, canary.Name)
+		canary.Completed = true
+		return noHold, 0
+	}
+	entry := &planEntry{
+		Machine:  machine,
+		Plan:     clusterPlan.Nodes[canary.Name],
+		Metadata: clusterPlan.Metadata[canary.Name],
+	}
+
+	if entry.Plan == nil || !entry.Plan.InSync || !entry.Plan.Healthy || entry.Plan.Failed || !kubeletVersionUpToDate(controlPlanes(entry), machine) {
+		canary.SoakStartTime = nil
+		return holdForCanary(canary.Name, fmt.Sprintf("waiting for canary machine %s to be upgraded", canary.Name)), 0
+	}
+
+	if canary.SoakStartTime == nil {
+		canary.SoakStartTime = &metav1.Time{Time: now}
+	}
+	soakTime := defaultUpgradeCanarySoakTime
+	if cp.Spec.UpgradeStrategy.Canary != nil && cp.Spec.UpgradeStrategy.Canary.SoakTime != nil {
+		soakTime = cp.Spec.UpgradeStrategy.Canary.SoakTime.Duration
+	}
+	if remaining := canary.SoakStartTime.Add(soakTime).Sub(now); remaining > 0 {
+		return holdForCanary(canary.Name, fmt.Sprintf("waiting for canary machine %s to soak", canary.Name)), remaining
+	}
+
+	logrus.Infof("[planner] rkecluster %s/%s: canary machine %s soaked, continuing the upgrade", cp.Namespace, cp.Name, canary.Name)
+	canary.Completed = true
+	return noHold, 0
+}
+
+// holdForCanary holds the upgrade of all machines but the canary machine for the reason.
+func holdForCanary(canaryName, reason string) holdFunc {
+	return func(entry *planEntry) string {
+		if entry.Machine.Name == canaryName {
+			return ""
+		}
+		return reason
+	}
+}
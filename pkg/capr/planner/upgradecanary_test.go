@@ -0,0 +1,213 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func upgradeCanaryPlan(kubeletVersion string, node *plan.Node) *plan.Plan {
+	clusterPlan := &plan.Plan{
+		Machines: map[string]*capi.Machine{},
+		Nodes:    map[string]*plan.Node{},
+		Metadata: map[string]*plan.Metadata{},
+	}
+	add := func(name string, labels map[string]string) {
+		clusterPlan.Machines[name] = &capi.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: capi.MachineStatus{
+				NodeInfo: &corev1.NodeSystemInfo{KubeletVersion: kubeletVersion},
+			},
+		}
+		clusterPlan.Nodes[name] = node
+		clusterPlan.Metadata[name] = &plan.Metadata{Labels: labels}
+	}
+	add("server-a", map[string]string{capr.EtcdRoleLabel: "true", capr.ControlPlaneRoleLabel: "true", capr.InitNodeLabel: "true"})
+	add("server-b", map[string]string{capr.EtcdRoleLabel: "true", capr.ControlPlaneRoleLabel: "true"})
+	add("worker-b", map[string]string{capr.WorkerRoleLabel: "true"})
+	add("worker-a", map[string]string{capr.WorkerRoleLabel: "true"})
+	return clusterPlan
+}
+
+func TestStartUpgradeCanary(t *testing.T) {
+	upgraded := rkev1.RKEControlPlaneStatus{
+		Ready:       true,
+		AppliedSpec: &rkev1.RKEControlPlaneSpec{KubernetesVersion: "v1.26.8+rke2r1"},
+	}
+
+	tests := []struct {
+		name     string
+		canary   *rkev1.UpgradeCanary
+		status   rkev1.RKEControlPlaneStatus
+		expected *rkev1.UpgradeCanaryStatus
+	}{
+		{
+			name:   "no canary",
+			status: rkev1.RKEControlPlaneStatus{UpgradeCanary: &rkev1.UpgradeCanaryStatus{KubernetesVersion: "v1.26.8+rke2r1"}},
+		},
+		{
+			name:     "initial provisioning",
+			canary:   &rkev1.UpgradeCanary{ControlPlane: true},
+			expected: &rkev1.UpgradeCanaryStatus{KubernetesVersion: "v1.27.5+rke2r1"},
+		},
+		{
+			name:   "upgrade",
+			canary: &rkev1.UpgradeCanary{ControlPlane: true},
+			status: upgraded,
+			expected: &rkev1.UpgradeCanaryStatus{
+				KubernetesVersion: "v1.27.5+rke2r1",
+				ControlPlane:      &rkev1.UpgradeCanaryMachine{Name: "server-a"},
+				Worker:            &rkev1.UpgradeCanaryMachine{Name: "worker-a"},
+			},
+		},
+		{
+			name:   "upgrade with configured worker",
+			canary: &rkev1.UpgradeCanary{Worker: "worker-b"},
+			status: upgraded,
+			expected: &rkev1.UpgradeCanaryStatus{
+				KubernetesVersion: "v1.27.5+rke2r1",
+				Worker:            &rkev1.UpgradeCanaryMachine{Name: "worker-b"},
+			},
+		},
+		{
+			name:     "upgrade with missing worker",
+			canary:   &rkev1.UpgradeCanary{Worker: "worker-c"},
+			status:   upgraded,
+			expected: &rkev1.UpgradeCanaryStatus{KubernetesVersion: "v1.27.5+rke2r1"},
+		},
+		{
+			name:   "upgrade in progress",
+			canary: &rkev1.UpgradeCanary{Worker: "worker-b"},
+			status: rkev1.RKEControlPlaneStatus{
+				Ready:       true,
+				AppliedSpec: upgraded.AppliedSpec,
+				UpgradeCanary: &rkev1.UpgradeCanaryStatus{
+					KubernetesVersion: "v1.27.5+rke2r1",
+					Worker:            &rkev1.UpgradeCanaryMachine{Name: "worker-a", Completed: true},
+				},
+			},
+			expected: &rkev1.UpgradeCanaryStatus{
+				KubernetesVersion: "v1.27.5+rke2r1",
+				Worker:            &rkev1.UpgradeCanaryMachine{Name: "worker-a", Completed: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &rkev1.RKEControlPlane{
+				Spec: rkev1.RKEControlPlaneSpec{
+					KubernetesVersion:    "v1.27.5+rke2r1",
+					RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{UpgradeStrategy: rkev1.ClusterUpgradeStrategy{Canary: tt.canary}},
+				},
+			}
+			status := startUpgradeCanary(cp, tt.status, upgradeCanaryPlan("v1.26.8+rke2r1", &plan.Node{InSync: true}))
+			assert.Equal(t, tt.expected, status.UpgradeCanary)
+		})
+	}
+}
+
+func TestSoakUpgradeCanary(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	started := func(ago time.Duration) *metav1.Time {
+		return &metav1.Time{Time: now.Add(-ago)}
+	}
+
+	tests := []struct {
+		name           string
+		canary         *rkev1.UpgradeCanaryMachine
+		kubeletVersion string
+		node           *plan.Node
+		expected       *rkev1.UpgradeCanaryMachine
+		expectedHold   string
+		expectedSoak   time.Duration
+	}{
+		{
+			name: "no canary",
+		},
+		{
+			name:     "completed",
+			canary:   &rkev1.UpgradeCanaryMachine{Name: "worker-a", Completed: true},
+			expected: &rkev1.UpgradeCanaryMachine{Name: "worker-a", Completed: true},
+		},
+		{
+			name:     "removed",
+			canary:   &rkev1.UpgradeCanaryMachine{Name: "worker-c"},
+			expected: &rkev1.UpgradeCanaryMachine{Name: "worker-c", Completed: true},
+		},
+		{
+			name:           "not upgraded",
+			canary:         &rkev1.UpgradeCanaryMachine{Name: "worker-a"},
+			kubeletVersion: "v1.26.8+rke2r1",
+			node:           &plan.Node{InSync: true, Healthy: true},
+			expected:       &rkev1.UpgradeCanaryMachine{Name: "worker-a"},
+			expectedHold:   "waiting for canary machine worker-a to be upgraded",
+		},
+		{
+			name:           "probes failing",
+			canary:         &rkev1.UpgradeCanaryMachine{Name: "worker-a", SoakStartTime: started(time.Minute)},
+			kubeletVersion: "v1.27.5+rke2r1",
+			node:           &plan.Node{InSync: true},
+			expected:       &rkev1.UpgradeCanaryMachine{Name: "worker-a"},
+			expectedHold:   "waiting for canary machine worker-a to be upgraded",
+		},
+		{
+			name:           "soak started",
+			canary:         &rkev1.UpgradeCanaryMachine{Name: "worker-a"},
+			kubeletVersion: "v1.27.5+rke2r1",
+			node:           &plan.Node{InSync: true, Healthy: true},
+			expected:       &rkev1.UpgradeCanaryMachine{Name: "worker-a", SoakStartTime: started(0)},
+			expectedHold:   "waiting for canary machine worker-a to soak",
+			expectedSoak:   10 * time.Minute,
+		},
+		{
+			name:           "soaking",
+			canary:         &rkev1.UpgradeCanaryMachine{Name: "worker-a", SoakStartTime: started(4 * time.Minute)},
+			kubeletVersion: "v1.27.5+rke2r1",
+			node:           &plan.Node{InSync: true, Healthy: true},
+			expected:       &rkev1.UpgradeCanaryMachine{Name: "worker-a", SoakStartTime: started(4 * time.Minute)},
+			expectedHold:   "waiting for canary machine worker-a to soak",
+			expectedSoak:   6 * time.Minute,
+		},
+		{
+			name:           "soaked",
+			canary:         &rkev1.UpgradeCanaryMachine{Name: "worker-a", SoakStartTime: started(10 * time.Minute)},
+			kubeletVersion: "v1.27.5+rke2r1",
+			node:           &plan.Node{InSync: true, Healthy: true},
+			expected:       &rkev1.UpgradeCanaryMachine{Name: "worker-a", SoakStartTime: started(10 * time.Minute), Completed: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &rkev1.RKEControlPlane{
+				Spec: rkev1.RKEControlPlaneSpec{
+					KubernetesVersion: "v1.27.5+rke2r1",
+					RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{UpgradeStrategy: rkev1.ClusterUpgradeStrategy{
+						Canary: &rkev1.UpgradeCanary{SoakTime: &metav1.Duration{Duration: 10 * time.Minute}},
+					}},
+				},
+				Status: rkev1.RKEControlPlaneStatus{AgentConnected: true},
+			}
+			clusterPlan := upgradeCanaryPlan(tt.kubeletVersion, tt.node)
+			hold, soak := soakUpgradeCanary(cp, tt.canary, clusterPlan, tierControlPlane(cp), now)
+			assert.Equal(t, tt.expected, tt.canary)
+			assert.Equal(t, tt.expectedSoak, soak)
+			assert.Equal(t, tt.expectedHold, hold(&planEntry{Machine: clusterPlan.Machines["worker-b"]}))
+			assert.Empty(t, hold(&planEntry{Machine: clusterPlan.Machines["worker-a"]}))
+		})
+	}
+}
+
+func TestHoldAny(t *testing.T) {
+	entry := &planEntry{Machine: &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-a"}}}
+
+	assert.Empty(t, holdAny()(entry))
+	assert.Empty(t, holdAny(noHold, holdForCanary("worker-a", "canary"))(entry))
+	assert.Equal(t, "budget", holdAny(noHold, holdAll("budget"), holdAll("canary"))(entry))
+}
@@ -0,0 +1,127 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	prewarmInstructionName       = "prewarm"
+	defaultUpgradePrewarmTimeout = 10 * time.Minute
+	rke2RuntimeImage             = "rancher/rke2-runtime"
+)
+
+// startUpgradePrewarm starts pulling the images once the Kubernetes version of the control plane changed. Like for the
+// canary machines, initial provisioning is not an upgrade, so nothing is pulled ahead of it.
+func startUpgradePrewarm(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, now time.Time) rkev1.RKEControlPlaneStatus {
+	if cp.Spec.UpgradeStrategy.Prewarm == nil {
+		status.UpgradePrewarm = nil
+		return status
+	}
+	if status.UpgradePrewarm != nil && status.UpgradePrewarm.KubernetesVersion == cp.Spec.KubernetesVersion {
+		status.UpgradePrewarm = status.UpgradePrewarm.DeepCopy()
+		return status
+	}
+
+	prewarmStatus := &rkev1.UpgradePrewarmStatus{KubernetesVersion: cp.Spec.KubernetesVersion, Completed: true}
+	if status.Ready && status.AppliedSpec != nil && status.AppliedSpec.KubernetesVersion != cp.Spec.KubernetesVersion {
+		logrus.Infof("[planner] rkecluster %s/%s: pulling the images of %s on all machines before upgrading", cp.Namespace, cp.Name, cp.Spec.KubernetesVersion)
+		prewarmStatus.StartTime = &metav1.Time{Time: now}
+		prewarmStatus.Completed = false
+	}
+	status.UpgradePrewarm = prewarmStatus
+	return status
+}
+
+// prewarmUpgrade delivers the prewarm plan to all linux machines that are not running the Kubernetes version of their
+// tier yet, and returns an errWaiting until all of them applied it or the timeout passed. The machines keep running while
+// the images are pulled, and receive their upgraded plans once the upgrade is rolled out, just like after a node command.
+// Pulling the images is best effort: machines whose prewarm plan failed are upgraded as usual.
+func (p *Planner) prewarmUpgrade(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, tokensSecret plan.Secret, clusterPlan *plan.Plan, joinServer string, now time.Time) (rkev1.RKEControlPlaneStatus, error) {
+	prewarm := status.UpgradePrewarm
+	if prewarm == nil || prewarm.Completed {
+		return status, nil
+	}
+
+	timeout := defaultUpgradePrewarmTimeout
+	if cp.Spec.UpgradeStrategy.Prewarm.Timeout != nil {
+		timeout = cp.Spec.UpgradeStrategy.Prewarm.Timeout.Duration
+	}
+	remaining := prewarm.StartTime.Add(timeout).Sub(now)
+	if remaining <= 0 {
+		logrus.Warnf("[planner] rkecluster %s/%s: timed out pulling the images of %s, continuing the upgrade", cp.Namespace, cp.Name, prewarm.KubernetesVersion)
+		prewarm.Completed = true
+		return status, nil
+	}
+
+	workerControlPlanes, err := p.workerControlPlanes(cp)
+	if err != nil {
+		return status, err
+	}
+
+	var pending []string
+	for _, entry := range collect(clusterPlan, roleAnd(anyRoleWithoutWindows, isNotDeleting)) {
+		controlPlane := cp
+		if isOnlyWorker(entry) {
+			controlPlane = workerControlPlanes(entry)
+		}
+		if entry.Plan == nil || kubeletVersionUpToDate(controlPlane, entry.Machine) {
+			continue
+		}
+		prewarmPlan, joinedServer, err := p.generatePrewarmPlan(controlPlane, tokensSecret, entry, joinServer)
+		if err != nil {
+			return status, err
+		}
+		err = assignAndCheckPlan(p.store, fmt.Sprintf("pulling images on machine %s", entry.Machine.Name), entry, prewarmPlan, joinedServer, 1, 1)
+		if IsErrWaiting(err) {
+			pending = append(pending, entry.Machine.Name)
+		} else if err != nil {
+			logrus.Warnf("[planner] rkecluster %s/%s: failed to pull the images of %s on machine %s: %v", cp.Namespace, cp.Name, prewarm.KubernetesVersion, entry.Machine.Name, err)
+		}
+	}
+	if len(pending) > 0 {
+		p.rkeControlPlanes.EnqueueAfter(cp.Namespace, cp.Name, remaining)
+		return status, errWaitingf("pulling the images of %s on machines %s", prewarm.KubernetesVersion, atMostThree(pending))
+	}
+
+	logrus.Infof("[planner] rkecluster %s/%s: pulled the images of %s, continuing the upgrade", cp.Namespace, cp.Name, prewarm.KubernetesVersion)
+	prewarm.Completed = true
+	return status, nil
+}
+
+// generatePrewarmPlan generates a plan that pulls the images of the Kubernetes version of the control plane on the
+// machine. Unlike the other plans that run an instruction on a running machine, it does not install the Kubernetes
+// version, as the binaries would otherwise be replaced before the machine is upgraded.
+func (p *Planner) generatePrewarmPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret plan.Secret, entry *planEntry, joinServer string) (plan.NodePlan, string, error) {
+	prewarmPlan, _, joinedServer, err := p.generatePlanWithConfigFiles(controlPlane, tokensSecret, entry, joinServer)
+	if err != nil {
+		return prewarmPlan, joinedServer, err
+	}
+	prewarmPlan.Instructions = append(prewarmPlan.Instructions, p.prewarmInstruction(controlPlane))
+	return prewarmPlan, joinedServer, nil
+}
+
+// prewarmInstruction returns the instruction that pulls the images of the Kubernetes version of the control plane. The
+// installer image is pulled by the system-agent to run the instruction. For RKE2, the runtime image is pulled into the
+// containerd of the machine as well, as RKE2 pulls it when it starts.
+func (p *Planner) prewarmInstruction(controlPlane *rkev1.RKEControlPlane) plan.OneTimeInstruction {
+	command := "true"
+	if runtime := capr.GetRuntime(controlPlane.Spec.KubernetesVersion); runtime == capr.RuntimeRKE2 {
+		runtimeImage := p.retrievalFunctions.ImageResolver(rke2RuntimeImage+":"+strings.ReplaceAll(controlPlane.Spec.KubernetesVersion, "+", "-"), controlPlane)
+		crictl := fmt.Sprintf("/var/lib/rancher/%s/bin/crictl", runtime)
+		command = fmt.Sprintf("if [ -x %s ]; then %s --runtime-endpoint unix:///run/k3s/containerd/containerd.sock pull %s; fi", crictl, crictl, runtimeImage)
+	}
+	return plan.OneTimeInstruction{
+		Name:    prewarmInstructionName,
+		Image:   p.getInstallerImage(controlPlane),
+		Command: "sh",
+		Args:    []string{"-c", command},
+	}
+}
@@ -0,0 +1,124 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/provisioningv2/image"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStartUpgradePrewarm(t *testing.T) {
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	upgraded := rkev1.RKEControlPlaneStatus{
+		Ready:       true,
+		AppliedSpec: &rkev1.RKEControlPlaneSpec{KubernetesVersion: "v1.26.8+rke2r1"},
+	}
+
+	tests := []struct {
+		name     string
+		prewarm  *rkev1.UpgradePrewarm
+		status   rkev1.RKEControlPlaneStatus
+		expected *rkev1.UpgradePrewarmStatus
+	}{
+		{
+			name:   "no prewarm",
+			status: rkev1.RKEControlPlaneStatus{UpgradePrewarm: &rkev1.UpgradePrewarmStatus{KubernetesVersion: "v1.26.8+rke2r1"}},
+		},
+		{
+			name:     "initial provisioning",
+			prewarm:  &rkev1.UpgradePrewarm{},
+			expected: &rkev1.UpgradePrewarmStatus{KubernetesVersion: "v1.27.5+rke2r1", Completed: true},
+		},
+		{
+			name:     "upgrade",
+			prewarm:  &rkev1.UpgradePrewarm{},
+			status:   upgraded,
+			expected: &rkev1.UpgradePrewarmStatus{KubernetesVersion: "v1.27.5+rke2r1", StartTime: &metav1.Time{Time: now}},
+		},
+		{
+			name:    "upgrade in progress",
+			prewarm: &rkev1.UpgradePrewarm{},
+			status: rkev1.RKEControlPlaneStatus{
+				Ready:          true,
+				AppliedSpec:    upgraded.AppliedSpec,
+				UpgradePrewarm: &rkev1.UpgradePrewarmStatus{KubernetesVersion: "v1.27.5+rke2r1", StartTime: &metav1.Time{Time: now.Add(-time.Minute)}, Completed: true},
+			},
+			expected: &rkev1.UpgradePrewarmStatus{KubernetesVersion: "v1.27.5+rke2r1", StartTime: &metav1.Time{Time: now.Add(-time.Minute)}, Completed: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &rkev1.RKEControlPlane{
+				Spec: rkev1.RKEControlPlaneSpec{
+					RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{UpgradeStrategy: rkev1.ClusterUpgradeStrategy{Prewarm: tt.prewarm}},
+					KubernetesVersion:    "v1.27.5+rke2r1",
+				},
+			}
+			assert.Equal(t, tt.expected, startUpgradePrewarm(cp, tt.status, now).UpgradePrewarm)
+		})
+	}
+}
+
+func TestPrewarmInstruction(t *testing.T) {
+	planner := &Planner{}
+	planner.retrievalFunctions.SystemAgentImage = func() string { return "rancher/system-agent-installer-" }
+	planner.retrievalFunctions.ImageResolver = image.ResolveWithControlPlane
+
+	tests := []struct {
+		name     string
+		version  string
+		registry string
+		expected plan.OneTimeInstruction
+	}{
+		{
+			name:    "rke2",
+			version: "v1.27.5+rke2r1",
+			expected: plan.OneTimeInstruction{
+				Name:    prewarmInstructionName,
+				Image:   "rancher/system-agent-installer-rke2:v1.27.5-rke2r1",
+				Command: "sh",
+				Args: []string{"-c", "if [ -x /var/lib/rancher/rke2/bin/crictl ]; then /var/lib/rancher/rke2/bin/crictl --runtime-endpoint " +
+					"unix:///run/k3s/containerd/containerd.sock pull rancher/rke2-runtime:v1.27.5-rke2r1; fi"},
+			},
+		},
+		{
+			name:     "rke2 with system default registry",
+			version:  "v1.27.5+rke2r1",
+			registry: "registry.example.com",
+			expected: plan.OneTimeInstruction{
+				Name:    prewarmInstructionName,
+				Image:   "registry.example.com/rancher/system-agent-installer-rke2:v1.27.5-rke2r1",
+				Command: "sh",
+				Args: []string{"-c", "if [ -x /var/lib/rancher/rke2/bin/crictl ]; then /var/lib/rancher/rke2/bin/crictl --runtime-endpoint " +
+					"unix:///run/k3s/containerd/containerd.sock pull registry.example.com/rancher/rke2-runtime:v1.27.5-rke2r1; fi"},
+			},
+		},
+		{
+			name:    "k3s",
+			version: "v1.27.5+k3s1",
+			expected: plan.OneTimeInstruction{
+				Name:    prewarmInstructionName,
+				Image:   "rancher/system-agent-installer-k3s:v1.27.5-k3s1",
+				Command: "sh",
+				Args:    []string{"-c", "true"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &rkev1.RKEControlPlane{
+				Spec: rkev1.RKEControlPlaneSpec{
+					RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{SystemDefaultRegistry: tt.registry},
+					KubernetesVersion:    tt.version,
+				},
+			}
+			assert.Equal(t, tt.expected, planner.prewarmInstruction(cp))
+		})
+	}
+}
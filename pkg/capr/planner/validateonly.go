@@ -0,0 +1,160 @@
+package planner
+
+import (
+	"encoding/json"
+	"fmt"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// renderedPlansSecretName returns the name of the secret that the plans of the machine pools of a validate-only cluster
+// are rendered to.
+func renderedPlansSecretName(controlPlane *rkev1.RKEControlPlane) string {
+	return name.SafeConcatName(controlPlane.Name, "rendered", "plans")
+}
+
+// renderPlans renders the plans that the first machine of each machine pool of a validate-only cluster would receive,
+// by the name of the pool, into the rendered plans secret of the cluster, and reports the outcome in the PlansRendered
+// condition. The first etcd pool is rendered as the init node, and the plans of the other pools are rendered without
+// a join server, as the addresses of the machines are not known before they exist. The machine specific bootstrap
+// secrets are not rendered.
+func (p *Planner) renderPlans(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
+	cluster, err := p.rancherClusterCache.Get(cp.Namespace, cp.Spec.ClusterName)
+	if err != nil {
+		return status, err
+	}
+	if cluster.Spec.RKEConfig == nil {
+		return status, errWaiting("cluster is validate-only: no machines are created")
+	}
+
+	_, tokens, err := p.ensureRKEStateSecret(cp)
+	if err != nil {
+		return status, err
+	}
+	controlPlanes, err := p.workerControlPlanes(cp)
+	if err != nil {
+		return status, err
+	}
+
+	data := map[string][]byte{}
+	initNode := false
+	for _, pool := range cluster.Spec.RKEConfig.MachinePools {
+		entry := poolPlanEntry(cp, pool, pool.EtcdRole && !initNode)
+		initNode = initNode || pool.EtcdRole
+
+		nodePlan, _, err := p.desiredPlan(controlPlanes(entry), tokens, entry, "")
+		if err != nil {
+			capr.PlansRendered.False(&status)
+			capr.PlansRendered.Reason(&status, "RenderFailed")
+			capr.PlansRendered.Message(&status, fmt.Sprintf("failed to render plan of machine pool %s: %v", pool.Name, err))
+			return status, errWaitingf("cluster is validate-only: failed to render plan of machine pool %s: %v", pool.Name, err)
+		}
+		content, err := json.MarshalIndent(nodePlan, "", "  ")
+		if err != nil {
+			return status, err
+		}
+		data[pool.Name] = content
+	}
+
+	if err := p.storeRenderedPlans(cp, data); err != nil {
+		return status, err
+	}
+	capr.PlansRendered.True(&status)
+	capr.PlansRendered.Reason(&status, "")
+	capr.PlansRendered.Message(&status, fmt.Sprintf("rendered plans of %d machine pools to secret %s/%s", len(data), cp.Namespace, renderedPlansSecretName(cp)))
+	return status, errWaiting("cluster is validate-only: no machines are created")
+}
+
+// poolPlanEntry returns a plan entry for a machine of the machine pool, with the labels, annotations and roles that the
+// machines of the pool are created with.
+func poolPlanEntry(cp *rkev1.RKEControlPlane, pool rancherv1.RKEMachinePool, initNode bool) *planEntry {
+	os := pool.MachineOS
+	if os == "" {
+		os = capr.DefaultMachineOS
+	}
+	labels := map[string]string{
+		capr.ClusterNameLabel:        cp.Spec.ClusterName,
+		capr.RKEMachinePoolNameLabel: pool.Name,
+		capr.CattleOSLabel:           os,
+	}
+	for k, v := range pool.Labels {
+		labels[k] = v
+	}
+	if pool.EtcdRole {
+		labels[capr.EtcdRoleLabel] = "true"
+	}
+	if pool.ControlPlaneRole {
+		labels[capr.ControlPlaneRoleLabel] = "true"
+	}
+	if pool.WorkerRole {
+		labels[capr.WorkerRoleLabel] = "true"
+	}
+	if initNode {
+		labels[capr.InitNodeLabel] = "true"
+	}
+
+	annotations := map[string]string{}
+	if len(pool.Labels) > 0 {
+		data, _ := json.Marshal(pool.Labels)
+		annotations[capr.LabelsAnnotation] = string(data)
+	}
+	if len(pool.Taints) > 0 {
+		data, _ := json.Marshal(pool.Taints)
+		annotations[capr.TaintsAnnotation] = string(data)
+	}
+
+	return &planEntry{
+		Machine: &capi.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name.SafeConcatName(cp.Spec.ClusterName, pool.Name),
+				Namespace: cp.Namespace,
+				Labels:    labels,
+			},
+			Spec: capi.MachineSpec{
+				ClusterName: cp.Spec.ClusterName,
+			},
+		},
+		Metadata: &plan.Metadata{
+			Labels:      labels,
+			Annotations: annotations,
+		},
+	}
+}
+
+// storeRenderedPlans creates or updates the rendered plans secret of the control plane with the rendered plans.
+func (p *Planner) storeRenderedPlans(cp *rkev1.RKEControlPlane, data map[string][]byte) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      renderedPlansSecretName(cp),
+			Namespace: cp.Namespace,
+			Labels: map[string]string{
+				capr.ClusterNameLabel: cp.Spec.ClusterName,
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: capr.RKEAPIVersion,
+				Kind:       "RKEControlPlane",
+				Name:       cp.Name,
+				UID:        cp.UID,
+			}},
+		},
+		Type: capr.SecretTypeRenderedPlans,
+		Data: data,
+	}
+	existing, err := p.secretCache.Get(secret.Namespace, secret.Name)
+	if apierrors.IsNotFound(err) {
+		_, err = p.secretClient.Create(secret)
+	} else if err == nil {
+		existing = existing.DeepCopy()
+		existing.Data = secret.Data
+		_, err = p.secretClient.Update(existing)
+	}
+	return err
+}
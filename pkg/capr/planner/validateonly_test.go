@@ -0,0 +1,44 @@
+package planner
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPoolPlanEntry(t *testing.T) {
+	cp := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "fleet-default"},
+		Spec:       rkev1.RKEControlPlaneSpec{ClusterName: "cluster"},
+	}
+
+	entry := poolPlanEntry(cp, rancherv1.RKEMachinePool{
+		Name:             "servers",
+		EtcdRole:         true,
+		ControlPlaneRole: true,
+		RKECommonNodeConfig: rkev1.RKECommonNodeConfig{
+			Labels: map[string]string{"zone": "a"},
+			Taints: []corev1.Taint{{Key: "dedicated", Value: "servers", Effect: corev1.TaintEffectNoSchedule}},
+		},
+	}, true)
+	assert.Equal(t, "servers", entry.Machine.Labels[capr.RKEMachinePoolNameLabel])
+	assert.Equal(t, capr.DefaultMachineOS, entry.Metadata.Labels[capr.CattleOSLabel])
+	assert.Equal(t, "a", entry.Metadata.Labels["zone"])
+	assert.True(t, isEtcd(entry))
+	assert.True(t, isControlPlane(entry))
+	assert.False(t, isWorker(entry))
+	assert.True(t, isInitNode(entry))
+	assert.Equal(t, `{"zone":"a"}`, entry.Metadata.Annotations[capr.LabelsAnnotation])
+	assert.Equal(t, `[{"key":"dedicated","value":"servers","effect":"NoSchedule"}]`, entry.Metadata.Annotations[capr.TaintsAnnotation])
+
+	entry = poolPlanEntry(cp, rancherv1.RKEMachinePool{Name: "workers", WorkerRole: true, MachineOS: "windows"}, false)
+	assert.Equal(t, "windows", entry.Metadata.Labels[capr.CattleOSLabel])
+	assert.True(t, isOnlyWorker(entry))
+	assert.False(t, isInitNode(entry))
+	assert.Empty(t, entry.Metadata.Annotations)
+}
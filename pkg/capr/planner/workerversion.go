@@ -0,0 +1,84 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// controlPlaneFunc returns the control plane that the plan of the machine of the entry is generated from.
+type controlPlaneFunc func(entry *planEntry) *rkev1.RKEControlPlane
+
+func tierControlPlane(controlPlane *rkev1.RKEControlPlane) controlPlaneFunc {
+	return func(*planEntry) *rkev1.RKEControlPlane {
+		return controlPlane
+	}
+}
+
+// workerControlPlanes returns the control planes that the plans of worker machines are generated from. The plans of
+// worker machines of a machine pool that sets its own Kubernetes version are generated from a copy of the control plane
+// with that version, so that workers can be upgraded pool by pool after the control plane was upgraded. The versions of
+// the pools are validated against the version skew policy of Kubernetes.
+func (p *Planner) workerControlPlanes(cp *rkev1.RKEControlPlane) (controlPlaneFunc, error) {
+	poolControlPlanes := map[string]*rkev1.RKEControlPlane{}
+	cluster, err := p.rancherClusterCache.Get(cp.Namespace, cp.Spec.ClusterName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	} else if err == nil && cluster.Spec.RKEConfig != nil {
+		for _, pool := range cluster.Spec.RKEConfig.MachinePools {
+			if pool.KubernetesVersion == "" || pool.KubernetesVersion == cp.Spec.KubernetesVersion || pool.EtcdRole || pool.ControlPlaneRole {
+				continue
+			}
+			if err := checkWorkerVersionSkew(cp.Spec.KubernetesVersion, pool.KubernetesVersion); err != nil {
+				return nil, fmt.Errorf("invalid kubernetes version of machine pool %s: %w", pool.Name, err)
+			}
+			poolControlPlane := cp.DeepCopy()
+			poolControlPlane.Spec.KubernetesVersion = pool.KubernetesVersion
+			poolControlPlanes[pool.Name] = poolControlPlane
+		}
+	}
+
+	return func(entry *planEntry) *rkev1.RKEControlPlane {
+		if poolControlPlane, ok := poolControlPlanes[entry.Machine.Labels[capr.RKEMachinePoolNameLabel]]; ok && isOnlyWorker(entry) {
+			return poolControlPlane
+		}
+		return cp
+	}, nil
+}
+
+// checkWorkerVersionSkew returns an error if kubelets of the worker version can not be used with a control plane of the
+// control plane version. Kubelets must be of the same distribution, must not be newer than the control plane, and may
+// be up to three minor versions older since Kubernetes 1.28, and up to two before.
+func checkWorkerVersionSkew(controlPlaneVersion, workerVersion string) error {
+	if capr.GetRuntime(controlPlaneVersion) != capr.GetRuntime(workerVersion) {
+		return fmt.Errorf("version %s is not of the same distribution as the cluster version %s", workerVersion, controlPlaneVersion)
+	}
+	cpVersion, err := semver.NewVersion(strings.TrimPrefix(controlPlaneVersion, "v"))
+	if err != nil {
+		return fmt.Errorf("error parsing cluster version %s: %w", controlPlaneVersion, err)
+	}
+	version, err := semver.NewVersion(strings.TrimPrefix(workerVersion, "v"))
+	if err != nil {
+		return fmt.Errorf("error parsing version %s: %w", workerVersion, err)
+	}
+
+	// compare without build metadata, which carries the release of the distribution
+	cpRelease, _ := cpVersion.SetMetadata("")
+	release, _ := version.SetMetadata("")
+	if release.GreaterThan(&cpRelease) {
+		return fmt.Errorf("version %s is newer than the cluster version %s", workerVersion, controlPlaneVersion)
+	}
+
+	maxSkew := uint64(2)
+	if cpVersion.Minor() >= 28 {
+		maxSkew = 3
+	}
+	if version.Major() != cpVersion.Major() || cpVersion.Minor()-version.Minor() > maxSkew {
+		return fmt.Errorf("version %s is more than %d minor versions older than the cluster version %s", workerVersion, maxSkew, controlPlaneVersion)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package planner
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckWorkerVersionSkew(t *testing.T) {
+	tests := []struct {
+		name         string
+		controlPlane string
+		worker       string
+		wantErr      bool
+	}{
+		{
+			name:         "older patch",
+			controlPlane: "v1.27.6+rke2r1",
+			worker:       "v1.27.4+rke2r1",
+		},
+		{
+			name:         "older distribution release",
+			controlPlane: "v1.27.6+rke2r2",
+			worker:       "v1.27.6+rke2r1",
+		},
+		{
+			name:         "two minors older",
+			controlPlane: "v1.27.6+rke2r1",
+			worker:       "v1.25.9+rke2r1",
+		},
+		{
+			name:         "three minors older before 1.28",
+			controlPlane: "v1.27.6+rke2r1",
+			worker:       "v1.24.9+rke2r1",
+			wantErr:      true,
+		},
+		{
+			name:         "three minors older since 1.28",
+			controlPlane: "v1.28.2+k3s1",
+			worker:       "v1.25.9+k3s1",
+		},
+		{
+			name:         "newer",
+			controlPlane: "v1.27.6+rke2r1",
+			worker:       "v1.28.2+rke2r1",
+			wantErr:      true,
+		},
+		{
+			name:         "other distribution",
+			controlPlane: "v1.27.6+rke2r1",
+			worker:       "v1.27.6+k3s1",
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkWorkerVersionSkew(tt.controlPlane, tt.worker)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWorkerControlPlanes(t *testing.T) {
+	cp := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "fleet-default"},
+		Spec:       rkev1.RKEControlPlaneSpec{ClusterName: "cluster", KubernetesVersion: "v1.27.6+rke2r1"},
+	}
+	cluster := &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "fleet-default"},
+		Spec: rancherv1.ClusterSpec{RKEConfig: &rancherv1.RKEConfig{MachinePools: []rancherv1.RKEMachinePool{
+			{Name: "workers", WorkerRole: true, KubernetesVersion: "v1.26.9+rke2r1"},
+			{Name: "servers", ControlPlaneRole: true, WorkerRole: true, KubernetesVersion: "v1.26.9+rke2r1"},
+		}}},
+	}
+	p := &Planner{rancherClusterCache: &fakeRancherClusterCache{cluster: cluster}}
+
+	controlPlanes, err := p.workerControlPlanes(cp)
+	require.NoError(t, err)
+
+	worker := poolEntry("workers")
+	worker.Metadata = &plan.Metadata{Labels: map[string]string{capr.WorkerRoleLabel: "true"}}
+	assert.Equal(t, "v1.26.9+rke2r1", controlPlanes(worker).Spec.KubernetesVersion)
+	assert.Equal(t, "v1.27.6+rke2r1", cp.Spec.KubernetesVersion)
+
+	server := poolEntry("servers")
+	server.Metadata = &plan.Metadata{Labels: map[string]string{capr.WorkerRoleLabel: "true", capr.ControlPlaneRoleLabel: "true"}}
+	assert.Same(t, cp, controlPlanes(server))
+	assert.Same(t, cp, controlPlanes(poolEntry("other")))
+
+	cluster.Spec.RKEConfig.MachinePools[0].KubernetesVersion = "v1.28.2+rke2r1"
+	_, err = p.workerControlPlanes(cp)
+	assert.Error(t, err)
+}
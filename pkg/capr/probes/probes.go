@@ -0,0 +1,211 @@
+// Package probes builds the HTTP(S) health probes the system-agent runs against the components of a cluster.
+package probes
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+)
+
+const (
+	Calico                = "calico"
+	ETCD                  = "etcd"
+	KubeAPIServer         = "kube-apiserver"
+	KubeScheduler         = "kube-scheduler"
+	KubeControllerManager = "kube-controller-manager"
+	Kubelet               = "kubelet"
+
+	caCertPathFormat = "/var/lib/rancher/%s/agent/probe-ca-%s.crt"
+)
+
+var defaults = map[string]plan.Probe{
+	Calico: {
+		InitialDelaySeconds: 1,
+		TimeoutSeconds:      5,
+		SuccessThreshold:    1,
+		FailureThreshold:    2,
+		HTTPGetAction: plan.HTTPGetAction{
+			URL: "http://127.0.0.1:9099/liveness",
+		},
+	},
+	ETCD: {
+		InitialDelaySeconds: 1,
+		TimeoutSeconds:      5,
+		SuccessThreshold:    1,
+		FailureThreshold:    2,
+		HTTPGetAction: plan.HTTPGetAction{
+			URL: "http://127.0.0.1:2381/health",
+		},
+	},
+	KubeAPIServer: {
+		InitialDelaySeconds: 1,
+		TimeoutSeconds:      5,
+		SuccessThreshold:    1,
+		FailureThreshold:    2,
+		HTTPGetAction: plan.HTTPGetAction{
+			URL:        "https://127.0.0.1:6443/readyz",
+			CACert:     "/var/lib/rancher/%s/server/tls/server-ca.crt",
+			ClientCert: "/var/lib/rancher/%s/server/tls/client-kube-apiserver.crt",
+			ClientKey:  "/var/lib/rancher/%s/server/tls/client-kube-apiserver.key",
+		},
+	},
+	KubeScheduler: {
+		InitialDelaySeconds: 1,
+		TimeoutSeconds:      5,
+		SuccessThreshold:    1,
+		FailureThreshold:    2,
+		HTTPGetAction: plan.HTTPGetAction{
+			URL: "https://127.0.0.1:%s/healthz",
+		},
+	},
+	KubeControllerManager: {
+		InitialDelaySeconds: 1,
+		TimeoutSeconds:      5,
+		SuccessThreshold:    1,
+		FailureThreshold:    2,
+		HTTPGetAction: plan.HTTPGetAction{
+			URL: "https://127.0.0.1:%s/healthz",
+		},
+	},
+	Kubelet: {
+		InitialDelaySeconds: 1,
+		TimeoutSeconds:      5,
+		SuccessThreshold:    1,
+		FailureThreshold:    2,
+		HTTPGetAction: plan.HTTPGetAction{
+			URL: "http://127.0.0.1:10248/healthz",
+		},
+	},
+}
+
+// Defaults returns the default probes of the given names for the runtime. The kube-scheduler and
+// kube-controller-manager probes must be completed with SetCACertAndPort.
+func Defaults(runtime string, names ...string) map[string]plan.Probe {
+	result := map[string]plan.Probe{}
+	for _, name := range names {
+		probe := defaults[name]
+		probe.HTTPGetAction.CACert = replaceRuntime(probe.HTTPGetAction.CACert, runtime)
+		probe.HTTPGetAction.ClientCert = replaceRuntime(probe.HTTPGetAction.ClientCert, runtime)
+		probe.HTTPGetAction.ClientKey = replaceRuntime(probe.HTTPGetAction.ClientKey, runtime)
+		result[name] = probe
+	}
+	return result
+}
+
+// SetCACertAndPort adds/replaces the CACert and URL with rendered values based on the values provided.
+func SetCACertAndPort(probe plan.Probe, cacert, port string) (plan.Probe, error) {
+	if cacert == "" || port == "" {
+		return plan.Probe{}, fmt.Errorf("CA cert (%s) or port (%s) not defined properly", cacert, port)
+	}
+	probe.HTTPGetAction.CACert = cacert
+	probe.HTTPGetAction.URL = fmt.Sprintf(probe.HTTPGetAction.URL, port)
+	return probe, nil
+}
+
+// Customize applies the customizations of the cluster to the probes, and returns the files of the CA certificates the
+// customized probes refer to, which must be delivered along with the probes. The server name and headers are only
+// applied if requestOptions is true, as older system-agents ignore them.
+func Customize(probes map[string]plan.Probe, customizations []rkev1.ProbeCustomization, runtime string, requestOptions bool) (map[string]plan.Probe, []plan.File, error) {
+	if len(customizations) == 0 {
+		return probes, nil, nil
+	}
+
+	result := make(map[string]plan.Probe, len(probes))
+	for name, probe := range probes {
+		result[name] = probe
+	}
+
+	var (
+		files []plan.File
+		seen  = map[string]bool{}
+	)
+	for i, customization := range customizations {
+		if err := validate(customization); err != nil {
+			return nil, nil, fmt.Errorf("invalid probe customization %d: %w", i, err)
+		}
+
+		var caCertPath string
+		if customization.CACerts != "" {
+			hash := sha256.Sum256([]byte(customization.CACerts))
+			caCertPath = fmt.Sprintf(caCertPathFormat, runtime, hex.EncodeToString(hash[:])[:8])
+		}
+
+		applied := false
+		for name, probe := range result {
+			if !appliesTo(customization, name) {
+				continue
+			}
+			applied = true
+			if caCertPath != "" {
+				probe.HTTPGetAction.CACert = caCertPath
+			}
+			if requestOptions && customization.ServerName != "" {
+				probe.HTTPGetAction.ServerName = customization.ServerName
+			}
+			if requestOptions && len(customization.Headers) > 0 {
+				headers := make(map[string]string, len(probe.HTTPGetAction.Headers)+len(customization.Headers))
+				for k, v := range probe.HTTPGetAction.Headers {
+					headers[k] = v
+				}
+				for k, v := range customization.Headers {
+					headers[k] = v
+				}
+				probe.HTTPGetAction.Headers = headers
+			}
+			result[name] = probe
+		}
+
+		if applied && caCertPath != "" && !seen[caCertPath] {
+			seen[caCertPath] = true
+			files = append(files, plan.File{
+				Content: base64.StdEncoding.EncodeToString([]byte(customization.CACerts)),
+				Path:    caCertPath,
+				Dynamic: true,
+				Minor:   true,
+			})
+		}
+	}
+	return result, files, nil
+}
+
+func validate(customization rkev1.ProbeCustomization) error {
+	for _, name := range customization.Probes {
+		if _, ok := defaults[name]; !ok {
+			return fmt.Errorf("unknown probe %q", name)
+		}
+	}
+	if customization.CACerts != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(customization.CACerts)) {
+		return fmt.Errorf("caCerts does not contain a PEM encoded certificate")
+	}
+	for k := range customization.Headers {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("header name must not be empty")
+		}
+	}
+	return nil
+}
+
+func appliesTo(customization rkev1.ProbeCustomization, name string) bool {
+	if len(customization.Probes) == 0 {
+		return true
+	}
+	for _, probe := range customization.Probes {
+		if probe == name {
+			return true
+		}
+	}
+	return false
+}
+
+func replaceRuntime(str string, runtime string) string {
+	if !strings.Contains(str, "%s") {
+		return str
+	}
+	return fmt.Sprintf(str, runtime)
+}
@@ -0,0 +1,169 @@
+package probes
+
+import (
+	"strings"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCA = `-----BEGIN CERTIFICATE-----
+MIIBhzCCAS2gAwIBAgIUCl/mN62vlvW9JOWcZGSUvBWuyfEwCgYIKoZIzj0EAwIw
+GDEWMBQGA1UEAwwNcHJvYmUtdGVzdC1jYTAgFw0yNjEwMTUwMTE5MjlaGA8yMTI2
+MDkyMTAxMTkyOVowGDEWMBQGA1UEAwwNcHJvYmUtdGVzdC1jYTBZMBMGByqGSM49
+AgEGCCqGSM49AwEHA0IABK9Raz6AjASjpVJr1yzJDAbwaIkt3W5wiOeP+jCur2Ac
+lSfiDHva7SyPsWMi0y9vAvUOq6wGCbmTfYpuPE7ANSqjUzBRMB0GA1UdDgQWBBRK
+rYrwn9utWOH1ApjTd8FA/2hGOzAfBgNVHSMEGDAWgBRKrYrwn9utWOH1ApjTd8FA
+/2hGOzAPBgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMCA0gAMEUCIQCsLEZ+JPVo
+sc45upp7/1MvYPFcjvUQbu57qwaRdpT8RAIgTVWcaixlElnUIIAiVxJPa+D6rK9u
+9M+RTh4LYYlN0lU=
+-----END CERTIFICATE-----
+`
+
+func TestDefaults(t *testing.T) {
+	probes := Defaults("rke2", KubeAPIServer, Kubelet)
+
+	assert.Len(t, probes, 2)
+	assert.Equal(t, "/var/lib/rancher/rke2/server/tls/server-ca.crt", probes[KubeAPIServer].HTTPGetAction.CACert)
+	assert.Equal(t, "/var/lib/rancher/rke2/server/tls/client-kube-apiserver.crt", probes[KubeAPIServer].HTTPGetAction.ClientCert)
+	assert.Equal(t, "http://127.0.0.1:10248/healthz", probes[Kubelet].HTTPGetAction.URL)
+}
+
+func TestSetCACertAndPort(t *testing.T) {
+	probe, err := SetCACertAndPort(Defaults("k3s", KubeScheduler)[KubeScheduler], "/tls/kube-scheduler.crt", "10259")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://127.0.0.1:10259/healthz", probe.HTTPGetAction.URL)
+	assert.Equal(t, "/tls/kube-scheduler.crt", probe.HTTPGetAction.CACert)
+
+	_, err = SetCACertAndPort(probe, "", "10259")
+	assert.Error(t, err)
+}
+
+func TestCustomize(t *testing.T) {
+	tests := []struct {
+		name           string
+		customizations []rkev1.ProbeCustomization
+		requestOptions bool
+		expected       map[string]plan.HTTPGetAction
+		expectedFiles  int
+		expectedErr    string
+	}{
+		{
+			name: "no customizations",
+			expected: map[string]plan.HTTPGetAction{
+				KubeAPIServer: Defaults("rke2", KubeAPIServer)[KubeAPIServer].HTTPGetAction,
+				Kubelet:       Defaults("rke2", Kubelet)[Kubelet].HTTPGetAction,
+			},
+		},
+		{
+			name: "customization of every probe",
+			customizations: []rkev1.ProbeCustomization{
+				{ServerName: "cluster.example.com", Headers: map[string]string{"X-Probe": "rancher"}},
+			},
+			requestOptions: true,
+			expected: map[string]plan.HTTPGetAction{
+				KubeAPIServer: {
+					URL:        "https://127.0.0.1:6443/readyz",
+					CACert:     "/var/lib/rancher/rke2/server/tls/server-ca.crt",
+					ClientCert: "/var/lib/rancher/rke2/server/tls/client-kube-apiserver.crt",
+					ClientKey:  "/var/lib/rancher/rke2/server/tls/client-kube-apiserver.key",
+					ServerName: "cluster.example.com",
+					Headers:    map[string]string{"X-Probe": "rancher"},
+				},
+				Kubelet: {
+					URL:        "http://127.0.0.1:10248/healthz",
+					ServerName: "cluster.example.com",
+					Headers:    map[string]string{"X-Probe": "rancher"},
+				},
+			},
+		},
+		{
+			name: "later customizations take precedence",
+			customizations: []rkev1.ProbeCustomization{
+				{Headers: map[string]string{"X-Probe": "rancher", "X-Cluster": "c-1"}},
+				{Probes: []string{KubeAPIServer}, CACerts: testCA, Headers: map[string]string{"X-Probe": "apiserver"}},
+			},
+			requestOptions: true,
+			expected: map[string]plan.HTTPGetAction{
+				KubeAPIServer: {
+					URL:        "https://127.0.0.1:6443/readyz",
+					CACert:     "/var/lib/rancher/rke2/agent/probe-ca-",
+					ClientCert: "/var/lib/rancher/rke2/server/tls/client-kube-apiserver.crt",
+					ClientKey:  "/var/lib/rancher/rke2/server/tls/client-kube-apiserver.key",
+					Headers:    map[string]string{"X-Probe": "apiserver", "X-Cluster": "c-1"},
+				},
+				Kubelet: {
+					URL:     "http://127.0.0.1:10248/healthz",
+					Headers: map[string]string{"X-Probe": "rancher", "X-Cluster": "c-1"},
+				},
+			},
+			expectedFiles: 1,
+		},
+		{
+			name: "server name and headers are not applied without request options",
+			customizations: []rkev1.ProbeCustomization{
+				{Probes: []string{KubeAPIServer}, CACerts: testCA, ServerName: "cluster.example.com", Headers: map[string]string{"X-Probe": "rancher"}},
+			},
+			expected: map[string]plan.HTTPGetAction{
+				KubeAPIServer: {
+					URL:        "https://127.0.0.1:6443/readyz",
+					CACert:     "/var/lib/rancher/rke2/agent/probe-ca-",
+					ClientCert: "/var/lib/rancher/rke2/server/tls/client-kube-apiserver.crt",
+					ClientKey:  "/var/lib/rancher/rke2/server/tls/client-kube-apiserver.key",
+				},
+				Kubelet: Defaults("rke2", Kubelet)[Kubelet].HTTPGetAction,
+			},
+			expectedFiles: 1,
+		},
+		{
+			name: "CA of probes not on the machine is not delivered",
+			customizations: []rkev1.ProbeCustomization{
+				{Probes: []string{ETCD}, CACerts: testCA},
+			},
+			expected: map[string]plan.HTTPGetAction{
+				KubeAPIServer: Defaults("rke2", KubeAPIServer)[KubeAPIServer].HTTPGetAction,
+				Kubelet:       Defaults("rke2", Kubelet)[Kubelet].HTTPGetAction,
+			},
+		},
+		{
+			name:           "unknown probe",
+			customizations: []rkev1.ProbeCustomization{{Probes: []string{"kube-proxy"}}},
+			expectedErr:    "invalid probe customization 0: unknown probe \"kube-proxy\"",
+		},
+		{
+			name:           "invalid CA",
+			customizations: []rkev1.ProbeCustomization{{CACerts: "not a certificate"}},
+			expectedErr:    "invalid probe customization 0: caCerts does not contain a PEM encoded certificate",
+		},
+		{
+			name:           "empty header name",
+			customizations: []rkev1.ProbeCustomization{{Headers: map[string]string{" ": "value"}}},
+			expectedErr:    "invalid probe customization 0: header name must not be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probes, files, err := Customize(Defaults("rke2", KubeAPIServer, Kubelet), tt.customizations, "rke2", tt.requestOptions)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, files, tt.expectedFiles)
+			assert.Len(t, probes, len(tt.expected))
+			for name, expected := range tt.expected {
+				actual := probes[name].HTTPGetAction
+				if strings.HasSuffix(expected.CACert, "probe-ca-") {
+					assert.True(t, strings.HasPrefix(actual.CACert, expected.CACert))
+					assert.Equal(t, files[0].Path, actual.CACert)
+					assert.True(t, files[0].Dynamic)
+					actual.CACert = expected.CACert
+				}
+				assert.Equal(t, expected, actual, name)
+			}
+		})
+	}
+}
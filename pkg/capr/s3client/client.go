@@ -0,0 +1,227 @@
+// Package s3client provides access to the S3 buckets of etcd snapshots from the management plane. Clients are pooled
+// per bucket configuration, so that the connections to an endpoint are reused across controllers and reconciliations.
+package s3client
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/rancher/rancher/pkg/metrics"
+)
+
+const (
+	defaultEndpoint = "s3.amazonaws.com"
+
+	// idleConnTimeout is how long an idle connection to an endpoint is kept open for reuse.
+	idleConnTimeout = 90 * time.Second
+	// maxIdleConnsPerHost is the number of idle connections kept open per endpoint.
+	maxIdleConnsPerHost = 10
+)
+
+// Config is the configuration used to access the S3 bucket of an etcd snapshot.
+type Config struct {
+	AccessKey     string
+	SecretKey     string
+	SessionToken  string
+	Region        string
+	Endpoint      string
+	EndpointCA    string
+	SkipSSLVerify bool
+	Bucket        string
+	Folder        string
+}
+
+// Client accesses the objects in the folder of an S3 bucket. Object names are relative to the folder.
+type Client struct {
+	client *minio.Client
+	bucket string
+	folder string
+}
+
+// Pool holds the S3 clients of the management plane. Clients, and the connections of their transports, are shared by
+// all users of the same endpoint with the same credentials and TLS settings.
+type Pool struct {
+	lock    sync.Mutex
+	clients map[string]*minio.Client
+}
+
+// NewPool returns an empty pool of S3 clients.
+func NewPool() *Pool {
+	return &Pool{
+		clients: map[string]*minio.Client{},
+	}
+}
+
+var defaultPool = NewPool()
+
+// Get returns a client for the bucket of the passed in config from the default pool.
+func Get(config Config) (*Client, error) {
+	return defaultPool.Get(config)
+}
+
+// Get returns a client for the bucket of the passed in config, reusing the pooled client of the endpoint if one exists.
+func (p *Pool) Get(config Config) (*Client, error) {
+	key := poolKey(config)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	client, ok := p.clients[key]
+	if !ok {
+		var err error
+		client, err = newMinioClient(config)
+		if err != nil {
+			return nil, err
+		}
+		p.clients[key] = client
+	}
+	return &Client{
+		client: client,
+		bucket: config.Bucket,
+		folder: config.Folder,
+	}, nil
+}
+
+// poolKey returns the key of the pooled client for the config. The bucket and folder are not part of the key, as they
+// are passed with every request.
+func poolKey(config Config) string {
+	config.Bucket, config.Folder = "", ""
+	// ignore errors, the config only contains strings and bools
+	b, _ := json.Marshal(config)
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:])
+}
+
+func newMinioClient(config Config) (*minio.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.IdleConnTimeout = idleConnTimeout
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	if config.EndpointCA != "" || config.SkipSSLVerify {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: config.SkipSSLVerify,
+		}
+		if config.EndpointCA != "" {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM([]byte(config.EndpointCA)) {
+				return nil, fmt.Errorf("failed to parse S3 endpoint CA")
+			}
+			transport.TLSClientConfig.RootCAs = pool
+		}
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	var creds *credentials.Credentials
+	if config.AccessKey == "" || config.SecretKey == "" {
+		// no access credentials, we assume IAM roles
+		creds = credentials.NewIAM("")
+	} else {
+		creds = credentials.NewStaticV4(config.AccessKey, config.SecretKey, config.SessionToken)
+	}
+
+	return minio.New(endpoint, &minio.Options{
+		Creds:        creds,
+		Region:       config.Region,
+		Secure:       true,
+		BucketLookup: bucketLookup(endpoint),
+		Transport:    transport,
+	})
+}
+
+// bucketLookup returns the bucket lookup style of the endpoint. Alibaba Cloud OSS only supports virtual-hosted-style
+// requests.
+func bucketLookup(endpoint string) minio.BucketLookupType {
+	if strings.Contains(endpoint, "aliyun") {
+		return minio.BucketLookupDNS
+	}
+	return minio.BucketLookupAuto
+}
+
+// objectName returns the key of the object with the passed in name in the folder of the client.
+func (c *Client) objectName(name string) string {
+	return path.Join(c.folder, name)
+}
+
+// BucketExists returns true if the bucket of the client exists and is accessible with its credentials.
+func (c *Client) BucketExists(ctx context.Context) (exists bool, err error) {
+	defer observe("bucket_exists", time.Now(), &err)
+	return c.client.BucketExists(ctx, c.bucket)
+}
+
+// Stat returns the info of the object with the passed in name.
+func (c *Client) Stat(ctx context.Context, name string) (info minio.ObjectInfo, err error) {
+	defer observe("stat", time.Now(), &err)
+	return c.client.StatObject(ctx, c.bucket, c.objectName(name), minio.StatObjectOptions{})
+}
+
+// List returns the info of the objects directly in the folder of the client.
+func (c *Client) List(ctx context.Context) (objects []minio.ObjectInfo, err error) {
+	defer observe("list", time.Now(), &err)
+	prefix := c.folder
+	if prefix != "" {
+		prefix += "/"
+	}
+	for object := range c.client.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// Remove deletes the object with the passed in name.
+func (c *Client) Remove(ctx context.Context, name string) (err error) {
+	defer observe("remove", time.Now(), &err)
+	return c.client.RemoveObject(ctx, c.bucket, c.objectName(name), minio.RemoveObjectOptions{})
+}
+
+// Open returns a reader that streams the content of the object with the passed in name. The object is only requested
+// once the reader is read from, errors of the request are returned by the reader. The reader must be closed.
+func (c *Client) Open(ctx context.Context, name string) (reader io.ReadCloser, err error) {
+	defer observe("open", time.Now(), &err)
+	object, err := c.client.GetObject(ctx, c.bucket, c.objectName(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// Put uploads the content of the reader as the object with the passed in name.
+func (c *Client) Put(ctx context.Context, name string, reader io.Reader, size int64, contentType string) (err error) {
+	defer observe("put", time.Now(), &err)
+	_, err = c.client.PutObject(ctx, c.bucket, c.objectName(name), reader, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+// ObjectURL returns the s3:// URL of the object with the passed in name.
+func (c *Client) ObjectURL(name string) string {
+	return fmt.Sprintf("s3://%s/%s", c.bucket, c.objectName(name))
+}
+
+// observe records the duration and result of an S3 operation started at the passed in time.
+func observe(operation string, start time.Time, err *error) {
+	metrics.ObserveCAPRS3Request(operation, time.Since(start), *err)
+}
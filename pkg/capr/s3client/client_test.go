@@ -0,0 +1,91 @@
+package s3client
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolGet(t *testing.T) {
+	pool := NewPool()
+	config := Config{
+		AccessKey: "access",
+		SecretKey: "secret",
+		Endpoint:  "s3.example.com",
+		Bucket:    "snapshots",
+		Folder:    "cluster-a",
+	}
+
+	a, err := pool.Get(config)
+	require.NoError(t, err)
+	assert.Equal(t, "cluster-a/snapshot-1", a.objectName("snapshot-1"))
+
+	config.Bucket, config.Folder = "other", ""
+	b, err := pool.Get(config)
+	require.NoError(t, err)
+	assert.Same(t, a.client, b.client, "the client of an endpoint is shared between buckets")
+	assert.Equal(t, "snapshot-1", b.objectName("snapshot-1"))
+
+	config.SecretKey = "rotated"
+	c, err := pool.Get(config)
+	require.NoError(t, err)
+	assert.NotSame(t, a.client, c.client, "changed credentials use a new client")
+	assert.Len(t, pool.clients, 2)
+
+	config.EndpointCA = "not a certificate"
+	_, err = pool.Get(config)
+	assert.EqualError(t, err, "failed to parse S3 endpoint CA")
+}
+
+func TestBucketLookup(t *testing.T) {
+	assert.Equal(t, minio.BucketLookupAuto, bucketLookup("s3.amazonaws.com"))
+	assert.Equal(t, minio.BucketLookupDNS, bucketLookup("oss-cn-hangzhou.aliyuncs.com"))
+}
+
+func TestClientCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/snapshots/cluster-a/snapshot-1" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+		rw.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		if req.Method == http.MethodGet {
+			_, _ = rw.Write([]byte("snapshot"))
+		} else {
+			rw.Header().Set("Content-Length", "8")
+		}
+	}))
+	defer server.Close()
+
+	ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	client, err := NewPool().Get(Config{
+		AccessKey:  "access",
+		SecretKey:  "secret",
+		Region:     "us-east-1",
+		Endpoint:   strings.TrimPrefix(server.URL, "https://"),
+		EndpointCA: ca,
+		Bucket:     "snapshots",
+		Folder:     "cluster-a",
+	})
+	require.NoError(t, err)
+
+	info, err := client.Stat(context.Background(), "snapshot-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), info.Size)
+
+	reader, err := client.Open(context.Background(), "snapshot-1")
+	require.NoError(t, err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot", string(content))
+}
@@ -0,0 +1,101 @@
+package s3client
+
+import (
+	"fmt"
+	"strings"
+)
+
+const policyVersion = "2012-10-17"
+
+// PolicyDocument is an AWS IAM or S3 bucket policy.
+type PolicyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []PolicyStatement `json:"Statement"`
+}
+
+// PolicyStatement is a statement of a PolicyDocument.
+type PolicyStatement struct {
+	Sid       string              `json:"Sid"`
+	Effect    string              `json:"Effect"`
+	Principal map[string][]string `json:"Principal,omitempty"`
+	Action    []string            `json:"Action"`
+	Resource  []string            `json:"Resource"`
+}
+
+// Policies are the policies that grant the access etcd snapshots require to the bucket of a config.
+type Policies struct {
+	// IAMPolicy is attached to the user or role whose credentials the clusters and Rancher use to access the bucket.
+	IAMPolicy PolicyDocument `json:"iamPolicy"`
+	// BucketPolicy grants the same access to the principal on the bucket. It is only set if a principal is passed.
+	BucketPolicy *PolicyDocument `json:"bucketPolicy,omitempty"`
+}
+
+// GeneratePolicies returns the least privilege policies for etcd snapshots in the bucket and folder of the config. Nodes
+// upload, list, download and prune snapshots, Rancher verifies and deletes them, and both check the bucket exists:
+//   - s3:ListBucket and s3:GetBucketLocation on the bucket. ListBucket is not restricted to the folder, as checking that
+//     a bucket exists requires it without a prefix.
+//   - s3:GetObject, s3:PutObject, s3:DeleteObject and the multipart upload actions on the objects in the folder.
+//
+// The principal of the bucket policy is the ARN of the dedicated user or role that holds the credentials, for example
+// arn:aws:iam::123456789012:user/etcd-snapshots.
+func GeneratePolicies(config Config, principal string) (Policies, error) {
+	if config.Bucket == "" {
+		return Policies{}, fmt.Errorf("bucket is required to generate the S3 policies of etcd snapshots")
+	}
+
+	partition := partition(config.Region)
+	bucket := fmt.Sprintf("arn:%s:s3:::%s", partition, config.Bucket)
+	objects := bucket + "/*"
+	if folder := strings.Trim(config.Folder, "/"); folder != "" {
+		objects = bucket + "/" + folder + "/*"
+	}
+
+	statements := []PolicyStatement{
+		{
+			Sid:      "EtcdSnapshotBucket",
+			Effect:   "Allow",
+			Action:   []string{"s3:GetBucketLocation", "s3:ListBucket", "s3:ListBucketMultipartUploads"},
+			Resource: []string{bucket},
+		},
+		{
+			Sid:    "EtcdSnapshotObjects",
+			Effect: "Allow",
+			Action: []string{
+				"s3:AbortMultipartUpload",
+				"s3:DeleteObject",
+				"s3:GetObject",
+				"s3:ListMultipartUploadParts",
+				"s3:PutObject",
+			},
+			Resource: []string{objects},
+		},
+	}
+
+	policies := Policies{
+		IAMPolicy: PolicyDocument{
+			Version:   policyVersion,
+			Statement: statements,
+		},
+	}
+	if principal != "" {
+		bucketPolicy := PolicyDocument{Version: policyVersion}
+		for _, statement := range statements {
+			statement.Principal = map[string][]string{"AWS": {principal}}
+			bucketPolicy.Statement = append(bucketPolicy.Statement, statement)
+		}
+		policies.BucketPolicy = &bucketPolicy
+	}
+	return policies, nil
+}
+
+// partition returns the AWS partition of the region, which is part of the ARNs of buckets and objects.
+func partition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	default:
+		return "aws"
+	}
+}
@@ -0,0 +1,84 @@
+package s3client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePolicies(t *testing.T) {
+	tests := []struct {
+		name              string
+		config            Config
+		principal         string
+		expectedBucket    string
+		expectedObjects   string
+		expectedPrincipal bool
+		expectErr         bool
+	}{
+		{
+			name:            "bucket",
+			config:          Config{Bucket: "snapshots", Region: "us-east-1"},
+			expectedBucket:  "arn:aws:s3:::snapshots",
+			expectedObjects: "arn:aws:s3:::snapshots/*",
+		},
+		{
+			name:              "folder and principal",
+			config:            Config{Bucket: "snapshots", Folder: "/clusters/prod/"},
+			principal:         "arn:aws:iam::123456789012:user/etcd-snapshots",
+			expectedBucket:    "arn:aws:s3:::snapshots",
+			expectedObjects:   "arn:aws:s3:::snapshots/clusters/prod/*",
+			expectedPrincipal: true,
+		},
+		{
+			name:            "china region",
+			config:          Config{Bucket: "snapshots", Region: "cn-north-1"},
+			expectedBucket:  "arn:aws-cn:s3:::snapshots",
+			expectedObjects: "arn:aws-cn:s3:::snapshots/*",
+		},
+		{
+			name:            "govcloud region",
+			config:          Config{Bucket: "snapshots", Region: "us-gov-west-1", Folder: "etcd"},
+			expectedBucket:  "arn:aws-us-gov:s3:::snapshots",
+			expectedObjects: "arn:aws-us-gov:s3:::snapshots/etcd/*",
+		},
+		{
+			name:      "no bucket",
+			config:    Config{Folder: "etcd"},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies, err := GeneratePolicies(tt.config, tt.principal)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, policies.IAMPolicy.Statement, 2)
+			assert.Equal(t, []string{tt.expectedBucket}, policies.IAMPolicy.Statement[0].Resource)
+			assert.Equal(t, []string{tt.expectedObjects}, policies.IAMPolicy.Statement[1].Resource)
+			for _, statement := range policies.IAMPolicy.Statement {
+				assert.Nil(t, statement.Principal)
+			}
+
+			if !tt.expectedPrincipal {
+				assert.Nil(t, policies.BucketPolicy)
+				return
+			}
+			require.NotNil(t, policies.BucketPolicy)
+			require.Len(t, policies.BucketPolicy.Statement, 2)
+			for i, statement := range policies.BucketPolicy.Statement {
+				assert.Equal(t, map[string][]string{"AWS": {tt.principal}}, statement.Principal)
+				assert.Equal(t, policies.IAMPolicy.Statement[i].Resource, statement.Resource)
+			}
+
+			b, err := json.Marshal(policies)
+			require.NoError(t, err)
+			assert.Contains(t, string(b), `"Version":"2012-10-17"`)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package capr
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rancher/lasso/pkg/client"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PlannerFieldManager is the field manager of the fields the planner applies, i.e. the plans of machines. It is recorded
+// in the managedFields of the objects.
+const PlannerFieldManager = "rancher-planner"
+
+// appliedMetadataFields are the fields of the metadata of an object that are part of the applied configuration.
+var appliedMetadataFields = []string{"name", "namespace", "labels", "annotations", "ownerReferences"}
+
+// ServerSideApplier writes objects with server-side apply on behalf of a field manager. The applied objects carry no
+// resource version, so writes do not fail with conflicts if webhooks or other controllers changed the objects in the
+// meantime. Fields that are owned by other field managers are taken over, so only the fields the field manager owns
+// must be applied.
+type ServerSideApplier struct {
+	fieldManager string
+	clients      client.SharedClientFactory
+}
+
+func NewServerSideApplier(fieldManager string, clients client.SharedClientFactory) *ServerSideApplier {
+	return &ServerSideApplier{
+		fieldManager: fieldManager,
+		clients:      clients,
+	}
+}
+
+// Apply applies obj and decodes the resulting object into result. obj must hold every field the field manager owns,
+// as fields that were applied before and are omitted are removed unless another field manager owns them as well. Only
+// the name, namespace, labels, annotations and owner references of the metadata of obj are applied. If the status
+// subresource is applied, only the status of obj is applied.
+func (s *ServerSideApplier) Apply(ctx context.Context, obj, result runtime.Object, subresources ...string) error {
+	return s.apply(ctx, obj, result, false, subresources...)
+}
+
+// ApplyMetadata applies only the name, namespace, labels, annotations and owner references of the metadata of obj and
+// decodes the resulting object into result. It is used for objects the field manager only sets metadata of, as the
+// spec of a typed object that is left unset holds the zero values of its required fields, which must not be applied.
+func (s *ServerSideApplier) ApplyMetadata(ctx context.Context, obj, result runtime.Object) error {
+	return s.apply(ctx, obj, result, true)
+}
+
+func (s *ServerSideApplier) apply(ctx context.Context, obj, result runtime.Object, metadataOnly bool, subresources ...string) error {
+	gvk, err := s.clients.GVKForObject(obj)
+	if err != nil {
+		return err
+	}
+	c, err := s.clients.ForKind(gvk)
+	if err != nil {
+		return err
+	}
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if metadataOnly {
+		data, err = metadataConfiguration(gvk, obj)
+	} else {
+		data, err = applyConfiguration(gvk, obj, subresources...)
+	}
+	if err != nil {
+		return err
+	}
+	return c.Patch(ctx, m.GetNamespace(), m.GetName(), types.ApplyPatchType, data, result, metav1.PatchOptions{
+		FieldManager: s.fieldManager,
+		Force:        &[]bool{true}[0],
+	}, subresources...)
+}
+
+// applyConfiguration returns the applied configuration of obj.
+func applyConfiguration(gvk schema.GroupVersionKind, obj runtime.Object, subresources ...string) ([]byte, error) {
+	content, err := configuration(gvk, obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(subresources) == 1 && subresources[0] == "status" {
+		content = map[string]interface{}{
+			"apiVersion": content["apiVersion"],
+			"kind":       content["kind"],
+			"metadata":   content["metadata"],
+			"status":     content["status"],
+		}
+	}
+	return json.Marshal(content)
+}
+
+// metadataConfiguration returns the applied configuration of obj that holds only its metadata.
+func metadataConfiguration(gvk schema.GroupVersionKind, obj runtime.Object) ([]byte, error) {
+	content, err := configuration(gvk, obj)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{
+		"apiVersion": content["apiVersion"],
+		"kind":       content["kind"],
+		"metadata":   content["metadata"],
+	})
+}
+
+// configuration converts obj to its unstructured content with only the applied fields of its metadata.
+func configuration(gvk schema.GroupVersionKind, obj runtime.Object) (map[string]interface{}, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{}
+	if objMetadata, ok := content["metadata"].(map[string]interface{}); ok {
+		for _, field := range appliedMetadataFields {
+			if value, ok := objMetadata[field]; ok && value != nil {
+				metadata[field] = value
+			}
+		}
+	}
+	content["metadata"] = metadata
+	content["apiVersion"], content["kind"] = gvk.ToAPIVersionAndKind()
+	return content, nil
+}
@@ -0,0 +1,72 @@
+package capr
+
+import (
+	"encoding/json"
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestApplyConfiguration(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-machine-plan",
+			Namespace:       "fleet-default",
+			ResourceVersion: "42",
+			Labels:          map[string]string{InitNodeLabel: "true"},
+			ManagedFields:   []metav1.ManagedFieldsEntry{{Manager: "rancher"}},
+		},
+		Data: map[string][]byte{"plan": []byte("{}")},
+	}
+	data, err := applyConfiguration(corev1.SchemeGroupVersion.WithKind("Secret"), secret)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"apiVersion": "v1",
+		"kind": "Secret",
+		"metadata": {
+			"name": "test-machine-plan",
+			"namespace": "fleet-default",
+			"labels": {"rke.cattle.io/init-node": "true"}
+		},
+		"data": {"plan": "e30="}
+	}`, string(data))
+
+	cp := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"},
+		Spec:       rkev1.RKEControlPlaneSpec{ClusterName: "test"},
+		Status:     rkev1.RKEControlPlaneStatus{ObservedGeneration: 2},
+	}
+	data, err = applyConfiguration(rkev1.SchemeGroupVersion.WithKind("RKEControlPlane"), cp, "status")
+	require.NoError(t, err)
+	applied := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(data, &applied))
+	assert.Equal(t, "rke.cattle.io/v1", applied["apiVersion"])
+	assert.NotContains(t, applied, "spec")
+	assert.Equal(t, float64(2), applied["status"].(map[string]interface{})["observedGeneration"])
+}
+
+func TestMetadataConfiguration(t *testing.T) {
+	machine := &capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-etcd",
+			Namespace:   "fleet-default",
+			Annotations: map[string]string{capi.ExcludeNodeDrainingAnnotation: "true"},
+		},
+	}
+	data, err := metadataConfiguration(capi.GroupVersion.WithKind("Machine"), machine)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"apiVersion": "cluster.x-k8s.io/v1beta1",
+		"kind": "Machine",
+		"metadata": {
+			"name": "test-etcd",
+			"namespace": "fleet-default",
+			"annotations": {"machine.cluster.x-k8s.io/exclude-node-draining": "true"}
+		}
+	}`, string(data))
+}
@@ -0,0 +1,254 @@
+// Package snapshotclient provides typed access to the etcd snapshots of the clusters provisioned by Rancher, and to the
+// snapshot create and restore fields of the clusters and their control planes, for controllers and for automation that
+// runs against the Rancher management cluster.
+package snapshotclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/generic"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+)
+
+// FailedStatus is the status of the snapshot file of snapshots that failed to be taken.
+const FailedStatus = "failed"
+
+// Client reads etcd snapshots and the snapshot status of control planes from caches, and requests snapshots and
+// restores through the clusters.
+type Client struct {
+	snapshots         rkecontrollers.ETCDSnapshotController
+	snapshotCache     rkecontrollers.ETCDSnapshotCache
+	controlPlaneCache rkecontrollers.RKEControlPlaneCache
+	clusters          provisioningcontrollers.ClusterClient
+	clusterCache      provisioningcontrollers.ClusterCache
+}
+
+// New returns a client that uses the passed in controllers.
+func New(snapshots rkecontrollers.ETCDSnapshotController, controlPlanes rkecontrollers.RKEControlPlaneController, clusters provisioningcontrollers.ClusterController) *Client {
+	return &Client{
+		snapshots:         snapshots,
+		snapshotCache:     snapshots.Cache(),
+		controlPlaneCache: controlPlanes.Cache(),
+		clusters:          clusters,
+		clusterCache:      clusters.Cache(),
+	}
+}
+
+// NewFromContext returns a client that uses the controllers of the wrangler context. The caches are started with the
+// other controllers of the context.
+func NewFromContext(clients *wrangler.Context) *Client {
+	return New(clients.RKE.ETCDSnapshot(), clients.RKE.RKEControlPlane(), clients.Provisioning.Cluster())
+}
+
+// NewForConfig returns a client for the Rancher management cluster of the rest config, for use outside of Rancher. The
+// caches of the client are started and synced before it is returned, and are stopped with the context.
+func NewForConfig(ctx context.Context, config *rest.Config) (*Client, error) {
+	factory, err := generic.NewFactoryFromConfigWithOptions(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	rke := rkecontrollers.New(factory.ControllerFactory())
+	provisioning := provisioningcontrollers.New(factory.ControllerFactory())
+	client := New(rke.ETCDSnapshot(), rke.RKEControlPlane(), provisioning.Cluster())
+
+	if err := factory.Start(ctx, 1); err != nil {
+		return nil, err
+	}
+	if err := factory.Sync(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Get returns the snapshot of the passed in namespace and name.
+func (c *Client) Get(namespace, name string) (*rkev1.ETCDSnapshot, error) {
+	return c.snapshotCache.Get(namespace, name)
+}
+
+// List returns the snapshots of the cluster, newest first.
+func (c *Client) List(namespace, clusterName string) ([]*rkev1.ETCDSnapshot, error) {
+	snapshots, err := c.snapshotCache.List(namespace, labels.SelectorFromSet(labels.Set{capr.ClusterNameLabel: clusterName}))
+	if err != nil {
+		return nil, err
+	}
+	SortNewestFirst(snapshots)
+	return snapshots, nil
+}
+
+// LatestSuccessful returns the newest successful snapshot of the cluster, or nil if there is none.
+func (c *Client) LatestSuccessful(namespace, clusterName string) (*rkev1.ETCDSnapshot, error) {
+	snapshots, err := c.List(namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return LatestSuccessful(snapshots), nil
+}
+
+// OnClusterSnapshotChange registers a handler for changes to snapshots that belong to a cluster, which is passed the
+// namespace and name of the cluster along with the snapshot. Snapshots that do not belong to a cluster are skipped.
+func (c *Client) OnClusterSnapshotChange(ctx context.Context, name string, handler func(namespace, clusterName string, snapshot *rkev1.ETCDSnapshot) error) {
+	c.snapshots.OnChange(ctx, name, func(_ string, snapshot *rkev1.ETCDSnapshot) (*rkev1.ETCDSnapshot, error) {
+		if snapshot == nil {
+			return nil, nil
+		}
+		clusterName := ClusterName(snapshot)
+		if clusterName == "" {
+			return snapshot, nil
+		}
+		return snapshot, handler(snapshot.Namespace, clusterName, snapshot)
+	})
+}
+
+// Status is the snapshot status of the control plane of a cluster.
+type Status struct {
+	Create       *rkev1.ETCDSnapshotCreate
+	CreatePhase  rkev1.ETCDSnapshotPhase
+	Restore      *rkev1.ETCDSnapshotRestore
+	RestorePhase rkev1.ETCDSnapshotPhase
+	Upload       *rkev1.ETCDSnapshotUpload
+	UploadPhase  rkev1.ETCDSnapshotPhase
+}
+
+// Status returns the snapshot status of the control plane of the cluster.
+func (c *Client) Status(namespace, clusterName string) (Status, error) {
+	cp, err := c.controlPlaneCache.Get(namespace, clusterName)
+	if err != nil {
+		return Status{}, err
+	}
+	return StatusOf(cp), nil
+}
+
+// StatusOf returns the snapshot status of the control plane.
+func StatusOf(cp *rkev1.RKEControlPlane) Status {
+	return Status{
+		Create:       cp.Status.ETCDSnapshotCreate,
+		CreatePhase:  cp.Status.ETCDSnapshotCreatePhase,
+		Restore:      cp.Status.ETCDSnapshotRestore,
+		RestorePhase: cp.Status.ETCDSnapshotRestorePhase,
+		Upload:       cp.Status.ETCDSnapshotUpload,
+		UploadPhase:  cp.Status.ETCDSnapshotUploadPhase,
+	}
+}
+
+// RestorePhase returns the phase of the restore of the snapshot by the control plane, or an empty phase if the control
+// plane has not started to restore it.
+func RestorePhase(cp *rkev1.RKEControlPlane, snapshotName string) rkev1.ETCDSnapshotPhase {
+	if cp.Status.ETCDSnapshotRestore == nil || cp.Status.ETCDSnapshotRestore.Name != snapshotName {
+		return ""
+	}
+	return cp.Status.ETCDSnapshotRestorePhase
+}
+
+// RequestSnapshot requests a snapshot of the cluster with the options of the passed in snapshot create, by incrementing
+// the generation of its snapshot create request, and returns the requested generation. The progress is reported in the
+// CreatePhase of the Status of the cluster.
+func (c *Client) RequestSnapshot(namespace, clusterName string, options rkev1.ETCDSnapshotCreate) (int, error) {
+	var generation int
+	err := c.updateRKEConfig(namespace, clusterName, func(rkeConfig *rancherv1.RKEConfig) {
+		generation = 1
+		if rkeConfig.ETCDSnapshotCreate != nil {
+			generation = rkeConfig.ETCDSnapshotCreate.Generation + 1
+		}
+		rkeConfig.ETCDSnapshotCreate = &rkev1.ETCDSnapshotCreate{
+			Generation:              generation,
+			EncryptionKeySecretName: options.EncryptionKeySecretName,
+			MaxConcurrency:          options.MaxConcurrency,
+		}
+	})
+	return generation, err
+}
+
+// RequestRestore requests the restore of the snapshot named by the passed in snapshot restore to the cluster, with its
+// options, by incrementing the generation of the snapshot restore request of the cluster, and returns the requested
+// generation. The RestoreRKEConfig of the options is one of "none", "kubernetesVersion" or "all". The progress is
+// reported in the RestorePhase of the Status of the cluster.
+func (c *Client) RequestRestore(namespace, clusterName string, options rkev1.ETCDSnapshotRestore) (int, error) {
+	if _, err := c.snapshotCache.Get(namespace, options.Name); err != nil {
+		return 0, err
+	}
+
+	var generation int
+	err := c.updateRKEConfig(namespace, clusterName, func(rkeConfig *rancherv1.RKEConfig) {
+		generation = 1
+		if rkeConfig.ETCDSnapshotRestore != nil {
+			generation = rkeConfig.ETCDSnapshotRestore.Generation + 1
+		}
+		rkeConfig.ETCDSnapshotRestore = &rkev1.ETCDSnapshotRestore{
+			Name:                    options.Name,
+			Generation:              generation,
+			RestoreRKEConfig:        options.RestoreRKEConfig,
+			EncryptionKeySecretName: options.EncryptionKeySecretName,
+			MigrateStorageVersions:  options.MigrateStorageVersions,
+		}
+	})
+	return generation, err
+}
+
+func (c *Client) updateRKEConfig(namespace, clusterName string, mutate func(rkeConfig *rancherv1.RKEConfig)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := c.clusterCache.Get(namespace, clusterName)
+		if err != nil {
+			return err
+		}
+		if cluster.Spec.RKEConfig == nil {
+			return fmt.Errorf("cluster %s/%s was not provisioned by Rancher and has no etcd snapshots", namespace, clusterName)
+		}
+		cluster = cluster.DeepCopy()
+		mutate(cluster.Spec.RKEConfig)
+		_, err = c.clusters.Update(cluster)
+		return err
+	})
+}
+
+// ClusterName returns the name of the cluster the snapshot was taken from, or an empty string if it is not known.
+func ClusterName(snapshot *rkev1.ETCDSnapshot) string {
+	if clusterName := snapshot.Labels[capr.ClusterNameLabel]; clusterName != "" {
+		return clusterName
+	}
+	return snapshot.Spec.ClusterName
+}
+
+// Successful returns true if the snapshot was taken successfully and its file is not missing.
+func Successful(snapshot *rkev1.ETCDSnapshot) bool {
+	return !snapshot.Status.Missing && snapshot.SnapshotFile.CreatedAt != nil && snapshot.SnapshotFile.Status != FailedStatus
+}
+
+// Newer returns true if snapshot a was created after snapshot b, or if b is nil. Snapshots created at the same time are
+// ordered by name, so that the order is stable.
+func Newer(a, b *rkev1.ETCDSnapshot) bool {
+	if b == nil {
+		return true
+	}
+	if a.SnapshotFile.CreatedAt.Equal(b.SnapshotFile.CreatedAt) {
+		return a.Name > b.Name
+	}
+	return b.SnapshotFile.CreatedAt.Before(a.SnapshotFile.CreatedAt)
+}
+
+// LatestSuccessful returns the newest of the successful snapshots, or nil if there is none.
+func LatestSuccessful(snapshots []*rkev1.ETCDSnapshot) *rkev1.ETCDSnapshot {
+	var latest *rkev1.ETCDSnapshot
+	for _, s := range snapshots {
+		if Successful(s) && Newer(s, latest) {
+			latest = s
+		}
+	}
+	return latest
+}
+
+// SortNewestFirst sorts the snapshots by the time they were created, newest first.
+func SortNewestFirst(snapshots []*rkev1.ETCDSnapshot) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return Newer(snapshots[i], snapshots[j])
+	})
+}
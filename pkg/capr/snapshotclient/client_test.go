@@ -0,0 +1,82 @@
+package snapshotclient
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLatestSuccessful(t *testing.T) {
+	now := time.Now()
+	snapshot := func(name string, age time.Duration, status string, missing bool) *rkev1.ETCDSnapshot {
+		return &rkev1.ETCDSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			SnapshotFile: rkev1.ETCDSnapshotFile{
+				CreatedAt: &metav1.Time{Time: now.Add(-age)},
+				Status:    status,
+			},
+			Status: rkev1.ETCDSnapshotStatus{Missing: missing},
+		}
+	}
+
+	assert.Nil(t, LatestSuccessful(nil))
+	latest := LatestSuccessful([]*rkev1.ETCDSnapshot{
+		snapshot("old", 3*time.Hour, "successful", false),
+		snapshot("recent", 2*time.Hour, "successful", false),
+		snapshot("missing", time.Hour, "successful", true),
+		snapshot("failed", time.Minute, FailedStatus, false),
+		{ObjectMeta: metav1.ObjectMeta{Name: "pending"}},
+	})
+	require.NotNil(t, latest)
+	assert.Equal(t, "recent", latest.Name)
+}
+
+func TestSortNewestFirst(t *testing.T) {
+	createdAt := metav1.NewTime(time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC))
+	snapshot := func(name string, age time.Duration) *rkev1.ETCDSnapshot {
+		return &rkev1.ETCDSnapshot{
+			ObjectMeta:   metav1.ObjectMeta{Name: name},
+			SnapshotFile: rkev1.ETCDSnapshotFile{CreatedAt: &metav1.Time{Time: createdAt.Add(-age)}},
+		}
+	}
+
+	snapshots := []*rkev1.ETCDSnapshot{
+		snapshot("b", time.Hour),
+		snapshot("a", 0),
+		snapshot("c", 2*time.Hour),
+		snapshot("d", 0),
+	}
+	SortNewestFirst(snapshots)
+
+	var names []string
+	for _, s := range snapshots {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"d", "a", "b", "c"}, names)
+}
+
+func TestClusterName(t *testing.T) {
+	assert.Equal(t, "labeled", ClusterName(&rkev1.ETCDSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{capr.ClusterNameLabel: "labeled"}},
+		Spec:       rkev1.ETCDSnapshotSpec{ClusterName: "spec"},
+	}))
+	assert.Equal(t, "spec", ClusterName(&rkev1.ETCDSnapshot{Spec: rkev1.ETCDSnapshotSpec{ClusterName: "spec"}}))
+	assert.Equal(t, "", ClusterName(&rkev1.ETCDSnapshot{}))
+}
+
+func TestRestorePhase(t *testing.T) {
+	cp := &rkev1.RKEControlPlane{
+		Status: rkev1.RKEControlPlaneStatus{
+			ETCDSnapshotRestore:      &rkev1.ETCDSnapshotRestore{Name: "snapshot"},
+			ETCDSnapshotRestorePhase: rkev1.ETCDSnapshotPhaseFinished,
+		},
+	}
+	assert.Equal(t, rkev1.ETCDSnapshotPhaseFinished, RestorePhase(cp, "snapshot"))
+	assert.Equal(t, rkev1.ETCDSnapshotPhase(""), RestorePhase(cp, "other"))
+	assert.Equal(t, rkev1.ETCDSnapshotPhase(""), RestorePhase(&rkev1.RKEControlPlane{}, "snapshot"))
+}
@@ -0,0 +1,112 @@
+package capr
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/data/convert"
+)
+
+// The keys of the extra metadata that is recorded in etcd snapshots, in addition to the provisioning cluster spec.
+const (
+	SnapshotMetadataKubernetesVersion = "kubernetes-version"
+	SnapshotMetadataETCDVersion       = "etcd-version"
+	SnapshotMetadataCNI               = "cni"
+	SnapshotMetadataClusterCIDR       = "cluster-cidr"
+	SnapshotMetadataServiceCIDR       = "service-cidr"
+
+	DefaultClusterCIDR = "10.42.0.0/16"
+	DefaultServiceCIDR = "10.43.0.0/16"
+
+	// DefaultRKE2CNI is the CNI Rancher configures for rke2 clusters that do not set one.
+	DefaultRKE2CNI = "calico"
+	// rke2DistributionCNI is the CNI rke2 deploys if its cni setting is empty.
+	rke2DistributionCNI = "canal"
+)
+
+var kubernetes122 = semver.MustParse("v1.22.0")
+
+// GetETCDVersion returns the minor version of etcd that is embedded in the given Kubernetes version of k3s and rke2, or
+// an empty string if the Kubernetes version cannot be parsed.
+func GetETCDVersion(kubernetesVersion string) string {
+	version, err := semver.NewVersion(kubernetesVersion)
+	if err != nil {
+		return ""
+	}
+	if version.LessThan(kubernetes122) {
+		return "3.4"
+	}
+	return "3.5"
+}
+
+// GetCNI returns the CNI of the cluster of the control plane as a comma separated list. rke2 clusters that do not set a
+// CNI are given the CNI Rancher configures by default, while rke2 clusters that set an empty CNI get canal, which rke2
+// deploys if its cni setting is empty.
+func GetCNI(controlPlane *rkev1.RKEControlPlane) string {
+	if GetRuntime(controlPlane.Spec.KubernetesVersion) == RuntimeK3S {
+		if backend := convert.ToString(controlPlane.Spec.MachineGlobalConfig.Data["flannel-backend"]); backend == "none" {
+			return "none"
+		}
+		return "flannel"
+	}
+	value, ok := controlPlane.Spec.MachineGlobalConfig.Data["cni"]
+	if !ok || value == nil {
+		return DefaultRKE2CNI
+	}
+	var cnis []string
+	for _, cni := range convert.ToStringSlice(value) {
+		for _, cni := range strings.Split(cni, ",") {
+			if cni = strings.TrimSpace(cni); cni != "" {
+				cnis = append(cnis, cni)
+			}
+		}
+	}
+	if len(cnis) == 0 {
+		return rke2DistributionCNI
+	}
+	return strings.Join(cnis, ",")
+}
+
+// SnapshotMetadata returns the metadata of the cluster of the control plane that is recorded in the etcd snapshots of
+// the cluster, so that it can be validated against the cluster a snapshot is restored to.
+func SnapshotMetadata(controlPlane *rkev1.RKEControlPlane) map[string]string {
+	clusterCIDR := convert.ToString(controlPlane.Spec.MachineGlobalConfig.Data["cluster-cidr"])
+	if clusterCIDR == "" {
+		clusterCIDR = DefaultClusterCIDR
+	}
+	serviceCIDR := convert.ToString(controlPlane.Spec.MachineGlobalConfig.Data["service-cidr"])
+	if serviceCIDR == "" {
+		serviceCIDR = DefaultServiceCIDR
+	}
+	return map[string]string{
+		SnapshotMetadataKubernetesVersion: controlPlane.Spec.KubernetesVersion,
+		SnapshotMetadataETCDVersion:       GetETCDVersion(controlPlane.Spec.KubernetesVersion),
+		SnapshotMetadataCNI:               GetCNI(controlPlane),
+		SnapshotMetadataClusterCIDR:       clusterCIDR,
+		SnapshotMetadataServiceCIDR:       serviceCIDR,
+	}
+}
+
+// ParseSnapshotMetadata returns the extra metadata recorded in the etcd snapshot, or an error if the snapshot does not
+// have any.
+func ParseSnapshotMetadata(snapshot *rkev1.ETCDSnapshot) (map[string]string, error) {
+	if snapshot == nil {
+		return nil, fmt.Errorf("snapshot was nil")
+	}
+	if snapshot.SnapshotFile.Metadata == "" {
+		return nil, fmt.Errorf("snapshot %s/%s does not have metadata", snapshot.Namespace, snapshot.Name)
+	}
+	b, err := base64.StdEncoding.DecodeString(snapshot.SnapshotFile.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	var md map[string]string
+	if err := json.Unmarshal(b, &md); err != nil {
+		return nil, err
+	}
+	return md, nil
+}
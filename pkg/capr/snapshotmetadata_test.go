@@ -0,0 +1,71 @@
+package capr
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCNI(t *testing.T) {
+	tests := []struct {
+		name              string
+		kubernetesVersion string
+		config            map[string]interface{}
+		expected          string
+	}{
+		{
+			name:              "rke2 without cni",
+			kubernetesVersion: "v1.26.4+rke2r1",
+			expected:          "calico",
+		},
+		{
+			name:              "rke2 with empty cni",
+			kubernetesVersion: "v1.26.4+rke2r1",
+			config:            map[string]interface{}{"cni": ""},
+			expected:          "canal",
+		},
+		{
+			name:              "rke2 with cni",
+			kubernetesVersion: "v1.26.4+rke2r1",
+			config:            map[string]interface{}{"cni": "cilium"},
+			expected:          "cilium",
+		},
+		{
+			name:              "rke2 with cni list",
+			kubernetesVersion: "v1.26.4+rke2r1",
+			config:            map[string]interface{}{"cni": []interface{}{"multus", "canal"}},
+			expected:          "multus,canal",
+		},
+		{
+			name:              "rke2 with comma separated cni",
+			kubernetesVersion: "v1.26.4+rke2r1",
+			config:            map[string]interface{}{"cni": "multus, calico"},
+			expected:          "multus,calico",
+		},
+		{
+			name:              "k3s",
+			kubernetesVersion: "v1.26.4+k3s1",
+			config:            map[string]interface{}{"cni": "calico"},
+			expected:          "flannel",
+		},
+		{
+			name:              "k3s without flannel",
+			kubernetesVersion: "v1.26.4+k3s1",
+			config:            map[string]interface{}{"flannel-backend": "none"},
+			expected:          "none",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlPlane := &rkev1.RKEControlPlane{
+				Spec: rkev1.RKEControlPlaneSpec{
+					KubernetesVersion: tt.kubernetesVersion,
+				},
+			}
+			controlPlane.Spec.MachineGlobalConfig.Data = tt.config
+			assert.Equal(t, tt.expected, GetCNI(controlPlane))
+		})
+	}
+}
@@ -48,6 +48,7 @@ type desiredKey struct {
 	namespace            string
 	name                 string
 	minVersion           string
+	exactVersion         string
 	installImageOverride string
 }
 
@@ -150,6 +151,7 @@ func (m *Manager) runSync() {
 					desired.key: desired.values,
 				}, desired.forceAdopt)
 				if err == nil {
+					m.removeOtherExactVersions(desired.key)
 					m.desiredCharts[desired.key] = desired.values
 				}
 			}
@@ -169,7 +171,7 @@ func (m *Manager) installCharts(charts map[desiredKey]map[string]interface{}, fo
 	var errs []error
 	for key, values := range charts {
 		for {
-			if err := m.install(key.namespace, key.name, key.minVersion, values, forceAdopt, key.installImageOverride); err == repo.ErrNoChartName || apierrors.IsNotFound(err) {
+			if err := m.install(key.namespace, key.name, key.minVersion, key.exactVersion, values, forceAdopt, key.installImageOverride); err == repo.ErrNoChartName || apierrors.IsNotFound(err) {
 				logrus.Errorf("Failed to find system chart %s will try again in 5 seconds: %v", key.name, err)
 				time.Sleep(5 * time.Second)
 				continue
@@ -227,27 +229,126 @@ func (m *Manager) Ensure(namespace, name, minVersion string, values map[string]i
 	return nil
 }
 
+// EnsureVersion ensures that exactly the given version of the chart is installed into the given namespace with the
+// given values, upgrading or downgrading the release as needed. It replaces the desired state of the chart set by
+// previous calls to Ensure or EnsureVersion with a different version.
+func (m *Manager) EnsureVersion(namespace, name, version string, values map[string]interface{}, forceAdopt bool, installImageOverride string) error {
+	go func() {
+		m.sync <- desired{
+			key: desiredKey{
+				namespace:            namespace,
+				name:                 name,
+				exactVersion:         version,
+				installImageOverride: installImageOverride,
+			},
+			values:     values,
+			forceAdopt: forceAdopt,
+		}
+	}()
+	return nil
+}
+
+// LatestVersion returns the latest version of the chart available in the rancher-charts repository.
+func (m *Manager) LatestVersion(name string) (string, error) {
+	index, err := m.content.Index("", "rancher-charts", true)
+	if err != nil {
+		return "", err
+	}
+
+	chart, err := index.Get(name, ">=0-a")
+	if err != nil {
+		return "", err
+	}
+	return chart.Version, nil
+}
+
+// Values returns the default values of the given version of the chart in the rancher-charts repository, or of its
+// latest version if version is empty.
+func (m *Manager) Values(name, version string) (map[string]interface{}, error) {
+	if version == "" {
+		var err error
+		if version, err = m.LatestVersion(name); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := m.content.Info("", "rancher-charts", name, version)
+	if err != nil {
+		return nil, err
+	}
+	return info.Values, nil
+}
+
+// Installed returns whether a release of the chart is deployed in the given namespace.
+func (m *Manager) Installed(namespace, name string) (bool, error) {
+	return m.hasStatus(namespace, name, action.ListDeployed)
+}
+
+// InstalledVersion returns the chart version of the deployed release of the given chart in the given namespace, or an
+// empty string if no release is deployed.
+func (m *Manager) InstalledVersion(namespace, name string) (string, error) {
+	helmcfg := &action.Configuration{}
+	if err := helmcfg.Init(m.restClientGetter, namespace, "", logrus.Infof); err != nil {
+		return "", err
+	}
+
+	l := action.NewList(helmcfg)
+	l.Filter = "^" + name + "$"
+	l.StateMask = action.ListDeployed
+
+	releases, err := l.Run()
+	if err != nil {
+		return "", err
+	}
+	for _, release := range releases {
+		if release.Chart != nil && release.Chart.Metadata != nil {
+			return release.Chart.Metadata.Version, nil
+		}
+	}
+	return "", nil
+}
+
+// removeOtherExactVersions removes the desired state of the chart of the key that was set for a different exact
+// version, so that the periodic sync does not alternate between the versions.
+func (m *Manager) removeOtherExactVersions(key desiredKey) {
+	for k := range m.desiredCharts {
+		if k.namespace == key.namespace && k.name == key.name && k.exactVersion != key.exactVersion {
+			delete(m.desiredCharts, k)
+		}
+	}
+}
+
 func (m *Manager) Remove(namespace, name, minVersion string) {
 	delete(m.desiredCharts, desiredKey{
 		namespace:  namespace,
 		name:       name,
 		minVersion: minVersion,
 	})
+	// charts ensured with EnsureVersion are keyed by their exact version instead
+	for k := range m.desiredCharts {
+		if k.namespace == namespace && k.name == name && k.exactVersion != "" {
+			delete(m.desiredCharts, k)
+		}
+	}
 }
 
-func (m *Manager) install(namespace, name, minVersion string, values map[string]interface{}, forceAdopt bool, installImageOverride string) error {
+func (m *Manager) install(namespace, name, minVersion, exactVersion string, values map[string]interface{}, forceAdopt bool, installImageOverride string) error {
 	index, err := m.content.Index("", "rancher-charts", true)
 	if err != nil {
 		return err
 	}
 
 	// get latest, the >=0-a is a weird syntax to match everything including prereleases build
-	chart, err := index.Get(name, ">=0-a")
+	versionConstraint := ">=0-a"
+	if exactVersion != "" {
+		versionConstraint = exactVersion
+	}
+	chart, err := index.Get(name, versionConstraint)
 	if err != nil {
 		return err
 	}
 
-	installed, desiredVersion, desiredValue, err := m.isInstalled(namespace, name, chart.Version, minVersion, values)
+	installed, desiredVersion, desiredValue, err := m.isInstalled(namespace, name, chart.Version, minVersion, exactVersion != "", values)
 	if err != nil {
 		return err
 	} else if installed {
@@ -351,8 +452,9 @@ func podDone(chart string, newPod *corev1.Pod) (bool, error) {
 	return false, nil
 }
 
-// isInstalled returns whether the release is installed, if false, it will return the version and values.yaml it should install/upgrade
-func (m *Manager) isInstalled(namespace, name, version, minVersion string, desiredValue map[string]interface{}) (bool, string, map[string]interface{}, error) {
+// isInstalled returns whether the release is installed, if false, it will return the version and values.yaml it should install/upgrade.
+// If exact is true, a release of any version other than the given version is not considered installed.
+func (m *Manager) isInstalled(namespace, name, version, minVersion string, exact bool, desiredValue map[string]interface{}) (bool, string, map[string]interface{}, error) {
 	helmcfg := &action.Configuration{}
 	if err := helmcfg.Init(m.restClientGetter, namespace, "", logrus.Infof); err != nil {
 		return false, "", nil, err
@@ -408,6 +510,13 @@ func (m *Manager) isInstalled(namespace, name, version, minVersion string, desir
 			return false, "", nil, err
 		}
 
+		if exact {
+			if desired.Equal(ver) && bytes.Equal(patchedJSON, actualValueJSON) {
+				return true, "", nil, nil
+			}
+			continue
+		}
+
 		if minVersion != "" {
 			min, err := semver.NewVersion(minVersion)
 			if err != nil {
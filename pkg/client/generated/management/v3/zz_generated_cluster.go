@@ -18,6 +18,7 @@
 	ClusterFieldAllocatable                                          = "allocatable"
 	ClusterFieldAnnotations                                          = "annotations"
 	ClusterFieldAppliedAgentEnvVars                                  = "appliedAgentEnvVars"
+	ClusterFieldAppliedClusterAgentDeploymentCustomization           = "appliedClusterAgentDeploymentCustomization"
 	ClusterFieldAppliedEnableNetworkPolicy                           = "appliedEnableNetworkPolicy"
 	ClusterFieldAppliedPodSecurityPolicyTemplateName                 = "appliedPodSecurityPolicyTemplateId"
 	ClusterFieldAppliedSpec                                          = "appliedSpec"
@@ -26,6 +27,7 @@
 	ClusterFieldCapabilities                                         = "capabilities"
 	ClusterFieldCapacity                                             = "capacity"
 	ClusterFieldCertificatesExpiration                               = "certificatesExpiration"
+	ClusterFieldClusterAgentDeploymentCustomization                  = "clusterAgentDeploymentCustomization"
 	ClusterFieldClusterSecrets                                       = "clusterSecrets"
 	ClusterFieldClusterTemplateAnswers                               = "answers"
 	ClusterFieldClusterTemplateID                                    = "clusterTemplateId"
@@ -50,6 +52,7 @@
 	ClusterFieldEnableClusterMonitoring                              = "enableClusterMonitoring"
 	ClusterFieldEnableNetworkPolicy                                  = "enableNetworkPolicy"
 	ClusterFieldFailedSpec                                           = "failedSpec"
+	ClusterFieldFleetAgentDeploymentCustomization                    = "fleetAgentDeploymentCustomization"
 	ClusterFieldFleetWorkspaceName                                   = "fleetWorkspaceName"
 	ClusterFieldGKEConfig                                            = "gkeConfig"
 	ClusterFieldGKEStatus                                            = "gkeStatus"
@@ -88,6 +91,9 @@
 )
 
 type Cluster struct {
+	AppliedClusterAgentDeploymentCustomization *AgentDeploymentCustomization `json:"appliedClusterAgentDeploymentCustomization,omitempty" yaml:"appliedClusterAgentDeploymentCustomization,omitempty"`
+	ClusterAgentDeploymentCustomization        *AgentDeploymentCustomization `json:"clusterAgentDeploymentCustomization,omitempty" yaml:"clusterAgentDeploymentCustomization,omitempty"`
+	FleetAgentDeploymentCustomization          *AgentDeploymentCustomization `json:"fleetAgentDeploymentCustomization,omitempty" yaml:"fleetAgentDeploymentCustomization,omitempty"`
 	types.Resource
 	AADClientCertSecret                                  string                         `json:"aadClientCertSecret,omitempty" yaml:"aadClientCertSecret,omitempty"`
 	AADClientSecret                                      string                         `json:"aadClientSecret,omitempty" yaml:"aadClientSecret,omitempty"`
@@ -203,6 +209,8 @@ type ClusterOperations interface {
 
 	ActionImportYaml(resource *Cluster, input *ImportClusterYamlInput) (*ImportYamlOutput, error)
 
+	ActionRefreshServiceAccountToken(resource *Cluster) (*RefreshServiceAccountTokenOutput, error)
+
 	ActionRestoreFromEtcdBackup(resource *Cluster, input *RestoreFromEtcdBackupInput) error
 
 	ActionRotateCertificates(resource *Cluster, input *RotateCertificateInput) (*RotateCertificateOutput, error)
@@ -212,6 +220,8 @@ type ClusterOperations interface {
 	ActionSaveAsTemplate(resource *Cluster, input *SaveAsTemplateInput) (*SaveAsTemplateOutput, error)
 
 	ActionViewMonitoring(resource *Cluster) (*MonitoringOutput, error)
+
+	CollectionActionGenerateKubeconfigBundle(resource *ClusterCollection, input *GenerateKubeconfigBundleInput) (*GenerateKubeConfigOutput, error)
 }
 
 func newClusterClient(apiClient *Client) *ClusterClient {
@@ -321,6 +331,12 @@ func (c *ClusterClient) ActionImportYaml(resource *Cluster, input *ImportCluster
 	return resp, err
 }
 
+func (c *ClusterClient) ActionRefreshServiceAccountToken(resource *Cluster) (*RefreshServiceAccountTokenOutput, error) {
+	resp := &RefreshServiceAccountTokenOutput{}
+	err := c.apiClient.Ops.DoAction(ClusterType, "refreshServiceAccountToken", &resource.Resource, nil, resp)
+	return resp, err
+}
+
 func (c *ClusterClient) ActionRestoreFromEtcdBackup(resource *Cluster, input *RestoreFromEtcdBackupInput) error {
 	err := c.apiClient.Ops.DoAction(ClusterType, "restoreFromEtcdBackup", &resource.Resource, input, nil)
 	return err
@@ -349,3 +365,9 @@ func (c *ClusterClient) ActionViewMonitoring(resource *Cluster) (*MonitoringOutp
 	err := c.apiClient.Ops.DoAction(ClusterType, "viewMonitoring", &resource.Resource, nil, resp)
 	return resp, err
 }
+
+func (c *ClusterClient) CollectionActionGenerateKubeconfigBundle(resource *ClusterCollection, input *GenerateKubeconfigBundleInput) (*GenerateKubeConfigOutput, error) {
+	resp := &GenerateKubeConfigOutput{}
+	err := c.apiClient.Ops.DoCollectionAction(ClusterType, "generateKubeconfigBundle", &resource.Collection, input, resp)
+	return resp, err
+}
@@ -0,0 +1,10 @@
+package client
+
+const (
+	GenerateKubeconfigBundleInputType          = "generateKubeconfigBundleInput"
+	GenerateKubeconfigBundleInputFieldAuthMode = "authMode"
+)
+
+type GenerateKubeconfigBundleInput struct {
+	AuthMode string `json:"authMode,omitempty" yaml:"authMode,omitempty"`
+}
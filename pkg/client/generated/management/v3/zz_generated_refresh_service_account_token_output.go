@@ -0,0 +1,10 @@
+package client
+
+const (
+	RefreshServiceAccountTokenOutputType         = "refreshServiceAccountTokenOutput"
+	RefreshServiceAccountTokenOutputFieldMessage = "message"
+)
+
+type RefreshServiceAccountTokenOutput struct {
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
@@ -37,7 +37,6 @@
 )
 
 const (
-	rkeBootstrapName                       = "rke.cattle.io/rkebootstrap-name"
 	capiMachinePreTerminateAnnotation      = "pre-terminate.delete.hook.machine.cluster.x-k8s.io/rke-bootstrap-cleanup"
 	capiMachinePreTerminateAnnotationOwner = "rke-bootstrap-controller"
 )
@@ -88,7 +87,7 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 
 	relatedresource.Watch(ctx, "rke-bootstrap-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
 		if sa, ok := obj.(*corev1.ServiceAccount); ok {
-			if name, ok := sa.Labels[rkeBootstrapName]; ok {
+			if name, ok := sa.Labels[capr.RKEBootstrapNameLabel]; ok {
 				return []relatedresource.Key{
 					{
 						Namespace: sa.Namespace,
@@ -105,8 +104,25 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 				}}, nil
 			}
 		}
+		if planSecret, ok := obj.(*corev1.Secret); ok && planSecret.Type == capr.SecretTypeMachinePlan {
+			// plan secrets are updated by the system agent on every plan application, only enqueue the bootstrap
+			// when the server the machine joined through changed.
+			for _, owner := range planSecret.OwnerReferences {
+				if owner.Kind != "RKEBootstrap" {
+					continue
+				}
+				bootstrap, err := h.rkeBootstrap.Cache().Get(planSecret.Namespace, owner.Name)
+				if err != nil || bootstrap.Status.JoinedTo == planSecret.Annotations[capr.JoinedToAnnotation] {
+					return nil, nil
+				}
+				return []relatedresource.Key{{
+					Namespace: bootstrap.Namespace,
+					Name:      bootstrap.Name,
+				}}, nil
+			}
+		}
 		return nil, nil
-	}, clients.RKE.RKEBootstrap(), clients.Core.ServiceAccount(), clients.CAPI.Machine())
+	}, clients.RKE.RKEBootstrap(), clients.Core.ServiceAccount(), clients.CAPI.Machine(), clients.Core.Secret())
 }
 
 func (h *handler) getBootstrapSecret(namespace, name string, envVars []corev1.EnvVar, machine *capi.Machine) (*corev1.Secret, error) {
@@ -146,7 +162,7 @@ func (h *handler) getBootstrapSecret(namespace, name string, envVars []corev1.En
 		Data: map[string][]byte{
 			"value": data,
 		},
-		Type: "rke.cattle.io/bootstrap",
+		Type: capr.SecretTypeBootstrap,
 	}, nil
 }
 
@@ -159,10 +175,10 @@ func (h *handler) assignPlanSecret(machine *capi.Machine, bootstrap *rkev1.RKEBo
 			Name:      secretName,
 			Namespace: bootstrap.Namespace,
 			Labels: map[string]string{
-				capr.MachineNameLabel: machine.Name,
-				rkeBootstrapName:      bootstrap.Name,
-				capr.RoleLabel:        capr.RolePlan,
-				capr.PlanSecret:       secretName,
+				capr.MachineNameLabel:      machine.Name,
+				capr.RKEBootstrapNameLabel: bootstrap.Name,
+				capr.RoleLabel:             capr.RolePlan,
+				capr.PlanSecret:            secretName,
 			},
 		},
 	}
@@ -252,9 +268,9 @@ func (h *handler) assignBootStrapSecret(machine *capi.Machine, bootstrap *rkev1.
 			Name:      secretName,
 			Namespace: bootstrap.Namespace,
 			Labels: map[string]string{
-				capr.MachineNameLabel: machine.Name,
-				rkeBootstrapName:      bootstrap.Name,
-				capr.RoleLabel:        capr.RoleBootstrap,
+				capr.MachineNameLabel:      machine.Name,
+				capr.RKEBootstrapNameLabel: bootstrap.Name,
+				capr.RoleLabel:             capr.RoleBootstrap,
 			},
 		},
 	}
@@ -349,6 +365,10 @@ func (h *handler) GeneratingHandler(bootstrap *rkev1.RKEBootstrap, status rkev1.
 		return nil, status, err
 	}
 
+	if status.JoinedTo, err = h.getJoinedTo(bootstrap); err != nil {
+		return nil, status, err
+	}
+
 	if bootstrapSecret != nil {
 		if status.DataSecretName == nil {
 			status.DataSecretName = &bootstrapSecret.Name
@@ -362,6 +382,18 @@ func (h *handler) GeneratingHandler(bootstrap *rkev1.RKEBootstrap, status rkev1.
 	return result, status, nil
 }
 
+// getJoinedTo returns the server that the machine of the bootstrap joined the cluster through, which the planner
+// records on the plan secret of the machine whenever it assigns a plan.
+func (h *handler) getJoinedTo(bootstrap *rkev1.RKEBootstrap) (string, error) {
+	planSecret, err := h.secretCache.Get(bootstrap.Namespace, capr.PlanSecretFromBootstrapName(bootstrap.Name))
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return planSecret.Annotations[capr.JoinedToAnnotation], nil
+}
+
 func (h *handler) rancherDeploymentHasHostPort() (bool, error) {
 	deployment, err := h.deploymentCache.Get(namespace.System, "rancher")
 	if err != nil {
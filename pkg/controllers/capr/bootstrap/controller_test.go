@@ -6,6 +6,7 @@
 	"fmt"
 	"testing"
 
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/capr"
 	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
 	"github.com/rancher/rancher/pkg/namespace"
@@ -16,6 +17,7 @@
 	"github.com/stretchr/testify/mock"
 	v1apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes/fake"
@@ -281,3 +283,26 @@ func getServiceAccountCacheMock(namespace, name string) *serviceAccountCacheMock
 	}, nil)
 	return mockServiceAccountCache
 }
+
+func Test_getJoinedTo(t *testing.T) {
+	bootstrap := &rkev1.RKEBootstrap{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "machine-bootstrap"}}
+	planSecretName := capr.PlanSecretFromBootstrapName(bootstrap.Name)
+
+	mockSecretCache := new(secretCacheMock)
+	mockSecretCache.On("Get", "fleet-default", planSecretName).Return(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        planSecretName,
+			Annotations: map[string]string{capr.JoinedToAnnotation: "https://10.0.0.1:9345"},
+		},
+	}, nil).Once()
+	mockSecretCache.On("Get", "fleet-default", planSecretName).Return((*v1.Secret)(nil), apierrors.NewNotFound(v1.Resource("secrets"), planSecretName)).Once()
+	h := handler{secretCache: mockSecretCache}
+
+	joinedTo, err := h.getJoinedTo(bootstrap)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://10.0.0.1:9345", joinedTo)
+
+	joinedTo, err = h.getJoinedTo(bootstrap)
+	assert.NoError(t, err)
+	assert.Empty(t, joinedTo)
+}
@@ -7,15 +7,24 @@
 	"github.com/rancher/rancher/pkg/capr/planner"
 	"github.com/rancher/rancher/pkg/controllers/capr/bootstrap"
 	"github.com/rancher/rancher/pkg/controllers/capr/dynamicschema"
+	"github.com/rancher/rancher/pkg/controllers/capr/machinedeletionhook"
 	"github.com/rancher/rancher/pkg/controllers/capr/machinedrain"
+	"github.com/rancher/rancher/pkg/controllers/capr/machinegc"
 	"github.com/rancher/rancher/pkg/controllers/capr/machinenodelookup"
 	"github.com/rancher/rancher/pkg/controllers/capr/machineprovision"
 	"github.com/rancher/rancher/pkg/controllers/capr/managesystemagent"
+	"github.com/rancher/rancher/pkg/controllers/capr/nodecommand"
 	plannercontroller "github.com/rancher/rancher/pkg/controllers/capr/planner"
 	"github.com/rancher/rancher/pkg/controllers/capr/plansecret"
 	"github.com/rancher/rancher/pkg/controllers/capr/rkecluster"
 	"github.com/rancher/rancher/pkg/controllers/capr/rkecontrolplane"
+	"github.com/rancher/rancher/pkg/controllers/capr/sanityprobe"
+	"github.com/rancher/rancher/pkg/controllers/capr/snapshotdrill"
+	"github.com/rancher/rancher/pkg/controllers/capr/snapshotprotection"
+	"github.com/rancher/rancher/pkg/controllers/capr/snapshotretention"
+	"github.com/rancher/rancher/pkg/controllers/capr/snapshotstaleness"
 	"github.com/rancher/rancher/pkg/controllers/capr/unmanaged"
+	"github.com/rancher/rancher/pkg/controllers/capr/versionchannel"
 	"github.com/rancher/rancher/pkg/features"
 	"github.com/rancher/rancher/pkg/provisioningv2/image"
 	"github.com/rancher/rancher/pkg/provisioningv2/kubeconfig"
@@ -35,6 +44,7 @@ func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager
 	}
 	rkecluster.Register(ctx, clients)
 	bootstrap.Register(ctx, clients)
+	machinegc.Register(ctx, clients)
 	machinenodelookup.Register(ctx, clients, kubeconfigManager)
 	plannercontroller.Register(ctx, clients, rkePlanner)
 	plansecret.Register(ctx, clients)
@@ -42,4 +52,12 @@ func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager
 	rkecontrolplane.Register(ctx, clients)
 	managesystemagent.Register(ctx, clients)
 	machinedrain.Register(ctx, clients)
+	machinedeletionhook.Register(ctx, clients)
+	snapshotprotection.Register(ctx, clients)
+	snapshotretention.Register(ctx, clients)
+	snapshotstaleness.Register(ctx, clients)
+	snapshotdrill.Register(ctx, clients, kubeconfigManager)
+	sanityprobe.Register(ctx, clients, kubeconfigManager)
+	nodecommand.Register(ctx, clients)
+	versionchannel.Register(ctx, clients)
 }
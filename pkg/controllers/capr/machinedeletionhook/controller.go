@@ -0,0 +1,469 @@
+package machinedeletionhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// preDrainHookAnnotation keeps CAPI from draining and deleting a machine until the deletion hooks of the machine ran.
+	preDrainHookAnnotation = "pre-drain.delete.hook.machine.cluster.x-k8s.io/rancher-machine-deletion-hooks"
+	preDrainHookOwner      = "rancher-machine-deletion-hooks"
+
+	defaultTimeout     = 5 * time.Minute
+	httpRequestTimeout = 30 * time.Second
+	minRetryInterval   = 10 * time.Second
+	maxRetryInterval   = 2 * time.Minute
+	maxResponseBytes   = 1024
+)
+
+// hookState is the progress of a deletion hook of a deleting machine.
+type hookState struct {
+	StartTime   time.Time `json:"startTime"`
+	NextAttempt time.Time `json:"nextAttempt,omitempty"`
+	Attempts    int       `json:"attempts,omitempty"`
+	Done        bool      `json:"done,omitempty"`
+	Message     string    `json:"message,omitempty"`
+}
+
+// payload is posted to the URL of HTTP deletion hooks.
+type payload struct {
+	Namespace   string                `json:"namespace"`
+	ClusterName string                `json:"clusterName"`
+	MachineName string                `json:"machineName"`
+	NodeName    string                `json:"nodeName,omitempty"`
+	Roles       []string              `json:"roles,omitempty"`
+	Addresses   []capi.MachineAddress `json:"addresses,omitempty"`
+}
+
+type handler struct {
+	machines         capicontrollers.MachineController
+	controlPlanes    rkecontroller.RKEControlPlaneCache
+	nodeCommands     rkecontroller.NodeCommandClient
+	nodeCommandCache rkecontroller.NodeCommandCache
+	secretCache      corecontrollers.SecretCache
+	allowedHosts     func() string
+}
+
+// Register starts the controller that runs the machine deletion hooks of a cluster before its machines are drained and
+// deleted. Machines of clusters with deletion hooks carry a CAPI pre-drain hook annotation, which is removed once all
+// hooks of a deleting machine succeeded or timed out.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		machines:         clients.CAPI.Machine(),
+		controlPlanes:    clients.RKE.RKEControlPlane().Cache(),
+		nodeCommands:     clients.RKE.NodeCommand(),
+		nodeCommandCache: clients.RKE.NodeCommand().Cache(),
+		secretCache:      clients.Core.Secret().Cache(),
+		allowedHosts:     settings.MachineDeletionHookAllowedHosts.Get,
+	}
+
+	clients.CAPI.Machine().OnChange(ctx, "machine-deletion-hooks", h.OnChange)
+	machineCache := clients.CAPI.Machine().Cache()
+	relatedresource.Watch(ctx, "machine-deletion-hooks-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		switch obj := obj.(type) {
+		case *rkev1.NodeCommand:
+			if machineName := obj.Labels[capr.MachineNameLabel]; machineName != "" {
+				return []relatedresource.Key{{Namespace: namespace, Name: machineName}}, nil
+			}
+		case *rkev1.RKEControlPlane:
+			machines, err := machineCache.List(namespace, labels.SelectorFromSet(labels.Set{capi.ClusterLabelName: name}))
+			if err != nil {
+				return nil, err
+			}
+			keys := make([]relatedresource.Key, 0, len(machines))
+			for _, machine := range machines {
+				keys = append(keys, relatedresource.Key{Namespace: namespace, Name: machine.Name})
+			}
+			return keys, nil
+		}
+		return nil, nil
+	}, clients.CAPI.Machine(), clients.RKE.NodeCommand(), clients.RKE.RKEControlPlane())
+}
+
+// OnChange adds the pre-drain hook annotation to the machines of clusters with deletion hooks, and runs the hooks of
+// deleting machines that carry the annotation.
+func (h *handler) OnChange(_ string, machine *capi.Machine) (*capi.Machine, error) {
+	if machine == nil {
+		return nil, nil
+	}
+	clusterName := machine.Labels[capi.ClusterLabelName]
+	if clusterName == "" {
+		return machine, nil
+	}
+	cp, err := h.controlPlanes.Get(machine.Namespace, clusterName)
+	if apierrors.IsNotFound(err) {
+		cp = nil
+	} else if err != nil {
+		return machine, err
+	}
+	var hooks []rkev1.MachineDeletionHook
+	if cp != nil {
+		hooks = cp.Spec.MachineDeletionHooks
+	}
+
+	if machine.DeletionTimestamp == nil {
+		return h.setPreDrainHook(machine, len(hooks) > 0)
+	}
+	if _, ok := machine.Annotations[preDrainHookAnnotation]; !ok {
+		return machine, nil
+	}
+	return h.runHooks(machine, cp, hooks, time.Now())
+}
+
+func (h *handler) setPreDrainHook(machine *capi.Machine, enabled bool) (*capi.Machine, error) {
+	if _, ok := machine.Annotations[preDrainHookAnnotation]; ok == enabled {
+		return machine, nil
+	}
+	machine = machine.DeepCopy()
+	if enabled {
+		if machine.Annotations == nil {
+			machine.Annotations = map[string]string{}
+		}
+		machine.Annotations[preDrainHookAnnotation] = preDrainHookOwner
+	} else {
+		delete(machine.Annotations, preDrainHookAnnotation)
+	}
+	return h.machines.Update(machine)
+}
+
+// runHooks runs the hooks of the deleting machine one after the other. A hook that neither succeeded nor timed out is
+// retried with a backoff, and the later hooks wait for it. Once all hooks are done, the pre-drain hook annotation is
+// removed so that CAPI continues the deletion of the machine.
+func (h *handler) runHooks(machine *capi.Machine, cp *rkev1.RKEControlPlane, hooks []rkev1.MachineDeletionHook, now time.Time) (*capi.Machine, error) {
+	states := map[string]hookState{}
+	if value := machine.Annotations[capr.MachineDeletionHooksAnnotation]; value != "" {
+		if err := json.Unmarshal([]byte(value), &states); err != nil {
+			logrus.Warnf("[machinedeletionhook] machine %s/%s: ignoring invalid deletion hook state: %v", machine.Namespace, machine.Name, err)
+			states = map[string]hookState{}
+		}
+	}
+
+	for _, hook := range hooks {
+		state := states[hook.Name]
+		if state.Done {
+			continue
+		}
+		if state.StartTime.IsZero() {
+			state.StartTime = now
+		}
+		if now.Before(state.NextAttempt) {
+			h.machines.EnqueueAfter(machine.Namespace, machine.Name, state.NextAttempt.Sub(now))
+			return machine, nil
+		}
+
+		if hook.Command != nil && cp.DeletionTimestamp != nil {
+			state.Done = true
+			state.Message = "skipped as the cluster is deleting"
+		} else {
+			done, err := h.runHook(machine, cp, hook)
+			state = recordAttempt(state, hook, now, done, err)
+		}
+		states[hook.Name] = state
+		if !state.Done {
+			machine, err := h.updateStates(machine, states, false)
+			if err != nil {
+				return machine, err
+			}
+			h.machines.EnqueueAfter(machine.Namespace, machine.Name, retryInterval(state.Attempts))
+			return machine, nil
+		}
+		logrus.Infof("[machinedeletionhook] machine %s/%s: deletion hook %s done: %s", machine.Namespace, machine.Name, hook.Name, state.Message)
+	}
+	return h.updateStates(machine, states, true)
+}
+
+// recordAttempt returns the state of the hook after an attempt that either finished, is still in progress, or failed
+// with the passed in error. Once the timeout of the hook expired, a hook with the Ignore failure policy is done.
+func recordAttempt(state hookState, hook rkev1.MachineDeletionHook, now time.Time, done bool, err error) hookState {
+	switch {
+	case err != nil:
+		state.Attempts++
+		state.Message = err.Error()
+		state.NextAttempt = now.Add(retryInterval(state.Attempts))
+	case done:
+		state.Done = true
+		state.Message = "succeeded"
+		state.NextAttempt = time.Time{}
+		return state
+	default:
+		state.Message = "running"
+	}
+
+	timeout := defaultTimeout
+	if hook.TimeoutSeconds != nil {
+		timeout = time.Duration(*hook.TimeoutSeconds) * time.Second
+	}
+	if now.Sub(state.StartTime) >= timeout && hook.FailurePolicy != rkev1.MachineDeletionHookFailurePolicyFail {
+		state.Done = true
+		state.Message = fmt.Sprintf("timed out after %s and %d failed attempts, ignoring: %s", timeout, state.Attempts, state.Message)
+		state.NextAttempt = time.Time{}
+	}
+	return state
+}
+
+// retryInterval doubles the interval between attempts of a hook with every failed attempt, up to a maximum.
+func retryInterval(attempts int) time.Duration {
+	interval := minRetryInterval
+	for i := 1; i < attempts && interval < maxRetryInterval; i++ {
+		interval *= 2
+	}
+	if interval > maxRetryInterval {
+		return maxRetryInterval
+	}
+	return interval
+}
+
+func (h *handler) updateStates(machine *capi.Machine, states map[string]hookState, finished bool) (*capi.Machine, error) {
+	data, err := json.Marshal(states)
+	if err != nil {
+		return machine, err
+	}
+	_, hasPreDrainHook := machine.Annotations[preDrainHookAnnotation]
+	if machine.Annotations[capr.MachineDeletionHooksAnnotation] == string(data) && (!finished || !hasPreDrainHook) {
+		return machine, nil
+	}
+	machine = machine.DeepCopy()
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	machine.Annotations[capr.MachineDeletionHooksAnnotation] = string(data)
+	if finished {
+		delete(machine.Annotations, preDrainHookAnnotation)
+	}
+	return h.machines.Update(machine)
+}
+
+// runHook runs one attempt of the hook. It returns true once the hook succeeded, and false without an error while the
+// node command of a command hook is still running.
+func (h *handler) runHook(machine *capi.Machine, cp *rkev1.RKEControlPlane, hook rkev1.MachineDeletionHook) (bool, error) {
+	if err := validateHook(hook); err != nil {
+		return false, err
+	}
+	if hook.HTTP != nil {
+		return true, h.callHTTP(machine, hook)
+	}
+	return h.runCommand(machine, cp, hook)
+}
+
+func validateHook(hook rkev1.MachineDeletionHook) error {
+	if (hook.HTTP == nil) == (hook.Command == nil) {
+		return fmt.Errorf("exactly one of http and command must be set")
+	}
+	if hook.HTTP != nil && !strings.HasPrefix(hook.HTTP.URL, "https://") && !strings.HasPrefix(hook.HTTP.URL, "http://") {
+		return fmt.Errorf("url %q must be an http or https URL", hook.HTTP.URL)
+	}
+	if hook.Command != nil && hook.Command.Command == "" {
+		return fmt.Errorf("command must be set")
+	}
+	if hook.TimeoutSeconds != nil && *hook.TimeoutSeconds <= 0 {
+		return fmt.Errorf("timeoutSeconds must be positive")
+	}
+	switch hook.FailurePolicy {
+	case "", rkev1.MachineDeletionHookFailurePolicyIgnore, rkev1.MachineDeletionHookFailurePolicyFail:
+		return nil
+	}
+	return fmt.Errorf("failurePolicy must be %s or %s", rkev1.MachineDeletionHookFailurePolicyIgnore, rkev1.MachineDeletionHookFailurePolicyFail)
+}
+
+// callHTTP posts the machine to the URL of the hook and returns an error unless the response has a 2xx status. The host
+// of the URL must be allowed by the machine-deletion-hook-allowed-hosts setting, and redirects are not followed, so
+// that hooks can not be used to reach other hosts from Rancher.
+func (h *handler) callHTTP(machine *capi.Machine, hook rkev1.MachineDeletionHook) error {
+	if err := checkHost(hook.HTTP.URL, h.allowedHosts()); err != nil {
+		return err
+	}
+	body, err := json.Marshal(machinePayload(machine))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, hook.HTTP.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if hook.HTTP.TokenSecretName != "" {
+		token, err := h.token(machine, hook)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client, err := httpClient(hook.HTTP.CABundle)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+		return fmt.Errorf("%s returned %s: %s", hook.HTTP.URL, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// token returns the token of the token secret of the hook. Only secrets that are labeled for the hook and the cluster of
+// the machine are used, so that a hook can not send arbitrary secrets of the namespace to its URL.
+func (h *handler) token(machine *capi.Machine, hook rkev1.MachineDeletionHook) (string, error) {
+	secret, err := h.secretCache.Get(machine.Namespace, hook.HTTP.TokenSecretName)
+	if err != nil {
+		return "", err
+	}
+	clusterName := machine.Labels[capi.ClusterLabelName]
+	if secret.Labels[capr.ClusterNameLabel] != clusterName || secret.Labels[capr.MachineDeletionHookLabel] != hook.Name {
+		return "", fmt.Errorf("secret %s/%s must be labeled %s=%s and %s=%s to be used by deletion hook %s", machine.Namespace, hook.HTTP.TokenSecretName,
+			capr.ClusterNameLabel, clusterName, capr.MachineDeletionHookLabel, hook.Name, hook.Name)
+	}
+	token := strings.TrimSpace(string(secret.Data["token"]))
+	if token == "" {
+		return "", fmt.Errorf("secret %s/%s has no token", machine.Namespace, hook.HTTP.TokenSecretName)
+	}
+	return token, nil
+}
+
+// checkHost returns an error unless the host of the URL matches one of the comma separated path.Match patterns of the
+// allowed hosts.
+func checkHost(rawURL, allowedHosts string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range strings.Split(allowedHosts, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, host); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s is not allowed by the %s setting", host, settings.MachineDeletionHookAllowedHosts.Name)
+}
+
+func httpClient(caBundle string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caBundle != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(caBundle)) {
+			return nil, fmt.Errorf("caBundle contains no valid certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   httpRequestTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
+
+func machinePayload(machine *capi.Machine) payload {
+	p := payload{
+		Namespace:   machine.Namespace,
+		ClusterName: machine.Labels[capi.ClusterLabelName],
+		MachineName: machine.Name,
+		Addresses:   machine.Status.Addresses,
+	}
+	if machine.Status.NodeRef != nil {
+		p.NodeName = machine.Status.NodeRef.Name
+	}
+	for _, role := range []struct {
+		label, name string
+	}{
+		{capr.EtcdRoleLabel, "etcd"},
+		{capr.ControlPlaneRoleLabel, "control-plane"},
+		{capr.WorkerRoleLabel, "worker"},
+	} {
+		if machine.Labels[role.label] == "true" {
+			p.Roles = append(p.Roles, role.name)
+		}
+	}
+	return p
+}
+
+// runCommand hands the command of the hook to the planner of the cluster as a node command. A failed node command is
+// deleted, so that it is created again on the next attempt.
+func (h *handler) runCommand(machine *capi.Machine, cp *rkev1.RKEControlPlane, hook rkev1.MachineDeletionHook) (bool, error) {
+	command := deletionHookCommand(machine, cp, hook)
+	existing, err := h.nodeCommandCache.Get(command.Namespace, command.Name)
+	if apierrors.IsNotFound(err) {
+		_, err = h.nodeCommands.Create(command)
+		return false, err
+	} else if err != nil {
+		return false, err
+	}
+
+	switch existing.Status.Phase {
+	case rkev1.NodeCommandPhaseSucceeded:
+		return true, nil
+	case rkev1.NodeCommandPhaseFailed:
+		if err := h.nodeCommands.Delete(existing.Namespace, existing.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		return false, fmt.Errorf("node command %s failed: %s", existing.Name, existing.Status.Message)
+	}
+	return false, nil
+}
+
+func deletionHookCommand(machine *capi.Machine, cp *rkev1.RKEControlPlane, hook rkev1.MachineDeletionHook) *rkev1.NodeCommand {
+	nodeName := ""
+	if machine.Status.NodeRef != nil {
+		nodeName = machine.Status.NodeRef.Name
+	}
+	replacer := strings.NewReplacer("$(MACHINE_NAME)", machine.Name, "$(NODE_NAME)", nodeName)
+	args := make([]string, 0, len(hook.Command.Args))
+	for _, arg := range hook.Command.Args {
+		args = append(args, replacer.Replace(arg))
+	}
+	return &rkev1.NodeCommand{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name.SafeConcatName(machine.Name, "deletion-hook", hook.Name),
+			Namespace: machine.Namespace,
+			Labels: map[string]string{
+				capr.ClusterNameLabel: cp.Name,
+				capr.MachineNameLabel: machine.Name,
+			},
+		},
+		Spec: rkev1.NodeCommandSpec{
+			ClusterName:     cp.Name,
+			MachineSelector: hook.Command.MachineSelector,
+			Command:         hook.Command.Command,
+			Args:            args,
+		},
+	}
+}
@@ -0,0 +1,306 @@
+package machinedeletionhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+type secretCacheMock struct {
+	corecontrollers.SecretCache
+	secrets map[string]*corev1.Secret
+}
+
+func (m *secretCacheMock) Get(_, name string) (*corev1.Secret, error) {
+	if secret, ok := m.secrets[name]; ok {
+		return secret, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func testMachine() *capi.Machine {
+	return &capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "machine",
+			Namespace: "fleet-default",
+			Labels: map[string]string{
+				capi.ClusterLabelName:      "cluster",
+				capr.ControlPlaneRoleLabel: "true",
+				capr.WorkerRoleLabel:       "true",
+			},
+		},
+		Status: capi.MachineStatus{
+			NodeRef:   &corev1.ObjectReference{Name: "node"},
+			Addresses: capi.MachineAddresses{{Type: capi.MachineInternalIP, Address: "10.0.0.1"}},
+		},
+	}
+}
+
+func TestRecordAttempt(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeout := int64(60)
+	tests := []struct {
+		name     string
+		state    hookState
+		hook     rkev1.MachineDeletionHook
+		now      time.Time
+		done     bool
+		err      error
+		expected hookState
+	}{
+		{
+			name:     "succeeded",
+			state:    hookState{StartTime: start, Attempts: 1, NextAttempt: start.Add(10 * time.Second)},
+			now:      start.Add(10 * time.Second),
+			done:     true,
+			expected: hookState{StartTime: start, Attempts: 1, Done: true, Message: "succeeded"},
+		},
+		{
+			name:     "running",
+			state:    hookState{StartTime: start},
+			now:      start,
+			expected: hookState{StartTime: start, Message: "running"},
+		},
+		{
+			name:     "failed",
+			state:    hookState{StartTime: start, Attempts: 1},
+			now:      start.Add(10 * time.Second),
+			err:      errors.New("connection refused"),
+			expected: hookState{StartTime: start, Attempts: 2, NextAttempt: start.Add(30 * time.Second), Message: "connection refused"},
+		},
+		{
+			name:     "timed out and ignored",
+			state:    hookState{StartTime: start, Attempts: 2},
+			hook:     rkev1.MachineDeletionHook{TimeoutSeconds: &timeout},
+			now:      start.Add(time.Minute),
+			err:      errors.New("connection refused"),
+			expected: hookState{StartTime: start, Attempts: 3, Done: true, Message: "timed out after 1m0s and 3 failed attempts, ignoring: connection refused"},
+		},
+		{
+			name:     "timed out and failing",
+			state:    hookState{StartTime: start, Attempts: 2},
+			hook:     rkev1.MachineDeletionHook{TimeoutSeconds: &timeout, FailurePolicy: rkev1.MachineDeletionHookFailurePolicyFail},
+			now:      start.Add(time.Minute),
+			err:      errors.New("connection refused"),
+			expected: hookState{StartTime: start, Attempts: 3, NextAttempt: start.Add(time.Minute + 40*time.Second), Message: "connection refused"},
+		},
+		{
+			name:     "default timeout not expired",
+			state:    hookState{StartTime: start},
+			now:      start.Add(4 * time.Minute),
+			err:      errors.New("connection refused"),
+			expected: hookState{StartTime: start, Attempts: 1, NextAttempt: start.Add(4*time.Minute + 10*time.Second), Message: "connection refused"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, recordAttempt(tt.state, tt.hook, tt.now, tt.done, tt.err))
+		})
+	}
+}
+
+func TestRetryInterval(t *testing.T) {
+	assert.Equal(t, 10*time.Second, retryInterval(0))
+	assert.Equal(t, 10*time.Second, retryInterval(1))
+	assert.Equal(t, 20*time.Second, retryInterval(2))
+	assert.Equal(t, 80*time.Second, retryInterval(4))
+	assert.Equal(t, 2*time.Minute, retryInterval(5))
+	assert.Equal(t, 2*time.Minute, retryInterval(50))
+}
+
+func TestValidateHook(t *testing.T) {
+	zero := int64(0)
+	tests := []struct {
+		name    string
+		hook    rkev1.MachineDeletionHook
+		wantErr bool
+	}{
+		{
+			name: "http",
+			hook: rkev1.MachineDeletionHook{Name: "cmdb", HTTP: &rkev1.MachineDeletionHookHTTP{URL: "https://cmdb.example.com/deregister"}},
+		},
+		{
+			name: "command",
+			hook: rkev1.MachineDeletionHook{Name: "lb", Command: &rkev1.MachineDeletionHookCommand{Command: "deregister"}, FailurePolicy: rkev1.MachineDeletionHookFailurePolicyFail},
+		},
+		{
+			name:    "neither",
+			hook:    rkev1.MachineDeletionHook{Name: "none"},
+			wantErr: true,
+		},
+		{
+			name: "both",
+			hook: rkev1.MachineDeletionHook{
+				Name:    "both",
+				HTTP:    &rkev1.MachineDeletionHookHTTP{URL: "https://cmdb.example.com"},
+				Command: &rkev1.MachineDeletionHookCommand{Command: "deregister"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "not an http url",
+			hook:    rkev1.MachineDeletionHook{Name: "cmdb", HTTP: &rkev1.MachineDeletionHookHTTP{URL: "file:///etc/passwd"}},
+			wantErr: true,
+		},
+		{
+			name:    "zero timeout",
+			hook:    rkev1.MachineDeletionHook{Name: "cmdb", HTTP: &rkev1.MachineDeletionHookHTTP{URL: "https://cmdb.example.com"}, TimeoutSeconds: &zero},
+			wantErr: true,
+		},
+		{
+			name:    "unknown failure policy",
+			hook:    rkev1.MachineDeletionHook{Name: "cmdb", HTTP: &rkev1.MachineDeletionHookHTTP{URL: "https://cmdb.example.com"}, FailurePolicy: "Retry"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateHook(tt.hook)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCallHTTP(t *testing.T) {
+	var received payload
+	var authorization string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		authorization = req.Header.Get("Authorization")
+		_ = json.NewDecoder(req.Body).Decode(&received)
+		rw.WriteHeader(status)
+		_, _ = rw.Write([]byte("unknown node"))
+	}))
+	defer server.Close()
+
+	labels := func(clusterName, hookName string) map[string]string {
+		return map[string]string{capr.ClusterNameLabel: clusterName, capr.MachineDeletionHookLabel: hookName}
+	}
+	h := &handler{
+		secretCache: &secretCacheMock{
+			secrets: map[string]*corev1.Secret{
+				"cmdb-token":    {ObjectMeta: metav1.ObjectMeta{Labels: labels("cluster", "cmdb")}, Data: map[string][]byte{"token": []byte("secret\n")}},
+				"other-hook":    {ObjectMeta: metav1.ObjectMeta{Labels: labels("cluster", "lb")}, Data: map[string][]byte{"token": []byte("secret")}},
+				"other-cluster": {ObjectMeta: metav1.ObjectMeta{Labels: labels("other", "cmdb")}, Data: map[string][]byte{"token": []byte("secret")}},
+				"unlabeled":     {Data: map[string][]byte{"token": []byte("secret")}},
+			},
+		},
+		allowedHosts: func() string { return "cmdb.example.com, 127.0.0.1" },
+	}
+	hook := func(url, tokenSecretName string) rkev1.MachineDeletionHook {
+		return rkev1.MachineDeletionHook{Name: "cmdb", HTTP: &rkev1.MachineDeletionHookHTTP{URL: url, TokenSecretName: tokenSecretName}}
+	}
+
+	err := h.callHTTP(testMachine(), hook(server.URL, "cmdb-token"))
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", authorization)
+	assert.Equal(t, payload{
+		Namespace:   "fleet-default",
+		ClusterName: "cluster",
+		MachineName: "machine",
+		NodeName:    "node",
+		Roles:       []string{"control-plane", "worker"},
+		Addresses:   []capi.MachineAddress{{Type: capi.MachineInternalIP, Address: "10.0.0.1"}},
+	}, received)
+
+	status = http.StatusNotFound
+	err = h.callHTTP(testMachine(), hook(server.URL, ""))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found: unknown node")
+	assert.Empty(t, authorization)
+
+	status = http.StatusFound
+	err = h.callHTTP(testMachine(), hook(server.URL, ""))
+	assert.ErrorContains(t, err, "302 Found", "redirects are not followed")
+
+	err = h.callHTTP(testMachine(), hook(server.URL, "missing"))
+	assert.Error(t, err)
+
+	for _, secretName := range []string{"other-hook", "other-cluster", "unlabeled"} {
+		authorization = ""
+		err = h.callHTTP(testMachine(), hook(server.URL, secretName))
+		assert.ErrorContains(t, err, "must be labeled rke.cattle.io/cluster-name=cluster and rke.cattle.io/machine-deletion-hook=cmdb to be used by deletion hook cmdb")
+		assert.Empty(t, authorization, "secrets of other hooks and clusters are not sent")
+	}
+
+	err = h.callHTTP(testMachine(), hook("http://169.254.169.254/latest/meta-data", ""))
+	assert.EqualError(t, err, "host 169.254.169.254 is not allowed by the machine-deletion-hook-allowed-hosts setting")
+}
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		allowedHosts string
+		wantErr      bool
+	}{
+		{
+			name:         "allowed host",
+			url:          "https://cmdb.example.com/deregister",
+			allowedHosts: "cmdb.example.com",
+		},
+		{
+			name:         "allowed by pattern with port",
+			url:          "https://CMDB.example.com:8443/deregister",
+			allowedHosts: "lb.example.org, *.example.com",
+		},
+		{
+			name:         "not allowed",
+			url:          "https://cmdb.example.org",
+			allowedHosts: "*.example.com",
+			wantErr:      true,
+		},
+		{
+			name:    "no allowed hosts",
+			url:     "https://cmdb.example.com",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHost(tt.url, tt.allowedHosts)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeletionHookCommand(t *testing.T) {
+	cp := &rkev1.RKEControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "fleet-default"}}
+	hook := rkev1.MachineDeletionHook{
+		Name: "lb",
+		Command: &rkev1.MachineDeletionHookCommand{
+			MachineSelector: &metav1.LabelSelector{MatchLabels: map[string]string{capr.ControlPlaneRoleLabel: "true"}},
+			Command:         "deregister",
+			Args:            []string{"--machine=$(MACHINE_NAME)", "--node", "$(NODE_NAME)"},
+		},
+	}
+
+	command := deletionHookCommand(testMachine(), cp, hook)
+	assert.Equal(t, "machine-deletion-hook-lb", command.Name)
+	assert.Equal(t, "fleet-default", command.Namespace)
+	assert.Equal(t, map[string]string{capr.ClusterNameLabel: "cluster", capr.MachineNameLabel: "machine"}, command.Labels)
+	assert.Equal(t, "cluster", command.Spec.ClusterName)
+	assert.Equal(t, hook.Command.MachineSelector, command.Spec.MachineSelector)
+	assert.Equal(t, "deregister", command.Spec.Command)
+	assert.Equal(t, []string{"--machine=machine", "--node", "node"}, command.Spec.Args)
+}
@@ -161,9 +161,16 @@ func (h *handler) drain(secret *corev1.Secret, machine *capi.Machine, drainData
 	}
 
 	if drainOpts.Enabled {
+		var err error
+		if secret, err = h.checkPodDisruptionBudgets(secret, machine, drainOpts); err != nil {
+			return secret, err
+		}
 		if err := h.performDrain(machine, drainOpts); err != nil {
 			return nil, err
 		}
+		if secret, err = h.setSecretAnnotation(secret, capr.DrainBlockedByAnnotation, ""); err != nil {
+			return nil, err
+		}
 	}
 
 	return h.updateSecretAnnotationIfCheckTrue(secret, capr.DrainDoneAnnotation, drainData, checkPreDrainHooks)
@@ -256,6 +263,7 @@ func (h *handler) cleanSecretAnnotationsIfCheckTrue(secret *corev1.Secret, drain
 		delete(secret.Annotations, capr.PostDrainAnnotation)
 		delete(secret.Annotations, capr.DrainAnnotation)
 		delete(secret.Annotations, capr.DrainDoneAnnotation)
+		delete(secret.Annotations, capr.DrainBlockedByAnnotation)
 		delete(secret.Annotations, capr.UnCordonAnnotation)
 		for _, hook := range drainOpts.PreDrainHooks {
 			delete(secret.Annotations, hook.Annotation)
@@ -0,0 +1,145 @@
+package machinedrain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// checkPodDisruptionBudgets records the pod disruption budgets that block the eviction of pods from the node of the
+// machine in the drain-blocked-by annotation of the plan secret, so that the planner can report them while the node
+// drains. If the drain options ask to fail on blocking budgets, an error naming the budgets is returned instead.
+func (h *handler) checkPodDisruptionBudgets(secret *corev1.Secret, machine *capi.Machine, drainOpts *rkev1.DrainOptions) (*corev1.Secret, error) {
+	if machine.Status.NodeRef == nil || machine.Status.NodeRef.Name == "" || drainOpts.DisableEviction {
+		return secret, nil
+	}
+
+	k8s, err := h.k8sClient(machine)
+	if err != nil {
+		return secret, err
+	}
+	blockers, err := blockingPodDisruptionBudgets(h.ctx, k8s, machine.Status.NodeRef.Name)
+	if err != nil {
+		return secret, err
+	}
+
+	secret, err = h.setSecretAnnotation(secret, capr.DrainBlockedByAnnotation, strings.Join(blockers, "; "))
+	if err != nil {
+		return secret, err
+	}
+	if len(blockers) > 0 && drainOpts.FailOnBlockingPodDisruptionBudgets {
+		return secret, fmt.Errorf("eviction is blocked by pod disruption budgets: %s: scale up the workloads or relax their pod disruption budgets, or drain with disableEviction to delete the pods regardless of their budgets",
+			strings.Join(blockers, "; "))
+	}
+	return secret, nil
+}
+
+// blockingPodDisruptionBudgets returns a description of each pod disruption budget that currently allows no disruption
+// of the pods on the node that a drain evicts, with the names of the pods it covers.
+func blockingPodDisruptionBudgets(ctx context.Context, client kubernetes.Interface, nodeName string) ([]string, error) {
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	budgets := map[string][]policyv1.PodDisruptionBudget{}
+	blocked := map[string][]string{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != nodeName || !evicted(pod) {
+			continue
+		}
+		namespaceBudgets, ok := budgets[pod.Namespace]
+		if !ok {
+			list, err := client.PolicyV1().PodDisruptionBudgets(pod.Namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+			namespaceBudgets = list.Items
+			budgets[pod.Namespace] = namespaceBudgets
+		}
+
+		for _, pdb := range namespaceBudgets {
+			if pdb.Status.DisruptionsAllowed > 0 {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil || selector.Empty() {
+				continue
+			}
+			if selector.Matches(labels.Set(pod.Labels)) {
+				key := pdb.Namespace + "/" + pdb.Name
+				blocked[key] = append(blocked[key], pod.Name)
+			}
+		}
+	}
+
+	var result []string
+	for key, podNames := range blocked {
+		sort.Strings(podNames)
+		result = append(result, fmt.Sprintf("poddisruptionbudget %s allows no disruption of pods %s", key, strings.Join(podNames, ", ")))
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// evicted returns whether a drain evicts the pod. Pods that completed, are already terminating, are mirror pods or are
+// managed by a daemonset are not evicted.
+func evicted(pod corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || pod.DeletionTimestamp != nil {
+		return false
+	}
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+	if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "DaemonSet" {
+		return false
+	}
+	return true
+}
+
+// setSecretAnnotation sets the annotation of the secret to the value, or removes it if the value is empty, and returns
+// the updated secret.
+func (h *handler) setSecretAnnotation(secret *corev1.Secret, annotation, value string) (*corev1.Secret, error) {
+	result := secret
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := h.secrets.Get(secret.Namespace, secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		result = current
+		if current.Annotations[annotation] == value {
+			return nil
+		}
+		current = current.DeepCopy()
+		if current.Annotations == nil {
+			current.Annotations = map[string]string{}
+		}
+		if value == "" {
+			delete(current.Annotations, annotation)
+		} else {
+			current.Annotations[annotation] = value
+		}
+		if current, err = h.secrets.Update(current); err != nil {
+			return err
+		}
+		result = current
+		return nil
+	})
+	return result, err
+}
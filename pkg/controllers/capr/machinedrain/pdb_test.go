@@ -0,0 +1,88 @@
+package machinedrain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBlockingPodDisruptionBudgets(t *testing.T) {
+	pod := func(name, node string, labels map[string]string, mutate ...func(*corev1.Pod)) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		for _, m := range mutate {
+			m(pod)
+		}
+		return pod
+	}
+	pdb := func(name string, app string, allowed int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: allowed},
+		}
+	}
+	daemonSet := func(pod *corev1.Pod) {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent", Controller: &controller}}
+	}
+	completed := func(pod *corev1.Pod) {
+		pod.Status.Phase = corev1.PodSucceeded
+	}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected []string
+	}{
+		{
+			name: "no budgets",
+			objects: []runtime.Object{
+				pod("web-1", "node1", map[string]string{"app": "web"}),
+			},
+		},
+		{
+			name: "budget allows disruptions",
+			objects: []runtime.Object{
+				pod("web-1", "node1", map[string]string{"app": "web"}),
+				pdb("web", "web", 1),
+			},
+		},
+		{
+			name: "budget blocks pods on node",
+			objects: []runtime.Object{
+				pod("web-2", "node1", map[string]string{"app": "web"}),
+				pod("web-1", "node1", map[string]string{"app": "web"}),
+				pod("web-3", "node2", map[string]string{"app": "web"}),
+				pod("db-1", "node1", map[string]string{"app": "db"}),
+				pdb("web", "web", 0),
+			},
+			expected: []string{"poddisruptionbudget default/web allows no disruption of pods web-1, web-2"},
+		},
+		{
+			name: "pods that are not evicted",
+			objects: []runtime.Object{
+				pod("agent-1", "node1", map[string]string{"app": "agent"}, daemonSet),
+				pod("job-1", "node1", map[string]string{"app": "agent"}, completed),
+				pdb("agent", "agent", 0),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockers, err := blockingPodDisruptionBudgets(context.Background(), fake.NewSimpleClientset(tt.objects...), "node1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, blockers)
+		})
+	}
+}
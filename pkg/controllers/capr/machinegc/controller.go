@@ -0,0 +1,228 @@
+package machinegc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rancher/rancher/pkg/capr"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/metrics"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/ticker"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+const (
+	gcInterval = time.Hour
+	// minOrphanAge is the minimum age of an object before it is considered for garbage collection, which prevents
+	// racing with the bootstrap controller while a machine and its objects are being created.
+	minOrphanAge = 30 * time.Minute
+
+	serviceAccountNameAnnotation = "kubernetes.io/service-account.name"
+)
+
+var (
+	// saTokenSuffixes are the suffixes of the service accounts created by the bootstrap controller for machines. Only
+	// service account token secrets for service accounts with these suffixes are collected.
+	saTokenSuffixes = []string{"-machine-plan", "-machine-bootstrap"}
+)
+
+type handler struct {
+	secrets             corecontrollers.SecretClient
+	secretCache         corecontrollers.SecretCache
+	serviceAccounts     corecontrollers.ServiceAccountClient
+	serviceAccountCache corecontrollers.ServiceAccountCache
+	machineCache        capicontrollers.MachineCache
+	rkeBootstrapCache   rkecontroller.RKEBootstrapCache
+	now                 func() time.Time
+}
+
+// Register starts the garbage collector that periodically removes plan secrets, bootstrap secrets, service accounts,
+// and service account tokens that were created for machines that no longer exist. These objects are normally removed
+// through owner references, but objects created by older versions or whose owners were removed while Rancher was
+// unavailable can be left behind.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		secrets:             clients.Core.Secret(),
+		secretCache:         clients.Core.Secret().Cache(),
+		serviceAccounts:     clients.Core.ServiceAccount(),
+		serviceAccountCache: clients.Core.ServiceAccount().Cache(),
+		machineCache:        clients.CAPI.Machine().Cache(),
+		rkeBootstrapCache:   clients.RKE.RKEBootstrap().Cache(),
+		now:                 time.Now,
+	}
+
+	go func() {
+		for range ticker.Context(ctx, gcInterval) {
+			h.collect()
+		}
+	}()
+}
+
+// collect removes all orphaned objects, and records the number of removed objects per kind.
+func (h *handler) collect() {
+	serviceAccounts, err := h.collectServiceAccounts()
+	if err != nil {
+		logrus.Errorf("[machinegc] error collecting orphaned service accounts: %v", err)
+	}
+	metrics.AddCAPRGarbageCollected("serviceaccount", serviceAccounts)
+
+	planSecrets, bootstrapSecrets, tokens, err := h.collectSecrets()
+	if err != nil {
+		logrus.Errorf("[machinegc] error collecting orphaned secrets: %v", err)
+	}
+	metrics.AddCAPRGarbageCollected("plansecret", planSecrets)
+	metrics.AddCAPRGarbageCollected("bootstrapsecret", bootstrapSecrets)
+	metrics.AddCAPRGarbageCollected("serviceaccounttoken", tokens)
+
+	if total := serviceAccounts + planSecrets + bootstrapSecrets + tokens; total > 0 {
+		logrus.Infof("[machinegc] removed %d orphaned service accounts, %d plan secrets, %d bootstrap secrets and %d service account tokens",
+			serviceAccounts, planSecrets, bootstrapSecrets, tokens)
+	}
+}
+
+func (h *handler) collectServiceAccounts() (int, error) {
+	selector, err := labels.NewRequirement(capr.RoleLabel, selection.Exists, nil)
+	if err != nil {
+		return 0, err
+	}
+	serviceAccounts, err := h.serviceAccountCache.List(metav1.NamespaceAll, labels.NewSelector().Add(*selector))
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, sa := range serviceAccounts {
+		if !h.oldEnough(sa.ObjectMeta) {
+			continue
+		}
+		orphaned, err := h.orphaned(sa.Namespace, sa.Labels[capr.MachineNameLabel], sa.Labels[capr.RKEBootstrapNameLabel])
+		if err != nil {
+			return count, err
+		}
+		if !orphaned {
+			continue
+		}
+		logrus.Debugf("[machinegc] removing orphaned service account %s/%s", sa.Namespace, sa.Name)
+		if err := h.serviceAccounts.Delete(sa.Namespace, sa.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (h *handler) collectSecrets() (planSecrets, bootstrapSecrets, tokens int, _ error) {
+	secrets, err := h.secretCache.List(metav1.NamespaceAll, labels.Everything())
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	for _, secret := range secrets {
+		if !h.oldEnough(secret.ObjectMeta) {
+			continue
+		}
+
+		var (
+			counter  *int
+			orphaned bool
+		)
+		switch secret.Type {
+		case capr.SecretTypeMachinePlan:
+			counter = &planSecrets
+			orphaned, err = h.orphaned(secret.Namespace, secret.Labels[capr.MachineNameLabel], bootstrapOwner(secret))
+		case capr.SecretTypeBootstrap:
+			counter = &bootstrapSecrets
+			orphaned, err = h.orphaned(secret.Namespace, "", bootstrapOwner(secret))
+		case corev1.SecretTypeServiceAccountToken:
+			counter = &tokens
+			orphaned, err = h.orphanedToken(secret)
+		default:
+			continue
+		}
+		if err != nil {
+			return planSecrets, bootstrapSecrets, tokens, err
+		}
+		if !orphaned {
+			continue
+		}
+
+		logrus.Debugf("[machinegc] removing orphaned secret %s/%s of type %s", secret.Namespace, secret.Name, secret.Type)
+		if err := h.secrets.Delete(secret.Namespace, secret.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return planSecrets, bootstrapSecrets, tokens, err
+		}
+		*counter++
+	}
+	return planSecrets, bootstrapSecrets, tokens, nil
+}
+
+// orphaned returns true if neither the machine nor the RKEBootstrap with the given names exist. If neither name is
+// known, the object is not considered orphaned as its owner cannot be determined.
+func (h *handler) orphaned(namespace, machineName, bootstrapName string) (bool, error) {
+	if machineName == "" && bootstrapName == "" {
+		return false, nil
+	}
+	if machineName != "" {
+		if _, err := h.machineCache.Get(namespace, machineName); err == nil {
+			return false, nil
+		} else if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	if bootstrapName != "" {
+		if _, err := h.rkeBootstrapCache.Get(namespace, bootstrapName); err == nil {
+			return false, nil
+		} else if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// orphanedToken returns true if the passed in secret is a token for a machine service account that no longer exists.
+func (h *handler) orphanedToken(secret *corev1.Secret) (bool, error) {
+	saName := secret.Annotations[serviceAccountNameAnnotation]
+	if !machineServiceAccountName(saName) {
+		return false, nil
+	}
+	if _, err := h.serviceAccountCache.Get(secret.Namespace, saName); err == nil {
+		return false, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *handler) oldEnough(meta metav1.ObjectMeta) bool {
+	return meta.DeletionTimestamp == nil && h.now().Sub(meta.CreationTimestamp.Time) > minOrphanAge
+}
+
+// bootstrapOwner returns the name of the RKEBootstrap that owns the passed in secret, if any.
+func bootstrapOwner(secret *corev1.Secret) string {
+	if name := secret.Labels[capr.RKEBootstrapNameLabel]; name != "" {
+		return name
+	}
+	for _, owner := range secret.OwnerReferences {
+		if owner.Kind == "RKEBootstrap" && strings.HasPrefix(owner.APIVersion, "rke.cattle.io/") {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+func machineServiceAccountName(name string) bool {
+	for _, suffix := range saTokenSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
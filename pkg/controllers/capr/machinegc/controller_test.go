@@ -0,0 +1,185 @@
+package machinegc
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+var now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+type machineCacheMock struct {
+	capicontrollers.MachineCache
+	machines map[string]bool
+}
+
+func (m *machineCacheMock) Get(namespace, name string) (*capi.Machine, error) {
+	if m.machines[namespace+"/"+name] {
+		return &capi.Machine{}, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+}
+
+type rkeBootstrapCacheMock struct {
+	rkecontroller.RKEBootstrapCache
+	bootstraps map[string]bool
+}
+
+func (m *rkeBootstrapCacheMock) Get(namespace, name string) (*rkev1.RKEBootstrap, error) {
+	if m.bootstraps[namespace+"/"+name] {
+		return &rkev1.RKEBootstrap{}, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+}
+
+type deleteRecorder struct {
+	deleted []string
+}
+
+type serviceAccountClientMock struct {
+	corecontrollers.ServiceAccountClient
+	deleteRecorder
+}
+
+func (m *serviceAccountClientMock) Delete(namespace, name string, _ *metav1.DeleteOptions) error {
+	m.deleted = append(m.deleted, namespace+"/"+name)
+	return nil
+}
+
+type serviceAccountCacheMock struct {
+	corecontrollers.ServiceAccountCache
+	serviceAccounts []*corev1.ServiceAccount
+}
+
+func (m *serviceAccountCacheMock) List(namespace string, selector labels.Selector) ([]*corev1.ServiceAccount, error) {
+	var result []*corev1.ServiceAccount
+	for _, sa := range m.serviceAccounts {
+		if selector.Matches(labels.Set(sa.Labels)) {
+			result = append(result, sa)
+		}
+	}
+	return result, nil
+}
+
+func (m *serviceAccountCacheMock) Get(namespace, name string) (*corev1.ServiceAccount, error) {
+	for _, sa := range m.serviceAccounts {
+		if sa.Namespace == namespace && sa.Name == name {
+			return sa, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+}
+
+type secretClientMock struct {
+	corecontrollers.SecretClient
+	deleteRecorder
+}
+
+func (m *secretClientMock) Delete(namespace, name string, _ *metav1.DeleteOptions) error {
+	m.deleted = append(m.deleted, namespace+"/"+name)
+	return nil
+}
+
+type secretCacheMock struct {
+	corecontrollers.SecretCache
+	secrets []*corev1.Secret
+}
+
+func (m *secretCacheMock) List(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+	return m.secrets, nil
+}
+
+func objectMeta(name string, age time.Duration, labels map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:         "fleet-default",
+		Name:              name,
+		Labels:            labels,
+		CreationTimestamp: metav1.NewTime(now.Add(-age)),
+	}
+}
+
+func TestCollect(t *testing.T) {
+	serviceAccountCache := &serviceAccountCacheMock{
+		serviceAccounts: []*corev1.ServiceAccount{
+			{ObjectMeta: objectMeta("live-machine-plan", time.Hour, map[string]string{
+				capr.RoleLabel:             capr.RolePlan,
+				capr.MachineNameLabel:      "live",
+				capr.RKEBootstrapNameLabel: "live",
+			})},
+			{ObjectMeta: objectMeta("gone-machine-plan", time.Hour, map[string]string{
+				capr.RoleLabel:             capr.RolePlan,
+				capr.MachineNameLabel:      "gone",
+				capr.RKEBootstrapNameLabel: "gone",
+			})},
+			{ObjectMeta: objectMeta("new-machine-plan", time.Minute, map[string]string{
+				capr.RoleLabel:             capr.RolePlan,
+				capr.MachineNameLabel:      "new",
+				capr.RKEBootstrapNameLabel: "new",
+			})},
+			{ObjectMeta: objectMeta("bootstrap-only-machine-bootstrap", time.Hour, map[string]string{
+				capr.RoleLabel:             capr.RoleBootstrap,
+				capr.MachineNameLabel:      "deleted-machine",
+				capr.RKEBootstrapNameLabel: "bootstrap-only",
+			})},
+		},
+	}
+
+	bootstrapOwned := func(name string, age time.Duration, secretType corev1.SecretType, labels map[string]string) *corev1.Secret {
+		meta := objectMeta(name, age, labels)
+		meta.OwnerReferences = []metav1.OwnerReference{{APIVersion: "rke.cattle.io/v1", Kind: "RKEBootstrap", Name: name}}
+		return &corev1.Secret{ObjectMeta: meta, Type: secretType}
+	}
+	token := func(name, saName string) *corev1.Secret {
+		meta := objectMeta(name, time.Hour, nil)
+		meta.Annotations = map[string]string{serviceAccountNameAnnotation: saName}
+		return &corev1.Secret{ObjectMeta: meta, Type: corev1.SecretTypeServiceAccountToken}
+	}
+
+	secretCache := &secretCacheMock{
+		secrets: []*corev1.Secret{
+			bootstrapOwned("live", time.Hour, capr.SecretTypeMachinePlan, map[string]string{capr.MachineNameLabel: "live"}),
+			bootstrapOwned("gone", time.Hour, capr.SecretTypeMachinePlan, map[string]string{capr.MachineNameLabel: "gone"}),
+			bootstrapOwned("new", time.Minute, capr.SecretTypeMachinePlan, map[string]string{capr.MachineNameLabel: "new"}),
+			bootstrapOwned("bootstrap-only", time.Hour, capr.SecretTypeMachinePlan, map[string]string{capr.MachineNameLabel: "deleted-machine"}),
+			bootstrapOwned("gone-machine-bootstrap", time.Hour, capr.SecretTypeBootstrap, nil),
+			{ObjectMeta: objectMeta("unowned-bootstrap", time.Hour, nil), Type: capr.SecretTypeBootstrap},
+			{ObjectMeta: objectMeta("unrelated", time.Hour, nil), Type: corev1.SecretTypeOpaque},
+			token("live-machine-plan-token-abcde", "live-machine-plan"),
+			token("removed-machine-plan-token-abcde", "removed-machine-plan"),
+			token("other-token-abcde", "other"),
+		},
+	}
+
+	secrets := &secretClientMock{}
+	serviceAccounts := &serviceAccountClientMock{}
+	h := &handler{
+		secrets:             secrets,
+		secretCache:         secretCache,
+		serviceAccounts:     serviceAccounts,
+		serviceAccountCache: serviceAccountCache,
+		machineCache:        &machineCacheMock{machines: map[string]bool{"fleet-default/live": true}},
+		rkeBootstrapCache:   &rkeBootstrapCacheMock{bootstraps: map[string]bool{"fleet-default/live": true, "fleet-default/bootstrap-only": true}},
+		now:                 func() time.Time { return now },
+	}
+
+	h.collect()
+
+	assert.Equal(t, []string{"fleet-default/gone-machine-plan"}, serviceAccounts.deleted)
+	assert.Equal(t, []string{
+		"fleet-default/gone",
+		"fleet-default/gone-machine-bootstrap",
+		"fleet-default/removed-machine-plan-token-abcde",
+	}, secrets.deleted)
+}
@@ -1,6 +1,7 @@
 package machineprovision
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -11,12 +12,14 @@
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/capr"
 	"github.com/rancher/rancher/pkg/controllers/management/drivers"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	namespace2 "github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/secretstore"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/wrangler/pkg/data"
 	"github.com/rancher/wrangler/pkg/data/convert"
@@ -33,6 +36,9 @@
 	capi "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
+// secretStoreTimeout is how long the data of a cloud credential may take to be read from an external secret store.
+const secretStoreTimeout = 30 * time.Second
+
 var (
 	regExHyphen     = regexp.MustCompile("([a-z])([A-Z])")
 	envNameOverride = map[string]string{
@@ -218,7 +224,13 @@ func (h *handler) getSecretData(machine *capi.Machine, obj data.Object, create b
 	}
 
 	if cloudCredentialSecretName != "" && machine != nil {
-		secret, err := GetCloudCredentialSecret(h.secrets, machine.GetNamespace(), cloudCredentialSecretName)
+		secret, err := GetCloudCredentialSecret(h.secrets, machine.GetNamespace(), cloudCredentialSecretName, machine.Spec.ClusterName)
+		if err != nil {
+			return "", "", nil, err
+		}
+		// the data of the secret store is only passed to the machine provisioning job, which needs it to call the
+		// API of the infrastructure provider
+		secret, err = ResolveCloudCredentialSecret(secret)
 		if err != nil {
 			return "", "", nil, err
 		}
@@ -236,12 +248,82 @@ func (h *handler) getSecretData(machine *capi.Machine, obj data.Object, create b
 	return bootstrapName, cloudCredentialSecretName, result, nil
 }
 
-func GetCloudCredentialSecret(secrets corecontrollers.SecretCache, namespace, name string) (*corev1.Secret, error) {
+// GetCloudCredentialSecret returns the secret of the named cloud credential for use by the cluster with the passed in
+// name and namespace. An error is returned if the cloud credential is restricted to other clusters. The data of a cloud
+// credential that refers to an external secret store is not read from the store, see ResolveCloudCredentialSecret.
+func GetCloudCredentialSecret(secrets corecontrollers.SecretCache, namespace, name, clusterName string) (*corev1.Secret, error) {
+	var (
+		secret *corev1.Secret
+		err    error
+	)
 	globalNS, globalName := kv.Split(name, ":")
 	if globalName != "" && globalNS == namespace2.GlobalNamespace {
-		return secrets.Get(globalNS, globalName)
+		secret, err = secrets.Get(globalNS, globalName)
+	} else {
+		secret, err = secrets.Get(namespace, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !cloudCredentialAllowed(secret, namespace, clusterName) {
+		return nil, fmt.Errorf("cloud credential %s is not available to cluster %s/%s", name, namespace, clusterName)
+	}
+	return secret, nil
+}
+
+// ResolveCloudCredentialSecret returns a copy of the cloud credential secret with the data of the external secret store
+// it refers to, if any. Keys of the store without a prefix are given the prefix of the keys of the secret, i.e.
+// accessKey becomes amazonec2credentialConfig-accessKey, so that they are read like the data of the secret. The path of
+// the store must be allowed for the namespace of the secret.
+func ResolveCloudCredentialSecret(secret *corev1.Secret) (*corev1.Secret, error) {
+	ref, ok := secret.Annotations[capr.CloudCredentialSecretStoreAnnotation]
+	if !ok {
+		return secret, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretStoreTimeout)
+	defer cancel()
+	data, err := secretstore.Resolve(ctx, ref, secret.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve cloud credential %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	var prefix string
+	for k := range secret.Data {
+		if p, _ := kv.RSplit(k, "-"); p != "" {
+			prefix = p + "-"
+			break
+		}
+	}
+	secret = secret.DeepCopy()
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	for k, v := range data {
+		if !strings.Contains(k, "-") {
+			k = prefix + k
+		}
+		secret.Data[k] = v
+	}
+	return secret, nil
+}
+
+// cloudCredentialAllowed returns true if the cloud credential secret may be used by the cluster with the passed in
+// namespace and name, according to the allowed clusters annotation of the secret.
+func cloudCredentialAllowed(secret *corev1.Secret, namespace, clusterName string) bool {
+	allowed, ok := secret.Annotations[capr.CloudCredentialAllowedClustersAnnotation]
+	if !ok {
+		return true
+	}
+
+	for _, entry := range strings.Split(allowed, ",") {
+		ns, name := kv.Split(strings.TrimSpace(entry), "/")
+		if ns == namespace && name != "" && (name == "*" || name == clusterName) {
+			return true
+		}
 	}
-	return secrets.Get(namespace, name)
+	return false
 }
 
 func toArgs(driverName string, args map[string]interface{}, clusterID string) (cmd []string) {
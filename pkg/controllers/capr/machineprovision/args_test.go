@@ -1,10 +1,15 @@
 package machineprovision
 
 import (
+	"context"
 	"testing"
 
 	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/secretstore"
+	"github.com/rancher/rancher/pkg/settings"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -177,3 +182,106 @@ func TestGetHostname(t *testing.T) {
 		})
 	}
 }
+
+func TestCloudCredentialAllowed(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		namespace   string
+		cluster     string
+		expected    bool
+	}{
+		{
+			name:      "unrestricted",
+			namespace: "fleet-default",
+			cluster:   "c1",
+			expected:  true,
+		},
+		{
+			name:        "allowed cluster",
+			annotations: map[string]string{capr.CloudCredentialAllowedClustersAnnotation: "fleet-default/c2, fleet-default/c1"},
+			namespace:   "fleet-default",
+			cluster:     "c1",
+			expected:    true,
+		},
+		{
+			name:        "allowed namespace",
+			annotations: map[string]string{capr.CloudCredentialAllowedClustersAnnotation: "tenant-a/*"},
+			namespace:   "tenant-a",
+			cluster:     "c1",
+			expected:    true,
+		},
+		{
+			name:        "cluster in other namespace",
+			annotations: map[string]string{capr.CloudCredentialAllowedClustersAnnotation: "tenant-a/c1,tenant-a/*"},
+			namespace:   "tenant-b",
+			cluster:     "c1",
+		},
+		{
+			name:        "other cluster",
+			annotations: map[string]string{capr.CloudCredentialAllowedClustersAnnotation: "fleet-default/c2"},
+			namespace:   "fleet-default",
+			cluster:     "c1",
+		},
+		{
+			name:        "no allowed clusters",
+			annotations: map[string]string{capr.CloudCredentialAllowedClustersAnnotation: ""},
+			namespace:   "fleet-default",
+			cluster:     "c1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			assert.Equal(t, tt.expected, cloudCredentialAllowed(secret, tt.namespace, tt.cluster))
+		})
+	}
+}
+
+type fakeSecretStore map[string][]byte
+
+func (f fakeSecretStore) Get(context.Context, string) (map[string][]byte, error) {
+	return f, nil
+}
+
+func TestResolveCloudCredentialSecret(t *testing.T) {
+	secretstore.Register("machineprovision-test", fakeSecretStore{
+		"accessKey":                           []byte("AKIA"),
+		"secretKey":                           []byte("secret"),
+		"amazonec2credentialConfig-sessionId": []byte("session"),
+	})
+
+	require.NoError(t, settings.SecretStoreAllowedPaths.Set("cloud/{namespace}/*"))
+	defer func() {
+		_ = settings.SecretStoreAllowedPaths.Set("")
+	}()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cc-abc", Namespace: "cattle-global-data"},
+		Data:       map[string][]byte{"amazonec2credentialConfig-defaultRegion": []byte("us-west-2")},
+	}
+	resolved, err := ResolveCloudCredentialSecret(secret)
+	assert.NoError(t, err)
+	assert.Same(t, secret, resolved, "secrets without a secret store reference are returned as is")
+
+	secret.Annotations = map[string]string{capr.CloudCredentialSecretStoreAnnotation: "machineprovision-test:cloud/cattle-global-data/aws"}
+	resolved, err = ResolveCloudCredentialSecret(secret)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"amazonec2credentialConfig-defaultRegion": []byte("us-west-2"),
+		"amazonec2credentialConfig-accessKey":     []byte("AKIA"),
+		"amazonec2credentialConfig-secretKey":     []byte("secret"),
+		"amazonec2credentialConfig-sessionId":     []byte("session"),
+	}, resolved.Data)
+	assert.Len(t, secret.Data, 1, "the cached secret is not modified")
+
+	secret.Annotations[capr.CloudCredentialSecretStoreAnnotation] = "machineprovision-test:cloud/fleet-default/aws"
+	_, err = ResolveCloudCredentialSecret(secret)
+	assert.EqualError(t, err, "failed to resolve cloud credential cattle-global-data/cc-abc: path cloud/fleet-default/aws of secret store "+
+		"machineprovision-test is not allowed for namespace cattle-global-data by the secret-store-allowed-paths setting")
+
+	secret.Annotations[capr.CloudCredentialSecretStoreAnnotation] = "unknown:cloud/cattle-global-data/aws"
+	_, err = ResolveCloudCredentialSecret(secret)
+	assert.EqualError(t, err, `failed to resolve cloud credential cattle-global-data/cc-abc: unknown secret store provider "unknown"`)
+}
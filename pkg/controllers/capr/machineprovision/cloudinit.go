@@ -0,0 +1,168 @@
+package machineprovision
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	cloudConfigHeader = "#cloud-config"
+
+	// customInstallScriptPath is the path that rancher-machine writes the bootstrap script passed as the custom install
+	// script to through the write_files of the user data.
+	customInstallScriptPath = "/usr/local/custom_script/install.sh"
+)
+
+// cloudInitField is the field of a machine config that holds the user data passed to the machine driver.
+type cloudInitField struct {
+	name   string
+	base64 bool
+}
+
+// cloudInitFields maps the drivers that accept cloud-init user data to the field of their machine config that holds it.
+var cloudInitFields = map[string]cloudInitField{
+	"amazonec2":     {name: "userdata"},
+	"azure":         {name: "customData"},
+	"digitalocean":  {name: "userdata"},
+	"exoscale":      {name: "userdata"},
+	"harvester":     {name: "userData", base64: true},
+	"openstack":     {name: "userDataFile"},
+	"packet":        {name: "userdata"},
+	"vmwarevsphere": {name: "cloudConfig"},
+}
+
+// reservedCloudInitKeys are managed by rancher-machine, which sets the hostname of the machine to the machine name.
+var reservedCloudInitKeys = map[string]bool{
+	"hostname": true,
+	"fqdn":     true,
+}
+
+// MergeCloudInit merges the cloud-config document of a machine pool into the user data field of the machine config data
+// of the driver. rancher-machine merges the Rancher bootstrap script into the result when the machine is created. Lists
+// of both documents are appended, any other key may only be set by one of them unless the values are equal. Files that
+// are written by both, and keys that are managed by rancher-machine, are reported as conflicts.
+func MergeCloudInit(driver string, machineConfig data.Object, cloudInit string) error {
+	if strings.TrimSpace(cloudInit) == "" {
+		return nil
+	}
+	field, ok := cloudInitFields[driver]
+	if !ok {
+		return fmt.Errorf("cloud-init is not supported by the %s machine driver", driver)
+	}
+
+	pool, err := parseCloudConfig(cloudInit)
+	if err != nil {
+		return fmt.Errorf("invalid cloud-init: %w", err)
+	}
+	for key := range pool {
+		if reservedCloudInitKeys[key] {
+			return fmt.Errorf("cloud-init key %s is managed by Rancher and can not be set", key)
+		}
+	}
+	if err := checkWriteFiles(nil, pool["write_files"], map[string]string{customInstallScriptPath: "Rancher"}); err != nil {
+		return err
+	}
+
+	userData := convert.ToString(machineConfig[field.name])
+	if field.base64 && userData != "" {
+		decoded, err := base64.StdEncoding.DecodeString(userData)
+		if err != nil {
+			return fmt.Errorf("decoding %s of machine config: %w", field.name, err)
+		}
+		userData = string(decoded)
+	}
+
+	merged := pool
+	if strings.TrimSpace(userData) != "" {
+		if !strings.HasPrefix(strings.TrimSpace(userData), cloudConfigHeader) {
+			return fmt.Errorf("cloud-init can not be merged with the %s of the machine config, which is not a cloud-config document", field.name)
+		}
+		base, err := parseCloudConfig(userData)
+		if err != nil {
+			return fmt.Errorf("invalid %s of machine config: %w", field.name, err)
+		}
+		merged, err = mergeCloudConfig(base, pool)
+		if err != nil {
+			return err
+		}
+	}
+
+	content, err := yaml.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	result := cloudConfigHeader + "\n" + string(content)
+	if field.base64 {
+		result = base64.StdEncoding.EncodeToString([]byte(result))
+	}
+	machineConfig.Set(field.name, result)
+	return nil
+}
+
+// parseCloudConfig parses a cloud-config document, which must be a map.
+func parseCloudConfig(content string) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &result); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	return result, nil
+}
+
+// mergeCloudConfig returns the cloud-config of the machine config with the cloud-config of the machine pool merged into
+// it.
+func mergeCloudConfig(base, pool map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(base)+len(pool))
+	for k, v := range base {
+		result[k] = v
+	}
+	for key, value := range pool {
+		existing, ok := result[key]
+		if !ok {
+			result[key] = value
+			continue
+		}
+		if key == "write_files" {
+			if err := checkWriteFiles(existing, value, nil); err != nil {
+				return nil, err
+			}
+		}
+		existingList, existingIsList := existing.([]interface{})
+		valueList, valueIsList := value.([]interface{})
+		switch {
+		case existingIsList && valueIsList:
+			result[key] = append(append([]interface{}{}, existingList...), valueList...)
+		case reflect.DeepEqual(existing, value):
+		default:
+			return nil, fmt.Errorf("cloud-init key %s is set by both the machine config and the machine pool", key)
+		}
+	}
+	return result, nil
+}
+
+// checkWriteFiles returns an error if a file written by the write_files of the machine pool is also written by the
+// write_files of the machine config, or is one of the reserved paths.
+func checkWriteFiles(base, pool interface{}, reserved map[string]string) error {
+	owners := map[string]string{}
+	for path, owner := range reserved {
+		owners[path] = owner
+	}
+	for _, file := range convert.ToInterfaceSlice(base) {
+		owners[convert.ToString(convert.ToMapInterface(file)["path"])] = "the machine config"
+	}
+	for _, file := range convert.ToInterfaceSlice(pool) {
+		path := convert.ToString(convert.ToMapInterface(file)["path"])
+		if owner, ok := owners[path]; ok && path != "" {
+			return fmt.Errorf("cloud-init file %s is also written by %s", path, owner)
+		}
+	}
+	return nil
+}
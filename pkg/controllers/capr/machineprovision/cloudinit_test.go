@@ -0,0 +1,129 @@
+package machineprovision
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const poolCloudInit = `#cloud-config
+packages:
+- lvm2
+runcmd:
+- vgcreate data /dev/sdb
+write_files:
+- path: /etc/agent.conf
+  content: enabled
+`
+
+func TestMergeCloudInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		driver   string
+		userData string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:   "no user data",
+			driver: "amazonec2",
+			expected: `#cloud-config
+packages:
+- lvm2
+runcmd:
+- vgcreate data /dev/sdb
+write_files:
+- content: enabled
+  path: /etc/agent.conf
+`,
+		},
+		{
+			name:   "lists are appended",
+			driver: "amazonec2",
+			userData: `#cloud-config
+runcmd:
+- echo hello
+timezone: UTC
+`,
+			expected: `#cloud-config
+packages:
+- lvm2
+runcmd:
+- echo hello
+- vgcreate data /dev/sdb
+timezone: UTC
+write_files:
+- content: enabled
+  path: /etc/agent.conf
+`,
+		},
+		{
+			name:   "conflicting key",
+			driver: "amazonec2",
+			userData: `#cloud-config
+packages: lvm2
+`,
+			wantErr: "cloud-init key packages is set by both the machine config and the machine pool",
+		},
+		{
+			name:   "conflicting file",
+			driver: "amazonec2",
+			userData: `#cloud-config
+write_files:
+- path: /etc/agent.conf
+  content: disabled
+`,
+			wantErr: "cloud-init file /etc/agent.conf is also written by the machine config",
+		},
+		{
+			name:     "user data is not cloud-config",
+			driver:   "amazonec2",
+			userData: "#!/bin/sh\necho hello\n",
+			wantErr:  "cloud-init can not be merged with the userdata of the machine config, which is not a cloud-config document",
+		},
+		{
+			name:    "unsupported driver",
+			driver:  "linode",
+			wantErr: "cloud-init is not supported by the linode machine driver",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machineConfig := data.Object{}
+			if tt.userData != "" {
+				machineConfig["userdata"] = tt.userData
+			}
+			err := MergeCloudInit(tt.driver, machineConfig, poolCloudInit)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, machineConfig["userdata"])
+		})
+	}
+}
+
+func TestMergeCloudInitReserved(t *testing.T) {
+	err := MergeCloudInit("amazonec2", data.Object{}, "#cloud-config\nhostname: node\n")
+	assert.EqualError(t, err, "cloud-init key hostname is managed by Rancher and can not be set")
+
+	err = MergeCloudInit("amazonec2", data.Object{}, "#cloud-config\nwrite_files:\n- path: /usr/local/custom_script/install.sh\n")
+	assert.EqualError(t, err, "cloud-init file /usr/local/custom_script/install.sh is also written by Rancher")
+
+	machineConfig := data.Object{"userdata": "#!/bin/sh\n"}
+	assert.NoError(t, MergeCloudInit("amazonec2", machineConfig, ""))
+	assert.Equal(t, "#!/bin/sh\n", machineConfig["userdata"], "user data is unchanged without cloud-init")
+}
+
+func TestMergeCloudInitBase64(t *testing.T) {
+	machineConfig := data.Object{"userData": base64.StdEncoding.EncodeToString([]byte("#cloud-config\npackages:\n- qemu-guest-agent\n"))}
+	require.NoError(t, MergeCloudInit("harvester", machineConfig, "#cloud-config\npackages:\n- lvm2\n"))
+
+	decoded, err := base64.StdEncoding.DecodeString(machineConfig.String("userData"))
+	require.NoError(t, err)
+	assert.Equal(t, "#cloud-config\npackages:\n- qemu-guest-agent\n- lvm2\n", string(decoded))
+}
@@ -19,6 +19,7 @@
 	"github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/provisioningv2/kubeconfig"
 	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/trustbundle"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/wrangler/pkg/apply"
 	"github.com/rancher/wrangler/pkg/condition"
@@ -91,9 +92,11 @@ type handler struct {
 	jobs                batchcontrollers.JobCache
 	pods                corecontrollers.PodCache
 	secrets             corecontrollers.SecretCache
+	configMaps          corecontrollers.ConfigMapCache
 	capiClusterCache    capicontrollers.ClusterCache
 	machineCache        capicontrollers.MachineCache
 	machineClient       capicontrollers.MachineClient
+	machineController   capicontrollers.MachineController
 	machineSetCache     capicontrollers.MachineSetCache
 	namespaces          corecontrollers.NamespaceCache
 	nodeDriverCache     mgmtcontrollers.NodeDriverCache
@@ -114,8 +117,10 @@ func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager
 		jobController:       clients.Batch.Job(),
 		jobs:                clients.Batch.Job().Cache(),
 		secrets:             clients.Core.Secret().Cache(),
+		configMaps:          clients.Core.ConfigMap().Cache(),
 		machineCache:        clients.CAPI.Machine().Cache(),
 		machineClient:       clients.CAPI.Machine(),
+		machineController:   clients.CAPI.Machine(),
 		machineSetCache:     clients.CAPI.MachineSet().Cache(),
 		capiClusterCache:    clients.CAPI.Cluster().Cache(),
 		nodeDriverCache:     clients.Mgmt.NodeDriver().Cache(),
@@ -130,6 +135,7 @@ func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager
 	clients.Dynamic.OnChange(ctx, "machine-provision-remove", validGVK, dynamic.FromKeyHandler(removeHandler))
 	clients.Dynamic.OnChange(ctx, "machine-provision", validGVK, h.OnChange)
 	clients.Batch.Job().OnChange(ctx, "machine-provision-pod", h.OnJobChange)
+	clients.CAPI.Machine().OnChange(ctx, "machine-provision-external-removal", h.OnMachineChange)
 }
 
 func validGVK(gvk schema.GroupVersionKind) bool {
@@ -841,6 +847,14 @@ func (h *handler) constructCertsSecret(machineName, machineNamespace string) (*c
 		certSecretData["internal-tls.crt"] = []byte(cert)
 	}
 
+	bundle, err := trustbundle.Get(h.secrets, h.configMaps)
+	if err != nil {
+		return nil, err
+	}
+	if len(bundle) > 0 {
+		certSecretData[trustbundle.LegacySecretKey] = bundle
+	}
+
 	if secret, err := h.secrets.Get(namespace.System, "tls-additional"); err == nil {
 		for key, val := range secret.Data {
 			certSecretData[key] = val
@@ -0,0 +1,125 @@
+package machineprovision
+
+import (
+	"strings"
+	"time"
+
+	provv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// externalRemovalGracePeriod is how long the node of a machine has to be missing from the downstream cluster before
+// the machine is considered to be removed outside of Rancher.
+const externalRemovalGracePeriod = 5 * time.Minute
+
+// OnMachineChange cleans up machines that were removed outside of Rancher, for example by deleting the virtual machine
+// in the console of the cloud provider, which in turn causes the cloud controller manager to delete the node from the
+// downstream cluster. The CAPI machine of such a machine would otherwise linger, keeping the machine plan secret and
+// preventing the machine set from replacing it. Deleting the CAPI machine runs the regular removal of the machine, and
+// the infrastructure machine is marked to be removed even if the deletion job fails because the host no longer exists.
+//
+// As a missing node may also be caused by an outage of the downstream cluster, machines are only deleted for clusters
+// that opted in with the remove externally deleted machines annotation, and only worker machines are deleted, one at a
+// time, so that etcd quorum and the control plane of the cluster are never put at risk.
+func (h *handler) OnMachineChange(_ string, machine *capi.Machine) (*capi.Machine, error) {
+	if machine == nil || !machine.DeletionTimestamp.IsZero() || machine.Spec.InfrastructureRef.APIVersion != capr.RKEMachineAPIVersion {
+		return machine, nil
+	}
+
+	removed, wait := externallyRemoved(machine, time.Now())
+	if !removed {
+		if wait > 0 {
+			h.machineController.EnqueueAfter(machine.Namespace, machine.Name, wait)
+		}
+		return machine, nil
+	}
+
+	cluster, err := h.rancherClusterCache.Get(machine.Namespace, machine.Labels[capi.ClusterLabelName])
+	if apierrors.IsNotFound(err) {
+		return machine, nil
+	} else if err != nil {
+		return machine, err
+	}
+	machines, err := h.machineCache.List(machine.Namespace, labels.SelectorFromSet(labels.Set{capi.ClusterLabelName: machine.Labels[capi.ClusterLabelName]}))
+	if err != nil {
+		return machine, err
+	}
+	if reason := externalRemovalBlocked(cluster, machine, machines); reason != "" {
+		logrus.Debugf("[machineprovision] %s/%s: node %s was removed from the cluster outside of Rancher, not deleting machine: %s", machine.Namespace, machine.Name, machine.Status.NodeRef.Name, reason)
+		h.machineController.EnqueueAfter(machine.Namespace, machine.Name, externalRemovalGracePeriod)
+		return machine, nil
+	}
+
+	logrus.Infof("[machineprovision] %s/%s: node %s was removed from the cluster outside of Rancher, deleting machine", machine.Namespace, machine.Name, machine.Status.NodeRef.Name)
+
+	infraRef := machine.Spec.InfrastructureRef
+	infraMachine, err := h.dynamic.Get(infraRef.GroupVersionKind(), machine.Namespace, infraRef.Name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return machine, err
+	}
+	if err == nil {
+		infra, err := newInfraObject(infraMachine)
+		if err != nil {
+			return machine, err
+		}
+		if strings.ToLower(infra.meta.GetAnnotations()[forceRemoveMachineAnn]) != "true" {
+			annotations := infra.meta.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[forceRemoveMachineAnn] = "true"
+			infra.meta.SetAnnotations(annotations)
+			if _, err := h.dynamic.Update(infra.obj); err != nil {
+				return machine, err
+			}
+		}
+	}
+
+	err = h.machineClient.Delete(machine.Namespace, machine.Name, &metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return machine, nil
+	}
+	return machine, err
+}
+
+// externallyRemoved returns true if the node of the machine has been observed and no longer exists in the downstream
+// cluster for at least the grace period. If the node is missing but the grace period has not passed yet, the remaining
+// time is returned.
+func externallyRemoved(machine *capi.Machine, now time.Time) (bool, time.Duration) {
+	if machine.Status.NodeRef == nil {
+		return false, 0
+	}
+
+	cond := conditions.Get(machine, capi.MachineNodeHealthyCondition)
+	if cond == nil || cond.Status != "False" || cond.Reason != capi.NodeNotFoundReason {
+		return false, 0
+	}
+
+	if wait := cond.LastTransitionTime.Add(externalRemovalGracePeriod).Sub(now); wait > 0 {
+		return false, wait
+	}
+	return true, 0
+}
+
+// externalRemovalBlocked returns why the externally removed machine must not be deleted, or an empty string if it may
+// be deleted.
+func externalRemovalBlocked(cluster *provv1.Cluster, machine *capi.Machine, machines []*capi.Machine) string {
+	if strings.ToLower(cluster.Annotations[capr.RemoveExternallyDeletedMachinesAnnotation]) != "true" {
+		return "cluster did not opt in with annotation " + capr.RemoveExternallyDeletedMachinesAnnotation
+	}
+	if machine.Labels[capr.EtcdRoleLabel] == "true" || machine.Labels[capr.ControlPlaneRoleLabel] == "true" {
+		return "etcd and control plane machines must be removed manually"
+	}
+	for _, other := range machines {
+		if other.Name != machine.Name && !other.DeletionTimestamp.IsZero() {
+			return "machine " + other.Name + " is being deleted"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package machineprovision
+
+import (
+	"testing"
+	"time"
+
+	provv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestExternallyRemoved(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name            string
+		nodeRef         bool
+		condition       *capi.Condition
+		expectedRemoved bool
+		expectedWait    time.Duration
+	}{
+		{
+			name: "no node",
+			condition: &capi.Condition{
+				Type:               capi.MachineNodeHealthyCondition,
+				Status:             corev1.ConditionFalse,
+				Reason:             capi.NodeNotFoundReason,
+				LastTransitionTime: metav1.NewTime(now.Add(-time.Hour)),
+			},
+		},
+		{
+			name:    "node healthy",
+			nodeRef: true,
+			condition: &capi.Condition{
+				Type:               capi.MachineNodeHealthyCondition,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(now.Add(-time.Hour)),
+			},
+		},
+		{
+			name:    "node unreachable",
+			nodeRef: true,
+			condition: &capi.Condition{
+				Type:               capi.MachineNodeHealthyCondition,
+				Status:             corev1.ConditionUnknown,
+				Reason:             capi.NodeConditionsFailedReason,
+				LastTransitionTime: metav1.NewTime(now.Add(-time.Hour)),
+			},
+		},
+		{
+			name:    "node recently removed",
+			nodeRef: true,
+			condition: &capi.Condition{
+				Type:               capi.MachineNodeHealthyCondition,
+				Status:             corev1.ConditionFalse,
+				Reason:             capi.NodeNotFoundReason,
+				LastTransitionTime: metav1.NewTime(now.Add(-time.Minute)),
+			},
+			expectedWait: externalRemovalGracePeriod - time.Minute,
+		},
+		{
+			name:    "node removed",
+			nodeRef: true,
+			condition: &capi.Condition{
+				Type:               capi.MachineNodeHealthyCondition,
+				Status:             corev1.ConditionFalse,
+				Reason:             capi.NodeNotFoundReason,
+				LastTransitionTime: metav1.NewTime(now.Add(-time.Hour)),
+			},
+			expectedRemoved: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine := &capi.Machine{}
+			if tt.nodeRef {
+				machine.Status.NodeRef = &corev1.ObjectReference{Name: "node"}
+			}
+			if tt.condition != nil {
+				machine.Status.Conditions = capi.Conditions{*tt.condition}
+			}
+
+			removed, wait := externallyRemoved(machine, now)
+			assert.Equal(t, tt.expectedRemoved, removed)
+			assert.Equal(t, tt.expectedWait, wait)
+		})
+	}
+}
+
+func TestExternalRemovalBlocked(t *testing.T) {
+	optedIn := &provv1.Cluster{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{capr.RemoveExternallyDeletedMachinesAnnotation: "true"}}}
+	worker := &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker", Labels: map[string]string{capr.WorkerRoleLabel: "true"}}}
+	deleting := &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: "deleting", DeletionTimestamp: &metav1.Time{Time: time.Now()}}}
+
+	tests := []struct {
+		name     string
+		cluster  *provv1.Cluster
+		machine  *capi.Machine
+		machines []*capi.Machine
+		expected string
+	}{
+		{
+			name:     "cluster did not opt in",
+			cluster:  &provv1.Cluster{},
+			machine:  worker,
+			machines: []*capi.Machine{worker},
+			expected: "cluster did not opt in with annotation provisioning.cattle.io/remove-externally-deleted-machines",
+		},
+		{
+			name:     "worker",
+			cluster:  optedIn,
+			machine:  worker,
+			machines: []*capi.Machine{worker},
+		},
+		{
+			name:     "etcd",
+			cluster:  optedIn,
+			machine:  &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: "etcd", Labels: map[string]string{capr.EtcdRoleLabel: "true", capr.WorkerRoleLabel: "true"}}},
+			expected: "etcd and control plane machines must be removed manually",
+		},
+		{
+			name:     "control plane",
+			cluster:  optedIn,
+			machine:  &capi.Machine{ObjectMeta: metav1.ObjectMeta{Name: "cp", Labels: map[string]string{capr.ControlPlaneRoleLabel: "true"}}},
+			expected: "etcd and control plane machines must be removed manually",
+		},
+		{
+			name:     "other machine is being deleted",
+			cluster:  optedIn,
+			machine:  worker,
+			machines: []*capi.Machine{worker, deleting},
+			expected: "machine deleting is being deleted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, externalRemovalBlocked(tt.cluster, tt.machine, tt.machines))
+		})
+	}
+}
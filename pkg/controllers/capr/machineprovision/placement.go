@@ -0,0 +1,130 @@
+package machineprovision
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// harvesterMachineSetNameLabel is set by the Harvester machine driver on the virtual machines it creates, to the name
+	// of the machine set of the machine.
+	harvesterMachineSetNameLabel = "harvesterhci.io/machineSetName"
+	hostnameTopologyKey          = "kubernetes.io/hostname"
+
+	maxAzureFaultDomainCount  = 3
+	maxAzureUpdateDomainCount = 20
+)
+
+// zoneFields are the fields of the machine config data of the machine drivers that select the zone machines are
+// created in.
+var zoneFields = map[string]string{
+	"amazonec2": "zone",
+	"azure":     "availabilityZone",
+	"google":    "zone",
+}
+
+// SetPlacement translates the placement settings of a machine pool into the fields of the machine config data of the
+// driver. machineSetName is the name of the machine deployment of the pool, which the machines of the pool are
+// identified by on the infrastructure provider.
+func SetPlacement(driver string, machineConfig data.Object, placement *rancherv1.RKEMachinePoolPlacement, machineSetName string) error {
+	if placement == nil {
+		return nil
+	}
+
+	if placement.HostAntiAffinity {
+		if driver != "harvester" {
+			return fmt.Errorf("host anti-affinity is not supported by the %s machine driver", driver)
+		}
+		if err := addHarvesterHostAntiAffinity(machineConfig, machineSetName); err != nil {
+			return err
+		}
+	}
+
+	if placement.AvailabilitySet == "" && placement.FaultDomainCount == 0 && placement.UpdateDomainCount == 0 {
+		return nil
+	}
+	if driver != "azure" {
+		return fmt.Errorf("availability sets are not supported by the %s machine driver", driver)
+	}
+	if placement.FaultDomainCount < 0 || placement.FaultDomainCount > maxAzureFaultDomainCount {
+		return fmt.Errorf("invalid fault domain count [%d], must be between 1 and %d", placement.FaultDomainCount, maxAzureFaultDomainCount)
+	}
+	if placement.UpdateDomainCount < 0 || placement.UpdateDomainCount > maxAzureUpdateDomainCount {
+		return fmt.Errorf("invalid update domain count [%d], must be between 1 and %d", placement.UpdateDomainCount, maxAzureUpdateDomainCount)
+	}
+	if placement.AvailabilitySet != "" {
+		machineConfig.Set("availabilitySet", placement.AvailabilitySet)
+	}
+	// Numeric flags of machine drivers are strings in the machine config.
+	if placement.FaultDomainCount > 0 {
+		machineConfig.Set("faultDomainCount", strconv.Itoa(placement.FaultDomainCount))
+	}
+	if placement.UpdateDomainCount > 0 {
+		machineConfig.Set("updateDomainCount", strconv.Itoa(placement.UpdateDomainCount))
+	}
+	return nil
+}
+
+// SetZone sets the zone of the machine config data of the driver, so that the machines of a machine pool that is spread
+// across zones are created in the zone of their machine deployment.
+func SetZone(driver string, machineConfig data.Object, zone string) error {
+	if zone == "" {
+		return nil
+	}
+	field, ok := zoneFields[driver]
+	if !ok {
+		return fmt.Errorf("zones are not supported by the %s machine driver", driver)
+	}
+	machineConfig.Set(field, zone)
+	return nil
+}
+
+// addHarvesterHostAntiAffinity adds a required anti-affinity term to the VM affinity of the Harvester machine config
+// that keeps the virtual machines of the machine set off the hosts already running one of them. The VM affinity is a
+// base64 encoded JSON affinity, any affinity already set in the machine config is kept.
+func addHarvesterHostAntiAffinity(machineConfig data.Object, machineSetName string) error {
+	affinity := &corev1.Affinity{}
+	if value := strings.TrimSpace(convert.ToString(machineConfig["vmAffinity"])); value != "" {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			// The machine driver also accepts the affinity as plain JSON.
+			decoded = []byte(value)
+		}
+		if err := json.Unmarshal(decoded, affinity); err != nil {
+			return fmt.Errorf("invalid vmAffinity of machine config: %w", err)
+		}
+	}
+
+	term := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{harvesterMachineSetNameLabel: machineSetName},
+		},
+		TopologyKey: hostnameTopologyKey,
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	for _, existing := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if reflect.DeepEqual(existing, term) {
+			return nil
+		}
+	}
+	affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, term)
+
+	encoded, err := json.Marshal(affinity)
+	if err != nil {
+		return err
+	}
+	machineConfig.Set("vmAffinity", base64.StdEncoding.EncodeToString(encoded))
+	return nil
+}
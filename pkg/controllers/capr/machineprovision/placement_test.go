@@ -0,0 +1,152 @@
+package machineprovision
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetPlacement(t *testing.T) {
+	tests := []struct {
+		name      string
+		driver    string
+		placement *rancherv1.RKEMachinePoolPlacement
+		expected  data.Object
+		wantErr   string
+	}{
+		{
+			name:     "no placement",
+			driver:   "amazonec2",
+			expected: data.Object{},
+		},
+		{
+			name:   "availability set",
+			driver: "azure",
+			placement: &rancherv1.RKEMachinePoolPlacement{
+				AvailabilitySet:   "etcd",
+				FaultDomainCount:  3,
+				UpdateDomainCount: 5,
+			},
+			expected: data.Object{
+				"availabilitySet":   "etcd",
+				"faultDomainCount":  "3",
+				"updateDomainCount": "5",
+			},
+		},
+		{
+			name:      "invalid fault domain count",
+			driver:    "azure",
+			placement: &rancherv1.RKEMachinePoolPlacement{AvailabilitySet: "etcd", FaultDomainCount: 4},
+			wantErr:   "invalid fault domain count [4], must be between 1 and 3",
+		},
+		{
+			name:      "availability set on unsupported driver",
+			driver:    "amazonec2",
+			placement: &rancherv1.RKEMachinePoolPlacement{AvailabilitySet: "etcd"},
+			wantErr:   "availability sets are not supported by the amazonec2 machine driver",
+		},
+		{
+			name:      "host anti-affinity on unsupported driver",
+			driver:    "vmwarevsphere",
+			placement: &rancherv1.RKEMachinePoolPlacement{HostAntiAffinity: true},
+			wantErr:   "host anti-affinity is not supported by the vmwarevsphere machine driver",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machineConfig := data.Object{}
+			err := SetPlacement(tt.driver, machineConfig, tt.placement, "test-etcd")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, machineConfig)
+		})
+	}
+}
+
+func TestSetZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		driver   string
+		zone     string
+		expected data.Object
+		wantErr  string
+	}{
+		{
+			name:     "no zone",
+			driver:   "vmwarevsphere",
+			expected: data.Object{"zone": "a"},
+		},
+		{
+			name:     "amazonec2",
+			driver:   "amazonec2",
+			zone:     "b",
+			expected: data.Object{"zone": "b"},
+		},
+		{
+			name:     "azure",
+			driver:   "azure",
+			zone:     "2",
+			expected: data.Object{"zone": "a", "availabilityZone": "2"},
+		},
+		{
+			name:    "unsupported driver",
+			driver:  "vmwarevsphere",
+			zone:    "b",
+			wantErr: "zones are not supported by the vmwarevsphere machine driver",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machineConfig := data.Object{"zone": "a"}
+			err := SetZone(tt.driver, machineConfig, tt.zone)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, machineConfig)
+		})
+	}
+}
+
+func TestSetPlacementHarvester(t *testing.T) {
+	decode := func(t *testing.T, machineConfig data.Object) *corev1.Affinity {
+		decoded, err := base64.StdEncoding.DecodeString(machineConfig.String("vmAffinity"))
+		require.NoError(t, err)
+		affinity := &corev1.Affinity{}
+		require.NoError(t, json.Unmarshal(decoded, affinity))
+		return affinity
+	}
+	placement := &rancherv1.RKEMachinePoolPlacement{HostAntiAffinity: true}
+
+	machineConfig := data.Object{}
+	require.NoError(t, SetPlacement("harvester", machineConfig, placement, "test-etcd"))
+	affinity := decode(t, machineConfig)
+	require.Len(t, affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, 1)
+	term := affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0]
+	assert.Equal(t, map[string]string{harvesterMachineSetNameLabel: "test-etcd"}, term.LabelSelector.MatchLabels)
+	assert.Equal(t, hostnameTopologyKey, term.TopologyKey)
+
+	require.NoError(t, SetPlacement("harvester", machineConfig, placement, "test-etcd"))
+	assert.Len(t, decode(t, machineConfig).PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, 1, "the term is only added once")
+
+	machineConfig = data.Object{
+		"vmAffinity": `{"nodeAffinity":{"requiredDuringSchedulingIgnoredDuringExecution":{"nodeSelectorTerms":[{"matchExpressions":[{"key":"zone","operator":"In","values":["a"]}]}]}}}`,
+	}
+	require.NoError(t, SetPlacement("harvester", machineConfig, placement, "test-etcd"))
+	affinity = decode(t, machineConfig)
+	assert.NotNil(t, affinity.NodeAffinity, "the existing affinity is kept")
+	assert.Len(t, affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, 1)
+
+	machineConfig = data.Object{"vmAffinity": "{"}
+	assert.ErrorContains(t, SetPlacement("harvester", machineConfig, placement, "test-etcd"), "invalid vmAffinity of machine config")
+}
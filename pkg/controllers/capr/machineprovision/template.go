@@ -4,6 +4,7 @@
 	"sort"
 	"strconv"
 
+	"github.com/rancher/rancher/pkg/capr"
 	name2 "github.com/rancher/wrangler/pkg/name"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -38,7 +39,7 @@ func objects(ready bool, args driverArgs) []runtime.Object {
 			Name:      args.StateSecretName,
 			Namespace: args.MachineNamespace,
 		},
-		Type: "rke.cattle.io/machine-state",
+		Type: capr.SecretTypeMachineState,
 	}
 
 	if ready {
@@ -0,0 +1,186 @@
+package nodecommand
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const defaultTTL = time.Hour
+
+type handler struct {
+	nodeCommands  rkecontroller.NodeCommandController
+	controlPlanes rkecontroller.RKEControlPlaneController
+	allowlist     func() string
+}
+
+// Register starts the controller that validates node commands, hands them to the planner of their cluster and deletes
+// them once their TTL after finishing expired. The planner runs the commands on the machines of the cluster.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		nodeCommands:  clients.RKE.NodeCommand(),
+		controlPlanes: clients.RKE.RKEControlPlane(),
+		allowlist:     settings.NodeCommandAllowedCommands.Get,
+	}
+
+	clients.RKE.NodeCommand().OnChange(ctx, "node-command", h.OnChange)
+	// Pending commands are started by the planner once the cluster is ready, so they are handed to the planner again
+	// whenever the control plane of the cluster changes.
+	nodeCommandCache := clients.RKE.NodeCommand().Cache()
+	relatedresource.Watch(ctx, "node-command-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		if _, ok := obj.(*rkev1.RKEControlPlane); !ok {
+			return nil, nil
+		}
+		commands, err := nodeCommandCache.List(namespace, labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		var keys []relatedresource.Key
+		for _, command := range commands {
+			if command.Spec.ClusterName == name && command.Status.Phase == rkev1.NodeCommandPhasePending {
+				keys = append(keys, relatedresource.Key{Namespace: namespace, Name: command.Name})
+			}
+		}
+		return keys, nil
+	}, clients.RKE.NodeCommand(), clients.RKE.RKEControlPlane())
+}
+
+// OnChange validates new and changed commands, enqueues the control plane of the cluster of pending and running
+// commands and deletes finished commands once their TTL expired.
+func (h *handler) OnChange(_ string, command *rkev1.NodeCommand) (*rkev1.NodeCommand, error) {
+	if command == nil || command.DeletionTimestamp != nil {
+		return command, nil
+	}
+
+	switch command.Status.Phase {
+	case "", rkev1.NodeCommandPhasePending:
+		if command.Status.Phase == "" || command.Status.ObservedGeneration != command.Generation {
+			command = command.DeepCopy()
+			h.validate(command)
+			return h.nodeCommands.UpdateStatus(command)
+		}
+		h.controlPlanes.Enqueue(command.Namespace, command.Spec.ClusterName)
+		return command, nil
+	case rkev1.NodeCommandPhaseRunning:
+		if command.Status.ObservedGeneration != command.Generation {
+			logrus.Infof("[nodecommand] failing node command %s/%s as its spec changed while it was running", command.Namespace, command.Name)
+			command = command.DeepCopy()
+			fail(command, "the spec of the command changed while it was running")
+			return h.nodeCommands.UpdateStatus(command)
+		}
+		h.controlPlanes.Enqueue(command.Namespace, command.Spec.ClusterName)
+		return command, nil
+	}
+
+	if remaining := expiresIn(command, time.Now()); remaining > 0 {
+		h.nodeCommands.EnqueueAfter(command.Namespace, command.Name, remaining)
+		return command, nil
+	}
+	logrus.Debugf("[nodecommand] deleting node command %s/%s as its TTL expired", command.Namespace, command.Name)
+	if err := h.nodeCommands.Delete(command.Namespace, command.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return command, err
+	}
+	return command, nil
+}
+
+// validate validates the current spec of the command. A valid spec is copied to the status, as the planner only runs
+// the validated copy, and the command becomes pending; otherwise the command fails.
+func (h *handler) validate(command *rkev1.NodeCommand) {
+	command.Status.ObservedGeneration = command.Generation
+	if err := validate(command, h.allowlist()); err != nil {
+		logrus.Infof("[nodecommand] rejecting node command %s/%s: %v", command.Namespace, command.Name, err)
+		fail(command, err.Error())
+		return
+	}
+	command.Status.Phase = rkev1.NodeCommandPhasePending
+	command.Status.ValidatedSpec = command.Spec.DeepCopy()
+}
+
+func fail(command *rkev1.NodeCommand, message string) {
+	now := metav1.Now()
+	command.Status.Phase = rkev1.NodeCommandPhaseFailed
+	command.Status.Message = message
+	command.Status.CompletionTime = &now
+	command.Status.ValidatedSpec = nil
+}
+
+// validate checks that the command targets a cluster, has a valid machine selector and is allowed by the allowlist, a
+// comma separated list of entries. An entry is a path.Match pattern of the command, optionally followed by a colon and
+// the subcommands that are allowed as first argument and the flags that are allowed as any argument separated by |, for
+// example crictl:ps|logs or journalctl:-u|--since. Flags start with a dash and are compared without their value; if an
+// entry has no flags, all flags are allowed.
+func validate(command *rkev1.NodeCommand, allowlist string) error {
+	if command.Spec.ClusterName == "" {
+		return fmt.Errorf("clusterName must be set")
+	}
+	if command.Spec.Command == "" {
+		return fmt.Errorf("command must be set")
+	}
+	if command.Spec.MachineSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(command.Spec.MachineSelector); err != nil {
+			return fmt.Errorf("invalid machine selector: %w", err)
+		}
+	}
+	for _, entry := range strings.Split(allowlist, ",") {
+		pattern, restrictions, restricted := strings.Cut(strings.TrimSpace(entry), ":")
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, command.Spec.Command); !ok {
+			continue
+		}
+		if !restricted {
+			return nil
+		}
+
+		var subcommands, flags []string
+		for _, restriction := range strings.Split(restrictions, "|") {
+			if restriction = strings.TrimSpace(restriction); strings.HasPrefix(restriction, "-") {
+				flags = append(flags, restriction)
+			} else if restriction != "" {
+				subcommands = append(subcommands, restriction)
+			}
+		}
+		if len(subcommands) > 0 && (len(command.Spec.Args) == 0 || !slices.Contains(subcommands, command.Spec.Args[0])) {
+			return fmt.Errorf("command %s is only allowed with the subcommands %s by the %s setting", command.Spec.Command,
+				strings.Join(subcommands, ", "), settings.NodeCommandAllowedCommands.Name)
+		}
+		if len(flags) > 0 {
+			for _, arg := range command.Spec.Args {
+				flag, _, _ := strings.Cut(arg, "=")
+				if strings.HasPrefix(flag, "-") && !slices.Contains(flags, flag) {
+					return fmt.Errorf("command %s is only allowed with the flags %s by the %s setting", command.Spec.Command,
+						strings.Join(flags, ", "), settings.NodeCommandAllowedCommands.Name)
+				}
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("command %s is not allowed by the %s setting", command.Spec.Command, settings.NodeCommandAllowedCommands.Name)
+}
+
+// expiresIn returns how long the finished command is kept until its TTL expires.
+func expiresIn(command *rkev1.NodeCommand, now time.Time) time.Duration {
+	if command.Status.CompletionTime == nil {
+		return 0
+	}
+	ttl := defaultTTL
+	if command.Spec.TTLSecondsAfterFinished != nil {
+		ttl = time.Duration(*command.Spec.TTLSecondsAfterFinished) * time.Second
+	}
+	return command.Status.CompletionTime.Add(ttl).Sub(now)
+}
@@ -0,0 +1,127 @@
+package nodecommand
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	const allowlist = "df, journalctl:-u|--since|-n|--no-pager,/var/lib/rancher/*/bin/crictl:ps|logs"
+	tests := []struct {
+		name    string
+		spec    rkev1.NodeCommandSpec
+		wantErr string
+	}{
+		{
+			name: "allowed command",
+			spec: rkev1.NodeCommandSpec{ClusterName: "test", Command: "journalctl", Args: []string{"-u", "rke2-server"}},
+		},
+		{
+			name: "allowed flags",
+			spec: rkev1.NodeCommandSpec{ClusterName: "test", Command: "journalctl", Args: []string{"-u", "rke2-server", "--since=-1h", "-n", "100", "--no-pager"}},
+		},
+		{
+			name:    "flag not allowed",
+			spec:    rkev1.NodeCommandSpec{ClusterName: "test", Command: "journalctl", Args: []string{"--vacuum-size=1M"}},
+			wantErr: "command journalctl is only allowed with the flags -u, --since, -n, --no-pager by the node-command-allowed-commands setting",
+		},
+		{
+			name:    "flag not allowed after allowed flags",
+			spec:    rkev1.NodeCommandSpec{ClusterName: "test", Command: "journalctl", Args: []string{"-u", "rke2-server", "--rotate"}},
+			wantErr: "command journalctl is only allowed with the flags -u, --since, -n, --no-pager by the node-command-allowed-commands setting",
+		},
+		{
+			name: "allowed by pattern",
+			spec: rkev1.NodeCommandSpec{ClusterName: "test", Command: "/var/lib/rancher/rke2/bin/crictl", Args: []string{"ps", "-a"}},
+		},
+		{
+			name:    "subcommand not allowed",
+			spec:    rkev1.NodeCommandSpec{ClusterName: "test", Command: "/var/lib/rancher/rke2/bin/crictl", Args: []string{"exec", "-it", "abc", "sh"}},
+			wantErr: "command /var/lib/rancher/rke2/bin/crictl is only allowed with the subcommands ps, logs by the node-command-allowed-commands setting",
+		},
+		{
+			name:    "flag instead of subcommand",
+			spec:    rkev1.NodeCommandSpec{ClusterName: "test", Command: "/var/lib/rancher/rke2/bin/crictl", Args: []string{"--config", "/tmp/crictl.yaml", "ps"}},
+			wantErr: "command /var/lib/rancher/rke2/bin/crictl is only allowed with the subcommands ps, logs by the node-command-allowed-commands setting",
+		},
+		{
+			name:    "missing subcommand",
+			spec:    rkev1.NodeCommandSpec{ClusterName: "test", Command: "/var/lib/rancher/rke2/bin/crictl"},
+			wantErr: "command /var/lib/rancher/rke2/bin/crictl is only allowed with the subcommands ps, logs by the node-command-allowed-commands setting",
+		},
+		{
+			name:    "not allowed",
+			spec:    rkev1.NodeCommandSpec{ClusterName: "test", Command: "rm"},
+			wantErr: "command rm is not allowed by the node-command-allowed-commands setting",
+		},
+		{
+			name:    "pattern does not match subdirectories",
+			spec:    rkev1.NodeCommandSpec{ClusterName: "test", Command: "/var/lib/rancher/rke2/data/bin/crictl"},
+			wantErr: "command /var/lib/rancher/rke2/data/bin/crictl is not allowed by the node-command-allowed-commands setting",
+		},
+		{
+			name:    "missing cluster",
+			spec:    rkev1.NodeCommandSpec{Command: "df"},
+			wantErr: "clusterName must be set",
+		},
+		{
+			name: "invalid selector",
+			spec: rkev1.NodeCommandSpec{
+				ClusterName: "test",
+				Command:     "df",
+				MachineSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "rke.cattle.io/etcd-role", Operator: "Maybe"}},
+				},
+			},
+			wantErr: `invalid machine selector: "Maybe" is not a valid pod selector operator`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(&rkev1.NodeCommand{Spec: tt.spec}, allowlist)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHandlerValidate(t *testing.T) {
+	h := &handler{allowlist: func() string { return "df" }}
+
+	command := &rkev1.NodeCommand{
+		ObjectMeta: metav1.ObjectMeta{Generation: 1},
+		Spec:       rkev1.NodeCommandSpec{ClusterName: "test", Command: "df", Args: []string{"-h"}},
+	}
+	h.validate(command)
+	assert.Equal(t, rkev1.NodeCommandPhasePending, command.Status.Phase)
+	assert.Equal(t, int64(1), command.Status.ObservedGeneration)
+	assert.Equal(t, &command.Spec, command.Status.ValidatedSpec)
+
+	command.Generation = 2
+	command.Spec.Command = "rm"
+	h.validate(command)
+	assert.Equal(t, rkev1.NodeCommandPhaseFailed, command.Status.Phase, "changed specs are validated again")
+	assert.Equal(t, int64(2), command.Status.ObservedGeneration)
+	assert.Nil(t, command.Status.ValidatedSpec)
+	assert.NotNil(t, command.Status.CompletionTime)
+}
+
+func TestExpiresIn(t *testing.T) {
+	now := time.Now()
+	ttl := int64(60)
+	command := &rkev1.NodeCommand{}
+	assert.Zero(t, expiresIn(command, now), "commands without completion time are expired")
+
+	command.Status.CompletionTime = &metav1.Time{Time: now.Add(-time.Minute)}
+	assert.Equal(t, defaultTTL-time.Minute, expiresIn(command, now))
+
+	command.Spec.TTLSecondsAfterFinished = &ttl
+	assert.Equal(t, time.Duration(0), expiresIn(command, now))
+}
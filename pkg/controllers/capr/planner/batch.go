@@ -0,0 +1,225 @@
+package planner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	v1 "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/genericcondition"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// statusBatchInterval is the minimum interval between two status writes of a control plane whose status only changed
+// in its conditions. Changes of the state the planner acts on are always written immediately.
+const statusBatchInterval = 15 * time.Second
+
+// statusBatcher coalesces the status writes of the planner in large cluster mode. Statuses are compared by a hash of
+// their semantic content, and changes that only affect the conditions of a control plane are deferred until the batch
+// interval passed since its last write.
+type statusBatcher struct {
+	controlPlanes v1.RKEControlPlaneController
+
+	lastWriteLock sync.Mutex
+	lastWrite     map[string]time.Time
+}
+
+func newStatusBatcher(controlPlanes v1.RKEControlPlaneController) *statusBatcher {
+	return &statusBatcher{
+		controlPlanes: controlPlanes,
+		lastWrite:     map[string]time.Time{},
+	}
+}
+
+// OnChange runs the status handler for the control plane and writes the resulting status if it needs to be written
+// now, otherwise it enqueues the control plane for when it does.
+func (b *statusBatcher) OnChange(key string, cp *rkev1.RKEControlPlane, handler v1.RKEControlPlaneStatusHandler) (*rkev1.RKEControlPlane, error) {
+	if cp == nil {
+		b.lastWriteLock.Lock()
+		delete(b.lastWrite, key)
+		b.lastWriteLock.Unlock()
+		return nil, nil
+	}
+
+	cp = cp.DeepCopy()
+	status, err := handler(cp, *cp.Status.DeepCopy())
+	if err != nil {
+		// same as the generated status handler, the status is not written if the handler failed
+		return cp, err
+	}
+
+	if statusHash(cp.Status, true) == statusHash(status, true) {
+		return cp, nil
+	}
+
+	if statusHash(cp.Status, false) == statusHash(status, false) {
+		if wait := b.wait(key, time.Now()); wait > 0 {
+			b.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, wait)
+			return cp, nil
+		}
+	}
+
+	updated, err := b.updateStatus(cp, status)
+	if err != nil {
+		return cp, err
+	}
+	b.lastWriteLock.Lock()
+	b.lastWrite[key] = time.Now()
+	b.lastWriteLock.Unlock()
+	return updated, nil
+}
+
+// wait returns how long the write of a condition only change of the control plane must be deferred.
+func (b *statusBatcher) wait(key string, now time.Time) time.Duration {
+	b.lastWriteLock.Lock()
+	defer b.lastWriteLock.Unlock()
+	lastWrite, ok := b.lastWrite[key]
+	if !ok {
+		return 0
+	}
+	return lastWrite.Add(statusBatchInterval).Sub(now)
+}
+
+// updateStatus writes the status of the control plane. On conflict, the fields of the status the handler changed are
+// applied to the latest version of the control plane, unless its spec changed in the meantime.
+func (b *statusBatcher) updateStatus(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (*rkev1.RKEControlPlane, error) {
+	var (
+		updated  *rkev1.RKEControlPlane
+		toUpdate = cp.DeepCopy()
+	)
+	toUpdate.Status = status
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		updated, err = b.controlPlanes.UpdateStatus(toUpdate)
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+
+		latest, getErr := b.controlPlanes.Get(cp.Namespace, cp.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return getErr
+		}
+		if latest.Generation != cp.Generation {
+			// the status was computed for an outdated spec, the control plane is processed again anyway
+			return err
+		}
+		merged, mergeErr := mergeStatus(cp.Status, status, latest.Status)
+		if mergeErr != nil {
+			return mergeErr
+		}
+		toUpdate = latest.DeepCopy()
+		toUpdate.Status = merged
+		return err
+	})
+	return updated, err
+}
+
+// mergeStatus applies the top level fields that changed from the original to the desired status onto the latest
+// status. Conditions are merged by their type, so that the conditions other controllers set in the meantime are kept.
+func mergeStatus(original, desired, latest rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
+	originalFields, err := toFields(original)
+	if err != nil {
+		return latest, err
+	}
+	desiredFields, err := toFields(desired)
+	if err != nil {
+		return latest, err
+	}
+	latestFields, err := toFields(latest)
+	if err != nil {
+		return latest, err
+	}
+
+	for k, v := range originalFields {
+		if _, ok := desiredFields[k]; !ok {
+			if reflect.DeepEqual(v, latestFields[k]) {
+				delete(latestFields, k)
+			}
+		}
+	}
+	for k, v := range desiredFields {
+		if !reflect.DeepEqual(v, originalFields[k]) {
+			latestFields[k] = v
+		}
+	}
+
+	var merged rkev1.RKEControlPlaneStatus
+	data, err := json.Marshal(latestFields)
+	if err != nil {
+		return latest, err
+	}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return latest, err
+	}
+	merged.Conditions = mergeConditions(original.Conditions, desired.Conditions, latest.Conditions)
+	return merged, nil
+}
+
+// mergeConditions applies the conditions that changed from the original to the desired conditions onto the latest
+// conditions, and removes the conditions that were removed from the desired conditions unless they changed since.
+func mergeConditions(original, desired, latest []genericcondition.GenericCondition) []genericcondition.GenericCondition {
+	find := func(conditions []genericcondition.GenericCondition, conditionType string) *genericcondition.GenericCondition {
+		for i := range conditions {
+			if conditions[i].Type == conditionType {
+				return &conditions[i]
+			}
+		}
+		return nil
+	}
+
+	var merged []genericcondition.GenericCondition
+	for _, condition := range latest {
+		if find(desired, condition.Type) == nil {
+			if originalCondition := find(original, condition.Type); originalCondition != nil && reflect.DeepEqual(*originalCondition, condition) {
+				continue
+			}
+		}
+		merged = append(merged, condition)
+	}
+	for _, condition := range desired {
+		if originalCondition := find(original, condition.Type); originalCondition != nil && reflect.DeepEqual(*originalCondition, condition) {
+			continue
+		}
+		if existing := find(merged, condition.Type); existing != nil {
+			*existing = condition
+		} else {
+			merged = append(merged, condition)
+		}
+	}
+	return merged
+}
+
+func toFields(status rkev1.RKEControlPlaneStatus) (map[string]interface{}, error) {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	return fields, json.Unmarshal(data, &fields)
+}
+
+// statusHash returns a hash of the semantic content of the status. The update and transition times of conditions are
+// never part of it, and the conditions themselves are only part of it if withConditions is set.
+func statusHash(status rkev1.RKEControlPlaneStatus, withConditions bool) string {
+	status = *status.DeepCopy()
+	if withConditions {
+		for i := range status.Conditions {
+			status.Conditions[i].LastUpdateTime = ""
+			status.Conditions[i].LastTransitionTime = ""
+		}
+	} else {
+		status.Conditions = nil
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,66 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/wrangler/pkg/genericcondition"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusHash(t *testing.T) {
+	status := rkev1.RKEControlPlaneStatus{
+		ObservedGeneration: 2,
+		Conditions: []genericcondition.GenericCondition{
+			{Type: "Ready", Status: "Unknown", Message: "waiting", LastUpdateTime: "2023-05-03T12:00:00Z"},
+		},
+	}
+
+	touched := *status.DeepCopy()
+	touched.Conditions[0].LastUpdateTime = "2023-05-03T12:00:05Z"
+	assert.Equal(t, statusHash(status, true), statusHash(touched, true))
+
+	message := *status.DeepCopy()
+	message.Conditions[0].Message = "still waiting"
+	assert.NotEqual(t, statusHash(status, true), statusHash(message, true))
+	assert.Equal(t, statusHash(status, false), statusHash(message, false))
+
+	snapshot := *status.DeepCopy()
+	snapshot.ETCDSnapshotCreate = &rkev1.ETCDSnapshotCreate{Generation: 1}
+	assert.NotEqual(t, statusHash(status, false), statusHash(snapshot, false))
+}
+
+func TestMergeStatus(t *testing.T) {
+	original := rkev1.RKEControlPlaneStatus{
+		ObservedGeneration:      1,
+		ETCDSnapshotCreate:      &rkev1.ETCDSnapshotCreate{Generation: 1},
+		ETCDSnapshotCreatePhase: rkev1.ETCDSnapshotPhaseStarted,
+		Conditions: []genericcondition.GenericCondition{
+			{Type: "Ready", Status: "Unknown"},
+			{Type: "Stable", Status: "False"},
+		},
+	}
+	desired := rkev1.RKEControlPlaneStatus{
+		ObservedGeneration: 1,
+		AgentConnected:     true,
+		Conditions: []genericcondition.GenericCondition{
+			{Type: "Ready", Status: "True"},
+		},
+	}
+	latest := *original.DeepCopy()
+	latest.ObservedGeneration = 2
+	latest.ChannelKubernetesVersion = "v1.26.4+rke2r1"
+	latest.Conditions = append(latest.Conditions, genericcondition.GenericCondition{Type: "Provisioned", Status: "True"})
+
+	merged, err := mergeStatus(original, desired, latest)
+	assert.NoError(t, err)
+	assert.Equal(t, rkev1.RKEControlPlaneStatus{
+		ObservedGeneration:       2,
+		AgentConnected:           true,
+		ChannelKubernetesVersion: "v1.26.4+rke2r1",
+		Conditions: []genericcondition.GenericCondition{
+			{Type: "Ready", Status: "True"},
+			{Type: "Provisioned", Status: "True"},
+		},
+	}, merged)
+}
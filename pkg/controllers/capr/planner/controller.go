@@ -8,7 +8,9 @@
 
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/faultinjection"
 	caprplanner "github.com/rancher/rancher/pkg/capr/planner"
+	"github.com/rancher/rancher/pkg/features"
 	v1 "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/wrangler/pkg/generic"
@@ -30,7 +32,14 @@ func Register(ctx context.Context, clients *wrangler.Context, planner *caprplann
 		planner:       planner,
 		controlPlanes: clients.RKE.RKEControlPlane(),
 	}
-	v1.RegisterRKEControlPlaneStatusHandler(ctx, clients.RKE.RKEControlPlane(), "", "planner", h.OnChange)
+	if features.LargeClusterMode.Enabled() {
+		batcher := newStatusBatcher(clients.RKE.RKEControlPlane())
+		clients.RKE.RKEControlPlane().OnChange(ctx, "planner", func(key string, cp *rkev1.RKEControlPlane) (*rkev1.RKEControlPlane, error) {
+			return batcher.OnChange(key, cp, h.OnChange)
+		})
+	} else {
+		v1.RegisterRKEControlPlaneStatusHandler(ctx, clients.RKE.RKEControlPlane(), "", "planner", h.OnChange)
+	}
 	relatedresource.Watch(ctx, "planner", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
 		if secret, ok := obj.(*corev1.Secret); ok {
 			var relatedResources []relatedresource.Key
@@ -75,9 +84,15 @@ func Register(ctx context.Context, clients *wrangler.Context, planner *caprplann
 				}
 			}
 			return relatedResources, nil
+		} else if approval, ok := obj.(*rkev1.Approval); ok && approval.Spec.ClusterName != "" {
+			logrus.Tracef("[planner] rkecluster %s/%s enqueue triggered by approval %s/%s", approval.Spec.ClusterNamespace, approval.Spec.ClusterName, approval.Namespace, approval.Name)
+			return []relatedresource.Key{{
+				Namespace: approval.Spec.ClusterNamespace,
+				Name:      approval.Spec.ClusterName,
+			}}, nil
 		}
 		return nil, nil
-	}, clients.RKE.RKEControlPlane(), clients.Core.Secret(), clients.CAPI.Machine(), clients.Core.ConfigMap())
+	}, clients.RKE.RKEControlPlane(), clients.Core.Secret(), clients.CAPI.Machine(), clients.Core.ConfigMap(), clients.RKE.Approval())
 }
 
 func (h *handler) OnChange(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
@@ -97,6 +112,10 @@ func (h *handler) OnChange(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPla
 		// * error - All other errors. This should be an actual error during planner processing.
 		if caprplanner.IsErrWaiting(err) {
 			logrus.Infof("[planner] rkecluster %s/%s: waiting: %v", cp.Namespace, cp.Name, err)
+			if delay := faultinjection.RequeueAfter(cp); delay > 0 {
+				// plans withheld by an injected agent response delay do not cause the control plane to be enqueued
+				h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, delay)
+			}
 			capr.Ready.SetStatus(&status, "Unknown")
 			capr.Ready.Message(&status, err.Error())
 			capr.Ready.Reason(&status, "Waiting")
@@ -0,0 +1,166 @@
+package plansecret
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/planner"
+	"github.com/rancher/rancher/pkg/metrics"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const (
+	defaultPlanConvergenceTimeout = time.Hour
+	planConvergenceTimeoutReason  = "Timeout"
+	maxStuckPlanOutputLength      = 1024
+)
+
+// convergence is the result of comparing the desired plan of a machine plan secret with the plan the system agent applied.
+type convergence struct {
+	// converged is true if the system agent applied the current plan.
+	converged bool
+	// stuck is true if the current plan has not been applied within the timeout.
+	stuck bool
+	// elapsed is the time since the current plan was set.
+	elapsed time.Duration
+	// known is false if the secret does not record when the plan was set.
+	known bool
+}
+
+// OnChangeConvergence flags machines whose plan has not been applied by the system agent within the
+// machine-plan-convergence-timeout by marking their PlanConverged condition as false with the last output of the plan,
+// so that a plan that never converges surfaces on the machine instead of the cluster waiting indefinitely.
+func (h *handler) OnChangeConvergence(_ string, secret *corev1.Secret) (*corev1.Secret, error) {
+	if secret == nil || secret.Type != capr.SecretTypeMachinePlan || len(secret.Data) == 0 || !secret.DeletionTimestamp.IsZero() {
+		return secret, nil
+	}
+
+	timeout := planConvergenceTimeout()
+	c := planConvergence(secret, time.Now(), timeout)
+	if !c.known {
+		return secret, nil
+	}
+
+	clusterName := secret.Labels[capr.ClusterNameLabel]
+	if c.converged {
+		metrics.ObserveCAPRPlanApplyDuration(secret.Namespace, clusterName, c.elapsed)
+		if err := h.reconcileMachinePlanConvergedCondition(secret, ""); err != nil {
+			return secret, err
+		}
+		secret = secret.DeepCopy()
+		delete(secret.Data, planner.PlanUpdatedAtKey)
+		return h.secrets.Update(secret)
+	}
+
+	if !c.stuck {
+		h.secrets.EnqueueAfter(secret.Namespace, secret.Name, timeout-c.elapsed)
+		return secret, nil
+	}
+
+	node, err := planner.SecretToNode(secret)
+	if err != nil {
+		return secret, err
+	}
+
+	message := fmt.Sprintf("plan has not been applied within %s", timeout)
+	if output := lastPlanOutput(node); output != "" {
+		message = fmt.Sprintf("%s, last output: %s", message, output)
+	}
+	return secret, h.reconcileMachinePlanConvergedCondition(secret, message)
+}
+
+// reconcileMachinePlanConvergedCondition marks the PlanConverged condition of the machine of the secret as false with
+// the given message, or as true if the message is empty.
+func (h *handler) reconcileMachinePlanConvergedCondition(secret *corev1.Secret, stuckMessage string) error {
+	condition := capi.ConditionType(capr.PlanConverged)
+
+	machineName, ok := secret.Labels[capr.MachineNameLabel]
+	if !ok {
+		return fmt.Errorf("did not find machine label on secret %s/%s", secret.Namespace, secret.Name)
+	}
+
+	machine, err := h.machinesCache.Get(secret.Namespace, machineName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if stuckMessage == "" {
+		if conditions.Has(machine, condition) && !conditions.IsTrue(machine, condition) {
+			machine = machine.DeepCopy()
+			conditions.MarkTrue(machine, condition)
+			_, err = h.machinesClient.UpdateStatus(machine)
+		}
+		return err
+	}
+
+	if conditions.IsFalse(machine, condition) && conditions.GetMessage(machine, condition) == stuckMessage {
+		return nil
+	}
+	if !conditions.IsFalse(machine, condition) {
+		logrus.Warnf("[plansecret] machine %s/%s: %s", machine.Namespace, machine.Name, stuckMessage)
+		metrics.IncCAPRPlansStuck(secret.Namespace, secret.Labels[capr.ClusterNameLabel])
+	}
+	machine = machine.DeepCopy()
+	conditions.MarkFalse(machine, condition, planConvergenceTimeoutReason, capi.ConditionSeverityWarning, stuckMessage)
+	_, err = h.machinesClient.UpdateStatus(machine)
+	return err
+}
+
+// planConvergence returns whether the plan of the secret has been applied or has been pending for longer than the
+// timeout, and how long ago the plan was set.
+func planConvergence(secret *corev1.Secret, now time.Time, timeout time.Duration) convergence {
+	updatedAt, err := time.Parse(time.RFC3339, string(secret.Data[planner.PlanUpdatedAtKey]))
+	if err != nil {
+		return convergence{}
+	}
+
+	c := convergence{
+		known:   true,
+		elapsed: now.Sub(updatedAt),
+	}
+	if string(secret.Data["applied-checksum"]) == planner.PlanHash(secret.Data["plan"]) {
+		c.converged = true
+		return c
+	}
+	c.stuck = c.elapsed >= timeout
+	return c
+}
+
+// planConvergenceTimeout returns the machine-plan-convergence-timeout setting, or the default if it is not a valid
+// positive duration.
+func planConvergenceTimeout() time.Duration {
+	timeout, err := time.ParseDuration(settings.MachinePlanConvergenceTimeout.Get())
+	if err != nil || timeout <= 0 {
+		logrus.Warnf("[plansecret] %s setting must be a positive duration, using default: %s", settings.MachinePlanConvergenceTimeout.Name, defaultPlanConvergenceTimeout)
+		return defaultPlanConvergenceTimeout
+	}
+	return timeout
+}
+
+// lastPlanOutput returns the end of the output of the last instruction of the plan that saved its output, if any.
+func lastPlanOutput(node *plan.Node) string {
+	if node == nil {
+		return ""
+	}
+	for i := len(node.Plan.Instructions) - 1; i >= 0; i-- {
+		output := strings.TrimSpace(string(node.Output[node.Plan.Instructions[i].Name]))
+		if output == "" {
+			continue
+		}
+		if len(output) > maxStuckPlanOutputLength {
+			output = "..." + output[len(output)-maxStuckPlanOutputLength:]
+		}
+		return output
+	}
+	return ""
+}
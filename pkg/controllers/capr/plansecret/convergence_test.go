@@ -0,0 +1,84 @@
+package plansecret
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr/planner"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPlanConvergence(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	desired := []byte(`{"instructions":[{"name":"install"}]}`)
+
+	tests := []struct {
+		name     string
+		data     map[string][]byte
+		expected convergence
+	}{
+		{
+			name: "no plan update time",
+			data: map[string][]byte{
+				"plan": desired,
+			},
+		},
+		{
+			name: "pending within timeout",
+			data: map[string][]byte{
+				"plan":                   desired,
+				planner.PlanUpdatedAtKey: []byte(now.Add(-10 * time.Minute).Format(time.RFC3339)),
+			},
+			expected: convergence{known: true, elapsed: 10 * time.Minute},
+		},
+		{
+			name: "pending past timeout",
+			data: map[string][]byte{
+				"plan":                   desired,
+				"applied-checksum":       []byte(planner.PlanHash([]byte(`{}`))),
+				planner.PlanUpdatedAtKey: []byte(now.Add(-2 * time.Hour).Format(time.RFC3339)),
+			},
+			expected: convergence{known: true, stuck: true, elapsed: 2 * time.Hour},
+		},
+		{
+			name: "applied",
+			data: map[string][]byte{
+				"plan":                   desired,
+				"applied-checksum":       []byte(planner.PlanHash(desired)),
+				planner.PlanUpdatedAtKey: []byte(now.Add(-2 * time.Hour).Format(time.RFC3339)),
+			},
+			expected: convergence{known: true, converged: true, elapsed: 2 * time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, planConvergence(&corev1.Secret{Data: tt.data}, now, time.Hour))
+		})
+	}
+}
+
+func TestLastPlanOutput(t *testing.T) {
+	node := &plan.Node{
+		Plan: plan.NodePlan{
+			Instructions: []plan.OneTimeInstruction{
+				{Name: "first"},
+				{Name: "second"},
+				{Name: "third"},
+			},
+		},
+		Output: map[string][]byte{
+			"first":  []byte("ok"),
+			"second": []byte("  error: connection refused\n"),
+		},
+	}
+	assert.Equal(t, "error: connection refused", lastPlanOutput(node))
+
+	node.Output["third"] = []byte(strings.Repeat("a", maxStuckPlanOutputLength) + "b")
+	assert.Equal(t, "..."+strings.Repeat("a", maxStuckPlanOutputLength-1)+"b", lastPlanOutput(node))
+
+	assert.Empty(t, lastPlanOutput(nil))
+}
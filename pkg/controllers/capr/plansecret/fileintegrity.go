@@ -0,0 +1,181 @@
+package plansecret
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/planner"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const (
+	fileDriftReason          = "Drifted"
+	fileRestoredReason       = "Restored"
+	maxFileDriftMessagePaths = 5
+)
+
+// fileDrift is a file of the plan of a machine that was changed or deleted on the machine, as reported by the file
+// integrity instruction.
+type fileDrift struct {
+	Path     string
+	State    string
+	Restored bool
+}
+
+// OnChangeFileIntegrity sets the ManagedFilesIntact condition of the machine of a plan secret from the last output of
+// the file integrity instruction of its plan. The condition is false while files of the plan differ from their content on
+// the machine, with a warning if they were not restored, and is removed if the files of the machine are not monitored.
+func (h *handler) OnChangeFileIntegrity(_ string, secret *corev1.Secret) (*corev1.Secret, error) {
+	if secret == nil || secret.Type != capr.SecretTypeMachinePlan || len(secret.Data) == 0 || !secret.DeletionTimestamp.IsZero() {
+		return secret, nil
+	}
+
+	node, err := planner.SecretToNode(secret)
+	if err != nil {
+		return secret, err
+	}
+
+	output, ok := node.PeriodicOutput[planner.FileIntegrityInstruction]
+	if ok && (output.ExitCode != 0 || output.LastSuccessfulRunTime == "") {
+		// the files could not be checked, so the condition is left as is
+		return secret, nil
+	}
+	drift := parseFileDrift(output.Stdout)
+	if len(drift) > 0 {
+		var restored bool
+		secret, restored, err = h.restoreFiles(secret, output.LastSuccessfulRunTime)
+		if err != nil {
+			return secret, err
+		}
+		for i := range drift {
+			drift[i].Restored = restored
+		}
+	}
+	return secret, h.reconcileMachineManagedFilesIntactCondition(secret, ok, drift)
+}
+
+// restoreFiles restores the files of the plan of the secret that the file integrity run reported as changed, if the
+// control plane of the machine restores them. The applied checksum of the plan is cleared, so that the system agent
+// applies the plan again, which writes its files. The plan is applied again only once for each run, as the output of
+// the run still reports the drift until the instruction runs again. It returns whether the files were restored.
+func (h *handler) restoreFiles(secret *corev1.Secret, run string) (*corev1.Secret, bool, error) {
+	if secret.Annotations[capr.FileIntegrityRestoredAnnotation] == run {
+		return secret, true, nil
+	}
+
+	controlPlane, err := h.controlPlaneCache.Get(secret.Namespace, secret.Labels[capr.ClusterNameLabel])
+	if apierrors.IsNotFound(err) {
+		return secret, false, nil
+	} else if err != nil {
+		return secret, false, err
+	}
+	if controlPlane.Spec.FileIntegrity == nil || !controlPlane.Spec.FileIntegrity.Restore {
+		return secret, false, nil
+	}
+	if string(secret.Data["applied-checksum"]) != planner.PlanHash(secret.Data["plan"]) {
+		// the plan is not applied yet, the files are written once it is
+		return secret, false, nil
+	}
+
+	logrus.Infof("[plansecret] applying the plan of secret %s/%s again to restore files that were changed on the machine", secret.Namespace, secret.Name)
+	secret = secret.DeepCopy()
+	delete(secret.Data, "applied-checksum")
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[capr.FileIntegrityRestoredAnnotation] = run
+	secret, err = h.secrets.Update(secret)
+	if err != nil {
+		return secret, false, err
+	}
+	return secret, true, nil
+}
+
+// reconcileMachineManagedFilesIntactCondition updates the ManagedFilesIntact condition of the machine of the secret
+// with the drift of its files, or removes it if the files are not monitored.
+func (h *handler) reconcileMachineManagedFilesIntactCondition(secret *corev1.Secret, monitored bool, drift []fileDrift) error {
+	condition := capi.ConditionType(capr.ManagedFilesIntact)
+
+	machineName, ok := secret.Labels[capr.MachineNameLabel]
+	if !ok && !monitored {
+		return nil
+	} else if !ok {
+		return fmt.Errorf("did not find machine label on secret %s/%s", secret.Namespace, secret.Name)
+	}
+
+	machine, err := h.machinesCache.Get(secret.Namespace, machineName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	switch {
+	case !monitored:
+		if !conditions.Has(machine, condition) {
+			return nil
+		}
+		machine = machine.DeepCopy()
+		conditions.Delete(machine, condition)
+	case len(drift) == 0:
+		if conditions.IsTrue(machine, condition) {
+			return nil
+		}
+		machine = machine.DeepCopy()
+		conditions.MarkTrue(machine, condition)
+	default:
+		reason, severity, message := fileDriftCondition(drift)
+		if conditions.IsFalse(machine, condition) && conditions.GetReason(machine, condition) == reason && conditions.GetMessage(machine, condition) == message {
+			return nil
+		}
+		logrus.Warnf("[plansecret] machine %s/%s: %s", machine.Namespace, machine.Name, message)
+		machine = machine.DeepCopy()
+		conditions.MarkFalse(machine, condition, reason, severity, message)
+	}
+	_, err = h.machinesClient.UpdateStatus(machine)
+	return err
+}
+
+// fileDriftCondition returns the reason, severity and message of the ManagedFilesIntact condition for the drift. Drift
+// that was restored is informational, as the files of the machine match their plan again.
+func fileDriftCondition(drift []fileDrift) (string, capi.ConditionSeverity, string) {
+	reason, severity, prefix := fileRestoredReason, capi.ConditionSeverityInfo, "applied the plan again to restore files that were changed on the machine"
+	for _, file := range drift {
+		if !file.Restored {
+			reason, severity, prefix = fileDriftReason, capi.ConditionSeverityWarning, "files of the plan were changed on the machine"
+			break
+		}
+	}
+
+	files := make([]string, 0, len(drift))
+	for i, file := range drift {
+		if i == maxFileDriftMessagePaths {
+			files = append(files, fmt.Sprintf("and %d more", len(drift)-i))
+			break
+		}
+		files = append(files, fmt.Sprintf("%s (%s)", file.Path, file.State))
+	}
+	return reason, severity, fmt.Sprintf("%s: %s", prefix, strings.Join(files, ", "))
+}
+
+// parseFileDrift parses the output of the file integrity instruction, which has a line per file of the plan that was
+// changed or deleted, i.e. "modified /etc/rancher/rke2/config.yaml".
+func parseFileDrift(output []byte) []fileDrift {
+	var drift []fileDrift
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		drift = append(drift, fileDrift{State: fields[0], Path: fields[1]})
+	}
+	return drift
+}
@@ -0,0 +1,73 @@
+package plansecret
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestParseFileDrift(t *testing.T) {
+	output := []byte("modified /etc/rancher/rke2/config.yaml\ndeleted /etc/rancher/rke2/registries.yaml\n\nunexpected\ndeleted /var/lib/rancher/rke2/server/manifests/my file.yaml\n")
+
+	assert.Equal(t, []fileDrift{
+		{Path: "/etc/rancher/rke2/config.yaml", State: "modified"},
+		{Path: "/etc/rancher/rke2/registries.yaml", State: "deleted"},
+		{Path: "/var/lib/rancher/rke2/server/manifests/my file.yaml", State: "deleted"},
+	}, parseFileDrift(output))
+	assert.Empty(t, parseFileDrift(nil))
+}
+
+func TestFileDriftCondition(t *testing.T) {
+	tests := []struct {
+		name             string
+		drift            []fileDrift
+		expectedReason   string
+		expectedSeverity capi.ConditionSeverity
+		expectedMessage  string
+	}{
+		{
+			name: "restored",
+			drift: []fileDrift{
+				{Path: "/etc/a", State: "modified", Restored: true},
+			},
+			expectedReason:   fileRestoredReason,
+			expectedSeverity: capi.ConditionSeverityInfo,
+			expectedMessage:  "applied the plan again to restore files that were changed on the machine: /etc/a (modified)",
+		},
+		{
+			name: "drifted",
+			drift: []fileDrift{
+				{Path: "/etc/a", State: "modified", Restored: true},
+				{Path: "/etc/b", State: "deleted"},
+			},
+			expectedReason:   fileDriftReason,
+			expectedSeverity: capi.ConditionSeverityWarning,
+			expectedMessage:  "files of the plan were changed on the machine: /etc/a (modified), /etc/b (deleted)",
+		},
+		{
+			name: "many files",
+			drift: []fileDrift{
+				{Path: "/etc/a", State: "modified"},
+				{Path: "/etc/b", State: "modified"},
+				{Path: "/etc/c", State: "modified"},
+				{Path: "/etc/d", State: "modified"},
+				{Path: "/etc/e", State: "modified"},
+				{Path: "/etc/f", State: "modified"},
+				{Path: "/etc/g", State: "modified"},
+			},
+			expectedReason:   fileDriftReason,
+			expectedSeverity: capi.ConditionSeverityWarning,
+			expectedMessage:  "files of the plan were changed on the machine: /etc/a (modified), /etc/b (modified), /etc/c (modified), /etc/d (modified), /etc/e (modified), and 2 more",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, severity, message := fileDriftCondition(tt.drift)
+			assert.Equal(t, tt.expectedReason, reason)
+			assert.Equal(t, tt.expectedSeverity, severity)
+			assert.Equal(t, tt.expectedMessage, message)
+		})
+	}
+}
@@ -12,6 +12,7 @@
 	"github.com/rancher/rancher/pkg/capr/planner"
 	sb "github.com/rancher/rancher/pkg/controllers/managementuser/snapshotbackpopulate"
 	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
 	rkev1controllers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/wrangler"
 	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
@@ -26,22 +27,32 @@
 )
 
 type handler struct {
-	secrets             corecontrollers.SecretClient
+	secrets             corecontrollers.SecretController
+	secretsCache        corecontrollers.SecretCache
+	controlPlaneCache   rkev1controllers.RKEControlPlaneCache
+	clusterCache        provisioningcontrollers.ClusterCache
 	machinesCache       capicontrollers.MachineCache
 	machinesClient      capicontrollers.MachineClient
 	etcdSnapshotsClient rkev1controllers.ETCDSnapshotClient
 	etcdSnapshotsCache  rkev1controllers.ETCDSnapshotCache
+	s3Stats             *s3StatCache
 }
 
 func Register(ctx context.Context, clients *wrangler.Context) {
 	h := handler{
 		secrets:             clients.Core.Secret(),
+		secretsCache:        clients.Core.Secret().Cache(),
+		controlPlaneCache:   clients.RKE.RKEControlPlane().Cache(),
+		clusterCache:        clients.Provisioning.Cluster().Cache(),
 		machinesCache:       clients.CAPI.Machine().Cache(),
 		machinesClient:      clients.CAPI.Machine(),
 		etcdSnapshotsClient: clients.RKE.ETCDSnapshot(),
 		etcdSnapshotsCache:  clients.RKE.ETCDSnapshot().Cache(),
+		s3Stats:             newS3StatCache(),
 	}
 	clients.Core.Secret().OnChange(ctx, "plan-secret", h.OnChange)
+	clients.Core.Secret().OnChange(ctx, "plan-secret-convergence", h.OnChangeConvergence)
+	clients.Core.Secret().OnChange(ctx, "plan-secret-file-integrity", h.OnChangeFileIntegrity)
 }
 
 func (h *handler) OnChange(key string, secret *corev1.Secret) (*corev1.Secret, error) {
@@ -68,6 +79,24 @@ func (h *handler) OnChange(key string, secret *corev1.Secret) (*corev1.Secret, e
 		}
 	}
 
+	if v, ok := node.Output[planner.ETCDSnapshotChecksumInstruction]; ok && len(v) > 0 {
+		if err := h.reconcileEtcdSnapshotChecksum(secret, v); err != nil {
+			logrus.Errorf("[plansecret] error reconciling etcd snapshot checksum for secret %s/%s: %v", secret.Namespace, secret.Name, err)
+		}
+	}
+
+	if v, ok := node.Output[planner.ETCDSnapshotVerifyInstruction]; ok && len(v) > 0 {
+		if err := h.reconcileEtcdSnapshotVerification(secret, v); err != nil {
+			logrus.Errorf("[plansecret] error reconciling etcd snapshot verification for secret %s/%s: %v", secret.Namespace, secret.Name, err)
+		}
+	}
+
+	if v, ok := node.Output[planner.ETCDSnapshotUploadInstruction]; ok && len(v) > 0 {
+		if err := h.reconcileEtcdSnapshotUpload(secret, v); err != nil {
+			logrus.Errorf("[plansecret] error reconciling etcd snapshot upload for secret %s/%s: %v", secret.Namespace, secret.Name, err)
+		}
+	}
+
 	appliedChecksum := string(secret.Data["applied-checksum"])
 	failedChecksum := string(secret.Data["failed-checksum"])
 	plan := secret.Data["plan"]
@@ -0,0 +1,299 @@
+package plansecret
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	v1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/planner"
+	"github.com/rancher/rancher/pkg/capr/s3client"
+	sb "github.com/rancher/rancher/pkg/controllers/managementuser/snapshotbackpopulate"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	s3StatTimeout = 30 * time.Second
+
+	// checksumSnapshotWaitTimeout is how long after the checksum of a snapshot was reported to wait for the
+	// snapshotbackpopulate controller to create the corresponding S3 etcd snapshot object.
+	checksumSnapshotWaitTimeout = 15 * time.Minute
+	checksumRetryInterval       = 30 * time.Second
+)
+
+// snapshotDigest is the parsed output of the etcd snapshot checksum instruction.
+type snapshotDigest struct {
+	Name    string
+	Created time.Time
+	Size    int64
+	SHA256  string
+	MD5     string
+	// Parts maps a part size to the MD5 digests of the parts of the file when split into parts of that size.
+	Parts map[int64][]string
+}
+
+// reconcileEtcdSnapshotChecksum verifies the S3 object of the snapshot reported by the checksum instruction output
+// against the digests of the snapshot file on the node, and records the result in the status of the S3 etcd snapshot.
+func (h *handler) reconcileEtcdSnapshotChecksum(secret *corev1.Secret, output []byte) error {
+	cnl := secret.Labels[capr.ClusterNameLabel]
+	if len(cnl) == 0 {
+		return fmt.Errorf("node secret did not have label %s", capr.ClusterNameLabel)
+	}
+
+	digest, err := parseSnapshotDigest(output)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := h.etcdSnapshotsCache.List(secret.Namespace, labels.SelectorFromSet(map[string]string{
+		capr.ClusterNameLabel: cnl,
+		capr.NodeNameLabel:    sb.StorageS3,
+	}))
+	if err != nil {
+		return err
+	}
+
+	var snapshot *v1.ETCDSnapshot
+	for _, s := range snapshots {
+		if s.SnapshotFile.Name == digest.Name && s.SnapshotFile.S3 != nil {
+			snapshot = s
+			break
+		}
+	}
+	if snapshot == nil {
+		// The S3 snapshot object is created once the downstream snapshot configmap has been updated, which may not have
+		// happened yet.
+		if time.Since(digest.Created) < checksumSnapshotWaitTimeout {
+			h.secrets.EnqueueAfter(secret.Namespace, secret.Name, checksumRetryInterval)
+		}
+		return nil
+	}
+
+	if snapshot.Status.Checksum != nil && snapshot.Status.Checksum.SHA256 == digest.SHA256 {
+		return nil
+	}
+
+	controlPlane, err := h.controlPlaneCache.Get(secret.Namespace, cnl)
+	if err != nil {
+		return err
+	}
+
+	checksum := &v1.ETCDSnapshotChecksum{
+		SHA256:     digest.SHA256,
+		VerifiedAt: &metav1.Time{Time: time.Now()},
+	}
+	s3 := snapshot.SnapshotFile.S3
+	info, done, err := h.s3Stats.get(fmt.Sprintf("%s/%s/%s", snapshot.Namespace, snapshot.Name, digest.SHA256), func() (minio.ObjectInfo, error) {
+		return h.statS3Object(s3, controlPlane, digest.Name)
+	}, func() {
+		h.secrets.Enqueue(secret.Namespace, secret.Name)
+	})
+	if !done {
+		return nil
+	}
+	if err != nil && time.Since(digest.Created) < checksumSnapshotWaitTimeout {
+		h.secrets.EnqueueAfter(secret.Namespace, secret.Name, checksumRetryInterval)
+		return fmt.Errorf("error retrieving S3 object for etcd snapshot %s/%s: %w", snapshot.Namespace, snapshot.Name, err)
+	} else if err != nil {
+		checksum.Result = v1.ETCDSnapshotChecksumUnverifiable
+		checksum.Message = fmt.Sprintf("unable to retrieve S3 object: %v", err)
+	} else {
+		checksum.ETag = info.ETag
+		checksum.Result, checksum.Message = digest.verify(info.Size, info.ETag)
+	}
+
+	if checksum.Result == v1.ETCDSnapshotChecksumMismatch {
+		logrus.Errorf("[plansecret] etcd snapshot %s/%s: %s", snapshot.Namespace, snapshot.Name, checksum.Message)
+	} else {
+		logrus.Debugf("[plansecret] etcd snapshot %s/%s: checksum verification result %s %s", snapshot.Namespace, snapshot.Name, checksum.Result, checksum.Message)
+	}
+
+	snapshot = snapshot.DeepCopy()
+	snapshot.Status.Checksum = checksum
+	_, err = h.etcdSnapshotsClient.UpdateStatus(snapshot)
+	return err
+}
+
+// s3StatCache retrieves the infos of S3 objects in the background, so that the handler does not block on S3 while
+// other plan secrets are waiting. The result of a retrieval is kept until it is taken by the handler, which is enqueued
+// again once it is available.
+type s3StatCache struct {
+	lock    sync.Mutex
+	results map[string]*s3StatResult
+}
+
+type s3StatResult struct {
+	done bool
+	info minio.ObjectInfo
+	err  error
+}
+
+func newS3StatCache() *s3StatCache {
+	return &s3StatCache{
+		results: map[string]*s3StatResult{},
+	}
+}
+
+// get returns the result of the retrieval with the passed in key and true if it finished, removing it from the cache
+// so that the next call retrieves the object info again. Otherwise, the retrieval is started unless it is already
+// running, and enqueue is called once it finished.
+func (c *s3StatCache) get(key string, stat func() (minio.ObjectInfo, error), enqueue func()) (minio.ObjectInfo, bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if result, ok := c.results[key]; ok {
+		if !result.done {
+			return minio.ObjectInfo{}, false, nil
+		}
+		delete(c.results, key)
+		return result.info, true, result.err
+	}
+
+	result := &s3StatResult{}
+	c.results[key] = result
+	go func() {
+		info, err := stat()
+		c.lock.Lock()
+		result.done, result.info, result.err = true, info, err
+		c.lock.Unlock()
+		enqueue()
+	}()
+	return minio.ObjectInfo{}, false, nil
+}
+
+// statS3Object retrieves the object info of the snapshot file with the passed in name from S3.
+func (h *handler) statS3Object(s3 *v1.ETCDSnapshotS3, controlPlane *v1.RKEControlPlane, fileName string) (minio.ObjectInfo, error) {
+	config, err := planner.GetS3Config(h.secretsCache, s3, controlPlane)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+
+	client, err := s3client.Get(config)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3StatTimeout)
+	defer cancel()
+	return client.Stat(ctx, fileName)
+}
+
+// parseSnapshotDigest parses the output of the etcd snapshot checksum instruction.
+func parseSnapshotDigest(output []byte) (*snapshotDigest, error) {
+	digest := &snapshotDigest{
+		Parts: map[int64][]string{},
+	}
+
+	scanner := bufio.NewScanner(bytes.NewBuffer(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "name":
+			digest.Name = fields[1]
+		case "created":
+			created, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid snapshot checksum creation time %s: %w", fields[1], err)
+			}
+			digest.Created = time.Unix(created, 0)
+		case "size":
+			size, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid snapshot size %s: %w", fields[1], err)
+			}
+			digest.Size = size
+		case "sha256":
+			digest.SHA256 = fields[1]
+		case "md5":
+			digest.MD5 = fields[1]
+		case "parts":
+			partSize, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid snapshot part size %s: %w", fields[1], err)
+			}
+			digest.Parts[partSize] = fields[2:]
+		}
+	}
+
+	if digest.Name == "" || digest.SHA256 == "" || digest.MD5 == "" {
+		return nil, fmt.Errorf("snapshot checksum output is incomplete")
+	}
+	return digest, nil
+}
+
+// verify compares the size and ETag of an S3 object with the digests of the snapshot file. The ETag of an object
+// uploaded in a single part is the MD5 digest of its content, while the ETag of a multipart upload is the MD5 digest of
+// the concatenated binary MD5 digests of its parts, followed by a dash and the number of parts.
+func (d *snapshotDigest) verify(size int64, etag string) (v1.ETCDSnapshotChecksumResult, string) {
+	if size != d.Size {
+		return v1.ETCDSnapshotChecksumMismatch, fmt.Sprintf("S3 object size %d does not match snapshot file size %d", size, d.Size)
+	}
+
+	etag = strings.ToLower(strings.Trim(etag, `"`))
+	hash, count, multipart := strings.Cut(etag, "-")
+	if !multipart {
+		if hash != d.MD5 {
+			return v1.ETCDSnapshotChecksumMismatch, fmt.Sprintf("S3 object ETag %s does not match snapshot file MD5 digest %s", etag, d.MD5)
+		}
+		return v1.ETCDSnapshotChecksumVerified, ""
+	}
+
+	parts, err := strconv.Atoi(count)
+	if err != nil {
+		return v1.ETCDSnapshotChecksumUnverifiable, fmt.Sprintf("S3 object ETag %s is not a multipart upload ETag", etag)
+	}
+
+	partSizes := make([]int64, 0, len(d.Parts))
+	for partSize := range d.Parts {
+		partSizes = append(partSizes, partSize)
+	}
+	sort.Slice(partSizes, func(i, j int) bool { return partSizes[i] < partSizes[j] })
+
+	var candidates []string
+	for _, partSize := range partSizes {
+		if len(d.Parts[partSize]) != parts {
+			continue
+		}
+		expected, err := multipartETag(d.Parts[partSize])
+		if err != nil {
+			return v1.ETCDSnapshotChecksumUnverifiable, err.Error()
+		}
+		if expected == etag {
+			return v1.ETCDSnapshotChecksumVerified, ""
+		}
+		candidates = append(candidates, expected)
+	}
+
+	if len(candidates) == 0 {
+		return v1.ETCDSnapshotChecksumUnverifiable, fmt.Sprintf("part size of multipart S3 object with ETag %s is unknown", etag)
+	}
+	return v1.ETCDSnapshotChecksumMismatch, fmt.Sprintf("S3 object ETag %s does not match snapshot file multipart ETag %s", etag, strings.Join(candidates, ", "))
+}
+
+func multipartETag(parts []string) (string, error) {
+	h := md5.New()
+	for _, part := range parts {
+		b, err := hex.DecodeString(part)
+		if err != nil {
+			return "", fmt.Errorf("invalid part digest %s: %w", part, err)
+		}
+		h.Write(b)
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(h.Sum(nil)), len(parts)), nil
+}
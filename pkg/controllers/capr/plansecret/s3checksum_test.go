@@ -0,0 +1,151 @@
+package plansecret
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	v1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func md5Hex(data string) string {
+	sum := md5.Sum([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseSnapshotDigest(t *testing.T) {
+	output := []byte(`name on-demand-node1-1683115200
+created 1683115260
+size 20
+sha256 4bd2f847f09e2fa4ca8b7b9aee3ad2fd8e9ec1d5c6d27e2c2a9c4e1f45c1d0f8
+md5 8ccd2b8ee5b6c71cd45a1e0a9d1e7e0e
+parts 16 aaaa bbbb
+`)
+
+	digest, err := parseSnapshotDigest(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "on-demand-node1-1683115200", digest.Name)
+	assert.Equal(t, int64(1683115260), digest.Created.Unix())
+	assert.Equal(t, int64(20), digest.Size)
+	assert.Equal(t, "4bd2f847f09e2fa4ca8b7b9aee3ad2fd8e9ec1d5c6d27e2c2a9c4e1f45c1d0f8", digest.SHA256)
+	assert.Equal(t, "8ccd2b8ee5b6c71cd45a1e0a9d1e7e0e", digest.MD5)
+	assert.Equal(t, map[int64][]string{16: {"aaaa", "bbbb"}}, digest.Parts)
+
+	_, err = parseSnapshotDigest([]byte("no etcd snapshot found in /var/lib/rancher/rke2/server/db/snapshots\n"))
+	assert.EqualError(t, err, "snapshot checksum output is incomplete")
+
+	_, err = parseSnapshotDigest([]byte("size abc\n"))
+	assert.Error(t, err)
+}
+
+func TestSnapshotDigestVerify(t *testing.T) {
+	content := "0123456789abcdefghij"
+	parts := []string{md5Hex(content[:16]), md5Hex(content[16:])}
+	multipart, err := multipartETag(parts)
+	assert.NoError(t, err)
+
+	digest := &snapshotDigest{
+		Size: int64(len(content)),
+		MD5:  md5Hex(content),
+		Parts: map[int64][]string{
+			16: parts,
+		},
+	}
+
+	tests := []struct {
+		name           string
+		size           int64
+		etag           string
+		expectedResult v1.ETCDSnapshotChecksumResult
+	}{
+		{
+			name:           "single part",
+			size:           digest.Size,
+			etag:           fmt.Sprintf(`"%s"`, digest.MD5),
+			expectedResult: v1.ETCDSnapshotChecksumVerified,
+		},
+		{
+			name:           "single part mismatch",
+			size:           digest.Size,
+			etag:           md5Hex("corrupted"),
+			expectedResult: v1.ETCDSnapshotChecksumMismatch,
+		},
+		{
+			name:           "size mismatch",
+			size:           digest.Size - 1,
+			etag:           digest.MD5,
+			expectedResult: v1.ETCDSnapshotChecksumMismatch,
+		},
+		{
+			name:           "multipart",
+			size:           digest.Size,
+			etag:           multipart,
+			expectedResult: v1.ETCDSnapshotChecksumVerified,
+		},
+		{
+			name:           "multipart mismatch",
+			size:           digest.Size,
+			etag:           md5Hex("corrupted") + "-2",
+			expectedResult: v1.ETCDSnapshotChecksumMismatch,
+		},
+		{
+			name:           "multipart with unknown part size",
+			size:           digest.Size,
+			etag:           md5Hex("corrupted") + "-3",
+			expectedResult: v1.ETCDSnapshotChecksumUnverifiable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := digest.verify(tt.size, tt.etag)
+			assert.Equal(t, tt.expectedResult, result)
+		})
+	}
+}
+
+func TestS3StatCache(t *testing.T) {
+	cache := newS3StatCache()
+	release := make(chan struct{})
+	enqueued := make(chan struct{}, 1)
+	var calls int
+	stat := func() (minio.ObjectInfo, error) {
+		calls++
+		<-release
+		return minio.ObjectInfo{ETag: fmt.Sprintf("etag-%d", calls)}, nil
+	}
+	enqueue := func() {
+		enqueued <- struct{}{}
+	}
+
+	// the retrieval is started in the background and only once while it is running
+	_, done, err := cache.get("snapshot", stat, enqueue)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	_, done, _ = cache.get("snapshot", stat, enqueue)
+	assert.False(t, done)
+
+	close(release)
+	select {
+	case <-enqueued:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not enqueued after the retrieval finished")
+	}
+
+	info, done, err := cache.get("snapshot", stat, enqueue)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, "etag-1", info.ETag)
+
+	// the result is taken, so the next call retrieves the object info again
+	_, done, _ = cache.get("snapshot", stat, enqueue)
+	assert.False(t, done)
+	<-enqueued
+	info, done, _ = cache.get("snapshot", stat, enqueue)
+	assert.True(t, done)
+	assert.Equal(t, "etag-2", info.ETag)
+}
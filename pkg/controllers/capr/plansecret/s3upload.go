@@ -0,0 +1,156 @@
+package plansecret
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/planner"
+	sb "github.com/rancher/rancher/pkg/controllers/managementuser/snapshotbackpopulate"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// uploadedSnapshot is a snapshot listed in the output of the etcd snapshot upload instruction.
+type uploadedSnapshot struct {
+	Name    string
+	Size    int64
+	Created time.Time
+}
+
+// reconcileEtcdSnapshotUpload records the snapshots that the etcd snapshot upload instruction uploaded, or found in S3
+// already, as S3 etcd snapshots, unless they are recorded already. The metadata of the snapshots is copied from the
+// local etcd snapshots of the node, so that the S3 snapshots can be restored along with the cluster spec.
+func (h *handler) reconcileEtcdSnapshotUpload(secret *corev1.Secret, output []byte) error {
+	cnl := secret.Labels[capr.ClusterNameLabel]
+	if len(cnl) == 0 {
+		return fmt.Errorf("node secret did not have label %s", capr.ClusterNameLabel)
+	}
+
+	uploaded := parseUploadedSnapshots(output)
+	if len(uploaded) == 0 {
+		return nil
+	}
+
+	controlPlane, err := h.controlPlaneCache.Get(secret.Namespace, cnl)
+	if err != nil {
+		return err
+	}
+	if controlPlane.Spec.ETCD == nil || !planner.S3Enabled(controlPlane.Spec.ETCD.S3) {
+		return nil
+	}
+	config, err := planner.GetS3Config(h.secretsCache, controlPlane.Spec.ETCD.S3, controlPlane)
+	if err != nil {
+		return err
+	}
+	cluster, err := h.clusterCache.Get(secret.Namespace, cnl)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := h.etcdSnapshotsCache.List(secret.Namespace, labels.SelectorFromSet(map[string]string{
+		capr.ClusterNameLabel: cnl,
+	}))
+	if err != nil {
+		return err
+	}
+	metadata := map[string]string{}
+	recorded := map[string]bool{}
+	for _, s := range snapshots {
+		if s.SnapshotFile.S3 != nil {
+			recorded[s.SnapshotFile.Name] = true
+		} else if s.SnapshotFile.Metadata != "" {
+			metadata[s.SnapshotFile.Name] = s.SnapshotFile.Metadata
+		}
+	}
+
+	s3 := controlPlane.Spec.ETCD.S3.DeepCopy()
+	s3.Bucket = config.Bucket
+	s3.Folder = config.Folder
+	s3.Region = config.Region
+	s3.Endpoint = config.Endpoint
+
+	for _, u := range uploaded {
+		if recorded[u.Name] {
+			continue
+		}
+		created := metav1.NewTime(u.Created)
+		snapshot := &v1.ETCDSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name.SafeConcatName(cnl, strings.ToLower(sb.InvalidKeyChars.ReplaceAllString(u.Name, "-")), sb.StorageS3),
+				Namespace: secret.Namespace,
+				Labels: map[string]string{
+					capr.ClusterNameLabel: cnl,
+					capr.NodeNameLabel:    sb.StorageS3,
+				},
+				Annotations: map[string]string{
+					sb.SnapshotNameKey:      u.Name,
+					sb.StorageAnnotationKey: sb.StorageS3,
+				},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion:         "provisioning.cattle.io/v1",
+					Kind:               "Cluster",
+					Name:               cluster.Name,
+					UID:                cluster.UID,
+					Controller:         &[]bool{true}[0],
+					BlockOwnerDeletion: &[]bool{true}[0],
+				}},
+			},
+			Spec: v1.ETCDSnapshotSpec{
+				ClusterName: cnl,
+			},
+			SnapshotFile: v1.ETCDSnapshotFile{
+				Name:      u.Name,
+				NodeName:  sb.StorageS3,
+				Location:  fmt.Sprintf("s3://%s/%s", config.Bucket, path.Join(config.Folder, u.Name)),
+				Metadata:  metadata[u.Name],
+				CreatedAt: &created,
+				Size:      u.Size,
+				S3:        s3,
+				Status:    "successful",
+			},
+		}
+		logrus.Infof("[plansecret] creating S3 etcd snapshot %s/%s for uploaded snapshot %s of cluster %s", snapshot.Namespace, snapshot.Name, u.Name, cnl)
+		if _, err := h.etcdSnapshotsClient.Create(snapshot); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("error while creating S3 etcd snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseUploadedSnapshots parses the lines of the output of the etcd snapshot upload instruction that list a snapshot
+// which is stored in S3, as either uploaded or existing. Lines that can not be parsed are ignored.
+func parseUploadedSnapshots(output []byte) []uploadedSnapshot {
+	var result []uploadedSnapshot
+	scanner := bufio.NewScanner(bytes.NewBuffer(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || (fields[0] != "uploaded" && fields[0] != "exists") {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		created, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		result = append(result, uploadedSnapshot{
+			Name:    fields[1],
+			Size:    size,
+			Created: time.Unix(created, 0).UTC(),
+		})
+	}
+	return result
+}
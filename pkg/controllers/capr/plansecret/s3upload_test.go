@@ -0,0 +1,31 @@
+package plansecret
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUploadedSnapshots(t *testing.T) {
+	output := []byte(`exists etcd-snapshot-node1-1683115200 2048 1683115200
+uploaded etcd-snapshot-node1-1683201600 4096 1683201600
+failed to upload etcd snapshot etcd-snapshot-node1-1683288000
+uploaded etcd-snapshot-node1-1683374400 abc 1683374400
+`)
+
+	assert.Equal(t, []uploadedSnapshot{
+		{
+			Name:    "etcd-snapshot-node1-1683115200",
+			Size:    2048,
+			Created: time.Unix(1683115200, 0).UTC(),
+		},
+		{
+			Name:    "etcd-snapshot-node1-1683201600",
+			Size:    4096,
+			Created: time.Unix(1683201600, 0).UTC(),
+		},
+	}, parseUploadedSnapshots(output))
+
+	assert.Empty(t, parseUploadedSnapshots([]byte("curl 7.75 or later is required to upload etcd snapshots to S3\n")))
+}
@@ -0,0 +1,52 @@
+package plansecret
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/planner"
+	sb "github.com/rancher/rancher/pkg/controllers/managementuser/snapshotbackpopulate"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reconcileEtcdSnapshotVerification records the size, digest and verification result of the snapshot file reported by
+// the etcd snapshot verify instruction output in the status of the local etcd snapshot of the file.
+func (h *handler) reconcileEtcdSnapshotVerification(secret *corev1.Secret, output []byte) error {
+	cnl := secret.Labels[capr.ClusterNameLabel]
+	if len(cnl) == 0 {
+		return fmt.Errorf("node secret did not have label %s", capr.ClusterNameLabel)
+	}
+
+	fileName, verification := planner.ParseETCDSnapshotVerifyOutput(output)
+	if fileName == "" || verification.SHA256 == "" {
+		return nil
+	}
+
+	snapshot, err := h.etcdSnapshotsCache.Get(secret.Namespace, name.SafeConcatName(cnl, fileName, sb.StorageLocal))
+	if apierrors.IsNotFound(err) {
+		// The local etcd snapshot object is created once the snapshot was listed on the node, which may not have happened
+		// yet. The output of the instruction is replaced as soon as the next plan is applied, so this is not retried forever.
+		h.secrets.EnqueueAfter(secret.Namespace, secret.Name, checksumRetryInterval)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if current := snapshot.Status.Verification; current != nil && current.SHA256 == verification.SHA256 && current.Result == verification.Result {
+		return nil
+	}
+
+	if verification.Message != "" {
+		logrus.Warnf("[plansecret] etcd snapshot %s/%s: verification result %s: %s", snapshot.Namespace, snapshot.Name, verification.Result, verification.Message)
+	}
+	verification.VerifiedAt = &metav1.Time{Time: time.Now()}
+	snapshot = snapshot.DeepCopy()
+	snapshot.Status.Verification = &verification
+	_, err = h.etcdSnapshotsClient.UpdateStatus(snapshot)
+	return err
+}
@@ -0,0 +1,295 @@
+package sanityprobe
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/provisioningv2/image"
+	"github.com/rancher/rancher/pkg/provisioningv2/kubeconfig"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// GenerationAnnotation is set on the probe pod to the generation of the sanity probes it runs.
+	GenerationAnnotation = "rke.cattle.io/sanity-probe-generation"
+
+	probePodName     = "cattle-sanity-probe"
+	defaultTimeout   = 5 * time.Minute
+	recheckInterval  = 10 * time.Second
+	resultsSeparator = "|"
+)
+
+// probeScript runs the probes and writes one line per probe to the termination log of the pod, made of the name of the
+// probe, whether it passed and a message, separated by the results separator.
+const probeScript = `
+result() {
+  echo "$1|$2|$3" >> /dev/termination-log
+}
+
+if out=$(getent hosts kubernetes.default.svc 2>&1); then
+  result dns true "kubernetes.default.svc resolved to $(echo $out | cut -d' ' -f1)"
+else
+  result dns false "kubernetes.default.svc did not resolve"
+fi
+
+if [ -n "$INGRESS_HOST" ]; then
+  code=$(curl -s -o /dev/null -w '%{http_code}' --max-time 10 -H "Host: $INGRESS_HOST" "http://$HOST_IP/")
+  if [ "$code" -ge 200 ] 2>/dev/null && [ "$code" -lt 400 ]; then
+    result ingress true "$INGRESS_HOST responded with status $code through the ingress controller on $HOST_IP"
+  else
+    result ingress false "$INGRESS_HOST responded with status $code through the ingress controller on $HOST_IP"
+  fi
+fi
+`
+
+type handler struct {
+	clusters  provisioningcontrollers.ClusterController
+	clientFor func(cluster *rancherv1.Cluster) (kubernetes.Interface, error)
+	now       func() time.Time
+}
+
+// Register starts the controller that runs the sanity probes of clusters once they report ready. The probes run in a
+// pod in the cluster, which is created and watched by the controller, and the results are reported in the
+// SanityProbesPassed condition of the cluster.
+func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager *kubeconfig.Manager) {
+	h := &handler{
+		clusters: clients.Provisioning.Cluster(),
+		clientFor: func(cluster *rancherv1.Cluster) (kubernetes.Interface, error) {
+			config, err := kubeconfigManager.GetRESTConfig(cluster, cluster.Status)
+			if err != nil {
+				return nil, err
+			}
+			return kubernetes.NewForConfig(config)
+		},
+		now: time.Now,
+	}
+
+	clients.Provisioning.Cluster().OnChange(ctx, "cluster-sanity-probe", h.OnChange)
+}
+
+// OnChange runs the sanity probes of a ready cluster once per generation of its probes. While the probe pod runs, the
+// cluster is checked again periodically, as the controller does not watch the pods of downstream clusters.
+func (h *handler) OnChange(_ string, cluster *rancherv1.Cluster) (*rancherv1.Cluster, error) {
+	if cluster == nil || !cluster.DeletionTimestamp.IsZero() || cluster.Spec.RKEConfig == nil {
+		return cluster, nil
+	}
+	probes := cluster.Spec.RKEConfig.SanityProbes
+	if probes == nil || !probes.Enabled || !cluster.Status.Ready {
+		return cluster, nil
+	}
+	if completed(cluster, probes) {
+		return cluster, nil
+	}
+
+	client, err := h.clientFor(cluster)
+	if err != nil {
+		return cluster, err
+	}
+	pods := client.CoreV1().Pods(namespace.System)
+
+	pod, err := pods.Get(context.TODO(), probePodName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		logrus.Infof("[sanityprobe] cluster %s/%s: running sanity probes", cluster.Namespace, cluster.Name)
+		if _, err := pods.Create(context.TODO(), probePod(cluster, probes), metav1.CreateOptions{}); err != nil {
+			return cluster, err
+		}
+		h.clusters.EnqueueAfter(cluster.Namespace, cluster.Name, recheckInterval)
+		return h.running(cluster)
+	} else if err != nil {
+		return cluster, err
+	}
+
+	if pod.Annotations[GenerationAnnotation] != strconv.FormatInt(probes.Generation, 10) {
+		// the pod was left from a previous generation of the probes, it is replaced once it is gone
+		h.clusters.EnqueueAfter(cluster.Namespace, cluster.Name, recheckInterval)
+		return cluster, deletePod(client, pod.Name)
+	}
+
+	var checks []rancherv1.SanityProbeCheck
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded, corev1.PodFailed:
+		checks = podResults(pod, probes)
+	default:
+		timeout := defaultTimeout
+		if probes.Timeout != nil {
+			timeout = probes.Timeout.Duration
+		}
+		if h.now().Sub(pod.CreationTimestamp.Time) <= timeout {
+			h.clusters.EnqueueAfter(cluster.Namespace, cluster.Name, recheckInterval)
+			return h.running(cluster)
+		}
+		checks = []rancherv1.SanityProbeCheck{{
+			Name:    "pod",
+			Message: fmt.Sprintf("probe pod did not complete within %s, it is %s", timeout, podState(pod)),
+		}}
+	}
+
+	if err := deletePod(client, pod.Name); err != nil {
+		return cluster, err
+	}
+	return h.complete(cluster, probes, checks)
+}
+
+// completed returns whether the results of the current generation of the probes are recorded on the cluster.
+func completed(cluster *rancherv1.Cluster, probes *rancherv1.SanityProbes) bool {
+	return cluster.Status.SanityProbes != nil &&
+		cluster.Status.SanityProbes.ObservedGeneration == probes.Generation &&
+		(capr.SanityProbesPassed.IsTrue(cluster) || capr.SanityProbesPassed.IsFalse(cluster))
+}
+
+// running reports that the probes of the cluster are running.
+func (h *handler) running(cluster *rancherv1.Cluster) (*rancherv1.Cluster, error) {
+	if capr.SanityProbesPassed.IsUnknown(cluster) {
+		return cluster, nil
+	}
+	cluster = cluster.DeepCopy()
+	capr.SanityProbesPassed.Unknown(cluster)
+	capr.SanityProbesPassed.Reason(cluster, "Running")
+	capr.SanityProbesPassed.Message(cluster, "sanity probes are running")
+	return h.clusters.UpdateStatus(cluster)
+}
+
+// complete records the results of the probes on the cluster.
+func (h *handler) complete(cluster *rancherv1.Cluster, probes *rancherv1.SanityProbes, checks []rancherv1.SanityProbeCheck) (*rancherv1.Cluster, error) {
+	var failed []string
+	for _, check := range checks {
+		if !check.Passed {
+			failed = append(failed, fmt.Sprintf("%s: %s", check.Name, check.Message))
+		}
+	}
+
+	cluster = cluster.DeepCopy()
+	cluster.Status.SanityProbes = &rancherv1.SanityProbesStatus{
+		ObservedGeneration: probes.Generation,
+		Checks:             checks,
+	}
+	if len(failed) > 0 {
+		logrus.Warnf("[sanityprobe] cluster %s/%s: sanity probes failed: %s", cluster.Namespace, cluster.Name, strings.Join(failed, ", "))
+		capr.SanityProbesPassed.False(cluster)
+		capr.SanityProbesPassed.Reason(cluster, "Failed")
+		capr.SanityProbesPassed.Message(cluster, strings.Join(failed, ", "))
+	} else {
+		logrus.Infof("[sanityprobe] cluster %s/%s: sanity probes passed", cluster.Namespace, cluster.Name)
+		capr.SanityProbesPassed.True(cluster)
+		capr.SanityProbesPassed.Reason(cluster, "")
+		capr.SanityProbesPassed.Message(cluster, "")
+	}
+	return h.clusters.UpdateStatus(cluster)
+}
+
+// probePod returns the pod that runs the probes in the cluster.
+func probePod(cluster *rancherv1.Cluster, probes *rancherv1.SanityProbes) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      probePodName,
+			Namespace: namespace.System,
+			Annotations: map[string]string{
+				GenerationAnnotation: strconv.FormatInt(probes.Generation, 10),
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:    "probe",
+				Image:   image.ResolveWithCluster(settings.ShellImage.Get(), cluster),
+				Command: []string{"sh", "-c", probeScript},
+				Env: []corev1.EnvVar{
+					{
+						Name:  "INGRESS_HOST",
+						Value: probes.IngressHost,
+					},
+					{
+						Name: "HOST_IP",
+						ValueFrom: &corev1.EnvVarSource{
+							FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"},
+						},
+					},
+				},
+				TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+			}},
+		},
+	}
+	if secret := image.GetPrivateRepoSecretFromCluster(cluster); secret != "" {
+		pod.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: secret}}
+	}
+	return pod
+}
+
+// podResults returns the results of the probes from the termination message of a completed probe pod. The pod check
+// passes if the pod succeeded, and probes that did not report a result fail.
+func podResults(pod *corev1.Pod, probes *rancherv1.SanityProbes) []rancherv1.SanityProbeCheck {
+	checks := []rancherv1.SanityProbeCheck{{
+		Name:    "pod",
+		Passed:  pod.Status.Phase == corev1.PodSucceeded,
+		Message: fmt.Sprintf("probe pod ran on node %s and is %s", pod.Spec.NodeName, podState(pod)),
+	}}
+
+	var message string
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			message = status.State.Terminated.Message
+		}
+	}
+	results := map[string]rancherv1.SanityProbeCheck{}
+	for _, line := range strings.Split(message, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), resultsSeparator, 3)
+		if len(parts) != 3 {
+			continue
+		}
+		results[parts[0]] = rancherv1.SanityProbeCheck{Name: parts[0], Passed: parts[1] == "true", Message: parts[2]}
+	}
+
+	expected := []string{"dns"}
+	if probes.IngressHost != "" {
+		expected = append(expected, "ingress")
+	}
+	for _, name := range expected {
+		check, ok := results[name]
+		if !ok {
+			check = rancherv1.SanityProbeCheck{Name: name, Message: fmt.Sprintf("probe pod did not report a result, it is %s", podState(pod))}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// podState describes the phase of the pod and the reason it is in it, if known.
+func podState(pod *corev1.Pod) string {
+	state := strings.ToLower(string(pod.Status.Phase))
+	if state == "" {
+		state = "pending"
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		switch {
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "":
+			return fmt.Sprintf("%s: %s", state, status.State.Waiting.Reason)
+		case status.State.Terminated != nil && status.State.Terminated.Reason != "":
+			return fmt.Sprintf("%s: %s", state, status.State.Terminated.Reason)
+		}
+	}
+	if pod.Status.Reason != "" {
+		return fmt.Sprintf("%s: %s", state, pod.Status.Reason)
+	}
+	return state
+}
+
+func deletePod(client kubernetes.Interface, name string) error {
+	err := client.CoreV1().Pods(namespace.System).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
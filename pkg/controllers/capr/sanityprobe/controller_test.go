@@ -0,0 +1,96 @@
+package sanityprobe
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodResults(t *testing.T) {
+	terminated := func(phase corev1.PodPhase, message string) *corev1.Pod {
+		return &corev1.Pod{
+			Spec: corev1.PodSpec{NodeName: "node1"},
+			Status: corev1.PodStatus{
+				Phase: phase,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message, Reason: "Completed"}},
+				}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		probes   *rancherv1.SanityProbes
+		expected []rancherv1.SanityProbeCheck
+	}{
+		{
+			name:   "dns passed",
+			pod:    terminated(corev1.PodSucceeded, "dns|true|kubernetes.default.svc resolved to 10.43.0.1\n"),
+			probes: &rancherv1.SanityProbes{Enabled: true},
+			expected: []rancherv1.SanityProbeCheck{
+				{Name: "pod", Passed: true, Message: "probe pod ran on node node1 and is succeeded: Completed"},
+				{Name: "dns", Passed: true, Message: "kubernetes.default.svc resolved to 10.43.0.1"},
+			},
+		},
+		{
+			name:   "ingress failed",
+			pod:    terminated(corev1.PodSucceeded, "dns|true|resolved\ningress|false|app.example.com responded with status 503\n"),
+			probes: &rancherv1.SanityProbes{Enabled: true, IngressHost: "app.example.com"},
+			expected: []rancherv1.SanityProbeCheck{
+				{Name: "pod", Passed: true, Message: "probe pod ran on node node1 and is succeeded: Completed"},
+				{Name: "dns", Passed: true, Message: "resolved"},
+				{Name: "ingress", Passed: false, Message: "app.example.com responded with status 503"},
+			},
+		},
+		{
+			name:   "no results",
+			pod:    terminated(corev1.PodFailed, ""),
+			probes: &rancherv1.SanityProbes{Enabled: true, IngressHost: "app.example.com"},
+			expected: []rancherv1.SanityProbeCheck{
+				{Name: "pod", Passed: false, Message: "probe pod ran on node node1 and is failed: Completed"},
+				{Name: "dns", Passed: false, Message: "probe pod did not report a result, it is failed: Completed"},
+				{Name: "ingress", Passed: false, Message: "probe pod did not report a result, it is failed: Completed"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, podResults(tt.pod, tt.probes))
+		})
+	}
+}
+
+func TestCompleted(t *testing.T) {
+	cluster := func(status string, observedGeneration int64) *rancherv1.Cluster {
+		cluster := &rancherv1.Cluster{}
+		if status != "" {
+			capr.SanityProbesPassed.SetStatus(cluster, status)
+			cluster.Status.SanityProbes = &rancherv1.SanityProbesStatus{ObservedGeneration: observedGeneration}
+		}
+		return cluster
+	}
+	probes := &rancherv1.SanityProbes{Enabled: true, Generation: 2}
+
+	assert.False(t, completed(cluster("", 0), probes))
+	assert.False(t, completed(cluster("Unknown", 2), probes))
+	assert.False(t, completed(cluster("True", 1), probes))
+	assert.True(t, completed(cluster("True", 2), probes))
+	assert.True(t, completed(cluster("False", 2), probes))
+}
+
+func TestProbePod(t *testing.T) {
+	cluster := &rancherv1.Cluster{Spec: rancherv1.ClusterSpec{RKEConfig: &rancherv1.RKEConfig{}}}
+	pod := probePod(cluster, &rancherv1.SanityProbes{Enabled: true, IngressHost: "app.example.com", Generation: 3})
+
+	assert.Equal(t, probePodName, pod.Name)
+	assert.Equal(t, "3", pod.Annotations[GenerationAnnotation])
+	assert.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	assert.Equal(t, corev1.EnvVar{Name: "INGRESS_HOST", Value: "app.example.com"}, pod.Spec.Containers[0].Env[0])
+	assert.Equal(t, "status.hostIP", pod.Spec.Containers[0].Env[1].ValueFrom.FieldRef.FieldPath)
+	assert.Empty(t, pod.Spec.ImagePullSecrets)
+}
@@ -0,0 +1,577 @@
+package snapshotdrill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	lassodynamic "github.com/rancher/lasso/pkg/dynamic"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/snapshotclient"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/kubeconfig"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/apply"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+)
+
+const (
+	// DrillLabel is set on the sandbox cluster of a drill to the name of the drill.
+	DrillLabel = "rke.cattle.io/etcd-snapshot-drill"
+	// DrillNamespaceLabel is set on the sandbox cluster of a drill to the namespace of the drill.
+	DrillNamespaceLabel = "rke.cattle.io/etcd-snapshot-drill-namespace"
+
+	defaultTimeout       = time.Hour
+	drillRecheckInterval = 15 * time.Second
+	sandboxPoolName      = "sandbox"
+)
+
+type handler struct {
+	drills            rkecontroller.ETCDSnapshotDrillController
+	etcdSnapshotCache rkecontroller.ETCDSnapshotCache
+	clusters          provisioningcontrollers.ClusterController
+	clusterCache      provisioningcontrollers.ClusterCache
+	controlPlaneCache rkecontroller.RKEControlPlaneCache
+	namespaces        corecontrollers.NamespaceClient
+	dynamic           *lassodynamic.Controller
+	apply             apply.Apply
+	kubeconfigManager *kubeconfig.Manager
+	quiesce           func(cluster *rancherv1.Cluster) error
+	verify            func(cluster *rancherv1.Cluster, drill *rkev1.ETCDSnapshotDrill) []rkev1.ETCDSnapshotDrillCheck
+}
+
+// Register starts the controller that runs etcd snapshot drills. A drill provisions a single node sandbox cluster from
+// the cluster spec recorded in the snapshot, restores the snapshot into it, verifies the restored cluster and removes
+// the sandbox again, so that backups can be tested without touching the cluster they were taken from.
+func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager *kubeconfig.Manager) {
+	h := &handler{
+		drills:            clients.RKE.ETCDSnapshotDrill(),
+		etcdSnapshotCache: clients.RKE.ETCDSnapshot().Cache(),
+		clusters:          clients.Provisioning.Cluster(),
+		clusterCache:      clients.Provisioning.Cluster().Cache(),
+		controlPlaneCache: clients.RKE.RKEControlPlane().Cache(),
+		namespaces:        clients.Core.Namespace(),
+		dynamic:           clients.Dynamic,
+		apply:             clients.Apply.WithSetID("etcd-snapshot-drill").WithDynamicLookup(),
+		kubeconfigManager: kubeconfigManager,
+	}
+	h.quiesce = h.quiesceSandbox
+	h.verify = h.verifySandbox
+
+	clients.RKE.ETCDSnapshotDrill().OnChange(ctx, "etcd-snapshot-drill", h.OnChange)
+	clients.RKE.ETCDSnapshotDrill().OnRemove(ctx, "etcd-snapshot-drill-remove", h.OnRemove)
+	relatedresource.Watch(ctx, "etcd-snapshot-drill-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		cluster, ok := obj.(*rancherv1.Cluster)
+		if !ok || cluster.Labels[DrillLabel] == "" {
+			return nil, nil
+		}
+		if cluster.Labels[DrillNamespaceLabel] != "" {
+			namespace = cluster.Labels[DrillNamespaceLabel]
+		}
+		return []relatedresource.Key{{Namespace: namespace, Name: cluster.Labels[DrillLabel]}}, nil
+	}, clients.RKE.ETCDSnapshotDrill(), clients.Provisioning.Cluster())
+}
+
+// OnChange moves the drill through its phases. Drills that are still running are checked again periodically, so that
+// the restore progress and the timeout are observed even if the sandbox cluster does not change.
+func (h *handler) OnChange(_ string, drill *rkev1.ETCDSnapshotDrill) (*rkev1.ETCDSnapshotDrill, error) {
+	if drill == nil || drill.DeletionTimestamp != nil {
+		return drill, nil
+	}
+
+	switch drill.Status.Phase {
+	case rkev1.ETCDSnapshotDrillPhaseSucceeded, rkev1.ETCDSnapshotDrillPhaseFailed:
+		if drill.Spec.KeepSandbox {
+			return drill, nil
+		}
+		removed, err := h.removeSandbox(drill)
+		if err == nil && !removed {
+			h.drills.EnqueueAfter(drill.Namespace, drill.Name, drillRecheckInterval)
+		}
+		return drill, err
+	case "":
+		drill = drill.DeepCopy()
+		drill.Status.Phase = rkev1.ETCDSnapshotDrillPhaseProvisioning
+		drill.Status.SandboxNamespace = name.SafeConcatName("drill", drill.Namespace, drill.Name)
+		drill.Status.SandboxClusterName = name.SafeConcatName("drill", drill.Name)
+		drill.Status.StartTime = &metav1.Time{Time: time.Now()}
+		return h.drills.UpdateStatus(drill)
+	}
+
+	timeout := defaultTimeout
+	if drill.Spec.Timeout != nil {
+		timeout = drill.Spec.Timeout.Duration
+	}
+	if drill.Status.StartTime != nil && time.Since(drill.Status.StartTime.Time) > timeout {
+		return h.complete(drill, rkev1.ETCDSnapshotDrillPhaseFailed, fmt.Sprintf("drill did not complete within %s while %s", timeout, drill.Status.Phase), nil)
+	}
+
+	var (
+		result *rkev1.ETCDSnapshotDrill
+		err    error
+	)
+	switch drill.Status.Phase {
+	case rkev1.ETCDSnapshotDrillPhaseProvisioning:
+		result, err = h.provision(drill)
+	case rkev1.ETCDSnapshotDrillPhaseRestoring:
+		result, err = h.restore(drill)
+	case rkev1.ETCDSnapshotDrillPhaseVerifying:
+		result, err = h.verifying(drill)
+	default:
+		return drill, nil
+	}
+
+	var drillErr *drillError
+	if errors.As(err, &drillErr) {
+		return h.complete(drill, rkev1.ETCDSnapshotDrillPhaseFailed, drillErr.Error(), nil)
+	}
+	if err == nil && result != nil && (result.Status.Phase == rkev1.ETCDSnapshotDrillPhaseSucceeded || result.Status.Phase == rkev1.ETCDSnapshotDrillPhaseFailed) {
+		return result, nil
+	}
+	h.drills.EnqueueAfter(drill.Namespace, drill.Name, drillRecheckInterval)
+	return result, err
+}
+
+// drillError is a problem that fails the drill instead of being retried.
+type drillError struct {
+	message string
+}
+
+func (e *drillError) Error() string {
+	return e.message
+}
+
+func failDrill(format string, args ...interface{}) error {
+	return &drillError{message: fmt.Sprintf(format, args...)}
+}
+
+// OnRemove removes the sandbox cluster and the sandbox namespace of a drill that is deleted, even if the sandbox was to
+// be kept.
+func (h *handler) OnRemove(_ string, drill *rkev1.ETCDSnapshotDrill) (*rkev1.ETCDSnapshotDrill, error) {
+	removed, err := h.removeSandbox(drill)
+	if err != nil {
+		return drill, err
+	}
+	if !removed {
+		return drill, fmt.Errorf("waiting for sandbox cluster %s/%s to be removed", sandboxNamespace(drill), drill.Status.SandboxClusterName)
+	}
+	return drill, nil
+}
+
+// sandboxNamespace returns the namespace of the sandbox cluster of the drill. Drills started before sandboxes were
+// given a namespace of their own have their sandbox in the namespace of the drill.
+func sandboxNamespace(drill *rkev1.ETCDSnapshotDrill) string {
+	if drill.Status.SandboxNamespace == "" {
+		return drill.Namespace
+	}
+	return drill.Status.SandboxNamespace
+}
+
+// provision creates the sandbox namespace and cluster, and starts the restore of the snapshot once the sandbox is
+// ready.
+func (h *handler) provision(drill *rkev1.ETCDSnapshotDrill) (*rkev1.ETCDSnapshotDrill, error) {
+	cluster, err := h.clusterCache.Get(sandboxNamespace(drill), drill.Status.SandboxClusterName)
+	if apierrors.IsNotFound(err) {
+		snapshot, err := h.etcdSnapshotCache.Get(drill.Namespace, drill.Spec.SnapshotName)
+		if apierrors.IsNotFound(err) {
+			return drill, failDrill("etcd snapshot %s/%s does not exist", drill.Namespace, drill.Spec.SnapshotName)
+		} else if err != nil {
+			return drill, err
+		}
+		cluster, err := sandboxCluster(drill, snapshot)
+		if err != nil {
+			return drill, err
+		}
+		if err := h.createSandboxNamespace(drill, snapshot); err != nil {
+			return drill, err
+		}
+		logrus.Infof("[snapshotdrill] drill %s/%s: creating sandbox cluster %s for etcd snapshot %s", drill.Namespace, drill.Name, cluster.Name, snapshot.Name)
+		_, err = h.clusters.Create(cluster)
+		return drill, err
+	} else if err != nil {
+		return drill, err
+	}
+
+	if !cluster.Status.Ready {
+		return drill, nil
+	}
+
+	cluster = cluster.DeepCopy()
+	cluster.Spec.RKEConfig.ETCDSnapshotRestore = &rkev1.ETCDSnapshotRestore{
+		Name:             drill.Spec.SnapshotName,
+		Generation:       1,
+		RestoreRKEConfig: "none",
+	}
+	logrus.Infof("[snapshotdrill] drill %s/%s: restoring etcd snapshot %s to sandbox cluster %s", drill.Namespace, drill.Name, drill.Spec.SnapshotName, cluster.Name)
+	if _, err := h.clusters.Update(cluster); err != nil {
+		return drill, err
+	}
+
+	drill = drill.DeepCopy()
+	drill.Status.Phase = rkev1.ETCDSnapshotDrillPhaseRestoring
+	return h.drills.UpdateStatus(drill)
+}
+
+// createSandboxNamespace creates the namespace of the sandbox cluster, along with copies of the snapshot and of the
+// machine config of the drill, as the sandbox cluster can only reference objects in its own namespace. Secrets of the
+// namespace of the drill, i.e. registry credentials of the cluster the snapshot was taken from, are not copied.
+func (h *handler) createSandboxNamespace(drill *rkev1.ETCDSnapshotDrill, snapshot *rkev1.ETCDSnapshot) error {
+	if drill.Status.SandboxNamespace == "" {
+		return nil
+	}
+	ref := drill.Spec.MachineConfigRef
+	apiVersion := ref.APIVersion
+	if apiVersion == "" {
+		apiVersion = capr.DefaultMachineConfigAPIVersion
+	}
+	machineConfig, err := h.dynamic.Get(schema.FromAPIVersionAndKind(apiVersion, ref.Kind), drill.Namespace, ref.Name)
+	if apierrors.IsNotFound(err) {
+		return failDrill("machine config %s %s/%s does not exist", ref.Kind, drill.Namespace, ref.Name)
+	} else if err != nil {
+		return err
+	}
+	objs, err := sandboxObjects(drill, snapshot, machineConfig)
+	if err != nil {
+		return err
+	}
+	return h.apply.WithOwner(drill).ApplyObjects(objs...)
+}
+
+// sandboxObjects returns the namespace of the sandbox cluster of the drill, and the copies of the snapshot and of the
+// machine config in it.
+func sandboxObjects(drill *rkev1.ETCDSnapshotDrill, snapshot *rkev1.ETCDSnapshot, machineConfig runtime.Object) ([]runtime.Object, error) {
+	labels := map[string]string{
+		DrillLabel:          drill.Name,
+		DrillNamespaceLabel: drill.Namespace,
+	}
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   drill.Status.SandboxNamespace,
+			Labels: labels,
+		},
+	}
+	snapshotCopy := &rkev1.ETCDSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        snapshot.Name,
+			Namespace:   drill.Status.SandboxNamespace,
+			Labels:      labels,
+			Annotations: snapshot.Annotations,
+		},
+		SnapshotFile: *snapshot.SnapshotFile.DeepCopy(),
+	}
+
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(machineConfig)
+	if err != nil {
+		return nil, err
+	}
+	original := &unstructured.Unstructured{Object: data}
+	configCopy := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for k, v := range original.DeepCopy().Object {
+		if k != "metadata" && k != "status" {
+			configCopy.Object[k] = v
+		}
+	}
+	configCopy.SetName(original.GetName())
+	configCopy.SetNamespace(drill.Status.SandboxNamespace)
+	configCopy.SetLabels(labels)
+
+	return []runtime.Object{ns, snapshotCopy, configCopy}, nil
+}
+
+// restore waits for the restore of the snapshot into the sandbox cluster to finish.
+func (h *handler) restore(drill *rkev1.ETCDSnapshotDrill) (*rkev1.ETCDSnapshotDrill, error) {
+	cp, err := h.controlPlaneCache.Get(sandboxNamespace(drill), drill.Status.SandboxClusterName)
+	if apierrors.IsNotFound(err) {
+		return drill, failDrill("control plane of sandbox cluster %s does not exist", drill.Status.SandboxClusterName)
+	} else if err != nil {
+		return drill, err
+	}
+
+	switch snapshotclient.RestorePhase(cp, drill.Spec.SnapshotName) {
+	case rkev1.ETCDSnapshotPhaseFinished:
+		drill = drill.DeepCopy()
+		drill.Status.Phase = rkev1.ETCDSnapshotDrillPhaseVerifying
+		return h.drills.UpdateStatus(drill)
+	case rkev1.ETCDSnapshotPhaseFailed:
+		return drill, failDrill("restore of etcd snapshot %s failed", drill.Spec.SnapshotName)
+	}
+	return drill, nil
+}
+
+// verifying scales the restored workloads of the sandbox cluster to zero and runs the verification against it once it
+// is ready again after the restore.
+func (h *handler) verifying(drill *rkev1.ETCDSnapshotDrill) (*rkev1.ETCDSnapshotDrill, error) {
+	cluster, err := h.clusterCache.Get(sandboxNamespace(drill), drill.Status.SandboxClusterName)
+	if apierrors.IsNotFound(err) {
+		return drill, failDrill("sandbox cluster %s does not exist", drill.Status.SandboxClusterName)
+	} else if err != nil {
+		return drill, err
+	}
+	if !cluster.Status.Ready {
+		return drill, nil
+	}
+	if err := h.quiesce(cluster); err != nil {
+		return drill, err
+	}
+
+	checks := h.verify(cluster, drill)
+	for _, check := range checks {
+		if !check.Passed {
+			return h.complete(drill, rkev1.ETCDSnapshotDrillPhaseFailed, fmt.Sprintf("verification %s failed", check.Name), checks)
+		}
+	}
+	return h.complete(drill, rkev1.ETCDSnapshotDrillPhaseSucceeded, fmt.Sprintf("etcd snapshot %s was restored and verified", drill.Spec.SnapshotName), checks)
+}
+
+// complete records the result of the drill. The sandbox cluster is removed once the result is recorded.
+func (h *handler) complete(drill *rkev1.ETCDSnapshotDrill, phase rkev1.ETCDSnapshotDrillPhase, message string, checks []rkev1.ETCDSnapshotDrillCheck) (*rkev1.ETCDSnapshotDrill, error) {
+	logrus.Infof("[snapshotdrill] drill %s/%s: %s: %s", drill.Namespace, drill.Name, phase, message)
+	drill = drill.DeepCopy()
+	drill.Status.Phase = phase
+	drill.Status.Message = message
+	drill.Status.Checks = checks
+	drill.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+	return h.drills.UpdateStatus(drill)
+}
+
+// removeSandbox deletes the sandbox cluster of the drill, and the sandbox namespace once the cluster is gone, so that the
+// machines of the cluster are removed before the namespace they are removed from terminates. It returns whether the
+// sandbox is removed.
+func (h *handler) removeSandbox(drill *rkev1.ETCDSnapshotDrill) (bool, error) {
+	if drill.Status.SandboxClusterName == "" {
+		return true, nil
+	}
+	cluster, err := h.clusterCache.Get(sandboxNamespace(drill), drill.Status.SandboxClusterName)
+	if err == nil {
+		if cluster.DeletionTimestamp == nil {
+			err := h.clusters.Delete(cluster.Namespace, cluster.Name, &metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return false, err
+			}
+		}
+		return false, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	if drill.Status.SandboxNamespace == "" {
+		return true, nil
+	}
+	err = h.namespaces.Delete(drill.Status.SandboxNamespace, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// sandboxCluster returns the sandbox cluster for the drill, built from the cluster spec recorded in the snapshot. The
+// sandbox has a single machine with all roles and neither takes snapshots nor has S3 configured, so that it does not
+// write into or prune the snapshot location of the cluster the snapshot was taken from. The snapshot itself is restored
+// from the S3 location recorded on it. The machine is tainted so that the restored workloads of the cluster the snapshot
+// was taken from, including its cluster agent, are not scheduled; only the cluster agent of the sandbox, which
+// tolerates the taints of the machine, runs. References to registry secrets are dropped, as the secrets of the cluster
+// the snapshot was taken from are not available to the sandbox.
+func sandboxCluster(drill *rkev1.ETCDSnapshotDrill, snapshot *rkev1.ETCDSnapshot) (*rancherv1.Cluster, error) {
+	if snapshot.SnapshotFile.S3 == nil {
+		return nil, failDrill("etcd snapshot %s is not stored in S3 and can not be restored to a sandbox cluster", snapshot.Name)
+	}
+	if drill.Spec.MachineConfigRef == nil {
+		return nil, failDrill("machineConfigRef must be set")
+	}
+	spec, err := capr.ParseSnapshotClusterSpecOrError(snapshot)
+	if err != nil {
+		return nil, failDrill("etcd snapshot %s does not record the spec of its cluster: %v", snapshot.Name, err)
+	}
+	if spec.RKEConfig == nil {
+		return nil, failDrill("etcd snapshot %s was not taken from a cluster provisioned by Rancher", snapshot.Name)
+	}
+
+	common := *spec.RKEConfig.RKEClusterSpecCommon.DeepCopy()
+	if common.ETCD == nil {
+		common.ETCD = &rkev1.ETCD{}
+	}
+	common.ETCD.DisableSnapshots = true
+	common.ETCD.SnapshotScheduleCron = ""
+	common.ETCD.S3 = nil
+	if common.Registries != nil {
+		for name, config := range common.Registries.Configs {
+			config.AuthConfigSecretName = ""
+			config.TLSSecretName = ""
+			common.Registries.Configs[name] = config
+		}
+	}
+
+	quantity := int32(1)
+	return &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      drill.Status.SandboxClusterName,
+			Namespace: sandboxNamespace(drill),
+			Labels: map[string]string{
+				DrillLabel:          drill.Name,
+				DrillNamespaceLabel: drill.Namespace,
+			},
+		},
+		Spec: rancherv1.ClusterSpec{
+			CloudCredentialSecretName: drill.Spec.CloudCredentialSecretName,
+			KubernetesVersion:         spec.KubernetesVersion,
+			RKEConfig: &rancherv1.RKEConfig{
+				RKEClusterSpecCommon: common,
+				MachinePools: []rancherv1.RKEMachinePool{{
+					RKECommonNodeConfig: rkev1.RKECommonNodeConfig{
+						Taints: []corev1.Taint{{
+							Key:    DrillLabel,
+							Value:  drill.Name,
+							Effect: corev1.TaintEffectNoExecute,
+						}},
+					},
+					Name:             sandboxPoolName,
+					EtcdRole:         true,
+					ControlPlaneRole: true,
+					WorkerRole:       true,
+					Quantity:         &quantity,
+					NodeConfig:       drill.Spec.MachineConfigRef.DeepCopy(),
+				}},
+			},
+		},
+	}, nil
+}
+
+// quiesceSandbox scales the restored deployments and statefulsets of the sandbox cluster to zero and suspends its
+// cronjobs, so that the workloads of the cluster the snapshot was taken from do not run in the sandbox even if they
+// tolerate its taints. The system components in kube-system and the cluster agent of the sandbox are left running.
+func (h *handler) quiesceSandbox(cluster *rancherv1.Cluster) error {
+	config, err := h.kubeconfigManager.GetRESTConfig(cluster, cluster.Status)
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	ctx := context.TODO()
+	scaleToZero := []byte(`{"spec":{"replicas":0}}`)
+
+	deployments, err := client.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, deployment := range deployments.Items {
+		if keepRunning(deployment.Namespace, deployment.Name) || (deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == 0) {
+			continue
+		}
+		if _, err := client.AppsV1().Deployments(deployment.Namespace).Patch(ctx, deployment.Name, types.MergePatchType, scaleToZero, metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	}
+
+	statefulSets, err := client.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, statefulSet := range statefulSets.Items {
+		if keepRunning(statefulSet.Namespace, statefulSet.Name) || (statefulSet.Spec.Replicas != nil && *statefulSet.Spec.Replicas == 0) {
+			continue
+		}
+		if _, err := client.AppsV1().StatefulSets(statefulSet.Namespace).Patch(ctx, statefulSet.Name, types.MergePatchType, scaleToZero, metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	}
+
+	cronJobs, err := client.BatchV1().CronJobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, cronJob := range cronJobs.Items {
+		if keepRunning(cronJob.Namespace, cronJob.Name) || (cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend) {
+			continue
+		}
+		if _, err := client.BatchV1().CronJobs(cronJob.Namespace).Patch(ctx, cronJob.Name, types.MergePatchType, []byte(`{"spec":{"suspend":true}}`), metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepRunning returns whether the workload is left running when the sandbox is quiesced.
+func keepRunning(namespace, name string) bool {
+	return namespace == metav1.NamespaceSystem || (namespace == "cattle-system" && name == "cattle-cluster-agent")
+}
+
+// verifySandbox checks that the API server of the sandbox cluster responds and that the resources listed in the
+// verification of the drill exist.
+func (h *handler) verifySandbox(cluster *rancherv1.Cluster, drill *rkev1.ETCDSnapshotDrill) []rkev1.ETCDSnapshotDrillCheck {
+	config, err := h.kubeconfigManager.GetRESTConfig(cluster, cluster.Status)
+	if err != nil {
+		return []rkev1.ETCDSnapshotDrillCheck{{Name: "apiserver", Message: err.Error()}}
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return []rkev1.ETCDSnapshotDrillCheck{{Name: "apiserver", Message: err.Error()}}
+	}
+	version, err := discoveryClient.ServerVersion()
+	if err != nil {
+		return []rkev1.ETCDSnapshotDrillCheck{{Name: "apiserver", Message: err.Error()}}
+	}
+	checks := []rkev1.ETCDSnapshotDrillCheck{{Name: "apiserver", Passed: true, Message: version.GitVersion}}
+
+	if len(drill.Spec.Verification.Resources) == 0 {
+		return checks
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return append(checks, rkev1.ETCDSnapshotDrillCheck{Name: "resources", Message: err.Error()})
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	for _, resource := range drill.Spec.Verification.Resources {
+		checks = append(checks, verifyResource(dynamicClient, mapper, resource))
+	}
+	return checks
+}
+
+func verifyResource(client dynamic.Interface, mapper meta.RESTMapper, resource rkev1.ETCDSnapshotDrillResource) rkev1.ETCDSnapshotDrillCheck {
+	check := rkev1.ETCDSnapshotDrillCheck{Name: fmt.Sprintf("%s %s", resource.Kind, resource.Name)}
+	if resource.Namespace != "" {
+		check.Name = fmt.Sprintf("%s %s/%s", resource.Kind, resource.Namespace, resource.Name)
+	}
+
+	gvk := schema.FromAPIVersionAndKind(resource.APIVersion, resource.Kind)
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		check.Message = err.Error()
+		return check
+	}
+	var getter dynamic.ResourceInterface = client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace := resource.Namespace
+		if namespace == "" {
+			namespace = corev1.NamespaceDefault
+		}
+		getter = client.Resource(mapping.Resource).Namespace(namespace)
+	}
+	if _, err := getter.Get(context.TODO(), resource.Name, metav1.GetOptions{}); err != nil {
+		check.Message = err.Error()
+		return check
+	}
+	check.Passed = true
+	return check
+}
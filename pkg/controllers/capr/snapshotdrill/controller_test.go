@@ -0,0 +1,149 @@
+package snapshotdrill
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSandboxCluster(t *testing.T) {
+	spec, err := capr.CompressInterface(rancherv1.ClusterSpec{
+		KubernetesVersion: "v1.25.9+rke2r1",
+		RKEConfig: &rancherv1.RKEConfig{
+			RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+				ETCD: &rkev1.ETCD{
+					SnapshotScheduleCron: "0 */5 * * *",
+					S3:                   &rkev1.ETCDSnapshotS3{Bucket: "snapshots"},
+				},
+				Registries: &rkev1.Registry{
+					Configs: map[string]rkev1.RegistryConfig{
+						"registry.example.com": {AuthConfigSecretName: "registry-auth", TLSSecretName: "registry-tls"},
+					},
+				},
+			},
+			MachinePools: []rancherv1.RKEMachinePool{{Name: "production"}},
+		},
+	})
+	require.NoError(t, err)
+	metadata, err := json.Marshal(map[string]string{"provisioning-cluster-spec": spec})
+	require.NoError(t, err)
+
+	drill := &rkev1.ETCDSnapshotDrill{
+		ObjectMeta: metav1.ObjectMeta{Name: "drill", Namespace: "fleet-default"},
+		Spec: rkev1.ETCDSnapshotDrillSpec{
+			SnapshotName:     "snapshot",
+			MachineConfigRef: &corev1.ObjectReference{Kind: "Amazonec2Config", Name: "sandbox"},
+		},
+		Status: rkev1.ETCDSnapshotDrillStatus{SandboxNamespace: "drill-fleet-default-drill", SandboxClusterName: "drill-drill"},
+	}
+	snapshot := &rkev1.ETCDSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "snapshot", Namespace: "fleet-default"},
+		SnapshotFile: rkev1.ETCDSnapshotFile{
+			S3:       &rkev1.ETCDSnapshotS3{Bucket: "snapshots"},
+			Metadata: base64.StdEncoding.EncodeToString(metadata),
+		},
+	}
+
+	cluster, err := sandboxCluster(drill, snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "drill-drill", cluster.Name)
+	assert.Equal(t, "drill-fleet-default-drill", cluster.Namespace)
+	assert.Equal(t, "drill", cluster.Labels[DrillLabel])
+	assert.Equal(t, "fleet-default", cluster.Labels[DrillNamespaceLabel])
+	assert.Empty(t, cluster.OwnerReferences)
+	assert.Equal(t, "v1.25.9+rke2r1", cluster.Spec.KubernetesVersion)
+	assert.True(t, cluster.Spec.RKEConfig.ETCD.DisableSnapshots)
+	assert.Empty(t, cluster.Spec.RKEConfig.ETCD.SnapshotScheduleCron)
+	assert.Nil(t, cluster.Spec.RKEConfig.ETCD.S3)
+	assert.Equal(t, rkev1.RegistryConfig{}, cluster.Spec.RKEConfig.Registries.Configs["registry.example.com"])
+	if assert.Len(t, cluster.Spec.RKEConfig.MachinePools, 1) {
+		pool := cluster.Spec.RKEConfig.MachinePools[0]
+		assert.Equal(t, sandboxPoolName, pool.Name)
+		assert.True(t, pool.EtcdRole && pool.ControlPlaneRole && pool.WorkerRole)
+		assert.Equal(t, int32(1), *pool.Quantity)
+		assert.Equal(t, "sandbox", pool.NodeConfig.Name)
+		assert.Equal(t, []corev1.Taint{{Key: DrillLabel, Value: "drill", Effect: corev1.TaintEffectNoExecute}}, pool.Taints)
+	}
+
+	localSnapshot := snapshot.DeepCopy()
+	localSnapshot.SnapshotFile.S3 = nil
+	_, err = sandboxCluster(drill, localSnapshot)
+	assert.IsType(t, &drillError{}, err)
+
+	noMetadata := snapshot.DeepCopy()
+	noMetadata.SnapshotFile.Metadata = ""
+	_, err = sandboxCluster(drill, noMetadata)
+	assert.IsType(t, &drillError{}, err)
+}
+
+func TestSandboxObjects(t *testing.T) {
+	drill := &rkev1.ETCDSnapshotDrill{
+		ObjectMeta: metav1.ObjectMeta{Name: "drill", Namespace: "fleet-default"},
+		Status:     rkev1.ETCDSnapshotDrillStatus{SandboxNamespace: "drill-fleet-default-drill"},
+	}
+	snapshot := &rkev1.ETCDSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "snapshot",
+			Namespace: "fleet-default",
+			Labels:    map[string]string{capr.ClusterNameLabel: "production"},
+			OwnerReferences: []metav1.OwnerReference{{
+				Kind: "Cluster",
+				Name: "production",
+			}},
+		},
+		SnapshotFile: rkev1.ETCDSnapshotFile{
+			Name: "on-demand-1",
+			S3:   &rkev1.ETCDSnapshotS3{Bucket: "snapshots"},
+		},
+	}
+	machineConfig := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion":   "rke-machine-config.cattle.io/v1",
+		"kind":         "Amazonec2Config",
+		"instanceType": "t3.large",
+		"metadata": map[string]interface{}{
+			"name":            "sandbox",
+			"namespace":       "fleet-default",
+			"resourceVersion": "10",
+			"ownerReferences": []interface{}{map[string]interface{}{"kind": "Cluster", "name": "production"}},
+		},
+	}}
+
+	objs, err := sandboxObjects(drill, snapshot, machineConfig)
+	require.NoError(t, err)
+	require.Len(t, objs, 3)
+
+	ns := objs[0].(*corev1.Namespace)
+	assert.Equal(t, "drill-fleet-default-drill", ns.Name)
+	assert.Equal(t, "drill", ns.Labels[DrillLabel])
+
+	snapshotCopy := objs[1].(*rkev1.ETCDSnapshot)
+	assert.Equal(t, "drill-fleet-default-drill", snapshotCopy.Namespace)
+	assert.Equal(t, "snapshot", snapshotCopy.Name)
+	assert.Empty(t, snapshotCopy.Labels[capr.ClusterNameLabel])
+	assert.Empty(t, snapshotCopy.OwnerReferences)
+	assert.Equal(t, snapshot.SnapshotFile, snapshotCopy.SnapshotFile)
+
+	configCopy := objs[2].(*unstructured.Unstructured)
+	assert.Equal(t, "drill-fleet-default-drill", configCopy.GetNamespace())
+	assert.Equal(t, "sandbox", configCopy.GetName())
+	assert.Equal(t, "Amazonec2Config", configCopy.GetKind())
+	assert.Equal(t, "t3.large", configCopy.Object["instanceType"])
+	assert.Empty(t, configCopy.GetResourceVersion())
+	assert.Empty(t, configCopy.GetOwnerReferences())
+}
+
+func TestKeepRunning(t *testing.T) {
+	assert.True(t, keepRunning("kube-system", "coredns"))
+	assert.True(t, keepRunning("cattle-system", "cattle-cluster-agent"))
+	assert.False(t, keepRunning("cattle-system", "rancher-webhook"))
+	assert.False(t, keepRunning("default", "web"))
+}
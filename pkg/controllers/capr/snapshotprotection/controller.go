@@ -0,0 +1,209 @@
+package snapshotprotection
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/snapshotclient"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/slice"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// RecoveryPointAnnotation is set to "true" on the etcd snapshot that is the most recent successful snapshot of its
+	// cluster, across both local and S3 storage.
+	RecoveryPointAnnotation = "etcdsnapshot.rke.io/recovery-point"
+	// ForceDeleteAnnotation allows an etcd snapshot that is the only recovery point of its cluster to be deleted.
+	ForceDeleteAnnotation = "etcdsnapshot.rke.io/force-delete"
+	// ProtectionFinalizer keeps the etcd snapshot object that is the only recovery point of its cluster until another
+	// snapshot succeeds. It only holds the object, the snapshot file is managed by the distribution and is still removed
+	// by its retention or by deleting it on the machine or in S3.
+	ProtectionFinalizer = "etcdsnapshot.rke.io/recovery-point-protection"
+
+	machineKind = "Machine"
+)
+
+type handler struct {
+	etcdSnapshots     rkecontroller.ETCDSnapshotController
+	etcdSnapshotCache rkecontroller.ETCDSnapshotCache
+	clusterCache      provisioningcontrollers.ClusterCache
+	machineCache      capicontrollers.MachineCache
+}
+
+// Register starts the controller that tracks the most recent successful etcd snapshot of each cluster and holds the
+// deletion of its object while no other successful snapshot exists, so that the cluster keeps a snapshot that can be
+// selected for a restore. Deletion requests are accepted and only complete once the snapshot is released, and the S3
+// retention of Rancher does not prune the recovery point. The snapshot file itself is not protected.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		etcdSnapshots:     clients.RKE.ETCDSnapshot(),
+		etcdSnapshotCache: clients.RKE.ETCDSnapshot().Cache(),
+		clusterCache:      clients.Provisioning.Cluster().Cache(),
+		machineCache:      clients.CAPI.Machine().Cache(),
+	}
+
+	clients.RKE.ETCDSnapshot().OnChange(ctx, "etcd-snapshot-protection", h.OnChange)
+}
+
+// OnChange reconciles the recovery point annotation and the protection finalizer of the snapshot against the other
+// snapshots of its cluster. The snapshots of the cluster whose annotation or finalizer no longer match are enqueued so
+// that the recovery point moves to a newer snapshot once one is taken.
+func (h *handler) OnChange(_ string, snapshot *rkev1.ETCDSnapshot) (*rkev1.ETCDSnapshot, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+
+	clusterName := snapshotclient.ClusterName(snapshot)
+	if clusterName == "" {
+		return snapshot, nil
+	}
+
+	snapshots, err := h.etcdSnapshotCache.List(snapshot.Namespace, labels.SelectorFromSet(labels.Set{capr.ClusterNameLabel: clusterName}))
+	if err != nil {
+		return snapshot, err
+	}
+
+	released, err := h.ownerRemoved(snapshot, clusterName)
+	if err != nil {
+		return snapshot, err
+	}
+
+	latest, protected := recoveryPoint(snapshots)
+	for _, s := range snapshots {
+		if s.Name == snapshot.Name {
+			continue
+		}
+		if isAnnotated(s) != (latest != nil && s.Name == latest.Name) || hasFinalizer(s) != (protected && s.Name == latest.Name) {
+			h.etcdSnapshots.Enqueue(s.Namespace, s.Name)
+		}
+	}
+
+	isRecoveryPoint := latest != nil && latest.Name == snapshot.Name
+	protect := isRecoveryPoint && protected && !released && strings.ToLower(snapshot.Annotations[ForceDeleteAnnotation]) != "true"
+	annotated := isAnnotated(snapshot)
+	if annotated == isRecoveryPoint && hasFinalizer(snapshot) == protect {
+		if protect && !snapshot.DeletionTimestamp.IsZero() {
+			logrus.Warnf("[snapshotprotection] etcd snapshot %s/%s is the only recovery point of cluster %s, its object is kept until another snapshot succeeds or it is annotated with %s=true",
+				snapshot.Namespace, snapshot.Name, clusterName, ForceDeleteAnnotation)
+		}
+		return snapshot, nil
+	}
+
+	snapshot = snapshot.DeepCopy()
+	if isRecoveryPoint {
+		if snapshot.Annotations == nil {
+			snapshot.Annotations = map[string]string{}
+		}
+		snapshot.Annotations[RecoveryPointAnnotation] = "true"
+	} else {
+		delete(snapshot.Annotations, RecoveryPointAnnotation)
+	}
+	if !protect {
+		snapshot.Finalizers = removeFinalizer(snapshot.Finalizers)
+	} else if snapshot.DeletionTimestamp.IsZero() {
+		snapshot.Finalizers = append(snapshot.Finalizers, ProtectionFinalizer)
+	} else if annotated == isRecoveryPoint {
+		// Finalizers cannot be added to a snapshot that is already being deleted.
+		return snapshot, nil
+	}
+	return h.update(snapshot)
+}
+
+func (h *handler) update(snapshot *rkev1.ETCDSnapshot) (*rkev1.ETCDSnapshot, error) {
+	updated, err := h.etcdSnapshots.Update(snapshot)
+	if apierrors.IsNotFound(err) {
+		return snapshot, nil
+	}
+	return updated, err
+}
+
+// ownerRemoved returns true if the cluster of the snapshot, or the machine that holds a local snapshot, is being
+// deleted or no longer exists, in which case the snapshot is no longer protected.
+func (h *handler) ownerRemoved(snapshot *rkev1.ETCDSnapshot, clusterName string) (bool, error) {
+	cluster, err := h.clusterCache.Get(snapshot.Namespace, clusterName)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !cluster.DeletionTimestamp.IsZero() {
+		return true, nil
+	}
+
+	for _, owner := range snapshot.OwnerReferences {
+		if owner.Kind != machineKind {
+			continue
+		}
+		machine, err := h.machineCache.Get(snapshot.Namespace, owner.Name)
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		} else if err != nil {
+			return false, fmt.Errorf("error getting machine %s/%s of etcd snapshot %s: %w", snapshot.Namespace, owner.Name, snapshot.Name, err)
+		}
+		if !machine.DeletionTimestamp.IsZero() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recoveryPoint returns the most recent successful snapshot of the given snapshots of a cluster, and whether its object
+// must be kept. Snapshots that are being deleted are only considered if no other successful snapshot exists,
+// in which case the most recent of them is the only recovery point of the cluster and is kept.
+func recoveryPoint(snapshots []*rkev1.ETCDSnapshot) (*rkev1.ETCDSnapshot, bool) {
+	var latest, latestDeleting *rkev1.ETCDSnapshot
+	for _, s := range snapshots {
+		if !snapshotclient.Successful(s) {
+			continue
+		}
+		if s.DeletionTimestamp.IsZero() {
+			if snapshotclient.Newer(s, latest) {
+				latest = s
+			}
+		} else if snapshotclient.Newer(s, latestDeleting) {
+			latestDeleting = s
+		}
+	}
+
+	if latest != nil {
+		return latest, isOnlySuccessful(latest, snapshots)
+	}
+	return latestDeleting, latestDeleting != nil
+}
+
+// isOnlySuccessful returns true if no successful snapshot other than the given one exists that is not being deleted.
+func isOnlySuccessful(snapshot *rkev1.ETCDSnapshot, snapshots []*rkev1.ETCDSnapshot) bool {
+	for _, s := range snapshots {
+		if s.Name != snapshot.Name && s.DeletionTimestamp.IsZero() && snapshotclient.Successful(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAnnotated(snapshot *rkev1.ETCDSnapshot) bool {
+	return snapshot.Annotations[RecoveryPointAnnotation] == "true"
+}
+
+func hasFinalizer(snapshot *rkev1.ETCDSnapshot) bool {
+	return slice.ContainsString(snapshot.Finalizers, ProtectionFinalizer)
+}
+
+func removeFinalizer(finalizers []string) []string {
+	var result []string
+	for _, f := range finalizers {
+		if f != ProtectionFinalizer {
+			result = append(result, f)
+		}
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package snapshotprotection
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecoveryPoint(t *testing.T) {
+	now := time.Now()
+	snapshot := func(name string, age time.Duration, status string, deleting, missing bool) *rkev1.ETCDSnapshot {
+		s := &rkev1.ETCDSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			SnapshotFile: rkev1.ETCDSnapshotFile{
+				CreatedAt: &metav1.Time{Time: now.Add(-age)},
+				Status:    status,
+			},
+			Status: rkev1.ETCDSnapshotStatus{Missing: missing},
+		}
+		if deleting {
+			s.DeletionTimestamp = &metav1.Time{Time: now}
+		}
+		return s
+	}
+
+	tests := []struct {
+		name              string
+		snapshots         []*rkev1.ETCDSnapshot
+		expectedLatest    string
+		expectedProtected bool
+	}{
+		{
+			name: "no snapshots",
+		},
+		{
+			name:              "only successful snapshot is protected",
+			snapshots:         []*rkev1.ETCDSnapshot{snapshot("local", time.Hour, "successful", false, false)},
+			expectedLatest:    "local",
+			expectedProtected: true,
+		},
+		{
+			name: "most recent across storage is the recovery point",
+			snapshots: []*rkev1.ETCDSnapshot{
+				snapshot("local", 2*time.Hour, "successful", false, false),
+				snapshot("s3", time.Hour, "successful", false, false),
+			},
+			expectedLatest: "s3",
+		},
+		{
+			name: "failed and missing snapshots are ignored",
+			snapshots: []*rkev1.ETCDSnapshot{
+				snapshot("old", 3*time.Hour, "successful", false, false),
+				snapshot("missing", 2*time.Hour, "successful", false, true),
+				snapshot("failed", time.Hour, "failed", false, false),
+			},
+			expectedLatest:    "old",
+			expectedProtected: true,
+		},
+		{
+			name: "deleting snapshots do not count as another recovery point",
+			snapshots: []*rkev1.ETCDSnapshot{
+				snapshot("old", 2*time.Hour, "successful", true, false),
+				snapshot("new", time.Hour, "successful", false, false),
+			},
+			expectedLatest:    "new",
+			expectedProtected: true,
+		},
+		{
+			name: "most recent deleting snapshot is protected if it is the only recovery point",
+			snapshots: []*rkev1.ETCDSnapshot{
+				snapshot("old", 2*time.Hour, "successful", true, false),
+				snapshot("new", time.Hour, "successful", true, false),
+				snapshot("failed", time.Minute, "failed", false, false),
+			},
+			expectedLatest:    "new",
+			expectedProtected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latest, protected := recoveryPoint(tt.snapshots)
+			if tt.expectedLatest == "" {
+				assert.Nil(t, latest)
+			} else if assert.NotNil(t, latest) {
+				assert.Equal(t, tt.expectedLatest, latest.Name)
+			}
+			assert.Equal(t, tt.expectedProtected, protected)
+		})
+	}
+}
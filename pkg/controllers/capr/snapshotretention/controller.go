@@ -0,0 +1,151 @@
+package snapshotretention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/planner"
+	"github.com/rancher/rancher/pkg/capr/s3client"
+	"github.com/rancher/rancher/pkg/capr/snapshotclient"
+	"github.com/rancher/rancher/pkg/controllers/capr/snapshotprotection"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const s3RemoveTimeout = time.Minute
+
+type handler struct {
+	controlPlanes     rkecontroller.RKEControlPlaneController
+	etcdSnapshots     rkecontroller.ETCDSnapshotClient
+	etcdSnapshotCache rkecontroller.ETCDSnapshotCache
+	secretCache       corecontrollers.SecretCache
+	now               func() time.Time
+	// removeObject deletes the snapshot file with the passed in name from the S3 bucket of the config.
+	removeObject func(config planner.S3Config, name string) error
+}
+
+// Register starts the controller that prunes the S3 etcd snapshots of clusters beyond the count and age of the
+// S3Retention of their etcd configuration.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		controlPlanes:     clients.RKE.RKEControlPlane(),
+		etcdSnapshots:     clients.RKE.ETCDSnapshot(),
+		etcdSnapshotCache: clients.RKE.ETCDSnapshot().Cache(),
+		secretCache:       clients.Core.Secret().Cache(),
+		now:               time.Now,
+		removeObject:      removeS3Object,
+	}
+
+	clients.RKE.RKEControlPlane().OnChange(ctx, "etcd-snapshot-retention", h.OnChange)
+	relatedresource.Watch(ctx, "etcd-snapshot-retention-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		snapshot, ok := obj.(*rkev1.ETCDSnapshot)
+		if !ok {
+			return nil, nil
+		}
+		clusterName := snapshotclient.ClusterName(snapshot)
+		if clusterName == "" {
+			return nil, nil
+		}
+		// The control plane has the same name as the cluster.
+		return []relatedresource.Key{{Namespace: namespace, Name: clusterName}}, nil
+	}, clients.RKE.RKEControlPlane(), clients.RKE.ETCDSnapshot())
+}
+
+// OnChange deletes the S3 snapshots of the control plane that are beyond its retention policy from S3, along with their
+// etcd snapshot objects. The control plane is enqueued again for when its next S3 snapshot exceeds the maximum age.
+func (h *handler) OnChange(_ string, controlPlane *rkev1.RKEControlPlane) (*rkev1.RKEControlPlane, error) {
+	if controlPlane == nil || !controlPlane.DeletionTimestamp.IsZero() || controlPlane.Spec.ETCD == nil || controlPlane.Spec.ETCD.S3Retention == nil {
+		return controlPlane, nil
+	}
+
+	snapshots, err := h.etcdSnapshotCache.List(controlPlane.Namespace, labels.SelectorFromSet(labels.Set{capr.ClusterNameLabel: controlPlane.Spec.ClusterName}))
+	if err != nil {
+		return controlPlane, err
+	}
+
+	restoring := ""
+	if controlPlane.Spec.ETCDSnapshotRestore != nil {
+		restoring = controlPlane.Spec.ETCDSnapshotRestore.Name
+	}
+	prune, next := expired(snapshots, controlPlane.Spec.ETCD.S3Retention, restoring, h.now())
+	for _, snapshot := range prune {
+		if err := h.prune(controlPlane, snapshot); err != nil {
+			return controlPlane, err
+		}
+	}
+	if !next.IsZero() {
+		h.controlPlanes.EnqueueAfter(controlPlane.Namespace, controlPlane.Name, next.Sub(h.now())+time.Second)
+	}
+	return controlPlane, nil
+}
+
+// prune deletes the file of the snapshot from S3 and then the snapshot object.
+func (h *handler) prune(controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot) error {
+	config, err := planner.GetS3Config(h.secretCache, snapshot.SnapshotFile.S3, controlPlane)
+	if err != nil {
+		return fmt.Errorf("failed to prune etcd snapshot %s/%s: %w", snapshot.Namespace, snapshot.Name, err)
+	}
+	if err := h.removeObject(config, snapshot.SnapshotFile.Name); err != nil {
+		return fmt.Errorf("failed to delete etcd snapshot %s/%s from S3: %w", snapshot.Namespace, snapshot.Name, err)
+	}
+	logrus.Infof("[snapshotretention] rkecluster %s/%s: pruned etcd snapshot %s from S3 bucket %s", controlPlane.Namespace, controlPlane.Spec.ClusterName, snapshot.SnapshotFile.Name, config.Bucket)
+	if err := h.etcdSnapshots.Delete(snapshot.Namespace, snapshot.Name, nil); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func removeS3Object(config planner.S3Config, name string) error {
+	client, err := s3client.Get(config)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s3RemoveTimeout)
+	defer cancel()
+	return client.Remove(ctx, name)
+}
+
+// expired returns the successful S3 snapshots that are beyond the retention policy at the passed in time, along with the
+// time the next of the remaining S3 snapshots exceeds the maximum age, which is zero if none will. The newest successful
+// snapshot of the cluster, across local and S3 snapshots, the snapshot that is its recovery point and the snapshot that
+// is being restored are never pruned.
+func expired(snapshots []*rkev1.ETCDSnapshot, retention *rkev1.ETCDSnapshotS3Retention, restoring string, now time.Time) (prune []*rkev1.ETCDSnapshot, next time.Time) {
+	latest := snapshotclient.LatestSuccessful(snapshots)
+
+	var s3Snapshots []*rkev1.ETCDSnapshot
+	for _, snapshot := range snapshots {
+		if snapshot.SnapshotFile.S3 != nil && snapshotclient.Successful(snapshot) && snapshot.DeletionTimestamp.IsZero() {
+			s3Snapshots = append(s3Snapshots, snapshot)
+		}
+	}
+	snapshotclient.SortNewestFirst(s3Snapshots)
+
+	var maxAge time.Duration
+	if retention.MaxAge != nil {
+		maxAge = retention.MaxAge.Duration
+	}
+	for i, snapshot := range s3Snapshots {
+		if snapshot == latest || snapshot.Name == restoring || snapshot.Annotations[snapshotprotection.RecoveryPointAnnotation] == "true" {
+			continue
+		}
+		expiry := snapshot.SnapshotFile.CreatedAt.Add(maxAge)
+		switch {
+		case retention.Count > 0 && i >= retention.Count:
+			prune = append(prune, snapshot)
+		case maxAge > 0 && !now.Before(expiry):
+			prune = append(prune, snapshot)
+		case maxAge > 0 && (next.IsZero() || expiry.Before(next)):
+			next = expiry
+		}
+	}
+	return prune, next
+}
@@ -0,0 +1,115 @@
+package snapshotretention
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/controllers/capr/snapshotprotection"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var now = time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+func newSnapshot(name string, age time.Duration, s3 bool) *rkev1.ETCDSnapshot {
+	snapshot := &rkev1.ETCDSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		SnapshotFile: rkev1.ETCDSnapshotFile{
+			Name:      name,
+			CreatedAt: &metav1.Time{Time: now.Add(-age)},
+		},
+	}
+	if s3 {
+		snapshot.SnapshotFile.S3 = &rkev1.ETCDSnapshotS3{Bucket: "snapshots"}
+	}
+	return snapshot
+}
+
+func names(snapshots []*rkev1.ETCDSnapshot) (result []string) {
+	for _, snapshot := range snapshots {
+		result = append(result, snapshot.Name)
+	}
+	return result
+}
+
+func TestExpired(t *testing.T) {
+	failed := newSnapshot("failed", 5*time.Hour, true)
+	failed.SnapshotFile.Status = "failed"
+	missing := newSnapshot("missing", 5*time.Hour, true)
+	missing.Status.Missing = true
+	recoveryPoint := newSnapshot("recovery-point", 4*time.Hour, true)
+	recoveryPoint.Annotations = map[string]string{snapshotprotection.RecoveryPointAnnotation: "true"}
+
+	tests := []struct {
+		name          string
+		snapshots     []*rkev1.ETCDSnapshot
+		retention     *rkev1.ETCDSnapshotS3Retention
+		restoring     string
+		expectedPrune []string
+		expectedNext  time.Time
+	}{
+		{
+			name: "count",
+			snapshots: []*rkev1.ETCDSnapshot{
+				newSnapshot("s3-3", 3*time.Hour, true),
+				newSnapshot("s3-1", time.Hour, true),
+				newSnapshot("s3-2", 2*time.Hour, true),
+				newSnapshot("local-1", time.Hour, false),
+			},
+			retention:     &rkev1.ETCDSnapshotS3Retention{Count: 2},
+			expectedPrune: []string{"s3-3"},
+		},
+		{
+			name: "max age",
+			snapshots: []*rkev1.ETCDSnapshot{
+				newSnapshot("local-1", 30*time.Minute, false),
+				newSnapshot("s3-1", time.Hour, true),
+				newSnapshot("s3-2", 2*time.Hour, true),
+				newSnapshot("s3-3", 3*time.Hour, true),
+			},
+			retention:     &rkev1.ETCDSnapshotS3Retention{MaxAge: &metav1.Duration{Duration: 2 * time.Hour}},
+			expectedPrune: []string{"s3-2", "s3-3"},
+			expectedNext:  now.Add(time.Hour),
+		},
+		{
+			name: "newest successful snapshot is kept",
+			snapshots: []*rkev1.ETCDSnapshot{
+				newSnapshot("s3-1", 3*time.Hour, true),
+				newSnapshot("s3-2", 4*time.Hour, true),
+				newSnapshot("local-1", 5*time.Hour, false),
+			},
+			retention:     &rkev1.ETCDSnapshotS3Retention{MaxAge: &metav1.Duration{Duration: time.Hour}},
+			expectedPrune: []string{"s3-2"},
+		},
+		{
+			name: "failed and missing snapshots are not pruned",
+			snapshots: []*rkev1.ETCDSnapshot{
+				newSnapshot("s3-1", time.Hour, true),
+				failed,
+				missing,
+			},
+			retention: &rkev1.ETCDSnapshotS3Retention{Count: 1, MaxAge: &metav1.Duration{Duration: 2 * time.Hour}},
+		},
+		{
+			name: "recovery point and restoring snapshots are kept",
+			snapshots: []*rkev1.ETCDSnapshot{
+				newSnapshot("s3-1", time.Hour, true),
+				newSnapshot("s3-2", 2*time.Hour, true),
+				newSnapshot("s3-3", 3*time.Hour, true),
+				recoveryPoint,
+			},
+			retention:     &rkev1.ETCDSnapshotS3Retention{Count: 1},
+			restoring:     "s3-3",
+			expectedPrune: []string{"s3-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prune, next := expired(tt.snapshots, tt.retention, tt.restoring, now)
+			assert.Equal(t, tt.expectedPrune, names(prune))
+			assert.Equal(t, tt.expectedNext, next)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package snapshotstaleness
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/snapshotclient"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/metrics"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/genericcondition"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type handler struct {
+	clusters          provisioningcontrollers.ClusterController
+	etcdSnapshotCache rkecontroller.ETCDSnapshotCache
+	now               func() time.Time
+}
+
+// Register starts the controller that flags clusters whose newest successful etcd snapshot is older than their maximum
+// snapshot age with the SnapshotOverdue condition and exports the age as a metric, so that clusters whose snapshots
+// silently stopped can be alerted on.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		clusters:          clients.Provisioning.Cluster(),
+		etcdSnapshotCache: clients.RKE.ETCDSnapshot().Cache(),
+		now:               time.Now,
+	}
+
+	clients.Provisioning.Cluster().OnChange(ctx, "etcd-snapshot-staleness", h.OnChange)
+	clients.Provisioning.Cluster().OnRemove(ctx, "etcd-snapshot-staleness-metrics", h.OnRemove)
+	relatedresource.Watch(ctx, "etcd-snapshot-staleness-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		snapshot, ok := obj.(*rkev1.ETCDSnapshot)
+		if !ok {
+			return nil, nil
+		}
+		clusterName := snapshotclient.ClusterName(snapshot)
+		if clusterName == "" {
+			return nil, nil
+		}
+		return []relatedresource.Key{{Namespace: namespace, Name: clusterName}}, nil
+	}, clients.Provisioning.Cluster(), clients.RKE.ETCDSnapshot())
+}
+
+// OnChange compares the newest successful snapshot of the cluster to its maximum snapshot age. The cluster is enqueued
+// again for when its newest snapshot becomes overdue, so that clusters are flagged even if nothing changes.
+func (h *handler) OnChange(_ string, cluster *rancherv1.Cluster) (*rancherv1.Cluster, error) {
+	if cluster == nil || !cluster.DeletionTimestamp.IsZero() || cluster.Spec.RKEConfig == nil {
+		return cluster, nil
+	}
+
+	maxAge := maxSnapshotAge(cluster)
+	if maxAge <= 0 {
+		metrics.DeleteCAPRETCDSnapshotStaleness(cluster.Namespace, cluster.Name)
+		return h.removeCondition(cluster)
+	}
+
+	snapshots, err := h.etcdSnapshotCache.List(cluster.Namespace, labels.SelectorFromSet(labels.Set{capr.ClusterNameLabel: cluster.Name}))
+	if err != nil {
+		return cluster, err
+	}
+
+	now := h.now()
+	newest := snapshotclient.LatestSuccessful(snapshots)
+	since := cluster.CreationTimestamp.Time
+	if newest != nil {
+		since = newest.SnapshotFile.CreatedAt.Time
+	}
+	age := now.Sub(since)
+	overdue := age > maxAge
+	metrics.SetCAPRETCDSnapshotStaleness(cluster.Namespace, cluster.Name, age, overdue)
+	if !overdue {
+		h.clusters.EnqueueAfter(cluster.Namespace, cluster.Name, maxAge-age+time.Second)
+	}
+
+	var message string
+	switch {
+	case overdue && newest == nil:
+		message = fmt.Sprintf("no successful etcd snapshot was taken since the cluster was created %s ago, the maximum snapshot age is %s", age.Round(time.Second), maxAge)
+	case overdue:
+		message = fmt.Sprintf("the newest successful etcd snapshot %s was taken %s ago, the maximum snapshot age is %s", newest.Name, age.Round(time.Second), maxAge)
+	}
+	if overdue == capr.SnapshotOverdue.IsTrue(cluster) && message == capr.SnapshotOverdue.GetMessage(cluster) {
+		return cluster, nil
+	}
+
+	if overdue {
+		logrus.Warnf("[snapshotstaleness] cluster %s/%s: %s", cluster.Namespace, cluster.Name, message)
+	}
+	cluster = cluster.DeepCopy()
+	capr.SnapshotOverdue.SetStatusBool(cluster, overdue)
+	capr.SnapshotOverdue.Message(cluster, message)
+	return h.clusters.UpdateStatus(cluster)
+}
+
+// OnRemove removes the metrics of the cluster.
+func (h *handler) OnRemove(_ string, cluster *rancherv1.Cluster) (*rancherv1.Cluster, error) {
+	metrics.DeleteCAPRETCDSnapshotStaleness(cluster.Namespace, cluster.Name)
+	return cluster, nil
+}
+
+// removeCondition removes the SnapshotOverdue condition of a cluster whose snapshot age is no longer checked.
+func (h *handler) removeCondition(cluster *rancherv1.Cluster) (*rancherv1.Cluster, error) {
+	var conditions []genericcondition.GenericCondition
+	for _, c := range cluster.Status.Conditions {
+		if c.Type != string(capr.SnapshotOverdue) {
+			conditions = append(conditions, c)
+		}
+	}
+	if len(conditions) == len(cluster.Status.Conditions) {
+		return cluster, nil
+	}
+
+	cluster = cluster.DeepCopy()
+	cluster.Status.Conditions = conditions
+	return h.clusters.UpdateStatus(cluster)
+}
+
+// maxSnapshotAge returns the maximum age of the newest successful snapshot of the cluster, or zero if the age is not
+// checked. The age of clusters with snapshots disabled is not checked.
+func maxSnapshotAge(cluster *rancherv1.Cluster) time.Duration {
+	etcd := cluster.Spec.RKEConfig.ETCD
+	if etcd != nil && etcd.DisableSnapshots {
+		return 0
+	}
+	if etcd != nil && etcd.SnapshotMaxAge != nil {
+		return etcd.SnapshotMaxAge.Duration
+	}
+
+	value := strings.TrimSpace(settings.ETCDSnapshotMaxAge.Get())
+	if value == "" {
+		return 0
+	}
+	maxAge, err := time.ParseDuration(value)
+	if err != nil {
+		logrus.Errorf("[snapshotstaleness] invalid value of setting %s: %v", settings.ETCDSnapshotMaxAge.Name, err)
+		return 0
+	}
+	return maxAge
+}
@@ -0,0 +1,66 @@
+package snapshotstaleness
+
+import (
+	"testing"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMaxSnapshotAge(t *testing.T) {
+	original := settings.ETCDSnapshotMaxAge.Get()
+	t.Cleanup(func() { _ = settings.ETCDSnapshotMaxAge.Set(original) })
+
+	cluster := func(etcd *rkev1.ETCD) *rancherv1.Cluster {
+		return &rancherv1.Cluster{Spec: rancherv1.ClusterSpec{RKEConfig: &rancherv1.RKEConfig{
+			RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{ETCD: etcd},
+		}}}
+	}
+
+	tests := []struct {
+		name     string
+		setting  string
+		etcd     *rkev1.ETCD
+		expected time.Duration
+	}{
+		{
+			name: "disabled by default",
+		},
+		{
+			name:     "setting",
+			setting:  "24h",
+			expected: 24 * time.Hour,
+		},
+		{
+			name:    "invalid setting",
+			setting: "daily",
+		},
+		{
+			name:     "cluster override",
+			setting:  "24h",
+			etcd:     &rkev1.ETCD{SnapshotMaxAge: &metav1.Duration{Duration: 6 * time.Hour}},
+			expected: 6 * time.Hour,
+		},
+		{
+			name:    "disabled for cluster",
+			setting: "24h",
+			etcd:    &rkev1.ETCD{SnapshotMaxAge: &metav1.Duration{}},
+		},
+		{
+			name:    "snapshots disabled",
+			setting: "24h",
+			etcd:    &rkev1.ETCD{DisableSnapshots: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, settings.ETCDSnapshotMaxAge.Set(tt.setting))
+			assert.Equal(t, tt.expected, maxSnapshotAge(cluster(tt.etcd)))
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package versionchannel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/rancher/channelserver/pkg/config"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/channelserver"
+	provcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	defaultChannel = "stable"
+
+	// resyncInterval is how often the channels of a subscribed cluster are resolved again, as the release data is not
+	// watched.
+	resyncInterval = time.Hour
+	// channelCacheTTL is how long the channels fetched from a custom channel server are reused.
+	channelCacheTTL = 10 * time.Minute
+)
+
+var (
+	httpClient = &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	buildNumber = regexp.MustCompile(`(\d+)$`)
+)
+
+// channelsFunc returns the latest version of every channel of the runtime, keyed by channel name. If url is set, the
+// channels are fetched from the channel server at the url instead of the KDM release data. Channels whose latest version
+// is not a release of the KDM release data are left out.
+type channelsFunc func(runtime, url string) (map[string]string, error)
+
+type handler struct {
+	controlPlanes rkecontrollers.RKEControlPlaneController
+	clusters      provcontrollers.ClusterClient
+	clusterCache  provcontrollers.ClusterCache
+	channels      channelsFunc
+	now           func() time.Time
+}
+
+// Register starts the controller that resolves the release channel a control plane is subscribed to, and upgrades the
+// cluster to the latest version of the channel within the auto-upgrade policy of the subscription.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		controlPlanes: clients.RKE.RKEControlPlane(),
+		clusters:      clients.Provisioning.Cluster(),
+		clusterCache:  clients.Provisioning.Cluster().Cache(),
+		channels:      newChannelResolver(ctx).channels,
+		now:           time.Now,
+	}
+
+	clients.RKE.RKEControlPlane().OnChange(ctx, "rke-control-plane-version-channel", h.OnChange)
+}
+
+func (h *handler) OnChange(_ string, cp *rkev1.RKEControlPlane) (*rkev1.RKEControlPlane, error) {
+	if cp == nil || !cp.DeletionTimestamp.IsZero() || cp.Spec.KubernetesVersion == "" {
+		return cp, nil
+	}
+
+	subscription := cp.Spec.KubernetesVersionChannel
+	if subscription == nil {
+		return h.setChannelVersion(cp, "")
+	}
+
+	if err := checkChannelServer(subscription.URL, settings.KubernetesVersionChannelServers.Get()); err != nil {
+		return cp, fmt.Errorf("resolving release channels of cluster %s/%s: %w", cp.Namespace, cp.Spec.ClusterName, err)
+	}
+	channels, err := h.channels(capr.GetRuntime(cp.Spec.KubernetesVersion), subscription.URL)
+	if err != nil {
+		return cp, fmt.Errorf("resolving release channels of cluster %s/%s: %w", cp.Namespace, cp.Spec.ClusterName, err)
+	}
+	h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, resyncInterval)
+
+	name := subscription.Name
+	if name == "" {
+		name = defaultChannel
+	}
+	latest := channels[name]
+	if latest == "" {
+		logrus.Warnf("[versionchannel] rkecontrolplane %s/%s: release channel %s not found", cp.Namespace, cp.Name, name)
+	}
+
+	cp, err = h.setChannelVersion(cp, latest)
+	if err != nil || latest == "" || subscription.AutoUpgrade == nil {
+		return cp, err
+	}
+
+	target := upgradeTarget(cp.Spec.KubernetesVersion, latest, subscription.AutoUpgrade.PatchOnly, channels)
+	if target == "" || !upgradeable(cp) {
+		return cp, nil
+	}
+
+	if window := subscription.AutoUpgrade.MaintenanceWindow; window != nil {
+		open, wait, err := capr.MaintenanceWindowState(window, h.now())
+		if err != nil {
+			logrus.Warnf("[versionchannel] rkecontrolplane %s/%s: invalid maintenance window: %v", cp.Namespace, cp.Name, err)
+			return cp, nil
+		}
+		if !open {
+			logrus.Debugf("[versionchannel] rkecontrolplane %s/%s: deferring upgrade to %s until the maintenance window opens in %s", cp.Namespace, cp.Name, target, wait)
+			h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, wait)
+			return cp, nil
+		}
+	}
+
+	return cp, h.upgrade(cp, target)
+}
+
+// setChannelVersion records the latest version of the subscribed channel on the status of the control plane.
+func (h *handler) setChannelVersion(cp *rkev1.RKEControlPlane, version string) (*rkev1.RKEControlPlane, error) {
+	if cp.Status.ChannelKubernetesVersion == version {
+		return cp, nil
+	}
+	cp = cp.DeepCopy()
+	cp.Status.ChannelKubernetesVersion = version
+	return h.controlPlanes.UpdateStatus(cp)
+}
+
+// upgrade sets the Kubernetes version of the provisioning cluster of the control plane, which is the source of the
+// version of the control plane, and lets the planner carry out the upgrade.
+func (h *handler) upgrade(cp *rkev1.RKEControlPlane, version string) error {
+	cluster, err := h.clusterCache.Get(cp.Namespace, cp.Spec.ClusterName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if cluster.Spec.KubernetesVersion != cp.Spec.KubernetesVersion {
+		// the version of the cluster was changed since the control plane was last updated
+		return nil
+	}
+
+	logrus.Infof("[versionchannel] rkecontrolplane %s/%s: upgrading cluster from %s to %s", cp.Namespace, cp.Name, cluster.Spec.KubernetesVersion, version)
+	cluster = cluster.DeepCopy()
+	cluster.Spec.KubernetesVersion = version
+	_, err = h.clusters.Update(cluster)
+	return err
+}
+
+// upgradeable returns true if the cluster is ready and the planner has applied the current spec of the control plane,
+// so that an automatic upgrade never starts on top of another change.
+func upgradeable(cp *rkev1.RKEControlPlane) bool {
+	return cp.Status.Ready &&
+		cp.Status.ObservedGeneration == cp.Generation &&
+		cp.Status.AppliedSpec != nil &&
+		cp.Status.AppliedSpec.KubernetesVersion == cp.Spec.KubernetesVersion
+}
+
+// upgradeTarget returns the version the cluster should be upgraded to from the current version, or an empty string if
+// no upgrade is available. Clusters are upgraded one minor version at a time, following the channel of the next minor
+// version, i.e. "v1.25", until they reach the minor version of the latest version of the channel. If patchOnly is
+// true, the cluster only follows the channel of its current minor version once the channel moved past it.
+func upgradeTarget(current, latest string, patchOnly bool, channels map[string]string) string {
+	currentVersion, err := semver.ParseTolerant(current)
+	if err != nil {
+		return ""
+	}
+	latestVersion, err := semver.ParseTolerant(latest)
+	if err != nil {
+		return ""
+	}
+
+	target := latest
+	if latestVersion.Major != currentVersion.Major {
+		return ""
+	} else if patchOnly && latestVersion.Minor != currentVersion.Minor {
+		target = channels[fmt.Sprintf("v%d.%d", currentVersion.Major, currentVersion.Minor)]
+	} else if latestVersion.Minor > currentVersion.Minor+1 {
+		target = channels[fmt.Sprintf("v%d.%d", currentVersion.Major, currentVersion.Minor+1)]
+	}
+
+	if target == "" || compareVersions(target, current) <= 0 {
+		return ""
+	}
+	return target
+}
+
+// compareVersions compares two Kubernetes versions of the same distribution, using the build number of the release,
+// i.e. the 2 of "v1.25.9+rke2r2", if the versions are otherwise equal. Versions that cannot be parsed are equal.
+func compareVersions(a, b string) int {
+	aVersion, err := semver.ParseTolerant(a)
+	if err != nil {
+		return 0
+	}
+	bVersion, err := semver.ParseTolerant(b)
+	if err != nil {
+		return 0
+	}
+	if c := aVersion.Compare(bVersion); c != 0 {
+		return c
+	}
+	aBuild, bBuild := releaseBuild(aVersion), releaseBuild(bVersion)
+	switch {
+	case aBuild < bBuild:
+		return -1
+	case aBuild > bBuild:
+		return 1
+	}
+	return 0
+}
+
+func releaseBuild(version semver.Version) int {
+	if len(version.Build) == 0 {
+		return 0
+	}
+	match := buildNumber.FindString(version.Build[len(version.Build)-1])
+	n, _ := strconv.Atoi(match)
+	return n
+}
+
+// checkChannelServer returns an error unless the url is empty or one of the comma separated channel servers that
+// clusters are allowed to subscribe to, so that Rancher only fetches channels from servers an administrator configured.
+func checkChannelServer(url, allowedServers string) error {
+	if url == "" {
+		return nil
+	}
+	for _, allowed := range strings.Split(allowedServers, ",") {
+		if strings.TrimSpace(allowed) == url {
+			return nil
+		}
+	}
+	return fmt.Errorf("channel server %s is not allowed by the %s setting", url, settings.KubernetesVersionChannelServers.Name)
+}
+
+type cachedChannels struct {
+	channels map[string]string
+	fetched  time.Time
+}
+
+// channelResolver resolves release channels from the KDM release data, or from custom channel servers.
+type channelResolver struct {
+	ctx context.Context
+
+	lock  sync.Mutex
+	cache map[string]cachedChannels
+}
+
+func newChannelResolver(ctx context.Context) *channelResolver {
+	return &channelResolver{
+		ctx:   ctx,
+		cache: map[string]cachedChannels{},
+	}
+}
+
+func (r *channelResolver) channels(runtime, url string) (map[string]string, error) {
+	config := channelserver.GetReleaseConfigByRuntime(r.ctx, runtime)
+	var releases []string
+	for _, release := range config.ReleasesConfig().Releases {
+		releases = append(releases, release.Version)
+	}
+
+	if url == "" {
+		return released(kdmChannels(config, releases), releases), nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if cached, ok := r.cache[url]; ok && time.Since(cached.fetched) < channelCacheTTL {
+		return released(cached.channels, releases), nil
+	}
+	channels, err := fetchChannels(url)
+	if err != nil {
+		return nil, err
+	}
+	r.cache[url] = cachedChannels{channels: channels, fetched: time.Now()}
+	return released(channels, releases), nil
+}
+
+// released returns the channels whose latest version is one of the releases, as clusters can only be provisioned with
+// the versions of the KDM release data.
+func released(channels map[string]string, releases []string) map[string]string {
+	known := make(map[string]bool, len(releases))
+	for _, release := range releases {
+		known[release] = true
+	}
+	result := map[string]string{}
+	for name, version := range channels {
+		if known[version] {
+			result[name] = version
+		}
+	}
+	return result
+}
+
+// kdmChannels returns the channels of the KDM release data of a runtime. Channels that are only defined by a regular
+// expression resolve to the newest release that matches it.
+func kdmChannels(releaseConfig *config.Config, releases []string) map[string]string {
+
+	result := map[string]string{}
+	for _, channel := range releaseConfig.ChannelsConfig().Channels {
+		if channel.Latest != "" {
+			result[channel.Name] = channel.Latest
+			continue
+		}
+		if latest := latestMatching(releases, channel.LatestRegexp, channel.ExcludeRegexp); latest != "" {
+			result[channel.Name] = latest
+		}
+	}
+	return result
+}
+
+// latestMatching returns the newest of the releases that matches the latest expression and not the exclude expression.
+func latestMatching(releases []string, latestRegexp, excludeRegexp string) string {
+	if latestRegexp == "" {
+		return ""
+	}
+	latest, err := regexp.Compile(latestRegexp)
+	if err != nil {
+		return ""
+	}
+	var exclude *regexp.Regexp
+	if excludeRegexp != "" {
+		if exclude, err = regexp.Compile(excludeRegexp); err != nil {
+			return ""
+		}
+	}
+
+	var result string
+	for _, release := range releases {
+		if !latest.MatchString(release) || (exclude != nil && exclude.MatchString(release)) {
+			continue
+		}
+		if result == "" || compareVersions(release, result) > 0 {
+			result = release
+		}
+	}
+	return result
+}
+
+// fetchChannels fetches the channels collection of a channel server, i.e. https://update.rke2.io/v1-release/channels.
+func fetchChannels(url string) (map[string]string, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("channel server %s returned status %d", url, resp.StatusCode)
+	}
+
+	var collection struct {
+		Data []struct {
+			Name   string `json:"name"`
+			Latest string `json:"latest"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("decoding channels of channel server %s: %w", url, err)
+	}
+
+	result := map[string]string{}
+	for _, channel := range collection.Data {
+		if channel.Latest != "" {
+			result[channel.Name] = channel.Latest
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,115 @@
+package versionchannel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradeTarget(t *testing.T) {
+	channels := map[string]string{
+		"stable": "v1.26.4+rke2r1",
+		"v1.24":  "v1.24.13+rke2r1",
+		"v1.25":  "v1.25.9+rke2r2",
+		"v1.26":  "v1.26.4+rke2r1",
+	}
+
+	tests := []struct {
+		name      string
+		current   string
+		latest    string
+		patchOnly bool
+		expected  string
+	}{
+		{
+			name:     "newer patch of the same minor",
+			current:  "v1.26.1+rke2r1",
+			latest:   "v1.26.4+rke2r1",
+			expected: "v1.26.4+rke2r1",
+		},
+		{
+			name:     "newer build of the same version",
+			current:  "v1.25.9+rke2r1",
+			latest:   "v1.25.9+rke2r2",
+			expected: "v1.25.9+rke2r2",
+		},
+		{
+			name:    "already on the latest version",
+			current: "v1.26.4+rke2r1",
+			latest:  "v1.26.4+rke2r1",
+		},
+		{
+			name:    "never downgrades",
+			current: "v1.27.1+rke2r1",
+			latest:  "v1.26.4+rke2r1",
+		},
+		{
+			name:     "next minor",
+			current:  "v1.25.9+rke2r2",
+			latest:   "v1.26.4+rke2r1",
+			expected: "v1.26.4+rke2r1",
+		},
+		{
+			name:     "one minor at a time",
+			current:  "v1.24.13+rke2r1",
+			latest:   "v1.26.4+rke2r1",
+			expected: "v1.25.9+rke2r2",
+		},
+		{
+			name:      "patch only follows the channel of the current minor",
+			current:   "v1.24.10+rke2r1",
+			latest:    "v1.26.4+rke2r1",
+			patchOnly: true,
+			expected:  "v1.24.13+rke2r1",
+		},
+		{
+			name:      "patch only without a channel of the current minor",
+			current:   "v1.23.10+rke2r1",
+			latest:    "v1.26.4+rke2r1",
+			patchOnly: true,
+		},
+		{
+			name:    "invalid version",
+			current: "v1.26.1+rke2r1",
+			latest:  "invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, upgradeTarget(tt.current, tt.latest, tt.patchOnly, channels))
+		})
+	}
+}
+
+func TestLatestMatching(t *testing.T) {
+	releases := []string{"v1.25.8+rke2r1", "v1.25.9+rke2r1", "v1.25.9+rke2r2", "v1.26.0-rc1+rke2r1", "v1.26.4+rke2r1"}
+
+	assert.Equal(t, "v1.25.9+rke2r2", latestMatching(releases, `^v1\.25\.`, ""))
+	assert.Equal(t, "v1.26.4+rke2r1", latestMatching(releases, `^v1\.`, `-rc`))
+	assert.Equal(t, "", latestMatching(releases, `^v1\.27\.`, ""))
+	assert.Equal(t, "", latestMatching(releases, "", ""))
+}
+
+func TestReleased(t *testing.T) {
+	channels := map[string]string{
+		"stable": "v1.26.4+rke2r1",
+		"latest": "v1.27.1+rke2r1",
+		"v1.25":  "v1.25.9+rke2r2",
+	}
+	releases := []string{"v1.25.9+rke2r2", "v1.26.4+rke2r1"}
+
+	assert.Equal(t, map[string]string{"stable": "v1.26.4+rke2r1", "v1.25": "v1.25.9+rke2r2"}, released(channels, releases))
+	assert.Empty(t, released(channels, nil))
+}
+
+func TestCheckChannelServer(t *testing.T) {
+	allowed := "https://update.rke2.io/v1-release/channels, https://channels.example.com/k3s"
+
+	assert.NoError(t, checkChannelServer("", ""))
+	assert.NoError(t, checkChannelServer("https://update.rke2.io/v1-release/channels", allowed))
+	assert.NoError(t, checkChannelServer("https://channels.example.com/k3s", allowed))
+	assert.EqualError(t, checkChannelServer("http://169.254.169.254/latest/meta-data", allowed),
+		"channel server http://169.254.169.254/latest/meta-data is not allowed by the kubernetes-version-channel-servers setting")
+	assert.Error(t, checkChannelServer("https://update.rke2.io/v1-release/channels", ""))
+}
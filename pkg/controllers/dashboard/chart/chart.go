@@ -21,11 +21,27 @@ type Manager interface {
 	// Ensure ensures that the chart is installed into the given namespace with the given minVersion version and values.
 	Ensure(namespace, name, minVersion string, values map[string]interface{}, forceAdopt bool, installImageOverride string) error
 
+	// EnsureVersion ensures that exactly the given version of the chart is installed into the given namespace with the given values.
+	EnsureVersion(namespace, name, version string, values map[string]interface{}, forceAdopt bool, installImageOverride string) error
+
+	// LatestVersion returns the latest version of the given chart that is available to be installed.
+	LatestVersion(name string) (string, error)
+
+	// Values returns the default values of the given version of the chart, or of its latest version if version is empty.
+	Values(name, version string) (map[string]interface{}, error)
+
 	// Uninstall uninstalls the given chart in the given namespace.
 	Uninstall(namespace, name string) error
 
 	// Remove removes the chart from the desired state
 	Remove(namespace, name, minVersion string)
+
+	// Installed returns whether a release of the given chart is deployed in the given namespace.
+	Installed(namespace, name string) (bool, error)
+
+	// InstalledVersion returns the chart version of the deployed release of the given chart in the given namespace, or
+	// an empty string if no release is deployed.
+	InstalledVersion(namespace, name string) (string, error)
 }
 
 // Definition defines a helm chart.
@@ -37,6 +53,8 @@ type Definition struct {
 	Enabled           func() bool
 	Uninstall         bool
 	RemoveNamespace   bool
+	// DependsOn is the names of the charts that must be installed before this chart, such as its CRD chart.
+	DependsOn []string
 }
 
 // RancherConfigGetter is used to get Rancher chart configuration information from the rancher config map
@@ -0,0 +1,113 @@
+package chart
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DependencyRequeueInterval is how long to wait before trying again to install a chart whose dependencies are not
+// installed yet.
+const DependencyRequeueInterval = 10 * time.Second
+
+// ErrDependencyNotInstalled is returned by EnsureInOrder if a chart can not be installed yet because a chart it depends
+// on is not installed.
+var ErrDependencyNotInstalled = errors.New("chart dependency is not installed")
+
+// Order returns the definitions ordered so that every chart comes after the charts it depends on, keeping the given
+// order of charts that do not depend on each other. Definitions that uninstall a chart can not be depended on. An error
+// is returned if a chart depends on a chart that is not among the definitions, or if the dependencies form a cycle.
+func Order(defs []*Definition) ([]*Definition, error) {
+	byName := map[string]*Definition{}
+	for _, def := range defs {
+		if def.Uninstall {
+			continue
+		}
+		if _, ok := byName[def.ChartName]; ok {
+			return nil, fmt.Errorf("chart %s is defined more than once", def.ChartName)
+		}
+		byName[def.ChartName] = def
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	var (
+		result []*Definition
+		state  = map[*Definition]int{}
+		path   []string
+	)
+	var visit func(def *Definition) error
+	visit = func(def *Definition) error {
+		switch state[def] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("charts have a dependency cycle: %s -> %s", strings.Join(path, " -> "), def.ChartName)
+		}
+		state[def] = visiting
+		path = append(path, def.ChartName)
+		for _, name := range def.DependsOn {
+			dependency, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("chart %s depends on chart %s which is not defined", def.ChartName, name)
+			}
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[def] = visited
+		result = append(result, def)
+		return nil
+	}
+
+	for _, def := range defs {
+		if err := visit(def); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// EnsureInOrder calls ensure for every enabled definition, ordered by their dependencies. A chart is only ensured once
+// the charts it depends on are installed. As the manager installs charts asynchronously, a dependency that was ensured
+// in the same call is usually not installed yet, in which case an error wrapping ErrDependencyNotInstalled is returned
+// and the caller is expected to try again after DependencyRequeueInterval.
+func EnsureInOrder(manager Manager, defs []*Definition, ensure func(def *Definition) error) error {
+	ordered, err := Order(defs)
+	if err != nil {
+		return err
+	}
+
+	byName := map[string]*Definition{}
+	for _, def := range ordered {
+		if !def.Uninstall {
+			byName[def.ChartName] = def
+		}
+	}
+
+	for _, def := range ordered {
+		if def.Enabled != nil && !def.Enabled() {
+			continue
+		}
+		if !def.Uninstall {
+			for _, name := range def.DependsOn {
+				dependency := byName[name]
+				installed, err := manager.Installed(dependency.ReleaseNamespace, dependency.ChartName)
+				if err != nil {
+					return err
+				}
+				if !installed {
+					return fmt.Errorf("waiting to install %s until %s is installed: %w", def.ChartName, dependency.ChartName, ErrDependencyNotInstalled)
+				}
+			}
+		}
+		if err := ensure(def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
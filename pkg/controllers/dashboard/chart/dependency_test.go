@@ -0,0 +1,136 @@
+package chart_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		defs     []*chart.Definition
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name: "no dependencies keep their order",
+			defs: []*chart.Definition{
+				{ChartName: "b"},
+				{ChartName: "a"},
+			},
+			expected: []string{"b", "a"},
+		},
+		{
+			name: "dependency is moved before its dependent",
+			defs: []*chart.Definition{
+				{ChartName: "operator", DependsOn: []string{"crd"}},
+				{ChartName: "webhook"},
+				{ChartName: "crd"},
+			},
+			expected: []string{"crd", "operator", "webhook"},
+		},
+		{
+			name: "transitive dependencies",
+			defs: []*chart.Definition{
+				{ChartName: "c", DependsOn: []string{"b"}},
+				{ChartName: "b", DependsOn: []string{"a"}},
+				{ChartName: "a"},
+			},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name: "unknown dependency",
+			defs: []*chart.Definition{
+				{ChartName: "operator", DependsOn: []string{"crd"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "uninstalled chart can not be depended on",
+			defs: []*chart.Definition{
+				{ChartName: "operator", DependsOn: []string{"crd"}},
+				{ChartName: "crd", Uninstall: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cycle",
+			defs: []*chart.Definition{
+				{ChartName: "a", DependsOn: []string{"b"}},
+				{ChartName: "b", DependsOn: []string{"a"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate chart",
+			defs: []*chart.Definition{
+				{ChartName: "a"},
+				{ChartName: "a"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := chart.Order(tt.defs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var names []string
+			for _, def := range ordered {
+				names = append(names, def.ChartName)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestEnsureInOrder(t *testing.T) {
+	crd := &chart.Definition{ReleaseNamespace: "ns", ChartName: "crd"}
+	operator := &chart.Definition{ReleaseNamespace: "ns", ChartName: "operator", DependsOn: []string{"crd"}}
+	disabled := &chart.Definition{ReleaseNamespace: "ns", ChartName: "disabled", DependsOn: []string{"crd"}, Enabled: func() bool { return false }}
+
+	tests := []struct {
+		name      string
+		installed bool
+		expected  []string
+		wantErr   error
+	}{
+		{
+			name:     "dependency not installed yet",
+			expected: []string{"crd"},
+			wantErr:  chart.ErrDependencyNotInstalled,
+		},
+		{
+			name:      "dependency installed",
+			installed: true,
+			expected:  []string{"crd", "operator"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			manager := fake.NewMockManager(ctrl)
+			manager.EXPECT().Installed("ns", "crd").Return(tt.installed, nil)
+
+			var ensured []string
+			err := chart.EnsureInOrder(manager, []*chart.Definition{disabled, operator, crd}, func(def *chart.Definition) error {
+				ensured = append(ensured, def.ChartName)
+				return nil
+			})
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "expected %v, got %v", tt.wantErr, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, ensured)
+		})
+	}
+}
@@ -47,6 +47,65 @@ func (mr *MockManagerMockRecorder) Ensure(arg0, arg1, arg2, arg3, arg4, arg5 int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ensure", reflect.TypeOf((*MockManager)(nil).Ensure), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
+// EnsureVersion mocks base method.
+func (m *MockManager) EnsureVersion(arg0, arg1, arg2 string, arg3 map[string]interface{}, arg4 bool, arg5 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureVersion", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureVersion indicates an expected call of EnsureVersion.
+func (mr *MockManagerMockRecorder) EnsureVersion(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureVersion", reflect.TypeOf((*MockManager)(nil).EnsureVersion), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// Installed mocks base method.
+func (m *MockManager) Installed(arg0, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Installed", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Installed indicates an expected call of Installed.
+func (mr *MockManagerMockRecorder) Installed(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Installed", reflect.TypeOf((*MockManager)(nil).Installed), arg0, arg1)
+}
+
+// InstalledVersion mocks base method.
+func (m *MockManager) InstalledVersion(arg0, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstalledVersion", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InstalledVersion indicates an expected call of InstalledVersion.
+func (mr *MockManagerMockRecorder) InstalledVersion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstalledVersion", reflect.TypeOf((*MockManager)(nil).InstalledVersion), arg0, arg1)
+}
+
+// LatestVersion mocks base method.
+func (m *MockManager) LatestVersion(arg0 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LatestVersion", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LatestVersion indicates an expected call of LatestVersion.
+func (mr *MockManagerMockRecorder) LatestVersion(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LatestVersion", reflect.TypeOf((*MockManager)(nil).LatestVersion), arg0)
+}
+
 // Remove mocks base method.
 func (m *MockManager) Remove(arg0, arg1, arg2 string) {
 	m.ctrl.T.Helper()
@@ -72,3 +131,18 @@ func (mr *MockManagerMockRecorder) Uninstall(arg0, arg1 interface{}) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uninstall", reflect.TypeOf((*MockManager)(nil).Uninstall), arg0, arg1)
 }
+
+// Values mocks base method.
+func (m *MockManager) Values(arg0, arg1 string) (map[string]interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Values", arg0, arg1)
+	ret0, _ := ret[0].(map[string]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Values indicates an expected call of Values.
+func (mr *MockManagerMockRecorder) Values(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Values", reflect.TypeOf((*MockManager)(nil).Values), arg0, arg1)
+}
@@ -16,6 +16,7 @@
 	"github.com/rancher/rancher/pkg/controllers/dashboard/mcmagent"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/scaleavailable"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/systemcharts"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/trustbundle"
 	"github.com/rancher/rancher/pkg/controllers/management/clusterconnected"
 	"github.com/rancher/rancher/pkg/controllers/provisioningv2"
 	"github.com/rancher/rancher/pkg/features"
@@ -51,6 +52,7 @@ func Register(ctx context.Context, wrangler *wrangler.Context, embedded bool, re
 
 	if features.MCM.Enabled() {
 		hostedcluster.Register(ctx, wrangler)
+		trustbundle.Register(ctx, wrangler)
 	}
 
 	if features.Fleet.Enabled() {
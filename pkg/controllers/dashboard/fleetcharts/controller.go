@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"errors"
 	"os"
 	"sync"
 
@@ -9,6 +10,7 @@
 	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
 	"github.com/rancher/rancher/pkg/features"
 	fleetconst "github.com/rancher/rancher/pkg/fleet"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/wrangler/pkg/relatedresource"
@@ -25,6 +27,7 @@
 	fleetChart = chart.Definition{
 		ReleaseNamespace: fleetconst.ReleaseNamespace,
 		ChartName:        fleetconst.ChartName,
+		DependsOn:        []string{fleetconst.CRDChartName},
 	}
 	fleetUninstallChart = chart.Definition{
 		ReleaseNamespace: fleetconst.ReleaseLegacyNamespace,
@@ -35,6 +38,7 @@
 func Register(ctx context.Context, wContext *wrangler.Context) error {
 	h := &handler{
 		manager:      wContext.SystemChartsManager,
+		settings:     wContext.Mgmt.Setting(),
 		chartsConfig: chart.RancherConfigGetter{ConfigCache: wContext.Core.ConfigMap().Cache()},
 	}
 
@@ -54,6 +58,7 @@ func Register(ctx context.Context, wContext *wrangler.Context) error {
 type handler struct {
 	sync.Mutex
 	manager      chart.Manager
+	settings     mgmtcontrollers.SettingController
 	chartsConfig chart.RancherConfigGetter
 }
 
@@ -75,11 +80,6 @@ func (h *handler) onSetting(key string, setting *v3.Setting) (*v3.Setting, error
 	}
 	h.Unlock()
 
-	err := h.manager.Ensure(fleetCRDChart.ReleaseNamespace, fleetCRDChart.ChartName, settings.FleetMinVersion.Get(), nil, true, "")
-	if err != nil {
-		return setting, err
-	}
-
 	systemGlobalRegistry := map[string]interface{}{
 		"cattle": map[string]interface{}{
 			"systemDefaultRegistry": settings.SystemDefaultRegistry.Get(),
@@ -124,5 +124,17 @@ func (h *handler) onSetting(key string, setting *v3.Setting) (*v3.Setting, error
 		fleetChartValues["gitjob"] = gitjobChartValues
 	}
 
-	return setting, h.manager.Ensure(fleetChart.ReleaseNamespace, fleetChart.ChartName, settings.FleetMinVersion.Get(), fleetChartValues, true, "")
+	err := chart.EnsureInOrder(h.manager, []*chart.Definition{&fleetCRDChart, &fleetChart}, func(def *chart.Definition) error {
+		var values map[string]interface{}
+		if def == &fleetChart {
+			values = fleetChartValues
+		}
+		return h.manager.Ensure(def.ReleaseNamespace, def.ChartName, settings.FleetMinVersion.Get(), values, true, "")
+	})
+	if errors.Is(err, chart.ErrDependencyNotInstalled) {
+		logrus.Debugf("[fleet-install] %v", err)
+		h.settings.EnqueueAfter(setting.Name, chart.DependencyRequeueInterval)
+		return setting, nil
+	}
+	return setting, err
 }
@@ -82,6 +82,7 @@ func Test_ChartInstallation(t *testing.T) {
 					gomock.AssignableToTypeOf(b),
 					"",
 				).Return(nil)
+				manager.EXPECT().Installed(fleetconst.ReleaseNamespace, fleetconst.CRDChartName).Return(true, nil)
 				manager.EXPECT().Ensure(
 					fleetconst.ReleaseNamespace,
 					fleetconst.ChartName,
@@ -124,6 +125,7 @@ func Test_ChartInstallation(t *testing.T) {
 					gomock.AssignableToTypeOf(b),
 					"",
 				).Return(nil)
+				manager.EXPECT().Installed(fleetconst.ReleaseNamespace, fleetconst.CRDChartName).Return(true, nil)
 				manager.EXPECT().Ensure(
 					fleetconst.ReleaseNamespace,
 					fleetconst.ChartName,
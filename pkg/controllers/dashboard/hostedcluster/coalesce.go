@@ -0,0 +1,108 @@
+package hostedcluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
+)
+
+// coalesceInterval is the minimum time between the starts of two reconciles of the charts of a provider.
+var coalesceInterval = 10 * time.Second
+
+// providerCoalescer bounds the reconciles of the charts of each provider. The charts are shared by all clusters of a
+// provider, so a burst of cluster events, such as all clusters being updated by a migration, only needs to ensure them
+// once. Only one reconcile per provider runs at a time, and at most once per interval. Events of other clusters in
+// the meantime are coalesced into a single pending cluster, which is reconciled once the provider is free again. The
+// result of the last reconcile of the charts is kept, so that it can be reported on the clusters whose events were
+// coalesced.
+type providerCoalescer struct {
+	lock     sync.Mutex
+	interval time.Duration
+	states   map[string]*providerReconcile
+}
+
+type providerReconcile struct {
+	running bool
+	started time.Time
+	// pending is the cluster that was deferred and enqueued to run the next reconcile of the provider. If it did not come
+	// back within an interval of the time it was enqueued for, for example as it was deleted, another cluster replaces it.
+	pending      string
+	pendingUntil time.Time
+	result       *chartsResult
+}
+
+// chartsResult is the outcome of ensuring the charts of a provider. failed is the chart that could not be installed, if
+// any, and err the reason.
+type chartsResult struct {
+	failed *chart.Definition
+	err    error
+}
+
+func newProviderCoalescer(interval time.Duration) *providerCoalescer {
+	return &providerCoalescer{
+		interval: interval,
+		states:   map[string]*providerReconcile{},
+	}
+}
+
+// admit returns true if the reconcile of the cluster can run now, in which case done must be called once it finished.
+// Otherwise, it returns the delay after which the cluster must be enqueued again, or zero if the event is coalesced
+// into the reconcile of another pending cluster and must be dropped.
+func (c *providerCoalescer) admit(provider, cluster string, now time.Time) (bool, time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state := c.states[provider]
+	if state == nil {
+		state = &providerReconcile{}
+		c.states[provider] = state
+	}
+
+	var delay time.Duration
+	if state.running {
+		delay = c.interval
+	} else if next := state.started.Add(c.interval); now.Before(next) {
+		delay = next.Sub(now)
+	}
+	if delay > 0 {
+		if state.pending != "" && state.pending != cluster && now.Before(state.pendingUntil.Add(c.interval)) {
+			return false, 0
+		}
+		state.pending = cluster
+		state.pendingUntil = now.Add(delay)
+		return false, delay
+	}
+
+	if state.pending == cluster {
+		state.pending = ""
+	}
+	state.running = true
+	state.started = now
+	return true, 0
+}
+
+// done records that the reconcile of the provider admitted last finished, along with the result of ensuring the charts
+// of the provider if the reconcile got that far.
+func (c *providerCoalescer) done(provider string, result *chartsResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if state := c.states[provider]; state != nil {
+		state.running = false
+		if result != nil {
+			state.result = result
+		}
+	}
+}
+
+// lastResult returns the result of the last reconcile of the provider that ensured its charts, or nil if there was none.
+func (c *providerCoalescer) lastResult(provider string) *chartsResult {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if state := c.states[provider]; state != nil {
+		return state.result
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package hostedcluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderCoalescer(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newProviderCoalescer(10 * time.Second)
+
+	admitted, _ := c.admit("eks", "c1", start)
+	assert.True(t, admitted)
+
+	// while the reconcile of the provider runs, the first other cluster is deferred and the rest are coalesced into it
+	admitted, delay := c.admit("eks", "c2", start.Add(time.Second))
+	assert.False(t, admitted)
+	assert.Equal(t, 10*time.Second, delay)
+	admitted, delay = c.admit("eks", "c3", start.Add(time.Second))
+	assert.False(t, admitted)
+	assert.Zero(t, delay)
+
+	// other providers are not affected
+	admitted, _ = c.admit("aks", "c4", start.Add(time.Second))
+	assert.True(t, admitted)
+
+	// once the reconcile finished, the next one waits for the interval to pass
+	c.done("eks", nil)
+	admitted, delay = c.admit("eks", "c2", start.Add(4*time.Second))
+	assert.False(t, admitted)
+	assert.Equal(t, 6*time.Second, delay)
+	admitted, _ = c.admit("eks", "c2", start.Add(10*time.Second))
+	assert.True(t, admitted)
+	c.done("eks", nil)
+
+	// a pending cluster that does not come back is replaced
+	admitted, delay = c.admit("eks", "c5", start.Add(11*time.Second))
+	assert.False(t, admitted)
+	assert.Equal(t, 9*time.Second, delay)
+	admitted, delay = c.admit("eks", "c6", start.Add(19*time.Second))
+	assert.False(t, admitted)
+	assert.Zero(t, delay)
+	admitted, _ = c.admit("eks", "c6", start.Add(31*time.Second))
+	assert.True(t, admitted)
+
+	// the result of the last reconcile that ensured the charts is kept until the next one ensures them
+	result := &chartsResult{}
+	c.done("eks", result)
+	assert.Same(t, result, c.lastResult("eks"))
+	admitted, _ = c.admit("eks", "c6", start.Add(41*time.Second))
+	assert.True(t, admitted)
+	c.done("eks", nil)
+	assert.Same(t, result, c.lastResult("eks"))
+	assert.Nil(t, c.lastResult("aks"))
+}
@@ -2,26 +2,32 @@
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
 	controllerv3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	controllerprojectv3 "github.com/rancher/rancher/pkg/generated/controllers/project.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/namespace"
-	"github.com/rancher/rancher/pkg/project"
-	"github.com/rancher/rancher/pkg/ref"
 	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/trustbundle"
 	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/pkg/data"
+	apiextcontrollers "github.com/rancher/wrangler/pkg/generated/controllers/apiextensions.k8s.io/v1"
+	batchcontrollers "github.com/rancher/wrangler/pkg/generated/controllers/batch/v1"
 	v1 "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	"github.com/rancher/wrangler/pkg/kv"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 )
 
 var (
@@ -32,6 +38,7 @@
 	AksChart = chart.Definition{
 		ReleaseNamespace: "cattle-system",
 		ChartName:        "rancher-aks-operator",
+		DependsOn:        []string{AksCrdChart.ChartName},
 	}
 	EksCrdChart = chart.Definition{
 		ReleaseNamespace: "cattle-system",
@@ -40,6 +47,7 @@
 	EksChart = chart.Definition{
 		ReleaseNamespace: "cattle-system",
 		ChartName:        "rancher-eks-operator",
+		DependsOn:        []string{EksCrdChart.ChartName},
 	}
 	GkeCrdChart = chart.Definition{
 		ReleaseNamespace: "cattle-system",
@@ -48,36 +56,71 @@
 	GkeChart = chart.Definition{
 		ReleaseNamespace: "cattle-system",
 		ChartName:        "rancher-gke-operator",
+		DependsOn:        []string{GkeCrdChart.ChartName},
 	}
 )
 
 var (
 	localCluster = "local"
 
-	legacyOperatorAppNameFormat = "rancher-%s-operator"
+	// ensureBackoff bounds the in-line retries of chart installation, smoothing over brief catalog outages.
+	ensureBackoff = wait.Backoff{
+		Steps:    3,
+		Duration: time.Second,
+		Factor:   2,
+		Jitter:   0.5,
+	}
+	// degradedRequeueInterval is how long to wait before trying again once the in-line retries have been exhausted.
+	degradedRequeueInterval = 2 * time.Minute
+
+	serviceMonitorCRDName = "servicemonitors.monitoring.coreos.com"
 )
 
 type handler struct {
-	manager      chart.Manager
-	appCache     controllerprojectv3.AppCache
-	apps         controllerprojectv3.AppController
-	projectCache controllerv3.ProjectCache
-	secretsCache v1.SecretCache
-	chartsConfig chart.RancherConfigGetter
+	manager        chart.Manager
+	clusters       controllerv3.ClusterController
+	appCache       controllerprojectv3.AppCache
+	apps           controllerprojectv3.AppController
+	projectCache   controllerv3.ProjectCache
+	secretsCache   v1.SecretCache
+	crdCache       apiextcontrollers.CustomResourceDefinitionCache
+	clusterCache   controllerv3.ClusterCache
+	configMaps     v1.ConfigMapClient
+	configMapCache v1.ConfigMapCache
+	chartsConfig   chart.RancherConfigGetter
+	jobs           batchcontrollers.JobClient
+	jobCache       batchcontrollers.JobCache
+	podCache       v1.PodCache
+	// inputs records the inputs the charts were last ensured with. If nil, charts are ensured on every cluster event.
+	inputs *chartInputs
+	// coalescer bounds the reconciles of the charts of each provider. If nil, every cluster event is reconciled.
+	coalescer *providerCoalescer
 }
 
 func Register(ctx context.Context, wContext *wrangler.Context) {
 	h := &handler{
-		manager:      wContext.SystemChartsManager,
-		apps:         wContext.Project.App(),
-		projectCache: wContext.Mgmt.Project().Cache(),
-		secretsCache: wContext.Core.Secret().Cache(),
-		appCache:     wContext.Project.App().Cache(),
-		chartsConfig: chart.RancherConfigGetter{ConfigCache: wContext.Core.ConfigMap().Cache()},
+		manager:        wContext.SystemChartsManager,
+		clusters:       wContext.Mgmt.Cluster(),
+		apps:           wContext.Project.App(),
+		projectCache:   wContext.Mgmt.Project().Cache(),
+		secretsCache:   wContext.Core.Secret().Cache(),
+		appCache:       wContext.Project.App().Cache(),
+		crdCache:       wContext.CRD.CustomResourceDefinition().Cache(),
+		clusterCache:   wContext.Mgmt.Cluster().Cache(),
+		configMaps:     wContext.Core.ConfigMap(),
+		configMapCache: wContext.Core.ConfigMap().Cache(),
+		chartsConfig:   chart.RancherConfigGetter{ConfigCache: wContext.Core.ConfigMap().Cache()},
+		jobs:           wContext.Batch.Job(),
+		jobCache:       wContext.Batch.Job().Cache(),
+		podCache:       wContext.Core.Pod().Cache(),
+		inputs:         newChartInputs(),
+		coalescer:      newProviderCoalescer(coalesceInterval),
 	}
 
 	wContext.Mgmt.Cluster().OnChange(ctx, "cluster-provisioning-operator", h.onClusterChange)
 	wContext.Core.Secret().OnChange(ctx, "watch-helm-release", h.onSecretChange)
+	wContext.CRD.CustomResourceDefinition().OnChange(ctx, "hosted-operator-service-monitor", h.onCRDChange)
+	wContext.Mgmt.Setting().OnChange(ctx, "hosted-operator-chart-values", h.onSettingChange)
 }
 
 func (h handler) onClusterChange(key string, cluster *v3.Cluster) (*v3.Cluster, error) {
@@ -85,33 +128,12 @@ func (h handler) onClusterChange(key string, cluster *v3.Cluster) (*v3.Cluster,
 		return cluster, nil
 	}
 
-	var toInstallCrdChart, toInstallChart *chart.Definition
-	var provider string
-	if cluster.Spec.AKSConfig != nil {
-		toInstallCrdChart = &AksCrdChart
-		toInstallChart = &AksChart
-		provider = "aks"
-	} else if cluster.Spec.EKSConfig != nil {
-		toInstallCrdChart = &EksCrdChart
-		toInstallChart = &EksChart
-		provider = "eks"
-	} else if cluster.Spec.GKEConfig != nil {
-		toInstallCrdChart = &GkeCrdChart
-		toInstallChart = &GkeChart
-		provider = "gke"
-	}
-
-	if toInstallCrdChart == nil || toInstallChart == nil {
+	provider := providerForCluster(cluster)
+	if provider == nil {
 		return cluster, nil
 	}
 
-	if err := h.removeLegacyOperatorIfExists(provider); err != nil {
-		return cluster, err
-	}
-
-	if err := h.manager.Ensure(toInstallCrdChart.ReleaseNamespace, toInstallCrdChart.ChartName, "", nil, true, ""); err != nil {
-		return cluster, err
-	}
+	crdChart, operatorChart := provider.Charts()
 
 	systemGlobalRegistry := map[string]interface{}{
 		"cattle": map[string]interface{}{
@@ -119,7 +141,7 @@ func (h handler) onClusterChange(key string, cluster *v3.Cluster) (*v3.Cluster,
 		},
 	}
 
-	additionalCA, err := getAdditionalCA(h.secretsCache)
+	additionalCA, err := trustbundle.Get(h.secretsCache, h.configMapCache)
 	if err != nil {
 		return cluster, err
 	}
@@ -134,19 +156,210 @@ func (h handler) onClusterChange(key string, cluster *v3.Cluster) (*v3.Cluster,
 	// add priority class value
 	if priorityClassName, err := h.chartsConfig.GetPriorityClassName(); err != nil {
 		if !apierror.IsNotFound(err) {
-			logrus.Warnf("Failed to get rancher priorityClassName for %q: %v", toInstallChart.ChartName, err)
+			logrus.Warnf("Failed to get rancher priorityClassName for %q: %v", operatorChart.ChartName, err)
 		}
 	} else {
 		chartValues[chart.PriorityClassKey] = priorityClassName
 	}
 
-	if err := h.manager.Ensure(toInstallChart.ReleaseNamespace, toInstallChart.ChartName, "", chartValues, true, ""); err != nil {
+	if settings.HostedOperatorServiceMonitor.Get() == "true" {
+		monitoringInstalled, err := h.monitoringInstalled()
+		if err != nil {
+			return cluster, err
+		}
+		chartValues["serviceMonitor"] = map[string]interface{}{
+			"enabled": monitoringInstalled,
+		}
+	}
+
+	providerValues, err := provider.Values(cluster)
+	if err != nil {
+		return cluster, fmt.Errorf("failed to get %s operator chart values for cluster %s: %w", provider.Name(), cluster.Name, err)
+	}
+	if len(providerValues) > 0 {
+		chartValues = data.MergeMaps(chartValues, providerValues)
+	}
+
+	partitionValues, ignoredPartitions, err := h.partitionValues(provider)
+	if err != nil {
+		return cluster, fmt.Errorf("failed to get %s operator chart partition values: %w", provider.Name(), err)
+	}
+	if err := ignoredPartitions[cluster.Name]; err != nil {
+		logrus.Warnf("[hostedcluster] ignoring the partition of cluster %s: %v", cluster.Name, err)
+	}
+	if len(partitionValues) > 0 {
+		chartValues = data.MergeMaps(chartValues, partitionValues)
+	}
+
+	settingValues, err := settingChartValues(provider)
+	if err != nil {
+		return cluster, fmt.Errorf("failed to get %s operator chart values of setting %s: %w", provider.Name(), settings.HostedOperatorChartValues.Name, err)
+	}
+	if len(settingValues) > 0 {
+		chartValues = data.MergeMaps(chartValues, settingValues)
+	}
+
+	// Only ensuring the charts, which are shared by all clusters of the provider, is coalesced. The values above are
+	// still rendered for every cluster, so that errors in them are reported on the cluster they belong to, and the
+	// result of the last reconcile of the charts is reported on the clusters whose events were coalesced.
+	var result *chartsResult
+	if h.coalescer != nil {
+		admitted, delay := h.coalescer.admit(provider.Name(), cluster.Name, time.Now())
+		if !admitted {
+			if delay > 0 {
+				logrus.Debugf("[hostedcluster] deferring %s operator charts reconcile for cluster %s by %s", provider.Name(), cluster.Name, delay)
+				h.clusters.EnqueueAfter(cluster.Name, delay)
+			} else {
+				logrus.Debugf("[hostedcluster] coalescing %s operator charts reconcile for cluster %s into a pending reconcile", provider.Name(), cluster.Name)
+			}
+			return h.reportCharts(cluster, h.coalescer.lastResult(provider.Name()))
+		}
+		defer func() { h.coalescer.done(provider.Name(), result) }()
+	}
+
+	if err := h.migrateOperator(provider, operatorMigrations); err != nil {
 		return cluster, err
 	}
 
+	var failed *chart.Definition
+	err = chart.EnsureInOrder(h.manager, []*chart.Definition{&crdChart, &operatorChart}, func(def *chart.Definition) error {
+		var err error
+		if def == &operatorChart {
+			err = h.ensureOperator(cluster, provider, def, chartValues)
+		} else {
+			err = h.ensureIfChanged(def, "", nil, func() error {
+				return h.ensure(def, nil)
+			})
+		}
+		if err != nil {
+			failed = def
+		}
+		return err
+	})
+	if errors.Is(err, chart.ErrDependencyNotInstalled) {
+		logrus.Debugf("[hostedcluster] %v", err)
+		h.clusters.EnqueueAfter(cluster.Name, chart.DependencyRequeueInterval)
+		return cluster, nil
+	} else if errors.Is(err, errImagesPulling) {
+		logrus.Debugf("[hostedcluster] %v", err)
+		h.clusters.EnqueueAfter(cluster.Name, prePullRequeueInterval)
+		return cluster, nil
+	} else if err != nil && failed == nil {
+		return cluster, err
+	}
+
+	result = &chartsResult{failed: failed, err: err}
+	return h.reportCharts(cluster, result)
+}
+
+// reportCharts records the result of ensuring the charts of the provider of the cluster on the cluster. Nothing is
+// recorded if there is no result yet.
+func (h handler) reportCharts(cluster *v3.Cluster, result *chartsResult) (*v3.Cluster, error) {
+	if result == nil {
+		return cluster, nil
+	}
+	if result.err != nil {
+		return h.setDegraded(cluster, result.failed, result.err)
+	}
+	return h.clearDegraded(cluster)
+}
+
+// ensure installs the given chart, retrying with a jittered backoff if the installation fails.
+func (h handler) ensure(def *chart.Definition, values map[string]interface{}) error {
+	return retry.OnError(ensureBackoff, func(error) bool { return true }, func() error {
+		return h.manager.Ensure(def.ReleaseNamespace, def.ChartName, "", values, true, "")
+	})
+}
+
+// setDegraded records the chart installation failure on the cluster and enqueues the cluster after a jittered delay
+// rather than returning the error, which would otherwise cause the cluster to be requeued in a tight loop while the
+// catalog is unavailable.
+func (h handler) setDegraded(cluster *v3.Cluster, def *chart.Definition, ensureErr error) (*v3.Cluster, error) {
+	logrus.Errorf("[hostedcluster] failed to install %s for cluster %s: %v", def.ChartName, cluster.Name, ensureErr)
+	if h.clusters == nil {
+		return cluster, ensureErr
+	}
+
+	err := fmt.Errorf("failed to install %s: %w", def.ChartName, ensureErr)
+	if !v3.ClusterConditionHostedOperatorDeployed.IsFalse(cluster) || v3.ClusterConditionHostedOperatorDeployed.GetMessage(cluster) != err.Error() {
+		cluster = cluster.DeepCopy()
+		v3.ClusterConditionHostedOperatorDeployed.False(cluster)
+		v3.ClusterConditionHostedOperatorDeployed.Reason(cluster, "ChartInstallFailed")
+		v3.ClusterConditionHostedOperatorDeployed.Message(cluster, err.Error())
+		updated, updateErr := h.clusters.Update(cluster)
+		if updateErr != nil {
+			return cluster, updateErr
+		}
+		cluster = updated
+	}
+
+	h.clusters.EnqueueAfter(cluster.Name, wait.Jitter(degradedRequeueInterval, 0.5))
 	return cluster, nil
 }
 
+// clearDegraded marks the operator as deployed if the cluster was previously marked as degraded.
+func (h handler) clearDegraded(cluster *v3.Cluster) (*v3.Cluster, error) {
+	if h.clusters == nil || v3.ClusterConditionHostedOperatorDeployed.GetStatus(cluster) == "" || v3.ClusterConditionHostedOperatorDeployed.IsTrue(cluster) {
+		return cluster, nil
+	}
+
+	cluster = cluster.DeepCopy()
+	v3.ClusterConditionHostedOperatorDeployed.True(cluster)
+	v3.ClusterConditionHostedOperatorDeployed.Reason(cluster, "")
+	v3.ClusterConditionHostedOperatorDeployed.Message(cluster, "")
+	return h.clusters.Update(cluster)
+}
+
+// monitoringInstalled returns true if the ServiceMonitor CRD installed by rancher-monitoring exists in the local
+// cluster. Enabling ServiceMonitors without it would fail the installation of the operator chart.
+func (h handler) monitoringInstalled() (bool, error) {
+	if _, err := h.crdCache.Get(serviceMonitorCRDName); apierror.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// onCRDChange enqueues a hosted cluster of every provider when the ServiceMonitor CRD is created or removed, so that
+// the operator charts are updated to match whether monitoring is installed.
+func (h handler) onCRDChange(key string, crd *apiextv1.CustomResourceDefinition) (*apiextv1.CustomResourceDefinition, error) {
+	if key != serviceMonitorCRDName || settings.HostedOperatorServiceMonitor.Get() != "true" {
+		return crd, nil
+	}
+
+	return crd, h.enqueueProviderClusters()
+}
+
+// onSettingChange enqueues a hosted cluster of every provider when the hosted-operator-chart-values setting changes, so
+// that the operator charts are updated with the new values.
+func (h handler) onSettingChange(key string, setting *v3.Setting) (*v3.Setting, error) {
+	if setting == nil || setting.Name != settings.HostedOperatorChartValues.Name {
+		return setting, nil
+	}
+	return setting, h.enqueueProviderClusters()
+}
+
+// enqueueProviderClusters enqueues one hosted cluster of every provider, which is enough to update the operator chart
+// that is shared by all clusters of the provider.
+func (h handler) enqueueProviderClusters() error {
+	clusters, err := h.clusterCache.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, cluster := range clusters {
+		provider := providerForCluster(cluster)
+		if provider == nil || seen[provider.Name()] {
+			continue
+		}
+		seen[provider.Name()] = true
+		h.clusters.Enqueue(cluster.Name)
+	}
+	return nil
+}
+
 // check helm release secrets for aks/eks/gke operator chart, if it has been uninstalled, then remove it in m.manager.desiredChart
 // so that we don't automatically redeploy it unless there is an AKS/EKS/GKE cluster triggering it
 func (h handler) onSecretChange(key string, obj *corev1.Secret) (*corev1.Secret, error) {
@@ -159,6 +372,9 @@ func (h handler) onSecretChange(key string, obj *corev1.Secret) (*corev1.Secret,
 				releaseName := parts[4]
 				if isOperatorChartRelease(releaseName) {
 					h.manager.Remove(ns, releaseName, "")
+					if h.inputs != nil {
+						h.inputs.forget(ns, releaseName)
+					}
 				}
 			}
 		}
@@ -167,44 +383,14 @@ func (h handler) onSecretChange(key string, obj *corev1.Secret) (*corev1.Secret,
 }
 
 func isOperatorChartRelease(name string) bool {
-	switch name {
-	case AksCrdChart.ChartName, AksChart.ChartName, EksCrdChart.ChartName, EksChart.ChartName, GkeChart.ChartName, GkeCrdChart.ChartName:
-		return true
-	}
-	return false
-}
-
-func (h handler) removeLegacyOperatorIfExists(provider string) error {
-	systemProject, err := project.GetSystemProject(localCluster, h.projectCache)
-	if err != nil {
-		return err
-	}
+	providersLock.RLock()
+	defer providersLock.RUnlock()
 
-	systemProjectID := ref.Ref(systemProject)
-	_, systemProjectName := ref.Parse(systemProjectID)
-
-	legacyOperatorAppName := fmt.Sprintf(legacyOperatorAppNameFormat, provider)
-	_, err = h.appCache.Get(systemProjectName, legacyOperatorAppName)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// legacy app doesn't exist, no-op
-			return nil
+	for _, p := range providers {
+		crdChart, operatorChart := p.Charts()
+		if name == crdChart.ChartName || name == operatorChart.ChartName {
+			return true
 		}
-		return err
 	}
-
-	return h.apps.Delete(systemProjectName, legacyOperatorAppName, &metav1.DeleteOptions{})
-}
-
-func getAdditionalCA(secretsCache v1.SecretCache) ([]byte, error) {
-	secret, err := secretsCache.Get(namespace.System, "tls-ca-additional")
-	if err != nil && !errors.IsNotFound(err) {
-		return nil, err
-	}
-
-	if secret == nil {
-		return nil, nil
-	}
-
-	return secret.Data["ca-additional.pem"], nil
+	return false
 }
@@ -5,16 +5,22 @@
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/chart/fake"
+	"github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 var priorityClassName = "rancher-critical"
@@ -58,6 +64,7 @@ func Test_handler_onClusterChange(t *testing.T) {
 					gomock.AssignableToTypeOf(b),
 					"",
 				).Return(nil)
+				manager.EXPECT().Installed(AksCrdChart.ReleaseNamespace, AksCrdChart.ChartName).Return(true, nil)
 				manager.EXPECT().Ensure(
 					AksChart.ReleaseNamespace,
 					AksChart.ChartName,
@@ -100,6 +107,7 @@ func Test_handler_onClusterChange(t *testing.T) {
 					gomock.AssignableToTypeOf(b),
 					"",
 				).Return(nil)
+				manager.EXPECT().Installed(AksCrdChart.ReleaseNamespace, AksCrdChart.ChartName).Return(true, nil)
 				manager.EXPECT().Ensure(
 					AksChart.ReleaseNamespace,
 					AksChart.ChartName,
@@ -131,6 +139,204 @@ func Test_handler_onClusterChange(t *testing.T) {
 	}
 }
 
+func Test_handler_onClusterChangeDegraded(t *testing.T) {
+	defaultBackoff := ensureBackoff
+	ensureBackoff = wait.Backoff{Steps: 3}
+	defer func() { ensureBackoff = defaultBackoff }()
+
+	settings.ConfigMapName.Set("error")
+	cond := v3.ClusterConditionHostedOperatorDeployed
+
+	tests := []struct {
+		name           string
+		cluster        *v3.Cluster
+		chartErr       error
+		expectedStatus string
+		expectUpdate   bool
+	}{
+		{
+			name: "chart install fails",
+			cluster: &v3.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "c-abc"},
+				Spec: v3.ClusterSpec{
+					AKSConfig: &aksv1.AKSClusterConfigSpec{},
+				},
+			},
+			chartErr:       fmt.Errorf("repo unavailable"),
+			expectedStatus: "False",
+			expectUpdate:   true,
+		},
+		{
+			name: "chart install fails with condition already set",
+			cluster: &v3.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "c-abc"},
+				Spec: v3.ClusterSpec{
+					AKSConfig: &aksv1.AKSClusterConfigSpec{},
+				},
+				Status: v3.ClusterStatus{
+					Conditions: []v3.ClusterCondition{
+						{
+							Type:    v3.ClusterConditionType(cond),
+							Status:  v1.ConditionFalse,
+							Reason:  "ChartInstallFailed",
+							Message: "failed to install rancher-aks-operator: repo unavailable",
+						},
+					},
+				},
+			},
+			chartErr:       fmt.Errorf("repo unavailable"),
+			expectedStatus: "False",
+		},
+		{
+			name: "chart install recovers",
+			cluster: &v3.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "c-abc"},
+				Spec: v3.ClusterSpec{
+					AKSConfig: &aksv1.AKSClusterConfigSpec{},
+				},
+				Status: v3.ClusterStatus{
+					Conditions: []v3.ClusterCondition{
+						{
+							Type:    v3.ClusterConditionType(cond),
+							Status:  v1.ConditionFalse,
+							Reason:  "ChartInstallFailed",
+							Message: "failed to install rancher-aks-operator: repo unavailable",
+						},
+					},
+				},
+			},
+			expectedStatus: "True",
+			expectUpdate:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			h := newHandler(ctrl)
+			manager := fake.NewMockManager(ctrl)
+			manager.EXPECT().Ensure(AksCrdChart.ReleaseNamespace, AksCrdChart.ChartName, "", nil, true, "").Return(nil)
+			manager.EXPECT().Installed(AksCrdChart.ReleaseNamespace, AksCrdChart.ChartName).Return(true, nil)
+			chartCalls := 1
+			if tt.chartErr != nil {
+				chartCalls = ensureBackoff.Steps
+			}
+			manager.EXPECT().Ensure(AksChart.ReleaseNamespace, AksChart.ChartName, "", gomock.Any(), true, "").Return(tt.chartErr).Times(chartCalls)
+			h.manager = manager
+
+			clusters := NewMockClusterController(ctrl)
+			var updated *v3.Cluster
+			if tt.expectUpdate {
+				clusters.EXPECT().Update(gomock.Any()).DoAndReturn(func(cluster *v3.Cluster) (*v3.Cluster, error) {
+					updated = cluster
+					return cluster, nil
+				})
+			}
+			if tt.chartErr != nil {
+				clusters.EXPECT().EnqueueAfter(tt.cluster.Name, gomock.AssignableToTypeOf(time.Duration(0)))
+			}
+			h.clusters = clusters
+
+			got, err := h.onClusterChange("", tt.cluster)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, cond.GetStatus(got))
+			if tt.expectUpdate {
+				assert.Equal(t, updated, got)
+			} else {
+				assert.Equal(t, tt.cluster, got)
+			}
+		})
+	}
+}
+
+func Test_handler_onClusterChangeCoalesced(t *testing.T) {
+	settings.ConfigMapName.Set("error")
+	cond := v3.ClusterConditionHostedOperatorDeployed
+
+	tests := []struct {
+		name           string
+		result         *chartsResult
+		conditions     []v3.ClusterCondition
+		expectedStatus string
+		expectUpdate   bool
+	}{
+		{
+			name: "charts not ensured yet",
+		},
+		{
+			name:           "charts failed",
+			result:         &chartsResult{failed: &AksChart, err: fmt.Errorf("repo unavailable")},
+			expectedStatus: "False",
+			expectUpdate:   true,
+		},
+		{
+			name:   "charts installed",
+			result: &chartsResult{},
+			conditions: []v3.ClusterCondition{{
+				Type:    v3.ClusterConditionType(cond),
+				Status:  v1.ConditionFalse,
+				Reason:  "ChartInstallFailed",
+				Message: "failed to install rancher-aks-operator: repo unavailable",
+			}},
+			expectedStatus: "True",
+			expectUpdate:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			secretsCache := NewMockSecretCache(ctrl)
+			secretsCache.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+			secretsCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+			configMapCache := NewMockConfigMapCache(ctrl)
+			configMapCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+			configCache := NewMockConfigMapCache(ctrl)
+			configCache.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("not found")).AnyTimes()
+
+			// the charts of the provider were ensured for another cluster just now
+			coalescer := newProviderCoalescer(time.Minute)
+			admitted, _ := coalescer.admit("aks", "c-first", time.Now())
+			assert.True(t, admitted)
+			coalescer.done("aks", tt.result)
+
+			clusters := NewMockClusterController(ctrl)
+			clusters.EXPECT().EnqueueAfter("c-abc", gomock.AssignableToTypeOf(time.Duration(0))).AnyTimes()
+			var updated *v3.Cluster
+			if tt.expectUpdate {
+				clusters.EXPECT().Update(gomock.Any()).DoAndReturn(func(cluster *v3.Cluster) (*v3.Cluster, error) {
+					updated = cluster
+					return cluster, nil
+				})
+			}
+
+			h := &handler{
+				manager:        fake.NewMockManager(ctrl),
+				clusters:       clusters,
+				secretsCache:   secretsCache,
+				configMapCache: configMapCache,
+				chartsConfig:   chart.RancherConfigGetter{ConfigCache: configCache},
+				clusterCache:   &fakeClusterCache{},
+				coalescer:      coalescer,
+			}
+			cluster := &v3.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "c-abc"},
+				Spec: v3.ClusterSpec{
+					AKSConfig: &aksv1.AKSClusterConfigSpec{},
+				},
+				Status: v3.ClusterStatus{Conditions: tt.conditions},
+			}
+
+			got, err := h.onClusterChange("", cluster)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, cond.GetStatus(got))
+			if tt.expectUpdate {
+				assert.Equal(t, updated, got)
+			} else {
+				assert.Equal(t, cluster, got)
+			}
+		})
+	}
+}
+
 func newHandler(ctrl *gomock.Controller) *handler {
 	appCache := NewMockAppCache(ctrl)
 	appCache.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nil)
@@ -140,14 +346,79 @@ func newHandler(ctrl *gomock.Controller) *handler {
 	projectCache.EXPECT().List(gomock.Any(), gomock.Any()).Return([]*v3.Project{{ObjectMeta: metav1.ObjectMeta{Name: "test"}}}, nil)
 	secretsCache := NewMockSecretCache(ctrl)
 	secretsCache.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nil)
+	secretsCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil)
+	configMapCache := NewMockConfigMapCache(ctrl)
+	configMapCache.EXPECT().Get(namespace.System, migrationConfigMapName).Return(nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, migrationConfigMapName)).AnyTimes()
+	configMapCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil)
 	configCache := NewMockConfigMapCache(ctrl)
 	configCache.EXPECT().Get(gomock.Any(), "pass").Return(&v1.ConfigMap{Data: map[string]string{"priorityClassName": priorityClassName}}, nil).AnyTimes()
 	configCache.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("not found")).AnyTimes()
 	return &handler{
-		appCache:     appCache,
-		apps:         apps,
-		projectCache: projectCache,
-		secretsCache: secretsCache,
-		chartsConfig: chart.RancherConfigGetter{ConfigCache: configCache},
+		appCache:       appCache,
+		apps:           apps,
+		projectCache:   projectCache,
+		secretsCache:   secretsCache,
+		configMapCache: configMapCache,
+		chartsConfig:   chart.RancherConfigGetter{ConfigCache: configCache},
+		clusterCache:   &fakeClusterCache{},
+	}
+}
+
+func Test_handler_onClusterChangeServiceMonitor(t *testing.T) {
+	tests := []struct {
+		name                string
+		setting             string
+		monitoringInstalled bool
+		expected            interface{}
+	}{
+		{
+			name:    "disabled",
+			setting: "false",
+		},
+		{
+			name:     "enabled without monitoring",
+			setting:  "true",
+			expected: map[string]interface{}{"enabled": false},
+		},
+		{
+			name:                "enabled with monitoring",
+			setting:             "true",
+			monitoringInstalled: true,
+			expected:            map[string]interface{}{"enabled": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := settings.HostedOperatorServiceMonitor.Get()
+			assert.NoError(t, settings.HostedOperatorServiceMonitor.Set(tt.setting))
+			defer settings.HostedOperatorServiceMonitor.Set(original)
+
+			ctrl := gomock.NewController(t)
+			h := newHandler(ctrl)
+			crdCache := NewMockCustomResourceDefinitionCache(ctrl)
+			if tt.monitoringInstalled {
+				crdCache.EXPECT().Get(serviceMonitorCRDName).Return(&apiextv1.CustomResourceDefinition{}, nil).AnyTimes()
+			} else {
+				crdCache.EXPECT().Get(serviceMonitorCRDName).Return(nil, apierrors.NewNotFound(schema.GroupResource{}, serviceMonitorCRDName)).AnyTimes()
+			}
+			h.crdCache = crdCache
+
+			manager := fake.NewMockManager(ctrl)
+			manager.EXPECT().Ensure(AksCrdChart.ReleaseNamespace, AksCrdChart.ChartName, "", nil, true, "").Return(nil)
+			manager.EXPECT().Installed(AksCrdChart.ReleaseNamespace, AksCrdChart.ChartName).Return(true, nil)
+			manager.EXPECT().Ensure(AksChart.ReleaseNamespace, AksChart.ChartName, "", gomock.Any(), true, "").DoAndReturn(
+				func(namespace, name, minVersion string, values map[string]interface{}, forceAdopt bool, installImageOverride string) error {
+					assert.Equal(t, tt.expected, values["serviceMonitor"])
+					return nil
+				})
+			h.manager = manager
+
+			_, err := h.onClusterChange("", &v3.Cluster{
+				Spec: v3.ClusterSpec{
+					AKSConfig: &aksv1.AKSClusterConfigSpec{},
+				},
+			})
+			assert.NoError(t, err)
+		})
 	}
 }
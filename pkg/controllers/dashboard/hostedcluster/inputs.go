@@ -0,0 +1,100 @@
+package hostedcluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
+	"github.com/rancher/rancher/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// chartInputs records the hash of the version and values that each chart was last ensured with, so that cluster events
+// that do not change the inputs of a chart do not cause it to be ensured again. The hashes are only kept in memory, so
+// every chart is ensured again once after Rancher restarted.
+type chartInputs struct {
+	lock   sync.Mutex
+	hashes map[string]string
+}
+
+func newChartInputs() *chartInputs {
+	return &chartInputs{
+		hashes: map[string]string{},
+	}
+}
+
+// ensureIfChanged calls ensure unless the chart was already ensured successfully with the same version and values.
+func (c *chartInputs) ensureIfChanged(def *chart.Definition, version string, values map[string]interface{}, ensure func() error) error {
+	hash, err := hashChartInputs(version, values)
+	if err != nil {
+		logrus.Debugf("[hostedcluster] failed to hash inputs of chart %s: %v", def.ChartName, err)
+		return ensure()
+	}
+
+	key := chartInputsKey(def.ReleaseNamespace, def.ChartName)
+	c.lock.Lock()
+	unchanged := c.hashes[key] == hash
+	c.lock.Unlock()
+	if unchanged {
+		logrus.Debugf("[hostedcluster] skipping chart %s as its version and values did not change", def.ChartName)
+		metrics.IncHostedOperatorChartEnsureSkipped(def.ChartName)
+		return nil
+	}
+
+	if err := ensure(); err != nil {
+		c.forget(def.ReleaseNamespace, def.ChartName)
+		return err
+	}
+
+	c.lock.Lock()
+	c.hashes[key] = hash
+	c.lock.Unlock()
+	return nil
+}
+
+// forget removes the inputs recorded for the chart, so that it is ensured on the next cluster event.
+func (c *chartInputs) forget(namespace, name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.hashes, chartInputsKey(namespace, name))
+}
+
+func chartInputsKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// hashChartInputs returns the hash of the chart version and values. Maps are encoded with sorted keys, so equal values
+// always result in the same hash.
+func hashChartInputs(version string, values map[string]interface{}) (string, error) {
+	data, err := json.Marshal(struct {
+		Version string                 `json:"version"`
+		Values  map[string]interface{} `json:"values"`
+	}{
+		Version: version,
+		Values:  values,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ensureIfChanged calls ensure unless the chart was already ensured with the same version and values. If version is
+// empty, the latest version of the chart is compared. Charts are always ensured if the handler does not record inputs.
+func (h handler) ensureIfChanged(def *chart.Definition, version string, values map[string]interface{}, ensure func() error) error {
+	if h.inputs == nil {
+		return ensure()
+	}
+	if version == "" {
+		latest, err := h.manager.LatestVersion(def.ChartName)
+		if err != nil {
+			logrus.Debugf("[hostedcluster] failed to get the latest version of chart %s: %v", def.ChartName, err)
+			return ensure()
+		}
+		version = latest
+	}
+	return h.inputs.ensureIfChanged(def, version, values, ensure)
+}
@@ -0,0 +1,84 @@
+package hostedcluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChartInputsEnsureIfChanged(t *testing.T) {
+	inputs := newChartInputs()
+	calls := 0
+	ensure := func() error {
+		calls++
+		return nil
+	}
+	values := map[string]interface{}{"httpProxy": "proxy", "global": map[string]interface{}{"cattle": "registry"}}
+
+	assert.NoError(t, inputs.ensureIfChanged(&AksChart, "1.0.0", values, ensure))
+	assert.Equal(t, 1, calls)
+
+	// unchanged inputs are skipped, regardless of the order the values were built in
+	sameValues := map[string]interface{}{"global": map[string]interface{}{"cattle": "registry"}, "httpProxy": "proxy"}
+	assert.NoError(t, inputs.ensureIfChanged(&AksChart, "1.0.0", sameValues, ensure))
+	assert.Equal(t, 1, calls)
+
+	// other charts are tracked separately
+	assert.NoError(t, inputs.ensureIfChanged(&EksChart, "1.0.0", values, ensure))
+	assert.Equal(t, 2, calls)
+
+	// a new version or new values are ensured
+	assert.NoError(t, inputs.ensureIfChanged(&AksChart, "1.0.1", values, ensure))
+	assert.Equal(t, 3, calls)
+	assert.NoError(t, inputs.ensureIfChanged(&AksChart, "1.0.1", map[string]interface{}{"httpProxy": "other"}, ensure))
+	assert.Equal(t, 4, calls)
+
+	// a forgotten chart is ensured again
+	inputs.forget(AksChart.ReleaseNamespace, AksChart.ChartName)
+	assert.NoError(t, inputs.ensureIfChanged(&AksChart, "1.0.1", map[string]interface{}{"httpProxy": "other"}, ensure))
+	assert.Equal(t, 5, calls)
+}
+
+func TestChartInputsEnsureIfChangedFailure(t *testing.T) {
+	inputs := newChartInputs()
+	calls := 0
+	ensureErr := errors.New("catalog unavailable")
+	failing := func() error {
+		calls++
+		return ensureErr
+	}
+
+	assert.ErrorIs(t, inputs.ensureIfChanged(&AksChart, "1.0.0", nil, failing), ensureErr)
+	// a failed installation is retried with the same inputs
+	assert.ErrorIs(t, inputs.ensureIfChanged(&AksChart, "1.0.0", nil, failing), ensureErr)
+	assert.Equal(t, 2, calls)
+}
+
+func TestHandlerEnsureIfChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	manager := fake.NewMockManager(ctrl)
+	manager.EXPECT().LatestVersion(AksChart.ChartName).Return("1.0.0", nil).Times(2)
+	manager.EXPECT().LatestVersion(AksChart.ChartName).Return("1.0.1", nil)
+
+	h := handler{manager: manager, inputs: newChartInputs()}
+	calls := 0
+	ensure := func() error {
+		calls++
+		return nil
+	}
+
+	assert.NoError(t, h.ensureIfChanged(&AksChart, "", nil, ensure))
+	assert.NoError(t, h.ensureIfChanged(&AksChart, "", nil, ensure))
+	assert.Equal(t, 1, calls)
+	// a new version of the chart became available
+	assert.NoError(t, h.ensureIfChanged(&AksChart, "", nil, ensure))
+	assert.Equal(t, 2, calls)
+
+	// without recorded inputs, charts are always ensured
+	h.inputs = nil
+	assert.NoError(t, h.ensureIfChanged(&AksChart, "", nil, ensure))
+	assert.Equal(t, 3, calls)
+}
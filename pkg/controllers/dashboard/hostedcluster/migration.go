@@ -0,0 +1,168 @@
+package hostedcluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/project"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// migrationConfigMapName is the name of the config map in the system namespace that records the completed operator
+// migrations of each provider.
+const migrationConfigMapName = "hosted-operator-migrations"
+
+// operatorMigration migrates the operator of a provider from a legacy installation to one of the charts of the
+// provider. Migrations are run in order before the charts of a provider are ensured, and are recorded as completed for
+// the provider once all of their steps succeeded, so that each migration is run once per provider.
+type operatorMigration struct {
+	// name identifies the migration in the record of completed migrations. It must not change once released.
+	name string
+	// legacyAppNameFormat is formatted with the name of the provider to the name of the legacy app in the system project
+	// of the local cluster, which is deleted if it exists.
+	legacyAppNameFormat string
+	// replacementChart returns the chart that replaces the legacy app. Its recorded inputs are forgotten once the legacy
+	// app was deleted, so that the chart is ensured again even if its values did not change.
+	replacementChart func(provider Provider) chart.Definition
+	// cleanup are run in order after the legacy app was deleted.
+	cleanup []func(h handler, provider Provider) error
+}
+
+// operatorMigrations are the migrations that are run for every provider. New migrations are appended.
+var operatorMigrations = []operatorMigration{
+	{
+		name:                "legacy-operator-app",
+		legacyAppNameFormat: "rancher-%s-operator",
+		replacementChart: func(provider Provider) chart.Definition {
+			_, operatorChart := provider.Charts()
+			return operatorChart
+		},
+	},
+}
+
+// migrateOperator runs the operator migrations that are not recorded as completed for the provider. Completions are
+// only recorded if the handler has config map clients, otherwise all migrations are run on every reconcile.
+func (h handler) migrateOperator(provider Provider, migrations []operatorMigration) error {
+	completed, err := h.completedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		key := migrationKey(provider, migration)
+		if _, ok := completed[key]; ok {
+			continue
+		}
+		if err := h.runMigration(provider, migration); err != nil {
+			return fmt.Errorf("failed to run %s operator migration %s: %w", provider.Name(), migration.name, err)
+		}
+		if err := h.recordMigration(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h handler) runMigration(provider Provider, migration operatorMigration) error {
+	if migration.legacyAppNameFormat != "" {
+		removed, err := h.removeLegacyApp(fmt.Sprintf(migration.legacyAppNameFormat, provider.Name()))
+		if err != nil {
+			return err
+		}
+		if removed {
+			logrus.Infof("[hostedcluster] removed legacy %s operator app in %s operator migration %s", provider.Name(), provider.Name(), migration.name)
+			if migration.replacementChart != nil && h.inputs != nil {
+				def := migration.replacementChart(provider)
+				h.inputs.forget(def.ReleaseNamespace, def.ChartName)
+			}
+		}
+	}
+
+	for _, cleanup := range migration.cleanup {
+		if err := cleanup(h, provider); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeLegacyApp deletes the app of the passed in name in the system project of the local cluster, and returns whether
+// the app existed.
+func (h handler) removeLegacyApp(appName string) (bool, error) {
+	systemProject, err := project.GetSystemProject(localCluster, h.projectCache)
+	if err != nil {
+		return false, err
+	}
+
+	systemProjectID := ref.Ref(systemProject)
+	_, systemProjectName := ref.Parse(systemProjectID)
+
+	_, err = h.appCache.Get(systemProjectName, appName)
+	if err != nil {
+		if apierror.IsNotFound(err) {
+			// legacy app doesn't exist, no-op
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := h.apps.Delete(systemProjectName, appName, &metav1.DeleteOptions{}); err != nil && !apierror.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+func migrationKey(provider Provider, migration operatorMigration) string {
+	return provider.Name() + "." + migration.name
+}
+
+func (h handler) completedMigrations() (map[string]string, error) {
+	if h.configMapCache == nil {
+		return nil, nil
+	}
+	configMap, err := h.configMapCache.Get(namespace.System, migrationConfigMapName)
+	if apierror.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return configMap.Data, nil
+}
+
+func (h handler) recordMigration(key string) error {
+	if h.configMaps == nil {
+		return nil
+	}
+	completedAt := time.Now().UTC().Format(time.RFC3339)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := h.configMaps.Get(namespace.System, migrationConfigMapName, metav1.GetOptions{})
+		if apierror.IsNotFound(err) {
+			_, err = h.configMaps.Create(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      migrationConfigMapName,
+					Namespace: namespace.System,
+				},
+				Data: map[string]string{key: completedAt},
+			})
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		configMap = configMap.DeepCopy()
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[key] = completedAt
+		_, err = h.configMaps.Update(configMap)
+		return err
+	})
+}
@@ -0,0 +1,108 @@
+package hostedcluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func Test_handler_migrateOperator(t *testing.T) {
+	aks := providerForCluster(&v3.Cluster{Spec: v3.ClusterSpec{AKSConfig: &aksv1.AKSClusterConfigSpec{}}})
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "apps"}, "rancher-aks-operator")
+
+	tests := []struct {
+		name        string
+		completed   map[string]string
+		appErr      error
+		cleanupErr  error
+		wantDeleted bool
+		wantCleanup bool
+		wantErr     bool
+	}{
+		{
+			name:        "legacy app exists",
+			wantDeleted: true,
+			wantCleanup: true,
+		},
+		{
+			name:        "legacy app does not exist",
+			appErr:      notFound,
+			wantCleanup: true,
+		},
+		{
+			name:      "completed",
+			completed: map[string]string{"aks.legacy-operator-app": "2023-10-01T00:00:00Z"},
+		},
+		{
+			name:        "completed for other provider",
+			completed:   map[string]string{"eks.legacy-operator-app": "2023-10-01T00:00:00Z"},
+			appErr:      notFound,
+			wantCleanup: true,
+		},
+		{
+			name:        "cleanup fails",
+			appErr:      notFound,
+			cleanupErr:  fmt.Errorf("cleanup failed"),
+			wantCleanup: true,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			configMapCache := NewMockConfigMapCache(ctrl)
+			if tt.completed != nil {
+				configMapCache.EXPECT().Get(namespace.System, migrationConfigMapName).Return(&v1.ConfigMap{Data: tt.completed}, nil)
+			} else {
+				configMapCache.EXPECT().Get(namespace.System, migrationConfigMapName).Return(nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, migrationConfigMapName))
+			}
+			appCache := NewMockAppCache(ctrl)
+			apps := NewMockAppController(ctrl)
+			projectCache := NewMockProjectCache(ctrl)
+			if tt.completed["aks.legacy-operator-app"] == "" {
+				projectCache.EXPECT().List(gomock.Any(), gomock.Any()).Return([]*v3.Project{{ObjectMeta: metav1.ObjectMeta{Name: "test"}}}, nil)
+				appCache.EXPECT().Get(gomock.Any(), "rancher-aks-operator").Return(nil, tt.appErr)
+			}
+			if tt.wantDeleted {
+				apps.EXPECT().Delete(gomock.Any(), "rancher-aks-operator", gomock.Any()).Return(nil)
+			}
+
+			h := handler{
+				appCache:       appCache,
+				apps:           apps,
+				projectCache:   projectCache,
+				configMapCache: configMapCache,
+				inputs:         newChartInputs(),
+			}
+			h.inputs.hashes[chartInputsKey(AksChart.ReleaseNamespace, AksChart.ChartName)] = "hash"
+
+			cleanedUp := false
+			migrations := []operatorMigration{operatorMigrations[0]}
+			migrations[0].cleanup = []func(handler, Provider) error{
+				func(h handler, provider Provider) error {
+					cleanedUp = true
+					return tt.cleanupErr
+				},
+			}
+
+			err := h.migrateOperator(aks, migrations)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCleanup, cleanedUp)
+			_, recorded := h.inputs.hashes[chartInputsKey(AksChart.ReleaseNamespace, AksChart.ChartName)]
+			assert.Equal(t, !tt.wantDeleted, recorded)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package hostedcluster
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// publicPartitions are the partitions of the providers that the operators use by default.
+var publicPartitions = map[string]string{
+	"aks": "AzurePublicCloud",
+	"eks": "aws",
+}
+
+// knownPartitions are the endpoints of the partitions of the providers that are known to Rancher. The endpoints can be
+// overridden by the partition of a cluster.
+var knownPartitions = map[string]map[string]map[string]string{
+	"aks": {
+		"AzureUSGovernmentCloud": {
+			"resourceManager": "https://management.usgovcloudapi.net/",
+			"activeDirectory": "https://login.microsoftonline.us/",
+		},
+		"AzureChinaCloud": {
+			"resourceManager": "https://management.chinacloudapi.cn/",
+			"activeDirectory": "https://login.chinacloudapi.cn/",
+		},
+	},
+	"eks": {
+		"aws-us-gov": {
+			"dnsSuffix": "amazonaws.com",
+		},
+		"aws-cn": {
+			"dnsSuffix": "amazonaws.com.cn",
+		},
+	},
+}
+
+// clusterPartition returns the name and endpoints of the partition of the cluster. The public partition of the provider
+// is returned without endpoints.
+func clusterPartition(provider string, cluster *v3.Cluster) (string, map[string]string, error) {
+	var name string
+	var overrides map[string]string
+	if cluster.Spec.HostedPartition != nil {
+		name, overrides = cluster.Spec.HostedPartition.Name, cluster.Spec.HostedPartition.Endpoints
+	}
+	if name == "" && provider == "eks" && cluster.Spec.EKSConfig != nil {
+		name = eksRegionPartition(cluster.Spec.EKSConfig.Region)
+	}
+	if name == "" || name == publicPartitions[provider] {
+		if len(overrides) > 0 {
+			return "", nil, fmt.Errorf("endpoints of the public partition of %s can not be overridden", provider)
+		}
+		return "", nil, nil
+	}
+
+	endpoints := map[string]string{}
+	for service, endpoint := range knownPartitions[provider][name] {
+		endpoints[service] = endpoint
+	}
+	for service, endpoint := range overrides {
+		endpoints[service] = endpoint
+	}
+	if len(endpoints) == 0 {
+		return "", nil, fmt.Errorf("partition %s is not known for %s, its endpoints must be set", name, provider)
+	}
+	return name, endpoints, nil
+}
+
+// eksRegionPartition returns the AWS partition of the region.
+func eksRegionPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	}
+	return ""
+}
+
+// partitionValues returns the operator chart values of the provider with the endpoints of the partitions that its
+// clusters are in, and the reason the partition of a cluster was ignored, keyed by cluster name. The operator chart is
+// shared by all clusters of the provider, so the partitions of all of them are included, and a cluster whose partition
+// is invalid, or configures different endpoints than another cluster in the same partition, is ignored rather than
+// blocking the others. Partitions are only passed to operator charts that read them.
+func (h handler) partitionValues(provider Provider) (map[string]interface{}, map[string]error, error) {
+	clusters, err := h.clusterCache.List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+
+	valuesProvider, supported := provider.(PartitionValuesProvider)
+	partitions := map[string]map[string]string{}
+	owners := map[string]string{}
+	ignored := map[string]error{}
+	for _, cluster := range clusters {
+		if p := providerForCluster(cluster); p == nil || p.Name() != provider.Name() {
+			continue
+		}
+		name, endpoints, err := clusterPartition(provider.Name(), cluster)
+		if err != nil {
+			ignored[cluster.Name] = fmt.Errorf("invalid partition: %w", err)
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		if !supported {
+			ignored[cluster.Name] = fmt.Errorf("the %s operator chart does not support partition %s", provider.Name(), name)
+			continue
+		}
+		if existing, ok := partitions[name]; ok {
+			if !reflect.DeepEqual(existing, endpoints) {
+				ignored[cluster.Name] = fmt.Errorf("cluster %s configures different endpoints for partition %s", owners[name], name)
+			}
+			continue
+		}
+		partitions[name] = endpoints
+		owners[name] = cluster.Name
+	}
+
+	if len(partitions) == 0 {
+		return nil, ignored, nil
+	}
+	return valuesProvider.PartitionValues(partitions), ignored, nil
+}
@@ -0,0 +1,179 @@
+package hostedcluster
+
+import (
+	"testing"
+
+	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	gkev1 "github.com/rancher/gke-operator/pkg/apis/gke.cattle.io/v1"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	controllerv3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeClusterCache struct {
+	clusters []*v3.Cluster
+}
+
+func (f *fakeClusterCache) Get(name string) (*v3.Cluster, error) {
+	for _, cluster := range f.clusters {
+		if cluster.Name == name {
+			return cluster, nil
+		}
+	}
+	return nil, apierror.NewNotFound(schema.GroupResource{Group: "management.cattle.io", Resource: "clusters"}, name)
+}
+
+func (f *fakeClusterCache) List(labels.Selector) ([]*v3.Cluster, error) {
+	return f.clusters, nil
+}
+
+func (f *fakeClusterCache) AddIndexer(string, controllerv3.ClusterIndexer) {}
+
+func (f *fakeClusterCache) GetByIndex(string, string) ([]*v3.Cluster, error) {
+	return nil, nil
+}
+
+func eksCluster(name, region string, partition *v3.HostedPartition) *v3.Cluster {
+	return &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v3.ClusterSpec{
+			EKSConfig:       &eksv1.EKSClusterConfigSpec{Region: region},
+			HostedPartition: partition,
+		},
+	}
+}
+
+func Test_clusterPartition(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     string
+		cluster      *v3.Cluster
+		expectedName string
+		expected     map[string]string
+		wantErr      bool
+	}{
+		{
+			name:     "public eks region",
+			provider: "eks",
+			cluster:  eksCluster("c-1", "us-west-2", nil),
+		},
+		{
+			name:         "govcloud region",
+			provider:     "eks",
+			cluster:      eksCluster("c-1", "us-gov-west-1", nil),
+			expectedName: "aws-us-gov",
+			expected:     map[string]string{"dnsSuffix": "amazonaws.com"},
+		},
+		{
+			name:         "china region with endpoint override",
+			provider:     "eks",
+			cluster:      eksCluster("c-1", "cn-north-1", &v3.HostedPartition{Endpoints: map[string]string{"sts": "https://sts.cn-north-1.example.cn"}}),
+			expectedName: "aws-cn",
+			expected:     map[string]string{"dnsSuffix": "amazonaws.com.cn", "sts": "https://sts.cn-north-1.example.cn"},
+		},
+		{
+			name:     "public partition with endpoints",
+			provider: "aks",
+			cluster: &v3.Cluster{Spec: v3.ClusterSpec{
+				AKSConfig:       &aksv1.AKSClusterConfigSpec{},
+				HostedPartition: &v3.HostedPartition{Name: "AzurePublicCloud", Endpoints: map[string]string{"resourceManager": "https://example.com"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name:     "azure government",
+			provider: "aks",
+			cluster: &v3.Cluster{Spec: v3.ClusterSpec{
+				AKSConfig:       &aksv1.AKSClusterConfigSpec{},
+				HostedPartition: &v3.HostedPartition{Name: "AzureUSGovernmentCloud"},
+			}},
+			expectedName: "AzureUSGovernmentCloud",
+			expected: map[string]string{
+				"resourceManager": "https://management.usgovcloudapi.net/",
+				"activeDirectory": "https://login.microsoftonline.us/",
+			},
+		},
+		{
+			name:     "unknown gke partition without endpoints",
+			provider: "gke",
+			cluster: &v3.Cluster{Spec: v3.ClusterSpec{
+				GKEConfig:       &gkev1.GKEClusterConfigSpec{},
+				HostedPartition: &v3.HostedPartition{Name: "eu-sovereign"},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, endpoints, err := clusterPartition(tt.provider, tt.cluster)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedName, name)
+			assert.Equal(t, tt.expected, endpoints)
+		})
+	}
+}
+
+// partitionProvider is a provider whose operator chart reads the endpoints of partitions.
+type partitionProvider struct {
+	Provider
+}
+
+func (partitionProvider) PartitionValues(partitions map[string]map[string]string) map[string]interface{} {
+	return map[string]interface{}{"cloudEndpoints": partitions}
+}
+
+func Test_handler_partitionValues(t *testing.T) {
+	eks := providerForCluster(eksCluster("c-1", "us-west-2", nil))
+	require.NotNil(t, eks)
+	supported := partitionProvider{Provider: eks}
+
+	h := handler{clusterCache: &fakeClusterCache{clusters: []*v3.Cluster{
+		eksCluster("c-1", "us-west-2", nil),
+		eksCluster("c-2", "us-gov-west-1", nil),
+		eksCluster("c-3", "us-gov-east-1", nil),
+		{ObjectMeta: metav1.ObjectMeta{Name: "c-4"}, Spec: v3.ClusterSpec{AKSConfig: &aksv1.AKSClusterConfigSpec{}, HostedPartition: &v3.HostedPartition{Name: "AzureChinaCloud"}}},
+	}}}
+	values, ignored, err := h.partitionValues(supported)
+	require.NoError(t, err)
+	assert.Empty(t, ignored)
+	assert.Equal(t, map[string]interface{}{
+		"cloudEndpoints": map[string]map[string]string{
+			"aws-us-gov": {"dnsSuffix": "amazonaws.com"},
+		},
+	}, values)
+
+	h.clusterCache.(*fakeClusterCache).clusters = append(h.clusterCache.(*fakeClusterCache).clusters,
+		eksCluster("c-5", "us-gov-west-1", &v3.HostedPartition{Endpoints: map[string]string{"dnsSuffix": "example.com"}}),
+		eksCluster("c-6", "us-west-2", &v3.HostedPartition{Name: "aws", Endpoints: map[string]string{"dnsSuffix": "example.com"}}))
+	values, ignored, err = h.partitionValues(supported)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"cloudEndpoints": map[string]map[string]string{
+			"aws-us-gov": {"dnsSuffix": "amazonaws.com"},
+		},
+	}, values, "invalid and conflicting partitions do not block the others")
+	assert.Len(t, ignored, 2)
+	assert.EqualError(t, ignored["c-5"], "cluster c-2 configures different endpoints for partition aws-us-gov")
+	assert.EqualError(t, ignored["c-6"], "invalid partition: endpoints of the public partition of eks can not be overridden")
+
+	values, ignored, err = h.partitionValues(eks)
+	require.NoError(t, err)
+	assert.Nil(t, values, "operator charts that do not read partitions get no values")
+	assert.EqualError(t, ignored["c-2"], "the eks operator chart does not support partition aws-us-gov")
+
+	h.clusterCache = &fakeClusterCache{clusters: []*v3.Cluster{eksCluster("c-1", "us-west-2", nil)}}
+	values, ignored, err = h.partitionValues(supported)
+	assert.NoError(t, err)
+	assert.Empty(t, ignored)
+	assert.Nil(t, values)
+}
@@ -0,0 +1,221 @@
+package hostedcluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	prePullJobNameFormat     = "%s-image-pre-pull"
+	prePullChartLabel        = "hosted.cattle.io/image-pre-pull"
+	prePullImagesAnnotation  = "hosted.cattle.io/image-pre-pull-hash"
+	prePullContainerNameBase = "image"
+)
+
+var (
+	// prePullRequeueInterval is how often the pre-pull job is checked while the images are pulled.
+	prePullRequeueInterval = 10 * time.Second
+
+	errImagesPulling = errors.New("waiting for the operator images to be pulled")
+
+	// imagePullFailureReasons are the reasons of waiting containers whose image can not be pulled.
+	imagePullFailureReasons = map[string]bool{
+		"ErrImagePull":        true,
+		"ImagePullBackOff":    true,
+		"InvalidImageName":    true,
+		"ErrImageNeverPull":   true,
+		"RegistryUnavailable": true,
+	}
+	// imagePendingReasons are the reasons of waiting containers whose image has not been pulled yet.
+	imagePendingReasons = map[string]bool{
+		"":                  true,
+		"ContainerCreating": true,
+		"PodInitializing":   true,
+	}
+)
+
+// prePullImages verifies that the images of the given version of the operator chart, rendered with the given values,
+// can be pulled before the chart is installed, if the hosted-operator-image-pre-pull setting is enabled. The images
+// are pulled by a job in the release namespace of the chart that is recreated whenever the images change. An error
+// wrapping errImagesPulling is returned while the images are pulled.
+func (h handler) prePullImages(def *chart.Definition, version string, values map[string]interface{}) error {
+	if settings.HostedOperatorImagePrePull.Get() != "true" {
+		return nil
+	}
+
+	defaults, err := h.manager.Values(def.ChartName, version)
+	if err != nil {
+		return fmt.Errorf("failed to get the values of %s: %w", def.ChartName, err)
+	}
+	images := chartImages(data.MergeMaps(defaults, values))
+	if len(images) == 0 {
+		return nil
+	}
+
+	name := fmt.Sprintf(prePullJobNameFormat, def.ChartName)
+	job, err := h.jobCache.Get(def.ReleaseNamespace, name)
+	if apierror.IsNotFound(err) {
+		if _, err := h.jobs.Create(prePullJob(def, name, images)); err != nil && !apierror.IsAlreadyExists(err) {
+			return err
+		}
+		return fmt.Errorf("%s: %w", name, errImagesPulling)
+	} else if err != nil {
+		return err
+	}
+
+	if job.DeletionTimestamp != nil {
+		return fmt.Errorf("%s: %w", name, errImagesPulling)
+	}
+	if job.Annotations[prePullImagesAnnotation] != imagesHash(images) {
+		propagation := metav1.DeletePropagationBackground
+		if err := h.jobs.Delete(job.Namespace, job.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !apierror.IsNotFound(err) {
+			return err
+		}
+		return fmt.Errorf("%s: %w", name, errImagesPulling)
+	}
+
+	sel, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	pods, err := h.podCache.List(job.Namespace, sel)
+	if err != nil {
+		return err
+	}
+	return prePullStatus(name, pods)
+}
+
+// prePullStatus returns nil if one of the pods of the pre-pull job pulled all of its images, an actionable error if an
+// image can not be pulled, and an error wrapping errImagesPulling otherwise.
+func prePullStatus(name string, pods []*corev1.Pod) error {
+	for _, pod := range pods {
+		images := map[string]string{}
+		for _, container := range pod.Spec.Containers {
+			images[container.Name] = container.Image
+		}
+
+		pulled := len(pod.Status.ContainerStatuses) == len(pod.Spec.Containers)
+		for _, status := range pod.Status.ContainerStatuses {
+			waiting := status.State.Waiting
+			if waiting == nil {
+				// the container is running or has terminated, so its image was pulled
+				continue
+			}
+			if imagePullFailureReasons[waiting.Reason] {
+				return fmt.Errorf("image %s can not be pulled, make sure that it is available in the configured registry: %s: %s",
+					images[status.Name], waiting.Reason, waiting.Message)
+			}
+			if imagePendingReasons[waiting.Reason] {
+				pulled = false
+			}
+		}
+		if pulled {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %w", name, errImagesPulling)
+}
+
+// prePullJob returns a job that pulls the images with one container for each of them. The containers do not need to run
+// successfully, the images only need to be pulled.
+func prePullJob(def *chart.Definition, name string, images []string) *batchv1.Job {
+	var containers []corev1.Container
+	for i, image := range images {
+		containers = append(containers, corev1.Container{
+			Name:            fmt.Sprintf("%s-%d", prePullContainerNameBase, i),
+			Image:           image,
+			ImagePullPolicy: corev1.PullAlways,
+			Command:         []string{"true"},
+		})
+	}
+
+	backoffLimit := int32(0)
+	automountToken := false
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: def.ReleaseNamespace,
+			Labels: map[string]string{
+				prePullChartLabel: def.ChartName,
+			},
+			Annotations: map[string]string{
+				prePullImagesAnnotation: imagesHash(images),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						prePullChartLabel: def.ChartName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: &automountToken,
+					NodeSelector: map[string]string{
+						corev1.LabelOSStable: "linux",
+					},
+					Containers: containers,
+				},
+			},
+		},
+	}
+}
+
+// chartImages returns the images referenced by chart values, which reference images by maps with a repository and a
+// tag. The repositories are prefixed with the system default registry of the values, like the Rancher charts do.
+func chartImages(values map[string]interface{}) []string {
+	registry := convert.ToString(data.GetValueN(values, "global", "cattle", "systemDefaultRegistry"))
+	seen := map[string]bool{}
+	var images []string
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			repository, _ := v["repository"].(string)
+			tag, _ := v["tag"].(string)
+			if repository != "" && tag != "" {
+				image := repository + ":" + tag
+				if registry != "" {
+					image = strings.TrimSuffix(registry, "/") + "/" + image
+				}
+				if !seen[image] {
+					seen[image] = true
+					images = append(images, image)
+				}
+				return
+			}
+			for _, value := range v {
+				walk(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	walk(values)
+	sort.Strings(images)
+	return images
+}
+
+// imagesHash returns a hash of the images, used to recreate the pre-pull job when the images change.
+func imagesHash(images []string) string {
+	hash := sha256.Sum256([]byte(strings.Join(images, ",")))
+	return hex.EncodeToString(hash[:])[:16]
+}
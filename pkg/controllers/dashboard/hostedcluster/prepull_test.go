@@ -0,0 +1,121 @@
+package hostedcluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_chartImages(t *testing.T) {
+	values := map[string]interface{}{
+		"global": map[string]interface{}{
+			"cattle": map[string]interface{}{
+				"systemDefaultRegistry": "registry.example.com",
+			},
+		},
+		"eksOperator": map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": "rancher/eks-operator",
+				"tag":        "v1.2.0",
+			},
+		},
+		"sidecars": []interface{}{
+			map[string]interface{}{
+				"image": map[string]interface{}{"repository": "rancher/kubectl", "tag": "v1.26.0"},
+			},
+			map[string]interface{}{
+				"image": map[string]interface{}{"repository": "rancher/eks-operator", "tag": "v1.2.0"},
+			},
+		},
+		"untagged": map[string]interface{}{
+			"repository": "rancher/untagged",
+		},
+	}
+	assert.Equal(t, []string{
+		"registry.example.com/rancher/eks-operator:v1.2.0",
+		"registry.example.com/rancher/kubectl:v1.26.0",
+	}, chartImages(values))
+
+	delete(values, "global")
+	assert.Equal(t, []string{
+		"rancher/eks-operator:v1.2.0",
+		"rancher/kubectl:v1.26.0",
+	}, chartImages(values))
+}
+
+func prePullPod(statuses ...corev1.ContainerStatus) *corev1.Pod {
+	job := prePullJob(&EksChart, "rancher-eks-operator-image-pre-pull", []string{"rancher/eks-operator:v1.2.0", "rancher/kubectl:v1.26.0"})
+	return &corev1.Pod{
+		Spec:   job.Spec.Template.Spec,
+		Status: corev1.PodStatus{ContainerStatuses: statuses},
+	}
+}
+
+func Test_prePullStatus(t *testing.T) {
+	terminated := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 128, Reason: "StartError"}}
+	creating := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+	backOff := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}
+
+	tests := []struct {
+		name    string
+		pods    []*corev1.Pod
+		pending bool
+		wantErr string
+	}{
+		{
+			name:    "no pods",
+			pending: true,
+		},
+		{
+			name:    "pulling",
+			pods:    []*corev1.Pod{prePullPod(corev1.ContainerStatus{Name: "image-0", State: terminated}, corev1.ContainerStatus{Name: "image-1", State: creating})},
+			pending: true,
+		},
+		{
+			name:    "no container statuses",
+			pods:    []*corev1.Pod{prePullPod()},
+			pending: true,
+		},
+		{
+			name: "pulled",
+			pods: []*corev1.Pod{prePullPod(
+				corev1.ContainerStatus{Name: "image-0", State: terminated},
+				corev1.ContainerStatus{Name: "image-1", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CreateContainerError"}}},
+			)},
+		},
+		{
+			name:    "pull failure",
+			pods:    []*corev1.Pod{prePullPod(corev1.ContainerStatus{Name: "image-0", State: terminated}, corev1.ContainerStatus{Name: "image-1", State: backOff})},
+			wantErr: "image rancher/kubectl:v1.26.0 can not be pulled, make sure that it is available in the configured registry: ImagePullBackOff: Back-off pulling image",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := prePullStatus("rancher-eks-operator-image-pre-pull", tt.pods)
+			switch {
+			case tt.pending:
+				assert.True(t, errors.Is(err, errImagesPulling), "unexpected error: %v", err)
+			case tt.wantErr != "":
+				assert.EqualError(t, err, tt.wantErr)
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_prePullJob(t *testing.T) {
+	images := []string{"rancher/eks-operator:v1.2.0"}
+	job := prePullJob(&EksChart, "rancher-eks-operator-image-pre-pull", images)
+	assert.Equal(t, EksChart.ReleaseNamespace, job.Namespace)
+	assert.Equal(t, imagesHash(images), job.Annotations[prePullImagesAnnotation])
+	assert.Equal(t, int32(0), *job.Spec.BackoffLimit)
+	assert.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+	if assert.Len(t, job.Spec.Template.Spec.Containers, 1) {
+		assert.Equal(t, "rancher/eks-operator:v1.2.0", job.Spec.Template.Spec.Containers[0].Image)
+		assert.Equal(t, corev1.PullAlways, job.Spec.Template.Spec.Containers[0].ImagePullPolicy)
+	}
+	assert.NotEqual(t, imagesHash(images), imagesHash(append(images, "rancher/kubectl:v1.26.0")))
+}
@@ -0,0 +1,109 @@
+package hostedcluster
+
+import (
+	"fmt"
+	"sync"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
+)
+
+// Provider is a hosted cluster provider whose operator charts are installed in the local cluster once a cluster of the
+// provider exists.
+type Provider interface {
+	// Name returns the short name of the provider, such as "aks". It must be unique among the registered providers.
+	Name() string
+	// Detect returns true if the cluster is provisioned by the provider.
+	Detect(cluster *v3.Cluster) bool
+	// Charts returns the CRD chart and the operator chart of the provider. The operator chart is expected to depend on the
+	// CRD chart, so that it is only installed once the CRD chart is.
+	Charts() (crdChart, operatorChart chart.Definition)
+	// Values returns values for the operator chart that are merged over the values common to all providers.
+	Values(cluster *v3.Cluster) (map[string]interface{}, error)
+}
+
+// PartitionValuesProvider is optionally implemented by providers whose operator chart can be configured with the
+// endpoints of cloud partitions. The partitions of clusters of other providers are ignored.
+type PartitionValuesProvider interface {
+	// PartitionValues returns the operator chart values that configure the endpoints of the partitions, which are keyed
+	// by partition name and service.
+	PartitionValues(partitions map[string]map[string]string) map[string]interface{}
+}
+
+var (
+	providersLock sync.RWMutex
+	providers     []Provider
+)
+
+func init() {
+	RegisterProvider(&operatorProvider{
+		name:          "aks",
+		crdChart:      AksCrdChart,
+		operatorChart: AksChart,
+		detect:        func(cluster *v3.Cluster) bool { return cluster.Spec.AKSConfig != nil },
+	})
+	RegisterProvider(&operatorProvider{
+		name:          "eks",
+		crdChart:      EksCrdChart,
+		operatorChart: EksChart,
+		detect:        func(cluster *v3.Cluster) bool { return cluster.Spec.EKSConfig != nil },
+	})
+	RegisterProvider(&operatorProvider{
+		name:          "gke",
+		crdChart:      GkeCrdChart,
+		operatorChart: GkeChart,
+		detect:        func(cluster *v3.Cluster) bool { return cluster.Spec.GKEConfig != nil },
+	})
+}
+
+// RegisterProvider registers a hosted cluster provider. Providers are consulted in the order they were registered, and
+// the first provider that detects a cluster is used for it. RegisterProvider panics if a provider with the same name is
+// already registered, and must be called before the controller is registered.
+func RegisterProvider(provider Provider) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	for _, p := range providers {
+		if p.Name() == provider.Name() {
+			panic(fmt.Sprintf("hosted cluster provider %s is already registered", provider.Name()))
+		}
+	}
+	providers = append(providers, provider)
+}
+
+// providerForCluster returns the registered provider that detects the cluster, or nil if there is none.
+func providerForCluster(cluster *v3.Cluster) Provider {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+
+	for _, p := range providers {
+		if p.Detect(cluster) {
+			return p
+		}
+	}
+	return nil
+}
+
+// operatorProvider is a provider for the operators shipped with Rancher, which need no provider specific values.
+type operatorProvider struct {
+	name          string
+	crdChart      chart.Definition
+	operatorChart chart.Definition
+	detect        func(cluster *v3.Cluster) bool
+}
+
+func (o *operatorProvider) Name() string {
+	return o.name
+}
+
+func (o *operatorProvider) Detect(cluster *v3.Cluster) bool {
+	return o.detect(cluster)
+}
+
+func (o *operatorProvider) Charts() (chart.Definition, chart.Definition) {
+	return o.crdChart, o.operatorChart
+}
+
+func (o *operatorProvider) Values(*v3.Cluster) (map[string]interface{}, error) {
+	return nil, nil
+}
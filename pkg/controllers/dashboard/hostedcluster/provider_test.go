@@ -0,0 +1,149 @@
+package hostedcluster
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	gkev1 "github.com/rancher/gke-operator/pkg/apis/gke.cattle.io/v1"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	testCrdChart = chart.Definition{
+		ReleaseNamespace: "cattle-system",
+		ChartName:        "rancher-test-operator-crd",
+	}
+	testChart = chart.Definition{
+		ReleaseNamespace: "cattle-system",
+		ChartName:        "rancher-test-operator",
+	}
+)
+
+type testProvider struct{}
+
+func (testProvider) Name() string {
+	return "test"
+}
+
+func (testProvider) Detect(cluster *v3.Cluster) bool {
+	return cluster.Labels["provider"] == "test"
+}
+
+func (testProvider) Charts() (chart.Definition, chart.Definition) {
+	return testCrdChart, testChart
+}
+
+func (testProvider) Values(cluster *v3.Cluster) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"clusterName": cluster.Name,
+		"global": map[string]interface{}{
+			"region": "test-region",
+		},
+	}, nil
+}
+
+// registerTestProvider registers the test provider and removes it again once the test has finished.
+func registerTestProvider(t *testing.T) {
+	providersLock.RLock()
+	original := providers
+	providersLock.RUnlock()
+
+	RegisterProvider(testProvider{})
+	t.Cleanup(func() {
+		providersLock.Lock()
+		providers = original
+		providersLock.Unlock()
+	})
+}
+
+func Test_providerForCluster(t *testing.T) {
+	registerTestProvider(t)
+
+	tests := []struct {
+		name     string
+		cluster  *v3.Cluster
+		expected string
+	}{
+		{
+			name:     "aks",
+			cluster:  &v3.Cluster{Spec: v3.ClusterSpec{AKSConfig: &aksv1.AKSClusterConfigSpec{}}},
+			expected: "aks",
+		},
+		{
+			name:     "eks",
+			cluster:  &v3.Cluster{Spec: v3.ClusterSpec{EKSConfig: &eksv1.EKSClusterConfigSpec{}}},
+			expected: "eks",
+		},
+		{
+			name:     "gke",
+			cluster:  &v3.Cluster{Spec: v3.ClusterSpec{GKEConfig: &gkev1.GKEClusterConfigSpec{}}},
+			expected: "gke",
+		},
+		{
+			name:     "registered provider",
+			cluster:  &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"provider": "test"}}},
+			expected: "test",
+		},
+		{
+			name:    "not hosted",
+			cluster: &v3.Cluster{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := providerForCluster(tt.cluster)
+			if tt.expected == "" {
+				assert.Nil(t, provider)
+				return
+			}
+			assert.Equal(t, tt.expected, provider.Name())
+		})
+	}
+}
+
+func TestRegisterProviderDuplicate(t *testing.T) {
+	registerTestProvider(t)
+	assert.Panics(t, func() { RegisterProvider(testProvider{}) })
+}
+
+func Test_isOperatorChartRelease(t *testing.T) {
+	registerTestProvider(t)
+
+	assert.True(t, isOperatorChartRelease(AksCrdChart.ChartName))
+	assert.True(t, isOperatorChartRelease(GkeChart.ChartName))
+	assert.True(t, isOperatorChartRelease(testChart.ChartName))
+	assert.False(t, isOperatorChartRelease("rancher-webhook"))
+}
+
+func Test_handler_onClusterChangeProviderValues(t *testing.T) {
+	registerTestProvider(t)
+
+	ctrl := gomock.NewController(t)
+	h := newHandler(ctrl)
+	manager := fake.NewMockManager(ctrl)
+	manager.EXPECT().Ensure(testCrdChart.ReleaseNamespace, testCrdChart.ChartName, "", nil, true, "").Return(nil)
+	manager.EXPECT().Ensure(testChart.ReleaseNamespace, testChart.ChartName, "", gomock.Any(), true, "").DoAndReturn(
+		func(namespace, name, minVersion string, values map[string]interface{}, forceAdopt bool, installImageOverride string) error {
+			assert.Equal(t, "c-test", values["clusterName"])
+			global := values["global"].(map[string]interface{})
+			assert.Equal(t, "test-region", global["region"])
+			assert.Contains(t, global, "cattle", "common values must be kept when merging provider values")
+			return nil
+		})
+	h.manager = manager
+
+	_, err := h.onClusterChange("", &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "c-test",
+			Labels: map[string]string{"provider": "test"},
+		},
+	})
+	assert.NoError(t, err)
+}
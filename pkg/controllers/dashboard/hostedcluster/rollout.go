@@ -0,0 +1,268 @@
+package hostedcluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// rolloutConfigMapName is the name of the config map in the system namespace that records the state of the rollout
+	// of every operator chart, keyed by chart name.
+	rolloutConfigMapName = "hosted-operator-rollout"
+
+	defaultVerificationPeriod = 15 * time.Minute
+)
+
+// operatorRollout is the state of the rollout of an operator chart. The chart is shared by all clusters of its
+// provider, so a new version is installed for all of them at once, and the verification clusters of the provider are
+// only used to decide whether the version is accepted. A version is never downgraded, since the operator may already
+// have migrated the clusters it manages.
+type operatorRollout struct {
+	// AcceptedVersion is the last version of the chart that kept the verification clusters healthy.
+	AcceptedVersion string `json:"acceptedVersion,omitempty"`
+	// InstalledVersion is the version of the chart installed by the rollout.
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	// CandidateVersion is the installed version of the chart that is being verified against the verification clusters.
+	CandidateVersion string `json:"candidateVersion,omitempty"`
+	// CandidateSince is when the candidate version was installed.
+	CandidateSince *metav1.Time `json:"candidateSince,omitempty"`
+	// FailedVersion is the last candidate version that left verification clusters unhealthy. It stays installed, but
+	// is not accepted, and newer versions are still installed once available.
+	FailedVersion string `json:"failedVersion,omitempty"`
+	// Message describes why the last candidate version failed.
+	Message string `json:"message,omitempty"`
+}
+
+// ensureOperator installs the operator chart of the provider. If verification clusters are configured for the
+// provider, new versions of the chart are installed one at a time, and only accepted once the verification clusters
+// stayed healthy for the verification period. Otherwise, the latest version is installed.
+func (h handler) ensureOperator(cluster *v3.Cluster, provider Provider, def *chart.Definition, values map[string]interface{}) error {
+	verifying, err := h.verificationClusters(provider)
+	if err != nil {
+		return err
+	}
+	if len(verifying) == 0 {
+		return h.ensureIfChanged(def, "", values, func() error {
+			if err := h.prePullImages(def, "", values); err != nil {
+				return err
+			}
+			return h.ensure(def, values)
+		})
+	}
+
+	latest, err := h.manager.LatestVersion(def.ChartName)
+	if err != nil {
+		return err
+	}
+
+	rollouts, err := h.getRollouts()
+	if err != nil {
+		return err
+	}
+
+	state := rollouts[def.ChartName]
+	var installed string
+	if state.InstalledVersion == "" {
+		if installed, err = h.manager.InstalledVersion(def.ReleaseNamespace, def.ChartName); err != nil {
+			return err
+		}
+	}
+
+	next, version, requeue := planRollout(state, installed, latest, unhealthyClusters(verifying), time.Now(), verificationPeriod())
+	if next != state {
+		logRollout(def.ChartName, state, next)
+		if err := h.saveRollout(def.ChartName, next); err != nil {
+			return err
+		}
+	}
+	if requeue > 0 {
+		h.clusters.EnqueueAfter(cluster.Name, requeue)
+	}
+
+	return h.ensureIfChanged(def, version, values, func() error {
+		if err := h.prePullImages(def, version, values); err != nil {
+			return err
+		}
+		return retry.OnError(ensureBackoff, func(error) bool { return true }, func() error {
+			return h.manager.EnsureVersion(def.ReleaseNamespace, def.ChartName, version, values, true, "")
+		})
+	})
+}
+
+// planRollout returns the next state of the rollout of an operator chart, the version of the chart to install, and
+// when the rollout must be checked again. installed is the version of the deployed chart, which is only used to start
+// the rollout, so that configuring verification clusters neither upgrades nor downgrades the chart right away.
+func planRollout(state operatorRollout, installed, latest string, unhealthy []string, now time.Time, period time.Duration) (operatorRollout, string, time.Duration) {
+	if state.InstalledVersion == "" {
+		if installed == "" {
+			installed = latest
+		}
+		state.InstalledVersion = installed
+		state.AcceptedVersion = installed
+		return state, installed, 0
+	}
+
+	if state.CandidateVersion != "" && newerVersion(latest, state.CandidateVersion) {
+		// A newer version became available while the candidate was verified, it is verified instead.
+		state.CandidateVersion = ""
+		state.CandidateSince = nil
+	}
+
+	if state.CandidateVersion == "" {
+		if latest == state.FailedVersion || !newerVersion(latest, state.InstalledVersion) {
+			return state, state.InstalledVersion, 0
+		}
+		state.InstalledVersion = latest
+		state.CandidateVersion = latest
+		state.CandidateSince = &metav1.Time{Time: now}
+		return state, latest, period
+	}
+
+	if remaining := state.CandidateSince.Add(period).Sub(now); remaining > 0 {
+		return state, state.InstalledVersion, remaining
+	}
+
+	if len(unhealthy) > 0 {
+		state.FailedVersion = state.CandidateVersion
+		state.Message = fmt.Sprintf("clusters [%s] were unhealthy after upgrading to %s", strings.Join(unhealthy, ", "), state.CandidateVersion)
+	} else {
+		state.AcceptedVersion = state.CandidateVersion
+	}
+	state.CandidateVersion = ""
+	state.CandidateSince = nil
+	return state, state.InstalledVersion, 0
+}
+
+// newerVersion returns whether version a is newer than version b. Versions that are not semantic versions are only
+// compared for equality.
+func newerVersion(a, b string) bool {
+	av, err := semver.NewVersion(a)
+	if err != nil {
+		return a != b
+	}
+	bv, err := semver.NewVersion(b)
+	if err != nil {
+		return a != b
+	}
+	return av.GreaterThan(bv)
+}
+
+func logRollout(chartName string, previous, next operatorRollout) {
+	switch {
+	case next.CandidateVersion != "" && next.CandidateVersion != previous.CandidateVersion:
+		logrus.Infof("[hostedcluster] upgrading %s to %s, verifying clusters", chartName, next.CandidateVersion)
+	case next.FailedVersion != previous.FailedVersion:
+		logrus.Warnf("[hostedcluster] version %s of %s was not accepted: %s", next.FailedVersion, chartName, next.Message)
+	case next.AcceptedVersion != previous.AcceptedVersion:
+		logrus.Infof("[hostedcluster] accepted version %s of %s", next.AcceptedVersion, chartName)
+	}
+}
+
+// verificationClusters returns the clusters of the hosted-operator-canary-clusters setting that are provisioned by the
+// provider.
+func (h handler) verificationClusters(provider Provider) ([]*v3.Cluster, error) {
+	var result []*v3.Cluster
+	for _, name := range strings.Split(settings.HostedOperatorCanaryClusters.Get(), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		cluster, err := h.clusterCache.Get(name)
+		if apierror.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if p := providerForCluster(cluster); p != nil && p.Name() == provider.Name() {
+			result = append(result, cluster)
+		}
+	}
+	return result, nil
+}
+
+// unhealthyClusters returns the names of the clusters that failed to be provisioned or updated by the operator.
+func unhealthyClusters(clusters []*v3.Cluster) []string {
+	var result []string
+	for _, cluster := range clusters {
+		if v3.ClusterConditionProvisioned.IsFalse(cluster) || v3.ClusterConditionUpdated.IsFalse(cluster) {
+			result = append(result, cluster.Name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// verificationPeriod returns the hosted-operator-canary-period setting, or the default if it is not a valid positive
+// duration.
+func verificationPeriod() time.Duration {
+	period, err := time.ParseDuration(settings.HostedOperatorCanaryPeriod.Get())
+	if err != nil || period <= 0 {
+		logrus.Warnf("[hostedcluster] %s setting must be a positive duration, using default: %s", settings.HostedOperatorCanaryPeriod.Name, defaultVerificationPeriod)
+		return defaultVerificationPeriod
+	}
+	return period
+}
+
+func (h handler) getRollouts() (map[string]operatorRollout, error) {
+	configMap, err := h.configMapCache.Get(namespace.System, rolloutConfigMapName)
+	if apierror.IsNotFound(err) {
+		return map[string]operatorRollout{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	result := map[string]operatorRollout{}
+	for chartName, value := range configMap.Data {
+		var state operatorRollout
+		if err := json.Unmarshal([]byte(value), &state); err != nil {
+			return nil, fmt.Errorf("invalid rollout state of %s in config map %s/%s: %w", chartName, namespace.System, rolloutConfigMapName, err)
+		}
+		result[chartName] = state
+	}
+	return result, nil
+}
+
+func (h handler) saveRollout(chartName string, state operatorRollout) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := h.configMaps.Get(namespace.System, rolloutConfigMapName, metav1.GetOptions{})
+		if apierror.IsNotFound(err) {
+			_, err = h.configMaps.Create(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      rolloutConfigMapName,
+					Namespace: namespace.System,
+				},
+				Data: map[string]string{chartName: string(value)},
+			})
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		configMap = configMap.DeepCopy()
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[chartName] = string(value)
+		_, err = h.configMaps.Update(configMap)
+		return err
+	})
+}
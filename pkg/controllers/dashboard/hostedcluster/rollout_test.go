@@ -0,0 +1,125 @@
+package hostedcluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_planRollout(t *testing.T) {
+	now := time.Now()
+	period := 15 * time.Minute
+	since := func(d time.Duration) *metav1.Time {
+		return &metav1.Time{Time: now.Add(-d)}
+	}
+
+	tests := []struct {
+		name            string
+		state           operatorRollout
+		installed       string
+		latest          string
+		unhealthy       []string
+		expectedState   operatorRollout
+		expectedVersion string
+		expectedRequeue time.Duration
+	}{
+		{
+			name:            "installed version is accepted first",
+			installed:       "1.0.0",
+			latest:          "1.1.0",
+			expectedState:   operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.0.0"},
+			expectedVersion: "1.0.0",
+		},
+		{
+			name:            "latest version is accepted first if none is installed",
+			latest:          "1.1.0",
+			expectedState:   operatorRollout{AcceptedVersion: "1.1.0", InstalledVersion: "1.1.0"},
+			expectedVersion: "1.1.0",
+		},
+		{
+			name:            "installed version is kept",
+			state:           operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.0.0"},
+			latest:          "1.0.0",
+			expectedState:   operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.0.0"},
+			expectedVersion: "1.0.0",
+		},
+		{
+			name:            "older version is not installed",
+			state:           operatorRollout{AcceptedVersion: "1.1.0", InstalledVersion: "1.1.0"},
+			latest:          "1.0.0",
+			expectedState:   operatorRollout{AcceptedVersion: "1.1.0", InstalledVersion: "1.1.0"},
+			expectedVersion: "1.1.0",
+		},
+		{
+			name:            "new version becomes the candidate",
+			state:           operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.0.0"},
+			latest:          "1.1.0",
+			expectedState:   operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.1.0", CandidateVersion: "1.1.0", CandidateSince: &metav1.Time{Time: now}},
+			expectedVersion: "1.1.0",
+			expectedRequeue: period,
+		},
+		{
+			name:            "candidate is verified until the period passed",
+			state:           operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.1.0", CandidateVersion: "1.1.0", CandidateSince: since(5 * time.Minute)},
+			latest:          "1.1.0",
+			unhealthy:       []string{"c-verify"},
+			expectedState:   operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.1.0", CandidateVersion: "1.1.0", CandidateSince: since(5 * time.Minute)},
+			expectedVersion: "1.1.0",
+			expectedRequeue: 10 * time.Minute,
+		},
+		{
+			name:            "candidate is accepted if the clusters are healthy",
+			state:           operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.1.0", CandidateVersion: "1.1.0", CandidateSince: since(period)},
+			latest:          "1.1.0",
+			expectedState:   operatorRollout{AcceptedVersion: "1.1.0", InstalledVersion: "1.1.0"},
+			expectedVersion: "1.1.0",
+		},
+		{
+			name:      "candidate fails without a rollback if a cluster is unhealthy",
+			state:     operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.1.0", CandidateVersion: "1.1.0", CandidateSince: since(period)},
+			latest:    "1.1.0",
+			unhealthy: []string{"c-a", "c-b"},
+			expectedState: operatorRollout{
+				AcceptedVersion:  "1.0.0",
+				InstalledVersion: "1.1.0",
+				FailedVersion:    "1.1.0",
+				Message:          "clusters [c-a, c-b] were unhealthy after upgrading to 1.1.0",
+			},
+			expectedVersion: "1.1.0",
+		},
+		{
+			name:            "failed version is not verified again",
+			state:           operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.1.0", FailedVersion: "1.1.0"},
+			latest:          "1.1.0",
+			expectedState:   operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.1.0", FailedVersion: "1.1.0"},
+			expectedVersion: "1.1.0",
+		},
+		{
+			name:            "newer version replaces the candidate",
+			state:           operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.1.0", CandidateVersion: "1.1.0", CandidateSince: since(5 * time.Minute)},
+			latest:          "1.2.0",
+			expectedState:   operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.2.0", CandidateVersion: "1.2.0", CandidateSince: &metav1.Time{Time: now}},
+			expectedVersion: "1.2.0",
+			expectedRequeue: period,
+		},
+		{
+			name:            "candidate is kept if the latest version is older",
+			state:           operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.1.0", CandidateVersion: "1.1.0", CandidateSince: since(5 * time.Minute)},
+			latest:          "1.0.0",
+			expectedState:   operatorRollout{AcceptedVersion: "1.0.0", InstalledVersion: "1.1.0", CandidateVersion: "1.1.0", CandidateSince: since(5 * time.Minute)},
+			expectedVersion: "1.1.0",
+			expectedRequeue: 10 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, version, requeue := planRollout(tt.state, tt.installed, tt.latest, tt.unhealthy, now, period)
+			assert.Equal(t, tt.expectedState, state)
+			assert.Equal(t, tt.expectedVersion, version)
+			assert.Equal(t, tt.expectedRequeue, requeue)
+		})
+	}
+}
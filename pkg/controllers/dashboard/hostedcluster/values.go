@@ -0,0 +1,28 @@
+package hostedcluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rancher/rancher/pkg/settings"
+)
+
+// settingChartValues returns the operator chart values of the provider from the hosted-operator-chart-values setting.
+// The operator chart is shared by all clusters of the provider, so the values are the same for all of them.
+func settingChartValues(provider Provider) (map[string]interface{}, error) {
+	value := strings.TrimSpace(settings.HostedOperatorChartValues.Get())
+	if value == "" {
+		return nil, nil
+	}
+
+	var providerValues map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &providerValues); err != nil {
+		return nil, fmt.Errorf("invalid chart values: %w", err)
+	}
+	values := providerValues[provider.Name()]
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return values, nil
+}
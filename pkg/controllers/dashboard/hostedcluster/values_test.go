@@ -0,0 +1,96 @@
+package hostedcluster
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/chart/fake"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func setChartValues(t *testing.T, value string) {
+	original := settings.HostedOperatorChartValues.Get()
+	require.NoError(t, settings.HostedOperatorChartValues.Set(value))
+	t.Cleanup(func() { _ = settings.HostedOperatorChartValues.Set(original) })
+}
+
+func Test_settingChartValues(t *testing.T) {
+	eks := providerForCluster(&v3.Cluster{Spec: v3.ClusterSpec{EKSConfig: &eksv1.EKSClusterConfigSpec{}}})
+	require.NotNil(t, eks)
+
+	tests := []struct {
+		name     string
+		setting  string
+		expected map[string]interface{}
+		wantErr  bool
+	}{
+		{
+			name: "unset",
+		},
+		{
+			name:    "other provider",
+			setting: `{"aks": {"endpoint": "https://management.example.com"}}`,
+		},
+		{
+			name:    "values of the provider",
+			setting: `{"eks": {"endpoints": {"sts": "https://sts.amazonaws.eu", "list": ["a", 1]}, "replicas": 2}}`,
+			expected: map[string]interface{}{
+				"endpoints": map[string]interface{}{
+					"sts":  "https://sts.amazonaws.eu",
+					"list": []interface{}{"a", float64(1)},
+				},
+				"replicas": float64(2),
+			},
+		},
+		{
+			name:    "invalid json",
+			setting: `{"eks": []}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setChartValues(t, tt.setting)
+			values, err := settingChartValues(eks)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, values)
+		})
+	}
+}
+
+func Test_handler_onClusterChangeSettingValues(t *testing.T) {
+	registerTestProvider(t)
+	setChartValues(t, `{"test": {"clusterName": "override", "global": {"cluster": "shared"}}}`)
+
+	ctrl := gomock.NewController(t)
+	h := newHandler(ctrl)
+	manager := fake.NewMockManager(ctrl)
+	manager.EXPECT().Ensure(testCrdChart.ReleaseNamespace, testCrdChart.ChartName, "", nil, true, "").Return(nil)
+	manager.EXPECT().Ensure(testChart.ReleaseNamespace, testChart.ChartName, "", gomock.Any(), true, "").DoAndReturn(
+		func(namespace, name, minVersion string, values map[string]interface{}, forceAdopt bool, installImageOverride string) error {
+			assert.Equal(t, "override", values["clusterName"], "setting values are merged over provider values")
+			global := values["global"].(map[string]interface{})
+			assert.Equal(t, "shared", global["cluster"])
+			assert.Equal(t, "test-region", global["region"])
+			return nil
+		})
+	h.manager = manager
+
+	_, err := h.onClusterChange("", &v3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "c-test",
+			Labels: map[string]string{"provider": "test"},
+		},
+		Spec: v3.ClusterSpec{DisplayName: "Test"},
+	})
+	assert.NoError(t, err)
+}
@@ -2,10 +2,12 @@
 
 import (
 	"context"
+	goerrors "errors"
 
 	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/chart"
 	"github.com/rancher/rancher/pkg/features"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
 	namespace "github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/wrangler"
@@ -27,6 +29,7 @@ func Register(ctx context.Context, wContext *wrangler.Context, registryOverride
 	h := &handler{
 		manager:          wContext.SystemChartsManager,
 		namespaces:       wContext.Core.Namespace(),
+		repos:            wContext.Catalog.ClusterRepo(),
 		chartsConfig:     chart.RancherConfigGetter{ConfigCache: wContext.Core.ConfigMap().Cache()},
 		registryOverride: registryOverride,
 	}
@@ -43,6 +46,7 @@ func Register(ctx context.Context, wContext *wrangler.Context, registryOverride
 type handler struct {
 	manager          chart.Manager
 	namespaces       corecontrollers.NamespaceController
+	repos            catalogcontrollers.ClusterRepoController
 	chartsConfig     chart.RancherConfigGetter
 	registryOverride string
 }
@@ -64,21 +68,17 @@ func (h *handler) onRepo(key string, repo *catalog.ClusterRepo) (*catalog.Cluste
 		registryMap["systemDefaultRegistry"] = ""
 		systemGlobalRegistry["cattle"] = registryMap
 	}
-	for _, chartDef := range h.getChartsToInstall() {
-		if chartDef.Enabled != nil && !chartDef.Enabled() {
-			continue
-		}
-
+	err := chart.EnsureInOrder(h.manager, h.getChartsToInstall(), func(chartDef *chart.Definition) error {
 		if chartDef.Uninstall {
 			if err := h.manager.Uninstall(chartDef.ReleaseNamespace, chartDef.ChartName); err != nil {
-				return repo, err
+				return err
 			}
 			if chartDef.RemoveNamespace {
 				if err := h.namespaces.Delete(chartDef.ReleaseNamespace, nil); err != nil && !errors.IsNotFound(err) {
-					return repo, err
+					return err
 				}
 			}
-			continue
+			return nil
 		}
 
 		values := map[string]interface{}{
@@ -101,12 +101,14 @@ func (h *handler) onRepo(key string, repo *catalog.ClusterRepo) (*catalog.Cluste
 		}
 		// webhook needs to be able to adopt the MutatingWebhookConfiguration which originally wasn't a part of the
 		// chart definition, but is now part of the chart definition
-		if err := h.manager.Ensure(chartDef.ReleaseNamespace, chartDef.ChartName, chartDef.MinVersionSetting.Get(), values, chartDef.ChartName == webhookChartName, installImageOverride); err != nil {
-			return repo, err
-		}
+		return h.manager.Ensure(chartDef.ReleaseNamespace, chartDef.ChartName, chartDef.MinVersionSetting.Get(), values, chartDef.ChartName == webhookChartName, installImageOverride)
+	})
+	if goerrors.Is(err, chart.ErrDependencyNotInstalled) {
+		logrus.Debugf("[systemcharts] %v", err)
+		h.repos.EnqueueAfter(repo.Name, chart.DependencyRequeueInterval)
+		return repo, nil
 	}
-
-	return repo, nil
+	return repo, err
 }
 
 func (h *handler) getChartsToInstall() []*chart.Definition {
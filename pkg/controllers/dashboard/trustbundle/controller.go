@@ -0,0 +1,92 @@
+package trustbundle
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/trustbundle"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type handler struct {
+	secrets        corecontrollers.SecretClient
+	secretCache    corecontrollers.SecretCache
+	configMapCache corecontrollers.ConfigMapCache
+}
+
+// Register registers the controller that writes the trust bundle into the tls-ca-additional secret, which the hosted
+// cluster operator charts mount, so that the operators trust the certificates of the labeled secrets and config maps
+// too. A tls-ca-additional secret that was not created by Rancher is left as is.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		secrets:        clients.Core.Secret(),
+		secretCache:    clients.Core.Secret().Cache(),
+		configMapCache: clients.Core.ConfigMap().Cache(),
+	}
+
+	clients.Core.Secret().OnChange(ctx, "trust-bundle", h.OnChange)
+	relatedresource.Watch(ctx, "trust-bundle-trigger", func(ns, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		if ns != namespace.System {
+			return nil, nil
+		}
+		// the labels of deleted objects are not known, so any deletion in the namespace triggers a sync
+		if obj != nil {
+			objMeta, err := meta.Accessor(obj)
+			if err != nil || !trustbundle.Source(ns, name, objMeta.GetLabels()) {
+				return nil, nil
+			}
+		}
+		return []relatedresource.Key{{Namespace: namespace.System, Name: trustbundle.LegacySecretName}}, nil
+	}, clients.Core.Secret(), clients.Core.Secret(), clients.Core.ConfigMap())
+}
+
+func (h *handler) OnChange(key string, secret *corev1.Secret) (*corev1.Secret, error) {
+	if key != namespace.System+"/"+trustbundle.LegacySecretName {
+		return secret, nil
+	}
+	if secret != nil && !trustbundle.Managed(secret) {
+		return secret, nil
+	}
+
+	bundle, err := trustbundle.Get(h.secretCache, h.configMapCache)
+	if err != nil {
+		return secret, err
+	}
+
+	if len(bundle) == 0 {
+		if secret == nil {
+			return nil, nil
+		}
+		logrus.Infof("[trustbundle] removing %s/%s as no secrets or config maps are labeled %s", namespace.System, trustbundle.LegacySecretName, trustbundle.Label)
+		if err := h.secrets.Delete(namespace.System, trustbundle.LegacySecretName, nil); err != nil && !apierrors.IsNotFound(err) {
+			return secret, err
+		}
+		return nil, nil
+	}
+
+	if secret == nil {
+		return h.secrets.Create(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        trustbundle.LegacySecretName,
+				Namespace:   namespace.System,
+				Annotations: map[string]string{trustbundle.ManagedAnnotation: "true"},
+			},
+			Data: map[string][]byte{trustbundle.LegacySecretKey: bundle},
+		})
+	}
+	if bytes.Equal(secret.Data[trustbundle.LegacySecretKey], bundle) {
+		return secret, nil
+	}
+	secret = secret.DeepCopy()
+	secret.Data = map[string][]byte{trustbundle.LegacySecretKey: bundle}
+	return h.secrets.Update(secret)
+}
@@ -55,20 +55,22 @@ func Register(ctx context.Context, wContext *wrangler.Context, mgmtCtx *config.M
 	aksCCDynamicClient := mgmtCtx.DynamicClient.Resource(aksClusterConfigResource)
 	e := &aksOperatorController{
 		OperatorController: clusteroperator.OperatorController{
-			ClusterEnqueueAfter:  wContext.Mgmt.Cluster().EnqueueAfter,
-			SecretsCache:         wContext.Core.Secret().Cache(),
-			Secrets:              mgmtCtx.Core.Secrets(""),
-			TemplateCache:        wContext.Mgmt.CatalogTemplate().Cache(),
-			ProjectCache:         wContext.Mgmt.Project().Cache(),
-			AppLister:            mgmtCtx.Project.Apps("").Controller().Lister(),
-			AppClient:            mgmtCtx.Project.Apps(""),
-			NsClient:             mgmtCtx.Core.Namespaces(""),
-			ClusterClient:        wContext.Mgmt.Cluster(),
-			CatalogManager:       mgmtCtx.CatalogManager,
-			SystemAccountManager: systemaccount.NewManager(mgmtCtx),
-			DynamicClient:        aksCCDynamicClient,
-			ClientDialer:         mgmtCtx.Dialer,
-			Discovery:            wContext.K8s.Discovery(),
+			ClusterEnqueueAfter:      wContext.Mgmt.Cluster().EnqueueAfter,
+			SecretsCache:             wContext.Core.Secret().Cache(),
+			ConfigMapCache:           wContext.Core.ConfigMap().Cache(),
+			Secrets:                  mgmtCtx.Core.Secrets(""),
+			TemplateCache:            wContext.Mgmt.CatalogTemplate().Cache(),
+			ProjectCache:             wContext.Mgmt.Project().Cache(),
+			AppLister:                mgmtCtx.Project.Apps("").Controller().Lister(),
+			AppClient:                mgmtCtx.Project.Apps(""),
+			NsClient:                 mgmtCtx.Core.Namespaces(""),
+			ClusterClient:            wContext.Mgmt.Cluster(),
+			CatalogManager:           mgmtCtx.CatalogManager,
+			SystemAccountManager:     systemaccount.NewManager(mgmtCtx),
+			DynamicClient:            aksCCDynamicClient,
+			ClientDialer:             mgmtCtx.Dialer,
+			Discovery:                wContext.K8s.Discovery(),
+			HostedClusterPolicyCache: wContext.Mgmt.HostedClusterPolicy().Cache(),
 		},
 		secretClient: wContext.Core.Secret(),
 	}
@@ -106,6 +108,18 @@ func (e *aksOperatorController) onClusterChange(_ string, cluster *apimgmtv3.Clu
 			return cluster, err
 		}
 
+		if !cluster.Spec.AKSConfig.Imported {
+			var proceed bool
+			cluster, proceed, err = e.HostedPolicyPreflight(cluster, nil)
+			if err != nil || !proceed {
+				return cluster, err
+			}
+			cluster, proceed, err = e.QuotaPreflight(cluster, clusteroperator.AKSNodePoolDemand(cluster.Spec.AKSConfig, nil), e.quotaChecker(cluster.Spec.AKSConfig))
+			if err != nil || !proceed {
+				return cluster, err
+			}
+		}
+
 		aksClusterConfigDynamic, err = buildAKSCCCreateObject(cluster)
 		if err != nil {
 			return cluster, err
@@ -117,13 +131,27 @@ func (e *aksOperatorController) onClusterChange(_ string, cluster *apimgmtv3.Clu
 		}
 	}
 
-	aksClusterConfigMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&cluster.Spec.AKSConfig)
+	aksConfig, err := clusteroperator.AKSConfigWithAutoscaling(cluster.Spec.AKSConfig, cluster.Spec.HostedNodePoolAutoscaling)
+	if err != nil {
+		return cluster, err
+	}
+
+	aksClusterConfigMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(aksConfig)
 	if err != nil {
 		return cluster, err
 	}
 
 	// check for changes between aks spec on cluster and the aks spec on the aksClusterConfig object
 	if !reflect.DeepEqual(aksClusterConfigMap, aksClusterConfigDynamic.Object["spec"]) {
+		var proceed bool
+		cluster, proceed, err = e.HostedPolicyPreflight(cluster, nil)
+		if err != nil || !proceed {
+			return cluster, err
+		}
+		cluster, proceed, err = e.QuotaPreflight(cluster, clusteroperator.AKSNodePoolDemand(aksConfig, cluster.Status.AKSStatus.UpstreamSpec), e.quotaChecker(aksConfig))
+		if err != nil || !proceed {
+			return cluster, err
+		}
 		logrus.Infof("change detected for cluster [%s], updating AKSClusterConfig", cluster.Name)
 		return e.updateAKSClusterConfig(cluster, aksClusterConfigDynamic, aksClusterConfigMap)
 	}
@@ -221,6 +249,10 @@ func (e *aksOperatorController) onClusterChange(_ string, cluster *apimgmtv3.Clu
 			}
 		}
 
+		if clusteroperator.ServiceAccountTokenRefreshRequested(cluster) {
+			return e.RefreshServiceAccountToken(cluster, e.rotateServiceAccountToken)
+		}
+
 		cluster, err = e.recordAppliedSpec(cluster)
 		if err != nil {
 			return cluster, err
@@ -349,9 +381,32 @@ func (e *aksOperatorController) generateAndSetServiceAccount(cluster *apimgmtv3.
 	return e.ClusterClient.Update(cluster)
 }
 
+// rotateServiceAccountToken uses the API endpoint and CA cert to revoke the service account token of the cluster and
+// generate a new one.
+func (e *aksOperatorController) rotateServiceAccountToken(cluster *apimgmtv3.Cluster) (string, error) {
+	restConfig, err := e.getRestConfig(cluster)
+	if err != nil {
+		return "", fmt.Errorf("error getting kube config: %v", err)
+	}
+
+	clusterDialer, err := e.ClientDialer.ClusterDialer(cluster.Name)
+	if err != nil {
+		return "", err
+	}
+
+	restConfig.Dial = clusterDialer
+	return clusteroperator.RotateSAToken(restConfig)
+}
+
 // buildAKSCCCreateObject returns an object that can be used with the kubernetes dynamic client to
-// create an AKSClusterConfig that matches the spec contained in the cluster's AKSConfig.
+// create an AKSClusterConfig that matches the spec contained in the cluster's AKSConfig, with the
+// node pool autoscaling of the cluster applied.
 func buildAKSCCCreateObject(cluster *apimgmtv3.Cluster) (*unstructured.Unstructured, error) {
+	aksConfig, err := clusteroperator.AKSConfigWithAutoscaling(cluster.Spec.AKSConfig, cluster.Spec.HostedNodePoolAutoscaling)
+	if err != nil {
+		return nil, err
+	}
+
 	aksClusterConfig := aksv1.AKSClusterConfig{
 		TypeMeta: v1.TypeMeta{
 			Kind:       "AKSClusterConfig",
@@ -368,7 +423,7 @@ func buildAKSCCCreateObject(cluster *apimgmtv3.Cluster) (*unstructured.Unstructu
 				},
 			},
 		},
-		Spec: *cluster.Spec.AKSConfig,
+		Spec: *aksConfig,
 	}
 
 	// convert AKS cluster config into unstructured object so it can be used with dynamic client
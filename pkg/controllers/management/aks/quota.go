@@ -0,0 +1,94 @@
+package aks
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2020-06-30/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/rancher/aks-operator/pkg/aks"
+	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/controllers/management/clusteroperator"
+)
+
+// totalRegionalVCPUsQuota is the name of the compute usage of all vCPUs of a subscription in a location.
+const totalRegionalVCPUsQuota = "cores"
+
+// quotaChecker returns a checker of the regional vCPU quotas, in total and per VM family, of the subscription of the AKS
+// config.
+func (e *aksOperatorController) quotaChecker(config *aksv1.AKSClusterConfigSpec) clusteroperator.QuotaChecker {
+	return func(ctx context.Context, demand []clusteroperator.NodePoolDemand) ([]clusteroperator.Quota, error) {
+		cred, err := aks.GetSecrets(e.SecretsCache, e.secretClient, config)
+		if err != nil {
+			return nil, err
+		}
+		authorizer, err := aks.NewClientAuthorizer(cred)
+		if err != nil {
+			return nil, err
+		}
+
+		skusClient := compute.NewResourceSkusClientWithBaseURI(to.String(cred.BaseURL), cred.SubscriptionID)
+		skusClient.Authorizer = authorizer
+		skus, err := skusClient.ListComplete(ctx, fmt.Sprintf("location eq '%s'", config.ResourceLocation))
+		if err != nil {
+			return nil, fmt.Errorf("error listing VM sizes: %w", err)
+		}
+		families := map[string]string{}
+		vcpus := map[string]float64{}
+		for ; skus.NotDone(); err = skus.NextWithContext(ctx) {
+			if err != nil {
+				return nil, fmt.Errorf("error listing VM sizes: %w", err)
+			}
+			sku := skus.Value()
+			if to.String(sku.ResourceType) != "virtualMachines" || sku.Capabilities == nil {
+				continue
+			}
+			for _, capability := range *sku.Capabilities {
+				if to.String(capability.Name) == "vCPUs" {
+					vcpus[to.String(sku.Name)], _ = strconv.ParseFloat(to.String(capability.Value), 64)
+				}
+			}
+			families[to.String(sku.Name)] = to.String(sku.Family)
+		}
+
+		required := map[string]float64{}
+		for _, nodePool := range demand {
+			size, ok := vcpus[nodePool.MachineType]
+			if !ok {
+				return nil, fmt.Errorf("VM size %s of node pool %s is not available in %s", nodePool.MachineType, nodePool.NodePool, config.ResourceLocation)
+			}
+			required[totalRegionalVCPUsQuota] += size * float64(nodePool.Nodes)
+			required[families[nodePool.MachineType]] += size * float64(nodePool.Nodes)
+		}
+
+		usageClient := compute.NewUsageClientWithBaseURI(to.String(cred.BaseURL), cred.SubscriptionID)
+		usageClient.Authorizer = authorizer
+		usages, err := usageClient.ListComplete(ctx, config.ResourceLocation)
+		if err != nil {
+			return nil, fmt.Errorf("error listing compute usage: %w", err)
+		}
+		var quotas []clusteroperator.Quota
+		for ; usages.NotDone(); err = usages.NextWithContext(ctx) {
+			if err != nil {
+				return nil, fmt.Errorf("error listing compute usage: %w", err)
+			}
+			usage := usages.Value()
+			if usage.Name == nil || usage.Limit == nil || usage.CurrentValue == nil {
+				continue
+			}
+			name := to.String(usage.Name.Value)
+			for quota, vcpus := range required {
+				if strings.EqualFold(quota, name) {
+					quotas = append(quotas, clusteroperator.Quota{
+						Name:      fmt.Sprintf("%s vCPUs in %s", name, config.ResourceLocation),
+						Available: float64(*usage.Limit - int64(*usage.CurrentValue)),
+						Required:  vcpus,
+					})
+				}
+			}
+		}
+		return quotas, nil
+	}
+}
@@ -0,0 +1,162 @@
+package clusteroperator
+
+import (
+	"fmt"
+
+	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	gkev1 "github.com/rancher/gke-operator/pkg/apis/gke.cattle.io/v1"
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+)
+
+// EKSConfigWithAutoscaling returns a copy of the EKS config with the autoscaling of the cluster applied to its node
+// groups. Enabled autoscaling sets the minimum and maximum size of a node group, and keeps its desired size within them.
+func EKSConfigWithAutoscaling(config *eksv1.EKSClusterConfigSpec, autoscaling []apimgmtv3.NodePoolAutoscaling) (*eksv1.EKSClusterConfigSpec, error) {
+	if len(autoscaling) == 0 {
+		return config, nil
+	}
+	config = config.DeepCopy()
+	names := make([]string, 0, len(config.NodeGroups))
+	for _, nodeGroup := range config.NodeGroups {
+		if nodeGroup.NodegroupName != nil {
+			names = append(names, *nodeGroup.NodegroupName)
+		}
+	}
+	if err := validateAutoscaling(autoscaling, names); err != nil {
+		return nil, err
+	}
+
+	for i, nodeGroup := range config.NodeGroups {
+		if nodeGroup.NodegroupName == nil {
+			continue
+		}
+		nodePool := findAutoscaling(autoscaling, *nodeGroup.NodegroupName)
+		if nodePool == nil || !nodePool.Enabled {
+			continue
+		}
+		minSize, maxSize := nodePool.MinSize, nodePool.MaxSize
+		config.NodeGroups[i].MinSize = &minSize
+		config.NodeGroups[i].MaxSize = &maxSize
+		if desiredSize := nodeGroup.DesiredSize; desiredSize != nil {
+			switch {
+			case *desiredSize < minSize:
+				config.NodeGroups[i].DesiredSize = &minSize
+			case *desiredSize > maxSize:
+				config.NodeGroups[i].DesiredSize = &maxSize
+			}
+		}
+	}
+	return config, nil
+}
+
+// AKSConfigWithAutoscaling returns a copy of the AKS config with the autoscaling of the cluster applied to its node
+// pools.
+func AKSConfigWithAutoscaling(config *aksv1.AKSClusterConfigSpec, autoscaling []apimgmtv3.NodePoolAutoscaling) (*aksv1.AKSClusterConfigSpec, error) {
+	if len(autoscaling) == 0 {
+		return config, nil
+	}
+	config = config.DeepCopy()
+	names := make([]string, 0, len(config.NodePools))
+	for _, nodePool := range config.NodePools {
+		if nodePool.Name != nil {
+			names = append(names, *nodePool.Name)
+		}
+	}
+	if err := validateAutoscaling(autoscaling, names); err != nil {
+		return nil, err
+	}
+
+	for i, nodePool := range config.NodePools {
+		if nodePool.Name == nil {
+			continue
+		}
+		scaling := findAutoscaling(autoscaling, *nodePool.Name)
+		if scaling == nil {
+			continue
+		}
+		enabled := scaling.Enabled
+		config.NodePools[i].EnableAutoScaling = &enabled
+		if !enabled {
+			config.NodePools[i].MinCount = nil
+			config.NodePools[i].MaxCount = nil
+			continue
+		}
+		minCount, maxCount := int32(scaling.MinSize), int32(scaling.MaxSize)
+		config.NodePools[i].MinCount = &minCount
+		config.NodePools[i].MaxCount = &maxCount
+	}
+	return config, nil
+}
+
+// GKEConfigWithAutoscaling returns a copy of the GKE config with the autoscaling of the cluster applied to its node
+// pools.
+func GKEConfigWithAutoscaling(config *gkev1.GKEClusterConfigSpec, autoscaling []apimgmtv3.NodePoolAutoscaling) (*gkev1.GKEClusterConfigSpec, error) {
+	if len(autoscaling) == 0 {
+		return config, nil
+	}
+	config = config.DeepCopy()
+	names := make([]string, 0, len(config.NodePools))
+	for _, nodePool := range config.NodePools {
+		if nodePool.Name != nil {
+			names = append(names, *nodePool.Name)
+		}
+	}
+	if err := validateAutoscaling(autoscaling, names); err != nil {
+		return nil, err
+	}
+
+	for i, nodePool := range config.NodePools {
+		if nodePool.Name == nil {
+			continue
+		}
+		scaling := findAutoscaling(autoscaling, *nodePool.Name)
+		if scaling == nil {
+			continue
+		}
+		if !scaling.Enabled {
+			config.NodePools[i].Autoscaling = &gkev1.GKENodePoolAutoscaling{}
+			continue
+		}
+		config.NodePools[i].Autoscaling = &gkev1.GKENodePoolAutoscaling{
+			Enabled:      true,
+			MinNodeCount: scaling.MinSize,
+			MaxNodeCount: scaling.MaxSize,
+		}
+	}
+	return config, nil
+}
+
+// validateAutoscaling returns an error if the autoscaling refers to a node pool that is not one of the given node
+// pools, configures a node pool more than once, or has invalid sizes.
+func validateAutoscaling(autoscaling []apimgmtv3.NodePoolAutoscaling, nodePools []string) error {
+	known := make(map[string]bool, len(nodePools))
+	for _, name := range nodePools {
+		known[name] = true
+	}
+	seen := map[string]bool{}
+	for _, scaling := range autoscaling {
+		if !known[scaling.NodePool] {
+			return fmt.Errorf("autoscaling refers to node pool %q which does not exist", scaling.NodePool)
+		}
+		if seen[scaling.NodePool] {
+			return fmt.Errorf("autoscaling of node pool %q is configured more than once", scaling.NodePool)
+		}
+		seen[scaling.NodePool] = true
+		if !scaling.Enabled {
+			continue
+		}
+		if scaling.MinSize < 0 || scaling.MaxSize < 1 || scaling.MinSize > scaling.MaxSize {
+			return fmt.Errorf("autoscaling of node pool %q must have a minimum size of at least 0 and a maximum size of at least 1 that is not less than the minimum size, got %d and %d", scaling.NodePool, scaling.MinSize, scaling.MaxSize)
+		}
+	}
+	return nil
+}
+
+func findAutoscaling(autoscaling []apimgmtv3.NodePoolAutoscaling, nodePool string) *apimgmtv3.NodePoolAutoscaling {
+	for i := range autoscaling {
+		if autoscaling[i].NodePool == nodePool {
+			return &autoscaling[i]
+		}
+	}
+	return nil
+}
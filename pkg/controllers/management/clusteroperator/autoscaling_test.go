@@ -0,0 +1,125 @@
+package clusteroperator
+
+import (
+	"testing"
+
+	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	gkev1 "github.com/rancher/gke-operator/pkg/apis/gke.cattle.io/v1"
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEKSConfigWithAutoscaling(t *testing.T) {
+	config := &eksv1.EKSClusterConfigSpec{
+		NodeGroups: []eksv1.NodeGroup{
+			{NodegroupName: stringPtr("small"), DesiredSize: int64Ptr(1), MinSize: int64Ptr(1), MaxSize: int64Ptr(1)},
+			{NodegroupName: stringPtr("large"), DesiredSize: int64Ptr(10), MinSize: int64Ptr(1), MaxSize: int64Ptr(10)},
+			{NodegroupName: stringPtr("fixed"), DesiredSize: int64Ptr(3), MinSize: int64Ptr(3), MaxSize: int64Ptr(3)},
+		},
+	}
+
+	result, err := EKSConfigWithAutoscaling(config, []apimgmtv3.NodePoolAutoscaling{
+		{NodePool: "small", Enabled: true, MinSize: 2, MaxSize: 5},
+		{NodePool: "large", Enabled: true, MinSize: 1, MaxSize: 4},
+		{NodePool: "fixed"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(2), *result.NodeGroups[0].MinSize)
+	assert.Equal(t, int64(5), *result.NodeGroups[0].MaxSize)
+	assert.Equal(t, int64(2), *result.NodeGroups[0].DesiredSize)
+	assert.Equal(t, int64(4), *result.NodeGroups[1].MaxSize)
+	assert.Equal(t, int64(4), *result.NodeGroups[1].DesiredSize)
+	assert.Equal(t, config.NodeGroups[2], result.NodeGroups[2])
+	// the config of the cluster is not modified
+	assert.Equal(t, int64(1), *config.NodeGroups[0].MinSize)
+}
+
+func TestAKSConfigWithAutoscaling(t *testing.T) {
+	config := &aksv1.AKSClusterConfigSpec{
+		NodePools: []aksv1.AKSNodePool{
+			{Name: stringPtr("pool1")},
+			{Name: stringPtr("pool2"), EnableAutoScaling: boolPtr(true), MinCount: int32Ptr(1), MaxCount: int32Ptr(3)},
+		},
+	}
+
+	result, err := AKSConfigWithAutoscaling(config, []apimgmtv3.NodePoolAutoscaling{
+		{NodePool: "pool1", Enabled: true, MinSize: 1, MaxSize: 5},
+		{NodePool: "pool2"},
+	})
+	assert.NoError(t, err)
+
+	assert.True(t, *result.NodePools[0].EnableAutoScaling)
+	assert.Equal(t, int32(1), *result.NodePools[0].MinCount)
+	assert.Equal(t, int32(5), *result.NodePools[0].MaxCount)
+	assert.False(t, *result.NodePools[1].EnableAutoScaling)
+	assert.Nil(t, result.NodePools[1].MinCount)
+	assert.Nil(t, result.NodePools[1].MaxCount)
+}
+
+func TestGKEConfigWithAutoscaling(t *testing.T) {
+	config := &gkev1.GKEClusterConfigSpec{
+		NodePools: []gkev1.GKENodePoolConfig{
+			{Name: stringPtr("pool1")},
+			{Name: stringPtr("pool2"), Autoscaling: &gkev1.GKENodePoolAutoscaling{Enabled: true, MinNodeCount: 1, MaxNodeCount: 3}},
+		},
+	}
+
+	result, err := GKEConfigWithAutoscaling(config, []apimgmtv3.NodePoolAutoscaling{
+		{NodePool: "pool1", Enabled: true, MinSize: 0, MaxSize: 5},
+		{NodePool: "pool2"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, &gkev1.GKENodePoolAutoscaling{Enabled: true, MaxNodeCount: 5}, result.NodePools[0].Autoscaling)
+	assert.Equal(t, &gkev1.GKENodePoolAutoscaling{}, result.NodePools[1].Autoscaling)
+}
+
+func TestValidateAutoscaling(t *testing.T) {
+	tests := []struct {
+		name        string
+		autoscaling []apimgmtv3.NodePoolAutoscaling
+		expectedErr string
+	}{
+		{
+			name:        "valid",
+			autoscaling: []apimgmtv3.NodePoolAutoscaling{{NodePool: "pool1", Enabled: true, MinSize: 0, MaxSize: 1}, {NodePool: "pool2"}},
+		},
+		{
+			name:        "unknown node pool",
+			autoscaling: []apimgmtv3.NodePoolAutoscaling{{NodePool: "pool3", Enabled: true, MaxSize: 1}},
+			expectedErr: `autoscaling refers to node pool "pool3" which does not exist`,
+		},
+		{
+			name:        "duplicate node pool",
+			autoscaling: []apimgmtv3.NodePoolAutoscaling{{NodePool: "pool1"}, {NodePool: "pool1"}},
+			expectedErr: `autoscaling of node pool "pool1" is configured more than once`,
+		},
+		{
+			name:        "minimum size greater than maximum size",
+			autoscaling: []apimgmtv3.NodePoolAutoscaling{{NodePool: "pool1", Enabled: true, MinSize: 3, MaxSize: 2}},
+			expectedErr: `autoscaling of node pool "pool1" must have a minimum size of at least 0 and a maximum size of at least 1 that is not less than the minimum size, got 3 and 2`,
+		},
+		{
+			name:        "sizes of disabled autoscaling are ignored",
+			autoscaling: []apimgmtv3.NodePoolAutoscaling{{NodePool: "pool1", MinSize: 3, MaxSize: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAutoscaling(tt.autoscaling, []string{"pool1", "pool2"})
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func stringPtr(s string) *string { return &s }
+func int64Ptr(i int64) *int64    { return &i }
+func int32Ptr(i int32) *int32    { return &i }
+func boolPtr(b bool) *bool       { return &b }
@@ -0,0 +1,282 @@
+package clusteroperator
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go/aws"
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	hostedPolicyPreflightTimeout = time.Minute
+	hostedPolicyRecheckInterval  = 5 * time.Minute
+)
+
+// HostedClusterConfig is the part of the config of a hosted cluster that hosted cluster policies constrain.
+type HostedClusterConfig struct {
+	Provider      string
+	Region        string
+	InstanceTypes []string
+	NodeCount     int64
+}
+
+type hostedClusterPolicyViolation struct {
+	key       string
+	message   string
+	nodeCount bool
+}
+
+// InstanceTypeResolver returns the instance types of the node pools of the hosted cluster spec that are not part of its
+// config, such as the instance types of the launch templates of EKS node groups.
+type InstanceTypeResolver func(ctx context.Context, spec *apimgmtv3.ClusterSpec) ([]string, error)
+
+// HostedClusterPolicyViolations returns the violations of the hosted cluster policies by the config that the previous
+// config, which is nil for new clusters, did not have, so that adding a policy does not block unrelated changes of
+// existing clusters.
+func HostedClusterPolicyViolations(policies []*apimgmtv3.HostedClusterPolicy, config, prevConfig *HostedClusterConfig) []string {
+	violations := hostedClusterPolicyViolations(policies, config)
+	if prevConfig != nil && len(violations) > 0 {
+		violations = newHostedClusterPolicyViolations(violations, hostedClusterPolicyViolations(policies, prevConfig), config, prevConfig)
+	}
+	var messages []string
+	for _, violation := range violations {
+		messages = append(messages, violation.message)
+	}
+	return messages
+}
+
+// HostedPolicyPreflight checks the config of the cluster against the hosted cluster policies before it is passed to its
+// operator, so that clusters created or updated without the Rancher API are held to them as well. Violations the applied
+// config of the cluster already had are tolerated. The outcome is reported with the HostedPolicyCompliant condition, and
+// false is returned while the config violates a policy, or while the instance types of the resolver cannot be checked.
+func (e *OperatorController) HostedPolicyPreflight(cluster *mgmtv3.Cluster, resolve InstanceTypeResolver) (*mgmtv3.Cluster, bool, error) {
+	if e.HostedClusterPolicyCache == nil {
+		return cluster, true, nil
+	}
+	config := HostedConfigOf(&cluster.Spec)
+	if config == nil {
+		return cluster, true, nil
+	}
+	policies, err := e.HostedClusterPolicyCache.List(labels.Everything())
+	if err != nil {
+		return cluster, false, err
+	}
+
+	prevConfig := HostedConfigOf(&cluster.Status.AppliedSpec)
+	if resolve != nil && restrictsInstanceTypes(policies, config.Provider) {
+		ctx, cancel := context.WithTimeout(context.Background(), hostedPolicyPreflightTimeout)
+		defer cancel()
+		instanceTypes, err := resolve(ctx, &cluster.Spec)
+		if err == nil && prevConfig != nil {
+			var prevInstanceTypes []string
+			prevInstanceTypes, err = resolve(ctx, &cluster.Status.AppliedSpec)
+			prevConfig.InstanceTypes = append(prevConfig.InstanceTypes, prevInstanceTypes...)
+		}
+		if err != nil {
+			logrus.Warnf("error resolving instance types of cluster [%s]: %v", cluster.Name, err)
+			cluster, err = e.SetUnknown(cluster, apimgmtv3.ClusterConditionHostedPolicyCompliant, fmt.Sprintf("failed to resolve instance types: %v", err))
+			if err != nil {
+				return cluster, false, err
+			}
+			e.ClusterEnqueueAfter(cluster.Name, hostedPolicyRecheckInterval)
+			return cluster, false, nil
+		}
+		config.InstanceTypes = append(config.InstanceTypes, instanceTypes...)
+	}
+
+	if violations := HostedClusterPolicyViolations(policies, config, prevConfig); len(violations) > 0 {
+		message := "cluster violates hosted cluster policies: " + strings.Join(violations, "; ")
+		logrus.Infof("holding back config change of cluster [%s]: %s", cluster.Name, message)
+		cluster, err = e.SetFalse(cluster, apimgmtv3.ClusterConditionHostedPolicyCompliant, message)
+		if err != nil {
+			return cluster, false, err
+		}
+		e.ClusterEnqueueAfter(cluster.Name, hostedPolicyRecheckInterval)
+		return cluster, false, nil
+	}
+
+	if apimgmtv3.ClusterConditionHostedPolicyCompliant.GetStatus(cluster) == "" {
+		return cluster, true, nil
+	}
+	cluster, err = e.SetTrue(cluster, apimgmtv3.ClusterConditionHostedPolicyCompliant, "")
+	return cluster, err == nil, err
+}
+
+// restrictsInstanceTypes returns true if one of the policies that apply to the provider restricts instance types.
+func restrictsInstanceTypes(policies []*apimgmtv3.HostedClusterPolicy, provider string) bool {
+	for _, policy := range policies {
+		if policyAppliesTo(policy, provider) && len(policy.Spec.AllowedInstanceTypes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newHostedClusterPolicyViolations returns the violations of the config that the previous config did not have. Exceeding
+// the maximum node count is only a new violation if the config has more nodes than the previous config.
+func newHostedClusterPolicyViolations(violations, prevViolations []hostedClusterPolicyViolation, config, prevConfig *HostedClusterConfig) []hostedClusterPolicyViolation {
+	existing := sets.NewString()
+	for _, violation := range prevViolations {
+		existing.Insert(violation.key)
+	}
+	var result []hostedClusterPolicyViolation
+	for _, violation := range violations {
+		if existing.Has(violation.key) && (!violation.nodeCount || config.NodeCount <= prevConfig.NodeCount) {
+			continue
+		}
+		result = append(result, violation)
+	}
+	return result
+}
+
+// HostedConfigOf returns the constrained part of the EKS, AKS or GKE config of the cluster spec, with the autoscaling of
+// the cluster applied. Imported clusters are not constrained and nil is returned for them. The instance types of EKS
+// launch templates are not part of the config.
+func HostedConfigOf(spec *apimgmtv3.ClusterSpec) *HostedClusterConfig {
+	switch {
+	case spec.EKSConfig != nil && !spec.EKSConfig.Imported:
+		eksConfig := spec.EKSConfig
+		if scaled, err := EKSConfigWithAutoscaling(eksConfig, spec.HostedNodePoolAutoscaling); err == nil {
+			eksConfig = scaled
+		}
+		config := &HostedClusterConfig{
+			Provider: apimgmtv3.ClusterDriverEKS,
+			Region:   eksConfig.Region,
+		}
+		for _, nodeGroup := range eksConfig.NodeGroups {
+			config.NodeCount += maxInt64(aws.Int64Value(nodeGroup.DesiredSize), aws.Int64Value(nodeGroup.MaxSize))
+			if instanceType := aws.StringValue(nodeGroup.InstanceType); instanceType != "" {
+				config.InstanceTypes = append(config.InstanceTypes, instanceType)
+			}
+			config.InstanceTypes = append(config.InstanceTypes, aws.StringValueSlice(nodeGroup.SpotInstanceTypes)...)
+		}
+		return config
+	case spec.AKSConfig != nil && !spec.AKSConfig.Imported:
+		aksConfig := spec.AKSConfig
+		if scaled, err := AKSConfigWithAutoscaling(aksConfig, spec.HostedNodePoolAutoscaling); err == nil {
+			aksConfig = scaled
+		}
+		config := &HostedClusterConfig{
+			Provider: apimgmtv3.ClusterDriverAKS,
+			Region:   aksConfig.ResourceLocation,
+		}
+		for _, nodePool := range aksConfig.NodePools {
+			count := int64(to.Int32(nodePool.Count))
+			if to.Bool(nodePool.EnableAutoScaling) {
+				count = maxInt64(count, int64(to.Int32(nodePool.MaxCount)))
+			}
+			config.NodeCount += count
+			if nodePool.VMSize != "" {
+				config.InstanceTypes = append(config.InstanceTypes, nodePool.VMSize)
+			}
+		}
+		return config
+	case spec.GKEConfig != nil && !spec.GKEConfig.Imported:
+		gkeConfig := spec.GKEConfig
+		if scaled, err := GKEConfigWithAutoscaling(gkeConfig, spec.HostedNodePoolAutoscaling); err == nil {
+			gkeConfig = scaled
+		}
+		config := &HostedClusterConfig{
+			Provider: apimgmtv3.ClusterDriverGKE,
+			Region:   gkeConfig.Region,
+		}
+		if gkeConfig.Zone != "" {
+			config.Region = gkeConfig.Zone
+		}
+		for _, nodePool := range gkeConfig.NodePools {
+			count := to.Int64(nodePool.InitialNodeCount)
+			if nodePool.Autoscaling != nil && nodePool.Autoscaling.Enabled {
+				count = maxInt64(count, nodePool.Autoscaling.MaxNodeCount)
+			}
+			config.NodeCount += count
+			if nodePool.Config != nil && nodePool.Config.MachineType != "" {
+				config.InstanceTypes = append(config.InstanceTypes, nodePool.Config.MachineType)
+			}
+		}
+		return config
+	}
+	return nil
+}
+
+// hostedClusterPolicyViolations returns the violations of the policies that apply to the provider of the config, sorted
+// by policy.
+func hostedClusterPolicyViolations(policies []*apimgmtv3.HostedClusterPolicy, config *HostedClusterConfig) []hostedClusterPolicyViolation {
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+
+	var violations []hostedClusterPolicyViolation
+	for _, policy := range policies {
+		if !policyAppliesTo(policy, config.Provider) {
+			continue
+		}
+		prefix := "policy " + policy.Name
+		if policy.Spec.Description != "" {
+			prefix = fmt.Sprintf("policy %s (%s)", policy.Name, policy.Spec.Description)
+		}
+		if policy.Spec.MaxNodeCount != nil && config.NodeCount > *policy.Spec.MaxNodeCount {
+			violations = append(violations, hostedClusterPolicyViolation{
+				key:       policy.Name + "/nodes",
+				message:   fmt.Sprintf("%s allows at most %d nodes, the cluster can scale to %d nodes", prefix, *policy.Spec.MaxNodeCount, config.NodeCount),
+				nodeCount: true,
+			})
+		}
+		for _, instanceType := range sets.NewString(config.InstanceTypes...).List() {
+			if !matchesAny(policy.Spec.AllowedInstanceTypes, instanceType) {
+				violations = append(violations, hostedClusterPolicyViolation{
+					key:     policy.Name + "/instance-type/" + instanceType,
+					message: fmt.Sprintf("%s does not allow instance type %s", prefix, instanceType),
+				})
+			}
+		}
+		if config.Region != "" && !matchesAny(policy.Spec.AllowedRegions, config.Region) {
+			violations = append(violations, hostedClusterPolicyViolation{
+				key:     policy.Name + "/region/" + config.Region,
+				message: fmt.Sprintf("%s does not allow region %s", prefix, config.Region),
+			})
+		}
+	}
+	return violations
+}
+
+func policyAppliesTo(policy *apimgmtv3.HostedClusterPolicy, provider string) bool {
+	if len(policy.Spec.Providers) == 0 {
+		return true
+	}
+	for _, p := range policy.Spec.Providers {
+		if strings.EqualFold(p, provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAny returns true if the value matches one of the glob patterns, or if there are no patterns.
+func matchesAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
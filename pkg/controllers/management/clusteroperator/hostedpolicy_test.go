@@ -0,0 +1,258 @@
+package clusteroperator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	gkev1 "github.com/rancher/gke-operator/pkg/apis/gke.cattle.io/v1"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestHostedConfigOf(t *testing.T) {
+	eksSpec := &v32.ClusterSpec{
+		EKSConfig: &eksv1.EKSClusterConfigSpec{
+			Region: "us-east-1",
+			NodeGroups: []eksv1.NodeGroup{
+				{NodegroupName: aws.String("a"), DesiredSize: aws.Int64(2), MaxSize: aws.Int64(3), InstanceType: aws.String("m5.large")},
+				{NodegroupName: aws.String("b"), DesiredSize: aws.Int64(1), MaxSize: aws.Int64(1), SpotInstanceTypes: []*string{aws.String("t3.large")}},
+			},
+		},
+		HostedNodePoolAutoscaling: []v32.NodePoolAutoscaling{{NodePool: "b", Enabled: true, MinSize: 1, MaxSize: 5}},
+	}
+	assert.Equal(t, &HostedClusterConfig{
+		Provider:      v32.ClusterDriverEKS,
+		Region:        "us-east-1",
+		InstanceTypes: []string{"m5.large", "t3.large"},
+		NodeCount:     8,
+	}, HostedConfigOf(eksSpec))
+
+	gkeSpec := &v32.ClusterSpec{
+		GKEConfig: &gkev1.GKEClusterConfigSpec{
+			Region: "us-central1",
+			Zone:   "us-central1-a",
+			NodePools: []gkev1.GKENodePoolConfig{
+				{Name: aws.String("a"), InitialNodeCount: aws.Int64(3), Config: &gkev1.GKENodeConfig{MachineType: "n2-standard-4"}},
+			},
+		},
+	}
+	assert.Equal(t, &HostedClusterConfig{
+		Provider:      v32.ClusterDriverGKE,
+		Region:        "us-central1-a",
+		InstanceTypes: []string{"n2-standard-4"},
+		NodeCount:     3,
+	}, HostedConfigOf(gkeSpec))
+
+	assert.Nil(t, HostedConfigOf(&v32.ClusterSpec{EKSConfig: &eksv1.EKSClusterConfigSpec{Imported: true}}))
+	assert.Nil(t, HostedConfigOf(&v32.ClusterSpec{}))
+}
+
+func TestHostedClusterPolicyViolations(t *testing.T) {
+	policy := func(name string, spec v32.HostedClusterPolicySpec) *v32.HostedClusterPolicy {
+		return &v32.HostedClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: spec}
+	}
+	maxNodes := int64(5)
+	config := &HostedClusterConfig{
+		Provider:      v32.ClusterDriverEKS,
+		Region:        "eu-west-1",
+		InstanceTypes: []string{"m5.large", "p4d.24xlarge"},
+		NodeCount:     8,
+	}
+
+	tests := []struct {
+		name     string
+		policies []*v32.HostedClusterPolicy
+		expected []string
+	}{
+		{
+			name: "no policies",
+		},
+		{
+			name: "policy of another provider",
+			policies: []*v32.HostedClusterPolicy{
+				policy("aks", v32.HostedClusterPolicySpec{Providers: []string{"AKS"}, MaxNodeCount: &maxNodes}),
+			},
+		},
+		{
+			name: "satisfied policy",
+			policies: []*v32.HostedClusterPolicy{
+				policy("eks", v32.HostedClusterPolicySpec{Providers: []string{"eks"}, AllowedInstanceTypes: []string{"m5.*", "p4d.*"}, AllowedRegions: []string{"eu-*"}}),
+			},
+		},
+		{
+			name: "violated policies",
+			policies: []*v32.HostedClusterPolicy{
+				policy("regions", v32.HostedClusterPolicySpec{AllowedRegions: []string{"us-*"}}),
+				policy("budget", v32.HostedClusterPolicySpec{Description: "team budget", MaxNodeCount: &maxNodes, AllowedInstanceTypes: []string{"m5.*"}}),
+			},
+			expected: []string{
+				"policy budget (team budget) allows at most 5 nodes, the cluster can scale to 8 nodes",
+				"policy budget (team budget) does not allow instance type p4d.24xlarge",
+				"policy regions does not allow region eu-west-1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages []string
+			for _, violation := range hostedClusterPolicyViolations(tt.policies, config) {
+				messages = append(messages, violation.message)
+			}
+			assert.Equal(t, tt.expected, messages)
+		})
+	}
+}
+
+func TestNewHostedClusterPolicyViolations(t *testing.T) {
+	maxNodes := int64(5)
+	policies := []*v32.HostedClusterPolicy{{
+		ObjectMeta: metav1.ObjectMeta{Name: "budget"},
+		Spec:       v32.HostedClusterPolicySpec{MaxNodeCount: &maxNodes, AllowedInstanceTypes: []string{"m5.*"}},
+	}}
+	prevConfig := &HostedClusterConfig{Provider: v32.ClusterDriverEKS, InstanceTypes: []string{"p4d.24xlarge"}, NodeCount: 8}
+	prevViolations := hostedClusterPolicyViolations(policies, prevConfig)
+
+	// scaling down and keeping the instance type does not add violations
+	config := &HostedClusterConfig{Provider: v32.ClusterDriverEKS, InstanceTypes: []string{"p4d.24xlarge"}, NodeCount: 6}
+	assert.Empty(t, newHostedClusterPolicyViolations(hostedClusterPolicyViolations(policies, config), prevViolations, config, prevConfig))
+
+	// scaling up and adding an instance type does
+	config = &HostedClusterConfig{Provider: v32.ClusterDriverEKS, InstanceTypes: []string{"p4d.24xlarge", "x1.32xlarge"}, NodeCount: 9}
+	violations := newHostedClusterPolicyViolations(hostedClusterPolicyViolations(policies, config), prevViolations, config, prevConfig)
+	if assert.Len(t, violations, 2) {
+		assert.Equal(t, "budget/nodes", violations[0].key)
+		assert.Equal(t, "budget/instance-type/x1.32xlarge", violations[1].key)
+	}
+}
+
+type fakeHostedClusterPolicyCache struct {
+	v3.HostedClusterPolicyCache
+	policies []*v32.HostedClusterPolicy
+}
+
+func (f *fakeHostedClusterPolicyCache) List(labels.Selector) ([]*v32.HostedClusterPolicy, error) {
+	return append([]*v32.HostedClusterPolicy(nil), f.policies...), nil
+}
+
+type fakeClusterClient struct {
+	v3.ClusterClient
+}
+
+func (f *fakeClusterClient) Update(cluster *v32.Cluster) (*v32.Cluster, error) {
+	return cluster, nil
+}
+
+func TestHostedPolicyPreflight(t *testing.T) {
+	policies := []*v32.HostedClusterPolicy{{
+		ObjectMeta: metav1.ObjectMeta{Name: "budget"},
+		Spec:       v32.HostedClusterPolicySpec{Providers: []string{"EKS"}, AllowedInstanceTypes: []string{"m5.*"}},
+	}}
+	eksConfig := func(instanceType string) *eksv1.EKSClusterConfigSpec {
+		return &eksv1.EKSClusterConfigSpec{
+			Region: "us-east-1",
+			NodeGroups: []eksv1.NodeGroup{
+				{NodegroupName: aws.String("a"), DesiredSize: aws.Int64(1), InstanceType: aws.String(instanceType)},
+				{NodegroupName: aws.String("b"), DesiredSize: aws.Int64(1), LaunchTemplate: &eksv1.LaunchTemplate{ID: aws.String(instanceType)}},
+			},
+		}
+	}
+	resolver := func(instanceTypes map[string]string) InstanceTypeResolver {
+		return func(_ context.Context, spec *v32.ClusterSpec) ([]string, error) {
+			var resolved []string
+			for _, nodeGroup := range spec.EKSConfig.NodeGroups {
+				if nodeGroup.LaunchTemplate == nil {
+					continue
+				}
+				instanceType, ok := instanceTypes[*nodeGroup.LaunchTemplate.ID]
+				if !ok {
+					return nil, fmt.Errorf("launch template %s not found", *nodeGroup.LaunchTemplate.ID)
+				}
+				resolved = append(resolved, instanceType)
+			}
+			return resolved, nil
+		}
+	}
+
+	tests := []struct {
+		name            string
+		policies        []*v32.HostedClusterPolicy
+		config          *eksv1.EKSClusterConfigSpec
+		appliedConfig   *eksv1.EKSClusterConfigSpec
+		resolve         InstanceTypeResolver
+		expectedProceed bool
+		expectedStatus  string
+		expectedMessage string
+	}{
+		{
+			name:            "no policies",
+			config:          eksConfig("p4d.24xlarge"),
+			expectedProceed: true,
+		},
+		{
+			name:            "compliant",
+			policies:        policies,
+			config:          eksConfig("m5.large"),
+			resolve:         resolver(map[string]string{"m5.large": "m5.xlarge"}),
+			expectedProceed: true,
+		},
+		{
+			name:            "instance type of the config",
+			policies:        policies,
+			config:          eksConfig("p4d.24xlarge"),
+			resolve:         resolver(map[string]string{"p4d.24xlarge": "m5.large"}),
+			expectedStatus:  "False",
+			expectedMessage: "cluster violates hosted cluster policies: policy budget does not allow instance type p4d.24xlarge",
+		},
+		{
+			name:            "instance type of the launch template",
+			policies:        policies,
+			config:          eksConfig("m5.large"),
+			resolve:         resolver(map[string]string{"m5.large": "x1.32xlarge"}),
+			expectedStatus:  "False",
+			expectedMessage: "cluster violates hosted cluster policies: policy budget does not allow instance type x1.32xlarge",
+		},
+		{
+			name:            "violation of the applied config",
+			policies:        policies,
+			config:          eksConfig("p4d.24xlarge"),
+			appliedConfig:   eksConfig("p4d.24xlarge"),
+			resolve:         resolver(map[string]string{"p4d.24xlarge": "x1.32xlarge"}),
+			expectedProceed: true,
+		},
+		{
+			name:            "unresolved launch template",
+			policies:        policies,
+			config:          eksConfig("m5.large"),
+			resolve:         resolver(nil),
+			expectedStatus:  "Unknown",
+			expectedMessage: "failed to resolve instance types: launch template m5.large not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var enqueued bool
+			e := &OperatorController{
+				ClusterEnqueueAfter:      func(string, time.Duration) { enqueued = true },
+				ClusterClient:            &fakeClusterClient{},
+				HostedClusterPolicyCache: &fakeHostedClusterPolicyCache{policies: tt.policies},
+			}
+			cluster := &v32.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-test"}}
+			cluster.Spec.EKSConfig = tt.config
+			cluster.Status.AppliedSpec.EKSConfig = tt.appliedConfig
+
+			cluster, proceed, err := e.HostedPolicyPreflight(cluster, tt.resolve)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedProceed, proceed)
+			assert.Equal(t, !tt.expectedProceed, enqueued)
+			assert.Equal(t, tt.expectedStatus, v32.ClusterConditionHostedPolicyCompliant.GetStatus(cluster))
+			assert.Equal(t, tt.expectedMessage, v32.ClusterConditionHostedPolicyCompliant.GetMessage(cluster))
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package clusteroperator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	gkev1 "github.com/rancher/gke-operator/pkg/apis/gke.cattle.io/v1"
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	quotaPreflightTimeout = time.Minute
+	quotaRecheckInterval  = 5 * time.Minute
+
+	// gkeRegionalZones is the number of zones the node pools of a regional GKE cluster are spread across if the zones
+	// of the cluster are not set.
+	gkeRegionalZones = 3
+)
+
+// NodePoolDemand is the number of nodes of a machine type that a change to a node pool of a hosted cluster adds.
+type NodePoolDemand struct {
+	NodePool    string
+	MachineType string
+	Nodes       int64
+}
+
+// Quota is a quota of the cloud account of a hosted cluster that the nodes of a change to its node pools draw from.
+type Quota struct {
+	Name      string
+	Available float64
+	Required  float64
+}
+
+// QuotaChecker returns the quotas that the demand of a change to the node pools of a hosted cluster draws from.
+type QuotaChecker func(ctx context.Context, demand []NodePoolDemand) ([]Quota, error)
+
+// QuotaShortfalls returns a description of each of the quotas that is predicted to be exceeded, sorted by name.
+func QuotaShortfalls(quotas []Quota) []string {
+	var shortfalls []string
+	for _, quota := range quotas {
+		if quota.Required > quota.Available {
+			shortfalls = append(shortfalls, fmt.Sprintf("%s requires %g but %g is available", quota.Name, quota.Required, quota.Available))
+		}
+	}
+	sort.Strings(shortfalls)
+	return shortfalls
+}
+
+// QuotaPreflight checks the quotas of the cloud account of the cluster before the change to its node pools that adds
+// the demand is passed to its operator, if the preflight is enabled for the cluster. The outcome is reported with the
+// HostedQuotaSufficient condition, and false is returned while a shortfall is predicted, so that the change is held
+// back rather than leaving node pools half created. The check is repeated until the quotas suffice. A check that fails
+// does not hold back the change, as the quotas may not be readable with the credentials of the cluster.
+func (e *OperatorController) QuotaPreflight(cluster *mgmtv3.Cluster, demand []NodePoolDemand, check QuotaChecker) (*mgmtv3.Cluster, bool, error) {
+	if !cluster.Spec.HostedQuotaPreflight || len(demand) == 0 {
+		return cluster, true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), quotaPreflightTimeout)
+	defer cancel()
+	quotas, err := check(ctx, demand)
+	if err != nil {
+		logrus.Warnf("error checking quotas for cluster [%s]: %v", cluster.Name, err)
+		cluster, err = e.SetUnknown(cluster, apimgmtv3.ClusterConditionHostedQuotaSufficient, fmt.Sprintf("failed to check quotas: %v", err))
+		return cluster, err == nil, err
+	}
+
+	if shortfalls := QuotaShortfalls(quotas); len(shortfalls) > 0 {
+		message := fmt.Sprintf("insufficient quota for node pools: %s", strings.Join(shortfalls, ", "))
+		logrus.Infof("holding back node pool change of cluster [%s]: %s", cluster.Name, message)
+		cluster, err = e.SetFalse(cluster, apimgmtv3.ClusterConditionHostedQuotaSufficient, message)
+		if err != nil {
+			return cluster, false, err
+		}
+		e.ClusterEnqueueAfter(cluster.Name, quotaRecheckInterval)
+		return cluster, false, nil
+	}
+
+	cluster, err = e.SetTrue(cluster, apimgmtv3.ClusterConditionHostedQuotaSufficient, "")
+	return cluster, err == nil, err
+}
+
+// AKSNodePoolDemand returns the nodes that the node pools of the config add to those of the upstream config of an AKS
+// cluster. A node pool whose VM size changed is replaced, so all of its nodes are added.
+func AKSNodePoolDemand(config, upstream *aksv1.AKSClusterConfigSpec) []NodePoolDemand {
+	current := map[string]aksv1.AKSNodePool{}
+	if upstream != nil {
+		for _, nodePool := range upstream.NodePools {
+			if nodePool.Name != nil {
+				current[*nodePool.Name] = nodePool
+			}
+		}
+	}
+
+	var demand []NodePoolDemand
+	for _, nodePool := range config.NodePools {
+		if nodePool.Name == nil || nodePool.Count == nil {
+			continue
+		}
+		nodes := int64(*nodePool.Count)
+		if existing, ok := current[*nodePool.Name]; ok && existing.VMSize == nodePool.VMSize && existing.Count != nil {
+			nodes -= int64(*existing.Count)
+		}
+		demand = appendDemand(demand, *nodePool.Name, nodePool.VMSize, nodes)
+	}
+	return demand
+}
+
+// EKSNodeGroupDemand returns the nodes that the node groups of the config add to those of the upstream config of an EKS
+// cluster. Node groups of spot instances are left out, as they do not draw from the on-demand quotas.
+func EKSNodeGroupDemand(config, upstream *eksv1.EKSClusterConfigSpec) []NodePoolDemand {
+	current := map[string]eksv1.NodeGroup{}
+	if upstream != nil {
+		for _, nodeGroup := range upstream.NodeGroups {
+			if nodeGroup.NodegroupName != nil {
+				current[*nodeGroup.NodegroupName] = nodeGroup
+			}
+		}
+	}
+
+	var demand []NodePoolDemand
+	for _, nodeGroup := range config.NodeGroups {
+		if nodeGroup.NodegroupName == nil || nodeGroup.DesiredSize == nil || nodeGroup.InstanceType == nil ||
+			(nodeGroup.RequestSpotInstances != nil && *nodeGroup.RequestSpotInstances) {
+			continue
+		}
+		nodes := *nodeGroup.DesiredSize
+		if existing, ok := current[*nodeGroup.NodegroupName]; ok && existing.InstanceType != nil &&
+			*existing.InstanceType == *nodeGroup.InstanceType && existing.DesiredSize != nil {
+			nodes -= *existing.DesiredSize
+		}
+		demand = appendDemand(demand, *nodeGroup.NodegroupName, *nodeGroup.InstanceType, nodes)
+	}
+	return demand
+}
+
+// GKENodePoolDemand returns the nodes that the node pools of the config add to those of the upstream config of a GKE
+// cluster. The node count of a node pool is per zone of the cluster.
+func GKENodePoolDemand(config, upstream *gkev1.GKEClusterConfigSpec) []NodePoolDemand {
+	current := map[string]gkev1.GKENodePoolConfig{}
+	if upstream != nil {
+		for _, nodePool := range upstream.NodePools {
+			if nodePool.Name != nil {
+				current[*nodePool.Name] = nodePool
+			}
+		}
+	}
+
+	zones := int64(len(config.Locations))
+	if zones == 0 && config.Region != "" {
+		zones = gkeRegionalZones
+	} else if zones == 0 {
+		zones = 1
+	}
+
+	var demand []NodePoolDemand
+	for _, nodePool := range config.NodePools {
+		if nodePool.Name == nil || nodePool.InitialNodeCount == nil || nodePool.Config == nil {
+			continue
+		}
+		nodes := *nodePool.InitialNodeCount
+		if existing, ok := current[*nodePool.Name]; ok && existing.Config != nil &&
+			existing.Config.MachineType == nodePool.Config.MachineType && existing.InitialNodeCount != nil {
+			nodes -= *existing.InitialNodeCount
+		}
+		demand = appendDemand(demand, *nodePool.Name, nodePool.Config.MachineType, nodes*zones)
+	}
+	return demand
+}
+
+func appendDemand(demand []NodePoolDemand, nodePool, machineType string, nodes int64) []NodePoolDemand {
+	if nodes <= 0 {
+		return demand
+	}
+	return append(demand, NodePoolDemand{NodePool: nodePool, MachineType: machineType, Nodes: nodes})
+}
@@ -0,0 +1,113 @@
+package clusteroperator
+
+import (
+	"testing"
+
+	aksv1 "github.com/rancher/aks-operator/pkg/apis/aks.cattle.io/v1"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	gkev1 "github.com/rancher/gke-operator/pkg/apis/gke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaShortfalls(t *testing.T) {
+	quotas := []Quota{
+		{Name: "standardDSv3Family vCPUs in eastus", Available: 8, Required: 8},
+		{Name: "cores vCPUs in eastus", Available: 4, Required: 8},
+		{Name: "IN_USE_ADDRESSES in us-east1", Available: 1.5, Required: 2},
+	}
+
+	assert.Equal(t, []string{
+		"IN_USE_ADDRESSES in us-east1 requires 2 but 1.5 is available",
+		"cores vCPUs in eastus requires 8 but 4 is available",
+	}, QuotaShortfalls(quotas))
+	assert.Empty(t, QuotaShortfalls(quotas[:1]))
+}
+
+func TestAKSNodePoolDemand(t *testing.T) {
+	nodePool := func(name, vmSize string, count int32) aksv1.AKSNodePool {
+		return aksv1.AKSNodePool{Name: &name, VMSize: vmSize, Count: &count}
+	}
+	upstream := &aksv1.AKSClusterConfigSpec{NodePools: []aksv1.AKSNodePool{
+		nodePool("system", "Standard_D2s_v3", 3),
+		nodePool("user", "Standard_D2s_v3", 2),
+		nodePool("batch", "Standard_D2s_v3", 2),
+	}}
+	config := &aksv1.AKSClusterConfigSpec{NodePools: []aksv1.AKSNodePool{
+		nodePool("system", "Standard_D2s_v3", 2),
+		nodePool("user", "Standard_D2s_v3", 5),
+		nodePool("batch", "Standard_D4s_v3", 2),
+		nodePool("gpu", "Standard_NC6", 1),
+	}}
+
+	assert.Equal(t, []NodePoolDemand{
+		{NodePool: "user", MachineType: "Standard_D2s_v3", Nodes: 3},
+		{NodePool: "batch", MachineType: "Standard_D4s_v3", Nodes: 2},
+		{NodePool: "gpu", MachineType: "Standard_NC6", Nodes: 1},
+	}, AKSNodePoolDemand(config, upstream))
+	assert.Len(t, AKSNodePoolDemand(config, nil), 4)
+	assert.Empty(t, AKSNodePoolDemand(upstream, upstream))
+}
+
+func TestEKSNodeGroupDemand(t *testing.T) {
+	spot := true
+	nodeGroup := func(name, instanceType string, size int64) eksv1.NodeGroup {
+		return eksv1.NodeGroup{NodegroupName: &name, InstanceType: &instanceType, DesiredSize: &size}
+	}
+	spotGroup := nodeGroup("spot", "m5.large", 4)
+	spotGroup.RequestSpotInstances = &spot
+	upstream := &eksv1.EKSClusterConfigSpec{NodeGroups: []eksv1.NodeGroup{nodeGroup("workers", "m5.large", 2)}}
+	config := &eksv1.EKSClusterConfigSpec{NodeGroups: []eksv1.NodeGroup{
+		nodeGroup("workers", "m5.large", 3),
+		nodeGroup("compute", "c5.xlarge", 2),
+		spotGroup,
+	}}
+
+	assert.Equal(t, []NodePoolDemand{
+		{NodePool: "workers", MachineType: "m5.large", Nodes: 1},
+		{NodePool: "compute", MachineType: "c5.xlarge", Nodes: 2},
+	}, EKSNodeGroupDemand(config, upstream))
+}
+
+func TestGKENodePoolDemand(t *testing.T) {
+	nodePool := func(name, machineType string, count int64) gkev1.GKENodePoolConfig {
+		return gkev1.GKENodePoolConfig{Name: &name, InitialNodeCount: &count, Config: &gkev1.GKENodeConfig{MachineType: machineType}}
+	}
+	upstream := &gkev1.GKEClusterConfigSpec{NodePools: []gkev1.GKENodePoolConfig{nodePool("default", "e2-standard-2", 1)}}
+
+	tests := []struct {
+		name     string
+		config   *gkev1.GKEClusterConfigSpec
+		expected []NodePoolDemand
+	}{
+		{
+			name: "zonal",
+			config: &gkev1.GKEClusterConfigSpec{
+				Zone:      "us-east1-b",
+				NodePools: []gkev1.GKENodePoolConfig{nodePool("default", "e2-standard-2", 3)},
+			},
+			expected: []NodePoolDemand{{NodePool: "default", MachineType: "e2-standard-2", Nodes: 2}},
+		},
+		{
+			name: "regional",
+			config: &gkev1.GKEClusterConfigSpec{
+				Region:    "us-east1",
+				NodePools: []gkev1.GKENodePoolConfig{nodePool("default", "e2-standard-2", 2)},
+			},
+			expected: []NodePoolDemand{{NodePool: "default", MachineType: "e2-standard-2", Nodes: 3}},
+		},
+		{
+			name: "locations",
+			config: &gkev1.GKEClusterConfigSpec{
+				Region:    "us-east1",
+				Locations: []string{"us-east1-b", "us-east1-c"},
+				NodePools: []gkev1.GKENodePoolConfig{nodePool("default", "n2-standard-4", 1)},
+			},
+			expected: []NodePoolDemand{{NodePool: "default", MachineType: "n2-standard-4", Nodes: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, GKENodePoolDemand(tt.config, upstream))
+		})
+	}
+}
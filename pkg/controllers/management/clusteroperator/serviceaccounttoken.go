@@ -0,0 +1,60 @@
+package clusteroperator
+
+import (
+	"fmt"
+
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/secretmigrator"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ServiceAccountTokenRefreshAnnotation is set by the refreshServiceAccountToken action of a hosted cluster to the time
+// the refresh was requested. It is removed by the operator controller of the cluster once the token was rotated.
+const ServiceAccountTokenRefreshAnnotation = "clusters.management.cattle.io/refresh-service-account-token"
+
+// ServiceAccountTokenRefreshRequested returns true if a refresh of the service account token of the cluster was
+// requested and has not been handled yet.
+func ServiceAccountTokenRefreshRequested(cluster *mgmtv3.Cluster) bool {
+	_, ok := cluster.Annotations[ServiceAccountTokenRefreshAnnotation]
+	return ok
+}
+
+// RefreshServiceAccountToken rotates the service account token of the cluster with the given function and stores the
+// new token in a new secret, so that the cluster manager rebuilds the clients of the cluster with it. The outcome is
+// reported with the ServiceAccountTokenRefreshed condition. A failed refresh is retried until it succeeds.
+func (e *OperatorController) RefreshServiceAccountToken(cluster *mgmtv3.Cluster, rotate func(cluster *mgmtv3.Cluster) (string, error)) (*mgmtv3.Cluster, error) {
+	saToken, err := rotate(cluster)
+	if err != nil {
+		var statusErr error
+		cluster, statusErr = e.SetFalse(cluster, apimgmtv3.ClusterConditionServiceAccountTokenRefreshed,
+			fmt.Sprintf("failed to refresh service account token: %v", err))
+		if statusErr != nil {
+			return cluster, statusErr
+		}
+		return cluster, err
+	}
+
+	oldSecret := cluster.Status.ServiceAccountTokenSecret
+	cluster = cluster.DeepCopy()
+	secret, err := secretmigrator.NewMigrator(e.SecretsCache, e.Secrets).CreateOrUpdateServiceAccountTokenSecret("", saToken, cluster)
+	if err != nil {
+		return cluster, err
+	}
+	cluster.Status.ServiceAccountTokenSecret = secret.Name
+	cluster.Status.ServiceAccountToken = ""
+	delete(cluster.Annotations, ServiceAccountTokenRefreshAnnotation)
+	apimgmtv3.ClusterConditionServiceAccountTokenRefreshed.True(cluster)
+	apimgmtv3.ClusterConditionServiceAccountTokenRefreshed.Message(cluster, "")
+	cluster, err = e.ClusterClient.Update(cluster)
+	if err != nil {
+		return cluster, err
+	}
+
+	if oldSecret != "" {
+		if err := e.Secrets.DeleteNamespaced(secretmigrator.SecretNamespace, oldSecret, nil); err != nil && !errors.IsNotFound(err) {
+			return cluster, fmt.Errorf("error deleting previous service account token secret %s: %w", oldSecret, err)
+		}
+	}
+	return cluster, nil
+}
@@ -1,7 +1,6 @@
 package clusteroperator
 
 import (
-	"encoding/base64"
 	"fmt"
 	"strings"
 	"time"
@@ -16,6 +15,7 @@
 	"github.com/rancher/rancher/pkg/kontainer-engine/drivers/util"
 	"github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/systemaccount"
+	"github.com/rancher/rancher/pkg/trustbundle"
 	typesDialer "github.com/rancher/rancher/pkg/types/config/dialer"
 	wranglerv1 "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
 	"github.com/sirupsen/logrus"
@@ -37,6 +37,7 @@
 type OperatorController struct {
 	ClusterEnqueueAfter  func(name string, duration time.Duration)
 	SecretsCache         wranglerv1.SecretCache
+	ConfigMapCache       wranglerv1.ConfigMapCache
 	Secrets              corev1.SecretInterface
 	TemplateCache        v3.CatalogTemplateCache
 	ProjectCache         v3.ProjectCache
@@ -49,6 +50,9 @@ type OperatorController struct {
 	DynamicClient        dynamic.NamespaceableResourceInterface
 	ClientDialer         typesDialer.Factory
 	Discovery            discovery.DiscoveryInterface
+	// HostedClusterPolicyCache is used to hold back changes that violate hosted cluster policies, which are not checked
+	// if it is nil.
+	HostedClusterPolicyCache v3.HostedClusterPolicyCache
 }
 
 func (e *OperatorController) SetUnknown(cluster *mgmtv3.Cluster, condition condition.Cond, message string) (*mgmtv3.Cluster, error) {
@@ -127,7 +131,11 @@ func (e *OperatorController) RecordCAAndAPIEndpoint(cluster *mgmtv3.Cluster) (*m
 	if !strings.HasPrefix(apiEndpoint, "https://") {
 		apiEndpoint = "https://" + apiEndpoint
 	}
-	caCert, err := addAdditionalCA(e.SecretsCache, string(caSecret.Data["ca"]))
+	bundle, err := trustbundle.Get(e.SecretsCache, e.ConfigMapCache)
+	if err != nil {
+		return cluster, err
+	}
+	caCert, err := trustbundle.AppendBase64(string(caSecret.Data["ca"]), bundle)
 	if err != nil {
 		return cluster, err
 	}
@@ -175,32 +183,12 @@ func GenerateSAToken(restConfig *rest.Config) (string, error) {
 	return util.GenerateServiceAccountToken(clientSet)
 }
 
-func addAdditionalCA(secretsCache wranglerv1.SecretCache, caCert string) (string, error) {
-	additionalCA, err := getAdditionalCA(secretsCache)
-	if err != nil {
-		return caCert, err
-	}
-	if additionalCA == nil {
-		return caCert, nil
-	}
-
-	caBytes, err := base64.StdEncoding.DecodeString(caCert)
+// RotateSAToken revokes the service account token generated by GenerateSAToken and generates a new one.
+func RotateSAToken(restConfig *rest.Config) (string, error) {
+	clientSet, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
-		return "", err
-	}
-
-	return base64.StdEncoding.EncodeToString(append(caBytes, additionalCA...)), nil
-}
-
-func getAdditionalCA(secretsCache wranglerv1.SecretCache) ([]byte, error) {
-	secret, err := secretsCache.Get(namespace.System, "tls-ca-additional")
-	if err != nil && !errors.IsNotFound(err) {
-		return nil, err
-	}
-
-	if secret == nil {
-		return nil, nil
+		return "", fmt.Errorf("error creating clientset: %v", err)
 	}
 
-	return secret.Data["ca-additional.pem"], nil
+	return util.RotateServiceAccountToken(clientSet)
 }
@@ -61,20 +61,22 @@ func Register(ctx context.Context, wContext *wrangler.Context, mgmtCtx *config.M
 
 	eksCCDynamicClient := mgmtCtx.DynamicClient.Resource(eksClusterConfigResource)
 	e := &eksOperatorController{clusteroperator.OperatorController{
-		ClusterEnqueueAfter:  wContext.Mgmt.Cluster().EnqueueAfter,
-		SecretsCache:         wContext.Core.Secret().Cache(),
-		Secrets:              mgmtCtx.Core.Secrets(""),
-		TemplateCache:        wContext.Mgmt.CatalogTemplate().Cache(),
-		ProjectCache:         wContext.Mgmt.Project().Cache(),
-		AppLister:            mgmtCtx.Project.Apps("").Controller().Lister(),
-		AppClient:            mgmtCtx.Project.Apps(""),
-		NsClient:             mgmtCtx.Core.Namespaces(""),
-		ClusterClient:        wContext.Mgmt.Cluster(),
-		CatalogManager:       mgmtCtx.CatalogManager,
-		SystemAccountManager: systemaccount.NewManager(mgmtCtx),
-		DynamicClient:        eksCCDynamicClient,
-		ClientDialer:         mgmtCtx.Dialer,
-		Discovery:            wContext.K8s.Discovery(),
+		ClusterEnqueueAfter:      wContext.Mgmt.Cluster().EnqueueAfter,
+		SecretsCache:             wContext.Core.Secret().Cache(),
+		ConfigMapCache:           wContext.Core.ConfigMap().Cache(),
+		Secrets:                  mgmtCtx.Core.Secrets(""),
+		TemplateCache:            wContext.Mgmt.CatalogTemplate().Cache(),
+		ProjectCache:             wContext.Mgmt.Project().Cache(),
+		AppLister:                mgmtCtx.Project.Apps("").Controller().Lister(),
+		AppClient:                mgmtCtx.Project.Apps(""),
+		NsClient:                 mgmtCtx.Core.Namespaces(""),
+		ClusterClient:            wContext.Mgmt.Cluster(),
+		CatalogManager:           mgmtCtx.CatalogManager,
+		SystemAccountManager:     systemaccount.NewManager(mgmtCtx),
+		DynamicClient:            eksCCDynamicClient,
+		ClientDialer:             mgmtCtx.Dialer,
+		Discovery:                wContext.K8s.Discovery(),
+		HostedClusterPolicyCache: wContext.Mgmt.HostedClusterPolicy().Cache(),
 	}}
 
 	wContext.Mgmt.Cluster().OnChange(ctx, "eks-operator-controller", e.onClusterChange)
@@ -117,6 +119,18 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 			return cluster, err
 		}
 
+		if !cluster.Spec.EKSConfig.Imported {
+			var proceed bool
+			cluster, proceed, err = e.HostedPolicyPreflight(cluster, e.launchTemplateInstanceTypes)
+			if err != nil || !proceed {
+				return cluster, err
+			}
+			cluster, proceed, err = e.QuotaPreflight(cluster, clusteroperator.EKSNodeGroupDemand(cluster.Spec.EKSConfig, nil), e.quotaChecker(cluster.Spec.EKSConfig))
+			if err != nil || !proceed {
+				return cluster, err
+			}
+		}
+
 		eksClusterConfigDynamic, err = buildEKSCCCreateObject(cluster)
 		if err != nil {
 			return cluster, err
@@ -129,13 +143,27 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 
 	}
 
-	eksClusterConfigMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&cluster.Spec.EKSConfig)
+	eksConfig, err := clusteroperator.EKSConfigWithAutoscaling(cluster.Spec.EKSConfig, cluster.Spec.HostedNodePoolAutoscaling)
+	if err != nil {
+		return cluster, err
+	}
+
+	eksClusterConfigMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(eksConfig)
 	if err != nil {
 		return cluster, err
 	}
 
 	// check for changes between EKS spec on cluster and the EKS spec on the EKSClusterConfig object
 	if !reflect.DeepEqual(eksClusterConfigMap, eksClusterConfigDynamic.Object["spec"]) {
+		var proceed bool
+		cluster, proceed, err = e.HostedPolicyPreflight(cluster, e.launchTemplateInstanceTypes)
+		if err != nil || !proceed {
+			return cluster, err
+		}
+		cluster, proceed, err = e.QuotaPreflight(cluster, clusteroperator.EKSNodeGroupDemand(eksConfig, cluster.Status.EKSStatus.UpstreamSpec), e.quotaChecker(eksConfig))
+		if err != nil || !proceed {
+			return cluster, err
+		}
 		logrus.Infof("change detected for cluster [%s], updating EKSClusterConfig", cluster.Name)
 		return e.updateEKSClusterConfig(cluster, eksClusterConfigDynamic, eksClusterConfigMap)
 	}
@@ -288,6 +316,10 @@ func (e *eksOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 			}
 		}
 
+		if clusteroperator.ServiceAccountTokenRefreshRequested(cluster) {
+			return e.RefreshServiceAccountToken(cluster, e.rotateServiceAccountToken)
+		}
+
 		managedLaunchTemplateID, _ := status["managedLaunchTemplateID"].(string)
 		if managedLaunchTemplateID != "" && cluster.Status.EKSStatus.ManagedLaunchTemplateID != managedLaunchTemplateID {
 			cluster = cluster.DeepCopy()
@@ -450,9 +482,30 @@ func (e *eksOperatorController) generateAndSetServiceAccount(cluster *mgmtv3.Clu
 	return e.ClusterClient.Update(cluster)
 }
 
+// rotateServiceAccountToken revokes the service account token of the cluster and generates a new one.
+func (e *eksOperatorController) rotateServiceAccountToken(cluster *mgmtv3.Cluster) (string, error) {
+	clusterDialer, err := e.ClientDialer.ClusterDialer(cluster.Name)
+	if err != nil {
+		return "", err
+	}
+
+	restConfig, err := e.getRestConfig(cluster, clusterDialer)
+	if err != nil {
+		return "", err
+	}
+
+	return clusteroperator.RotateSAToken(restConfig)
+}
+
 // buildEKSCCCreateObject returns an object that can be used with the kubernetes dynamic client to
-// create an EKSClusterConfig that matches the spec contained in the cluster's EKSConfig.
+// create an EKSClusterConfig that matches the spec contained in the cluster's EKSConfig, with the
+// node pool autoscaling of the cluster applied.
 func buildEKSCCCreateObject(cluster *mgmtv3.Cluster) (*unstructured.Unstructured, error) {
+	eksConfig, err := clusteroperator.EKSConfigWithAutoscaling(cluster.Spec.EKSConfig, cluster.Spec.HostedNodePoolAutoscaling)
+	if err != nil {
+		return nil, err
+	}
+
 	eksClusterConfig := eksv1.EKSClusterConfig{
 		TypeMeta: v1.TypeMeta{
 			Kind:       "EKSClusterConfig",
@@ -469,7 +522,7 @@ func buildEKSCCCreateObject(cluster *mgmtv3.Cluster) (*unstructured.Unstructured
 				},
 			},
 		},
-		Spec: *cluster.Spec.EKSConfig,
+		Spec: *eksConfig,
 	}
 
 	// convert EKS cluster config into unstructured object so it can be used with dynamic client
@@ -0,0 +1,58 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/rancher/eks-operator/controller"
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+)
+
+// launchTemplateInstanceTypes returns the instance types of the launch templates of the node groups of the EKS config
+// of the cluster spec, which are not part of the config itself.
+func (e *eksOperatorController) launchTemplateInstanceTypes(ctx context.Context, spec *apimgmtv3.ClusterSpec) ([]string, error) {
+	if spec.EKSConfig == nil {
+		return nil, nil
+	}
+
+	var ec2Service *ec2.EC2
+	var instanceTypes []string
+	for _, nodeGroup := range spec.EKSConfig.NodeGroups {
+		launchTemplate := nodeGroup.LaunchTemplate
+		if launchTemplate == nil {
+			continue
+		}
+		if ec2Service == nil {
+			sess, _, err := controller.StartAWSSessions(e.SecretsCache, *spec.EKSConfig)
+			if err != nil {
+				return nil, err
+			}
+			ec2Service = ec2.New(sess)
+		}
+
+		input := &ec2.DescribeLaunchTemplateVersionsInput{}
+		if id := aws.StringValue(launchTemplate.ID); id != "" {
+			input.LaunchTemplateId = aws.String(id)
+		} else {
+			input.LaunchTemplateName = launchTemplate.Name
+		}
+		if launchTemplate.Version != nil {
+			input.Versions = aws.StringSlice([]string{strconv.FormatInt(*launchTemplate.Version, 10)})
+		} else {
+			input.Versions = aws.StringSlice([]string{"$Default"})
+		}
+		output, err := ec2Service.DescribeLaunchTemplateVersionsWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error describing launch template of node group %s: %w", aws.StringValue(nodeGroup.NodegroupName), err)
+		}
+		for _, version := range output.LaunchTemplateVersions {
+			if version.LaunchTemplateData != nil && aws.StringValue(version.LaunchTemplateData.InstanceType) != "" {
+				instanceTypes = append(instanceTypes, *version.LaunchTemplateData.InstanceType)
+			}
+		}
+	}
+	return instanceTypes, nil
+}
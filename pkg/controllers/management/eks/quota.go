@@ -0,0 +1,101 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/rancher/eks-operator/controller"
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/controllers/management/clusteroperator"
+)
+
+const (
+	// standardOnDemandVCPUsQuotaCode is the code of the EC2 quota of the vCPUs of running on-demand instances of the
+	// standard instance families.
+	standardOnDemandVCPUsQuotaCode = "L-1216C47A"
+	// standardInstanceFamilies are the first letters of the instance types that draw from the standard on-demand quota.
+	standardInstanceFamilies = "acdhimrtz"
+)
+
+// quotaChecker returns a checker of the standard on-demand vCPU quota of the account and region of the EKS config. The
+// demand of instance types of other families is not checked.
+func (e *eksOperatorController) quotaChecker(config *eksv1.EKSClusterConfigSpec) clusteroperator.QuotaChecker {
+	return func(ctx context.Context, demand []clusteroperator.NodePoolDemand) ([]clusteroperator.Quota, error) {
+		var instanceTypes []*string
+		for _, nodeGroup := range demand {
+			if standardInstanceType(nodeGroup.MachineType) {
+				instanceTypes = append(instanceTypes, aws.String(nodeGroup.MachineType))
+			}
+		}
+		if len(instanceTypes) == 0 {
+			return nil, nil
+		}
+
+		sess, _, err := controller.StartAWSSessions(e.SecretsCache, *config)
+		if err != nil {
+			return nil, err
+		}
+		ec2Service := ec2.New(sess)
+
+		types, err := ec2Service.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{InstanceTypes: instanceTypes})
+		if err != nil {
+			return nil, fmt.Errorf("error describing instance types: %w", err)
+		}
+		vcpus := map[string]float64{}
+		for _, instanceType := range types.InstanceTypes {
+			if instanceType.VCpuInfo != nil {
+				vcpus[aws.StringValue(instanceType.InstanceType)] = float64(aws.Int64Value(instanceType.VCpuInfo.DefaultVCpus))
+			}
+		}
+		var required float64
+		for _, nodeGroup := range demand {
+			if standardInstanceType(nodeGroup.MachineType) {
+				required += vcpus[nodeGroup.MachineType] * float64(nodeGroup.Nodes)
+			}
+		}
+
+		quota, err := servicequotas.New(sess).GetServiceQuotaWithContext(ctx, &servicequotas.GetServiceQuotaInput{
+			ServiceCode: aws.String("ec2"),
+			QuotaCode:   aws.String(standardOnDemandVCPUsQuotaCode),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting on-demand vCPU quota: %w", err)
+		}
+		if quota.Quota == nil {
+			return nil, fmt.Errorf("on-demand vCPU quota not found")
+		}
+
+		var used float64
+		err = ec2Service.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{"pending", "running"})}},
+		}, func(output *ec2.DescribeInstancesOutput, _ bool) bool {
+			for _, reservation := range output.Reservations {
+				for _, instance := range reservation.Instances {
+					if instance.InstanceLifecycle != nil || instance.CpuOptions == nil || !standardInstanceType(aws.StringValue(instance.InstanceType)) {
+						continue
+					}
+					used += float64(aws.Int64Value(instance.CpuOptions.CoreCount) * aws.Int64Value(instance.CpuOptions.ThreadsPerCore))
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error describing instances: %w", err)
+		}
+
+		return []clusteroperator.Quota{{
+			Name:      fmt.Sprintf("standard on-demand vCPUs in %s", config.Region),
+			Available: aws.Float64Value(quota.Quota.Value) - used,
+			Required:  required,
+		}}, nil
+	}
+}
+
+// standardInstanceType returns true if the instance type draws from the standard on-demand vCPU quota.
+func standardInstanceType(instanceType string) bool {
+	return instanceType != "" && strings.ContainsRune(standardInstanceFamilies, rune(instanceType[0]))
+}
@@ -59,20 +59,22 @@ func Register(ctx context.Context, wContext *wrangler.Context, mgmtCtx *config.M
 
 	gkeCCDynamicClient := mgmtCtx.DynamicClient.Resource(gkeClusterConfigResource)
 	e := &gkeOperatorController{clusteroperator.OperatorController{
-		ClusterEnqueueAfter:  wContext.Mgmt.Cluster().EnqueueAfter,
-		Secrets:              mgmtCtx.Core.Secrets(""),
-		SecretsCache:         wContext.Core.Secret().Cache(),
-		TemplateCache:        wContext.Mgmt.CatalogTemplate().Cache(),
-		ProjectCache:         wContext.Mgmt.Project().Cache(),
-		AppLister:            mgmtCtx.Project.Apps("").Controller().Lister(),
-		AppClient:            mgmtCtx.Project.Apps(""),
-		NsClient:             mgmtCtx.Core.Namespaces(""),
-		ClusterClient:        wContext.Mgmt.Cluster(),
-		CatalogManager:       mgmtCtx.CatalogManager,
-		SystemAccountManager: systemaccount.NewManager(mgmtCtx),
-		DynamicClient:        gkeCCDynamicClient,
-		ClientDialer:         mgmtCtx.Dialer,
-		Discovery:            wContext.K8s.Discovery(),
+		ClusterEnqueueAfter:      wContext.Mgmt.Cluster().EnqueueAfter,
+		Secrets:                  mgmtCtx.Core.Secrets(""),
+		SecretsCache:             wContext.Core.Secret().Cache(),
+		ConfigMapCache:           wContext.Core.ConfigMap().Cache(),
+		TemplateCache:            wContext.Mgmt.CatalogTemplate().Cache(),
+		ProjectCache:             wContext.Mgmt.Project().Cache(),
+		AppLister:                mgmtCtx.Project.Apps("").Controller().Lister(),
+		AppClient:                mgmtCtx.Project.Apps(""),
+		NsClient:                 mgmtCtx.Core.Namespaces(""),
+		ClusterClient:            wContext.Mgmt.Cluster(),
+		CatalogManager:           mgmtCtx.CatalogManager,
+		SystemAccountManager:     systemaccount.NewManager(mgmtCtx),
+		DynamicClient:            gkeCCDynamicClient,
+		ClientDialer:             mgmtCtx.Dialer,
+		Discovery:                wContext.K8s.Discovery(),
+		HostedClusterPolicyCache: wContext.Mgmt.HostedClusterPolicy().Cache(),
 	}}
 
 	wContext.Mgmt.Cluster().OnChange(ctx, "gke-operator-controller", e.onClusterChange)
@@ -111,6 +113,18 @@ func (e *gkeOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 			return cluster, err
 		}
 
+		if !cluster.Spec.GKEConfig.Imported {
+			var proceed bool
+			cluster, proceed, err = e.HostedPolicyPreflight(cluster, nil)
+			if err != nil || !proceed {
+				return cluster, err
+			}
+			cluster, proceed, err = e.QuotaPreflight(cluster, clusteroperator.GKENodePoolDemand(cluster.Spec.GKEConfig, nil), e.quotaChecker(cluster.Spec.GKEConfig))
+			if err != nil || !proceed {
+				return cluster, err
+			}
+		}
+
 		gkeClusterConfigDynamic, err = buildGKECCCreateObject(cluster)
 		if err != nil {
 			return cluster, err
@@ -123,13 +137,27 @@ func (e *gkeOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 
 	}
 
-	gkeClusterConfigMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&cluster.Spec.GKEConfig)
+	gkeConfig, err := clusteroperator.GKEConfigWithAutoscaling(cluster.Spec.GKEConfig, cluster.Spec.HostedNodePoolAutoscaling)
+	if err != nil {
+		return cluster, err
+	}
+
+	gkeClusterConfigMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(gkeConfig)
 	if err != nil {
 		return cluster, err
 	}
 
 	// check for changes between gke spec on cluster and the gke spec on the gkeClusterConfig object
 	if !reflect.DeepEqual(gkeClusterConfigMap, gkeClusterConfigDynamic.Object["spec"]) {
+		var proceed bool
+		cluster, proceed, err = e.HostedPolicyPreflight(cluster, nil)
+		if err != nil || !proceed {
+			return cluster, err
+		}
+		cluster, proceed, err = e.QuotaPreflight(cluster, clusteroperator.GKENodePoolDemand(gkeConfig, cluster.Status.GKEStatus.UpstreamSpec), e.quotaChecker(gkeConfig))
+		if err != nil || !proceed {
+			return cluster, err
+		}
 		logrus.Infof("change detected for cluster [%s], updating GKEClusterConfig", cluster.Name)
 		return e.updateGKEClusterConfig(cluster, gkeClusterConfigDynamic, gkeClusterConfigMap)
 	}
@@ -231,6 +259,10 @@ func (e *gkeOperatorController) onClusterChange(key string, cluster *mgmtv3.Clus
 			}
 		}
 
+		if clusteroperator.ServiceAccountTokenRefreshRequested(cluster) {
+			return e.RefreshServiceAccountToken(cluster, e.rotateServiceAccountToken)
+		}
+
 		cluster, err = e.recordAppliedSpec(cluster)
 		if err != nil {
 			return cluster, err
@@ -371,9 +403,30 @@ func (e *gkeOperatorController) generateAndSetServiceAccount(cluster *mgmtv3.Clu
 	return e.ClusterClient.Update(cluster)
 }
 
+// rotateServiceAccountToken revokes the service account token of the cluster and generates a new one.
+func (e *gkeOperatorController) rotateServiceAccountToken(cluster *mgmtv3.Cluster) (string, error) {
+	clusterDialer, err := e.ClientDialer.ClusterDialer(cluster.Name)
+	if err != nil {
+		return "", err
+	}
+
+	restConfig, err := e.getRestConfig(cluster, clusterDialer)
+	if err != nil {
+		return "", err
+	}
+
+	return clusteroperator.RotateSAToken(restConfig)
+}
+
 // buildGKECCCreateObject returns an object that can be used with the kubernetes dynamic client to
-// create an GKEClusterConfig that matches the spec contained in the cluster's GKEConfig.
+// create an GKEClusterConfig that matches the spec contained in the cluster's GKEConfig, with the
+// node pool autoscaling of the cluster applied.
 func buildGKECCCreateObject(cluster *mgmtv3.Cluster) (*unstructured.Unstructured, error) {
+	gkeConfig, err := clusteroperator.GKEConfigWithAutoscaling(cluster.Spec.GKEConfig, cluster.Spec.HostedNodePoolAutoscaling)
+	if err != nil {
+		return nil, err
+	}
+
 	gkeClusterConfig := gkev1.GKEClusterConfig{
 		TypeMeta: v1.TypeMeta{
 			Kind:       "GKEClusterConfig",
@@ -390,7 +443,7 @@ func buildGKECCCreateObject(cluster *mgmtv3.Cluster) (*unstructured.Unstructured
 				},
 			},
 		},
-		Spec: *cluster.Spec.GKEConfig,
+		Spec: *gkeConfig,
 	}
 
 	// convert GKE cluster config into unstructured object so it can be used with dynamic client
@@ -0,0 +1,93 @@
+package gke
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	gkev1 "github.com/rancher/gke-operator/pkg/apis/gke.cattle.io/v1"
+	"github.com/rancher/gke-operator/pkg/gke"
+	"github.com/rancher/rancher/pkg/controllers/management/clusteroperator"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+const (
+	cpusQuota            = "CPUS"
+	inUseAddressesQuota  = "IN_USE_ADDRESSES"
+	googleCredentialData = "googlecredentialConfig-authEncodedJson"
+)
+
+// quotaChecker returns a checker of the regional CPU quotas, in total and per machine family, of the project of the GKE
+// config, and of its regional quota of external IP addresses if its nodes are not private.
+func (e *gkeOperatorController) quotaChecker(config *gkev1.GKEClusterConfigSpec) clusteroperator.QuotaChecker {
+	return func(ctx context.Context, demand []clusteroperator.NodePoolDemand) ([]clusteroperator.Quota, error) {
+		ns, name := parseCredential(config.GoogleCredentialSecret)
+		secret, err := e.SecretsCache.Get(ns, name)
+		if err != nil {
+			return nil, err
+		}
+		ts, err := gke.GetTokenSource(ctx, string(secret.Data[googleCredentialData]))
+		if err != nil {
+			return nil, err
+		}
+		service, err := compute.NewService(ctx, option.WithHTTPClient(oauth2.NewClient(ctx, ts)))
+		if err != nil {
+			return nil, err
+		}
+
+		regionName, zone := config.Region, config.Zone
+		if regionName == "" && !strings.Contains(zone, "-") {
+			return nil, fmt.Errorf("neither region nor zone set")
+		} else if regionName == "" {
+			regionName = zone[:strings.LastIndex(zone, "-")]
+		}
+		region, err := service.Regions.Get(config.ProjectID, regionName).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("error getting quotas of region %s: %w", regionName, err)
+		}
+		if zone == "" && len(config.Locations) > 0 {
+			zone = config.Locations[0]
+		} else if zone == "" && len(region.Zones) > 0 {
+			zone = path.Base(region.Zones[0])
+		}
+
+		required := map[string]float64{}
+		for _, nodePool := range demand {
+			machineType, err := service.MachineTypes.Get(config.ProjectID, zone, nodePool.MachineType).Context(ctx).Do()
+			if err != nil {
+				return nil, fmt.Errorf("error getting machine type %s of node pool %s: %w", nodePool.MachineType, nodePool.NodePool, err)
+			}
+			cpus := float64(machineType.GuestCpus * nodePool.Nodes)
+			required[cpusQuota] += cpus
+			family := strings.ToUpper(strings.SplitN(nodePool.MachineType, "-", 2)[0])
+			required[family+"_"+cpusQuota] += cpus
+			if config.PrivateClusterConfig == nil || !config.PrivateClusterConfig.EnablePrivateNodes {
+				required[inUseAddressesQuota] += float64(nodePool.Nodes)
+			}
+		}
+
+		var quotas []clusteroperator.Quota
+		for _, quota := range region.Quotas {
+			if amount, ok := required[quota.Metric]; ok {
+				quotas = append(quotas, clusteroperator.Quota{
+					Name:      fmt.Sprintf("%s in %s", quota.Metric, regionName),
+					Available: quota.Limit - quota.Usage,
+					Required:  amount,
+				})
+			}
+		}
+		return quotas, nil
+	}
+}
+
+// parseCredential splits the reference of a cloud credential of the form namespace:name.
+func parseCredential(ref string) (namespace string, name string) {
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) == 1 {
+		return "", parts[0]
+	}
+	return parts[0], parts[1]
+}
@@ -300,6 +300,9 @@ func (h *handler) createNewCluster(cluster *v1.Cluster, status v1.ClusterStatus,
 		})
 	}
 
+	spec.ClusterAgentDeploymentCustomization = toAgentDeploymentCustomization(cluster.Spec.ClusterAgentDeploymentCustomization)
+	spec.FleetAgentDeploymentCustomization = toAgentDeploymentCustomization(cluster.Spec.FleetAgentDeploymentCustomization)
+
 	if cluster.Spec.RKEConfig != nil {
 		if err := h.updateFeatureLockedValue(true); err != nil {
 			return nil, status, err
@@ -345,6 +348,20 @@ func (h *handler) createNewCluster(cluster *v1.Cluster, status v1.ClusterStatus,
 	}, cluster, status, newCluster)
 }
 
+// toAgentDeploymentCustomization converts the agent deployment customization of a provisioning cluster to the one of the
+// management cluster.
+func toAgentDeploymentCustomization(customization *v1.AgentDeploymentCustomization) *v3.AgentDeploymentCustomization {
+	if customization == nil {
+		return nil
+	}
+	customization = customization.DeepCopy()
+	return &v3.AgentDeploymentCustomization{
+		AppendTolerations:            customization.AppendTolerations,
+		OverrideAffinity:             customization.OverrideAffinity,
+		OverrideResourceRequirements: customization.OverrideResourceRequirements,
+	}
+}
+
 // updateStatus will update the status on the clusters.provisioning.cattle.io/v1 object.
 func (h *handler) updateStatus(objs []runtime.Object, cluster *v1.Cluster, status v1.ClusterStatus, rCluster *v3.Cluster) ([]runtime.Object, v1.ClusterStatus, error) {
 	ready := false
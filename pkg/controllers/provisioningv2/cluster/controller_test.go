@@ -3,7 +3,11 @@
 import (
 	"testing"
 
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func TestRegexp(t *testing.T) {
@@ -15,3 +19,59 @@ func TestRegexp(t *testing.T) {
 	assert.False(t, mgmtNameRegexp.MatchString("c-12345b"))
 	assert.False(t, mgmtNameRegexp.MatchString("ac-12345b"))
 }
+
+func TestToAgentDeploymentCustomization(t *testing.T) {
+	tolerations := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "agents", Effect: corev1.TaintEffectNoSchedule}}
+	affinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "agents", Operator: corev1.NodeSelectorOpExists}},
+				}},
+			},
+		},
+	}
+	resources := &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+
+	tests := []struct {
+		name          string
+		customization *v1.AgentDeploymentCustomization
+		expected      *v3.AgentDeploymentCustomization
+	}{
+		{
+			name: "unset",
+		},
+		{
+			name:          "empty",
+			customization: &v1.AgentDeploymentCustomization{},
+			expected:      &v3.AgentDeploymentCustomization{},
+		},
+		{
+			name: "tolerations, affinity and resources",
+			customization: &v1.AgentDeploymentCustomization{
+				AppendTolerations:            tolerations,
+				OverrideAffinity:             affinity,
+				OverrideResourceRequirements: resources,
+			},
+			expected: &v3.AgentDeploymentCustomization{
+				AppendTolerations:            tolerations,
+				OverrideAffinity:             affinity,
+				OverrideResourceRequirements: resources,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := toAgentDeploymentCustomization(tt.customization)
+			assert.Equal(t, tt.expected, result)
+			if result != nil && result.OverrideAffinity != nil {
+				// the customization is copied so that the management cluster does not share it with the provisioning one
+				assert.NotSame(t, tt.customization.OverrideAffinity, result.OverrideAffinity)
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@
 	fleet "github.com/rancher/fleet/pkg/apis/fleet.cattle.io/v1alpha1"
 	mgmt "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	v1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
 	mgmtcluster "github.com/rancher/rancher/pkg/cluster"
 	fleetconst "github.com/rancher/rancher/pkg/fleet"
 	fleetcontrollers "github.com/rancher/rancher/pkg/generated/controllers/fleet.cattle.io/v1alpha1"
@@ -18,16 +20,25 @@
 	"github.com/rancher/wrangler/pkg/apply"
 	"github.com/rancher/wrangler/pkg/generic"
 	"github.com/rancher/wrangler/pkg/yaml"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation"
 )
 
+const (
+	// pausedForRestoreAnnotation is set on fleet clusters that were paused while an etcd snapshot of the cluster is
+	// restored, so that only clusters paused by Rancher are resumed once the restore has finished.
+	pausedForRestoreAnnotation = "provisioning.cattle.io/fleet-paused-for-restore"
+)
+
 type handler struct {
-	clusters      mgmtcontrollers.ClusterClient
-	clustersCache mgmtcontrollers.ClusterCache
-	fleetClusters fleetcontrollers.ClusterController
-	apply         apply.Apply
+	clusters           mgmtcontrollers.ClusterClient
+	clustersCache      mgmtcontrollers.ClusterCache
+	fleetClusters      fleetcontrollers.ClusterController
+	fleetClustersCache fleetcontrollers.ClusterCache
+	apply              apply.Apply
 }
 
 // Register registers the fleetcluster controller, which is responsible for creating fleet cluster objects.
@@ -37,10 +48,11 @@ type handler struct {
 // corresponding clusters.management.cattle.io/v3 object, if one does not already exist, and vice-versa)
 func Register(ctx context.Context, clients *wrangler.Context) {
 	h := &handler{
-		clusters:      clients.Mgmt.Cluster(),
-		clustersCache: clients.Mgmt.Cluster().Cache(),
-		fleetClusters: clients.Fleet.Cluster(),
-		apply:         clients.Apply.WithCacheTypes(clients.Provisioning.Cluster()),
+		clusters:           clients.Mgmt.Cluster(),
+		clustersCache:      clients.Mgmt.Cluster().Cache(),
+		fleetClusters:      clients.Fleet.Cluster(),
+		fleetClustersCache: clients.Fleet.Cluster().Cache(),
+		apply:              clients.Apply.WithCacheTypes(clients.Provisioning.Cluster()),
 	}
 
 	rocontrollers.RegisterClusterGeneratingHandler(ctx,
@@ -56,6 +68,59 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 
 	clients.Mgmt.Cluster().OnChange(ctx, "fleet-cluster-assign", h.assignWorkspace)
 	clients.Fleet.Cluster().OnChange(ctx, "fleet-local-agent-migration", h.ensureAgentMigrated)
+	clients.RKE.RKEControlPlane().OnChange(ctx, "fleet-cluster-restore-pause", h.pauseDuringRestore)
+}
+
+// pauseDuringRestore pauses the fleet cluster of a cluster while an etcd snapshot is restored, so that GitOps does not
+// reconcile the cluster against the state it is being restored from, and resumes it once the restore has finished and
+// the control plane is ready again. The RKEControlPlane shares the name and namespace of the fleet cluster.
+func (h *handler) pauseDuringRestore(_ string, cp *rkev1.RKEControlPlane) (*rkev1.RKEControlPlane, error) {
+	if cp == nil {
+		return cp, nil
+	}
+
+	fleetCluster, err := h.fleetClustersCache.Get(cp.Namespace, cp.Name)
+	if apierrors.IsNotFound(err) {
+		return cp, nil
+	} else if err != nil {
+		return cp, err
+	}
+
+	restoring := restoreInProgress(cp)
+	_, pausedForRestore := fleetCluster.Annotations[pausedForRestoreAnnotation]
+	switch {
+	case restoring && !fleetCluster.Spec.Paused:
+		fleetCluster = fleetCluster.DeepCopy()
+		if fleetCluster.Annotations == nil {
+			fleetCluster.Annotations = map[string]string{}
+		}
+		fleetCluster.Annotations[pausedForRestoreAnnotation] = "true"
+		fleetCluster.Spec.Paused = true
+		logrus.Infof("[fleetcluster] pausing fleet cluster %s/%s during etcd snapshot restore", fleetCluster.Namespace, fleetCluster.Name)
+	case !restoring && pausedForRestore:
+		fleetCluster = fleetCluster.DeepCopy()
+		delete(fleetCluster.Annotations, pausedForRestoreAnnotation)
+		fleetCluster.Spec.Paused = false
+		logrus.Infof("[fleetcluster] resuming fleet cluster %s/%s after etcd snapshot restore", fleetCluster.Namespace, fleetCluster.Name)
+	default:
+		return cp, nil
+	}
+
+	_, err = h.fleetClusters.Update(fleetCluster)
+	return cp, err
+}
+
+// restoreInProgress returns true if an etcd snapshot restore of the control plane has started and either has not
+// finished, or has finished but the control plane has not become ready since.
+func restoreInProgress(cp *rkev1.RKEControlPlane) bool {
+	switch cp.Status.ETCDSnapshotRestorePhase {
+	case "", rkev1.ETCDSnapshotPhaseFailed, rkev1.ETCDSnapshotPhaseCancelled, rkev1.ETCDSnapshotPhasePreviewed:
+		return false
+	case rkev1.ETCDSnapshotPhaseFinished:
+		return !capr.Ready.IsTrue(cp)
+	default:
+		return true
+	}
 }
 
 func (h *handler) assignWorkspace(key string, cluster *mgmt.Cluster) (*mgmt.Cluster, error) {
@@ -135,7 +200,7 @@ func (h *handler) createCluster(cluster *v1.Cluster, status v1.ClusterStatus) ([
 		privateRepoURL = image.GetPrivateRepoURLFromCluster(cluster)
 	}
 
-	return []runtime.Object{&fleet.Cluster{
+	fleetCluster := &fleet.Cluster{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      cluster.Name,
 			Namespace: cluster.Namespace,
@@ -147,5 +212,13 @@ func (h *handler) createCluster(cluster *v1.Cluster, status v1.ClusterStatus) ([
 			AgentNamespace:   agentNamespace,
 			PrivateRepoURL:   privateRepoURL,
 		},
-	}}, status, nil
+	}
+
+	if customization := mgmtCluster.Spec.FleetAgentDeploymentCustomization; customization != nil {
+		fleetCluster.Spec.AgentTolerations = customization.AppendTolerations
+		fleetCluster.Spec.AgentAffinity = customization.OverrideAffinity
+		fleetCluster.Spec.AgentResources = customization.OverrideResourceRequirements
+	}
+
+	return []runtime.Object{fleetCluster}, status, nil
 }
@@ -0,0 +1,62 @@
+package fleetcluster
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreInProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		phase    rkev1.ETCDSnapshotPhase
+		ready    bool
+		expected bool
+	}{
+		{
+			name: "no restore",
+		},
+		{
+			name:     "shutdown",
+			phase:    rkev1.ETCDSnapshotPhaseShutdown,
+			ready:    true,
+			expected: true,
+		},
+		{
+			name:     "restarting cluster",
+			phase:    rkev1.ETCDSnapshotPhaseRestartCluster,
+			expected: true,
+		},
+		{
+			name:     "finished but not ready",
+			phase:    rkev1.ETCDSnapshotPhaseFinished,
+			expected: true,
+		},
+		{
+			name:  "finished and ready",
+			phase: rkev1.ETCDSnapshotPhaseFinished,
+			ready: true,
+		},
+		{
+			name:  "failed",
+			phase: rkev1.ETCDSnapshotPhaseFailed,
+		},
+		{
+			name:  "cancelled",
+			phase: rkev1.ETCDSnapshotPhaseCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &rkev1.RKEControlPlane{}
+			cp.Status.ETCDSnapshotRestorePhase = tt.phase
+			if tt.ready {
+				capr.Ready.True(cp)
+			}
+			assert.Equal(t, tt.expected, restoreInProgress(cp))
+		})
+	}
+}
@@ -5,6 +5,7 @@
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/rancher/lasso/pkg/dynamic"
 	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
@@ -26,12 +27,15 @@
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 const (
 	byNodeInfra                       = "by-node-infra"
+	byClusterClass                    = "by-cluster-class"
 	restoreRKEConfigKubernetesVersion = "kubernetesVersion"
 	restoreRKEConfigAll               = "all"
 	restoreRKEConfigNone              = "none"
@@ -73,6 +77,8 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 
 	clients.Dynamic.OnChange(ctx, "rke-dynamic", matchRKENodeGroup, h.infraWatch)
 	clients.Provisioning.Cluster().Cache().AddIndexer(byNodeInfra, byNodeInfraIndex)
+	clients.Dynamic.OnChange(ctx, "rke-cluster-class", matchClusterClass, h.clusterClassWatch)
+	clients.Provisioning.Cluster().Cache().AddIndexer(byClusterClass, byClusterClassIndex)
 
 	rocontrollers.RegisterClusterGeneratingHandler(ctx,
 		clients.Provisioning.Cluster(),
@@ -166,6 +172,41 @@ func (h *handler) infraWatch(obj runtime.Object) (runtime.Object, error) {
 	return obj, nil
 }
 
+func byClusterClassIndex(obj *rancherv1.Cluster) ([]string, error) {
+	if obj.Spec.RKEConfig == nil || obj.Spec.RKEConfig.Topology == nil || obj.Spec.RKEConfig.Topology.Class == "" {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("%s/%s", obj.Namespace, obj.Spec.RKEConfig.Topology.Class)}, nil
+}
+
+func matchClusterClass(gvk schema.GroupVersionKind) bool {
+	return gvk == capi.GroupVersion.WithKind("ClusterClass")
+}
+
+// clusterClassWatch enqueues the clusters whose topology refers to the ClusterClass, so that their machine deployments
+// are generated again from its worker classes when it changes.
+func (h *handler) clusterClassWatch(obj runtime.Object) (runtime.Object, error) {
+	if obj == nil {
+		return nil, nil
+	}
+
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters, err := h.clusterCache.GetByIndex(byClusterClass, fmt.Sprintf("%s/%s", meta.GetNamespace(), meta.GetName()))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cluster := range clusters {
+		h.clusterController.Enqueue(cluster.Namespace, cluster.Name)
+	}
+
+	return obj, nil
+}
+
 func (h *handler) OnChange(_ string, cluster *rancherv1.Cluster) (*rancherv1.Cluster, error) {
 	if cluster == nil || !cluster.DeletionTimestamp.IsZero() || cluster.Spec.RKEConfig == nil {
 		return cluster, nil
@@ -215,6 +256,18 @@ func (h *handler) OnChange(_ string, cluster *rancherv1.Cluster) (*rancherv1.Clu
 	return cluster, nil
 }
 
+// restoresRKEConfig returns true if the cluster must be updated with the Kubernetes version or RKE config recorded on the
+// snapshot of the etcd snapshot restore. Previewed restores never update the cluster, the planner renders their plans
+// against the spec of the snapshot instead.
+func restoresRKEConfig(restore *rkev1.ETCDSnapshotRestore) bool {
+	return restore != nil &&
+		restore.Name != "" &&
+		!restore.Cancel &&
+		!restore.PreviewOnly &&
+		restore.RestoreRKEConfig != "" &&
+		restore.RestoreRKEConfig != restoreRKEConfigNone
+}
+
 func (h *handler) findSnapshotClusterSpec(snapshotNamespace, snapshotName string) (*rancherv1.ClusterSpec, error) {
 	snapshot, err := h.etcdSnapshotCache.Get(snapshotNamespace, snapshotName)
 	if err != nil {
@@ -297,10 +350,7 @@ func (h *handler) OnRancherClusterChange(obj *rancherv1.Cluster, status rancherv
 	// If the rkecontrolplane is not nil, we can check it to determine action items.
 	if rkeCP != nil {
 		// If EtcdSnapshotRestore is not nil, we need to check to see if we need to update the cluster object it.
-		if obj.Spec.RKEConfig.ETCDSnapshotRestore != nil &&
-			obj.Spec.RKEConfig.ETCDSnapshotRestore.Name != "" &&
-			obj.Spec.RKEConfig.ETCDSnapshotRestore.RestoreRKEConfig != "" &&
-			obj.Spec.RKEConfig.ETCDSnapshotRestore.RestoreRKEConfig != restoreRKEConfigNone {
+		if restoresRKEConfig(obj.Spec.RKEConfig.ETCDSnapshotRestore) {
 			logrus.Debugf("rkecluster %s/%s: Reconciling rkeconfig against specified etcd restore snapshot metadata", obj.Namespace, obj.Name)
 			if !equality.Semantic.DeepEqual(rkeCP.Status.ETCDSnapshotRestore, obj.Spec.RKEConfig.ETCDSnapshotRestore) {
 				clusterSpec, err := h.findSnapshotClusterSpec(obj.Namespace, obj.Spec.RKEConfig.ETCDSnapshotRestore.Name)
@@ -335,6 +385,12 @@ func (h *handler) OnRancherClusterChange(obj *rancherv1.Cluster, status rancherv
 
 		reconcileCondition(&status, capr.Updated, rkeCP, capr.Ready)
 		reconcileCondition(&status, capr.Provisioned, rkeCP, capr.Ready)
+		reconcileCondition(&status, capr.Validated, rkeCP, capr.Validated)
+		reconcileCondition(&status, capr.ImagesAllowed, rkeCP, capr.ImagesAllowed)
+		reconcileCondition(&status, capr.InstructionsAllowed, rkeCP, capr.InstructionsAllowed)
+		reconcileCondition(&status, capr.FileSourcesResolved, rkeCP, capr.FileSourcesResolved)
+		reconcileCondition(&status, capr.PlansRendered, rkeCP, capr.PlansRendered)
+		reconcileProvisioningMilestones(obj, &status, rkeCP, time.Now())
 
 		// If the Stable condition is not true, then copy the Ready condition from the rkeControlPlane to the v1.Clusters object
 		// Otherwise, use the v3 clusters Ready condition. Note that we use `IsTrue` here because `IsFalse` specifically looks
@@ -356,10 +412,40 @@ func (h *handler) OnRancherClusterChange(obj *rancherv1.Cluster, status rancherv
 		}
 	}
 
+	if obj.Spec.RKEConfig.ValidateOnly {
+		provisioned, err := h.provisioned(obj)
+		if err != nil {
+			return nil, status, err
+		}
+		if provisioned {
+			// scaling the machine deployments of a provisioned cluster to zero would delete its machines, so the
+			// cluster is reconciled as if it was not validate-only
+			logrus.Warnf("rkecluster %s/%s: ignoring validateOnly as the cluster was already provisioned", obj.Namespace, obj.Name)
+			obj = obj.DeepCopy()
+			obj.Spec.RKEConfig.ValidateOnly = false
+			capr.PlansRendered.False(&status)
+			capr.PlansRendered.Reason(&status, "AlreadyProvisioned")
+			capr.PlansRendered.Message(&status, "validateOnly is ignored as the cluster was already provisioned, unset it")
+		}
+	}
+
 	objs, err := objects(obj, h.dynamic, h.dynamicSchema, h.secretCache)
 	return objs, status, err
 }
 
+// provisioned returns true if the cluster was provisioned before or has machines. validateOnly is only honored before a
+// cluster is first provisioned.
+func (h *handler) provisioned(cluster *rancherv1.Cluster) (bool, error) {
+	if capr.Provisioned.IsTrue(cluster) {
+		return true, nil
+	}
+	machines, err := h.capiMachineCache.List(cluster.Namespace, labels.SelectorFromSet(labels.Set{capi.ClusterLabelName: cluster.Name}))
+	if err != nil {
+		return false, err
+	}
+	return len(machines) > 0, nil
+}
+
 // getRKEControlPlaneForCluster retrieves the rkecontrolplane that corresponds to a provisioning cluster object.
 // If it cannot retrieve the corresponding CAPI cluster (is not found), if the capi cluster controlplane ref is not
 // an rkecontrolplane, or the rkecontrolplane object can't be found and the cluster is deleting, it returns nil, nil.
@@ -3,11 +3,116 @@
 import (
 	"testing"
 
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
 	"github.com/rancher/wrangler/pkg/condition"
 	"github.com/rancher/wrangler/pkg/genericcondition"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
+type machineCacheMock struct {
+	capicontrollers.MachineCache
+	machines []*capi.Machine
+}
+
+func (m *machineCacheMock) List(namespace string, selector labels.Selector) ([]*capi.Machine, error) {
+	var result []*capi.Machine
+	for _, machine := range m.machines {
+		if machine.Namespace == namespace && selector.Matches(labels.Set(machine.Labels)) {
+			result = append(result, machine)
+		}
+	}
+	return result, nil
+}
+
+func TestProvisioned(t *testing.T) {
+	machine := &capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "fleet-default", Labels: map[string]string{capi.ClusterLabelName: "test"}},
+	}
+	provisionedCluster := &rancherv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"}}
+	capr.Provisioned.True(provisionedCluster)
+
+	tests := []struct {
+		name     string
+		cluster  *rancherv1.Cluster
+		machines []*capi.Machine
+		expected bool
+	}{
+		{
+			name:    "new cluster",
+			cluster: &rancherv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"}},
+		},
+		{
+			name:     "cluster with machines",
+			cluster:  &rancherv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"}},
+			machines: []*capi.Machine{machine},
+			expected: true,
+		},
+		{
+			name:     "machines of other cluster",
+			cluster:  &rancherv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "fleet-default"}},
+			machines: []*capi.Machine{machine},
+		},
+		{
+			name:     "provisioned cluster without machines",
+			cluster:  provisionedCluster,
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &handler{capiMachineCache: &machineCacheMock{machines: tt.machines}}
+			provisioned, err := h.provisioned(tt.cluster)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, provisioned)
+		})
+	}
+}
+
+func TestRestoresRKEConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		restore  *rkev1.ETCDSnapshotRestore
+		expected bool
+	}{
+		{
+			name: "no restore",
+		},
+		{
+			name:     "kubernetes version",
+			restore:  &rkev1.ETCDSnapshotRestore{Name: "snapshot", RestoreRKEConfig: "kubernetesVersion"},
+			expected: true,
+		},
+		{
+			name:     "all",
+			restore:  &rkev1.ETCDSnapshotRestore{Name: "snapshot", RestoreRKEConfig: "all"},
+			expected: true,
+		},
+		{
+			name:    "none",
+			restore: &rkev1.ETCDSnapshotRestore{Name: "snapshot", RestoreRKEConfig: "none"},
+		},
+		{
+			name:    "cancelled",
+			restore: &rkev1.ETCDSnapshotRestore{Name: "snapshot", RestoreRKEConfig: "all", Cancel: true},
+		},
+		{
+			name:    "preview",
+			restore: &rkev1.ETCDSnapshotRestore{Name: "snapshot", RestoreRKEConfig: "all", PreviewOnly: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, restoresRKEConfig(tt.restore))
+		})
+	}
+}
+
 func TestProvisioningClusterController_reconcileConditions(t *testing.T) {
 	type dummyObject struct {
 		Status struct {
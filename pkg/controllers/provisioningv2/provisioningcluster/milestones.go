@@ -0,0 +1,71 @@
+package provisioningcluster
+
+import (
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// provisioningMilestones are the milestones of the provisioning of a cluster, in the order they are expected to be
+// reached.
+var provisioningMilestones = []struct {
+	name    string
+	reached func(cp *rkev1.RKEControlPlane) bool
+}{
+	{
+		// the init node of the cluster was bootstrapped and its join URL is known
+		name:    "Initialized",
+		reached: func(cp *rkev1.RKEControlPlane) bool { return cp.Status.Initialized },
+	},
+	{
+		// the etcd and control plane nodes of the cluster were bootstrapped
+		name:    "Bootstrapped",
+		reached: func(cp *rkev1.RKEControlPlane) bool { return capr.Bootstrapped.IsTrue(cp) },
+	},
+	{
+		// the cluster agent connected to Rancher
+		name:    "AgentConnected",
+		reached: func(cp *rkev1.RKEControlPlane) bool { return cp.Status.AgentConnected },
+	},
+	{
+		// the machines of the cluster were provisioned with their plans
+		name:    "Ready",
+		reached: func(cp *rkev1.RKEControlPlane) bool { return cp.Status.Ready },
+	},
+}
+
+// reconcileProvisioningMilestones records the milestones that the control plane of the cluster reached for the first
+// time in the status of the cluster, with the time elapsed since the cluster was created, and observes the elapsed time
+// in the provisioning milestone metrics. Milestones are never recorded again once recorded. Clusters that were already
+// provisioned before the milestones were tracked are skipped, as the elapsed times would not reflect their provisioning.
+func reconcileProvisioningMilestones(cluster *rancherv1.Cluster, status *rancherv1.ClusterStatus, cp *rkev1.RKEControlPlane, now time.Time) {
+	last := provisioningMilestones[len(provisioningMilestones)-1]
+	if len(status.ProvisioningMilestones) == 0 && last.reached(cp) {
+		return
+	}
+
+	recorded := map[string]bool{}
+	for _, milestone := range status.ProvisioningMilestones {
+		recorded[milestone.Name] = true
+	}
+
+	for _, milestone := range provisioningMilestones {
+		if recorded[milestone.name] || !milestone.reached(cp) {
+			continue
+		}
+		elapsed := now.Sub(cluster.CreationTimestamp.Time).Round(time.Second)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		status.ProvisioningMilestones = append(status.ProvisioningMilestones, rancherv1.ProvisioningMilestone{
+			Name:    milestone.name,
+			Time:    metav1.NewTime(now),
+			Elapsed: metav1.Duration{Duration: elapsed},
+		})
+		metrics.ObserveCAPRClusterProvisioningMilestone(milestone.name, elapsed)
+	}
+}
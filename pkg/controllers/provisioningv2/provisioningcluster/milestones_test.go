@@ -0,0 +1,52 @@
+package provisioningcluster
+
+import (
+	"testing"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReconcileProvisioningMilestones(t *testing.T) {
+	created := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	cluster := &rancherv1.Cluster{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}}
+	cp := &rkev1.RKEControlPlane{}
+	var status rancherv1.ClusterStatus
+
+	reconcileProvisioningMilestones(cluster, &status, cp, created.Add(time.Minute))
+	assert.Empty(t, status.ProvisioningMilestones)
+
+	cp.Status.Initialized = true
+	capr.Bootstrapped.True(cp)
+	reconcileProvisioningMilestones(cluster, &status, cp, created.Add(5*time.Minute))
+	if assert.Len(t, status.ProvisioningMilestones, 2) {
+		assert.Equal(t, "Initialized", status.ProvisioningMilestones[0].Name)
+		assert.Equal(t, "Bootstrapped", status.ProvisioningMilestones[1].Name)
+		assert.Equal(t, 5*time.Minute, status.ProvisioningMilestones[1].Elapsed.Duration)
+	}
+
+	cp.Status.AgentConnected = true
+	cp.Status.Ready = true
+	reconcileProvisioningMilestones(cluster, &status, cp, created.Add(12*time.Minute))
+	if assert.Len(t, status.ProvisioningMilestones, 4) {
+		assert.Equal(t, 5*time.Minute, status.ProvisioningMilestones[0].Elapsed.Duration)
+		assert.Equal(t, "Ready", status.ProvisioningMilestones[3].Name)
+		assert.Equal(t, 12*time.Minute, status.ProvisioningMilestones[3].Elapsed.Duration)
+		assert.True(t, status.ProvisioningMilestones[3].Time.Time.Equal(created.Add(12*time.Minute)))
+	}
+
+	cp.Status.Ready = false
+	reconcileProvisioningMilestones(cluster, &status, cp, created.Add(time.Hour))
+	cp.Status.Ready = true
+	reconcileProvisioningMilestones(cluster, &status, cp, created.Add(2*time.Hour))
+	assert.Len(t, status.ProvisioningMilestones, 4)
+
+	// clusters that were provisioned before milestones were tracked are not recorded
+	status = rancherv1.ClusterStatus{}
+	reconcileProvisioningMilestones(cluster, &status, cp, created.Add(24*time.Hour))
+	assert.Empty(t, status.ProvisioningMilestones)
+}
@@ -32,9 +32,12 @@
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	capi "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
+const harvesterConfigKind = "HarvesterConfig"
+
 func getInfraRef(rkeCluster *rkev1.RKECluster) *corev1.ObjectReference {
 	gvk, _ := gvk.Get(rkeCluster)
 	infraRef := &corev1.ObjectReference{
@@ -123,7 +126,7 @@ func takeOwnership(dynamic *dynamic.Controller, cluster *rancherv1.Cluster, node
 	return err
 }
 
-func toMachineTemplate(machinePoolName string, cluster *rancherv1.Cluster, machinePool rancherv1.RKEMachinePool,
+func toMachineTemplate(machinePoolName string, cluster *rancherv1.Cluster, machinePool rancherv1.RKEMachinePool, zone string,
 	dynamic *dynamic.Controller, secrets v1.SecretCache) (*unstructured.Unstructured, error) {
 	apiVersion := machinePool.NodeConfig.APIVersion
 	kind := machinePool.NodeConfig.Kind
@@ -158,6 +161,23 @@ func toMachineTemplate(machinePoolName string, cluster *rancherv1.Cluster, machi
 
 	pruneBySchema(machinePoolData, spec)
 
+	if err := setHarvesterConfig(machinePool, kind, machinePoolData); err != nil {
+		return nil, err
+	}
+
+	driver := strings.ToLower(strings.TrimSuffix(kind, "Config"))
+	if err := machineprovision.MergeCloudInit(driver, machinePoolData, machinePool.CloudInit); err != nil {
+		return nil, fmt.Errorf("machinePool [%s]: %w", machinePool.Name, err)
+	}
+
+	if err := machineprovision.SetPlacement(driver, machinePoolData, machinePool.Placement, machinePoolName); err != nil {
+		return nil, fmt.Errorf("machinePool [%s]: %w", machinePool.Name, err)
+	}
+
+	if err := machineprovision.SetZone(driver, machinePoolData, zone); err != nil {
+		return nil, fmt.Errorf("machinePool [%s]: %w", machinePool.Name, err)
+	}
+
 	commonData, err := convert.EncodeToMap(machinePool.RKECommonNodeConfig)
 	if err != nil {
 		return nil, err
@@ -170,7 +190,7 @@ func toMachineTemplate(machinePoolName string, cluster *rancherv1.Cluster, machi
 	}
 
 	if secretName != "" {
-		_, err := machineprovision.GetCloudCredentialSecret(secrets, cluster.Namespace, secretName)
+		_, err := machineprovision.GetCloudCredentialSecret(secrets, cluster.Namespace, secretName, cluster.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -209,6 +229,47 @@ func toMachineTemplate(machinePoolName string, cluster *rancherv1.Cluster, machi
 	return ustr, nil
 }
 
+// setHarvesterConfig renders the Harvester devices and hugepages of the machine pool into the machine template data, from
+// which they are passed to the Harvester machine driver as the pciDevices and hugepagesPageSize flags. A device is
+// listed once for every instance of it requested.
+func setHarvesterConfig(machinePool rancherv1.RKEMachinePool, kind string, machinePoolData data.Object) error {
+	config := machinePool.Harvester
+	if config == nil {
+		return nil
+	}
+	if kind != harvesterConfigKind {
+		return fmt.Errorf("harvester settings of machinePool [%s] require a %s, not a %s", machinePool.Name, harvesterConfigKind, kind)
+	}
+
+	var devices []interface{}
+	for _, device := range config.PCIDevices {
+		if device.ResourceName == "" {
+			return fmt.Errorf("missing resourceName of PCI device of machinePool [%s]", machinePool.Name)
+		}
+		count := device.Count
+		if count == 0 {
+			count = 1
+		} else if count < 0 {
+			return fmt.Errorf("invalid count [%d] of PCI device [%s] of machinePool [%s]", device.Count, device.ResourceName, machinePool.Name)
+		}
+		for i := 0; i < count; i++ {
+			devices = append(devices, device.ResourceName)
+		}
+	}
+	if len(devices) > 0 {
+		machinePoolData.Set("pciDevices", devices)
+	}
+
+	switch config.HugepagesPageSize {
+	case "":
+	case "2Mi", "1Gi":
+		machinePoolData.Set("hugepagesPageSize", config.HugepagesPageSize)
+	default:
+		return fmt.Errorf("invalid hugepagesPageSize [%s] of machinePool [%s], must be 2Mi or 1Gi", config.HugepagesPageSize, machinePool.Name)
+	}
+	return nil
+}
+
 func populateHostnameLengthLimitAnnotation(mp rancherv1.RKEMachinePool, cluster *rancherv1.Cluster, annotations map[string]string) error {
 	if cluster == nil {
 		return errors.New("cannot add hostname length limit annotation for nil cluster")
@@ -275,7 +336,8 @@ func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, d
 		return nil, nil
 	}
 
-	if len(cluster.Spec.RKEConfig.MachinePools) > 0 {
+	topology := cluster.Spec.RKEConfig.Topology
+	if len(cluster.Spec.RKEConfig.MachinePools) > 0 || (topology != nil && len(topology.MachineDeployments) > 0) {
 		result = append(result, &rkev1.RKEBootstrapTemplate{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: cluster.Namespace,
@@ -317,23 +379,20 @@ func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, d
 		var (
 			machineDeploymentName = name.SafeConcatName(cluster.Name, machinePool.Name)
 			infraRef              corev1.ObjectReference
+			machineConfig         = machinePool.NodeConfig.APIVersion == "" || machinePool.NodeConfig.APIVersion == "rke-machine-config.cattle.io/v1"
 		)
 
-		if machinePool.NodeConfig.APIVersion == "" || machinePool.NodeConfig.APIVersion == "rke-machine-config.cattle.io/v1" {
-			machineTemplate, err := toMachineTemplate(machineDeploymentName, cluster, machinePool, dynamic, secrets)
+		if !machineConfig {
+			infraRef = *machinePool.NodeConfig
+		} else if len(machinePool.Zones) == 0 {
+			// the machine templates of pools that are spread across zones are generated for each zone below
+			machineTemplate, err := toMachineTemplate(machineDeploymentName, cluster, machinePool, "", dynamic, secrets)
 			if err != nil {
 				return nil, err
 			}
 
 			result = append(result, machineTemplate)
-			infraRef = corev1.ObjectReference{
-				APIVersion: machineTemplate.GetAPIVersion(),
-				Kind:       machineTemplate.GetKind(),
-				Namespace:  machineTemplate.GetNamespace(),
-				Name:       machineTemplate.GetName(),
-			}
-		} else {
-			infraRef = *machinePool.NodeConfig
+			infraRef = machineTemplateRef(machineTemplate)
 		}
 
 		if machinePool.MachineOS == "" {
@@ -447,18 +506,104 @@ func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, d
 			}
 		}
 
-		result = append(result, machineDeployment)
+		machineDeployments, err := spreadAcrossZones(machineDeployment, machinePool)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, machineDeployment := range machineDeployments {
+			if zone := machineDeployment.Spec.Template.Labels[capr.RKEMachinePoolZoneLabel]; machineConfig && zone != "" {
+				// machine drivers do not know about failure domains, the zone is set in the machine template instead
+				machineTemplate, err := toMachineTemplate(machineDeployment.Name, cluster, machinePool, zone, dynamic, secrets)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, machineTemplate)
+				machineDeployment.Spec.Template.Spec.InfrastructureRef = machineTemplateRef(machineTemplate)
+			}
+			if cluster.Spec.RKEConfig.ValidateOnly {
+				// the machine deployments of a validate-only cluster are generated for review, but must not create machines
+				machineDeployment.Spec.Replicas = &[]int32{0}[0]
+			}
+			result = append(result, machineDeployment)
+
+			// if a health check timeout was specified create health checks for this machine pool
+			if machinePool.UnhealthyNodeTimeout != nil && machinePool.UnhealthyNodeTimeout.Duration > 0 {
+				hc := deploymentHealthChecks(machineDeployment, machinePool)
+				result = append(result, hc)
+			}
+		}
+	}
 
-		// if a health check timeout was specified create health checks for this machine pool
-		if machinePool.UnhealthyNodeTimeout != nil && machinePool.UnhealthyNodeTimeout.Duration > 0 {
-			hc := deploymentHealthChecks(machineDeployment, machinePool)
-			result = append(result, hc)
+	if topology != nil {
+		clusterClass, err := getClusterClass(dynamic, cluster)
+		if err != nil {
+			return nil, err
+		}
+		topologyMachineDeployments, err := topologyMachineDeployments(cluster, capiCluster, clusterClass, bootstrapName)
+		if err != nil {
+			return nil, err
 		}
+		result = append(result, topologyMachineDeployments...)
 	}
 
 	return result, nil
 }
 
+// machineTemplateRef returns the reference of a machine deployment to the machine template.
+func machineTemplateRef(machineTemplate *unstructured.Unstructured) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		APIVersion: machineTemplate.GetAPIVersion(),
+		Kind:       machineTemplate.GetKind(),
+		Namespace:  machineTemplate.GetNamespace(),
+		Name:       machineTemplate.GetName(),
+	}
+}
+
+// spreadAcrossZones returns a copy of the machine deployment for each zone of the machine pool, with the machines of
+// each copy assigned to the failure domain of its zone and the quantity of the machine pool divided between them. The
+// copy of the first zone keeps the name of the machine deployment, so that the machines of an existing pool are not
+// replaced when it is spread across zones, the copies of the other zones are suffixed with their zone. If the machine
+// pool has no zones, the machine deployment is returned as is.
+func spreadAcrossZones(machineDeployment *capi.MachineDeployment, machinePool rancherv1.RKEMachinePool) ([]*capi.MachineDeployment, error) {
+	if len(machinePool.Zones) == 0 {
+		return []*capi.MachineDeployment{machineDeployment}, nil
+	}
+
+	zones := map[string]bool{}
+	var result []*capi.MachineDeployment
+	for i, zone := range machinePool.Zones {
+		if errs := validation.IsDNS1123Label(zone); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid zone [%s] for machinePool [%s]: %s", zone, machinePool.Name, strings.Join(errs, ", "))
+		}
+		if zones[zone] {
+			return nil, fmt.Errorf("duplicate zone [%s] used in machinePool [%s]", zone, machinePool.Name)
+		}
+		zones[zone] = true
+
+		zoneDeployment := machineDeployment.DeepCopy()
+		if i > 0 {
+			zoneDeployment.Name = name.SafeConcatName(machineDeployment.Name, zone)
+		}
+		if machinePool.Quantity != nil {
+			replicas := *machinePool.Quantity / int32(len(machinePool.Zones))
+			if int32(i) < *machinePool.Quantity%int32(len(machinePool.Zones)) {
+				replicas++
+			}
+			zoneDeployment.Spec.Replicas = &replicas
+		}
+		if zoneDeployment.Labels == nil {
+			zoneDeployment.Labels = map[string]string{}
+		}
+		zoneDeployment.Labels[capr.RKEMachinePoolZoneLabel] = zone
+		zoneDeployment.Spec.Template.Labels[capi.MachineDeploymentLabelName] = zoneDeployment.Name
+		zoneDeployment.Spec.Template.Labels[capr.RKEMachinePoolZoneLabel] = zone
+		zoneDeployment.Spec.Template.Spec.FailureDomain = &[]string{zone}[0]
+		result = append(result, zoneDeployment)
+	}
+	return result, nil
+}
+
 // deploymentHealthChecks Health checks will mark a machine as failed if it has any of the conditions below for the duration of the given timeout. https://cluster-api.sigs.k8s.io/tasks/healthcheck.html#what-is-a-machinehealthcheck
 func deploymentHealthChecks(machineDeployment *capi.MachineDeployment, machinePool rancherv1.RKEMachinePool) *capi.MachineHealthCheck {
 	var maxUnhealthy *intstr.IntOrString
@@ -524,8 +669,11 @@ func rkeControlPlane(cluster *rancherv1.Cluster) (*rkev1.RKEControlPlane, error)
 	// set the corresponding specification for various operations to nil as these cause unnecessary reconciliation.
 	filteredClusterSpec.RKEConfig.ETCDSnapshotRestore = nil
 	filteredClusterSpec.RKEConfig.ETCDSnapshotCreate = nil
+	filteredClusterSpec.RKEConfig.ETCDSnapshotUpload = nil
+	filteredClusterSpec.RKEConfig.ETCDSnapshotSchedule = nil
 	filteredClusterSpec.RKEConfig.RotateEncryptionKeys = nil
 	filteredClusterSpec.RKEConfig.RotateCertificates = nil
+	filteredClusterSpec.RKEConfig.SanityProbes = nil
 	b64GZCluster, err := capr.CompressInterface(filteredClusterSpec)
 	if err != nil {
 		logrus.Errorf("cluster: %s/%s : error while gz/b64 encoding cluster specification: %v", cluster.Namespace, cluster.Name, err)
@@ -548,6 +696,8 @@ func rkeControlPlane(cluster *rancherv1.Cluster) (*rkev1.RKEControlPlane, error)
 			LocalClusterAuthEndpoint: *cluster.Spec.LocalClusterAuthEndpoint.DeepCopy(),
 			ETCDSnapshotRestore:      rkeConfig.ETCDSnapshotRestore,
 			ETCDSnapshotCreate:       rkeConfig.ETCDSnapshotCreate,
+			ETCDSnapshotUpload:       rkeConfig.ETCDSnapshotUpload,
+			ETCDSnapshotSchedule:     rkeConfig.ETCDSnapshotSchedule,
 			RotateCertificates:       rkeConfig.RotateCertificates,
 			RotateEncryptionKeys:     rkeConfig.RotateEncryptionKeys,
 			KubernetesVersion:        cluster.Spec.KubernetesVersion,
@@ -4,7 +4,11 @@
 	"testing"
 
 	provv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/pkg/data"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 func TestPopulateHostnameLengthLimitAnnotation(t *testing.T) {
@@ -94,3 +98,156 @@ func TestPopulateHostnameLengthLimitAnnotation(t *testing.T) {
 		})
 	}
 }
+
+func TestSpreadAcrossZones(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+
+	tests := []struct {
+		name             string
+		machinePool      provv1.RKEMachinePool
+		expectedNames    []string
+		expectedReplicas []*int32
+		expectedErr      string
+	}{
+		{
+			name:             "no zones",
+			machinePool:      provv1.RKEMachinePool{Quantity: int32Ptr(3)},
+			expectedNames:    []string{"c-pool"},
+			expectedReplicas: []*int32{int32Ptr(3)},
+		},
+		{
+			name:             "quantity divided evenly",
+			machinePool:      provv1.RKEMachinePool{Quantity: int32Ptr(4), Zones: []string{"a", "b"}},
+			expectedNames:    []string{"c-pool", "c-pool-b"},
+			expectedReplicas: []*int32{int32Ptr(2), int32Ptr(2)},
+		},
+		{
+			name:             "remainder assigned to first zones",
+			machinePool:      provv1.RKEMachinePool{Quantity: int32Ptr(5), Zones: []string{"a", "b", "c"}},
+			expectedNames:    []string{"c-pool", "c-pool-b", "c-pool-c"},
+			expectedReplicas: []*int32{int32Ptr(2), int32Ptr(2), int32Ptr(1)},
+		},
+		{
+			name:             "no quantity",
+			machinePool:      provv1.RKEMachinePool{Zones: []string{"a", "b"}},
+			expectedNames:    []string{"c-pool", "c-pool-b"},
+			expectedReplicas: []*int32{nil, nil},
+		},
+		{
+			name:        "duplicate zone",
+			machinePool: provv1.RKEMachinePool{Zones: []string{"a", "a"}},
+			expectedErr: "duplicate zone [a] used in machinePool [pool]",
+		},
+		{
+			name:        "invalid zone",
+			machinePool: provv1.RKEMachinePool{Zones: []string{"Zone_A"}},
+			expectedErr: "invalid zone [Zone_A] for machinePool [pool]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.machinePool.Name = "pool"
+			machineDeployment := &capi.MachineDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "c-pool"},
+				Spec: capi.MachineDeploymentSpec{
+					Replicas: tt.machinePool.Quantity,
+					Template: capi.MachineTemplateSpec{
+						ObjectMeta: capi.ObjectMeta{
+							Labels: map[string]string{capi.MachineDeploymentLabelName: "c-pool"},
+						},
+					},
+				},
+			}
+
+			result, err := spreadAcrossZones(machineDeployment, tt.machinePool)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, result, len(tt.expectedNames))
+			for i, md := range result {
+				assert.Equal(t, tt.expectedNames[i], md.Name)
+				assert.Equal(t, tt.expectedReplicas[i], md.Spec.Replicas)
+				assert.Equal(t, md.Name, md.Spec.Template.Labels[capi.MachineDeploymentLabelName])
+				if len(tt.machinePool.Zones) == 0 {
+					assert.Nil(t, md.Spec.Template.Spec.FailureDomain)
+					continue
+				}
+				zone := tt.machinePool.Zones[i]
+				assert.Equal(t, &zone, md.Spec.Template.Spec.FailureDomain)
+				assert.Equal(t, zone, md.Labels[capr.RKEMachinePoolZoneLabel])
+				assert.Equal(t, zone, md.Spec.Template.Labels[capr.RKEMachinePoolZoneLabel])
+			}
+		})
+	}
+}
+
+func TestSetHarvesterConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		kind        string
+		harvester   *provv1.HarvesterMachinePoolConfig
+		expected    data.Object
+		expectedErr string
+	}{
+		{
+			name:     "no harvester config",
+			kind:     "Amazonec2Config",
+			expected: data.Object{},
+		},
+		{
+			name: "devices and hugepages",
+			kind: "HarvesterConfig",
+			harvester: &provv1.HarvesterMachinePoolConfig{
+				PCIDevices: []provv1.HarvesterPCIDevice{
+					{ResourceName: "nvidia.com/TU104GL_TESLA_T4", Count: 2},
+					{ResourceName: "intel.com/QAT"},
+				},
+				HugepagesPageSize: "1Gi",
+			},
+			expected: data.Object{
+				"pciDevices":        []interface{}{"nvidia.com/TU104GL_TESLA_T4", "nvidia.com/TU104GL_TESLA_T4", "intel.com/QAT"},
+				"hugepagesPageSize": "1Gi",
+			},
+		},
+		{
+			name:        "not a harvester machine config",
+			kind:        "Amazonec2Config",
+			harvester:   &provv1.HarvesterMachinePoolConfig{HugepagesPageSize: "2Mi"},
+			expectedErr: "harvester settings of machinePool [pool] require a HarvesterConfig, not a Amazonec2Config",
+		},
+		{
+			name:        "missing resource name",
+			kind:        "HarvesterConfig",
+			harvester:   &provv1.HarvesterMachinePoolConfig{PCIDevices: []provv1.HarvesterPCIDevice{{Count: 1}}},
+			expectedErr: "missing resourceName of PCI device of machinePool [pool]",
+		},
+		{
+			name:        "negative count",
+			kind:        "HarvesterConfig",
+			harvester:   &provv1.HarvesterMachinePoolConfig{PCIDevices: []provv1.HarvesterPCIDevice{{ResourceName: "intel.com/QAT", Count: -1}}},
+			expectedErr: "invalid count [-1] of PCI device [intel.com/QAT] of machinePool [pool]",
+		},
+		{
+			name:        "invalid hugepages page size",
+			kind:        "HarvesterConfig",
+			harvester:   &provv1.HarvesterMachinePoolConfig{HugepagesPageSize: "4Ki"},
+			expectedErr: "invalid hugepagesPageSize [4Ki] of machinePool [pool], must be 2Mi or 1Gi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machinePoolData := data.Object{}
+			err := setHarvesterConfig(provv1.RKEMachinePool{Name: "pool", Harvester: tt.harvester}, tt.kind, machinePoolData)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, machinePoolData)
+		})
+	}
+}
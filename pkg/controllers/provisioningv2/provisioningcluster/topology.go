@@ -0,0 +1,187 @@
+package provisioningcluster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/lasso/pkg/dynamic"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/capr/planner"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	"github.com/rancher/wrangler/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// getClusterClass returns the ClusterClass of the topology of the cluster.
+func getClusterClass(dynamic *dynamic.Controller, cluster *rancherv1.Cluster) (*capi.ClusterClass, error) {
+	obj, err := dynamic.Get(capi.GroupVersion.WithKind("ClusterClass"), cluster.Namespace, cluster.Spec.RKEConfig.Topology.Class)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ClusterClass %s of cluster %s/%s: %w", cluster.Spec.RKEConfig.Topology.Class, cluster.Namespace, cluster.Name, err)
+	}
+	clusterClass := &capi.ClusterClass{}
+	if err := convert.ToObj(obj, clusterClass); err != nil {
+		return nil, err
+	}
+	return clusterClass, nil
+}
+
+// topologyMachineDeployments generates the machine deployments of the topology of the cluster from the worker classes
+// of its ClusterClass. The machines of the machine deployments are bootstrapped with the bootstrap template of the
+// cluster and labeled with the name of their machine deployment in the topology as their machine pool, so that the
+// planner handles them like the machines of a machine pool. The topology is validated by the planner's preflight
+// validation, and only the worker classes it refers to are checked here.
+func topologyMachineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, clusterClass *capi.ClusterClass, bootstrapName string) (result []runtime.Object, _ error) {
+	if problems := planner.ValidateTopology(cluster); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid topology: %s", strings.Join(problems, ", "))
+	}
+
+	classes := map[string]capi.MachineDeploymentClass{}
+	for _, class := range clusterClass.Spec.Workers.MachineDeployments {
+		classes[class.Class] = class
+	}
+
+	for _, topology := range cluster.Spec.RKEConfig.Topology.MachineDeployments {
+		class, ok := classes[topology.Class]
+		if !ok {
+			return nil, fmt.Errorf("ClusterClass %s has no worker class [%s] for machine deployment [%s] of the topology", clusterClass.Name, topology.Class, topology.Name)
+		}
+		if ref := class.Template.Bootstrap.Ref; ref == nil || ref.Kind != "RKEBootstrapTemplate" {
+			return nil, fmt.Errorf("worker class [%s] of ClusterClass %s must use an RKEBootstrapTemplate", class.Class, clusterClass.Name)
+		}
+		if class.Template.Infrastructure.Ref == nil {
+			return nil, fmt.Errorf("worker class [%s] of ClusterClass %s has no infrastructure template", class.Class, clusterClass.Name)
+		}
+
+		machineDeployment := topologyMachineDeployment(cluster, capiCluster, class, topology, bootstrapName)
+		if cluster.Spec.RKEConfig.ValidateOnly {
+			machineDeployment.Spec.Replicas = &[]int32{0}[0]
+		}
+		result = append(result, machineDeployment)
+
+		if class.MachineHealthCheck != nil {
+			result = append(result, topologyHealthCheck(machineDeployment, class.MachineHealthCheck))
+		}
+	}
+	return result, nil
+}
+
+// topologyMachineDeployment returns the machine deployment of the topology, with the metadata of the topology merged
+// on top of the metadata of its class.
+func topologyMachineDeployment(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, class capi.MachineDeploymentClass,
+	topology rancherv1.RKEMachineDeploymentTopology, bootstrapName string) *capi.MachineDeployment {
+	machineDeploymentName := name.SafeConcatName(cluster.Name, topology.Name)
+
+	labels := map[string]string{}
+	for k, v := range class.Template.Metadata.Labels {
+		labels[k] = v
+	}
+	for k, v := range topology.Labels {
+		labels[k] = v
+	}
+	if labels[capr.CattleOSLabel] == "" {
+		labels[capr.CattleOSLabel] = capr.DefaultMachineOS
+	}
+	labels[capi.ClusterTopologyMachineDeploymentLabelName] = topology.Name
+	annotations := map[string]string{}
+	for k, v := range class.Template.Metadata.Annotations {
+		annotations[k] = v
+	}
+	for k, v := range topology.Annotations {
+		annotations[k] = v
+	}
+
+	machineLabels := map[string]string{}
+	for k, v := range labels {
+		machineLabels[k] = v
+	}
+	machineLabels[capi.ClusterLabelName] = capiCluster.Name
+	machineLabels[capr.ClusterNameLabel] = capiCluster.Name
+	machineLabels[capi.MachineDeploymentLabelName] = machineDeploymentName
+	machineLabels[capr.RKEMachinePoolNameLabel] = topology.Name
+	if topology.EtcdRole {
+		machineLabels[capr.EtcdRoleLabel] = "true"
+	}
+	if topology.ControlPlaneRole {
+		machineLabels[capr.ControlPlaneRoleLabel] = "true"
+		machineLabels[capi.MachineControlPlaneLabelName] = "true"
+	}
+	if topology.WorkerRole {
+		machineLabels[capr.WorkerRoleLabel] = "true"
+	}
+	machineAnnotations := map[string]string{}
+	for k, v := range annotations {
+		machineAnnotations[k] = v
+	}
+	if topology.EtcdRole {
+		machineAnnotations[capi.ExcludeNodeDrainingAnnotation] = "true"
+	}
+
+	infraRef := *class.Template.Infrastructure.Ref
+	infraRef.Namespace = cluster.Namespace
+
+	return &capi.MachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   cluster.Namespace,
+			Name:        machineDeploymentName,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: capi.MachineDeploymentSpec{
+			ClusterName: capiCluster.Name,
+			Replicas:    topology.Replicas,
+			Strategy: &capi.MachineDeploymentStrategy{
+				Type: capi.RollingUpdateMachineDeploymentStrategyType,
+				RollingUpdate: &capi.MachineRollingUpdateDeployment{
+					DeletePolicy: &[]string{string(capi.OldestMachineSetDeletePolicy)}[0],
+				},
+			},
+			Template: capi.MachineTemplateSpec{
+				ObjectMeta: capi.ObjectMeta{
+					Labels:      machineLabels,
+					Annotations: machineAnnotations,
+				},
+				Spec: capi.MachineSpec{
+					ClusterName: capiCluster.Name,
+					Bootstrap: capi.Bootstrap{
+						ConfigRef: &corev1.ObjectReference{
+							Kind:       "RKEBootstrapTemplate",
+							Namespace:  cluster.Namespace,
+							Name:       bootstrapName,
+							APIVersion: capr.RKEAPIVersion,
+						},
+					},
+					InfrastructureRef: infraRef,
+				},
+			},
+			Paused: topology.Paused,
+		},
+	}
+}
+
+// topologyHealthCheck returns the health check of the machines of the machine deployment, as defined by the health check
+// of its worker class.
+func topologyHealthCheck(machineDeployment *capi.MachineDeployment, healthCheck *capi.MachineHealthCheckClass) *capi.MachineHealthCheck {
+	return &capi.MachineHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      machineDeployment.Name,
+			Namespace: machineDeployment.Namespace,
+		},
+		Spec: capi.MachineHealthCheckSpec{
+			ClusterName: machineDeployment.Spec.ClusterName,
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					capi.MachineDeploymentLabelName: machineDeployment.Name,
+				},
+			},
+			UnhealthyConditions: healthCheck.UnhealthyConditions,
+			MaxUnhealthy:        healthCheck.MaxUnhealthy,
+			UnhealthyRange:      healthCheck.UnhealthyRange,
+			NodeStartupTimeout:  healthCheck.NodeStartupTimeout,
+			RemediationTemplate: healthCheck.RemediationTemplate,
+		},
+	}
+}
@@ -0,0 +1,133 @@
+package provisioningcluster
+
+import (
+	"testing"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestTopologyMachineDeployments(t *testing.T) {
+	replicas := int32(3)
+	clusterClass := &capi.ClusterClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "rke2-small"},
+		Spec: capi.ClusterClassSpec{
+			Workers: capi.WorkersClass{
+				MachineDeployments: []capi.MachineDeploymentClass{
+					{
+						Class: "default-worker",
+						Template: capi.MachineDeploymentClassTemplate{
+							Metadata: capi.ObjectMeta{
+								Labels:      map[string]string{"tier": "standard", "zone": "a"},
+								Annotations: map[string]string{"owner": "platform"},
+							},
+							Bootstrap: capi.LocalObjectTemplate{Ref: &corev1.ObjectReference{
+								APIVersion: capr.RKEAPIVersion,
+								Kind:       "RKEBootstrapTemplate",
+								Name:       "class-bootstrap",
+							}},
+							Infrastructure: capi.LocalObjectTemplate{Ref: &corev1.ObjectReference{
+								APIVersion: "rke-machine.cattle.io/v1",
+								Kind:       "Amazonec2MachineTemplate",
+								Name:       "small",
+							}},
+						},
+						MachineHealthCheck: &capi.MachineHealthCheckClass{
+							UnhealthyConditions: []capi.UnhealthyCondition{
+								{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Timeout: metav1.Duration{}},
+							},
+						},
+					},
+					{
+						Class: "kubeadm-worker",
+						Template: capi.MachineDeploymentClassTemplate{
+							Bootstrap: capi.LocalObjectTemplate{Ref: &corev1.ObjectReference{Kind: "KubeadmConfigTemplate", Name: "kubeadm"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	newCluster := func(machineDeployments ...rancherv1.RKEMachineDeploymentTopology) *rancherv1.Cluster {
+		return &rancherv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"},
+			Spec: rancherv1.ClusterSpec{
+				RKEConfig: &rancherv1.RKEConfig{
+					Topology: &rancherv1.RKETopology{Class: "rke2-small", MachineDeployments: machineDeployments},
+				},
+			},
+		}
+	}
+	capiCluster := &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"}}
+
+	result, err := topologyMachineDeployments(newCluster(rancherv1.RKEMachineDeploymentTopology{
+		Class:      "default-worker",
+		Name:       "workers",
+		Replicas:   &replicas,
+		WorkerRole: true,
+		Labels:     map[string]string{"zone": "b"},
+	}), capiCluster, clusterClass, "test-bootstrap-template")
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	machineDeployment := result[0].(*capi.MachineDeployment)
+	assert.Equal(t, "test-workers", machineDeployment.Name)
+	assert.Equal(t, &replicas, machineDeployment.Spec.Replicas)
+	assert.Equal(t, map[string]string{
+		"tier":             "standard",
+		"zone":             "b",
+		capr.CattleOSLabel: capr.DefaultMachineOS,
+		capi.ClusterTopologyMachineDeploymentLabelName: "workers",
+	}, machineDeployment.Labels)
+	assert.Equal(t, "workers", machineDeployment.Spec.Template.Labels[capr.RKEMachinePoolNameLabel])
+	assert.Equal(t, "true", machineDeployment.Spec.Template.Labels[capr.WorkerRoleLabel])
+	assert.Empty(t, machineDeployment.Spec.Template.Labels[capr.EtcdRoleLabel])
+	assert.Equal(t, "platform", machineDeployment.Spec.Template.Annotations["owner"])
+	assert.Equal(t, "test-bootstrap-template", machineDeployment.Spec.Template.Spec.Bootstrap.ConfigRef.Name)
+	assert.Equal(t, corev1.ObjectReference{
+		APIVersion: "rke-machine.cattle.io/v1",
+		Kind:       "Amazonec2MachineTemplate",
+		Namespace:  "fleet-default",
+		Name:       "small",
+	}, machineDeployment.Spec.Template.Spec.InfrastructureRef)
+
+	healthCheck := result[1].(*capi.MachineHealthCheck)
+	assert.Equal(t, "test-workers", healthCheck.Name)
+	assert.Equal(t, clusterClass.Spec.Workers.MachineDeployments[0].MachineHealthCheck.UnhealthyConditions, healthCheck.Spec.UnhealthyConditions)
+
+	_, err = topologyMachineDeployments(newCluster(rancherv1.RKEMachineDeploymentTopology{Class: "default-worker", Name: "workers"}),
+		capiCluster, clusterClass, "test-bootstrap-template")
+	assert.EqualError(t, err, "invalid topology: machine deployment workers must have at least one role")
+
+	_, err = topologyMachineDeployments(newCluster(rancherv1.RKEMachineDeploymentTopology{Class: "missing", Name: "workers", WorkerRole: true}),
+		capiCluster, clusterClass, "test-bootstrap-template")
+	assert.EqualError(t, err, "ClusterClass rke2-small has no worker class [missing] for machine deployment [workers] of the topology")
+
+	_, err = topologyMachineDeployments(newCluster(rancherv1.RKEMachineDeploymentTopology{Class: "kubeadm-worker", Name: "workers", WorkerRole: true}),
+		capiCluster, clusterClass, "test-bootstrap-template")
+	assert.EqualError(t, err, "worker class [kubeadm-worker] of ClusterClass rke2-small must use an RKEBootstrapTemplate")
+}
+
+func TestByClusterClassIndex(t *testing.T) {
+	cluster := &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"},
+		Spec: rancherv1.ClusterSpec{
+			RKEConfig: &rancherv1.RKEConfig{
+				Topology: &rancherv1.RKETopology{Class: "rke2-small"},
+			},
+		},
+	}
+	keys, err := byClusterClassIndex(cluster)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fleet-default/rke2-small"}, keys)
+
+	cluster.Spec.RKEConfig.Topology = nil
+	keys, err = byClusterClassIndex(cluster)
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
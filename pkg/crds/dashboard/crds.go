@@ -52,6 +52,14 @@ func List(cfg *rest.Config) (_ []crd.CRD, err error) {
 			c.GVK.Version = "v3"
 			return c
 		}),
+		newCRD(&v3.HostedClusterPolicy{}, func(c crd.CRD) crd.CRD {
+			c.NonNamespace = true
+			c.GVK.Kind = "HostedClusterPolicy"
+			c.GVK.Version = "v3"
+			return c.
+				WithColumn("Providers", ".spec.providers").
+				WithColumn("Max Nodes", ".spec.maxNodeCount")
+		}),
 		newCRD(&v3.Cluster{}, func(c crd.CRD) crd.CRD {
 			c.Status = false
 			c.NonNamespace = true
@@ -84,7 +84,9 @@ func rke2() []crd.CRD {
 			c.Labels = map[string]string{
 				"cluster.x-k8s.io/v1beta1": "v1",
 			}
-			return clusterIndexed(c)
+			return clusterIndexed(c).
+				WithColumn("Ready", ".status.ready").
+				WithColumn("Joined To", ".status.joinedTo")
 		}),
 		newRKECRD(&rkev1.RKEBootstrapTemplate{}, func(c crd.CRD) crd.CRD {
 			c.Labels = map[string]string{
@@ -110,6 +112,26 @@ func rke2() []crd.CRD {
 			}
 			return clusterIndexed(c)
 		}),
+		newRKECRD(&rkev1.ETCDSnapshotDrill{}, nil),
+		newRKECRD(&rkev1.InstructionPolicy{}, func(c crd.CRD) crd.CRD {
+			c.NonNamespace = true
+			return c
+		}),
+		newRKECRD(&rkev1.Approval{}, func(c crd.CRD) crd.CRD {
+			return c.
+				WithColumn("Cluster Namespace", ".spec.clusterNamespace").
+				WithColumn("Cluster", ".spec.clusterName").
+				WithColumn("Operation", ".spec.operation").
+				WithColumn("Snapshot", ".spec.snapshotName").
+				WithColumn("Generation", ".spec.generation").
+				WithColumn("Approver", ".spec.approver")
+		}),
+		newRKECRD(&rkev1.NodeCommand{}, func(c crd.CRD) crd.CRD {
+			return c.
+				WithColumn("Cluster", ".spec.clusterName").
+				WithColumn("Command", ".spec.command").
+				WithColumn("Phase", ".status.phase")
+		}),
 	}
 }
 
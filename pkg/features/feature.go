@@ -107,6 +107,12 @@
 		true,
 		true,
 		true)
+	LargeClusterMode = newFeature(
+		"large-cluster-mode",
+		"Batch the status writes of the provisioning planner to reduce the write volume of clusters with frequent transient states",
+		false,
+		false,
+		true)
 )
 
 type Feature struct {
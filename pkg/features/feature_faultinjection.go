@@ -0,0 +1,13 @@
+//go:build faultinjection
+// +build faultinjection
+
+package features
+
+var (
+	PlannerFaultInjection = newFeature(
+		"planner-fault-injection",
+		"Inject the faults configured on clusters into the planner. Only available in development builds.",
+		false,
+		true,
+		false)
+)
@@ -28,6 +28,10 @@ type FakeRkeV1 struct {
 	*testing.Fake
 }
 
+func (c *FakeRkeV1) Approvals(namespace string) v1.ApprovalInterface {
+	return &FakeApprovals{c, namespace}
+}
+
 func (c *FakeRkeV1) CustomMachines(namespace string) v1.CustomMachineInterface {
 	return &FakeCustomMachines{c, namespace}
 }
@@ -36,6 +40,18 @@ func (c *FakeRkeV1) ETCDSnapshots(namespace string) v1.ETCDSnapshotInterface {
 	return &FakeETCDSnapshots{c, namespace}
 }
 
+func (c *FakeRkeV1) ETCDSnapshotDrills(namespace string) v1.ETCDSnapshotDrillInterface {
+	return &FakeETCDSnapshotDrills{c, namespace}
+}
+
+func (c *FakeRkeV1) InstructionPolicies() v1.InstructionPolicyInterface {
+	return &FakeInstructionPolicies{c}
+}
+
+func (c *FakeRkeV1) NodeCommands(namespace string) v1.NodeCommandInterface {
+	return &FakeNodeCommands{c, namespace}
+}
+
 func (c *FakeRkeV1) RKEBootstraps(namespace string) v1.RKEBootstrapInterface {
 	return &FakeRKEBootstraps{c, namespace}
 }
@@ -18,10 +18,18 @@
 
 package v1
 
+type ApprovalExpansion interface{}
+
 type CustomMachineExpansion interface{}
 
 type ETCDSnapshotExpansion interface{}
 
+type ETCDSnapshotDrillExpansion interface{}
+
+type InstructionPolicyExpansion interface{}
+
+type NodeCommandExpansion interface{}
+
 type RKEBootstrapExpansion interface{}
 
 type RKEBootstrapTemplateExpansion interface{}
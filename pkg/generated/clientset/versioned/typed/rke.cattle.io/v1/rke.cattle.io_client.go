@@ -28,8 +28,12 @@
 
 type RkeV1Interface interface {
 	RESTClient() rest.Interface
+	ApprovalsGetter
 	CustomMachinesGetter
 	ETCDSnapshotsGetter
+	ETCDSnapshotDrillsGetter
+	InstructionPoliciesGetter
+	NodeCommandsGetter
 	RKEBootstrapsGetter
 	RKEBootstrapTemplatesGetter
 	RKEClustersGetter
@@ -41,6 +45,10 @@ type RkeV1Client struct {
 	restClient rest.Interface
 }
 
+func (c *RkeV1Client) Approvals(namespace string) ApprovalInterface {
+	return newApprovals(c, namespace)
+}
+
 func (c *RkeV1Client) CustomMachines(namespace string) CustomMachineInterface {
 	return newCustomMachines(c, namespace)
 }
@@ -49,6 +57,18 @@ func (c *RkeV1Client) ETCDSnapshots(namespace string) ETCDSnapshotInterface {
 	return newETCDSnapshots(c, namespace)
 }
 
+func (c *RkeV1Client) ETCDSnapshotDrills(namespace string) ETCDSnapshotDrillInterface {
+	return newETCDSnapshotDrills(c, namespace)
+}
+
+func (c *RkeV1Client) InstructionPolicies() InstructionPolicyInterface {
+	return newInstructionPolicies(c)
+}
+
+func (c *RkeV1Client) NodeCommands(namespace string) NodeCommandInterface {
+	return newNodeCommands(c, namespace)
+}
+
 func (c *RkeV1Client) RKEBootstraps(namespace string) RKEBootstrapInterface {
 	return newRKEBootstraps(c, namespace)
 }
@@ -65,6 +65,7 @@ type Interface interface {
 	GoogleOAuthProvider() GoogleOAuthProviderController
 	Group() GroupController
 	GroupMember() GroupMemberController
+	HostedClusterPolicy() HostedClusterPolicyController
 	KontainerDriver() KontainerDriverController
 	LocalProvider() LocalProviderController
 	ManagedChart() ManagedChartController
@@ -223,6 +224,9 @@ func (c *version) Group() GroupController {
 func (c *version) GroupMember() GroupMemberController {
 	return NewGroupMemberController(schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "GroupMember"}, "groupmembers", false, c.controllerFactory)
 }
+func (c *version) HostedClusterPolicy() HostedClusterPolicyController {
+	return NewHostedClusterPolicyController(schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "HostedClusterPolicy"}, "hostedclusterpolicies", false, c.controllerFactory)
+}
 func (c *version) KontainerDriver() KontainerDriverController {
 	return NewKontainerDriverController(schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "KontainerDriver"}, "kontainerdrivers", false, c.controllerFactory)
 }
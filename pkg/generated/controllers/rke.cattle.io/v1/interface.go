@@ -30,8 +30,12 @@ func init() {
 }
 
 type Interface interface {
+	Approval() ApprovalController
 	CustomMachine() CustomMachineController
 	ETCDSnapshot() ETCDSnapshotController
+	ETCDSnapshotDrill() ETCDSnapshotDrillController
+	InstructionPolicy() InstructionPolicyController
+	NodeCommand() NodeCommandController
 	RKEBootstrap() RKEBootstrapController
 	RKEBootstrapTemplate() RKEBootstrapTemplateController
 	RKECluster() RKEClusterController
@@ -48,12 +52,24 @@ type version struct {
 	controllerFactory controller.SharedControllerFactory
 }
 
+func (c *version) Approval() ApprovalController {
+	return NewApprovalController(schema.GroupVersionKind{Group: "rke.cattle.io", Version: "v1", Kind: "Approval"}, "approvals", true, c.controllerFactory)
+}
 func (c *version) CustomMachine() CustomMachineController {
 	return NewCustomMachineController(schema.GroupVersionKind{Group: "rke.cattle.io", Version: "v1", Kind: "CustomMachine"}, "custommachines", true, c.controllerFactory)
 }
 func (c *version) ETCDSnapshot() ETCDSnapshotController {
 	return NewETCDSnapshotController(schema.GroupVersionKind{Group: "rke.cattle.io", Version: "v1", Kind: "ETCDSnapshot"}, "etcdsnapshots", true, c.controllerFactory)
 }
+func (c *version) ETCDSnapshotDrill() ETCDSnapshotDrillController {
+	return NewETCDSnapshotDrillController(schema.GroupVersionKind{Group: "rke.cattle.io", Version: "v1", Kind: "ETCDSnapshotDrill"}, "etcdsnapshotdrills", true, c.controllerFactory)
+}
+func (c *version) InstructionPolicy() InstructionPolicyController {
+	return NewInstructionPolicyController(schema.GroupVersionKind{Group: "rke.cattle.io", Version: "v1", Kind: "InstructionPolicy"}, "instructionpolicies", true, c.controllerFactory)
+}
+func (c *version) NodeCommand() NodeCommandController {
+	return NewNodeCommandController(schema.GroupVersionKind{Group: "rke.cattle.io", Version: "v1", Kind: "NodeCommand"}, "nodecommands", true, c.controllerFactory)
+}
 func (c *version) RKEBootstrap() RKEBootstrapController {
 	return NewRKEBootstrapController(schema.GroupVersionKind{Group: "rke.cattle.io", Version: "v1", Kind: "RKEBootstrap"}, "rkebootstraps", true, c.controllerFactory)
 }
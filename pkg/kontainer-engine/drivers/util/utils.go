@@ -102,6 +102,42 @@ func GenerateServiceAccountToken(clientset kubernetes.Interface) (string, error)
 	return string(secret.Data["token"]), nil
 }
 
+// RotateServiceAccountToken revokes the tokens of the service account generated by GenerateServiceAccountToken by
+// deleting their secrets, and generates a new token for it given a rest clientset.
+func RotateServiceAccountToken(clientset kubernetes.Interface) (string, error) {
+	if err := deleteServiceAccountTokenSecrets(clientset); err != nil {
+		return "", err
+	}
+	return GenerateServiceAccountToken(clientset)
+}
+
+// deleteServiceAccountTokenSecrets deletes the secrets referenced by the service account generated by
+// GenerateServiceAccountToken, and removes the references from the service account.
+func deleteServiceAccountTokenSecrets(clientset kubernetes.Interface) error {
+	serviceAccount, err := clientset.CoreV1().ServiceAccounts(cattleNamespace).Get(context.TODO(), kontainerEngine, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting service account: %w", err)
+	}
+	if len(serviceAccount.Secrets) == 0 {
+		return nil
+	}
+
+	for _, ref := range serviceAccount.Secrets {
+		err := clientset.CoreV1().Secrets(cattleNamespace).Delete(context.TODO(), ref.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting service account token secret %s: %w", ref.Name, err)
+		}
+	}
+	serviceAccount = serviceAccount.DeepCopy()
+	serviceAccount.Secrets = nil
+	if _, err := clientset.CoreV1().ServiceAccounts(cattleNamespace).Update(context.TODO(), serviceAccount, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("error updating service account: %w", err)
+	}
+	return nil
+}
+
 func DeleteLegacyServiceAccountAndRoleBinding(clientset kubernetes.Interface) error {
 	_, err := clientset.CoreV1().ServiceAccounts(defaultNamespace).Get(context.TODO(), netesDefault, metav1.GetOptions{})
 	if !errors.IsNotFound(err) {
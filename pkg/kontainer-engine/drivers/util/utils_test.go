@@ -0,0 +1,38 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeleteServiceAccountTokenSecrets(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&v1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: kontainerEngine, Namespace: cattleNamespace},
+			Secrets:    []v1.ObjectReference{{Name: "kontainer-engine-token-abcde"}, {Name: "missing"}},
+		},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "kontainer-engine-token-abcde", Namespace: cattleNamespace}},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: cattleNamespace}},
+	)
+
+	require.NoError(t, deleteServiceAccountTokenSecrets(clientset))
+
+	_, err := clientset.CoreV1().Secrets(cattleNamespace).Get(context.TODO(), "kontainer-engine-token-abcde", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = clientset.CoreV1().Secrets(cattleNamespace).Get(context.TODO(), "other", metav1.GetOptions{})
+	assert.NoError(t, err)
+	serviceAccount, err := clientset.CoreV1().ServiceAccounts(cattleNamespace).Get(context.TODO(), kontainerEngine, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, serviceAccount.Secrets)
+}
+
+func TestDeleteServiceAccountTokenSecretsWithoutServiceAccount(t *testing.T) {
+	assert.NoError(t, deleteServiceAccountTokenSecrets(fake.NewSimpleClientset()))
+}
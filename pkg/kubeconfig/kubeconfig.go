@@ -164,3 +164,48 @@ func ForClusterTokenBased(cluster *managementv3.Cluster, nodes []*mgmtv3.Node, c
 	err := tokenTemplate.Execute(buf, data)
 	return buf.String(), err
 }
+
+// BundleCluster is a downstream cluster to add to a kubeconfig bundle.
+type BundleCluster struct {
+	ID   string
+	Name string
+}
+
+// ForTokenBasedBundle returns a kubeconfig with a context for each of the clusters, all using the same user. The display
+// name of a cluster is used as the name of its context, unless it is empty, shared with another cluster or the ID of
+// another cluster, in which case the cluster ID is used. If token is empty, the user authenticates with the rancher CLI as exec credential plugin. The
+// first cluster is the current context.
+func ForTokenBasedBundle(clusters []BundleCluster, host, user, token string) (string, error) {
+	names := map[string]int{}
+	ids := map[string]bool{}
+	for _, c := range clusters {
+		names[c.Name]++
+		ids[c.ID] = true
+	}
+
+	nodes := make([]kubeNode, 0, len(clusters))
+	for _, c := range clusters {
+		name := c.Name
+		if name == "" || names[name] > 1 || (ids[name] && name != c.ID) {
+			name = c.ID
+		}
+		n := getDefaultNode(name, c.ID, host)
+		n.User = user
+		nodes = append(nodes, n)
+	}
+
+	data := &data{
+		Host:  host,
+		Cert:  caCertString(),
+		User:  user,
+		Token: token,
+		Nodes: nodes,
+	}
+	if len(nodes) > 0 {
+		data.ClusterName = nodes[0].ClusterName
+	}
+
+	buf := &bytes.Buffer{}
+	err := tokenTemplate.Execute(buf, data)
+	return buf.String(), err
+}
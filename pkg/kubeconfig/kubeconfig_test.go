@@ -0,0 +1,68 @@
+package kubeconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestForTokenBasedBundle(t *testing.T) {
+	clusters := []BundleCluster{
+		{ID: "c-abc12", Name: "prod"},
+		{ID: "c-def34", Name: "dev"},
+		{ID: "c-ghi56", Name: "dev"},
+		{ID: "c-jkl78", Name: "local"},
+		{ID: "local"},
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{
+			name:  "token",
+			token: "kubeconfig-u-abc:secret",
+		},
+		{
+			name: "exec",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := ForTokenBasedBundle(clusters, "rancher.example.com", "u-abc", tt.token)
+			require.NoError(t, err)
+
+			cfg, err := clientcmd.Load([]byte(out))
+			require.NoError(t, err)
+
+			assert.Equal(t, "prod", cfg.CurrentContext)
+			assert.Len(t, cfg.Contexts, 5)
+			for name, server := range map[string]string{
+				"prod":    "https://rancher.example.com/k8s/clusters/c-abc12",
+				"c-def34": "https://rancher.example.com/k8s/clusters/c-def34",
+				"c-ghi56": "https://rancher.example.com/k8s/clusters/c-ghi56",
+				"c-jkl78": "https://rancher.example.com/k8s/clusters/c-jkl78",
+				"local":   "https://rancher.example.com/k8s/clusters/local",
+			} {
+				require.Contains(t, cfg.Contexts, name)
+				assert.Equal(t, name, cfg.Contexts[name].Cluster)
+				assert.Equal(t, "u-abc", cfg.Contexts[name].AuthInfo)
+				assert.Equal(t, server, cfg.Clusters[name].Server)
+			}
+
+			require.Len(t, cfg.AuthInfos, 1)
+			user := cfg.AuthInfos["u-abc"]
+			if tt.token != "" {
+				assert.Equal(t, tt.token, user.Token)
+				assert.Nil(t, user.Exec)
+				return
+			}
+			require.NotNil(t, user.Exec)
+			assert.Equal(t, "rancher", user.Exec.Command)
+			assert.Equal(t, []string{"token", "--server=rancher.example.com", "--user=u-abc"}, user.Exec.Args)
+		})
+	}
+}
@@ -35,6 +35,90 @@
 		},
 		[]string{"cluster", "owner"},
 	)
+
+	caprGarbageCollected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "capr",
+			Name:      "garbage_collected_objects_total",
+			Help:      "Number of orphaned machine plan secrets, bootstrap secrets, service accounts, and service account tokens removed",
+		},
+		[]string{"kind"},
+	)
+
+	caprPlanApplyDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "capr",
+			Name:      "machine_plan_apply_duration_seconds",
+			Help:      "Time from a machine plan being changed until it was applied by the system agent",
+			Buckets:   prometheus.ExponentialBuckets(10, 2, 12),
+		},
+		[]string{"namespace", "cluster"},
+	)
+
+	caprPlansStuck = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "capr",
+			Name:      "machine_plans_stuck_total",
+			Help:      "Number of machine plans that were not applied within the machine-plan-convergence-timeout",
+		},
+		[]string{"namespace", "cluster"},
+	)
+
+	caprS3Requests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "capr",
+			Name:      "s3_requests_total",
+			Help:      "Number of requests to etcd snapshot S3 buckets made by Rancher, by operation and result",
+		},
+		[]string{"operation", "result"},
+	)
+
+	caprS3RequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "capr",
+			Name:      "s3_request_duration_seconds",
+			Help:      "Duration of requests to etcd snapshot S3 buckets made by Rancher",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		},
+		[]string{"operation"},
+	)
+
+	caprETCDSnapshotAge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: "capr",
+			Name:      "etcd_snapshot_age_seconds",
+			Help:      "Age of the newest successful etcd snapshot of a cluster, or of the cluster itself if it has no successful snapshot",
+		},
+		[]string{"namespace", "cluster"},
+	)
+
+	caprETCDSnapshotOverdue = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: "capr",
+			Name:      "etcd_snapshot_overdue",
+			Help:      "Whether the newest successful etcd snapshot of a cluster is older than its maximum snapshot age",
+		},
+		[]string{"namespace", "cluster"},
+	)
+
+	caprClusterProvisioningMilestone = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "capr",
+			Name:      "cluster_provisioning_milestone_seconds",
+			Help:      "Time from the creation of a cluster until it first reached a provisioning milestone",
+			Buckets:   prometheus.ExponentialBuckets(30, 2, 10),
+		},
+		[]string{"milestone"},
+	)
+
+	hostedOperatorChartEnsureSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "hosted_operator",
+			Name:      "chart_ensures_skipped_total",
+			Help:      "Number of hosted operator chart installations skipped because the chart version and values did not change",
+		},
+		[]string{"chart"},
+	)
 )
 
 type metricsHandler struct {
@@ -226,6 +310,27 @@ func Register(ctx context.Context, scaledContext *config.ScaledContext) {
 	prometheus.MustRegister(numNodes)
 	prometheus.MustRegister(numCores)
 
+	// capr garbage collection metrics
+	prometheus.MustRegister(caprGarbageCollected)
+
+	// capr machine plan convergence metrics
+	prometheus.MustRegister(caprPlanApplyDuration)
+	prometheus.MustRegister(caprPlansStuck)
+
+	// capr etcd snapshot S3 client metrics
+	prometheus.MustRegister(caprS3Requests)
+	prometheus.MustRegister(caprS3RequestDuration)
+
+	// capr etcd snapshot staleness metrics
+	prometheus.MustRegister(caprETCDSnapshotAge)
+	prometheus.MustRegister(caprETCDSnapshotOverdue)
+
+	// capr cluster provisioning metrics
+	prometheus.MustRegister(caprClusterProvisioningMilestone)
+
+	// hosted operator chart metrics
+	prometheus.MustRegister(hostedOperatorChartEnsureSkipped)
+
 	gc := metricGarbageCollector{
 		clusterLister:  scaledContext.Management.Clusters("").Controller().Lister(),
 		nodeLister:     scaledContext.Management.Nodes("").Controller().Lister(),
@@ -265,3 +370,103 @@ func UnsetClusterOwner(id, clusterID string) {
 			}).Set(float64(0))
 	}
 }
+
+// AddCAPRGarbageCollected records the number of objects of the given kind removed by the capr garbage collector.
+func AddCAPRGarbageCollected(kind string, count int) {
+	if prometheusMetrics && count > 0 {
+		caprGarbageCollected.With(
+			prometheus.Labels{
+				"kind": kind,
+			}).Add(float64(count))
+	}
+}
+
+func ObserveCAPRPlanApplyDuration(namespace, cluster string, duration time.Duration) {
+	if prometheusMetrics {
+		caprPlanApplyDuration.With(
+			prometheus.Labels{
+				"namespace": namespace,
+				"cluster":   cluster,
+			}).Observe(duration.Seconds())
+	}
+}
+
+func IncCAPRPlansStuck(namespace, cluster string) {
+	if prometheusMetrics {
+		caprPlansStuck.With(
+			prometheus.Labels{
+				"namespace": namespace,
+				"cluster":   cluster,
+			}).Inc()
+	}
+}
+
+// ObserveCAPRS3Request records the duration and result of a request to an etcd snapshot S3 bucket.
+func ObserveCAPRS3Request(operation string, duration time.Duration, err error) {
+	if prometheusMetrics {
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		caprS3Requests.With(
+			prometheus.Labels{
+				"operation": operation,
+				"result":    result,
+			}).Inc()
+		caprS3RequestDuration.With(
+			prometheus.Labels{
+				"operation": operation,
+			}).Observe(duration.Seconds())
+	}
+}
+
+// SetCAPRETCDSnapshotStaleness records the age of the newest successful etcd snapshot of the cluster and whether it is
+// overdue.
+func SetCAPRETCDSnapshotStaleness(namespace, cluster string, age time.Duration, overdue bool) {
+	if prometheusMetrics {
+		labels := prometheus.Labels{
+			"namespace": namespace,
+			"cluster":   cluster,
+		}
+		caprETCDSnapshotAge.With(labels).Set(age.Seconds())
+		value := float64(0)
+		if overdue {
+			value = 1
+		}
+		caprETCDSnapshotOverdue.With(labels).Set(value)
+	}
+}
+
+// DeleteCAPRETCDSnapshotStaleness removes the etcd snapshot staleness metrics of the cluster.
+func DeleteCAPRETCDSnapshotStaleness(namespace, cluster string) {
+	if prometheusMetrics {
+		labels := prometheus.Labels{
+			"namespace": namespace,
+			"cluster":   cluster,
+		}
+		caprETCDSnapshotAge.Delete(labels)
+		caprETCDSnapshotOverdue.Delete(labels)
+	}
+}
+
+// ObserveCAPRClusterProvisioningMilestone records the time elapsed from the creation of a cluster until it first
+// reached the provisioning milestone.
+func ObserveCAPRClusterProvisioningMilestone(milestone string, elapsed time.Duration) {
+	if prometheusMetrics {
+		caprClusterProvisioningMilestone.With(
+			prometheus.Labels{
+				"milestone": milestone,
+			}).Observe(elapsed.Seconds())
+	}
+}
+
+// IncHostedOperatorChartEnsureSkipped records that the installation of the hosted operator chart was skipped as its
+// inputs did not change.
+func IncHostedOperatorChartEnsureSkipped(chart string) {
+	if prometheusMetrics {
+		hostedOperatorChartEnsureSkipped.With(
+			prometheus.Labels{
+				"chart": chart,
+			}).Inc()
+	}
+}
@@ -38,36 +38,40 @@ func GetPrivateRepoSecretFromCluster(cluster *v1.Cluster) string {
 	return "" // don't return settings.SystemDefaultRegistry.Get() as the global system-default-registry requires no auth
 }
 
-// GetPrivateRepoURLFromCluster returns the system-default-registry URL from either the clusters
+// GetPrivateRepoURLFromCluster returns the system-default-registry URL from either the clusters systemDefaultRegistry,
 // machineGlobalConfig, or one of its machineSelectorConfig's which has no label selectors.
 // If no cluster level system-default-registry is configured, it will return the global system-default-registry.
 func GetPrivateRepoURLFromCluster(cluster *v1.Cluster) string {
 	if cluster != nil && cluster.Spec.RKEConfig != nil {
-		return getPrivateRepoURL(cluster.Spec.RKEConfig.MachineGlobalConfig, cluster.Spec.RKEConfig.MachineSelectorConfig)
+		return getPrivateRepoURL(cluster.Spec.RKEConfig.RKEClusterSpecCommon)
 	}
 
 	return settings.SystemDefaultRegistry.Get()
 }
 
 // GetPrivateRepoURLFromControlPlane returns the system-default-registry URL from either the control planes
-// machineGlobalConfig, or one of its machineSelectorConfig's which has no label selectors.
+// systemDefaultRegistry, machineGlobalConfig, or one of its machineSelectorConfig's which has no label selectors.
 // If no cluster level system-default-registry is configured, it will return the global system-default-registry.
 func GetPrivateRepoURLFromControlPlane(cp *rkev1.RKEControlPlane) string {
 	if cp != nil {
-		return getPrivateRepoURL(cp.Spec.MachineGlobalConfig, cp.Spec.MachineSelectorConfig)
+		return getPrivateRepoURL(cp.Spec.RKEClusterSpecCommon)
 	}
 
 	return settings.SystemDefaultRegistry.Get()
 }
 
-func getPrivateRepoURL(machineGlobalConfig rkev1.GenericMap, machineSelectorConfig []rkev1.RKESystemConfig) string {
-	for key, val := range machineGlobalConfig.Data {
+func getPrivateRepoURL(spec rkev1.RKEClusterSpecCommon) string {
+	if spec.SystemDefaultRegistry != "" {
+		return strings.TrimSuffix(spec.SystemDefaultRegistry, "/")
+	}
+
+	for key, val := range spec.MachineGlobalConfig.Data {
 		if val, ok := val.(string); ok && key == "system-default-registry" {
 			return val
 		}
 	}
 
-	for _, config := range machineSelectorConfig {
+	for _, config := range spec.MachineSelectorConfig {
 		if registryVal, ok := config.Config.Data["system-default-registry"]; config.MachineLabelSelector == nil && ok {
 			if registry, ok := registryVal.(string); ok {
 				return registry
@@ -233,10 +233,12 @@ func clusterTypes(schemas *types.Schemas) *types.Schemas {
 		MustImport(&Version, v3.Cluster{}).
 		MustImport(&Version, v3.ClusterRegistrationToken{}).
 		MustImport(&Version, v3.GenerateKubeConfigOutput{}).
+		MustImport(&Version, v3.GenerateKubeconfigBundleInput{}).
 		MustImport(&Version, v3.ImportClusterYamlInput{}).
 		MustImport(&Version, v3.RotateCertificateInput{}).
 		MustImport(&Version, v3.RotateCertificateOutput{}).
 		MustImport(&Version, v3.RotateEncryptionKeyOutput{}).
+		MustImport(&Version, v3.RefreshServiceAccountTokenOutput{}).
 		MustImport(&Version, v3.ImportYamlOutput{}).
 		MustImport(&Version, v3.ExportOutput{}).
 		MustImport(&Version, v3.MonitoringInput{}).
@@ -295,10 +297,19 @@ func clusterTypes(schemas *types.Schemas) *types.Schemas {
 			schema.ResourceActions[v3.ClusterActionRotateEncryptionKey] = types.Action{
 				Output: "rotateEncryptionKeyOutput",
 			}
+			schema.ResourceActions[v3.ClusterActionRefreshServiceAccountToken] = types.Action{
+				Output: "refreshServiceAccountTokenOutput",
+			}
 			schema.ResourceActions[v3.ClusterActionSaveAsTemplate] = types.Action{
 				Input:  "saveAsTemplateInput",
 				Output: "saveAsTemplateOutput",
 			}
+			schema.CollectionActions = map[string]types.Action{
+				v3.ClusterActionGenerateKubeconfigBundle: {
+					Input:  "generateKubeconfigBundleInput",
+					Output: "generateKubeConfigOutput",
+				},
+			}
 		})
 }
 
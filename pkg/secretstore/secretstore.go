@@ -0,0 +1,115 @@
+// Package secretstore resolves the data of secrets from external secret stores, so that long-lived keys do not have to
+// be stored in secrets of the management cluster. A secret refers to the data in a store with a reference of the form
+// <provider>:<path>, i.e. "vault:kv/data/cloud/aws".
+package secretstore
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/pkg/kv"
+)
+
+// cacheTTL is how long the data read from a secret store is reused before it is read again.
+const cacheTTL = 5 * time.Minute
+
+// Provider reads secrets from an external secret store.
+type Provider interface {
+	// Get returns the data of the secret at the path of the store.
+	Get(ctx context.Context, path string) (map[string][]byte, error)
+}
+
+type cacheEntry struct {
+	data   map[string][]byte
+	expiry time.Time
+}
+
+var (
+	lock      sync.RWMutex
+	providers = map[string]Provider{
+		VaultProviderName: &vaultProvider{},
+	}
+
+	cacheLock sync.Mutex
+	cache     = map[string]cacheEntry{}
+)
+
+// Register registers the provider under the name, replacing any provider registered under it before.
+func Register(name string, provider Provider) {
+	lock.Lock()
+	defer lock.Unlock()
+	providers[name] = provider
+}
+
+// Resolve returns the data of the secret the reference of a secret in the namespace refers to. The path of the reference
+// must be allowed for the namespace by the secret-store-allowed-paths setting, so that a secret cannot refer to the data
+// of the secrets of other namespaces. The data is cached for a few minutes.
+func Resolve(ctx context.Context, ref, namespace string) (map[string][]byte, error) {
+	name, p := kv.Split(ref, ":")
+	if p == "" {
+		return nil, fmt.Errorf("invalid secret store reference %q: must be of the form <provider>:<path>", ref)
+	}
+	if !pathAllowed(p, namespace, settings.SecretStoreAllowedPaths.Get()) {
+		return nil, fmt.Errorf("path %s of secret store %s is not allowed for namespace %s by the %s setting", p, name, namespace, settings.SecretStoreAllowedPaths.Name)
+	}
+	p, _ = cleanPath(p)
+
+	lock.RLock()
+	provider, ok := providers[name]
+	lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown secret store provider %q", name)
+	}
+
+	cacheLock.Lock()
+	entry, ok := cache[ref]
+	cacheLock.Unlock()
+	if ok && time.Now().Before(entry.expiry) {
+		return entry.data, nil
+	}
+
+	data, err := provider.Get(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from secret store %s: %w", p, name, err)
+	}
+	cacheLock.Lock()
+	cache[ref] = cacheEntry{data: data, expiry: time.Now().Add(cacheTTL)}
+	cacheLock.Unlock()
+	return data, nil
+}
+
+// cleanPath returns the path without leading slashes, redundant separators and . segments. Paths with .. segments are
+// rejected, as they could escape the prefix of an allowed path.
+func cleanPath(p string) (string, bool) {
+	for _, segment := range strings.Split(p, "/") {
+		if segment == ".." {
+			return "", false
+		}
+	}
+	return strings.TrimPrefix(path.Clean("/"+p), "/"), true
+}
+
+// pathAllowed returns true if the cleaned path matches one of the comma separated path.Match patterns of allowed, in
+// which {namespace} is replaced with the namespace.
+func pathAllowed(p, namespace, allowed string) bool {
+	p, ok := cleanPath(p)
+	if !ok {
+		return false
+	}
+	for _, pattern := range strings.Split(allowed, ",") {
+		pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+		pattern = strings.ReplaceAll(pattern, "{namespace}", namespace)
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
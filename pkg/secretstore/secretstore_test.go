@@ -0,0 +1,117 @@
+package secretstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	data  map[string]map[string][]byte
+	reads int
+}
+
+func (f *fakeProvider) Get(_ context.Context, path string) (map[string][]byte, error) {
+	f.reads++
+	return f.data[path], nil
+}
+
+func TestResolve(t *testing.T) {
+	provider := &fakeProvider{data: map[string]map[string][]byte{"cloud/fleet-default/aws": {"accessKey": []byte("AKIA")}}}
+	Register("fake", provider)
+	require.NoError(t, settings.SecretStoreAllowedPaths.Set("cloud/{namespace}/*"))
+	defer func() {
+		lock.Lock()
+		delete(providers, "fake")
+		lock.Unlock()
+		cacheLock.Lock()
+		cache = map[string]cacheEntry{}
+		cacheLock.Unlock()
+		_ = settings.SecretStoreAllowedPaths.Set("")
+	}()
+
+	data, err := Resolve(context.Background(), "fake:cloud/fleet-default/aws", "fleet-default")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"accessKey": []byte("AKIA")}, data)
+	_, err = Resolve(context.Background(), "fake:cloud/fleet-default/aws", "fleet-default")
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.reads, "the data is cached")
+
+	_, err = Resolve(context.Background(), "fake:cloud/fleet-default/aws", "other")
+	assert.EqualError(t, err, "path cloud/fleet-default/aws of secret store fake is not allowed for namespace other by the secret-store-allowed-paths setting")
+
+	_, err = Resolve(context.Background(), "fake", "fleet-default")
+	assert.EqualError(t, err, `invalid secret store reference "fake": must be of the form <provider>:<path>`)
+
+	_, err = Resolve(context.Background(), "unknown:cloud/fleet-default/aws", "fleet-default")
+	assert.EqualError(t, err, `unknown secret store provider "unknown"`)
+}
+
+func TestPathAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		namespace string
+		allowed   string
+		expected  bool
+	}{
+		{
+			name:      "no allowed paths",
+			path:      "kv/data/rancher/fleet-default/aws",
+			namespace: "fleet-default",
+		},
+		{
+			name:      "namespace of the secret",
+			path:      "kv/data/rancher/fleet-default/aws",
+			namespace: "fleet-default",
+			allowed:   "kv/data/rancher/{namespace}/*",
+			expected:  true,
+		},
+		{
+			name:      "other namespace",
+			path:      "kv/data/rancher/fleet-default/aws",
+			namespace: "fleet-local",
+			allowed:   "kv/data/rancher/{namespace}/*",
+		},
+		{
+			name:      "nested path",
+			path:      "kv/data/rancher/fleet-default/aws/admin",
+			namespace: "fleet-default",
+			allowed:   "kv/data/rancher/{namespace}/*",
+		},
+		{
+			name:      "leading slashes",
+			path:      "/kv/data/shared/aws",
+			namespace: "fleet-default",
+			allowed:   "kv/data/rancher/{namespace}/*, /kv/data/shared/*",
+			expected:  true,
+		},
+		{
+			name:      "traversal to other namespace",
+			path:      "kv/data/rancher/fleet-default/../fleet-local/aws",
+			namespace: "fleet-default",
+			allowed:   "kv/data/rancher/{namespace}/*, kv/data/rancher/*/*/aws",
+		},
+		{
+			name:      "traversal segment matched by wildcard",
+			path:      "kv/data/rancher/fleet-default/..",
+			namespace: "fleet-default",
+			allowed:   "kv/data/rancher/{namespace}/*",
+		},
+		{
+			name:      "redundant separators",
+			path:      "kv/data/rancher//fleet-default/./aws",
+			namespace: "fleet-default",
+			allowed:   "kv/data/rancher/{namespace}/*",
+			expected:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, pathAllowed(tt.path, tt.namespace, tt.allowed))
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package secretstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/rancher/pkg/settings"
+)
+
+const (
+	// VaultProviderName is the name of the provider that reads secrets from the KV secrets engine of HashiCorp Vault.
+	VaultProviderName = "vault"
+
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	// vaultTokenRenewBefore is how long before the expiry of a Vault token a new one is requested.
+	vaultTokenRenewBefore = time.Minute
+	// vaultRequestTimeout is how long a request to the Vault server may take.
+	vaultRequestTimeout = 10 * time.Second
+)
+
+// vaultProvider reads secrets from the Vault server of the secret-store-vault-address setting. It authenticates with
+// the token of the VAULT_TOKEN environment variable or, if there is none, logs in with the service account of Rancher
+// through the Kubernetes auth method of the server. The certificate of the server is verified with the CAs of the system
+// and of the secret-store-vault-ca-certs setting.
+type vaultProvider struct {
+	lock    sync.Mutex
+	address string
+	token   string
+	expiry  time.Time
+
+	clientLock sync.Mutex
+	caCerts    string
+	client     *http.Client
+	// serviceAccountToken returns the token of the service account that is exchanged for a Vault token.
+	serviceAccountToken func() ([]byte, error)
+}
+
+type vaultResponse struct {
+	Auth *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+// Get reads the secret at the path, which is the API path of the secret without the /v1/ prefix, i.e.
+// kv/data/cloud/aws. Secrets of version 1 and 2 of the KV secrets engine are supported.
+func (v *vaultProvider) Get(ctx context.Context, path string) (map[string][]byte, error) {
+	address := strings.TrimSuffix(settings.SecretStoreVaultAddress.Get(), "/")
+	if address == "" {
+		return nil, fmt.Errorf("the %s setting is not set", settings.SecretStoreVaultAddress.Name)
+	}
+	token, err := v.getToken(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	var response vaultResponse
+	if err := v.do(ctx, http.MethodGet, address+"/v1/"+strings.TrimPrefix(path, "/"), token, nil, &response); err != nil {
+		return nil, err
+	}
+	data := response.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	result := map[string][]byte{}
+	for k, value := range data {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("value of key %s is not a string", k)
+		}
+		result[k] = []byte(s)
+	}
+	return result, nil
+}
+
+// getToken returns the token of the environment, or a cached token of a login with the service account of Rancher that
+// has not expired yet.
+func (v *vaultProvider) getToken(ctx context.Context, address string) (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.token != "" && v.address == address && time.Now().Before(v.expiry) {
+		return v.token, nil
+	}
+
+	role := settings.SecretStoreVaultRole.Get()
+	if role == "" {
+		return "", fmt.Errorf("the %s setting is not set", settings.SecretStoreVaultRole.Name)
+	}
+	readToken := v.serviceAccountToken
+	if readToken == nil {
+		readToken = func() ([]byte, error) {
+			return os.ReadFile(serviceAccountTokenPath)
+		}
+	}
+	jwt, err := readToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	body, err := json.Marshal(map[string]string{"role": role, "jwt": strings.TrimSpace(string(jwt))})
+	if err != nil {
+		return "", err
+	}
+	var response vaultResponse
+	mount := strings.Trim(settings.SecretStoreVaultAuthMount.Get(), "/")
+	if err := v.do(ctx, http.MethodPost, fmt.Sprintf("%s/v1/auth/%s/login", address, mount), "", body, &response); err != nil {
+		return "", fmt.Errorf("failed to log in to Vault: %w", err)
+	}
+	if response.Auth == nil || response.Auth.ClientToken == "" {
+		return "", fmt.Errorf("failed to log in to Vault: no token returned")
+	}
+
+	v.address = address
+	v.token = response.Auth.ClientToken
+	v.expiry = time.Now().Add(time.Duration(response.Auth.LeaseDuration)*time.Second - vaultTokenRenewBefore)
+	return v.token, nil
+}
+
+func (v *vaultProvider) do(ctx context.Context, method, url, token string, body []byte, response *vaultResponse) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	client, err := v.httpClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, response); err != nil {
+			return fmt.Errorf("failed to parse response of Vault: %w", err)
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(response.Errors) > 0 {
+			return fmt.Errorf("vault returned %s: %s", resp.Status, strings.Join(response.Errors, ", "))
+		}
+		return fmt.Errorf("vault returned %s", resp.Status)
+	}
+	return nil
+}
+
+// httpClient returns the client for the CAs of the secret-store-vault-ca-certs setting, which is rebuilt whenever the
+// setting changes.
+func (v *vaultProvider) httpClient() (*http.Client, error) {
+	caCerts := settings.SecretStoreVaultCACerts.Get()
+
+	v.clientLock.Lock()
+	defer v.clientLock.Unlock()
+	if v.client != nil && v.caCerts == caCerts {
+		return v.client, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if caCerts != "" && !pool.AppendCertsFromPEM([]byte(caCerts)) {
+		return nil, fmt.Errorf("the %s setting does not contain any PEM encoded certificate", settings.SecretStoreVaultCACerts.Name)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	v.client = &http.Client{Transport: transport, Timeout: vaultRequestTimeout}
+	v.caCerts = caCerts
+	return v.client, nil
+}
@@ -0,0 +1,111 @@
+package secretstore
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultProviderGet(t *testing.T) {
+	var logins int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			var login map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&login))
+			if login["role"] != "rancher" || login["jwt"] != "sa-token" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			logins++
+			w.Write([]byte(`{"auth":{"client_token":"vault-token","lease_duration":3600}}`))
+		case "/v1/kv/data/cloud/aws":
+			if r.Header.Get("X-Vault-Token") != "vault-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"data":{"data":{"accessKey":"AKIA","secretKey":"secret"},"metadata":{"version":2}}}`))
+		case "/v1/secret/cloud/aws":
+			w.Write([]byte(`{"data":{"accessKey":"AKIA"}}`))
+		case "/v1/secret/cloud/invalid":
+			w.Write([]byte(`{"data":{"port":22}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	require.NoError(t, settings.SecretStoreVaultAddress.Set(server.URL+"/"))
+	require.NoError(t, settings.SecretStoreVaultRole.Set("rancher"))
+	defer func() {
+		_ = settings.SecretStoreVaultAddress.Set("")
+		_ = settings.SecretStoreVaultRole.Set("")
+	}()
+
+	provider := &vaultProvider{
+		client: server.Client(),
+		serviceAccountToken: func() ([]byte, error) {
+			return []byte("sa-token\n"), nil
+		},
+	}
+	ctx := context.Background()
+
+	data, err := provider.Get(ctx, "kv/data/cloud/aws")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"accessKey": []byte("AKIA"), "secretKey": []byte("secret")}, data)
+
+	data, err = provider.Get(ctx, "/secret/cloud/aws")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"accessKey": []byte("AKIA")}, data)
+	assert.Equal(t, 1, logins, "the token of the first login is reused")
+
+	_, err = provider.Get(ctx, "secret/cloud/invalid")
+	assert.EqualError(t, err, "value of key port is not a string")
+
+	_, err = provider.Get(ctx, "secret/cloud/missing")
+	assert.EqualError(t, err, "vault returned 404 Not Found")
+
+	provider = &vaultProvider{
+		client: server.Client(),
+		serviceAccountToken: func() ([]byte, error) {
+			return []byte("other-token"), nil
+		},
+	}
+	_, err = provider.Get(ctx, "kv/data/cloud/aws")
+	assert.EqualError(t, err, "failed to log in to Vault: vault returned 403 Forbidden: permission denied")
+}
+
+func TestVaultProviderHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caCerts := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	defer func() {
+		_ = settings.SecretStoreVaultCACerts.Set("")
+	}()
+
+	provider := &vaultProvider{}
+	client, err := provider.httpClient()
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err, "the certificate of the server is not trusted without the CA setting")
+
+	require.NoError(t, settings.SecretStoreVaultCACerts.Set(string(caCerts)))
+	client, err = provider.httpClient()
+	require.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.NoError(t, settings.SecretStoreVaultCACerts.Set("invalid"))
+	_, err = provider.httpClient()
+	assert.EqualError(t, err, "the secret-store-vault-ca-certs setting does not contain any PEM encoded certificate")
+}
@@ -128,7 +128,22 @@
 	GKEUpstreamRefresh                  = NewSetting("gke-refresh", "300")
 	HideLocalCluster                    = NewSetting("hide-local-cluster", "false")
 	MachineProvisionImage               = NewSetting("machine-provision-image", "rancher/machine:v0.15.0-rancher99")
+	MachinePlanConvergenceTimeout       = NewSetting("machine-plan-convergence-timeout", "1h")  // how long the plan of a machine may take to be applied before the machine is flagged as stuck
+	MachinePlanCompressionThreshold     = NewSetting("machine-plan-compression-threshold", "0") // size in bytes above which the plan of a machine is stored compressed, i.e. "524288", 0 to disable; ignored for system-agents older than v0.3.4
+	ETCDSnapshotMaxAge                  = NewSetting("etcd-snapshot-max-age", "")               // how old the newest successful etcd snapshot of a cluster may be before it is flagged as overdue, empty to disable
 	SystemFeatureChartRefreshSeconds    = NewSetting("system-feature-chart-refresh-seconds", "900")
+	// commands node commands may run, as a comma separated list of path.Match patterns, each optionally followed by the
+	// subcommands that are allowed as first argument and the flags that are allowed, i.e. "crictl:ps|logs" or
+	// "journalctl:-u|--since"
+	NodeCommandAllowedCommands      = NewSetting("node-command-allowed-commands", "df,free,uptime,journalctl:-u|--unit|--since|--until|-n|--lines|--no-pager|-o|--output|-p|--priority|-b|--boot,crictl:ps|pods|images|inspect|inspectp|inspecti|logs|stats|info|version,/var/lib/rancher/*/bin/crictl:ps|pods|images|inspect|inspectp|inspecti|logs|stats|info|version")
+	ETCDSnapshotRestoreApprovals    = NewSetting("etcd-snapshot-restore-approvals", "0")        // how many distinct users must approve etcd snapshot restores before they are run, 0 to disable
+	SecretStoreVaultAddress         = NewSetting("secret-store-vault-address", "")              // URL of the Vault server that cloud credentials are read from, empty to disable
+	SecretStoreVaultAuthMount       = NewSetting("secret-store-vault-auth-mount", "kubernetes") // path of the Kubernetes auth method of Vault that Rancher logs in with
+	SecretStoreVaultRole            = NewSetting("secret-store-vault-role", "")                 // role of the Kubernetes auth method of Vault that Rancher logs in as
+	SecretStoreVaultCACerts         = NewSetting("secret-store-vault-ca-certs", "")             // PEM encoded CA certificates the certificate of the Vault server is verified with, in addition to the system CAs
+	SecretStoreAllowedPaths         = NewSetting("secret-store-allowed-paths", "")              // paths of secret stores cloud credentials may refer to, as a comma separated list of path.Match patterns in which {namespace} is the namespace of the cloud credential, i.e. "kv/data/rancher/{namespace}/*", empty to disable
+	MachineDeletionHookAllowedHosts = NewSetting("machine-deletion-hook-allowed-hosts", "")     // hosts HTTP machine deletion hooks may call, as a comma separated list of path.Match patterns, i.e. "*.example.com", empty to disable HTTP hooks
+	KubernetesVersionChannelServers = NewSetting("kubernetes-version-channel-servers", "")      // URLs of the channel servers clusters may subscribe to release channels of, as a comma separated list, empty to only allow the KDM release data
 
 	Rke2DefaultVersion = NewSetting("rke2-default-version", "")
 	K3sDefaultVersion  = NewSetting("k3s-default-version", "")
@@ -152,6 +167,30 @@
 	// FleetMinVersion is the minimum version of the fleet chart that rancher will install.
 	FleetMinVersion = NewSetting("fleet-min-version", "")
 
+	// HostedOperatorServiceMonitor enables the creation of ServiceMonitors for the AKS, EKS, and GKE operators, so that
+	// their metrics are scraped when rancher-monitoring is installed in the local cluster.
+	HostedOperatorServiceMonitor = NewSetting("hosted-operator-service-monitor", "false")
+
+	// HostedOperatorCanaryClusters is a comma separated list of the IDs of hosted clusters used to verify new versions
+	// of the AKS, EKS, and GKE operators. The operator of a provider is shared by all its clusters, so a new version is
+	// installed for all of them, and is only accepted once the listed clusters stayed healthy for the
+	// hosted-operator-canary-period. A version that is not accepted is reported, but not rolled back, and no other
+	// version is installed until a newer one is available.
+	HostedOperatorCanaryClusters = NewSetting("hosted-operator-canary-clusters", "")
+
+	// HostedOperatorChartValues is a JSON object of additional values of the AKS, EKS, and GKE operator charts, keyed by
+	// provider name, i.e. {"eks": {"endpoint": "https://eks.example.com"}}. The operator chart of a provider is shared by
+	// all of its clusters, so the values apply to all of them.
+	HostedOperatorChartValues = NewSetting("hosted-operator-chart-values", "")
+
+	// HostedOperatorCanaryPeriod is how long the hosted-operator-canary-clusters must stay healthy after a hosted
+	// operator upgrade.
+	HostedOperatorCanaryPeriod = NewSetting("hosted-operator-canary-period", "15m")
+
+	// HostedOperatorImagePrePull enables verifying that the images of the AKS, EKS, and GKE operator charts can be pulled
+	// from the configured registry with a job before the charts are installed, which is useful for air-gapped installs.
+	HostedOperatorImagePrePull = NewSetting("hosted-operator-image-pre-pull", "false")
+
 	// KubeconfigDefaultTokenTTLMinutes is the default time to live applied to kubeconfigs created for users.
 	// This setting will take effect regardless of the kubeconfig-generate-token status.
 	KubeconfigDefaultTokenTTLMinutes = NewSetting("kubeconfig-default-token-ttl-minutes", "0") // 0 TTL = never expire
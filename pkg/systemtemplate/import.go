@@ -43,6 +43,9 @@ type context struct {
 	IsRKE                 bool
 	PrivateRegistryConfig string
 	Tolerations           string
+	AppendTolerations     string
+	OverrideAffinity      string
+	ResourceRequirements  string
 	ClusterRegistry       string
 }
 
@@ -95,6 +98,20 @@ func SystemTemplate(resp io.Writer, agentImage, authImage, namespace, token, url
 
 	agentEnvVars = templates.ToYAML(envVars)
 
+	var appendTolerations, overrideAffinity, resourceRequirements string
+	if cluster != nil && cluster.Spec.ClusterAgentDeploymentCustomization != nil {
+		customization := cluster.Spec.ClusterAgentDeploymentCustomization
+		if len(customization.AppendTolerations) > 0 {
+			appendTolerations = templates.ToYAML(customization.AppendTolerations)
+		}
+		if customization.OverrideAffinity != nil {
+			overrideAffinity = templates.ToYAML(customization.OverrideAffinity)
+		}
+		if customization.OverrideResourceRequirements != nil {
+			resourceRequirements = templates.ToYAML(customization.OverrideResourceRequirements)
+		}
+	}
+
 	context := &context{
 		Features:              toFeatureString(features),
 		CAChecksum:            CAChecksum(),
@@ -110,6 +127,9 @@ func SystemTemplate(resp io.Writer, agentImage, authImage, namespace, token, url
 		IsRKE:                 cluster != nil && cluster.Status.Driver == apimgmtv3.ClusterDriverRKE,
 		PrivateRegistryConfig: registryConfig,
 		Tolerations:           tolerations,
+		AppendTolerations:     appendTolerations,
+		OverrideAffinity:      overrideAffinity,
+		ResourceRequirements:  resourceRequirements,
 		ClusterRegistry:       registryURL,
 	}
 
@@ -125,6 +125,9 @@
         app: cattle-cluster-agent
     spec:
       affinity:
+      {{- if .OverrideAffinity }}
+{{ .OverrideAffinity | indent 8 }}
+      {{- else }}
         podAntiAffinity:
           preferredDuringSchedulingIgnoredDuringExecution:
           - weight: 100
@@ -173,6 +176,7 @@
                 values:
                 - "true"
             weight: 1
+      {{- end }}
       serviceAccountName: cattle
       tolerations:
       {{- if .Tolerations }}
@@ -190,6 +194,10 @@
         key: "node-role.kubernetes.io/master"
         operator: "Exists"
       {{- end }}
+      {{- if .AppendTolerations }}
+      # Tolerations added from the cluster agent deployment customization
+{{ .AppendTolerations | indent 6 }}
+      {{- end }}
       containers:
         - name: cluster-register
           imagePullPolicy: IfNotPresent
@@ -214,6 +222,10 @@
 {{ .AgentEnvVars | indent 10 }}
       {{- end }}
           image: {{.AgentImage}}
+      {{- if .ResourceRequirements }}
+          resources:
+{{ .ResourceRequirements | indent 12 }}
+      {{- end }}
           volumeMounts:
           - name: cattle-credentials
             mountPath: /cattle-credentials
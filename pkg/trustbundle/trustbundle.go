@@ -0,0 +1,149 @@
+// Package trustbundle assembles the additional certificate authorities that Rancher, the hosted cluster operators and
+// the clusters provisioned by Rancher trust. The bundle is aggregated from the tls-ca-additional secret and from every
+// secret and config map in the cattle-system namespace that is labeled for inclusion in the bundle.
+package trustbundle
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"sort"
+
+	"github.com/rancher/rancher/pkg/namespace"
+	corecontrollers "github.com/rancher/wrangler/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// Label marks the secrets and config maps in the cattle-system namespace whose certificates are included in the
+	// trust bundle when set to "true". Every key of a labeled source is read, and data that is not a PEM certificate is
+	// ignored.
+	Label = "cattle.io/trust-bundle"
+
+	// LegacySecretName is the name of the secret in the cattle-system namespace that holds the additional certificate
+	// authorities that Rancher was installed with. It is mounted by the Rancher and hosted cluster operator charts.
+	LegacySecretName = "tls-ca-additional"
+
+	// LegacySecretKey is the key of the certificates in the legacy secret.
+	LegacySecretKey = "ca-additional.pem"
+
+	// ManagedAnnotation marks a legacy secret that Rancher created from the labeled sources, so that its contents are
+	// not counted as a source of the bundle.
+	ManagedAnnotation = "cattle.io/trust-bundle-managed"
+)
+
+// Get returns the certificates of the trust bundle in PEM format, or nil if the bundle is empty. The certificates of
+// the legacy secret come first, followed by those of the labeled secrets and config maps in the order of their names.
+// Certificates that appear in more than one source are only included once.
+func Get(secrets corecontrollers.SecretCache, configMaps corecontrollers.ConfigMapCache) ([]byte, error) {
+	var sources [][]byte
+
+	legacy, err := secrets.Get(namespace.System, LegacySecretName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if legacy != nil && !Managed(legacy) {
+		sources = append(sources, legacy.Data[LegacySecretKey])
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{Label: "true"})
+	labeledSecrets, err := secrets.List(namespace.System, selector)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(labeledSecrets, func(i, j int) bool {
+		return labeledSecrets[i].Name < labeledSecrets[j].Name
+	})
+	for _, secret := range labeledSecrets {
+		if secret.Name == LegacySecretName {
+			continue
+		}
+		for _, key := range sortedKeys(secret.Data) {
+			sources = append(sources, secret.Data[key])
+		}
+	}
+
+	labeledConfigMaps, err := configMaps.List(namespace.System, selector)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(labeledConfigMaps, func(i, j int) bool {
+		return labeledConfigMaps[i].Name < labeledConfigMaps[j].Name
+	})
+	for _, configMap := range labeledConfigMaps {
+		data := map[string][]byte{}
+		for key, value := range configMap.Data {
+			data[key] = []byte(value)
+		}
+		for key, value := range configMap.BinaryData {
+			data[key] = value
+		}
+		for _, key := range sortedKeys(data) {
+			sources = append(sources, data[key])
+		}
+	}
+
+	return Merge(sources...), nil
+}
+
+// Merge returns the PEM certificates of the sources in order, without duplicates, or nil if there are none. Blocks
+// that are not certificates are dropped.
+func Merge(sources ...[]byte) []byte {
+	var (
+		result []byte
+		seen   = map[string]bool{}
+	)
+	for _, source := range sources {
+		for rest := source; len(rest) > 0; {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" || seen[string(block.Bytes)] {
+				continue
+			}
+			seen[string(block.Bytes)] = true
+			result = append(result, pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: block.Bytes})...)
+		}
+	}
+	return result
+}
+
+// AppendBase64 appends the bundle to the base64 encoded PEM certificates, and returns the result base64 encoded.
+// Certificates that are already present are not appended again.
+func AppendBase64(caCert string, bundle []byte) (string, error) {
+	if len(bundle) == 0 {
+		return caCert, nil
+	}
+
+	caBytes, err := base64.StdEncoding.DecodeString(caCert)
+	if err != nil {
+		return "", err
+	}
+	if len(Merge(caBytes)) == 0 {
+		// keep certificates that are not PEM encoded as they are
+		return base64.StdEncoding.EncodeToString(append(caBytes, bundle...)), nil
+	}
+	return base64.StdEncoding.EncodeToString(Merge(caBytes, bundle)), nil
+}
+
+// Managed returns true if the secret is the legacy secret as created by Rancher from the labeled sources.
+func Managed(secret *corev1.Secret) bool {
+	return secret.Annotations[ManagedAnnotation] == "true"
+}
+
+// Source returns true if the object with the passed in namespace, name and labels contributes to the trust bundle.
+func Source(ns, name string, objLabels map[string]string) bool {
+	return ns == namespace.System && (name == LegacySecretName || objLabels[Label] == "true")
+}
+
+func sortedKeys(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package trustbundle
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCert(t *testing.T, name string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestMerge(t *testing.T) {
+	a := newCert(t, "a")
+	b := newCert(t, "b")
+	key := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")})
+
+	tests := []struct {
+		name     string
+		sources  [][]byte
+		expected []byte
+	}{
+		{
+			name: "no sources",
+		},
+		{
+			name:    "no certificates",
+			sources: [][]byte{[]byte("not a certificate"), key},
+		},
+		{
+			name:     "keeps order",
+			sources:  [][]byte{b, a},
+			expected: append(append([]byte{}, b...), a...),
+		},
+		{
+			name:     "drops duplicates and other blocks",
+			sources:  [][]byte{append(append([]byte{}, a...), key...), nil, append(append([]byte{}, b...), a...)},
+			expected: append(append([]byte{}, a...), b...),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Merge(tt.sources...))
+		})
+	}
+}
+
+func TestAppendBase64(t *testing.T) {
+	a := newCert(t, "a")
+	b := newCert(t, "b")
+	encode := base64.StdEncoding.EncodeToString
+
+	result, err := AppendBase64(encode(a), nil)
+	require.NoError(t, err)
+	assert.Equal(t, encode(a), result)
+
+	result, err = AppendBase64(encode(a), append(append([]byte{}, a...), b...))
+	require.NoError(t, err)
+	assert.Equal(t, encode(append(append([]byte{}, a...), b...)), result)
+
+	_, err = AppendBase64("not base64", b)
+	assert.Error(t, err)
+}